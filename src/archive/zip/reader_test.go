@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -1046,3 +1047,65 @@ func TestIssue12449(t *testing.T) {
 		t.Errorf("Error reading the archive: %v", err)
 	}
 }
+
+func TestFS(t *testing.T) {
+	z, err := OpenReader("testdata/unix.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	if err := fstest.TestFS(z, "hello", "dir/bar", "dir/empty", "readonly"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSImpliedDirs(t *testing.T) {
+	// Build an archive whose directories are only implied
+	// by the names of the files inside them.
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, name := range []string{"a/b/c", "a/d", "e"} {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(name))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	z, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(z, "a", "a/b", "a/b/c", "a/d", "e"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSOpenErrors(t *testing.T) {
+	z, err := OpenReader("testdata/unix.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+
+	for _, name := range []string{"missing", "dir/missing", "/hello", "dir/../hello", "dir/"} {
+		if _, err := z.Open(name); err == nil {
+			t.Errorf("Open(%q) succeeded, want error", name)
+		}
+	}
+
+	f, err := z.Open("dir/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() {
+		t.Errorf("Stat(dir/empty).IsDir() = false, want true")
+	}
+}
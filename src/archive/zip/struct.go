@@ -20,6 +20,7 @@ fields must be used instead.
 package zip
 
 import (
+	"io/fs"
 	"os"
 	"path"
 	"time"
@@ -134,7 +135,7 @@ func (h *FileHeader) FileInfo() os.FileInfo {
 	return headerFileInfo{h}
 }
 
-// headerFileInfo implements os.FileInfo.
+// headerFileInfo implements os.FileInfo and fs.DirEntry.
 type headerFileInfo struct {
 	fh *FileHeader
 }
@@ -151,6 +152,9 @@ func (fi headerFileInfo) ModTime() time.Time { return fi.fh.ModTime() }
 func (fi headerFileInfo) Mode() os.FileMode  { return fi.fh.Mode() }
 func (fi headerFileInfo) Sys() interface{}   { return fi.fh }
 
+func (fi headerFileInfo) Type() fs.FileMode          { return fi.Mode().Type() }
+func (fi headerFileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
 // FileInfoHeader creates a partially-populated FileHeader from an
 // os.FileInfo.
 // Because os.FileInfo's Name method returns only the base name of
@@ -616,6 +616,7 @@ func tostruct0(t *types.Type, l []*Node) {
 		fields[i] = f
 	}
 	t.SetFields(fields)
+	t.SetPkg(fieldsPkg(fields))
 
 	checkdupfields("field", t)
 
@@ -624,6 +625,20 @@ func tostruct0(t *types.Type, l []*Node) {
 	}
 }
 
+// fieldsPkg returns the package of the unexported names in fields
+// if it is not the local package (as in instances of generics of
+// other packages), and nil otherwise.
+func fieldsPkg(fields ...[]*types.Field) *types.Pkg {
+	for _, l := range fields {
+		for _, f := range l {
+			if s := origSym(f.Sym); s != nil && !types.IsExported(s.Name) && s.Pkg != localpkg {
+				return s.Pkg
+			}
+		}
+	}
+	return nil
+}
+
 func tofunargs(l []*Node, funarg types.Funarg) *types.Type {
 	t := types.New(TSTRUCT)
 	t.StructType().Funarg = funarg
@@ -710,6 +725,7 @@ func tointerface0(t *types.Type, l []*Node) {
 		fields = append(fields, f)
 	}
 	t.SetInterface(fields)
+	t.SetPkg(fieldsPkg(fields))
 }
 
 func fakeRecv() *Node {
@@ -750,6 +766,7 @@ func functype0(t *types.Type, this *Node, in, out []*Node) {
 	t.FuncType().Results = tofunargs(out, types.FunargResults)
 
 	checkdupfields("argument", t.Recvs(), t.Params(), t.Results())
+	t.SetPkg(fieldsPkg(t.Recvs().FieldSlice(), t.Params().FieldSlice(), t.Results().FieldSlice()))
 
 	if t.Recvs().Broke() || t.Results().Broke() || t.Params().Broke() {
 		t.SetBroke(true)
@@ -894,7 +911,7 @@ func addmethod(msym *types.Sym, t *types.Type, local, nointerface bool) *types.F
 		return nil
 	}
 
-	if local && mt.Sym.Pkg != localpkg {
+	if local && mt.Sym.Pkg != localpkg && !instantiatedHere(mt) {
 		yyerror("cannot define new methods on non-local type %v", mt)
 		return nil
 	}
@@ -0,0 +1,344 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types"
+	"cmd/internal/obj"
+	"cmd/internal/src"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// embedCfg is the embedding configuration read from the -embedcfg
+// file. The go command resolves the patterns listed in //go:embed
+// directives and tells the compiler which files they match and
+// where those files are.
+var embedCfg struct {
+	Patterns map[string][]string // pattern -> files matched, relative to the package directory
+	Files    map[string]string   // file -> path of file on disk
+}
+
+func readEmbedCfg(file string) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Fatalf("-embedcfg: %v", err)
+	}
+	if err := json.Unmarshal(data, &embedCfg); err != nil {
+		log.Fatalf("%s: %v", file, err)
+	}
+	if embedCfg.Patterns == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Patterns", file)
+	}
+	if embedCfg.Files == nil {
+		log.Fatalf("%s: invalid embedcfg: missing Files", file)
+	}
+}
+
+// An embedPragma is a //go:embed directive.
+type embedPragma struct {
+	pos      syntax.Pos
+	patterns []string
+}
+
+// An embedVar is a variable initialized by //go:embed directives.
+type embedVar struct {
+	n        *Node
+	pos      src.XPos
+	patterns []string
+}
+
+// embedlist lists the variables to be initialized by initEmbed.
+var embedlist []*embedVar
+
+const (
+	embedUnknown = iota
+	embedBytes
+	embedString
+	embedFiles
+)
+
+// pragmaEmbed records the //go:embed directive at pos with arguments args.
+// The directive applies to the variable declared next; see varEmbed.
+func (p *noder) pragmaEmbed(pos syntax.Pos, args string) {
+	patterns, err := parseGoEmbed(args)
+	if err != nil {
+		p.error(syntax.Error{Pos: pos, Msg: err.Error()})
+		return
+	}
+	if len(patterns) == 0 {
+		p.error(syntax.Error{Pos: pos, Msg: "usage: //go:embed pattern..."})
+		return
+	}
+	p.embeds = append(p.embeds, embedPragma{pos, patterns})
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the
+// patterns. Patterns are separated by spaces and may be written as Go
+// double-quoted or raw strings.
+// This is a copy of parseGoEmbed in go/build/read.go.
+func parseGoEmbed(args string) ([]string, error) {
+	var list []string
+	for args = strings.TrimSpace(args); args != ""; args = strings.TrimSpace(args) {
+		var pattern string
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			pattern = args[:i]
+			args = args[i:]
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			pattern = args[1 : 1+i]
+			args = args[1+i+1:]
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					pattern = q
+					args = args[i+1:]
+					break Switch
+				}
+			}
+			return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+		}
+
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, pattern)
+	}
+	return list, nil
+}
+
+// takeEmbeds removes and returns the pending //go:embed directives
+// that precede pos.
+func (p *noder) takeEmbeds(pos syntax.Pos) []embedPragma {
+	i := 0
+	for i < len(p.embeds) && posBefore(p.embeds[i].pos, pos) {
+		i++
+	}
+	embeds := p.embeds[:i]
+	p.embeds = p.embeds[i:]
+	return embeds
+}
+
+// misplacedEmbeds reports the pending //go:embed directives that
+// precede pos. It is called for declarations other than variable
+// declarations, which //go:embed directives cannot apply to.
+func (p *noder) misplacedEmbeds(pos syntax.Pos) {
+	for _, e := range p.takeEmbeds(pos) {
+		p.yyerrorpos(e.pos, "misplaced go:embed directive")
+	}
+}
+
+func posBefore(x, y syntax.Pos) bool {
+	return x.Line() < y.Line() || x.Line() == y.Line() && x.Col() < y.Col()
+}
+
+// varEmbed applies the //go:embed directives preceding the variable
+// declaration decl, if any, to the declared variable.
+func (p *noder) varEmbed(decl *syntax.VarDecl, names []*Node, typ *Node, exprs []*Node) {
+	embeds := p.takeEmbeds(decl.Pos())
+	if len(embeds) == 0 {
+		return
+	}
+	pos := embeds[0].pos
+	switch {
+	case !p.importedEmbed:
+		p.yyerrorpos(pos, "go:embed only allowed in Go files that import \"embed\"")
+		return
+	case len(names) > 1:
+		p.yyerrorpos(pos, "go:embed cannot apply to multiple vars")
+		return
+	case len(exprs) > 0:
+		p.yyerrorpos(pos, "go:embed cannot apply to var with initializer")
+		return
+	case typ == nil:
+		p.yyerrorpos(pos, "go:embed cannot apply to var without type")
+		return
+	case dclcontext != PEXTERN:
+		p.yyerrorpos(pos, "go:embed cannot apply to var inside func")
+		return
+	case embedCfg.Patterns == nil:
+		p.yyerrorpos(pos, "invalid go:embed: build system did not supply embed configuration")
+		return
+	}
+
+	var patterns []string
+	for _, e := range embeds {
+		patterns = append(patterns, e.patterns...)
+	}
+	embedlist = append(embedlist, &embedVar{names[0], p.makeXPos(pos), patterns})
+}
+
+// embedKind determines the kind of embedding variable.
+func embedKind(typ *types.Type) int {
+	if typ.Sym != nil && typ.Sym.Name == "FS" && (typ.Sym.Pkg.Path == "embed" || typ.Sym.Pkg == localpkg && myimportpath == "embed") {
+		return embedFiles
+	}
+	if typ.Etype == TSTRING {
+		return embedString
+	}
+	if typ.Sym == nil && typ.IsSlice() && typ.Elem().Etype == TUINT8 {
+		return embedBytes
+	}
+	return embedUnknown
+}
+
+// embedFileNameSplit splits the embedded file name into the
+// directory containing it and its final element. Directory names
+// have a trailing slash.
+func embedFileNameSplit(name string) (dir, elem string) {
+	name = strings.TrimSuffix(name, "/")
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return ".", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// embedFileLess implements the sort order for a list of embedded files.
+// See the comment inside ../../../../embed/embed.go's FS struct.
+func embedFileLess(x, y string) bool {
+	xdir, xelem := embedFileNameSplit(x)
+	ydir, yelem := embedFileNameSplit(y)
+	return xdir < ydir || xdir == ydir && xelem < yelem
+}
+
+// embedFileList returns the sorted list of files to embed in v,
+// or nil if there is an error.
+func embedFileList(v *embedVar, kind int) []string {
+	have := make(map[string]bool)
+	var list []string
+	for _, pattern := range v.patterns {
+		files, ok := embedCfg.Patterns[pattern]
+		if !ok {
+			yyerrorl(v.pos, "invalid go:embed: build system did not map pattern: %s", pattern)
+		}
+		for _, file := range files {
+			if embedCfg.Files[file] == "" {
+				yyerrorl(v.pos, "invalid go:embed: build system did not map file: %s", file)
+				continue
+			}
+			if !have[file] {
+				have[file] = true
+				list = append(list, file)
+			}
+			if kind == embedFiles {
+				for dir := path.Dir(file); dir != "." && !have[dir+"/"]; dir = path.Dir(dir) {
+					have[dir+"/"] = true
+					list = append(list, dir+"/")
+				}
+			}
+		}
+	}
+	obj.SortSlice(list, func(i, j int) bool {
+		return embedFileLess(list[i], list[j])
+	})
+
+	if kind == embedString || kind == embedBytes {
+		if len(list) > 1 {
+			yyerrorl(v.pos, "invalid go:embed: multiple files for type %v", v.n.Type)
+			return nil
+		}
+	}
+	return list
+}
+
+// initEmbed emits the initialized data for the variable v, which
+// must have been type checked.
+func initEmbed(v *embedVar) {
+	kind := embedKind(v.n.Type)
+	if kind == embedUnknown {
+		yyerrorl(v.pos, "go:embed cannot apply to var of type %v", v.n.Type)
+		return
+	}
+	files := embedFileList(v, kind)
+	if len(files) == 0 {
+		return
+	}
+
+	sym := v.n.Sym.Linksym()
+	switch kind {
+	case embedString, embedBytes:
+		data, err := ioutil.ReadFile(embedCfg.Files[files[0]])
+		if err != nil {
+			yyerrorl(v.pos, "embed %s: %v", files[0], err)
+			return
+		}
+		if kind == embedBytes {
+			// The data must be writable.
+			slicebytes(v.n, string(data), len(data))
+			return
+		}
+		off := dsymptr(sym, 0, stringsym(v.pos, string(data)), 0)
+		duintptr(sym, off, uint64(len(data)))
+
+	case embedFiles:
+		// The FS holds a pointer to a []file slice. The slice
+		// header and the array it refers to are laid out in a
+		// single read-only symbol.
+		slicedata := Ctxt.Lookup(`"".` + v.n.Sym.Name + `.files`)
+		off := 0
+		off = dsymptr(slicedata, off, slicedata, 3*Widthptr)
+		off = duintptr(slicedata, off, uint64(len(files)))
+		off = duintptr(slicedata, off, uint64(len(files)))
+
+		// ../../../../embed/embed.go's file type is:
+		//	name string
+		//	data string
+		// Emit one of these per file in the set.
+		for _, file := range files {
+			off = dsymptr(slicedata, off, stringsym(v.pos, file), 0)
+			off = duintptr(slicedata, off, uint64(len(file)))
+			if strings.HasSuffix(file, "/") {
+				// directory: no data
+				off = duintptr(slicedata, off, 0)
+				off = duintptr(slicedata, off, 0)
+				continue
+			}
+			data, err := ioutil.ReadFile(embedCfg.Files[file])
+			if err != nil {
+				yyerrorl(v.pos, "embed %s: %v", file, err)
+				return
+			}
+			off = dsymptr(slicedata, off, stringsym(v.pos, string(data)), 0)
+			off = duintptr(slicedata, off, uint64(len(data)))
+		}
+		ggloblsym(slicedata, int32(off), obj.RODATA|obj.LOCAL)
+		dsymptr(sym, 0, slicedata, 0)
+	}
+}
@@ -588,21 +588,22 @@ func symfmt(s *types.Sym, flag FmtFlag, mode fmtMode) string {
 	if s.Pkg != nil && flag&FmtShort == 0 {
 		switch mode {
 		case FErr: // This is for the user
+			name := instDisplayName(s.Name)
 			if s.Pkg == builtinpkg || s.Pkg == localpkg {
-				return s.Name
+				return name
 			}
 
 			// If the name was used by multiple packages, display the full path,
 			if s.Pkg.Name != "" && numImport[s.Pkg.Name] > 1 {
-				return fmt.Sprintf("%q.%s", s.Pkg.Path, s.Name)
+				return fmt.Sprintf("%q.%s", s.Pkg.Path, name)
 			}
-			return s.Pkg.Name + "." + s.Name
+			return s.Pkg.Name + "." + name
 
 		case FDbg:
 			return s.Pkg.Name + "." + s.Name
 
 		case FTypeIdName:
-			return s.Pkg.Name + "." + s.Name // dcommontype, typehash
+			return s.Pkg.Name + "." + instDisplayName(s.Name) // dcommontype, typehash
 
 		case FTypeId:
 			return s.Pkg.Prefix + "." + s.Name // (methodsym), typesym, weaksym
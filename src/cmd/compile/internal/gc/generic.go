@@ -0,0 +1,1382 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types"
+	"cmd/internal/objabi"
+	"cmd/internal/src"
+)
+
+// Generic functions and types are implemented by stenciling.
+//
+// The noder does not translate generic declarations into Nodes.
+// Instead, it records their syntax trees in a genericDecl and
+// declares the generic name with a placeholder Node marked as
+// generic. Whenever the type checker encounters an instantiation
+// (an index expression or call whose operand is a generic name),
+// it type-checks the type arguments, or infers them from the
+// call arguments, checks them against the type parameter
+// constraints and asks the genericDecl for the corresponding
+// instance. The first request for a set of type arguments nodes
+// the declaration again, this time with the type parameter names
+// bound to the type arguments, and type-checks the result like
+// any other top-level declaration. Later requests return the same
+// instance.
+//
+// Interfaces that contain type elements (~T, A|B) or that embed
+// comparable are constraint interfaces. They cannot be represented
+// as types and are recorded as generic declarations as well; they
+// are only interpreted (on the syntax tree) when checking type
+// arguments.
+//
+// To make generic declarations usable from other packages, the
+// source of the files declaring them is appended to the export
+// data, along with the package-level declarations they refer to.
+// The importer parses these files again and records their generic
+// declarations in the imported package (see importGenerics).
+//
+// Instances belong to the package of the generic declaration, so
+// that lib.Stack[int] is one type however many packages use it.
+// A package that needs an instance uses the one declared in its
+// imports' export data if there is one, and otherwise compiles it
+// itself; the functions, methods and type descriptors of instances
+// are DUPOK, so the linker keeps one copy.
+
+// A genericDecl describes a generic function or type, a constraint
+// interface, or the predeclared comparable constraint.
+type genericDecl struct {
+	p       *noder           // noder for the file containing the declaration
+	n       *Node            // placeholder Node declaring the generic name
+	tparams []*syntax.Field  // type parameters, one per name
+	fun     *syntax.FuncDecl // generic function, or nil
+	typ     *syntax.TypeDecl // generic type or constraint interface, or nil
+	methods []genericMethod  // methods of a generic type
+
+	constraint bool // the declaration is a constraint interface
+	comparable bool // the predeclared comparable constraint
+
+	insts []*genericInst
+}
+
+// A genericMethod is a method declared with a generic receiver type.
+type genericMethod struct {
+	p   *noder
+	fun *syntax.FuncDecl
+}
+
+// A genericInst is an instantiation of a generic declaration.
+type genericInst struct {
+	g        *genericDecl
+	targs    []*types.Type
+	n        *Node // instantiated function (ONAME) or type (OTYPE)
+	imported bool  // n was read from export data, not compiled here
+}
+
+// An importDef records the definition of an imported package
+// name (or dot-imported name) in a file declaring generics.
+type importDef struct {
+	sym *types.Sym
+	def *types.Node
+}
+
+var (
+	// generics maps generic placeholder Nodes to their declarations.
+	generics = make(map[*Node]*genericDecl)
+
+	// genericInsts maps instantiated types to their instantiation.
+	genericInsts = make(map[*types.Type]*genericInst)
+
+	// pendingMethods collects methods with generic receiver types
+	// until all files are noded; see bindGenericMethods.
+	pendingMethods []genericMethod
+
+	// constraintIfaces is the set of names of package-level
+	// constraint interfaces; see collectConstraints.
+	constraintIfaces map[string]bool
+
+	// genericFiles lists the files declaring generics, local or
+	// imported, whose source is written to the export data.
+	genericFiles []*noder
+
+	// importedGenerics records the packages whose generic
+	// declarations have been imported.
+	importedGenerics = make(map[*types.Pkg]bool)
+
+	// foreignInsts records the instances (functions, methods
+	// and types) of generic declarations of other packages.
+	foreignInsts = make(map[*Node]bool)
+)
+
+// maxInsts limits the number of instances of a single generic
+// declaration. Exceeding it indicates an instantiation cycle such
+// as a generic function calling itself with ever growing type
+// arguments.
+const maxInsts = 1000
+
+// genericOf returns the generic declaration named by n, or nil.
+func genericOf(n *Node) *genericDecl {
+	if n == nil {
+		return nil
+	}
+	n = resolve(n)
+	if (n.Op != ONAME && n.Op != OTYPE) || n.Name == nil || !n.Name.Generic() {
+		return nil
+	}
+	return generics[n]
+}
+
+// kind describes g for error messages.
+func (g *genericDecl) kind() string {
+	switch {
+	case g.fun != nil:
+		return "function"
+	case g.constraint:
+		return "constraint"
+	}
+	return "type"
+}
+
+// genericRecv returns the instantiated receiver base type
+// expression of a method declared on a generic type, or nil.
+func genericRecv(recv *syntax.Field) *syntax.IndexExpr {
+	if recv == nil {
+		return nil
+	}
+	typ := recv.Type
+	if op, ok := typ.(*syntax.Operation); ok && op.Op == syntax.Mul && op.Y == nil {
+		typ = op.X
+	}
+	inst, _ := typ.(*syntax.IndexExpr)
+	return inst
+}
+
+// tparamNames returns the type parameter names of a generic
+// receiver type expression.
+func tparamNames(inst *syntax.IndexExpr) []*syntax.Name {
+	var list []syntax.Expr
+	if l, ok := inst.Index.(*syntax.ListExpr); ok {
+		list = l.ElemList
+	} else {
+		list = []syntax.Expr{inst.Index}
+	}
+	var names []*syntax.Name
+	for _, x := range list {
+		if name, ok := x.(*syntax.Name); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) != len(list) {
+		return nil
+	}
+	return names
+}
+
+// collectConstraints determines the set of package-level constraint
+// interfaces across all files of the package. An interface is a
+// constraint interface if it contains a type element or embeds
+// comparable or another constraint interface.
+func collectConstraints(noders []*noder) {
+	ifaces := make(map[string]*syntax.InterfaceType)
+	for _, p := range noders {
+		if p.file == nil {
+			continue
+		}
+		for _, decl := range p.file.DeclList {
+			if decl, ok := decl.(*syntax.TypeDecl); ok {
+				if it, ok := decl.Type.(*syntax.InterfaceType); ok {
+					ifaces[decl.Name.Value] = it
+				}
+			}
+		}
+	}
+
+	constraintIfaces = make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for name, it := range ifaces {
+			if !constraintIfaces[name] && isConstraintIface(it) {
+				constraintIfaces[name] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// isConstraintIface reports whether it is a constraint interface,
+// given the package-level constraint interfaces known so far.
+func isConstraintIface(it *syntax.InterfaceType) bool {
+	for _, m := range it.MethodList {
+		if m.Name != nil {
+			continue // method
+		}
+		switch t := m.Type.(type) {
+		case *syntax.Name:
+			if t.Value == "comparable" || constraintIfaces[t.Value] {
+				return true
+			}
+		case *syntax.SelectorExpr:
+			// imported interface
+		case *syntax.IndexExpr:
+			if x, ok := t.X.(*syntax.Name); ok && constraintIfaces[x.Value] {
+				return true
+			}
+		default:
+			return true // type term or union
+		}
+	}
+	return false
+}
+
+// declareGeneric declares the generic name n in the package scope.
+// Generic names are neither exported nor compiled, so they bypass
+// declare.
+func (p *noder) declareGeneric(n *Node, g *genericDecl) {
+	p.generic = true
+	n.Name.SetGeneric(true)
+	g.n = n
+	generics[n] = g
+
+	s := n.Sym
+	if s.IsBlank() {
+		return
+	}
+	if p.pkg != nil {
+		// imported; see importGenerics
+		s.Def = asTypesNode(n)
+		return
+	}
+	if s.Block == types.Block {
+		redeclare(n.Pos, s, "in this block")
+	}
+	s.Block = types.Block
+	s.Lastlineno = lineno
+	s.Def = asTypesNode(n)
+}
+
+// checkTParams reports duplicate type parameter names in list.
+func (p *noder) checkTParams(list []*syntax.Field) {
+	seen := make(map[string]*syntax.Name)
+	for _, f := range list {
+		name := f.Name
+		if name.Value == "_" {
+			continue
+		}
+		if prev := seen[name.Value]; prev != nil {
+			p.yyerrorpos(name.Pos(), "%s redeclared in this block\n\tprevious declaration at %v", name.Value, linestr(p.makeXPos(prev.Pos())))
+			continue
+		}
+		seen[name.Value] = name
+	}
+}
+
+// genericTypeDecl records the generic type or constraint interface decl.
+func (p *noder) genericTypeDecl(decl *syntax.TypeDecl) {
+	if dclcontext != PEXTERN {
+		p.yyerrorpos(decl.Pos(), "generic type cannot be declared inside a function")
+		return
+	}
+	if decl.Alias {
+		p.yyerrorpos(decl.Pos(), "generic type cannot be alias")
+		return
+	}
+	if decl.Type == nil {
+		return // syntax error reported by parser
+	}
+
+	g := &genericDecl{p: p, tparams: decl.TParamList, typ: decl}
+	if it, ok := decl.Type.(*syntax.InterfaceType); ok {
+		g.constraint = constraintIfaces[decl.Name.Value] || isConstraintIface(it)
+	}
+	p.checkTParams(g.tparams)
+	for _, f := range g.tparams {
+		if name, ok := decl.Type.(*syntax.Name); ok && name.Value == f.Name.Value {
+			p.yyerrorpos(decl.Type.Pos(), "cannot use a type parameter as RHS in type declaration")
+			return
+		}
+	}
+
+	n := p.setlineno(decl.Name, dclname(p.genericSym(decl.Name)))
+	n.Op = OTYPE
+	p.declareGeneric(n, g)
+	if p.pkg != nil {
+		return
+	}
+
+	// Node the declaration once, without keeping the result, to
+	// report errors detected by the noder and to mark the imported
+	// packages it refers to as used.
+	p.withTParams(tparamList(g.tparams), placeholders(len(g.tparams)), func() {
+		p.constraints(g.tparams)
+		if !g.constraint {
+			p.typeExpr(decl.Type)
+		}
+	})
+}
+
+// genericFuncDecl records the generic function or method decl.
+func (p *noder) genericFuncDecl(fun *syntax.FuncDecl) {
+	if fun.Body == nil {
+		p.yyerrorpos(fun.Pos(), "missing function body")
+		return
+	}
+
+	if fun.Recv != nil {
+		// method of generic type; see bindGenericMethods
+		p.generic = true
+		names := tparamNames(genericRecv(fun.Recv))
+		if names == nil {
+			p.yyerrorpos(fun.Recv.Type.Pos(), "receiver type parameters must be identifiers")
+			return
+		}
+		pendingMethods = append(pendingMethods, genericMethod{p, fun})
+		if p.pkg == nil {
+			p.withTParams(names, placeholders(len(names)), func() {
+				p.funcInst(fun, nil, types.Types[TINTER])
+			})
+		}
+		return
+	}
+
+	switch fun.Name.Value {
+	case "init":
+		p.yyerrorpos(fun.Pos(), "func init must have no type parameters")
+		return
+	case "main":
+		if p.pkg == nil && localpkg.Name == "main" {
+			p.yyerrorpos(fun.Pos(), "func main must have no type parameters")
+			return
+		}
+	}
+
+	g := &genericDecl{p: p, tparams: fun.TParamList, fun: fun}
+	p.checkTParams(g.tparams)
+
+	n := p.setlineno(fun.Name, newname(p.genericSym(fun.Name)))
+	n.SetClass(PFUNC)
+	p.declareGeneric(n, g)
+	if p.pkg != nil {
+		return
+	}
+
+	// see genericTypeDecl
+	p.withTParams(tparamList(g.tparams), placeholders(len(g.tparams)), func() {
+		p.constraints(g.tparams)
+		p.funcInst(fun, nil, nil)
+	})
+}
+
+// constraints nodes the type parameter constraints in list,
+// discarding the result; see genericTypeDecl.
+func (p *noder) constraints(list []*syntax.Field) {
+	var last syntax.Expr
+	for _, f := range list {
+		if f.Type != last {
+			p.constraint(f.Type)
+			last = f.Type
+		}
+	}
+}
+
+func (p *noder) constraint(c syntax.Expr) {
+	switch c := c.(type) {
+	case *syntax.Operation:
+		if c.Op == syntax.Tilde || c.Op == syntax.Or {
+			p.constraint(c.X)
+			if c.Y != nil {
+				p.constraint(c.Y)
+			}
+			return
+		}
+	case *syntax.InterfaceType:
+		for _, m := range c.MethodList {
+			if m.Name != nil {
+				p.typeExpr(m.Type)
+			} else {
+				p.constraint(m.Type)
+			}
+		}
+		return
+	case *syntax.Name:
+		if c.Value == "comparable" {
+			return
+		}
+	}
+	p.typeExpr(c)
+}
+
+// bindGenericMethods associates the methods declared with generic
+// receiver types with their generic type declarations.
+func bindGenericMethods() {
+	for _, m := range pendingMethods {
+		inst := genericRecv(m.fun.Recv)
+		var g *genericDecl
+		if base, ok := inst.X.(*syntax.Name); ok {
+			g = genericOf(asNode(m.p.genericSym(base).Def))
+		}
+		switch {
+		case g == nil || g.typ == nil:
+			m.p.yyerrorpos(inst.X.Pos(), "%s is not a generic type", syntax.String(inst.X))
+		case g.constraint:
+			m.p.yyerrorpos(inst.X.Pos(), "invalid receiver type %s (constraint interface)", syntax.String(inst))
+		case len(tparamNames(inst)) != len(g.tparams):
+			m.p.yyerrorpos(inst.Pos(), "got %d type parameters, but receiver base type declares %d", len(tparamNames(inst)), len(g.tparams))
+		default:
+			g.methods = append(g.methods, m)
+		}
+	}
+	pendingMethods = nil
+}
+
+// saveImports records the imported package names of the file
+// being noded; see importDef.
+func (p *noder) saveImports() {
+	for _, s := range localpkg.Syms {
+		n := asNode(s.Def)
+		if n != nil && (n.Op == OPACK || IsAlias(s)) {
+			p.imports = append(p.imports, importDef{s, s.Def})
+		}
+	}
+}
+
+// genericSym returns the symbol of the generic declaration name.
+// Generic declarations of files read from export data belong to
+// the imported package.
+func (p *noder) genericSym(name *syntax.Name) *types.Sym {
+	if p.pkg != nil {
+		return p.pkg.Lookup(name.Value)
+	}
+	return p.name(name)
+}
+
+// importGenerics declares the generic declarations of the files
+// noders of an imported package, read from export data. The
+// package-level declarations they refer to must have been imported.
+func importGenerics(noders []*noder) {
+	pkg := noders[0].pkg
+	if pkg.Path == myimportpath || importedGenerics[pkg] {
+		return
+	}
+	importedGenerics[pkg] = true
+
+	for _, p := range noders {
+		base := syntax.NewFileBase(p.filename)
+		p.basemap = map[*syntax.PosBase]*src.PosBase{
+			base: src.NewFileBase(p.filename, p.filename),
+		}
+		p.err = make(chan syntax.Error)
+		go func(p *noder) {
+			defer close(p.err)
+			p.file, _ = syntax.Parse(base, strings.NewReader(p.src), p.error, p.pragma, syntax.CheckBranches) // errors are tracked via p.error
+		}(p)
+		for e := range p.err {
+			p.yyerrorpos(e.Pos, "%s", e.Msg)
+		}
+	}
+	if nsyntaxerrors != 0 {
+		errorexit()
+	}
+
+	// The constraint interfaces and pending methods of the
+	// package being compiled are not known to the imported
+	// files (and vice versa).
+	savedConstraints, savedMethods := constraintIfaces, pendingMethods
+	pendingMethods = nil
+	collectConstraints(noders)
+
+	for _, p := range noders {
+		var imports []importDef
+		i := 0
+		for _, decl := range p.file.DeclList {
+			switch decl := decl.(type) {
+			case *syntax.ImportDecl:
+				if i < len(p.importPkgs) && p.importPkgs[i] != nil {
+					imports = append(imports, p.importGeneric(decl, p.importPkgs[i])...)
+				}
+				i++
+			case *syntax.TypeDecl:
+				if decl.TParamList != nil || constraintIfaces[decl.Name.Value] {
+					p.genericTypeDecl(decl)
+				}
+			case *syntax.FuncDecl:
+				if decl.TParamList != nil || genericRecv(decl.Recv) != nil {
+					p.genericFuncDecl(decl)
+				}
+			}
+		}
+
+		// Universe scope, file scope, package scope.
+		for _, s := range builtinpkg.Syms {
+			if s1 := p.lookup(s.Name); s.Def != nil && s1.Def == nil {
+				p.imports = append(p.imports, importDef{s1, s.Def})
+			}
+		}
+		p.imports = append(p.imports, imports...)
+		for _, s := range pkg.Syms {
+			if types.IsExported(s.Name) && s.Def != nil {
+				p.imports = append(p.imports, importDef{lookup(s.Name), s.Def})
+			}
+		}
+	}
+	bindGenericMethods()
+	constraintIfaces, pendingMethods = savedConstraints, savedMethods
+
+	genericFiles = append(genericFiles, noders...)
+}
+
+// importGeneric returns the definitions of the package name or
+// dot-imported names declared by the import decl of the package
+// ipkg in a file read from export data. Packages the file's
+// generics do not refer to are unknown and ignored.
+func (p *noder) importGeneric(imp *syntax.ImportDecl, ipkg *types.Pkg) []importDef {
+	if ipkg.Name == "" {
+		return nil
+	}
+
+	pack := p.nod(imp, OPACK, nil, nil)
+	pack.Name.Pkg = ipkg
+
+	var my *types.Sym
+	if imp.LocalPkgName != nil {
+		my = p.name(imp.LocalPkgName)
+	} else {
+		my = p.lookup(ipkg.Name)
+	}
+	pack.Sym = my
+
+	var defs []importDef
+	switch my.Name {
+	case ".":
+		for _, s := range ipkg.Syms {
+			if types.IsExported(s.Name) && s.Def != nil {
+				defs = append(defs, importDef{lookup(s.Name), s.Def})
+			}
+		}
+	case "_":
+		// imported for its side effects only
+	default:
+		defs = append(defs, importDef{my, asTypesNode(pack)})
+	}
+	return defs
+}
+
+// genericRefs returns the package-level declarations, other than
+// generic ones, that the generic declarations in genericFiles refer
+// to. They are written to the export data so that importers can
+// instantiate the generics.
+func genericRefs() []*Node {
+	var refs []*Node
+	seen := make(map[*Node]bool)
+	for _, p := range genericFiles {
+		// Node each generic declaration as when it is declared;
+		// see genericTypeDecl.
+		p.refs = []*Node{}
+		for _, decl := range p.file.DeclList {
+			switch decl := decl.(type) {
+			case *syntax.TypeDecl:
+				g := genericOf(asNode(p.genericSym(decl.Name).Def))
+				if g == nil || g.typ != decl {
+					continue
+				}
+				p.inScope(tparamList(g.tparams), placeholderTypes(len(g.tparams)), func() {
+					p.constraints(g.tparams)
+					if g.constraint {
+						p.constraint(decl.Type)
+					} else {
+						p.typeExpr(decl.Type)
+					}
+				})
+			case *syntax.FuncDecl:
+				if inst := genericRecv(decl.Recv); inst != nil {
+					names := tparamNames(inst)
+					if names == nil {
+						continue
+					}
+					p.inScope(names, placeholderTypes(len(names)), func() {
+						p.funcInst(decl, nil, types.Types[TINTER])
+					})
+					continue
+				}
+				g := genericOf(asNode(p.genericSym(decl.Name).Def))
+				if g == nil || g.fun != decl {
+					continue
+				}
+				p.inScope(tparamList(g.tparams), placeholderTypes(len(g.tparams)), func() {
+					p.constraints(g.tparams)
+					p.funcInst(decl, nil, nil)
+				})
+			}
+		}
+
+		for _, n := range p.refs {
+			n = resolve(n)
+			if seen[n] || n.Sym == nil || n.Name == nil || n.Name.Generic() || n.Name.Curfn != nil {
+				continue
+			}
+			seen[n] = true
+			switch {
+			case n.Op == OTYPE,
+				n.Op == OLITERAL && asNode(n.Sym.Def) == n,
+				n.Op == ONAME && (n.Class() == PEXTERN || n.Class() == PFUNC) && asNode(n.Sym.Def) == n:
+				refs = append(refs, n)
+			}
+		}
+		p.refs = nil
+	}
+	return refs
+}
+
+// placeholderTypes returns n types to bind type parameters to
+// when noding a generic declaration without instantiating it.
+func placeholderTypes(n int) []*types.Type {
+	l := make([]*types.Type, n)
+	for i := range l {
+		l[i] = types.Types[TINTER]
+	}
+	return l
+}
+
+func tparamList(list []*syntax.Field) []*syntax.Name {
+	names := make([]*syntax.Name, len(list))
+	for i, f := range list {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// placeholders returns n type Nodes to bind type parameters to
+// when noding a generic declaration without instantiating it.
+func placeholders(n int) []*Node {
+	l := make([]*Node, n)
+	for i := range l {
+		l[i] = typenod(types.Types[TINTER])
+	}
+	return l
+}
+
+// withTParams calls f in a new block scope with the type parameter
+// names bound to the corresponding type Nodes in targs.
+func (p *noder) withTParams(names []*syntax.Name, targs []*Node, f func()) {
+	types.Markdcl()
+	for i, name := range names {
+		if name.Value == "_" {
+			continue
+		}
+		s := p.name(name)
+		types.Pushdcl(s)
+		s.Def = asTypesNode(targs[i])
+		s.Block = types.Block
+	}
+	f()
+	types.Popdcl()
+}
+
+// inScope calls f with the file scope of p re-established and the
+// type parameters names bound to targs. It is used to node (parts
+// of) generic declarations while type checking.
+func (p *noder) inScope(names []*syntax.Name, targs []*types.Type, f func()) {
+	lno := lineno
+	savedCurfn, savedContext := Curfn, dclcontext
+	Curfn, dclcontext = nil, PEXTERN
+
+	types.Markdcl()
+	for _, d := range p.imports {
+		types.Pushdcl(d.sym)
+		d.sym.Def = d.def
+	}
+	nodes := make([]*Node, len(targs))
+	for i, t := range targs {
+		nodes[i] = typenod(t)
+	}
+	p.withTParams(names, nodes, f)
+	types.Popdcl()
+
+	Curfn, dclcontext = savedCurfn, savedContext
+	lineno = lno
+}
+
+// evalType evaluates the type expression x in the scope of g,
+// with the type parameters bound to targs.
+func (g *genericDecl) evalType(x syntax.Expr, targs []*types.Type) *types.Type {
+	var n *Node
+	g.p.inScope(tparamList(g.tparams), targs, func() {
+		n = g.p.typeExpr(x)
+	})
+	n = typecheck(n, Etype)
+	return n.Type
+}
+
+// evalName resolves the (possibly qualified or instantiated) name x
+// in the scope of g, with the type parameters bound to targs, without
+// type-checking it.
+func (g *genericDecl) evalName(x syntax.Expr, targs []*types.Type) *Node {
+	var n *Node
+	g.p.inScope(tparamList(g.tparams), targs, func() {
+		n = g.p.typeExpr(x)
+	})
+	return n
+}
+
+// typecheckGeneric type-checks the instantiation n of the generic
+// declaration g: an OINDEX with the type arguments in n.Right (one)
+// or n.List (several). The result is the instantiated function or
+// type, type-checked in context top.
+func typecheckGeneric(n *Node, g *genericDecl, top int) *Node {
+	if g.constraint {
+		yyerror("cannot use %v outside a type constraint", g.n.Sym)
+		n.Type = nil
+		return n
+	}
+
+	targs := typeArgs(n)
+	if targs == nil {
+		n.Type = nil
+		return n
+	}
+	if len(targs) != len(g.tparams) {
+		if len(targs) < len(g.tparams) && g.fun != nil {
+			yyerror("cannot infer %s", g.tparams[len(targs)].Name.Value)
+		} else {
+			yyerror("got %d type arguments but %v has %d type parameters", len(targs), g.n.Sym, len(g.tparams))
+		}
+		n.Type = nil
+		return n
+	}
+
+	inst := g.instantiate(targs)
+	if inst == nil {
+		n.Type = nil
+		return n
+	}
+	return typecheck(inst, top)
+}
+
+// typeArgs type-checks the type arguments of the instantiation n.
+// It returns nil if there were errors.
+func typeArgs(n *Node) []*types.Type {
+	args := n.List.Slice()
+	if n.Right != nil {
+		args = []*Node{n.Right}
+	}
+	targs := make([]*types.Type, len(args))
+	for i, arg := range args {
+		arg = typecheck(arg, Etype)
+		args[i] = arg
+		if arg.Type == nil {
+			return nil
+		}
+		targs[i] = arg.Type
+	}
+	return targs
+}
+
+// typecheckGenericCall replaces the generic function operand of the
+// call n (a generic name or an instantiation with a partial type
+// argument list) with the instance inferred from the call arguments.
+// It returns false if there were errors.
+func typecheckGenericCall(n *Node, g *genericDecl) bool {
+	var targs []*types.Type
+	if n.Left.Op == OINDEX {
+		if targs = typeArgs(n.Left); targs == nil {
+			return false
+		}
+		if len(targs) > len(g.tparams) {
+			yyerror("got %d type arguments but %v has %d type parameters", len(targs), g.n.Sym, len(g.tparams))
+			return false
+		}
+	}
+
+	typecheckslice(n.List.Slice(), Erv)
+	targs = g.infer(n, targs)
+	if targs == nil {
+		return false
+	}
+	inst := g.instantiate(targs)
+	if inst == nil {
+		return false
+	}
+	n.Left = inst
+	return true
+}
+
+// infer infers the type arguments of the generic function g from
+// the arguments of the call n, given the explicitly provided
+// (leading) type arguments targs. It returns nil if there were
+// errors.
+func (g *genericDecl) infer(n *Node, explicit []*types.Type) []*types.Type {
+	u := &unifier{g: g, index: make(map[string]int), targs: make([]*types.Type, len(g.tparams))}
+	for i, f := range g.tparams {
+		u.index[f.Name.Value] = i
+	}
+	copy(u.targs, explicit)
+
+	// argument types
+	var args []*Node
+	var atypes []*types.Type
+	if n.List.Len() == 1 && n.List.First().Type != nil && n.List.First().Type.IsFuncArgStruct() {
+		// f(g()) where g has multiple results
+		for _, f := range n.List.First().Type.FieldSlice() {
+			args = append(args, nil)
+			atypes = append(atypes, f.Type)
+		}
+	} else {
+		for _, arg := range n.List.Slice() {
+			if arg.Type == nil {
+				return nil // error reported elsewhere
+			}
+			args = append(args, arg)
+			atypes = append(atypes, arg.Type)
+		}
+	}
+
+	// parameter types, matched to the arguments
+	var params []syntax.Expr
+	for _, f := range g.fun.Type.ParamList {
+		params = append(params, f.Type)
+	}
+	ptype := func(i int) syntax.Expr {
+		if len(params) > 0 {
+			if dots, ok := params[len(params)-1].(*syntax.DotsType); ok && i >= len(params)-1 {
+				if n.Isddd() {
+					return &syntax.SliceType{Elem: dots.Elem}
+				}
+				return dots.Elem
+			}
+		}
+		if i < len(params) {
+			return params[i]
+		}
+		return nil
+	}
+
+	// Typed arguments first, then untyped constants for
+	// parameters whose type is a type parameter on its own.
+	for i, t := range atypes {
+		if p := ptype(i); p != nil && !t.IsUntyped() {
+			u.unify(p, t)
+		}
+	}
+	for i, t := range atypes {
+		if p := ptype(i); p != nil && t.IsUntyped() && t.Etype != TNIL {
+			if name, ok := p.(*syntax.Name); ok {
+				if j, ok := u.index[name.Value]; ok && u.targs[j] == nil {
+					args[i] = defaultlit(args[i], nil)
+					u.targs[j] = args[i].Type
+					n.List.SetIndex(i, args[i])
+				}
+			}
+		}
+	}
+
+	// Core types of constraints with a single type term
+	// determine the remaining type arguments, if any
+	// (e.g., E in [S ~[]E, E any]).
+	for progress := true; progress; {
+		progress = false
+		for i, f := range g.tparams {
+			t := u.targs[i]
+			if t == nil {
+				continue
+			}
+			term, tilde := singleTerm(f.Type)
+			if term == nil {
+				continue
+			}
+			if tilde {
+				t = underlying(t)
+			}
+			if u.unify(term, t) {
+				progress = true
+			}
+		}
+	}
+
+	for i, t := range u.targs {
+		if t == nil {
+			yyerror("cannot infer %s", g.tparams[i].Name.Value)
+			return nil
+		}
+	}
+	return u.targs
+}
+
+// singleTerm returns the type term and whether it is a ~ term,
+// if the constraint c consists of exactly one type term.
+func singleTerm(c syntax.Expr) (term syntax.Expr, tilde bool) {
+	if it, ok := c.(*syntax.InterfaceType); ok {
+		if len(it.MethodList) != 1 || it.MethodList[0].Name != nil {
+			return nil, false
+		}
+		c = it.MethodList[0].Type
+	}
+	switch c := c.(type) {
+	case *syntax.Operation:
+		if c.Op == syntax.Tilde {
+			return c.X, true
+		}
+		return nil, false
+	case *syntax.Name, *syntax.SelectorExpr, *syntax.IndexExpr, *syntax.InterfaceType:
+		return nil, false // not a type literal
+	}
+	return c, false
+}
+
+// underlying returns the underlying type of t.
+func underlying(t *types.Type) *types.Type {
+	if t.Orig != nil {
+		return t.Orig
+	}
+	return t
+}
+
+// A unifier infers type arguments by matching parameter type
+// expressions of a generic function against argument types.
+type unifier struct {
+	g     *genericDecl
+	index map[string]int // type parameter indices by name
+	targs []*types.Type  // inferred type arguments; nil if unknown
+}
+
+// unify matches the type expression x against the type t and
+// records the type arguments it determines. It reports whether
+// any new type argument was inferred.
+func (u *unifier) unify(x syntax.Expr, t *types.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch x := x.(type) {
+	case *syntax.Name:
+		if i, ok := u.index[x.Value]; ok && u.targs[i] == nil {
+			u.targs[i] = t
+			return true
+		}
+	case *syntax.ParenExpr:
+		return u.unify(x.X, t)
+	case *syntax.Operation:
+		if x.Op == syntax.Mul && x.Y == nil && t.IsPtr() {
+			return u.unify(x.X, t.Elem())
+		}
+	case *syntax.SliceType:
+		if t.IsSlice() {
+			return u.unify(x.Elem, t.Elem())
+		}
+	case *syntax.ArrayType:
+		if t.IsArray() {
+			return u.unify(x.Elem, t.Elem())
+		}
+	case *syntax.MapType:
+		if t.IsMap() {
+			k := u.unify(x.Key, t.Key())
+			return u.unify(x.Value, t.Elem()) || k
+		}
+	case *syntax.ChanType:
+		if t.IsChan() {
+			return u.unify(x.Elem, t.Elem())
+		}
+	case *syntax.FuncType:
+		if t.Etype == TFUNC {
+			return u.unifyFields(x.ParamList, t.Params()) || u.unifyFields(x.ResultList, t.Results())
+		}
+	case *syntax.IndexExpr:
+		inst := genericInsts[t]
+		if base, ok := x.X.(*syntax.Name); ok && inst != nil && inst.g.n.Sym == u.g.p.genericSym(base) {
+			var list []syntax.Expr
+			if l, ok := x.Index.(*syntax.ListExpr); ok {
+				list = l.ElemList
+			} else {
+				list = []syntax.Expr{x.Index}
+			}
+			progress := false
+			for i, a := range list {
+				if i < len(inst.targs) && u.unify(a, inst.targs[i]) {
+					progress = true
+				}
+			}
+			return progress
+		}
+	}
+	return false
+}
+
+func (u *unifier) unifyFields(list []*syntax.Field, t *types.Type) bool {
+	fields := t.FieldSlice()
+	if len(list) != len(fields) {
+		return false
+	}
+	progress := false
+	for i, f := range list {
+		if u.unify(f.Type, fields[i].Type) {
+			progress = true
+		}
+	}
+	return progress
+}
+
+// instantiate returns the instance of g for the type arguments
+// targs, creating it if necessary. It returns nil if targs do not
+// satisfy the constraints of g.
+func (g *genericDecl) instantiate(targs []*types.Type) *Node {
+	for _, inst := range g.insts {
+		if identicalList(inst.targs, targs) {
+			return inst.n
+		}
+	}
+
+	for _, t := range targs {
+		if t.Broke() {
+			return nil
+		}
+	}
+	for i, f := range g.tparams {
+		if !g.satisfies(targs[i], f.Type, targs) {
+			return nil
+		}
+	}
+	if len(g.insts) >= maxInsts {
+		yyerror("instantiation cycle: too many instances of %v", g.n.Sym)
+		return nil
+	}
+
+	inst := &genericInst{g: g, targs: targs}
+	g.insts = append(g.insts, inst)
+	sym := instSym(g.n.Sym, targs)
+	if n := asNode(sym.Def); n != nil {
+		// Declared by the export data of an imported package,
+		// which compiled the instance.
+		n = resolve(n)
+		inst.n = n
+		inst.imported = true
+		if n.Op == OTYPE && n.Type != nil {
+			genericInsts[n.Type] = inst
+		}
+		return n
+	}
+
+	// Instances are top-level declarations.
+	savedCurfn := Curfn
+	Curfn = nil
+	deferred := defercalc == 0
+	if deferred {
+		defercheckwidth()
+	}
+
+	if g.fun != nil {
+		var fn *Node
+		g.p.inScope(tparamList(g.tparams), targs, func() {
+			fn = g.p.funcInst(g.fun, sym, nil)
+		})
+		inst.n = fn.Func.Nname
+		if g.p.pkg != nil {
+			foreignInsts[inst.n] = true
+		}
+		fn.Func.SetDupok(true)
+		fn = typecheck(fn, Etop)
+		xtop = append(xtop, fn)
+	} else {
+		var n *Node
+		g.p.inScope(tparamList(g.tparams), targs, func() {
+			n = newnamel(g.p.makeXPos(g.typ.Name.Pos()), sym)
+			n.Op = OTYPE
+			n.SetClass(PEXTERN)
+			n.Name.Param.Ntype = g.p.typeExpr(g.typ.Type)
+			n.Name.Param.Pragma = g.typ.Pragma
+		})
+		sym.Def = asTypesNode(n)
+		sym.Block = 1
+		inst.n = n
+		if g.p.pkg != nil {
+			foreignInsts[n] = true
+		}
+		n = typecheck(n, Etype)
+		if n.Type != nil {
+			genericInsts[n.Type] = inst
+
+			// instantiate the methods
+			for _, m := range g.methods {
+				recv := n.Type
+				if _, ok := m.fun.Recv.Type.(*syntax.Operation); ok {
+					recv = types.NewPtr(recv)
+				}
+				var fn *Node
+				m.p.inScope(tparamNames(genericRecv(m.fun.Recv)), targs, func() {
+					fn = m.p.funcInst(m.fun, nil, recv)
+				})
+				if g.p.pkg != nil {
+					foreignInsts[fn.Func.Nname] = true
+				}
+				fn.Func.SetDupok(true)
+				fn = typecheck(fn, Etop)
+				xtop = append(xtop, fn)
+			}
+		}
+	}
+
+	if deferred {
+		resumecheckwidth()
+	}
+	Curfn = savedCurfn
+	return inst.n
+}
+
+func identicalList(x, y []*types.Type) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !eqtype(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// instDisplayName returns the name of an instance as shown to users
+// and hashed for type switches, with the packages in its type
+// arguments spelled by the last element of their path:
+// List[example.com/x.T] is shown as List[x.T].
+func instDisplayName(name string) string {
+	i := strings.IndexByte(name, '[')
+	if i < 0 {
+		return name
+	}
+	name = strings.Replace(name, `"".`, localpkg.Name+".", -1)
+	if !strings.Contains(name[i:], "/") {
+		return name
+	}
+	var buf bytes.Buffer
+	buf.WriteString(name[:i])
+	start := -1 // start of the current path, or -1
+	for j := i; j < len(name); j++ {
+		switch c := name[j]; {
+		case strings.IndexByte("[](){},;* ", c) >= 0:
+			start = -1
+			buf.WriteByte(c)
+		case c == '/' && start >= 0:
+			buf.Truncate(start)
+		default:
+			if start < 0 {
+				start = buf.Len()
+			}
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// instantiatedHere reports whether t is an instance of a generic
+// type compiled by this package, which then defines its methods and
+// type descriptor even if the generic belongs to another package.
+func instantiatedHere(t *types.Type) bool {
+	inst := genericInsts[t]
+	return inst != nil && !inst.imported
+}
+
+// instSym returns the symbol naming the instance of the generic
+// declaration s with type arguments targs, such as List[int], in
+// the package of s. The type arguments are spelled as in linker
+// symbols, so the name is the same in every package that uses the
+// instance. Types of the package being compiled have the prefix "",
+// which the importer and the linker expand, unless its import path
+// is known.
+func instSym(s *types.Sym, targs []*types.Type) *types.Sym {
+	var buf bytes.Buffer
+	buf.WriteString(s.Name)
+	buf.WriteByte('[')
+	for i, t := range targs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(t.ShortString())
+	}
+	buf.WriteByte(']')
+
+	name := buf.String()
+	if myimportpath != "" {
+		name = strings.Replace(name, `"".`, objabi.PathToPrefix(myimportpath)+".", -1)
+	}
+	return s.Pkg.Lookup(name)
+}
+
+// funcInst nodes the generic function or method fun as an ordinary
+// function named sym. For methods, recv is the receiver type and
+// sym is ignored. The type parameters must be bound by the caller.
+func (p *noder) funcInst(fun *syntax.FuncDecl, sym *types.Sym, recv *types.Type) *Node {
+	t := p.signature(nil, fun.Type)
+	f := p.nod(fun, ODCLFUNC, nil, nil)
+
+	if recv != nil {
+		var rname *types.Sym
+		if fun.Recv.Name != nil {
+			rname = p.name(fun.Recv.Name)
+		}
+		t.Left = p.nodSym(fun.Recv, ODCLFIELD, typenod(recv), rname)
+		f.Func.Shortname = p.name(fun.Name)
+		sym = nblank.Sym // filled in by typecheckfunc
+	} else if sym == nil {
+		sym = nblank.Sym // not instantiated; see genericFuncDecl
+	}
+
+	f.Func.Nname = p.setlineno(fun.Name, newfuncname(sym))
+	f.Func.Nname.Name.Defn = f
+	f.Func.Nname.Name.Param.Ntype = t
+	f.Func.Nname.SetClass(PFUNC)
+	f.Func.Pragma = fun.Pragma
+	f.SetNoescape(fun.Pragma&Noescape != 0)
+
+	p.funcBody(f, fun.Body)
+	return f
+}
+
+// satisfies reports whether the type argument t satisfies the
+// constraint c of a type parameter of g, whose type parameters
+// are bound to targs. If not, it reports an error.
+func (g *genericDecl) satisfies(t *types.Type, c syntax.Expr, targs []*types.Type) bool {
+	if why := g.check(t, c, targs); why != "" {
+		yyerror("%v does not satisfy %s%s", t, syntax.String(c), why)
+		return false
+	}
+	return true
+}
+
+// check is the implementation of satisfies. It returns the
+// reason why t does not satisfy c, or "" if it does.
+func (g *genericDecl) check(t *types.Type, c syntax.Expr, targs []*types.Type) string {
+	switch c := c.(type) {
+	case *syntax.InterfaceType:
+		var methods []*syntax.Field
+		for _, m := range c.MethodList {
+			if m.Name != nil {
+				methods = append(methods, m)
+				continue
+			}
+			if why := g.check(t, m.Type, targs); why != "" {
+				return why
+			}
+		}
+		if len(methods) == 0 {
+			return ""
+		}
+		return implementsWhy(t, g.evalType(&syntax.InterfaceType{MethodList: methods}, targs))
+
+	case *syntax.Operation:
+		if !g.inTermSet(t, c, targs) {
+			return fmt.Sprintf(" (%v missing in %s)", t, syntax.String(c))
+		}
+		return ""
+	}
+
+	// (possibly qualified or instantiated) name
+	n := g.evalName(c, targs)
+	if cg := genericOf(n); cg != nil {
+		switch {
+		case cg.comparable:
+			if !IsComparable(t) {
+				return " (incomparable type)"
+			}
+			return ""
+		case cg.constraint:
+			var cargs []*types.Type
+			if n.Op == OINDEX {
+				if cargs = typeArgs(n); cargs == nil || len(cargs) != len(cg.tparams) {
+					return " (invalid constraint)"
+				}
+			}
+			return cg.check(t, cg.typ.Type, cargs)
+		}
+	}
+	ct := typecheck(n, Etype).Type
+	switch {
+	case ct == nil:
+		return " (invalid constraint)"
+	case ct.IsInterface():
+		return implementsWhy(t, ct)
+	case !eqtype(t, ct):
+		return fmt.Sprintf(" (%v missing in %s)", t, syntax.String(c))
+	}
+	return ""
+}
+
+// inTermSet reports whether t is in the type set described by
+// the union or ~ term x.
+func (g *genericDecl) inTermSet(t *types.Type, x syntax.Expr, targs []*types.Type) bool {
+	if op, ok := x.(*syntax.Operation); ok {
+		switch op.Op {
+		case syntax.Or:
+			return g.inTermSet(t, op.X, targs) || g.inTermSet(t, op.Y, targs)
+		case syntax.Tilde:
+			u := g.evalType(op.X, targs)
+			return u != nil && eqtype(underlying(t), underlying(u))
+		}
+	}
+	return g.check(t, x, targs) == ""
+}
+
+// implementsWhy returns the reason why t does not implement
+// the interface iface, or "" if it does.
+func implementsWhy(t, iface *types.Type) string {
+	var missing, have *types.Field
+	var ptr int
+	if implements(t, iface, &missing, &have, &ptr) {
+		return ""
+	}
+	switch {
+	case missing == nil:
+		return ""
+	case have != nil && have.Sym == missing.Sym:
+		return fmt.Sprintf(" (wrong type for %v method)\n\t\thave %v%0S\n\t\twant %v%0S", missing.Sym, have.Sym, have.Type, missing.Sym, missing.Type)
+	case ptr != 0:
+		return fmt.Sprintf(" (%v method has pointer receiver)", missing.Sym)
+	}
+	return fmt.Sprintf(" (missing %v method)", missing.Sym)
+}
+
+// declareComparable declares the predeclared comparable constraint.
+func declareComparable() {
+	s := builtinpkg.Lookup("comparable")
+	n := newnamel(src.NoXPos, s)
+	n.Op = OTYPE
+	n.Name.SetGeneric(true)
+	generics[n] = &genericDecl{n: n, constraint: true, comparable: true}
+	s.Def = asTypesNode(n)
+}
+
+// typecheckGenericName reports the use of the generic
+// declaration g without instantiation at lno.
+func typecheckGenericName(g *genericDecl, lno src.XPos) {
+	if g.constraint {
+		yyerrorl(lno, "cannot use %v outside a type constraint: interface contains type constraints", g.n.Sym)
+		return
+	}
+	yyerrorl(lno, "cannot use generic %s %v without instantiation", g.kind(), g.n.Sym)
+}
+
+// genericCallee returns the generic function called through the
+// call operand fn if (some of) its type arguments must be inferred
+// from the call arguments, or nil.
+func genericCallee(fn *Node) *genericDecl {
+	if fn.Op == OINDEX {
+		g := genericOf(fn.Left)
+		if g == nil || g.fun == nil {
+			return nil
+		}
+		n := fn.List.Len()
+		if fn.Right != nil {
+			n = 1
+		}
+		if n >= len(g.tparams) {
+			return nil
+		}
+		return g
+	}
+	if g := genericOf(fn); g != nil && g.fun != nil {
+		return g
+	}
+	return nil
+}
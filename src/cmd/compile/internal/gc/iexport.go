@@ -269,6 +269,17 @@ func iexport(out *bufio.Writer) {
 		p.pushDecl(n)
 	}
 
+	// Importers instantiating our generic declarations (and those
+	// we imported) need the declarations they refer to.
+	for _, n := range genericRefs() {
+		p.pushDecl(n)
+	}
+	for _, gp := range genericFiles {
+		if gp.pkg != nil {
+			p.allPkgs[gp.pkg] = true
+		}
+	}
+
 	// Loop until no more work. We use a queue because while
 	// writing out inline bodies, we may discover additional
 	// declarations that are needed.
@@ -281,6 +292,7 @@ func iexport(out *bufio.Writer) {
 	w := p.newWriter()
 	w.writeIndex(p.declIndex, true)
 	w.writeIndex(p.inlineIndex, false)
+	w.writeGenerics()
 	w.flush()
 
 	// Assemble header.
@@ -536,6 +548,45 @@ func (w *exportWriter) pos(pos src.XPos) {
 	w.prevLine = line
 }
 
+// writeGenerics writes out the source of the files declaring
+// generics, grouped by package. Imported packages are listed in
+// import order, so that dependencies precede their importers, and
+// the local package last.
+func (w *exportWriter) writeGenerics() {
+	var pkgs []*types.Pkg
+	files := map[*types.Pkg][]*noder{}
+	for _, p := range genericFiles {
+		pkg := p.pkg
+		if pkg == nil {
+			pkg = localpkg
+		}
+		if files[pkg] == nil && pkg != localpkg {
+			pkgs = append(pkgs, pkg)
+		}
+		files[pkg] = append(files[pkg], p)
+	}
+	if files[localpkg] != nil {
+		pkgs = append(pkgs, localpkg)
+	}
+
+	w.uint64(uint64(len(genericFiles)))
+	for _, pkg := range pkgs {
+		for _, p := range files[pkg] {
+			w.pkg(pkg)
+			w.string(p.filename)
+			w.string(p.src)
+			w.uint64(uint64(len(p.importPkgs)))
+			for _, ipkg := range p.importPkgs {
+				path := ""
+				if ipkg != nil {
+					path = ipkg.Path
+				}
+				w.string(path)
+			}
+		}
+	}
+}
+
 func (w *exportWriter) pkg(pkg *types.Pkg) {
 	// Ensure any referenced packages are declared in the main index.
 	w.p.allPkgs[pkg] = true
@@ -946,8 +997,9 @@ func (w *exportWriter) funcExt(n *Node) {
 		}
 	}
 
-	// Inline body.
-	if n.Func.Inl != nil {
+	// Inline body. Those of foreign instances refer to
+	// unexported names of another package.
+	if n.Func.Inl != nil && !foreignInsts[n] {
 		w.uint64(1 + uint64(n.Func.Inl.Cost))
 		if n.Func.ExportInline() {
 			w.p.doInline(n)
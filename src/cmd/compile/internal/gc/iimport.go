@@ -158,7 +158,7 @@ func iimport(pkg *types.Pkg, in *bio.Reader) {
 		}
 
 		for nSyms := ir.uint64(); nSyms > 0; nSyms-- {
-			s := pkg.Lookup(p.stringAt(ir.uint64()))
+			s := pkg.Lookup(p.nameAt(ir.uint64()))
 			off := ir.uint64()
 
 			if _, ok := declImporter[s]; ok {
@@ -180,7 +180,7 @@ func iimport(pkg *types.Pkg, in *bio.Reader) {
 		pkg := p.pkgAt(ir.uint64())
 
 		for nSyms := ir.uint64(); nSyms > 0; nSyms-- {
-			s := pkg.Lookup(p.stringAt(ir.uint64()))
+			s := pkg.Lookup(p.nameAt(ir.uint64()))
 			off := ir.uint64()
 
 			if _, ok := inlineImporter[s]; ok {
@@ -189,6 +189,30 @@ func iimport(pkg *types.Pkg, in *bio.Reader) {
 			inlineImporter[s] = iimporterAndOffset{p, off}
 		}
 	}
+
+	// Source of generic declarations, grouped by package.
+	var noders []*noder
+	for nFiles := ir.uint64(); nFiles > 0; nFiles-- {
+		gp := &noder{pkg: p.pkgAt(ir.uint64())}
+		gp.filename = p.stringAt(ir.uint64())
+		gp.src = p.stringAt(ir.uint64())
+		for nImports := ir.uint64(); nImports > 0; nImports-- {
+			var ipkg *types.Pkg
+			if path := p.stringAt(ir.uint64()); path != "" {
+				ipkg = types.NewPkg(path, "")
+			}
+			gp.importPkgs = append(gp.importPkgs, ipkg)
+		}
+
+		if len(noders) > 0 && noders[0].pkg != gp.pkg {
+			importGenerics(noders)
+			noders = nil
+		}
+		noders = append(noders, gp)
+	}
+	if len(noders) > 0 {
+		importGenerics(noders)
+	}
 }
 
 type iimporter struct {
@@ -202,6 +226,18 @@ type iimporter struct {
 	declData   string
 }
 
+// nameAt returns the name of a package-level declaration at off.
+// The names of instances of generic declarations spell the types of
+// the exporting package with the prefix "" if it was compiled
+// without an import path; see instSym.
+func (p *iimporter) nameAt(off uint64) string {
+	name := p.stringAt(off)
+	if strings.Contains(name, `"".`) {
+		name = strings.Replace(name, `"".`, p.ipkg.Prefix+".", -1)
+	}
+	return name
+}
+
 func (p *iimporter) stringAt(off uint64) string {
 	var x [binary.MaxVarintLen64]byte
 	n := copy(x[:], p.stringData[off:])
@@ -438,7 +474,7 @@ func (r *importReader) ident() *types.Sym {
 }
 
 func (r *importReader) qualifiedIdent() *types.Sym {
-	name := r.string()
+	name := r.p.nameAt(r.uint64())
 	pkg := r.pkg()
 	return pkg.Lookup(name)
 }
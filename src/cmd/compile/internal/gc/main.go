@@ -50,6 +50,7 @@ var (
 	Debug_typecheckinl int
 	Debug_gendwarfinl  int
 	Debug_softfloat    int
+	Debug_libfuzzer    int
 )
 
 // Debug arguments.
@@ -79,6 +80,7 @@ var debugtab = []struct {
 	{"typecheckinl", "eager typechecking of inline function bodies", &Debug_typecheckinl},
 	{"dwarfinl", "print information about DWARF inlined function creation", &Debug_gendwarfinl},
 	{"softfloat", "force compiler to emit soft-float code", &Debug_softfloat},
+	{"libfuzzer", "coverage instrumentation for fuzzing", &Debug_libfuzzer},
 }
 
 const debugHelpHeader = `usage: -d arg[,arg]* and arg is <key>[=<value>]
@@ -210,6 +212,7 @@ func Main(archInit func(*Arch)) {
 	objabi.Flagcount("i", "debug line number stack", &Debug['i'])
 	objabi.Flagfn1("importmap", "add `definition` of the form source=actual to import map", addImportMap)
 	objabi.Flagfn1("importcfg", "read import configuration from `file`", readImportCfg)
+	objabi.Flagfn1("embedcfg", "read go:embed configuration from `file`", readEmbedCfg)
 	flag.StringVar(&flag_installsuffix, "installsuffix", "", "set pkg directory `suffix`")
 	objabi.Flagcount("j", "debug runtime-initialized variables", &Debug['j'])
 	objabi.Flagcount("l", "disable inlining", &Debug['l'])
@@ -505,6 +508,16 @@ func Main(archInit func(*Arch)) {
 			xtop[i] = typecheck(n, Etop)
 		}
 	}
+	// Instances of generic types named only in the types of
+	// variables must be created now, so that their methods are
+	// type checked and compiled with the other functions.
+	if len(generics) > 0 {
+		for i, n := range externdcl {
+			if n.Op == ONAME {
+				externdcl[i] = typecheck(n, Erv)
+			}
+		}
+	}
 	resumecheckwidth()
 
 	// Phase 3: Type check function bodies.
@@ -668,6 +681,9 @@ func Main(archInit func(*Arch)) {
 			externdcl[i] = typecheck(externdcl[i], Erv)
 		}
 	}
+	for _, v := range embedlist {
+		initEmbed(v)
+	}
 
 	if nerrors+nsavederrors != 0 {
 		errorexit()
@@ -6,7 +6,7 @@ package gc
 
 import (
 	"fmt"
-	"os"
+	"io/ioutil"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -37,26 +37,38 @@ func parseFiles(filenames []string) uint {
 			defer close(p.err)
 			base := syntax.NewFileBase(filename)
 
-			f, err := os.Open(filename)
+			src, err := ioutil.ReadFile(filename)
 			if err != nil {
 				p.error(syntax.Error{Pos: syntax.MakePos(base, 0, 0), Msg: err.Error()})
 				return
 			}
-			defer f.Close()
+			p.filename = absFilename(filename)
+			p.src = string(src)
 
-			p.file, _ = syntax.Parse(base, f, p.error, p.pragma, syntax.CheckBranches) // errors are tracked via p.error
+			p.file, _ = syntax.Parse(base, strings.NewReader(p.src), p.error, p.pragma, syntax.CheckBranches) // errors are tracked via p.error
 		}(filename)
 	}
 
-	var lines uint
+	// Wait for all files to be parsed before noding any of them:
+	// constraint interfaces may be declared in any file of the
+	// package and must be known before noding.
 	for _, p := range noders {
 		for e := range p.err {
 			p.yyerrorpos(e.Pos, "%s", e.Msg)
 		}
+	}
+	collectConstraints(noders)
 
+	var lines uint
+	for _, p := range noders {
 		p.node()
 		lines += p.file.Lines
-		p.file = nil // release memory
+		if p.generic {
+			// keep the source for export; see genericFiles
+			genericFiles = append(genericFiles, p)
+		} else {
+			p.file, p.src = nil, "" // release memory
+		}
 
 		if nsyntaxerrors != 0 {
 			errorexit()
@@ -64,6 +76,7 @@ func parseFiles(filenames []string) uint {
 		// Always run testdclstack here, even when debug_dclstack is not set, as a sanity measure.
 		testdclstack()
 	}
+	bindGenericMethods()
 
 	localpkg.Height = myheight
 
@@ -136,6 +149,30 @@ type noder struct {
 	scopeVars []int
 
 	lastCloseScopePos syntax.Pos
+
+	// generic is set if the file declares generic functions or
+	// types; imports then records the file's imported package
+	// names for use when instantiating them.
+	generic bool
+	imports []importDef
+
+	// For files declaring generics, filename and src are the
+	// absolute file name and the source text, and importPkgs
+	// lists the packages imported by the file's import decls
+	// (nil if unknown). They are written to the export data.
+	// pkg is the package of a file read from export data, or
+	// nil for the package being compiled.
+	filename   string
+	src        string
+	importPkgs []*types.Pkg
+	pkg        *types.Pkg
+
+	// refs, if non-nil, collects the definitions of the names
+	// resolved while noding; see genericRefs.
+	refs []*Node
+
+	embeds        []embedPragma // pending //go:embed directives, in source order
+	importedEmbed bool          // file imports "embed"
 }
 
 func (p *noder) funcBody(fn *Node, block *syntax.BlockStmt) {
@@ -237,6 +274,9 @@ func (p *noder) node() {
 	mkpackage(p.file.PkgName.Value)
 
 	xtop = append(xtop, p.decls(p.file.DeclList)...)
+	for _, e := range p.embeds {
+		p.yyerrorpos(e.pos, "misplaced go:embed directive")
+	}
 
 	for _, n := range p.linknames {
 		if imported_unsafe {
@@ -248,6 +288,9 @@ func (p *noder) node() {
 
 	pragcgobuf = append(pragcgobuf, p.pragcgobuf...)
 	lineno = src.NoXPos
+	if p.generic {
+		p.saveImports()
+	}
 	clearImports()
 }
 
@@ -256,6 +299,9 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 
 	for _, decl := range decls {
 		p.lineno(decl)
+		if _, ok := decl.(*syntax.VarDecl); !ok {
+			p.misplacedEmbeds(decl.Pos())
+		}
 		switch decl := decl.(type) {
 		case *syntax.ImportDecl:
 			p.importDecl(decl)
@@ -267,10 +313,14 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 			l = append(l, p.constDecl(decl, &cs)...)
 
 		case *syntax.TypeDecl:
-			l = append(l, p.typeDecl(decl))
+			if n := p.typeDecl(decl); n != nil {
+				l = append(l, n)
+			}
 
 		case *syntax.FuncDecl:
-			l = append(l, p.funcDecl(decl))
+			if n := p.funcDecl(decl); n != nil {
+				l = append(l, n)
+			}
 
 		default:
 			panic("unhandled Decl")
@@ -283,6 +333,7 @@ func (p *noder) decls(decls []syntax.Decl) (l []*Node) {
 func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	val := p.basicLit(imp.Path)
 	ipkg := importfile(&val)
+	p.importPkgs = append(p.importPkgs, ipkg)
 
 	if ipkg == nil {
 		if nerrors == 0 {
@@ -292,6 +343,9 @@ func (p *noder) importDecl(imp *syntax.ImportDecl) {
 	}
 
 	ipkg.Direct = true
+	if ipkg.Path == "embed" {
+		p.importedEmbed = true
+	}
 
 	var my *types.Sym
 	if imp.LocalPkgName != nil {
@@ -330,6 +384,7 @@ func (p *noder) varDecl(decl *syntax.VarDecl) []*Node {
 	if decl.Values != nil {
 		exprs = p.exprList(decl.Values)
 	}
+	p.varEmbed(decl, names, typ, exprs)
 
 	p.lineno(decl)
 	return variter(names, typ, exprs)
@@ -397,6 +452,11 @@ func (p *noder) constDecl(decl *syntax.ConstDecl, cs *constState) []*Node {
 }
 
 func (p *noder) typeDecl(decl *syntax.TypeDecl) *Node {
+	if decl.TParamList != nil || dclcontext == PEXTERN && constraintIfaces[decl.Name.Value] {
+		p.genericTypeDecl(decl)
+		return nil
+	}
+
 	n := p.declName(decl.Name)
 	n.Op = OTYPE
 	declare(n, dclcontext)
@@ -430,6 +490,11 @@ func (p *noder) declName(name *syntax.Name) *Node {
 }
 
 func (p *noder) funcDecl(fun *syntax.FuncDecl) *Node {
+	if fun.TParamList != nil || genericRecv(fun.Recv) != nil {
+		p.genericFuncDecl(fun)
+		return nil
+	}
+
 	name := p.name(fun.Name)
 	t := p.signature(fun.Recv, fun.Type)
 	f := p.nod(fun, ODCLFUNC, nil, nil)
@@ -578,10 +643,18 @@ func (p *noder) expr(expr syntax.Expr) *Node {
 		obj := p.expr(expr.X)
 		if obj.Op == OPACK {
 			obj.Name.SetUsed(true)
-			return oldname(restrictlookup(expr.Sel.Value, obj.Name.Pkg))
+			s := restrictlookup(expr.Sel.Value, obj.Name.Pkg)
+			p.ref(s)
+			return oldname(s)
 		}
 		return p.setlineno(expr, nodSym(OXDOT, obj, p.name(expr.Sel)))
 	case *syntax.IndexExpr:
+		if list, ok := expr.Index.(*syntax.ListExpr); ok {
+			// type argument list; see typecheckGeneric
+			n := p.nod(expr, OINDEX, p.expr(expr.X), nil)
+			n.List.Set(p.exprs(list.ElemList))
+			return n
+		}
 		return p.nod(expr, OINDEX, p.expr(expr.X), p.expr(expr.Index))
 	case *syntax.SliceExpr:
 		op := OSLICE
@@ -789,7 +862,16 @@ func (p *noder) interfaceType(expr *syntax.InterfaceType) *Node {
 		p.lineno(method)
 		var n *Node
 		if method.Name == nil {
-			n = p.nodSym(method, ODCLFIELD, oldname(p.packname(method.Type)), nil)
+			switch typ := method.Type.(type) {
+			case *syntax.Name, *syntax.SelectorExpr:
+				n = p.nodSym(method, ODCLFIELD, oldname(p.packname(typ)), nil)
+			case *syntax.IndexExpr:
+				// instantiated generic interface
+				n = p.nodSym(method, ODCLFIELD, p.typeExpr(typ), nil)
+			default:
+				p.yyerrorpos(method.Pos(), "cannot use type element %s outside a type constraint", syntax.String(typ))
+				continue
+			}
 		} else {
 			mname := p.name(method.Name)
 			sig := p.typeExpr(method.Type)
@@ -812,6 +894,7 @@ func (p *noder) packname(expr syntax.Expr) *types.Sym {
 		if n := oldname(name); n.Name != nil && n.Name.Pack != nil {
 			n.Name.Pack.Name.SetUsed(true)
 		}
+		p.ref(name)
 		return name
 	case *syntax.SelectorExpr:
 		name := p.name(expr.X.(*syntax.Name))
@@ -823,7 +906,9 @@ func (p *noder) packname(expr syntax.Expr) *types.Sym {
 			asNode(name.Def).Name.SetUsed(true)
 			pkg = asNode(name.Def).Name.Pkg
 		}
-		return restrictlookup(expr.Sel.Value, pkg)
+		s := restrictlookup(expr.Sel.Value, pkg)
+		p.ref(s)
+		return s
 	}
 	panic(fmt.Sprintf("unexpected packname: %#v", expr))
 }
@@ -837,8 +922,16 @@ func (p *noder) embedded(typ syntax.Expr) *Node {
 		typ = op.X
 	}
 
-	sym := p.packname(typ)
-	n := p.nodSym(typ, ODCLFIELD, oldname(sym), lookup(sym.Name))
+	var n *Node
+	if inst, ok := typ.(*syntax.IndexExpr); ok {
+		// instantiated generic type; the field
+		// is named after the generic type
+		sym := p.packname(inst.X)
+		n = p.nodSym(typ, ODCLFIELD, p.typeExpr(inst), p.lookup(sym.Name))
+	} else {
+		sym := p.packname(typ)
+		n = p.nodSym(typ, ODCLFIELD, oldname(sym), p.lookup(sym.Name))
+	}
 	n.SetEmbedded(true)
 
 	if isStar {
@@ -1319,12 +1412,31 @@ func (p *noder) basicLit(lit *syntax.BasicLit) Val {
 }
 
 func (p *noder) name(name *syntax.Name) *types.Sym {
-	return lookup(name.Value)
+	return p.lookup(name.Value)
+}
+
+// lookup returns the symbol for name. In files read from export
+// data, unexported names belong to the imported package, while
+// exported names use the local package, as in iimport.go.
+func (p *noder) lookup(name string) *types.Sym {
+	if p.pkg != nil && !types.IsExported(name) {
+		return p.pkg.Lookup(name)
+	}
+	return lookup(name)
+}
+
+// ref records the definition of s if p collects references.
+func (p *noder) ref(s *types.Sym) {
+	if p.refs != nil && s.Def != nil {
+		p.refs = append(p.refs, asNode(s.Def))
+	}
 }
 
 func (p *noder) mkname(name *syntax.Name) *Node {
 	// TODO(mdempsky): Set line number?
-	return mkname(p.name(name))
+	s := p.name(name)
+	p.ref(s)
+	return mkname(s)
 }
 
 func (p *noder) newname(name *syntax.Name) *Node {
@@ -1402,6 +1514,9 @@ func (p *noder) pragma(pos syntax.Pos, text string) syntax.Pragma {
 		// line directives are handled by syntax package
 		panic("unreachable")
 
+	case text == "go:embed", strings.HasPrefix(text, "go:embed "), strings.HasPrefix(text, "go:embed\t"):
+		p.pragmaEmbed(pos, text[len("go:embed"):])
+
 	case strings.HasPrefix(text, "go:linkname "):
 		f := strings.Fields(text)
 		if len(f) != 3 {
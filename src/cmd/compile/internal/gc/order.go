@@ -273,11 +273,36 @@ func (o *Order) stmtList(l Nodes) {
 func orderBlock(n *Nodes) {
 	var order Order
 	mark := order.markTemp()
+	order.edge()
 	order.stmtList(*n)
 	order.cleanTemp(mark)
 	n.Set(order.out)
 }
 
+// edge inserts coverage instrumentation for fuzzing.
+func (o *Order) edge() {
+	if n := libfuzzerCounterIncr(); n != nil {
+		o.out = append(o.out, n)
+	}
+}
+
+// libfuzzerCounterIncr returns a statement incrementing a new coverage
+// counter, or nil if coverage instrumentation is disabled.
+// The linker places the counters where internal/fuzz can find them.
+func libfuzzerCounterIncr() *Node {
+	if Debug_libfuzzer == 0 {
+		return nil
+	}
+
+	counter := staticname(types.Types[TUINT8])
+	counter.Name.SetLibfuzzerExtraCounter(true)
+
+	// counter += 1
+	incr := nod(OASOP, counter, nodintconst(1))
+	incr.SetSubOp(OADD)
+	return typecheck(incr, Etop)
+}
+
 // exprInPlace orders the side effects in *np and
 // leaves them as the init list of the final *np.
 // The result of exprInPlace MUST be assigned back to n, e.g.
@@ -1091,6 +1116,12 @@ func (o *Order) expr(n, lhs *Node) *Node {
 		// Leave them on the stack so that they can be killed in the outer
 		// context in case the short circuit is taken.
 		n.Right = addinit(n.Right, o.cleanTempNoPop(mark))
+
+		// Count how often the second operand is evaluated, so that
+		// the fuzzer sees progress through a chain of conditions.
+		if incr := libfuzzerCounterIncr(); incr != nil {
+			n.Right = addinit(n.Right, []*Node{incr})
+		}
 		n.Right = o.exprInPlace(n.Right)
 
 	case OCALLFUNC,
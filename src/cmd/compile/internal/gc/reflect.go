@@ -1124,10 +1124,15 @@ func dtypesym(t *types.Type) *obj.LSym {
 	if tbase.Sym == nil {
 		dupok = obj.DUPOK
 	}
+	// Instances of generic types are defined by each package
+	// that instantiates them.
+	if instantiatedHere(tbase) {
+		dupok = obj.DUPOK
+	}
 
 	if myimportpath != "runtime" || (tbase != types.Types[tbase.Etype] && tbase != types.Bytetype && tbase != types.Runetype && tbase != types.Errortype) { // int, float, etc
 		// named types from other files are defined only by those files
-		if tbase.Sym != nil && tbase.Sym.Pkg != localpkg {
+		if tbase.Sym != nil && tbase.Sym.Pkg != localpkg && dupok == 0 {
 			return lsym
 		}
 		// TODO(mdempsky): Investigate whether this can happen.
@@ -1621,9 +1621,10 @@ func genwrapper(rcvr *types.Type, method *types.Field, newnam *types.Sym) {
 		fmt.Printf("genwrapper rcvrtype=%v method=%v newnam=%v\n", rcvr, method, newnam)
 	}
 
-	// Only generate (*T).M wrappers for T.M in T's own package.
+	// Only generate (*T).M wrappers for T.M in T's own package,
+	// or in the packages instantiating T if it is an instance.
 	if rcvr.IsPtr() && rcvr.Elem() == method.Type.Recv().Type &&
-		rcvr.Elem().Sym != nil && rcvr.Elem().Sym.Pkg != localpkg {
+		rcvr.Elem().Sym != nil && rcvr.Elem().Sym.Pkg != localpkg && !instantiatedHere(rcvr.Elem()) {
 		return
 	}
 
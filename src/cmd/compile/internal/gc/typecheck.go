@@ -964,6 +964,14 @@ func typecheck1(n *Node, top int) *Node {
 		}
 
 	case OINDEX:
+		if g := genericOf(n.Left); g != nil {
+			return typecheckGeneric(n, g, top)
+		}
+		if n.Right == nil {
+			yyerror("invalid operation: %v (%v is not a generic function or type)", n, n.Left)
+			n.Type = nil
+			return n
+		}
 		ok |= Erv
 		n.Left = typecheck(n.Left, Erv)
 		n.Left = defaultlit(n.Left, nil)
@@ -1155,6 +1163,12 @@ func typecheck1(n *Node, top int) *Node {
 
 	// call and call like
 	case OCALL:
+		if g := genericCallee(n.Left); g != nil {
+			if !typecheckGenericCall(n, g) {
+				n.Type = nil
+				return n
+			}
+		}
 		n.Left = typecheck(n.Left, Erv|Etype|Ecall)
 		if n.Left.Diag() {
 			n.SetDiag(true)
@@ -3626,6 +3640,15 @@ func typecheckdef(n *Node) {
 		return
 	}
 
+	if n.Name != nil && n.Name.Generic() {
+		// generic names are only valid when instantiated
+		if g := generics[n]; g != nil {
+			typecheckGenericName(g, lno)
+		}
+		lineno = lno
+		return
+	}
+
 	typecheckdefstack = append(typecheckdefstack, n)
 	if n.Walkdef() == 2 {
 		flusherrors()
@@ -420,6 +420,13 @@ func lexinit1() {
 	s.Def = asTypesNode(typenod(types.Runetype))
 	asNode(s.Def).Name = new(Name)
 
+	// any is an alias for interface{}
+	s = builtinpkg.Lookup("any")
+	s.Def = asTypesNode(typenod(types.Types[TINTER]))
+
+	// comparable constraint
+	declareComparable()
+
 	// backend-dependent builtin types (e.g. int).
 	for _, s := range typedefs {
 		s1 := builtinpkg.Lookup(s.name)
@@ -70,12 +70,14 @@ type (
 	}
 
 	// Name Type
+	// Name [TParamList] Type
 	TypeDecl struct {
-		Name   *Name
-		Alias  bool
-		Type   Expr
-		Group  *Group // nil means not part of a group
-		Pragma Pragma
+		Name       *Name
+		TParamList []*Field // nil means no type parameters
+		Alias      bool
+		Type       Expr
+		Group      *Group // nil means not part of a group
+		Pragma     Pragma
 		decl
 	}
 
@@ -90,17 +92,18 @@ type (
 		decl
 	}
 
-	// func          Name Type { Body }
-	// func          Name Type
+	// func          Name [TParamList] Type { Body }
+	// func          Name [TParamList] Type
 	// func Receiver Name Type { Body }
 	// func Receiver Name Type
 	FuncDecl struct {
-		Attr   map[string]bool // go:attr map
-		Recv   *Field          // nil means regular function
-		Name   *Name
-		Type   *FuncType
-		Body   *BlockStmt // nil means no body (forward declaration)
-		Pragma Pragma     // TODO(mdempsky): Cleaner solution.
+		Attr       map[string]bool // go:attr map
+		Recv       *Field          // nil means regular function
+		Name       *Name
+		TParamList []*Field // nil means no type parameters
+		Type       *FuncType
+		Body       *BlockStmt // nil means no body (forward declaration)
+		Pragma     Pragma     // TODO(mdempsky): Cleaner solution.
 		decl
 	}
 )
@@ -178,6 +181,7 @@ type (
 	}
 
 	// X[Index]
+	// X[T1, T2, ...] (with Ti = Index.(*ListExpr).ElemList[i])
 	IndexExpr struct {
 		X     Expr
 		Index Expr
@@ -267,6 +271,8 @@ type (
 	}
 
 	// interface { MethodList[0]; MethodList[1]; ... }
+	// An embedded element (Name == nil) may be a type or a
+	// type set literal of the form ~T or A|B (an Operation).
 	InterfaceType struct {
 		MethodList []*Field
 		expr
@@ -523,7 +523,7 @@ func (p *parser) constDecl(group *Group) Decl {
 	return d
 }
 
-// TypeSpec = identifier [ "=" ] Type .
+// TypeSpec = identifier [ TypeParams ] [ "=" ] Type .
 func (p *parser) typeDecl(group *Group) Decl {
 	if trace {
 		defer p.trace("typeDecl")()
@@ -533,8 +533,55 @@ func (p *parser) typeDecl(group *Group) Decl {
 	d.pos = p.pos()
 
 	d.Name = p.name()
-	d.Alias = p.got(_Assign)
-	d.Type = p.typeOrNil()
+	if p.tok == _Lbrack {
+		// array/slice type or type parameter list
+		pos := p.pos()
+		p.next()
+		switch p.tok {
+		case _Name:
+			// The name may be the start of an array length
+			// expression or the first type parameter name.
+			name := p.name()
+			if p.isTypeParamStart() {
+				// type parameter list
+				d.TParamList = p.typeParamList(name)
+				d.Alias = p.got(_Assign)
+				d.Type = p.typeOrNil()
+			} else {
+				// x [n]E
+				t := new(ArrayType)
+				t.pos = pos
+				p.xnest++
+				t.Len = p.binaryExpr(p.primaryExpr(name), 0)
+				p.want(_Rbrack)
+				p.xnest--
+				t.Elem = p.type_()
+				d.Type = t
+			}
+		case _Rbrack:
+			// x []E
+			p.next()
+			t := new(SliceType)
+			t.pos = pos
+			t.Elem = p.type_()
+			d.Type = t
+		default:
+			// x [n]E or x [...]E
+			t := new(ArrayType)
+			t.pos = pos
+			p.xnest++
+			if !p.got(_DotDotDot) {
+				t.Len = p.expr()
+			}
+			p.want(_Rbrack)
+			p.xnest--
+			t.Elem = p.type_()
+			d.Type = t
+		}
+	} else {
+		d.Alias = p.got(_Assign)
+		d.Type = p.typeOrNil()
+	}
 	if d.Type == nil {
 		d.Type = p.bad()
 		p.syntaxError("in type declaration")
@@ -569,7 +616,7 @@ func (p *parser) varDecl(group *Group) Decl {
 	return d
 }
 
-// FunctionDecl = "func" FunctionName ( Function | Signature ) .
+// FunctionDecl = "func" FunctionName [ TypeParams ] ( Function | Signature ) .
 // FunctionName = identifier .
 // Function     = Signature FunctionBody .
 // MethodDecl   = "func" Receiver MethodName ( Function | Signature ) .
@@ -602,6 +649,16 @@ func (p *parser) funcDeclOrNil() *FuncDecl {
 	}
 
 	f.Name = p.name()
+	if p.tok == _Lbrack {
+		pos := p.pos()
+		p.next()
+		tparams := p.typeParamList(p.name())
+		if f.Recv != nil {
+			p.errorAt(pos, "method must have no type parameters")
+		} else {
+			f.TParamList = tparams
+		}
+	}
 	f.Type = p.funcType()
 	if p.tok == _Lbrace {
 		f.Body = p.funcBody()
@@ -611,6 +668,115 @@ func (p *parser) funcDeclOrNil() *FuncDecl {
 	return f
 }
 
+// isTypeParamStart reports whether the current token, following
+// the first name after the opening "[" of a type declaration,
+// starts a type parameter constraint (or continues a list of type
+// parameter names) rather than an array length expression.
+func (p *parser) isTypeParamStart() bool {
+	switch p.tok {
+	case _Name, _Comma, _Arrow, _Func, _Chan, _Map, _Struct, _Interface:
+		return true
+	case _Operator:
+		return p.op == Tilde
+	}
+	return false
+}
+
+// TypeParams     = "[" TypeParamList [ "," ] "]" .
+// TypeParamList  = TypeParamDecl { "," TypeParamDecl } .
+// TypeParamDecl  = IdentifierList TypeConstraint .
+// TypeConstraint = TypeElem .
+// The opening "[" and the first name must have been consumed.
+func (p *parser) typeParamList(name *Name) []*Field {
+	if trace {
+		defer p.trace("typeParamList")()
+	}
+
+	var list []*Field
+	var names []*Name // names without constraint so far
+	for {
+		names = append(names, name)
+		if p.got(_Comma) {
+			if p.tok == _Rbrack {
+				break
+			}
+			name = p.name()
+			continue
+		}
+		if p.tok == _Rbrack {
+			break
+		}
+		typ := p.typeElem(nil)
+		for _, n := range names {
+			f := new(Field)
+			f.pos = n.Pos()
+			f.Name = n
+			f.Type = typ
+			list = append(list, f)
+		}
+		names = nil
+		if !p.got(_Comma) || p.tok == _Rbrack {
+			break
+		}
+		name = p.name()
+	}
+
+	if len(names) > 0 {
+		p.syntaxError("missing type constraint")
+		typ := p.bad()
+		for _, n := range names {
+			f := new(Field)
+			f.pos = n.Pos()
+			f.Name = n
+			f.Type = typ
+			list = append(list, f)
+		}
+	}
+	p.want(_Rbrack)
+
+	return list
+}
+
+// TypeElem = TypeTerm { "|" TypeTerm } .
+// If x is non-nil, it is the already parsed first term.
+func (p *parser) typeElem(x Expr) Expr {
+	if trace {
+		defer p.trace("typeElem")()
+	}
+
+	if x == nil {
+		x = p.typeTerm()
+	}
+	for p.tok == _Operator && p.op == Or {
+		t := new(Operation)
+		t.pos = p.pos()
+		t.Op = Or
+		p.next()
+		t.X = x
+		t.Y = p.typeTerm()
+		x = t
+	}
+	return x
+}
+
+// TypeTerm = Type | UnderlyingType .
+// UnderlyingType = "~" Type .
+func (p *parser) typeTerm() Expr {
+	if trace {
+		defer p.trace("typeTerm")()
+	}
+
+	if p.tok == _Operator && p.op == Tilde {
+		t := new(Operation)
+		t.pos = p.pos()
+		t.Op = Tilde
+		p.next()
+		t.X = p.type_()
+		return t
+	}
+	return p.type_()
+}
+
 func (p *parser) funcBody() *BlockStmt {
 	p.fnest++
 	errcnt := p.errcnt
@@ -635,14 +801,17 @@ func (p *parser) expr() Expr {
 		defer p.trace("expr")()
 	}
 
-	return p.binaryExpr(0)
+	return p.binaryExpr(nil, 0)
 }
 
 // Expression = UnaryExpr | Expression binary_op Expression .
-func (p *parser) binaryExpr(prec int) Expr {
+// If x is non-nil, it is the already parsed leftmost operand.
+func (p *parser) binaryExpr(x Expr, prec int) Expr {
 	// don't trace binaryExpr - only leads to overly nested trace output
 
-	x := p.unaryExpr()
+	if x == nil {
+		x = p.unaryExpr()
+	}
 	for (p.tok == _Operator || p.tok == _Star) && p.prec > prec {
 		t := new(Operation)
 		t.pos = p.pos()
@@ -650,7 +819,7 @@ func (p *parser) binaryExpr(prec int) Expr {
 		t.X = x
 		tprec := p.prec
 		p.next()
-		t.Y = p.binaryExpr(tprec)
+		t.Y = p.binaryExpr(nil, tprec)
 		x = t
 	}
 	return x
@@ -886,8 +1055,12 @@ func (p *parser) pexpr(keep_parens bool) Expr {
 		defer p.trace("pexpr")()
 	}
 
-	x := p.operand(keep_parens)
+	return p.primaryExpr(p.operand(keep_parens))
+}
 
+// primaryExpr parses the remainder of a PrimaryExpr
+// whose operand x has already been parsed.
+func (p *parser) primaryExpr(x Expr) Expr {
 loop:
 	for {
 		pos := p.pos()
@@ -932,6 +1105,17 @@ loop:
 			var i Expr
 			if p.tok != _Colon {
 				i = p.expr()
+				if p.tok == _Comma {
+					// x[i, ...] (instantiation)
+					t := new(IndexExpr)
+					t.pos = pos
+					t.X = x
+					t.Index = p.typeListRest(i)
+					p.want(_Rbrack)
+					x = t
+					p.xnest--
+					break
+				}
 				if p.got(_Rbrack) {
 					// x[i]
 					t := new(IndexExpr)
@@ -986,7 +1170,7 @@ loop:
 			// determine if '{' belongs to a composite literal or a block statement
 			complit_ok := false
 			switch t.(type) {
-			case *Name, *SelectorExpr:
+			case *Name, *SelectorExpr, *IndexExpr:
 				if p.xnest >= 0 {
 					// x is considered a composite literal type
 					complit_ok = true
@@ -1174,7 +1358,7 @@ func (p *parser) typeOrNil() Expr {
 		return p.interfaceType()
 
 	case _Name:
-		return p.dotname(p.name())
+		return p.typeInstance(p.dotname(p.name()))
 
 	case _Lparen:
 		p.next()
@@ -1230,6 +1414,111 @@ func (p *parser) dotname(name *Name) Expr {
 	return name
 }
 
+// typeInstance parses the optional type argument list following
+// the (possibly qualified) generic type name x.
+//
+// TypeArgs = "[" TypeList [ "," ] "]" .
+func (p *parser) typeInstance(x Expr) Expr {
+	if p.tok != _Lbrack {
+		return x
+	}
+
+	t := new(IndexExpr)
+	t.pos = p.pos()
+	t.X = x
+	p.next()
+	p.xnest++
+	t.Index = p.typeListRest(p.type_())
+	p.xnest--
+	p.want(_Rbrack)
+	return t
+}
+
+// typeListRest parses the remainder of a type list whose first
+// element x has already been parsed. If the list contains more
+// than one element, the result is a *ListExpr.
+//
+// TypeList = Type { "," Type } .
+func (p *parser) typeListRest(x Expr) Expr {
+	if p.got(_Comma) {
+		list := []Expr{x}
+		for p.tok != _Rbrack && p.tok != _EOF {
+			list = append(list, p.type_())
+			if !p.got(_Comma) {
+				break
+			}
+		}
+		if len(list) > 1 {
+			t := new(ListExpr)
+			t.pos = x.Pos()
+			t.ElemList = list
+			x = t
+		}
+	}
+	return x
+}
+
+// arrayOrTArgs parses the "[" ... "]" following a name which is
+// either a parameter or field name followed by an array or slice
+// type, or a generic type name followed by its type arguments.
+// In the latter case the result is an *IndexExpr whose X must be
+// filled in by the caller.
+func (p *parser) arrayOrTArgs() Expr {
+	if trace {
+		defer p.trace("arrayOrTArgs")()
+	}
+
+	pos := p.pos()
+	p.want(_Lbrack)
+	if p.got(_Rbrack) {
+		// name []E
+		t := new(SliceType)
+		t.pos = pos
+		t.Elem = p.type_()
+		return t
+	}
+
+	p.xnest++
+	var x Expr
+	if !p.got(_DotDotDot) {
+		x = p.expr()
+		if p.tok == _Comma {
+			// name [T1, T2, ...]
+			t := new(IndexExpr)
+			t.pos = pos
+			t.Index = p.typeListRest(x)
+			p.xnest--
+			p.want(_Rbrack)
+			return t
+		}
+	}
+	p.xnest--
+	p.want(_Rbrack)
+
+	if x != nil {
+		if elem := p.typeOrNil(); elem == nil {
+			// name [T]
+			t := new(IndexExpr)
+			t.pos = pos
+			t.Index = x
+			return t
+		} else {
+			// name [n]E
+			t := new(ArrayType)
+			t.pos = pos
+			t.Len = x
+			t.Elem = elem
+			return t
+		}
+	}
+
+	// name [...]E
+	t := new(ArrayType)
+	t.pos = pos
+	t.Elem = p.type_()
+	return t
+}
+
 // StructType = "struct" "{" { FieldDecl ";" } "}" .
 func (p *parser) structType() *StructType {
 	if trace {
@@ -1320,6 +1609,22 @@ func (p *parser) fieldDecl(styp *StructType) {
 	switch p.tok {
 	case _Name:
 		name := p.name()
+		if p.tok == _Lbrack {
+			// name "[" ...
+			typ := p.arrayOrTArgs()
+			if t, ok := typ.(*IndexExpr); ok {
+				// embedded instantiated type
+				t.X = name
+				tag := p.oliteral()
+				p.addField(styp, pos, nil, t, tag)
+				return
+			}
+			// name [n]E or name []E
+			tag := p.oliteral()
+			p.addField(styp, pos, name, typ, tag)
+			return
+		}
+
 		if p.tok == _Dot || p.tok == _Literal || p.tok == _Semi || p.tok == _Rbrace {
 			// embed oliteral
 			typ := p.qualifiedName(name)
@@ -1393,7 +1698,7 @@ func (p *parser) oliteral() *BasicLit {
 	return nil
 }
 
-// MethodSpec        = MethodName Signature | InterfaceTypeName .
+// MethodSpec        = MethodName Signature | InterfaceTypeName | TypeElem .
 // MethodName        = identifier .
 // InterfaceTypeName = TypeName .
 func (p *parser) methodDecl() *Field {
@@ -1419,8 +1724,8 @@ func (p *parser) methodDecl() *Field {
 		f := new(Field)
 		f.pos = name.Pos()
 		if p.tok != _Lparen {
-			// packname
-			f.Type = p.qualifiedName(name)
+			// packname or type union
+			f.Type = p.typeElem(p.qualifiedName(name))
 			return f
 		}
 
@@ -1437,11 +1742,20 @@ func (p *parser) methodDecl() *Field {
 		p.want(_Rparen)
 		return f
 
-	default:
-		p.syntaxError("expecting method or interface name")
-		p.advance(_Semi, _Rbrace)
-		return nil
+	case _Operator, _Star, _Arrow, _Func, _Lbrack, _Chan, _Map, _Struct, _Interface:
+		if p.tok == _Operator && p.op != Tilde {
+			break
+		}
+		// type element
+		f := new(Field)
+		f.pos = p.pos()
+		f.Type = p.typeElem(nil)
+		return f
 	}
+
+	p.syntaxError("expecting method or interface name")
+	p.advance(_Semi, _Rbrace)
+	return nil
 }
 
 // ParameterDecl = [ IdentifierList ] [ "..." ] Type .
@@ -1457,10 +1771,19 @@ func (p *parser) paramDeclOrNil() *Field {
 	case _Name:
 		f.Name = p.name()
 		switch p.tok {
-		case _Name, _Star, _Arrow, _Func, _Lbrack, _Chan, _Map, _Struct, _Interface, _Lparen:
+		case _Name, _Star, _Arrow, _Func, _Chan, _Map, _Struct, _Interface, _Lparen:
 			// sym name_or_type
 			f.Type = p.type_()
 
+		case _Lbrack:
+			// sym array_or_slice_type
+			// or instantiated name_or_type
+			f.Type = p.arrayOrTArgs()
+			if t, ok := f.Type.(*IndexExpr); ok {
+				t.X = f.Name
+				f.Name = nil
+			}
+
 		case _DotDotDot:
 			// sym dotdotdot
 			f.Type = p.dotsType()
@@ -1468,7 +1791,7 @@ func (p *parser) paramDeclOrNil() *Field {
 		case _Dot:
 			// name_or_type
 			// from dotname
-			f.Type = p.dotname(f.Name)
+			f.Type = p.typeInstance(p.dotname(f.Name))
 			f.Name = nil
 		}
 
@@ -2220,7 +2543,7 @@ func (p *parser) qualifiedName(name *Name) Expr {
 		p.advance(_Dot, _Semi, _Rbrace)
 	}
 
-	return p.dotname(name)
+	return p.typeInstance(p.dotname(name))
 }
 
 // ExpressionList = Expression { "," Expression } .
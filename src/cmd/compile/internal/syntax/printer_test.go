@@ -32,6 +32,22 @@ func TestPrintString(t *testing.T) {
 	for _, want := range []string{
 		"package p",
 		"package p; type _ = int; type T1 = struct{}; type ( _ = *struct{}; T2 = float32 )",
+
+		// generic code
+		"package p; type A[T any] []T",
+		"package p; type A[K comparable, V any] map[K]V",
+		"package p; type A[P, Q any, R interface{ m() }] struct{}",
+		"package p; type A[N ~int | ~int64] [N(1)]int",
+		"package p; type A [N]int; type B [N * 2]int; type C [pkg.N]int",
+		"package p; type I interface{ ~int | string; m() }",
+		"package p; func f[T any](x T) T",
+		"package p; func f[A, B any](a A, b B) (B, A)",
+		"package p; func (l *List[T]) Push(x T)",
+		"package p; func (m Map[_, V]) Vals() []V",
+		"package p; func f(List[int], pkg.Map[string, int])",
+		"package p; var _ = f[int, string](x)",
+		"package p; var _ = List[int]{}",
+		"package p; type S struct{ List[int]; a [n]int }",
 		// TODO(gri) expand
 	} {
 		ast, err := Parse(nil, strings.NewReader(want), nil, nil, 0)
@@ -302,6 +302,10 @@ redo:
 		s.op, s.prec = Not, 0
 		s.tok = _Operator
 
+	case '~':
+		s.op, s.prec = Tilde, 0
+		s.tok = _Operator
+
 	default:
 		s.tok = 0
 		s.error(fmt.Sprintf("invalid character %#U", c))
@@ -24,6 +24,7 @@
 // 	get         download and install packages and dependencies
 // 	install     compile and install packages and dependencies
 // 	list        list packages
+// 	mod         module maintenance
 // 	run         compile and run Go program
 // 	test        test packages
 // 	tool        run specified go tool
@@ -40,7 +41,9 @@
 // 	environment environment variables
 // 	filetype    file types
 // 	gopath      GOPATH environment variable
+// 	goproxy     module proxy protocol
 // 	importpath  import path syntax
+// 	modules     modules, module versions, and more
 // 	packages    package lists
 // 	testflag    testing flags
 // 	testfunc    testing functions
@@ -185,7 +188,7 @@
 //
 // Usage:
 //
-// 	go clean [-i] [-r] [-n] [-x] [-cache] [-testcache] [build flags] [packages]
+// 	go clean [-i] [-r] [-n] [-x] [-cache] [-testcache] [-modcache] [build flags] [packages]
 //
 // Clean removes object files from package source directories.
 // The go command builds most objects in a temporary directory,
@@ -228,6 +231,10 @@
 // The -testcache flag causes clean to expire all test results in the
 // go build cache.
 //
+// The -modcache flag causes clean to remove the entire module
+// download cache, including unpacked source code of versioned
+// dependencies. Its files are read-only, so use this rather than rm.
+//
 // For more about build flags, see 'go help build'.
 //
 // For more about specifying packages, see 'go help packages'.
@@ -551,6 +558,15 @@
 //
 // Get never checks out or updates code stored in vendor directories.
 //
+// When modules are enabled (see 'go help modules'), get instead resolves
+// each argument, of the form path[@version], to a module version and
+// records it as a requirement of the main module in go.mod. The version
+// may be a semantic version such as v1.2.3, "latest" (the default), or
+// "none", which removes the requirement. The -u flag upgrades all
+// modules in the build list to their latest versions. Code is fetched
+// through the module proxy named by $GOPROXY instead of from version
+// control, and the -f, -fix, and -insecure flags do not apply.
+//
 // For more about specifying packages, see 'go help packages'.
 //
 // For more about how 'go get' finds source code to
@@ -579,7 +595,7 @@
 //
 // Usage:
 //
-// 	go list [-deps] [-e] [-f format] [-json] [-test] [build flags] [packages]
+// 	go list [-deps] [-e] [-f format] [-json] [-m] [-test] [build flags] [packages]
 //
 // List lists the packages named by the import paths, one per line.
 //
@@ -609,6 +625,7 @@
 //         Root          string // Go root or Go path dir containing this package
 //         ConflictDir   string // this directory shadows Dir in $GOPATH
 //         BinaryOnly    bool   // binary-only package: cannot be recompiled from sources
+//         Module        *Module // info about package's containing module, if any
 //
 //         // Source files
 //         GoFiles        []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
@@ -626,6 +643,14 @@
 //         TestGoFiles    []string // _test.go files in package
 //         XTestGoFiles   []string // _test.go files outside package
 //
+//         // Embedded files
+//         EmbedPatterns      []string // //go:embed patterns
+//         EmbedFiles         []string // files matched by EmbedPatterns
+//         TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+//         TestEmbedFiles     []string // files matched by TestEmbedPatterns
+//         XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+//         XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
+//
 //         // Cgo directives
 //         CgoCFLAGS    []string // cgo: flags for C compiler
 //         CgoCPPFLAGS  []string // cgo: flags for C preprocessor
@@ -709,11 +734,93 @@
 // the test binary in brackets, as in "math/rand [math/rand.test]"
 // or "regexp [sort.test]".
 //
+// The -m flag causes list to list modules instead of packages.
+// It requires module-aware mode (see 'go help modules').
+// With no arguments, list -m reports the main module. The arguments
+// may name module paths, patterns containing "..." matched against
+// module paths, or "all", which names the main module and all its
+// dependencies (the build list). The default output shows the module
+// path and, for dependencies, the selected version and any replacement:
+//
+//     example.com/main
+//     example.com/a v1.2.3
+//     example.com/b v0.1.0 => ../b
+//
+// The -f and -json flags apply to modules as well, with this struct
+// passed to the template:
+//
+//     type Module struct {
+//         Path     string       // module path
+//         Version  string       // module version
+//         Time     *time.Time   // time version was created
+//         Replace  *Module      // replaced by this module
+//         Main     bool         // is this the main module?
+//         Indirect bool         // is this module only an indirect dependency of main module?
+//         Dir      string       // directory holding files for this module, if any
+//         GoMod    string       // path to go.mod file for this module, if any
+//         Error    *ModuleError // error loading module
+//     }
+//
+//     type ModuleError struct {
+//         Err string // the error itself
+//     }
+//
+// With -e, list -m reports unknown modules using the Error field
+// instead of failing.
+//
 // For more about build flags, see 'go help build'.
 //
 // For more about specifying packages, see 'go help packages'.
 //
 //
+// Module maintenance
+//
+// Usage:
+//
+// 	go mod [-v] [maintenance flags]
+//
+// Mod performs module maintenance operations as specified by the
+// following flags, which may be combined.
+//
+// The -v flag enables additional output about operations performed.
+//
+// The -init flag initializes and writes a new go.mod to the current directory,
+// in effect creating a new module rooted at the current directory.
+// The file go.mod must not already exist.
+// If possible, mod will guess the module path from import comments
+// (see 'go help importpath') or from the directory's location in GOPATH.
+// To override this guess, use the -module flag.
+//
+// The -module flag changes (or, with -init, sets) the module's path
+// (the go.mod file's module line).
+//
+// The -require=path@version and -droprequire=path flags
+// add and drop a requirement on the given module path and version.
+// Note that -require overrides any existing requirements on path.
+// These flags are mainly for tools that understand the module graph.
+// Users should prefer 'go get path@version' or 'go get path@none',
+// which make other go.mod adjustments as needed to satisfy
+// constraints imposed by other modules.
+//
+// The -sync flag synchronizes go.mod with the source code in the module.
+// It adds any missing modules necessary to build the current module's
+// packages and dependencies, and it removes unused modules that
+// don't provide any relevant packages.
+//
+// The -graph flag prints the module requirement graph (with replacements
+// applied) in text form. Each line in the output has two space-separated
+// fields: a module and one of its requirements. Each module is identified
+// as a string of the form path@version, except for the main module,
+// which has no @version suffix.
+//
+// The -verify flag checks that the dependencies of the main module
+// stored in the module cache have not been modified since
+// they were downloaded, by comparing their contents
+// with the checksums recorded in go.sum.
+//
+// See 'go help modules' for more about modules.
+//
+//
 // Compile and run Go program
 //
 // Usage:
@@ -894,7 +1001,7 @@
 //
 // Usage:
 //
-// 	go vet [-n] [-x] [build flags] [vet flags] [packages]
+// 	go vet [-n] [-x] [-vettool prog] [build flags] [vet flags] [packages]
 //
 // Vet runs the Go vet command on the packages named by the import paths.
 //
@@ -904,6 +1011,17 @@
 // The -n flag prints commands that would be executed.
 // The -x flag prints commands as they are executed.
 //
+// The -vettool=prog flag selects a different analysis tool with alternative
+// or additional checks, typically a program written using the
+// go/analysis/multichecker or go/analysis/unitchecker package.
+// For example, a checker in the current module could be run using:
+//
+//   go build -o mychecker ./cmd/mychecker
+//   go vet -vettool=./mychecker ./...
+//
+// Only the flags that the tool reports in response to its -flags flag
+// are accepted and passed to it.
+//
 // The build flags supported by go vet are those that control package resolution
 // and execution, such as -n, -x, -v, -tags, and -toolexec.
 // For more about these flags, see 'go help build'.
@@ -1026,6 +1144,9 @@
 //
 // 	GCCGO
 // 		The gccgo command to run for 'go build -compiler=gccgo'.
+// 	GO111MODULE
+// 		Controls module-aware mode: on, off, or auto (the default).
+// 		See 'go help modules'.
 // 	GOARCH
 // 		The architecture, or processor, for which to compile code.
 // 		Examples are amd64, 386, arm, ppc64.
@@ -1039,6 +1160,9 @@
 // 		Examples are linux, darwin, windows, netbsd.
 // 	GOPATH
 // 		For more details see: 'go help gopath'.
+// 	GOPROXY
+// 		URL of the module proxy used to download modules.
+// 		See 'go help goproxy'.
 // 	GORACE
 // 		Options for the race detector.
 // 		See https://golang.org/doc/articles/race_detector.html.
@@ -1315,6 +1439,57 @@
 // See https://golang.org/s/go15vendor for details.
 //
 //
+// Module proxy protocol
+//
+// The go command downloads modules from a module proxy, named by the
+// $GOPROXY environment variable. A module proxy is any web server, or
+// file system tree, that responds to GET requests for URLs of the
+// following form:
+//
+// 	GET $GOPROXY/<module>/@v/list returns a list of all known versions
+// 	of the given module, one per line.
+//
+// 	GET $GOPROXY/<module>/@v/<version>.info returns JSON-formatted
+// 	metadata about that version of the given module.
+//
+// 	GET $GOPROXY/<module>/@v/<version>.mod returns the go.mod file
+// 	for that version of the given module.
+//
+// 	GET $GOPROXY/<module>/@v/<version>.zip returns the zip archive
+// 	for that version of the given module.
+//
+// To avoid problems when serving from case-sensitive file systems,
+// the <module> and <version> elements are case-encoded, replacing every
+// uppercase letter with an exclamation mark followed by the corresponding
+// lower-case letter: github.com/Azure encodes as github.com/!azure.
+//
+// The JSON-formatted metadata about a given module corresponds to
+// this Go data structure, which may be expanded in the future:
+//
+// 	type Info struct {
+// 		Version string    // version string
+// 		Time    time.Time // commit time
+// 	}
+//
+// The zip archive for a specific version of a given module is a
+// standard zip file that contains the file tree corresponding
+// to the module's source code and related files. The archive uses
+// slash-separated paths, and every file path in the archive must
+// begin with <module>@<version>/, where the module and version are
+// substituted directly, not case-encoded. The root of the module
+// file tree corresponds to the <module>@<version>/ prefix in the
+// archive.
+//
+// $GOPROXY may be an http:// or https:// URL, or a file:// URL naming
+// a directory tree laid out as above. Because the module download cache
+// $GOPATH/pkg/mod/cache/download uses exactly this layout, it can be
+// copied to (or used directly as) a file:// proxy for offline builds.
+//
+// If $GOPROXY is unset or set to "off", the go command does not
+// download modules at all and instead uses only the versions already
+// present in the module cache.
+//
+//
 // Import path syntax
 //
 // An import path (see 'go help packages') denotes a package stored in the local
@@ -1488,6 +1663,122 @@
 // See https://golang.org/s/go14customimport for details.
 //
 //
+// Modules, module versions, and more
+//
+// A module is a collection of related Go packages.
+// Modules are the unit of source code interchange and versioning.
+// The go command has direct support for working with modules,
+// including recording and resolving dependencies on other modules.
+// Modules replace the old GOPATH-based approach to specifying
+// which source files are used in a given build.
+//
+// Preliminary module support
+//
+// Module support is enabled by the GO111MODULE environment variable.
+// If GO111MODULE=off, the go command never uses module support:
+// it looks in vendor directories and GOPATH to find dependencies.
+// If GO111MODULE=on, the go command always uses module support:
+// it never consults GOPATH/src, and it requires a main module.
+// If GO111MODULE is unset or set to auto, the go command uses module
+// support when the current directory is outside GOPATH/src and GOROOT
+// and either contains a file named go.mod or is below a directory
+// containing one. The directory containing go.mod is the root of
+// the main module.
+//
+// In module-aware mode, GOPATH no longer defines the meaning of imports
+// during a build, but it still stores downloaded dependencies (in
+// GOPATH/pkg/mod) and installed commands (in GOPATH/bin).
+//
+// Defining a module
+//
+// A module is defined by a tree of Go source files with a go.mod file
+// in the tree's root directory. The go.mod file defines the module path,
+// which is the import path prefix for all packages in the module,
+// and the module's requirements: the other modules, with minimum
+// versions, needed for a successful build.
+//
+// The go.mod file is line-oriented, with // comments and one directive
+// per line. The directives are:
+//
+// 	module path
+// 		declares the module path.
+// 	require path version
+// 		requires the given module at the given version or later.
+// 	exclude path version
+// 		excludes the given module version from use.
+// 	replace path [version] => newpath [newversion]
+// 		replaces the contents of a module (or one version of it) with
+// 		another module version, or, if newpath is a file system path
+// 		beginning with ./ or ../ or /, with the directory newpath.
+//
+// A directive can be applied to a block of several entries by
+// factoring out the leading keyword:
+//
+// 	require (
+// 		example.com/a v1.2.3
+// 		example.com/b v0.1.0 // indirect
+// 	)
+//
+// Exclude and replace directives apply only in the main module's
+// go.mod and are ignored in dependencies. An "// indirect" comment
+// marks a requirement that is not imported by any package in the
+// main module.
+//
+// To start a new module, run 'go mod -init' in the root of the source
+// tree, optionally with -module=path to set the module path.
+//
+// Module versions and minimal version selection
+//
+// Module versions are semantic versions of the form vMAJOR.MINOR.PATCH,
+// with optional -prerelease and +build suffixes. Modules with major
+// version 2 or later must include the major version as a final /vN
+// element of the module path, so that incompatible versions of a module
+// have different paths.
+//
+// The go command builds using the build list: the main module together
+// with the minimum version of each dependency satisfying every
+// requirement in the transitive requirement graph. For each module path,
+// the build list holds the highest version required by any module
+// in the graph (minimal version selection). Versions are never upgraded
+// beyond what some go.mod file asks for.
+//
+// When a package is imported that no module in the build list provides,
+// the go command looks up the latest version of a module that provides
+// it, adds that module to go.mod, and continues the build.
+// 'go get path@version' adds or changes a requirement explicitly,
+// and 'go get path@none' removes one; see 'go help get'.
+// 'go list -m' reports the modules in the build list, and
+// 'go mod' provides lower-level maintenance operations.
+//
+// Module authentication using go.sum
+//
+// The go command records the expected cryptographic checksum of each
+// module version it downloads, and of each go.mod file it reads from a
+// dependency, in a file named go.sum next to go.mod. Each line has the
+// form
+//
+// 	<module> <version>[/go.mod] <hash>
+//
+// If a later download does not match the recorded checksum, the go
+// command reports a security error and stops. go.sum should be
+// checked in to version control along with go.mod.
+// 'go mod -verify' checks that the modules in the module cache
+// still match go.sum.
+//
+// The module cache
+//
+// Downloaded modules are stored in the module cache, GOPATH/pkg/mod.
+// Each module version is extracted, read-only by convention, into
+// GOPATH/pkg/mod/path@version, and the files as downloaded are kept
+// in GOPATH/pkg/mod/cache/download. The go command consults the cache
+// before any proxy, so builds that need only cached module versions
+// never touch the network.
+//
+// Modules are downloaded through the proxy named by $GOPROXY.
+// If GOPROXY is unset or "off", no downloads are attempted and only
+// the module cache is used. See 'go help goproxy' for details.
+//
+//
 // Package lists
 //
 // Many commands apply to a set of packages:
@@ -1638,10 +1929,28 @@
 // 	-failfast
 // 	    Do not start new tests after the first test failure.
 //
+// 	-fuzz regexp
+// 	    Run the fuzz target matching the regular expression. When specified,
+// 	    the command line argument must match exactly one package, and regexp
+// 	    must match exactly one fuzz target within that package. The compiler
+// 	    instruments the package with coverage counters, which guide the
+// 	    fuzzer; -cover is not needed. Seed inputs are read from
+// 	    testdata/fuzz/<FuzzTargetName> and from F.Add; inputs that cause
+// 	    a failure are minimized and written to that directory.
+// 	    Interesting inputs found while fuzzing are kept in the build cache.
+// 	    By default, fuzzing runs until it finds a failure, and the -timeout
+// 	    flag does not apply to it.
+//
+// 	-fuzztime t
+// 	    Run enough iterations of the fuzz target to take t, specified as a
+// 	    time.Duration (for example, -fuzztime 1h30s).
+// 	    The default is to run forever.
+//
 // 	-list regexp
-// 	    List tests, benchmarks, or examples matching the regular expression.
-// 	    No tests, benchmarks or examples will be run. This will only
-// 	    list top-level tests. No subtest or subbenchmarks will be shown.
+// 	    List tests, benchmarks, fuzz targets, or examples matching the regular
+// 	    expression. No tests, benchmarks, fuzz targets, or examples will be
+// 	    run. This will only list top-level tests. No subtest or subbenchmarks
+// 	    will be shown.
 //
 // 	-parallel n
 // 	    Allow parallel execution of test functions that call t.Parallel.
@@ -1816,6 +2125,15 @@
 //
 // 	func BenchmarkXxx(b *testing.B) { ... }
 //
+// A fuzz target is one named FuzzXxx and should have the signature,
+//
+// 	func FuzzXxx(f *testing.F) { ... }
+//
+// A fuzz target adds seed inputs with f.Add and calls f.Fuzz with a function
+// whose first parameter is a *testing.T and whose other parameters are the
+// values to fuzz. Without the -fuzz flag, the function is run once for each
+// seed input, including those stored in testdata/fuzz/FuzzXxx.
+//
 // An example function is similar to a test function but, instead of using
 // *testing.T to report success or failure, prints output to os.Stdout.
 // If the last comment in the function starts with "Output:" then the output
@@ -190,6 +190,8 @@ func TestMain(m *testing.M) {
 	os.Unsetenv("GOBIN")
 	os.Unsetenv("GOPATH")
 	os.Unsetenv("GIT_ALLOW_PROTOCOL")
+	os.Unsetenv("GO111MODULE")
+	os.Unsetenv("GOPROXY")
 	if home, ccacheDir := os.Getenv("HOME"), os.Getenv("CCACHE_DIR"); home != "" && ccacheDir == "" {
 		// On some systems the default C compiler is ccache.
 		// Setting HOME to a non-existent directory will break
@@ -763,13 +765,28 @@ func (tg *testgoData) cleanup() {
 		}
 	}
 	for _, path := range tg.temps {
-		tg.check(os.RemoveAll(path))
+		tg.check(removeAll(path))
 	}
 	if tg.tempdir != "" {
-		tg.check(os.RemoveAll(tg.tempdir))
+		tg.check(removeAll(tg.tempdir))
 	}
 }
 
+// removeAll is os.RemoveAll for trees that may hold a module cache,
+// whose directories are read-only.
+func removeAll(dir string) error {
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // ignore errors walking the file system
+		}
+		if info.IsDir() {
+			os.Chmod(path, 0777)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
 // failSSH puts an ssh executable in the PATH that always fails.
 // This is to stub out uses of ssh by go get.
 func (tg *testgoData) failSSH() {
@@ -1035,6 +1052,41 @@ func TestGoInstallRebuildsStalePackagesInOtherGOPATH(t *testing.T) {
 	tg.wantNotStale("p1", "", "./testgo list claims p1 is stale after reinstall, incorrectly")
 }
 
+func TestGoBuildEmbed(t *testing.T) {
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.tempFile("src/p/p.go", `package p
+
+import _ "embed"
+
+//go:embed data.txt
+var Data string
+`)
+	tg.tempFile("src/p/data.txt", "one")
+	tg.tempFile("src/q/q.go", `package q
+
+import _ "embed"
+
+//go:embed missing.txt
+var Data string
+`)
+	tg.setenv("GOPATH", tg.path("."))
+
+	tg.run("list", "-f", "{{.EmbedPatterns}} {{.EmbedFiles}}", "p")
+	tg.grepStdout(`^\[data.txt\] \[data.txt\]$`, "go list did not report embedded file")
+
+	tg.run("install", "p")
+	tg.wantNotStale("p", "", "./testgo list claims p is stale, incorrectly")
+	tg.tempFile("src/p/data.txt", "two")
+	tg.wantStale("p", "build ID mismatch", "./testgo list claims p is NOT stale after changing embedded file, incorrectly")
+	tg.run("install", "p")
+	tg.wantNotStale("p", "", "./testgo list claims p is stale after reinstall, incorrectly")
+
+	tg.runFail("build", "q")
+	tg.grepStderr(`q.go:5:12: pattern missing.txt: no matching files found`, "missing error for unmatched pattern")
+}
+
 func TestGoInstallDetectsRemovedFiles(t *testing.T) {
 	tg := testgo(t)
 	defer tg.cleanup()
@@ -3168,6 +3220,54 @@ func TestGoTestMainAsNormalTest(t *testing.T) {
 	tg.grepBoth(okPattern, "go test did not say ok")
 }
 
+func TestGoTestFuzz(t *testing.T) {
+	skipIfGccgo(t, "gccgo does not insert coverage counters for fuzzing")
+	tg := testgo(t)
+	defer tg.cleanup()
+	tg.parallel()
+	tg.makeTempdir()
+	tg.setenv("GOCACHE", tg.path("cache"))
+	tg.setenv("GOPATH", tg.path("."))
+	// The fuzzer can only make progress through the && chain if
+	// each operand is instrumented on its own.
+	tg.tempFile("src/magic/magic.go", `package magic
+
+		func Check(b []byte) {
+			if len(b) >= 4 && b[0] == 'F' && b[1] == 'U' && b[2] == 'Z' && b[3] == 'Z' {
+				panic("found the magic word")
+			}
+		}
+	`)
+	tg.tempFile("src/magic/magic_test.go", `package magic
+
+		import "testing"
+
+		func FuzzMagic(f *testing.F) {
+			f.Add([]byte("hello"))
+			f.Fuzz(func(t *testing.T, b []byte) {
+				Check(b)
+			})
+		}
+	`)
+
+	// Without -fuzz, only the seed corpus is run.
+	tg.run("test", "-v", "magic")
+	tg.grepStdout(`--- PASS: FuzzMagic/seed#0`, "seed input was not run as a subtest")
+
+	tg.runFail("test", "-fuzz=FuzzMagic", "-fuzztime=5m", "magic")
+	tg.grepStdout(`panic: found the magic word`, "fuzzing did not find the failing input")
+	tg.grepStdout(`Failing input written to testdata/fuzz/FuzzMagic/`, "failing input was not written")
+	tg.mustExist(tg.path("src/magic/testdata/fuzz/FuzzMagic"))
+	tg.grepStdoutNot(`coverage:`, "fuzzing printed a coverage report")
+
+	// The failing input is now part of the seed corpus.
+	tg.runFail("test", "magic")
+	tg.grepBoth(`found the magic word`, "failing input was not run as a seed input")
+
+	tg.runFail("test", "-fuzz=FuzzMagic", "magic", "errors")
+	tg.grepStderr(`cannot use -fuzz flag with multiple packages`, "-fuzz accepted multiple packages")
+}
+
 func TestGoTestMainTwice(t *testing.T) {
 	tg := testgo(t)
 	defer tg.cleanup()
@@ -39,6 +39,8 @@ var (
 
 	CmdName string // "build", "install", "list", etc.
 
+	ModulesEnabled bool // whether the go command is in module-aware mode (see 'go help modules')
+
 	DebugActiongraph string // -debug-actiongraph flag (undocumented, unstable)
 )
 
@@ -17,11 +17,12 @@ import (
 	"cmd/go/internal/cache"
 	"cmd/go/internal/cfg"
 	"cmd/go/internal/load"
+	"cmd/go/internal/modfetch"
 	"cmd/go/internal/work"
 )
 
 var CmdClean = &base.Command{
-	UsageLine: "clean [-i] [-r] [-n] [-x] [-cache] [-testcache] [build flags] [packages]",
+	UsageLine: "clean [-i] [-r] [-n] [-x] [-cache] [-testcache] [-modcache] [build flags] [packages]",
 	Short:     "remove object files and cached files",
 	Long: `
 Clean removes object files from package source directories.
@@ -65,6 +66,10 @@ The -cache flag causes clean to remove the entire go build cache.
 The -testcache flag causes clean to expire all test results in the
 go build cache.
 
+The -modcache flag causes clean to remove the entire module
+download cache, including unpacked source code of versioned
+dependencies. Its files are read-only, so use this rather than rm.
+
 For more about build flags, see 'go help build'.
 
 For more about specifying packages, see 'go help packages'.
@@ -76,6 +81,7 @@ var (
 	cleanR         bool // clean -r flag
 	cleanCache     bool // clean -cache flag
 	cleanTestcache bool // clean -testcache flag
+	cleanModcache  bool // clean -modcache flag
 )
 
 func init() {
@@ -86,6 +92,7 @@ func init() {
 	CmdClean.Flag.BoolVar(&cleanR, "r", false, "")
 	CmdClean.Flag.BoolVar(&cleanCache, "cache", false, "")
 	CmdClean.Flag.BoolVar(&cleanTestcache, "testcache", false, "")
+	CmdClean.Flag.BoolVar(&cleanModcache, "modcache", false, "")
 
 	// -n and -x are important enough to be
 	// mentioned explicitly in the docs but they
@@ -95,8 +102,12 @@ func init() {
 }
 
 func runClean(cmd *base.Command, args []string) {
-	for _, pkg := range load.PackagesAndErrors(args) {
-		clean(pkg)
+	// Loading packages in module mode would download the modules
+	// that -modcache is about to remove, so don't default to ".".
+	if len(args) > 0 || !cleanModcache {
+		for _, pkg := range load.PackagesAndErrors(args) {
+			clean(pkg)
+		}
 	}
 
 	if cleanCache {
@@ -138,6 +149,24 @@ func runClean(cmd *base.Command, args []string) {
 			}
 		}
 	}
+
+	if cleanModcache {
+		list := filepath.SplitList(cfg.BuildContext.GOPATH)
+		if len(list) == 0 || list[0] == "" {
+			base.Fatalf("go clean -modcache: missing $GOPATH")
+		}
+		dir := filepath.Join(list[0], "pkg", "mod")
+		if cfg.BuildN || cfg.BuildX {
+			var b work.Builder
+			b.Print = fmt.Print
+			b.Showcmd("", "rm -rf %s", dir)
+		}
+		if !cfg.BuildN {
+			if err := modfetch.RemoveAll(dir); err != nil {
+				base.Errorf("go clean -modcache: %v", err)
+			}
+		}
+	}
 }
 
 var cleaned = map[*load.Package]bool{}
@@ -16,6 +16,7 @@ import (
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
 	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/str"
 	"cmd/go/internal/web"
 	"cmd/go/internal/work"
@@ -68,6 +69,15 @@ it also updates any git submodules referenced by the repository.
 
 Get never checks out or updates code stored in vendor directories.
 
+When modules are enabled (see 'go help modules'), get instead resolves
+each argument, of the form path[@version], to a module version and
+records it as a requirement of the main module in go.mod. The version
+may be a semantic version such as v1.2.3, "latest" (the default), or
+"none", which removes the requirement. The -u flag upgrades all
+modules in the build list to their latest versions. Code is fetched
+through the module proxy named by $GOPROXY instead of from version
+control, and the -f, -fix, and -insecure flags do not apply.
+
 For more about specifying packages, see 'go help packages'.
 
 For more about how 'go get' finds source code to
@@ -92,6 +102,11 @@ func init() {
 func runGet(cmd *base.Command, args []string) {
 	work.BuildInit()
 
+	if cfg.ModulesEnabled {
+		runModGet(args)
+		return
+	}
+
 	if *getF && !*getU {
 		base.Fatalf("go get: cannot use -f flag without -u")
 	}
@@ -164,6 +179,21 @@ func runGet(cmd *base.Command, args []string) {
 	work.InstallPackages(args, true)
 }
 
+// runModGet implements 'go get' in module-aware mode.
+func runModGet(args []string) {
+	if len(args) == 0 && !*getU {
+		base.Fatalf("go get: no module paths given")
+	}
+	pkgs := modload.Get(args, *getU)
+	if *getD || len(pkgs) == 0 {
+		return
+	}
+	load.ClearPackageCache()
+	load.ClearCmdCache()
+	load.PackagesForBuild(pkgs)
+	work.InstallPackages(pkgs, true)
+}
+
 // downloadPaths prepares the list of paths to pass to download.
 // It expands ... patterns that can be expanded. If there is no match
 // for a particular pattern, downloadPaths leaves it in the result list,
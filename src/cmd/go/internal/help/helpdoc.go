@@ -456,6 +456,9 @@ General-purpose environment variables:
 
 	GCCGO
 		The gccgo command to run for 'go build -compiler=gccgo'.
+	GO111MODULE
+		Controls module-aware mode: on, off, or auto (the default).
+		See 'go help modules'.
 	GOARCH
 		The architecture, or processor, for which to compile code.
 		Examples are amd64, 386, arm, ppc64.
@@ -469,6 +472,9 @@ General-purpose environment variables:
 		Examples are linux, darwin, windows, netbsd.
 	GOPATH
 		For more details see: 'go help gopath'.
+	GOPROXY
+		URL of the module proxy used to download modules.
+		See 'go help goproxy'.
 	GORACE
 		Options for the race detector.
 		See https://golang.org/doc/articles/race_detector.html.
@@ -19,11 +19,12 @@ import (
 	"cmd/go/internal/cache"
 	"cmd/go/internal/cfg"
 	"cmd/go/internal/load"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/work"
 )
 
 var CmdList = &base.Command{
-	UsageLine: "list [-deps] [-e] [-f format] [-json] [-test] [build flags] [packages]",
+	UsageLine: "list [-deps] [-e] [-f format] [-json] [-m] [-test] [build flags] [packages]",
 	Short:     "list packages",
 	Long: `
 List lists the packages named by the import paths, one per line.
@@ -54,6 +55,7 @@ syntax of package template. The default output is equivalent to -f
         Root          string // Go root or Go path dir containing this package
         ConflictDir   string // this directory shadows Dir in $GOPATH
         BinaryOnly    bool   // binary-only package: cannot be recompiled from sources
+        Module        *Module // info about package's containing module, if any
 
         // Source files
         GoFiles        []string // .go source files (excluding CgoFiles, TestGoFiles, XTestGoFiles)
@@ -71,6 +73,14 @@ syntax of package template. The default output is equivalent to -f
         TestGoFiles    []string // _test.go files in package
         XTestGoFiles   []string // _test.go files outside package
 
+        // Embedded files
+        EmbedPatterns      []string // //go:embed patterns
+        EmbedFiles         []string // files matched by EmbedPatterns
+        TestEmbedPatterns  []string // //go:embed patterns in TestGoFiles
+        TestEmbedFiles     []string // files matched by TestEmbedPatterns
+        XTestEmbedPatterns []string // //go:embed patterns in XTestGoFiles
+        XTestEmbedFiles    []string // files matched by XTestEmbedPatterns
+
         // Cgo directives
         CgoCFLAGS    []string // cgo: flags for C compiler
         CgoCPPFLAGS  []string // cgo: flags for C preprocessor
@@ -154,6 +164,40 @@ for a particular test binary is followed by a space and the name of
 the test binary in brackets, as in "math/rand [math/rand.test]"
 or "regexp [sort.test]".
 
+The -m flag causes list to list modules instead of packages.
+It requires module-aware mode (see 'go help modules').
+With no arguments, list -m reports the main module. The arguments
+may name module paths, patterns containing "..." matched against
+module paths, or "all", which names the main module and all its
+dependencies (the build list). The default output shows the module
+path and, for dependencies, the selected version and any replacement:
+
+    example.com/main
+    example.com/a v1.2.3
+    example.com/b v0.1.0 => ../b
+
+The -f and -json flags apply to modules as well, with this struct
+passed to the template:
+
+    type Module struct {
+        Path     string       // module path
+        Version  string       // module version
+        Time     *time.Time   // time version was created
+        Replace  *Module      // replaced by this module
+        Main     bool         // is this the main module?
+        Indirect bool         // is this module only an indirect dependency of main module?
+        Dir      string       // directory holding files for this module, if any
+        GoMod    string       // path to go.mod file for this module, if any
+        Error    *ModuleError // error loading module
+    }
+
+    type ModuleError struct {
+        Err string // the error itself
+    }
+
+With -e, list -m reports unknown modules using the Error field
+instead of failing.
+
 For more about build flags, see 'go help build'.
 
 For more about specifying packages, see 'go help packages'.
@@ -169,17 +213,21 @@ var listDeps = CmdList.Flag.Bool("deps", false, "")
 var listE = CmdList.Flag.Bool("e", false, "")
 var listFmt = CmdList.Flag.String("f", "{{.ImportPath}}", "")
 var listJson = CmdList.Flag.Bool("json", false, "")
+var listM = CmdList.Flag.Bool("m", false, "")
 var listTest = CmdList.Flag.Bool("test", false, "")
 var nl = []byte{'\n'}
 
 func runList(cmd *base.Command, args []string) {
 	work.BuildInit()
+	if *listM && *listFmt == "{{.ImportPath}}" {
+		*listFmt = "{{.String}}"
+	}
 	out := newTrackingWriter(os.Stdout)
 	defer out.w.Flush()
 
-	var do func(*load.PackagePublic)
+	var do func(interface{})
 	if *listJson {
-		do = func(p *load.PackagePublic) {
+		do = func(p interface{}) {
 			b, err := json.MarshalIndent(p, "", "\t")
 			if err != nil {
 				out.Flush()
@@ -204,7 +252,7 @@ func runList(cmd *base.Command, args []string) {
 		if err != nil {
 			base.Fatalf("%s", err)
 		}
-		do = func(p *load.PackagePublic) {
+		do = func(p interface{}) {
 			if err := tmpl.Execute(out, p); err != nil {
 				out.Flush()
 				base.Fatalf("%s", err)
@@ -215,6 +263,27 @@ func runList(cmd *base.Command, args []string) {
 		}
 	}
 
+	if *listM {
+		// Module mode.
+		if *listDeps {
+			base.Fatalf("go list -deps cannot be used with -m")
+		}
+		if *listTest {
+			base.Fatalf("go list -test cannot be used with -m")
+		}
+		if !cfg.ModulesEnabled {
+			base.Fatalf("go list -m: cannot use -m outside module mode; see 'go help modules'")
+		}
+		for _, m := range modload.ListModules(args) {
+			if m.Error != nil && !*listE {
+				base.Errorf("go list -m %s: %s", m.Path, m.Error.Err)
+				continue
+			}
+			do(m)
+		}
+		return
+	}
+
 	var pkgs []*load.Package
 	if *listE {
 		pkgs = load.PackagesAndErrors(args)
@@ -22,11 +22,51 @@ import (
 
 	"cmd/go/internal/base"
 	"cmd/go/internal/cfg"
+	"cmd/go/internal/modinfo"
 	"cmd/go/internal/str"
 )
 
 var IgnoreImports bool // control whether we ignore imports in packages
 
+// ModInit, set by package modload, determines whether module mode is
+// enabled and sets cfg.ModulesEnabled accordingly. The package loading
+// entry points call it before consulting cfg.ModulesEnabled, so that
+// commands which never load packages are unaffected by the module
+// configuration.
+var ModInit func()
+
+// modInit calls ModInit, if set.
+func modInit() {
+	if ModInit != nil {
+		ModInit()
+	}
+}
+
+// Module hooks, set by package modload.
+// They are consulted only when cfg.ModulesEnabled is true.
+var (
+	// ModLookup returns the directory holding the package with the
+	// given import path, or the empty string if the package should be
+	// found in GOROOT the usual way.
+	ModLookup func(path string) (dir string, err error)
+
+	// ModPackageModuleInfo returns information about the module
+	// providing the package with the given import path, if any.
+	ModPackageModuleInfo func(path string) *modinfo.ModulePublic
+
+	// ModImportPaths expands the command-line package patterns
+	// (after cleaning by ImportPathsNoDotExpansion) into import paths.
+	ModImportPaths func(args []string) []string
+
+	// ModDirImportPath returns the import path for the package
+	// in the given directory, or the empty string if the directory
+	// is not in the main module.
+	ModDirImportPath func(dir string) string
+
+	// ModBinDir returns the directory in which to install commands.
+	ModBinDir func() string
+)
+
 // A Package describes a single package found in a directory.
 type Package struct {
 	PackagePublic                 // visible in 'go list'
@@ -52,6 +92,8 @@ type PackagePublic struct {
 	ForTest       string `json:",omitempty"` // package is only for use in named test
 	DepOnly       bool   `json:",omitempty"` // package is only as a dependency, not explicitly listed
 
+	Module *modinfo.ModulePublic `json:",omitempty"` // info about package's module, if any
+
 	// Stale and StaleReason remain here *only* for the list command.
 	// They are only initialized in preparation for list execution.
 	// The regular build determines staleness on the fly during action execution.
@@ -74,6 +116,10 @@ type PackagePublic struct {
 	SwigCXXFiles   []string `json:",omitempty"` // .swigcxx files
 	SysoFiles      []string `json:",omitempty"` // .syso system object files added to package
 
+	// Embedded files
+	EmbedPatterns []string `json:",omitempty"` // //go:embed patterns
+	EmbedFiles    []string `json:",omitempty"` // files matched by EmbedPatterns
+
 	// Cgo directives
 	CgoCFLAGS    []string `json:",omitempty"` // cgo: flags for C compiler
 	CgoCPPFLAGS  []string `json:",omitempty"` // cgo: flags for C preprocessor
@@ -98,6 +144,11 @@ type PackagePublic struct {
 	TestImports  []string `json:",omitempty"` // imports from TestGoFiles
 	XTestGoFiles []string `json:",omitempty"` // _test.go files outside package
 	XTestImports []string `json:",omitempty"` // imports from XTestGoFiles
+
+	TestEmbedPatterns  []string `json:",omitempty"` // //go:embed patterns from TestGoFiles
+	TestEmbedFiles     []string `json:",omitempty"` // files matched by TestEmbedPatterns
+	XTestEmbedPatterns []string `json:",omitempty"` // //go:embed patterns from XTestGoFiles
+	XTestEmbedFiles    []string `json:",omitempty"` // files matched by XTestEmbedPatterns
 }
 
 // AllFiles returns the names of all the files considered for the package.
@@ -148,6 +199,9 @@ type PackageInternal struct {
 	OmitDebug    bool                 // tell linker not to write debug information
 	GobinSubdir  bool                 // install target would be subdir of GOBIN
 	TestmainGo   *[]byte              // content for _testmain.go
+	Embed        map[string][]string  // //go:embed pattern -> files it matches
+	TestEmbed    map[string][]string  // //go:embed pattern -> files, for TestGoFiles
+	XTestEmbed   map[string][]string  // //go:embed pattern -> files, for XTestGoFiles
 
 	Asmflags   []string // -asmflags for this package
 	Gcflags    []string // -gcflags for this package
@@ -262,6 +316,9 @@ func (p *Package) copyBuild(pp *build.Package) {
 	p.TestImports = pp.TestImports
 	p.XTestGoFiles = pp.XTestGoFiles
 	p.XTestImports = pp.XTestImports
+	p.EmbedPatterns = pp.EmbedPatterns
+	p.TestEmbedPatterns = pp.TestEmbedPatterns
+	p.XTestEmbedPatterns = pp.XTestEmbedPatterns
 	if IgnoreImports {
 		p.Imports = nil
 		p.TestImports = nil
@@ -269,6 +326,194 @@ func (p *Package) copyBuild(pp *build.Package) {
 	}
 }
 
+// An embedError describes a //go:embed pattern that cannot be resolved.
+type embedError struct {
+	pattern string
+	posList []token.Position
+	err     error
+}
+
+func (e *embedError) Error() string {
+	return fmt.Sprintf("pattern %s: %v", e.pattern, e.err)
+}
+
+// pos returns the position of the first use of the pattern, if known.
+func (e *embedError) pos() *token.Position {
+	if len(e.posList) == 0 {
+		return nil
+	}
+	return &e.posList[0]
+}
+
+// resolveEmbeds resolves the package's //go:embed patterns, setting
+// EmbedFiles, TestEmbedFiles and XTestEmbedFiles and the corresponding
+// pattern maps in p.Internal.
+func (p *Package) resolveEmbeds() *embedError {
+	var err *embedError
+	bp := p.Internal.Build
+	p.EmbedFiles, p.Internal.Embed, err = resolveEmbed(p.Dir, p.EmbedPatterns, bp.EmbedPatternPos)
+	if err != nil {
+		return err
+	}
+	p.TestEmbedFiles, p.Internal.TestEmbed, err = resolveEmbed(p.Dir, p.TestEmbedPatterns, bp.TestEmbedPatternPos)
+	if err != nil {
+		return err
+	}
+	p.XTestEmbedFiles, p.Internal.XTestEmbed, err = resolveEmbed(p.Dir, p.XTestEmbedPatterns, bp.XTestEmbedPatternPos)
+	return err
+}
+
+// resolveEmbed resolves the //go:embed patterns and returns the list
+// of files they match, relative to pkgdir, and a map from each pattern
+// to the files it matches.
+func resolveEmbed(pkgdir string, patterns []string, posMap map[string][]token.Position) (files []string, pmap map[string][]string, err *embedError) {
+	if len(patterns) == 0 {
+		return nil, nil, nil
+	}
+	pmap = make(map[string][]string)
+	have := make(map[string]int)
+	dirOK := make(map[string]bool)
+	for pid, pattern := range patterns {
+		list, e := matchEmbed(pkgdir, pattern, pid+1, have, dirOK)
+		if e != nil {
+			return nil, nil, &embedError{pattern, posMap[pattern], e}
+		}
+		pmap[pattern] = list
+	}
+	for file := range have {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, pmap, nil
+}
+
+// matchEmbed returns the sorted list of files matched by a single
+// //go:embed pattern. The have map records, for each file, the
+// pid of the last pattern that matched it, so that a file matched
+// twice by the same pattern is only listed once.
+func matchEmbed(pkgdir, pattern string, pid int, have map[string]int, dirOK map[string]bool) ([]string, error) {
+	if _, err := pathpkg.Match(pattern, ""); err != nil || !validEmbedPattern(pattern) {
+		return nil, fmt.Errorf("invalid pattern syntax")
+	}
+	match, err := filepath.Glob(pkgdir + string(filepath.Separator) + filepath.FromSlash(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []string
+	for _, file := range match {
+		rel := filepath.ToSlash(file[len(pkgdir)+1:])
+		what := "file"
+		info, err := os.Lstat(file)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			what = "directory"
+		}
+
+		// Check that directories along the path do not begin
+		// a new module and are not hidden or VCS directories.
+		for dir := file; len(dir) > len(pkgdir)+1 && !dirOK[dir]; dir = filepath.Dir(dir) {
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return nil, fmt.Errorf("cannot embed %s %s: in different module", what, rel)
+			}
+			if dir != file {
+				if info, err := os.Lstat(dir); err == nil && !info.IsDir() {
+					return nil, fmt.Errorf("cannot embed %s %s: in non-directory %s", what, rel, dir[len(pkgdir)+1:])
+				}
+			}
+			dirOK[dir] = true
+			if elem := filepath.Base(dir); isBadEmbedName(elem) {
+				if dir == file {
+					return nil, fmt.Errorf("cannot embed %s %s: invalid name %s", what, rel, elem)
+				}
+				return nil, fmt.Errorf("cannot embed %s %s: in invalid directory %s", what, rel, elem)
+			}
+		}
+
+		switch {
+		default:
+			return nil, fmt.Errorf("cannot embed irregular file %s", rel)
+
+		case info.Mode().IsRegular():
+			if have[rel] != pid {
+				have[rel] = pid
+				list = append(list, rel)
+			}
+
+		case info.IsDir():
+			// Gather all files in the named directory, stopping at module
+			// boundaries and ignoring hidden files and directories.
+			count := 0
+			err := filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				rel := filepath.ToSlash(path[len(pkgdir)+1:])
+				name := info.Name()
+				if path != file && (isBadEmbedName(name) || name[0] == '.' || name[0] == '_') {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.IsDir() {
+					if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !info.Mode().IsRegular() {
+					return nil
+				}
+				count++
+				if have[rel] != pid {
+					have[rel] = pid
+					list = append(list, rel)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return nil, fmt.Errorf("cannot embed directory %s: contains no embeddable files", rel)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no matching files found")
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// validEmbedPattern reports whether pattern is a valid //go:embed
+// pattern: a slash-separated path with no empty, "." or ".." elements
+// and no leading or trailing slash.
+func validEmbedPattern(pattern string) bool {
+	if pattern == "" || pattern == "." {
+		return false
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// isBadEmbedName reports whether name is the base name of a file
+// that must not be embedded, such as a version control directory.
+func isBadEmbedName(name string) bool {
+	switch name {
+	case "", ".bzr", ".hg", ".git", ".svn":
+		return true
+	}
+	return false
+}
+
 // isStandardImportPath reports whether $GOROOT/src/path should be considered
 // part of the standard distribution. For historical reasons we allow people to add
 // their own code to $GOROOT instead of using $GOPATH, but we assume that
@@ -414,6 +659,7 @@ const (
 // with ./ or ../). A local relative path is interpreted relative to srcDir.
 // It returns a *Package describing the package found in that directory.
 func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPos []token.Position, mode int) *Package {
+	modInit()
 	stk.Push(path)
 	defer stk.Pop()
 
@@ -453,7 +699,21 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 			// Not vendoring, or we already found the vendored path.
 			buildMode |= build.IgnoreVendor
 		}
-		bp, err := cfg.BuildContext.Import(path, srcDir, buildMode)
+		var bp *build.Package
+		var err error
+		if cfg.ModulesEnabled && !isLocal {
+			var dir string
+			dir, err = ModLookup(path)
+			if err != nil {
+				bp = &build.Package{}
+			} else if dir != "" {
+				bp, err = cfg.BuildContext.ImportDir(dir, buildMode)
+				bp.BinDir = ModBinDir()
+			}
+		}
+		if bp == nil {
+			bp, err = cfg.BuildContext.Import(path, srcDir, buildMode)
+		}
 		bp.ImportPath = importPath
 		if cfg.GOBIN != "" {
 			bp.BinDir = cfg.GOBIN
@@ -463,6 +723,9 @@ func LoadImport(path, srcDir string, parent *Package, stk *ImportStack, importPo
 			err = fmt.Errorf("code in directory %s expects import %q", bp.Dir, bp.ImportComment)
 		}
 		p.load(stk, bp, err)
+		if cfg.ModulesEnabled && !isLocal {
+			p.Module = ModPackageModuleInfo(path)
+		}
 		if p.Error != nil && p.Error.Pos == "" {
 			p = setErrorPos(p, importPos)
 		}
@@ -544,7 +807,16 @@ func isDir(path string) bool {
 // First, there is Go 1.5 vendoring (golang.org/s/go15vendor).
 // If vendor expansion doesn't trigger, then the path is also subject to
 // Go 1.11 vgo legacy conversion (golang.org/issue/25069).
+//
+// In module mode, vendor directories are only consulted for imports
+// in the standard library, and no legacy conversion is done.
 func ResolveImportPath(parent *Package, path string) (found string) {
+	if modInit(); cfg.ModulesEnabled {
+		if parent != nil && parent.Standard {
+			return VendoredImportPath(parent, path)
+		}
+		return path
+	}
 	found = VendoredImportPath(parent, path)
 	if found != path {
 		return found
@@ -1251,6 +1523,18 @@ func (p *Package) load(stk *ImportStack, bp *build.Package, err error) {
 		return
 	}
 
+	// Resolve //go:embed patterns to the files they match.
+	if err := p.resolveEmbeds(); err != nil {
+		p.Error = &PackageError{
+			ImportStack: stk.Copy(),
+			Err:         err.Error(),
+		}
+		if pos := err.pos(); pos != nil {
+			p.Error.Pos = pos.String()
+		}
+		return
+	}
+
 	// Build list of imported packages and full dependency list.
 	imports := make([]*Package, 0, len(p.Imports))
 	for i, path := range importPaths {
@@ -1537,6 +1821,7 @@ func ClearCmdCache() {
 // loadPackage accepts pseudo-paths beginning with cmd/ to denote commands
 // in the Go command directory, as well as paths to those directories.
 func LoadPackage(arg string, stk *ImportStack) *Package {
+	modInit()
 	if build.IsLocalImport(arg) {
 		dir := arg
 		if !filepath.IsAbs(dir) {
@@ -1547,6 +1832,12 @@ func LoadPackage(arg string, stk *ImportStack) *Package {
 		}
 		if sub, ok := hasSubdir(cfg.GOROOTsrc, dir); ok && strings.HasPrefix(sub, "cmd/") && !strings.Contains(sub[4:], "/") {
 			arg = sub
+		} else if cfg.ModulesEnabled {
+			// Directories in the main module are known by their
+			// module import paths, not their locations.
+			if importPath := ModDirImportPath(dir); importPath != "" {
+				arg = importPath
+			}
 		}
 	}
 	if strings.HasPrefix(arg, "cmd/") && !strings.Contains(arg[4:], "/") {
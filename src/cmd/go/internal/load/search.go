@@ -213,6 +213,19 @@ func treeCanMatchPattern(pattern string) func(name string) bool {
 	}
 }
 
+// MatchPattern(pattern)(name) reports whether
+// name matches pattern, using the same rules as
+// the go command's package patterns.
+func MatchPattern(pattern string) func(name string) bool {
+	return matchPattern(pattern)
+}
+
+// TreeCanMatchPattern(pattern)(name) reports whether
+// name or children of name can possibly match pattern.
+func TreeCanMatchPattern(pattern string) func(name string) bool {
+	return treeCanMatchPattern(pattern)
+}
+
 // matchPattern(pattern)(name) reports whether
 // name matches pattern. Pattern is a limited glob
 // pattern in which '...' means 'any string' and there
@@ -334,6 +347,9 @@ func replaceVendor(x, repl string) string {
 // ImportPaths returns the import paths to use for the given command line.
 func ImportPaths(args []string) []string {
 	args = ImportPathsNoDotExpansion(args)
+	if modInit(); cfg.ModulesEnabled {
+		return ModImportPaths(args)
+	}
 	var out []string
 	for _, a := range args {
 		if strings.Contains(a, "...") {
@@ -352,6 +368,7 @@ func ImportPaths(args []string) []string {
 // ImportPathsNoDotExpansion returns the import paths to use for the given
 // command line, but it does no ... expansion.
 func ImportPathsNoDotExpansion(args []string) []string {
+	modInit()
 	if cmdlineMatchers == nil {
 		SetCmdlinePatterns(args)
 	}
@@ -376,7 +393,10 @@ func ImportPathsNoDotExpansion(args []string) []string {
 		} else {
 			a = path.Clean(a)
 		}
-		if IsMetaPackage(a) {
+		if IsMetaPackage(a) && !(cfg.ModulesEnabled && a == "all") {
+			// In module mode, "all" means the packages of the
+			// main module and their dependencies, which only
+			// ModImportPaths knows how to compute.
 			out = append(out, allPackages(a)...)
 			continue
 		}
@@ -0,0 +1,261 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modcmd implements the ``go mod'' command.
+package modcmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modload"
+	"cmd/go/internal/module"
+)
+
+var CmdMod = &base.Command{
+	UsageLine: "mod [-v] [maintenance flags]",
+	Short:     "module maintenance",
+	Long: `
+Mod performs module maintenance operations as specified by the
+following flags, which may be combined.
+
+The -v flag enables additional output about operations performed.
+
+The -init flag initializes and writes a new go.mod to the current directory,
+in effect creating a new module rooted at the current directory.
+The file go.mod must not already exist.
+If possible, mod will guess the module path from import comments
+(see 'go help importpath') or from the directory's location in GOPATH.
+To override this guess, use the -module flag.
+
+The -module flag changes (or, with -init, sets) the module's path
+(the go.mod file's module line).
+
+The -require=path@version and -droprequire=path flags
+add and drop a requirement on the given module path and version.
+Note that -require overrides any existing requirements on path.
+These flags are mainly for tools that understand the module graph.
+Users should prefer 'go get path@version' or 'go get path@none',
+which make other go.mod adjustments as needed to satisfy
+constraints imposed by other modules.
+
+The -sync flag synchronizes go.mod with the source code in the module.
+It adds any missing modules necessary to build the current module's
+packages and dependencies, and it removes unused modules that
+don't provide any relevant packages.
+
+The -graph flag prints the module requirement graph (with replacements
+applied) in text form. Each line in the output has two space-separated
+fields: a module and one of its requirements. Each module is identified
+as a string of the form path@version, except for the main module,
+which has no @version suffix.
+
+The -verify flag checks that the dependencies of the main module
+stored in the module cache have not been modified since
+they were downloaded, by comparing their contents
+with the checksums recorded in go.sum.
+
+See 'go help modules' for more about modules.
+	`,
+}
+
+var (
+	modV = CmdMod.Flag.Bool("v", false, "")
+
+	modGraph  = CmdMod.Flag.Bool("graph", false, "")
+	modInit   = CmdMod.Flag.Bool("init", false, "")
+	modSync   = CmdMod.Flag.Bool("sync", false, "")
+	modVerify = CmdMod.Flag.Bool("verify", false, "")
+
+	modModule      = CmdMod.Flag.String("module", "", "")
+	modRequire     = CmdMod.Flag.String("require", "", "")
+	modDropRequire = CmdMod.Flag.String("droprequire", "", "")
+)
+
+func init() {
+	CmdMod.Run = runMod // break init cycle
+}
+
+func runMod(cmd *base.Command, args []string) {
+	if *modInit {
+		modload.CmdModInit = true
+	}
+	modload.Init()
+
+	if len(args) != 0 {
+		base.Fatalf("go mod: mod takes no arguments")
+	}
+	any := false
+	cmd.Flag.Visit(func(f *flag.Flag) {
+		if f.Name != "v" {
+			any = true
+		}
+	})
+	if !any {
+		base.Fatalf("go mod: no flags specified (see 'go help mod').")
+	}
+
+	if *modInit {
+		modload.InitMod(*modModule)
+	} else if *modModule != "" {
+		if err := module.CheckPath(*modModule); err != nil {
+			base.Fatalf("go mod: invalid -module: %v", err)
+		}
+		modload.SetModulePath(*modModule)
+	}
+
+	if req := *modRequire; req != "" {
+		i := strings.Index(req, "@")
+		if i < 0 {
+			base.Fatalf("go mod: -require=%s: need path@version", req)
+		}
+		path, vers := req[:i], req[i+1:]
+		if err := module.Check(path, vers); err != nil {
+			base.Fatalf("go mod: -require=%s: %v", req, err)
+		}
+		modload.AddRequire(path, vers)
+	}
+	if *modDropRequire != "" {
+		if err := module.CheckPath(*modDropRequire); err != nil {
+			base.Fatalf("go mod: -droprequire=%s: %v", *modDropRequire, err)
+		}
+		modload.DropRequire(*modDropRequire)
+	}
+
+	if *modSync {
+		modload.Sync()
+	} else {
+		modload.WriteGoMod()
+	}
+
+	if *modGraph {
+		modPrintGraph()
+	}
+	if *modVerify {
+		modVerifyDeps()
+	}
+}
+
+// modPrintGraph prints the module requirement graph.
+func modPrintGraph() {
+	reqs := modload.Reqs()
+	format := func(m module.Version) string {
+		if m.Version == "" {
+			return m.Path
+		}
+		return m.Path + "@" + m.Version
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	seen := make(map[module.Version]bool)
+	list := modload.LoadBuildList()
+	for _, m := range list {
+		seen[m] = true
+	}
+	queue := append([]module.Version(nil), list...)
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		deps, err := reqs.Required(m)
+		if err != nil {
+			base.Errorf("go mod -graph: %v", err)
+			continue
+		}
+		for _, dep := range deps {
+			fmt.Fprintf(w, "%s %s\n", format(m), format(dep))
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+	base.ExitIfErrors()
+}
+
+// modVerifyDeps checks the extracted modules and downloaded go.mod
+// files in the module cache against the checksums in go.sum.
+func modVerifyDeps() {
+	ok := true
+	for _, m := range modload.LoadBuildList()[1:] {
+		if r := modload.Replacement(m); r.Path != "" {
+			if r.Version == "" {
+				// A directory replacement has nothing to verify.
+				continue
+			}
+			m = r
+		}
+		ok = verifyMod(m) && ok
+	}
+	if ok {
+		fmt.Printf("all modules verified\n")
+	} else {
+		base.SetExitStatus(1)
+	}
+}
+
+func verifyMod(mod module.Version) bool {
+	ok := true
+	zipHash, zipErr := cachedFile(mod, "ziphash")
+	dir, dirErr := modfetch.DownloadDir(mod)
+	if dirErr == nil {
+		if _, err := os.Stat(dir); err != nil {
+			dirErr = err
+		}
+	}
+	if zipErr != nil && os.IsNotExist(zipErr) && dirErr != nil && os.IsNotExist(dirErr) {
+		// Nothing downloaded yet. Nothing to verify.
+		return true
+	}
+	if h := modfetch.Sum(mod); h != "" {
+		if zipErr == nil && zipHash != h {
+			base.Errorf("%s %s: zip has been modified", mod.Path, mod.Version)
+			ok = false
+		}
+		if dirErr == nil {
+			dh, err := modfetch.HashDir(dir, mod)
+			if err != nil {
+				base.Errorf("%s %s: %v", mod.Path, mod.Version, err)
+				ok = false
+			} else if dh != h {
+				base.Errorf("%s %s: dir has been modified (%s)", mod.Path, mod.Version, dir)
+				ok = false
+			}
+		}
+	} else if *modV {
+		fmt.Fprintf(os.Stderr, "go: %s %s: no checksum in go.sum\n", mod.Path, mod.Version)
+	}
+
+	gomod := module.Version{Path: mod.Path, Version: mod.Version + "/go.mod"}
+	if h := modfetch.Sum(gomod); h != "" {
+		file, err := modfetch.CachePath(mod, "mod")
+		if err == nil {
+			if data, err := ioutil.ReadFile(file); err == nil && modfetch.HashGoMod(data) != h {
+				base.Errorf("%s %s: go.mod has been modified", mod.Path, mod.Version)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// cachedFile returns the trimmed contents of the
+// cache file for mod with the given suffix.
+func cachedFile(mod module.Version, suffix string) (string, error) {
+	file, err := modfetch.CachePath(mod, suffix)
+	if err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
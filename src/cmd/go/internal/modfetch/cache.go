@@ -0,0 +1,250 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfetch downloads modules and maintains
+// the local module cache and go.sum checksums.
+package modfetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// PkgMod is the root of the module cache, $GOPATH/pkg/mod.
+// It is set by package modload during initialization.
+var PkgMod string
+
+// CacheRoot returns the root of the module download cache,
+// which is laid out exactly like a module proxy.
+func CacheRoot() string {
+	return filepath.Join(PkgMod, "cache", "download")
+}
+
+// CachePath returns the name of the file in the download cache
+// holding the given suffix ("info", "mod", "zip", "ziphash")
+// for module m.
+func CachePath(m module.Version, suffix string) (string, error) {
+	dir, err := cacheDir(m.Path)
+	if err != nil {
+		return "", err
+	}
+	if !semver.IsValid(m.Version) {
+		return "", fmt.Errorf("non-semver module version %q", m.Version)
+	}
+	enc, err := module.EncodeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, enc+"."+suffix), nil
+}
+
+// cacheDir returns the @v directory of the download cache for path.
+func cacheDir(path string) (string, error) {
+	if PkgMod == "" {
+		return "", fmt.Errorf("internal error: modfetch.PkgMod not set")
+	}
+	enc, err := module.EncodePath(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(CacheRoot(), enc, "@v"), nil
+}
+
+// DownloadDir returns the directory to which m should be extracted.
+func DownloadDir(m module.Version) (string, error) {
+	if PkgMod == "" {
+		return "", fmt.Errorf("internal error: modfetch.PkgMod not set")
+	}
+	enc, err := module.EncodePath(m.Path)
+	if err != nil {
+		return "", err
+	}
+	if !semver.IsValid(m.Version) {
+		return "", fmt.Errorf("non-semver module version %q", m.Version)
+	}
+	encVer, err := module.EncodeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(PkgMod, enc+"@"+encVer), nil
+}
+
+// SortVersions sorts a list of versions in semantic version order.
+func SortVersions(list []string) {
+	sort.Slice(list, func(i, j int) bool {
+		cmp := semver.Compare(list[i], list[j])
+		if cmp != 0 {
+			return cmp < 0
+		}
+		return list[i] < list[j]
+	})
+}
+
+// A cachingRepo serves module data from the download cache,
+// falling back to a proxy (if any) and saving what it fetches.
+type cachingRepo struct {
+	path  string
+	proxy *proxyRepo // nil if downloading is disabled
+}
+
+func (r *cachingRepo) ModulePath() string {
+	return r.path
+}
+
+func (r *cachingRepo) errNoProxy(what string) error {
+	return &NotFoundError{r.path, fmt.Errorf("%s not in module cache and GOPROXY is not set", what)}
+}
+
+func (r *cachingRepo) Versions() ([]string, error) {
+	if r.proxy != nil {
+		return r.proxy.Versions()
+	}
+	dir, err := cacheDir(r.path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "list"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, r.errNoProxy("version list")
+		}
+		return nil, err
+	}
+	var list []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if semver.IsValid(line) {
+			list = append(list, line)
+		}
+	}
+	SortVersions(list)
+	return list, nil
+}
+
+func (r *cachingRepo) Stat(version string) (*RevInfo, error) {
+	m := module.Version{Path: r.path, Version: version}
+	file, err := CachePath(m, "info")
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		info := new(RevInfo)
+		if err := json.Unmarshal(data, info); err == nil {
+			return info, nil
+		}
+	}
+	if r.proxy == nil {
+		return nil, r.errNoProxy(m.String())
+	}
+	info, err := r.proxy.Stat(version)
+	if err != nil {
+		return nil, err
+	}
+	if info.Version != version {
+		return nil, fmt.Errorf("%v: proxy returned info for version %s", m, info.Version)
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCacheFile(file, data); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (r *cachingRepo) GoMod(version string) ([]byte, error) {
+	m := module.Version{Path: r.path, Version: version}
+	file, err := CachePath(m, "mod")
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		return data, nil
+	}
+	if r.proxy == nil {
+		return nil, r.errNoProxy(m.String())
+	}
+	data, err := r.proxy.GoMod(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCacheFile(file, data); err != nil {
+		return nil, err
+	}
+	if err := addToList(r.path, version); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *cachingRepo) Zip(version string) ([]byte, error) {
+	m := module.Version{Path: r.path, Version: version}
+	file, err := CachePath(m, "zip")
+	if err != nil {
+		return nil, err
+	}
+	if data, err := ioutil.ReadFile(file); err == nil {
+		return data, nil
+	}
+	if r.proxy == nil {
+		return nil, r.errNoProxy(m.String())
+	}
+	data, err := r.proxy.Zip(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCacheFile(file, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeCacheFile writes data to file, creating parent directories
+// as needed. It writes to a temporary file and renames it into place
+// so that concurrent go commands never observe a partial file.
+func writeCacheFile(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// addToList records version in the cached version list for path,
+// so that the download cache can itself serve as a module proxy.
+func addToList(path, version string) error {
+	dir, err := cacheDir(path)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, "list")
+	data, _ := ioutil.ReadFile(file)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == version {
+			return nil
+		}
+	}
+	return writeCacheFile(file, append(data, version+"\n"...))
+}
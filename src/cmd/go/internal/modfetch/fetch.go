@@ -0,0 +1,420 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/module"
+)
+
+// maxZipFile is the maximum size of a single file in a module zip.
+const maxZipFile = 500 << 20
+
+// Download downloads the specific module version to the
+// local download cache and returns the name of the directory
+// corresponding to the root of the module's file tree.
+//
+// The module is extracted to a temporary directory that is renamed
+// into place, and its ziphash is written only after that, so a module
+// directory with a ziphash is always complete.
+func Download(mod module.Version) (dir string, err error) {
+	dir, err = DownloadDir(mod)
+	if err != nil {
+		return "", err
+	}
+	hashFile, err := CachePath(mod, "ziphash")
+	if err != nil {
+		return "", err
+	}
+	if data, err := ioutil.ReadFile(hashFile); err == nil {
+		if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+			// Already extracted. Check the recorded hash of the zip
+			// it came from, so that go.sum is maintained even for
+			// modules downloaded on behalf of some other main module.
+			if err := checkModSum(mod, strings.TrimSpace(string(data))); err != nil {
+				return "", err
+			}
+			return dir, nil
+		}
+	}
+
+	r, err := Lookup(mod.Path)
+	if err != nil {
+		return "", err
+	}
+	if cfg.BuildV {
+		fmt.Fprintf(os.Stderr, "go: downloading %s %s\n", mod.Path, mod.Version)
+	}
+	data, err := r.Zip(mod.Version)
+	if err != nil {
+		return "", err
+	}
+	hash, err := HashZip(mod, data)
+	if err != nil {
+		return "", err
+	}
+	if err := checkModSum(mod, hash); err != nil {
+		return "", err
+	}
+	if err := Unzip(dir, data, mod); err != nil {
+		if !os.IsExist(err) {
+			return "", err
+		}
+		// Another go command extracted the module first, and may
+		// not have written the ziphash yet; or one was interrupted
+		// after extracting it. Either way, the files must be
+		// those of the zip we have checked.
+		if h, err := HashDir(dir, mod); err != nil || h != hash {
+			return "", fmt.Errorf("%v: extracted module in %s does not match its zip; remove it with 'go clean -modcache'", mod, dir)
+		}
+	}
+	if err := writeCacheFile(hashFile, []byte(hash)); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// GoMod returns the go.mod file for the given module version,
+// verifying it against go.sum.
+func GoMod(path, version string) ([]byte, error) {
+	r, err := Lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.GoMod(version)
+	if err != nil {
+		return nil, err
+	}
+	h := HashGoMod(data)
+	if err := checkModSum(module.Version{Path: path, Version: version + "/go.mod"}, h); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Unzip extracts the module zip data, whose entries must all be
+// prefixed by mod.Path@mod.Version/, into the directory dir.
+// The files are extracted to a temporary directory next to dir
+// and made read-only, and the directory is then renamed to dir,
+// so dir never holds a partial module. If dir already exists and
+// is not empty, Unzip returns an error satisfying os.IsExist.
+func Unzip(dir string, data []byte, mod module.Version) error {
+	if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+		return &os.PathError{Op: "unzip", Path: dir, Err: os.ErrExist}
+	}
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("unzip %v: %v", mod, err)
+	}
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(parent, filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return err
+	}
+	if err := unzip(tmp, z, mod); err != nil {
+		RemoveAll(tmp)
+		return err
+	}
+	if err := makeReadOnly(tmp); err != nil {
+		RemoveAll(tmp)
+		return err
+	}
+	os.Remove(dir) // empty, if it exists; Rename may not replace it
+	if err := os.Rename(tmp, dir); err != nil {
+		RemoveAll(tmp)
+		if files, _ := ioutil.ReadDir(dir); len(files) > 0 {
+			// Lost a race with another go command.
+			return &os.PathError{Op: "unzip", Path: dir, Err: os.ErrExist}
+		}
+		return err
+	}
+	return nil
+}
+
+// unzip extracts the module zip z into the new directory dir.
+func unzip(dir string, z *zip.Reader, mod module.Version) error {
+	prefix := mod.Path + "@" + mod.Version + "/"
+	seen := make(map[string]bool)
+	for _, zf := range z.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			return fmt.Errorf("unzip %v: unexpected file name %s", mod, zf.Name)
+		}
+		name := zf.Name[len(prefix):]
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if path.Clean(name) != name || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return fmt.Errorf("unzip %v: invalid file name %s", mod, zf.Name)
+		}
+		if seen[strings.ToLower(name)] {
+			return fmt.Errorf("unzip %v: multiple entries for file %s", mod, name)
+		}
+		seen[strings.ToLower(name)] = true
+		if zf.UncompressedSize64 > maxZipFile {
+			return fmt.Errorf("unzip %v: file %s too large", mod, name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+			return err
+		}
+		w, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
+		if err != nil {
+			return fmt.Errorf("unzip %v: %v", mod, err)
+		}
+		r, err := zf.Open()
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("unzip %v: %v", mod, err)
+		}
+		lr := &io.LimitedReader{R: r, N: int64(zf.UncompressedSize64) + 1}
+		_, err = io.Copy(w, lr)
+		r.Close()
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("unzip %v: %v", mod, err)
+		}
+		if err := w.Close(); err != nil {
+			return fmt.Errorf("unzip %v: %v", mod, err)
+		}
+		if lr.N <= 0 {
+			return fmt.Errorf("unzip %v: content for %s larger than declared size", mod, name)
+		}
+	}
+	return nil
+}
+
+// makeReadOnly removes write permission from the files and
+// directories in the tree rooted at dir, so that extracted
+// modules are not modified by accident.
+func makeReadOnly(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if mode := info.Mode(); mode&0222 != 0 {
+			return os.Chmod(path, mode&^0222)
+		}
+		return nil
+	})
+}
+
+// RemoveAll removes the tree rooted at dir, which may contain
+// read-only directories from extracted modules.
+func RemoveAll(dir string) error {
+	// Directories must be writable for their entries to be removed.
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // ignore errors walking the file system
+		}
+		if info.IsDir() {
+			os.Chmod(path, 0777)
+		}
+		return nil
+	})
+	return os.RemoveAll(dir)
+}
+
+// HashZip returns the hash of the module zip data,
+// in the form used in go.sum: "h1:" followed by the base64 encoding
+// of the SHA-256 of a summary listing the SHA-256 of each file.
+// The hash depends only on file names and contents,
+// not on the zip encoding.
+func HashZip(mod module.Version, data []byte) (string, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("hash %v: %v", mod, err)
+	}
+	files := make(map[string]*zip.File)
+	var names []string
+	for _, zf := range z.File {
+		if strings.HasSuffix(zf.Name, "/") {
+			continue
+		}
+		names = append(names, zf.Name)
+		files[zf.Name] = zf
+	}
+	return hash1(names, func(name string) (io.ReadCloser, error) {
+		return files[name].Open()
+	})
+}
+
+// HashGoMod returns the go.sum hash of a go.mod file.
+func HashGoMod(data []byte) string {
+	h, _ := hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+	return h
+}
+
+// hash1 implements the "h1:" hash: a SHA-256 of lines of the form
+// "<sha256 hex>  <name>\n", one per file, sorted by name.
+func hash1(names []string, open func(string) (io.ReadCloser, error)) (string, error) {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		if strings.Contains(name, "\n") {
+			return "", fmt.Errorf("filenames with newlines are not supported")
+		}
+		r, err := open(name)
+		if err != nil {
+			return "", err
+		}
+		hf := sha256.New()
+		_, err = io.Copy(hf, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// GoSumFile is the name of the go.sum file of the main module.
+// It is set by package modload; if empty, checksums are not recorded.
+var GoSumFile string
+
+var goSum struct {
+	mu     sync.Mutex
+	m      map[module.Version][]string // content of go.sum file (+ lines added this run)
+	loaded bool
+	dirty  bool
+}
+
+// initGoSum loads go.sum. goSum.mu must be held.
+func initGoSum() {
+	if goSum.loaded || GoSumFile == "" {
+		return
+	}
+	goSum.loaded = true
+	goSum.m = make(map[module.Version][]string)
+	data, err := ioutil.ReadFile(GoSumFile)
+	if err != nil && !os.IsNotExist(err) {
+		base.Fatalf("go: %v", err)
+	}
+	for lineno, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			base.Fatalf("go: malformed go.sum:\n%s:%d: wrong number of fields %v", GoSumFile, lineno+1, len(f))
+		}
+		mod := module.Version{Path: f[0], Version: f[1]}
+		goSum.m[mod] = append(goSum.m[mod], f[2])
+	}
+}
+
+// checkModSum checks that the recorded checksum for mod is h,
+// recording h in go.sum if there is no checksum yet.
+func checkModSum(mod module.Version, h string) error {
+	goSum.mu.Lock()
+	defer goSum.mu.Unlock()
+	initGoSum()
+	if goSum.m == nil {
+		return nil
+	}
+	for _, vh := range goSum.m[mod] {
+		if h == vh {
+			return nil
+		}
+		if strings.HasPrefix(vh, "h1:") {
+			return fmt.Errorf("verifying %s@%s: checksum mismatch\n\tdownloaded: %v\n\tgo.sum:     %v", mod.Path, mod.Version, h, vh)
+		}
+	}
+	goSum.m[mod] = append(goSum.m[mod], h)
+	goSum.dirty = true
+	return nil
+}
+
+// Sum returns the checksum recorded in go.sum for the module version
+// (or "version/go.mod" entry), or the empty string if there is none.
+func Sum(mod module.Version) string {
+	goSum.mu.Lock()
+	defer goSum.mu.Unlock()
+	initGoSum()
+	for _, h := range goSum.m[mod] {
+		if strings.HasPrefix(h, "h1:") {
+			return h
+		}
+	}
+	return ""
+}
+
+// WriteGoSum writes the go.sum file if it needs to be updated.
+func WriteGoSum() {
+	goSum.mu.Lock()
+	defer goSum.mu.Unlock()
+	if !goSum.dirty {
+		return
+	}
+	var mods []module.Version
+	for m := range goSum.m {
+		mods = append(mods, m)
+	}
+	module.Sort(mods)
+	var buf bytes.Buffer
+	for _, m := range mods {
+		list := goSum.m[m]
+		sort.Strings(list)
+		for _, h := range list {
+			fmt.Fprintf(&buf, "%s %s %s\n", m.Path, m.Version, h)
+		}
+	}
+	if err := ioutil.WriteFile(GoSumFile, buf.Bytes(), 0666); err != nil {
+		base.Fatalf("go: writing go.sum: %v", err)
+	}
+	goSum.dirty = false
+}
+
+// HashDir returns the "h1:" hash of the file tree rooted at dir,
+// with file names prefixed as they would be in the module zip.
+// It is used by 'go mod -verify' to check extracted modules.
+func HashDir(dir string, mod module.Version) (string, error) {
+	prefix := mod.Path + "@" + mod.Version + "/"
+	var names []string
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := prefix + filepath.ToSlash(rel)
+		names = append(names, name)
+		files[name] = file
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash1(names, func(name string) (io.ReadCloser, error) {
+		return os.Open(files[name])
+	})
+}
@@ -0,0 +1,360 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+)
+
+var testMod = module.Version{Path: "example.com/m", Version: "v1.0.0"}
+
+// makeZip returns a module zip holding files, named relative to the
+// module root.
+func makeZip(t *testing.T, mod module.Version, files map[string]string) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := z.Create(mod.Path + "@" + mod.Version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+var testFiles = map[string]string{
+	"go.mod":   "module example.com/m\n",
+	"m.go":     "package m\n",
+	"sub/s.go": "package sub\n",
+}
+
+// setup points the module cache and go.sum at a new temporary
+// directory, with downloading disabled, and returns the directory.
+// The caller must call the returned cleanup function.
+func setup(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "modfetch-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldProxy, oldPkgMod, oldGoSumFile := os.Getenv("GOPROXY"), PkgMod, GoSumFile
+	os.Setenv("GOPROXY", "off")
+	PkgMod = filepath.Join(dir, "pkg", "mod")
+	GoSumFile = filepath.Join(dir, "go.sum")
+	resetGoSum()
+	return dir, func() {
+		os.Setenv("GOPROXY", oldProxy)
+		PkgMod, GoSumFile = oldPkgMod, oldGoSumFile
+		resetGoSum()
+		if err := RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// resetGoSum discards go.sum state, so that it is read again.
+func resetGoSum() {
+	goSum.m = nil
+	goSum.loaded = false
+	goSum.dirty = false
+}
+
+// cacheZip puts data in the download cache as the zip for mod.
+func cacheZip(t *testing.T, mod module.Version, data []byte) {
+	file, err := CachePath(mod, "zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCacheFile(file, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkTree checks that dir holds exactly testFiles, read-only.
+func checkTree(t *testing.T, dir string) {
+	for name, want := range testFiles {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	n := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		if !info.IsDir() {
+			n++
+		}
+		if runtime.GOOS != "windows" && info.Mode()&0222 != 0 {
+			t.Errorf("%s is writable: mode %v", path, info.Mode())
+		}
+		return nil
+	})
+	if n != len(testFiles) {
+		t.Errorf("extracted %d files, want %d", n, len(testFiles))
+	}
+}
+
+// checkNoTemp checks that no temporary extraction directories
+// are left next to dir.
+func checkNoTemp(t *testing.T, dir string) {
+	tmps, _ := filepath.Glob(dir + ".tmp-*")
+	if len(tmps) > 0 {
+		t.Errorf("temporary directories left behind: %v", tmps)
+	}
+}
+
+func TestUnzip(t *testing.T) {
+	tmp, cleanup := setup(t)
+	defer cleanup()
+
+	dir := filepath.Join(tmp, "m@v1.0.0")
+	if err := Unzip(dir, makeZip(t, testMod, testFiles), testMod); err != nil {
+		t.Fatal(err)
+	}
+	checkTree(t, dir)
+	checkNoTemp(t, dir)
+
+	// A second extraction does not touch the first.
+	err := Unzip(dir, makeZip(t, testMod, map[string]string{"go.mod": "changed\n"}), testMod)
+	if !os.IsExist(err) {
+		t.Errorf("Unzip to existing directory: %v, want exist error", err)
+	}
+	checkTree(t, dir)
+	checkNoTemp(t, dir)
+}
+
+func TestUnzipBad(t *testing.T) {
+	tmp, cleanup := setup(t)
+	defer cleanup()
+
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{"dotdot", map[string]string{"go.mod": "module m\n", "../x.go": "package x\n"}, "invalid file name"},
+		{"dup", map[string]string{"go.mod": "module m\n", "a.go": "package m\n", "A.go": "package m\n"}, "multiple entries"},
+	} {
+		// The entries are in random order, so the bad one may
+		// come after good ones; nothing extracted may remain.
+		dir := filepath.Join(tmp, tt.name+"@v1.0.0")
+		err := Unzip(dir, makeZip(t, testMod, tt.files), testMod)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: Unzip error = %v, want %q", tt.name, err, tt.err)
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s: module directory exists after failed Unzip", tt.name)
+		}
+		checkNoTemp(t, dir)
+	}
+
+	dir := filepath.Join(tmp, "notzip@v1.0.0")
+	if err := Unzip(dir, []byte("not a zip"), testMod); err == nil {
+		t.Errorf("Unzip of non-zip data succeeded")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("module directory exists after failed Unzip")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	_, cleanup := setup(t)
+	defer cleanup()
+
+	data := makeZip(t, testMod, testFiles)
+	hash, err := HashZip(testMod, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheZip(t, testMod, data)
+
+	dir, err := Download(testMod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTree(t, dir)
+	checkNoTemp(t, dir)
+	hashFile, _ := CachePath(testMod, "ziphash")
+	if got, err := ioutil.ReadFile(hashFile); err != nil || string(got) != hash {
+		t.Errorf("ziphash = %q, %v; want %q", got, err, hash)
+	}
+	if got := Sum(testMod); got != hash {
+		t.Errorf("Sum = %q, want %q", got, hash)
+	}
+
+	// The extracted module is used from then on, even without a zip.
+	zipFile, _ := CachePath(testMod, "zip")
+	if err := os.Remove(zipFile); err != nil {
+		t.Fatal(err)
+	}
+	if dir2, err := Download(testMod); err != nil || dir2 != dir {
+		t.Errorf("second Download = %q, %v; want %q", dir2, err, dir)
+	}
+}
+
+func TestDownloadPartial(t *testing.T) {
+	_, cleanup := setup(t)
+	defer cleanup()
+
+	data := makeZip(t, testMod, testFiles)
+	cacheZip(t, testMod, data)
+	dir, err := DownloadDir(testMod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted extraction by an older go command left some
+	// of the files, and no ziphash. The directory must not be used.
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(testFiles["go.mod"]), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Download(testMod); err == nil || !strings.Contains(err.Error(), "does not match its zip") {
+		t.Errorf("Download over partial extraction: %v, want mismatch error", err)
+	}
+	hashFile, _ := CachePath(testMod, "ziphash")
+	if _, err := os.Stat(hashFile); !os.IsNotExist(err) {
+		t.Errorf("ziphash written for partial extraction")
+	}
+
+	// A complete extraction with no ziphash, as left by a concurrent
+	// go command that has not written it yet, is used.
+	if err := RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unzip(dir, data, testMod); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Download(testMod); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(hashFile); err != nil {
+		t.Errorf("ziphash not written: %v", err)
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	tmp, cleanup := setup(t)
+	defer cleanup()
+
+	data := makeZip(t, testMod, testFiles)
+	hash, err := HashZip(testMod, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheZip(t, testMod, data)
+	const badHash = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	if err := ioutil.WriteFile(filepath.Join(tmp, "go.sum"), []byte("example.com/m v1.0.0 "+badHash+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Download(testMod); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Download with wrong go.sum: %v, want checksum mismatch", err)
+	}
+	dir, _ := DownloadDir(testMod)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("module extracted despite checksum mismatch")
+	}
+	hashFile, _ := CachePath(testMod, "ziphash")
+	if _, err := os.Stat(hashFile); !os.IsNotExist(err) {
+		t.Errorf("ziphash written despite checksum mismatch")
+	}
+
+	// Once extracted, the ziphash is still checked against go.sum.
+	if err := ioutil.WriteFile(filepath.Join(tmp, "go.sum"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	resetGoSum()
+	if _, err := Download(testMod); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "go.sum"), []byte("example.com/m v1.0.0 "+badHash+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	resetGoSum()
+	if _, err := Download(testMod); err == nil || !strings.Contains(err.Error(), hash) {
+		t.Errorf("Download of extracted module with wrong go.sum: %v, want mismatch reporting %s", err, hash)
+	}
+}
+
+func TestGoSum(t *testing.T) {
+	tmp, cleanup := setup(t)
+	defer cleanup()
+
+	const (
+		h1  = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+		h2  = "h1:BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB="
+		old = "h0:legacy"
+	)
+	a := module.Version{Path: "example.com/a", Version: "v1.0.0"}
+	amod := module.Version{Path: "example.com/a", Version: "v1.0.0/go.mod"}
+	b := module.Version{Path: "example.com/b", Version: "v1.2.0"}
+	sum := "example.com/b v1.2.0 " + old + "\n" +
+		"example.com/a v1.0.0 " + h1 + "\n"
+	if err := ioutil.WriteFile(GoSumFile, []byte(sum), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := Sum(a); got != h1 {
+		t.Errorf("Sum(a) = %q, want %q", got, h1)
+	}
+	if got := Sum(b); got != "" {
+		t.Errorf("Sum(b) = %q, want \"\" (only an unknown hash kind is recorded)", got)
+	}
+	if err := checkModSum(a, h1); err != nil {
+		t.Errorf("checkModSum with recorded hash: %v", err)
+	}
+	if err := checkModSum(a, h2); err == nil {
+		t.Errorf("checkModSum with wrong hash succeeded")
+	}
+	WriteGoSum()
+	if data, _ := ioutil.ReadFile(GoSumFile); string(data) != sum {
+		t.Errorf("go.sum rewritten without changes:\n%s", data)
+	}
+
+	// New hashes are recorded, and hashes of unknown kinds kept.
+	if err := checkModSum(amod, h2); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkModSum(b, h2); err != nil {
+		t.Fatal(err)
+	}
+	WriteGoSum()
+	want := "example.com/a v1.0.0 " + h1 + "\n" +
+		"example.com/a v1.0.0/go.mod " + h2 + "\n" +
+		"example.com/b v1.2.0 " + old + "\n" +
+		"example.com/b v1.2.0 " + h2 + "\n"
+	data, err := ioutil.ReadFile(filepath.Join(tmp, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("go.sum =\n%s\nwant\n%s", data, want)
+	}
+}
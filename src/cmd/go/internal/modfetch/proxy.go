@@ -0,0 +1,227 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/web"
+)
+
+var HelpGoproxy = &base.Command{
+	UsageLine: "goproxy",
+	Short:     "module proxy protocol",
+	Long: `
+The go command downloads modules from a module proxy, named by the
+$GOPROXY environment variable. A module proxy is any web server, or
+file system tree, that responds to GET requests for URLs of the
+following form:
+
+	GET $GOPROXY/<module>/@v/list returns a list of all known versions
+	of the given module, one per line.
+
+	GET $GOPROXY/<module>/@v/<version>.info returns JSON-formatted
+	metadata about that version of the given module.
+
+	GET $GOPROXY/<module>/@v/<version>.mod returns the go.mod file
+	for that version of the given module.
+
+	GET $GOPROXY/<module>/@v/<version>.zip returns the zip archive
+	for that version of the given module.
+
+To avoid problems when serving from case-sensitive file systems,
+the <module> and <version> elements are case-encoded, replacing every
+uppercase letter with an exclamation mark followed by the corresponding
+lower-case letter: github.com/Azure encodes as github.com/!azure.
+
+The JSON-formatted metadata about a given module corresponds to
+this Go data structure, which may be expanded in the future:
+
+	type Info struct {
+		Version string    // version string
+		Time    time.Time // commit time
+	}
+
+The zip archive for a specific version of a given module is a
+standard zip file that contains the file tree corresponding
+to the module's source code and related files. The archive uses
+slash-separated paths, and every file path in the archive must
+begin with <module>@<version>/, where the module and version are
+substituted directly, not case-encoded. The root of the module
+file tree corresponds to the <module>@<version>/ prefix in the
+archive.
+
+$GOPROXY may be an http:// or https:// URL, or a file:// URL naming
+a directory tree laid out as above. Because the module download cache
+$GOPATH/pkg/mod/cache/download uses exactly this layout, it can be
+copied to (or used directly as) a file:// proxy for offline builds.
+
+If $GOPROXY is unset or set to "off", the go command does not
+download modules at all and instead uses only the versions already
+present in the module cache.
+	`,
+}
+
+// A RevInfo describes a single revision of a module.
+type RevInfo struct {
+	Version string    // version string
+	Time    time.Time // commit time
+}
+
+// A Repo represents a source of module versions.
+type Repo interface {
+	// ModulePath returns the module path.
+	ModulePath() string
+
+	// Versions lists all known versions, in semantic version order.
+	Versions() ([]string, error)
+
+	// Stat returns information about the given version.
+	Stat(version string) (*RevInfo, error)
+
+	// GoMod returns the go.mod file for the given version.
+	GoMod(version string) ([]byte, error)
+
+	// Zip returns the zip archive for the given version.
+	Zip(version string) ([]byte, error)
+}
+
+// A NotFoundError reports that the proxy or cache
+// has no information about the requested module or version.
+type NotFoundError struct {
+	Path string
+	Err  error
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("module %s: not found: %v", e.Path, e.Err)
+}
+
+// proxyURL returns the configured proxy URL,
+// or the empty string if downloading is disabled.
+func proxyURL() string {
+	u := os.Getenv("GOPROXY")
+	if u == "off" {
+		return ""
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// Lookup returns the Repo for the module with the given path.
+// Results are served from the module cache when possible,
+// consulting $GOPROXY only for information the cache lacks.
+func Lookup(path string) (Repo, error) {
+	enc, err := module.EncodePath(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &cachingRepo{path: path}
+	if u := proxyURL(); u != "" {
+		r.proxy = &proxyRepo{url: u + "/" + enc, path: path}
+	}
+	return r, nil
+}
+
+// A proxyRepo fetches module data using the module proxy protocol.
+type proxyRepo struct {
+	url  string
+	path string
+}
+
+func (p *proxyRepo) get(suffix string) ([]byte, error) {
+	u := p.url + "/@v/" + suffix
+	var (
+		data []byte
+		err  error
+	)
+	if strings.HasPrefix(u, "file://") {
+		data, err = readFileURL(u)
+		if os.IsNotExist(err) {
+			return nil, &NotFoundError{p.path, err}
+		}
+	} else {
+		data, err = web.Get(u)
+		if herr, ok := err.(*web.HTTPError); ok && (herr.StatusCode == 404 || herr.StatusCode == 410) {
+			return nil, &NotFoundError{p.path, err}
+		}
+	}
+	return data, err
+}
+
+// readFileURL returns the contents of the file named by the file:// URL u.
+func readFileURL(u string) ([]byte, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	name := pu.Path
+	if runtime.GOOS == "windows" {
+		name = strings.TrimPrefix(name, "/")
+	}
+	return ioutil.ReadFile(filepath.FromSlash(name))
+}
+
+func (p *proxyRepo) ModulePath() string {
+	return p.path
+}
+
+func (p *proxyRepo) Versions() ([]string, error) {
+	data, err := p.get("list")
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) >= 1 && semver.IsValid(f[0]) {
+			list = append(list, f[0])
+		}
+	}
+	SortVersions(list)
+	return list, nil
+}
+
+func (p *proxyRepo) Stat(version string) (*RevInfo, error) {
+	enc, err := module.EncodeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.get(enc + ".info")
+	if err != nil {
+		return nil, err
+	}
+	info := new(RevInfo)
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("%s@%s: invalid info: %v", p.path, version, err)
+	}
+	return info, nil
+}
+
+func (p *proxyRepo) GoMod(version string) ([]byte, error) {
+	enc, err := module.EncodeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return p.get(enc + ".mod")
+}
+
+func (p *proxyRepo) Zip(version string) ([]byte, error) {
+	enc, err := module.EncodeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return p.get(enc + ".zip")
+}
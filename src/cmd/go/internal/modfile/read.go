@@ -0,0 +1,322 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modfile implements parsing and formatting for go.mod files.
+//
+// A go.mod file is a sequence of lines, each holding a verb followed by
+// its arguments. The verbs are module, require, exclude, and replace.
+// Consecutive lines with the same verb may be grouped into a block:
+//
+//	require (
+//		golang.org/x/text v0.3.0
+//		rsc.io/quote v1.5.2 // indirect
+//	)
+//
+// Arguments are separated by white space and may be written as
+// Go double-quoted or back-quoted strings. Comments begin with //
+// and run to the end of the line.
+package modfile
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+)
+
+// A File is the parsed, interpreted form of a go.mod file.
+type File struct {
+	Module  *Module
+	Require []*Require
+	Exclude []*Exclude
+	Replace []*Replace
+}
+
+// A Module is the module statement.
+type Module struct {
+	Mod module.Version
+}
+
+// A Require is a single requirement statement.
+type Require struct {
+	Mod      module.Version
+	Indirect bool // has "// indirect" comment
+}
+
+// An Exclude is a single exclude statement.
+type Exclude struct {
+	Mod module.Version
+}
+
+// A Replace is a single replace statement.
+// If New.Version is empty, New.Path is a file system directory
+// holding the replacement module.
+type Replace struct {
+	Old module.Version
+	New module.Version
+}
+
+// An ErrorList is a list of errors found while parsing a go.mod file.
+type ErrorList []error
+
+func (e ErrorList) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// A line is a single logical line of a go.mod file after lexing.
+type line struct {
+	lineno  int
+	verb    string
+	args    []string
+	comment string // text of trailing // comment, without the slashes
+}
+
+// Parse parses the data, reported in errors as being from file,
+// into a File struct.
+func Parse(file string, data []byte) (*File, error) {
+	lines, err := lex(file, data)
+	if err != nil {
+		return nil, err
+	}
+	f := new(File)
+	var errs ErrorList
+	for _, l := range lines {
+		if err := f.add(file, l); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return f, nil
+}
+
+// lex splits data into logical lines, expanding blocks so that
+// each returned line carries its own verb.
+func lex(file string, data []byte) ([]*line, error) {
+	var (
+		lines []*line
+		block string // verb of enclosing block, if any
+		errs  ErrorList
+	)
+	for i, text := range strings.Split(string(data), "\n") {
+		lineno := i + 1
+		toks, comment, err := tokenize(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %v", file, lineno, err))
+			continue
+		}
+		if len(toks) == 0 {
+			continue
+		}
+		if block != "" {
+			if len(toks) == 1 && toks[0] == ")" {
+				block = ""
+				continue
+			}
+			lines = append(lines, &line{lineno, block, toks, comment})
+			continue
+		}
+		if len(toks) == 2 && toks[1] == "(" {
+			block = toks[0]
+			continue
+		}
+		lines = append(lines, &line{lineno, toks[0], toks[1:], comment})
+	}
+	if block != "" {
+		errs = append(errs, fmt.Errorf("%s: unexpected EOF in %s block", file, block))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return lines, nil
+}
+
+// tokenize splits a single line of text into tokens,
+// returning any trailing comment separately.
+func tokenize(text string) (toks []string, comment string, err error) {
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return toks, "", nil
+		}
+		if strings.HasPrefix(text, "//") {
+			return toks, strings.TrimSpace(text[2:]), nil
+		}
+		switch text[0] {
+		case '(', ')':
+			toks = append(toks, text[:1])
+			text = text[1:]
+		case '"', '`':
+			end := -1
+			for i := 1; i < len(text); i++ {
+				if text[0] == '"' && text[i] == '\\' {
+					i++
+					continue
+				}
+				if text[i] == text[0] {
+					end = i + 1
+					break
+				}
+			}
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated quoted string")
+			}
+			s, err := strconv.Unquote(text[:end])
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid quoted string %s: %v", text[:end], err)
+			}
+			toks = append(toks, s)
+			text = text[end:]
+		default:
+			i := strings.IndexFunc(text, func(r rune) bool {
+				return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' || r == '`'
+			})
+			if i < 0 {
+				i = len(text)
+			}
+			if j := strings.Index(text[:i], "//"); j >= 0 {
+				i = j
+			}
+			toks = append(toks, text[:i])
+			text = text[i:]
+		}
+	}
+}
+
+func (f *File) add(file string, l *line) error {
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s:%d: %s", file, l.lineno, fmt.Sprintf(format, args...))
+	}
+	switch l.verb {
+	default:
+		return errorf("unknown directive: %s", l.verb)
+
+	case "module":
+		if f.Module != nil {
+			return errorf("repeated module statement")
+		}
+		if len(l.args) != 1 {
+			return errorf("usage: module module/path")
+		}
+		f.Module = &Module{Mod: module.Version{Path: l.args[0]}}
+
+	case "require", "exclude":
+		if len(l.args) != 2 {
+			return errorf("usage: %s module/path v1.2.3", l.verb)
+		}
+		s, v := l.args[0], l.args[1]
+		if err := module.Check(s, v); err != nil {
+			return errorf("invalid module: %v", err)
+		}
+		m := module.Version{Path: s, Version: semver.Canonical(v)}
+		if l.verb == "require" {
+			f.Require = append(f.Require, &Require{Mod: m, Indirect: l.comment == "indirect"})
+		} else {
+			f.Exclude = append(f.Exclude, &Exclude{Mod: m})
+		}
+
+	case "replace":
+		arrow := 2
+		if len(l.args) >= 2 && l.args[1] == "=>" {
+			arrow = 1
+		}
+		if len(l.args) < arrow+2 || len(l.args) > arrow+3 || l.args[arrow] != "=>" {
+			return errorf("usage: %s module/path [v1.2.3] => other/module v1.4\n\t or %s module/path [v1.2.3] => ../local/directory", l.verb, l.verb)
+		}
+		s := l.args[0]
+		if err := module.CheckPath(s); err != nil {
+			return errorf("invalid module path: %v", err)
+		}
+		var v string
+		if arrow == 2 {
+			v = l.args[1]
+			if !semver.IsValid(v) {
+				return errorf("invalid module version %q", v)
+			}
+			v = semver.Canonical(v)
+		}
+		ns := l.args[arrow+1]
+		var nv string
+		if len(l.args) == arrow+2 {
+			if !IsDirectoryPath(ns) {
+				return errorf("replacement module without version must be directory path (rooted or starting with ./ or ../)")
+			}
+		} else {
+			nv = l.args[arrow+2]
+			if IsDirectoryPath(ns) {
+				return errorf("replacement module directory path %q cannot have version", ns)
+			}
+			if err := module.Check(ns, nv); err != nil {
+				return errorf("invalid replacement module: %v", err)
+			}
+			nv = semver.Canonical(nv)
+		}
+		f.Replace = append(f.Replace, &Replace{
+			Old: module.Version{Path: s, Version: v},
+			New: module.Version{Path: ns, Version: nv},
+		})
+	}
+	return nil
+}
+
+// IsDirectoryPath reports whether the given path should be interpreted
+// as a directory path. Just like on the go command line, relative paths
+// and rooted paths are directory paths; the rest are module paths.
+func IsDirectoryPath(ns string) bool {
+	return strings.HasPrefix(ns, "./") || strings.HasPrefix(ns, "../") || strings.HasPrefix(ns, "/") ||
+		strings.HasPrefix(ns, `.\`) || strings.HasPrefix(ns, `..\`) || strings.HasPrefix(ns, `\`) ||
+		len(ns) >= 2 && ('A' <= ns[0] && ns[0] <= 'Z' || 'a' <= ns[0] && ns[0] <= 'z') && ns[1] == ':'
+}
+
+// ModulePath returns the module path from the gomod file text.
+// If it cannot find a module path, it returns an empty string.
+// It is tolerant of unrelated problems in the go.mod file.
+func ModulePath(mod []byte) string {
+	for len(mod) > 0 {
+		line := mod
+		mod = nil
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, mod = line[:i], line[i+1:]
+		}
+		toks, _, err := tokenize(string(line))
+		if err != nil || len(toks) != 2 || toks[0] != "module" {
+			continue
+		}
+		return toks[1]
+	}
+	return ""
+}
+
+// mustQuote reports whether s must be quoted in order to appear as
+// a single token in a go.mod file.
+func mustQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) || r == '"' || r == '`' || r == '(' || r == ')' || r == utf8.RuneError {
+			return true
+		}
+	}
+	return strings.Contains(s, "//") || strings.Contains(s, "/*")
+}
+
+// AutoQuote returns s or, if quoting is required for s to appear in a go.mod,
+// the quotation of s.
+func AutoQuote(s string) string {
+	if mustQuote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMod = `// comment
+module "x.y/z"
+
+require (
+	rsc.io/quote v1.5.2
+	golang.org/x/text v0.3 // indirect
+)
+
+exclude rsc.io/sampler v1.99.99
+
+replace rsc.io/quote v1.5.2 => example.com/quote v1.5.3
+replace golang.org/x/text => ../text
+`
+
+const testModFormatted = `module x.y/z
+
+require (
+	rsc.io/quote v1.5.2
+	golang.org/x/text v0.3.0 // indirect
+)
+
+exclude rsc.io/sampler v1.99.99
+
+replace (
+	rsc.io/quote v1.5.2 => example.com/quote v1.5.3
+	golang.org/x/text => ../text
+)
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse("go.mod", []byte(testMod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Module == nil || f.Module.Mod.Path != "x.y/z" {
+		t.Fatalf("module = %+v, want x.y/z", f.Module)
+	}
+	if len(f.Require) != 2 {
+		t.Fatalf("got %d requirements, want 2", len(f.Require))
+	}
+	if r := f.Require[1]; r.Mod.Path != "golang.org/x/text" || r.Mod.Version != "v0.3.0" || !r.Indirect {
+		t.Errorf("Require[1] = %+v, want golang.org/x/text v0.3.0 indirect", r)
+	}
+	if len(f.Exclude) != 1 || f.Exclude[0].Mod.Version != "v1.99.99" {
+		t.Errorf("Exclude = %+v", f.Exclude)
+	}
+	if len(f.Replace) != 2 || f.Replace[1].New.Path != "../text" || f.Replace[1].New.Version != "" {
+		t.Errorf("Replace = %+v", f.Replace)
+	}
+
+	out := string(f.Format())
+	if out != testModFormatted {
+		t.Errorf("Format:\nhave:\n%s\nwant:\n%s", out, testModFormatted)
+	}
+	f2, err := Parse("go.mod", []byte(out))
+	if err != nil {
+		t.Fatalf("reparsing formatted output: %v", err)
+	}
+	if out2 := string(f2.Format()); out2 != out {
+		t.Errorf("Format not idempotent:\nhave:\n%s\nwant:\n%s", out2, out)
+	}
+}
+
+var parseErrorTests = []struct {
+	text string
+	err  string
+}{
+	{"module x.y/z\nmodule x.y/w\n", "repeated module statement"},
+	{"bogus x.y/z\n", "unknown directive: bogus"},
+	{"require x.y/z\n", "usage: require"},
+	{"require x.y/z 1.0\n", "invalid module"},
+	{"require x.y/z/v2 v1.0.0\n", "invalid module"},
+	{"replace x.y/z => x.y/w\n", "must be directory path"},
+	{"replace x.y/z => ./w v1.0.0\n", "cannot have version"},
+	{"require (\nx.y/z v1.0.0\n", "unexpected EOF"},
+	{"module \"x.y/z\n", "unterminated quoted string"},
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range parseErrorTests {
+		_, err := Parse("go.mod", []byte(tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Parse(%q) = %v, want error containing %q", tt.text, err, tt.err)
+		}
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for _, tt := range []struct{ text, path string }{
+		{"module x.y/z\n", "x.y/z"},
+		{"// comment\nmodule \"x.y/z\" // comment\n", "x.y/z"},
+		{"require x.y/z v1.0.0\n", ""},
+	} {
+		if path := ModulePath([]byte(tt.text)); path != tt.path {
+			t.Errorf("ModulePath(%q) = %q, want %q", tt.text, path, tt.path)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"bytes"
+	"fmt"
+
+	"cmd/go/internal/module"
+)
+
+// Format returns the canonical go.mod text for f.
+// Comments other than "// indirect" annotations are not preserved.
+func (f *File) Format() []byte {
+	var buf bytes.Buffer
+	if f.Module != nil {
+		fmt.Fprintf(&buf, "module %s\n", AutoQuote(f.Module.Mod.Path))
+	}
+
+	var reqs []string
+	for _, r := range f.Require {
+		s := AutoQuote(r.Mod.Path) + " " + AutoQuote(r.Mod.Version)
+		if r.Indirect {
+			s += " // indirect"
+		}
+		reqs = append(reqs, s)
+	}
+	writeBlock(&buf, "require", reqs)
+
+	var excls []string
+	for _, x := range f.Exclude {
+		excls = append(excls, AutoQuote(x.Mod.Path)+" "+AutoQuote(x.Mod.Version))
+	}
+	writeBlock(&buf, "exclude", excls)
+
+	var repls []string
+	for _, r := range f.Replace {
+		s := AutoQuote(r.Old.Path)
+		if r.Old.Version != "" {
+			s += " " + AutoQuote(r.Old.Version)
+		}
+		s += " => " + AutoQuote(r.New.Path)
+		if r.New.Version != "" {
+			s += " " + AutoQuote(r.New.Version)
+		}
+		repls = append(repls, s)
+	}
+	writeBlock(&buf, "replace", repls)
+
+	return buf.Bytes()
+}
+
+// writeBlock writes the lines for verb to buf,
+// using a parenthesized block when there is more than one.
+func writeBlock(buf *bytes.Buffer, verb string, lines []string) {
+	switch len(lines) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(buf, "\n%s %s\n", verb, lines[0])
+	default:
+		fmt.Fprintf(buf, "\n%s (\n", verb)
+		for _, l := range lines {
+			fmt.Fprintf(buf, "\t%s\n", l)
+		}
+		buf.WriteString(")\n")
+	}
+}
+
+// AddModuleStmt sets the module path of f.
+func (f *File) AddModuleStmt(path string) {
+	f.Module = &Module{Mod: module.Version{Path: path}}
+}
+
+// AddRequire sets the required version of path to vers,
+// adding a new requirement if f does not already list path.
+func (f *File) AddRequire(path, vers string) {
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			r.Mod.Version = vers
+			return
+		}
+	}
+	f.Require = append(f.Require, &Require{Mod: module.Version{Path: path, Version: vers}})
+}
+
+// DropRequire removes any requirement of path from f.
+func (f *File) DropRequire(path string) {
+	var kept []*Require
+	for _, r := range f.Require {
+		if r.Mod.Path != path {
+			kept = append(kept, r)
+		}
+	}
+	f.Require = kept
+}
+
+// SetRequire replaces the requirements of f with req,
+// sorted by module path.
+func (f *File) SetRequire(req []*Require) {
+	f.Require = append([]*Require(nil), req...)
+	f.SortRequire()
+}
+
+// SortRequire sorts the requirements of f by module path.
+func (f *File) SortRequire() {
+	mods := make([]module.Version, len(f.Require))
+	byMod := make(map[module.Version]*Require)
+	for i, r := range f.Require {
+		mods[i] = r.Mod
+		byMod[r.Mod] = r
+	}
+	module.Sort(mods)
+	for i, m := range mods {
+		f.Require[i] = byMod[m]
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/module"
+	"cmd/go/internal/mvs"
+	"cmd/go/internal/semver"
+)
+
+// Get implements the module-aware part of 'go get'.
+// Each argument has the form path[@version], where path is a module
+// or package path and version is a semantic version, "latest"
+// (the default), or "none" (remove the requirement).
+// If upgrade is set, every module in the build list is first
+// upgraded to its latest version.
+//
+// Get updates the requirements of the main module and returns the
+// import paths of the named packages, for building and installing.
+func Get(args []string, upgrade bool) []string {
+	LoadBuildList()
+
+	if upgrade {
+		for _, m := range buildList[1:] {
+			v, err := latestVersion(m.Path)
+			if err != nil {
+				base.Errorf("go get: %v", err)
+				continue
+			}
+			if semver.Compare(v, m.Version) > 0 {
+				modFile.AddRequire(m.Path, v)
+			}
+		}
+		base.ExitIfErrors()
+	}
+
+	var pkgs []string
+	for _, arg := range args {
+		path, vers := arg, "latest"
+		if i := strings.Index(arg, "@"); i >= 0 {
+			path, vers = arg[:i], arg[i+1:]
+		}
+		if vers == "none" {
+			modFile.DropRequire(path)
+			continue
+		}
+
+		var m module.Version
+		var err error
+		if vers == "latest" {
+			m, err = queryPackage(path)
+			if _, ok := err.(*ImportMissingError); ok {
+				// Perhaps a module with no package at its root.
+				m.Path = path
+				m.Version, err = latestVersion(path)
+			}
+		} else {
+			if !semver.IsValid(vers) {
+				base.Errorf("go get %s: invalid module version %q", arg, vers)
+				continue
+			}
+			m, err = findModule(path, semver.Canonical(vers))
+		}
+		if err != nil {
+			base.Errorf("go get %s: %v", arg, err)
+			continue
+		}
+		if excluded[m] {
+			base.Errorf("go get %s: %v is excluded by go.mod", arg, m)
+			continue
+		}
+		if old := buildListVersion(m.Path); old != "" && old != m.Version {
+			fmt.Fprintf(os.Stderr, "go: updating %s %s => %s\n", m.Path, old, m.Version)
+		}
+		modFile.AddRequire(m.Path, m.Version)
+
+		root, err := moduleDir(m)
+		if err != nil {
+			base.Errorf("go get %s: %v", arg, err)
+			continue
+		}
+		if _, ok := dirInModule(path, m.Path, root); ok {
+			pkgs = append(pkgs, path)
+		}
+	}
+	base.ExitIfErrors()
+
+	buildList = nil
+	LoadBuildList()
+	WriteGoMod()
+	return pkgs
+}
+
+// findModule returns the module at the given version that
+// provides path, trying path itself and then each of its parents.
+func findModule(path, vers string) (module.Version, error) {
+	for p := path; p != "." && p != "/"; p = pathDir(p) {
+		if module.Check(p, vers) != nil {
+			continue
+		}
+		m := module.Version{Path: p, Version: vers}
+		if _, _, err := goModData(m); err != nil {
+			continue
+		}
+		return m, nil
+	}
+	return module.Version{}, fmt.Errorf("no module providing %s at %s", path, vers)
+}
+
+// buildListVersion returns the selected version of the module path,
+// or the empty string if it is not in the build list.
+func buildListVersion(path string) string {
+	for _, m := range buildList {
+		if m.Path == path {
+			return m.Version
+		}
+	}
+	return ""
+}
+
+// Sync updates go.mod to require only the modules that provide
+// packages used by the main module (see AllPackages), at their
+// currently selected versions, dropping all other requirements.
+func Sync() {
+	LoadBuildList()
+	used := usedModules()
+	indirect := make(map[string]bool)
+	for _, r := range modFile.Require {
+		indirect[r.Mod.Path] = r.Indirect
+	}
+	var keep []*modfile.Require
+	for _, m := range buildList[1:] {
+		if used[m] {
+			keep = append(keep, &modfile.Require{Mod: m, Indirect: indirect[m.Path]})
+		}
+	}
+	modFile.SetRequire(keep)
+
+	// The used modules are already at their selected versions,
+	// so rebuilding the list cannot select anything newer.
+	// WriteGoMod trims requirements implied by others.
+	list, err := mvs.BuildList(Target, Reqs())
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	buildList = list
+	WriteGoMod()
+}
+
+// SetModulePath changes the module path declared in go.mod.
+func SetModulePath(path string) {
+	readModFile()
+	modFile.AddModuleStmt(path)
+	Target = modFile.Module.Mod
+}
+
+// AddRequire sets the main module's requirement on path to vers,
+// for 'go mod -require'. No other requirements are adjusted.
+func AddRequire(path, vers string) {
+	readModFile()
+	modFile.AddRequire(path, vers)
+	buildList = nil
+}
+
+// DropRequire removes the main module's requirement on path,
+// for 'go mod -droprequire'.
+func DropRequire(path string) {
+	readModFile()
+	modFile.DropRequire(path)
+	buildList = nil
+}
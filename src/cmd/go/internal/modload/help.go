@@ -0,0 +1,126 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import "cmd/go/internal/base"
+
+var HelpModules = &base.Command{
+	UsageLine: "modules",
+	Short:     "modules, module versions, and more",
+	Long: `
+A module is a collection of related Go packages.
+Modules are the unit of source code interchange and versioning.
+The go command has direct support for working with modules,
+including recording and resolving dependencies on other modules.
+Modules replace the old GOPATH-based approach to specifying
+which source files are used in a given build.
+
+Preliminary module support
+
+Module support is enabled by the GO111MODULE environment variable.
+If GO111MODULE=off, the go command never uses module support:
+it looks in vendor directories and GOPATH to find dependencies.
+If GO111MODULE=on, the go command always uses module support:
+it never consults GOPATH/src, and it requires a main module.
+If GO111MODULE is unset or set to auto, the go command uses module
+support when the current directory is outside GOPATH/src and GOROOT
+and either contains a file named go.mod or is below a directory
+containing one. The directory containing go.mod is the root of
+the main module.
+
+In module-aware mode, GOPATH no longer defines the meaning of imports
+during a build, but it still stores downloaded dependencies (in
+GOPATH/pkg/mod) and installed commands (in GOPATH/bin).
+
+Defining a module
+
+A module is defined by a tree of Go source files with a go.mod file
+in the tree's root directory. The go.mod file defines the module path,
+which is the import path prefix for all packages in the module,
+and the module's requirements: the other modules, with minimum
+versions, needed for a successful build.
+
+The go.mod file is line-oriented, with // comments and one directive
+per line. The directives are:
+
+	module path
+		declares the module path.
+	require path version
+		requires the given module at the given version or later.
+	exclude path version
+		excludes the given module version from use.
+	replace path [version] => newpath [newversion]
+		replaces the contents of a module (or one version of it) with
+		another module version, or, if newpath is a file system path
+		beginning with ./ or ../ or /, with the directory newpath.
+
+A directive can be applied to a block of several entries by
+factoring out the leading keyword:
+
+	require (
+		example.com/a v1.2.3
+		example.com/b v0.1.0 // indirect
+	)
+
+Exclude and replace directives apply only in the main module's
+go.mod and are ignored in dependencies. An "// indirect" comment
+marks a requirement that is not imported by any package in the
+main module.
+
+To start a new module, run 'go mod -init' in the root of the source
+tree, optionally with -module=path to set the module path.
+
+Module versions and minimal version selection
+
+Module versions are semantic versions of the form vMAJOR.MINOR.PATCH,
+with optional -prerelease and +build suffixes. Modules with major
+version 2 or later must include the major version as a final /vN
+element of the module path, so that incompatible versions of a module
+have different paths.
+
+The go command builds using the build list: the main module together
+with the minimum version of each dependency satisfying every
+requirement in the transitive requirement graph. For each module path,
+the build list holds the highest version required by any module
+in the graph (minimal version selection). Versions are never upgraded
+beyond what some go.mod file asks for.
+
+When a package is imported that no module in the build list provides,
+the go command looks up the latest version of a module that provides
+it, adds that module to go.mod, and continues the build.
+'go get path@version' adds or changes a requirement explicitly,
+and 'go get path@none' removes one; see 'go help get'.
+'go list -m' reports the modules in the build list, and
+'go mod' provides lower-level maintenance operations.
+
+Module authentication using go.sum
+
+The go command records the expected cryptographic checksum of each
+module version it downloads, and of each go.mod file it reads from a
+dependency, in a file named go.sum next to go.mod. Each line has the
+form
+
+	<module> <version>[/go.mod] <hash>
+
+If a later download does not match the recorded checksum, the go
+command reports a security error and stops. go.sum should be
+checked in to version control along with go.mod.
+'go mod -verify' checks that the modules in the module cache
+still match go.sum.
+
+The module cache
+
+Downloaded modules are stored in the module cache, GOPATH/pkg/mod.
+Each module version is extracted, read-only by convention, into
+GOPATH/pkg/mod/path@version, and the files as downloaded are kept
+in GOPATH/pkg/mod/cache/download. The go command consults the cache
+before any proxy, so builds that need only cached module versions
+never touch the network.
+
+Modules are downloaded through the proxy named by $GOPROXY.
+If GOPROXY is unset or "off", no downloads are attempted and only
+the module cache is used. See 'go help goproxy' for details.
+	`,
+}
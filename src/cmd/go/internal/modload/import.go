@@ -0,0 +1,345 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modinfo"
+	"cmd/go/internal/module"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/str"
+)
+
+// An ImportMissingError reports that no module in the build list
+// provides the imported package.
+type ImportMissingError struct {
+	ImportPath string
+}
+
+func (e *ImportMissingError) Error() string {
+	return fmt.Sprintf("cannot find module providing package %s", e.ImportPath)
+}
+
+var (
+	pkgModule = make(map[string]module.Version) // import path -> providing module
+	modDirs   = make(map[module.Version]string) // module -> root directory
+)
+
+// Lookup returns the source directory of the package with the given
+// import path, or the empty string if the package is part of the
+// standard library and should be found in GOROOT.
+//
+// If no module in the build list provides the package,
+// Lookup adds a requirement on the latest version of a module
+// that does, and the new requirement is recorded in go.mod
+// when the go command exits.
+func Lookup(path string) (dir string, err error) {
+	LoadBuildList()
+	if !pathInModule(path, Target.Path) && isStandardImportPath(path) {
+		return "", nil
+	}
+	m, dir, err := importFromBuildList(path)
+	if _, ok := err.(*ImportMissingError); ok {
+		m, err = queryPackage(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(os.Stderr, "go: found %s in %s %s\n", path, m.Path, m.Version)
+		modFile.AddRequire(m.Path, m.Version)
+		buildList = nil
+		LoadBuildList()
+		m, dir, err = importFromBuildList(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	pkgModule[path] = m
+	return dir, nil
+}
+
+// isStandardImportPath reports whether path looks like a standard
+// library import path: one whose first element contains no dot.
+func isStandardImportPath(path string) bool {
+	i := strings.Index(path, "/")
+	if i < 0 {
+		i = len(path)
+	}
+	return !strings.Contains(path[:i], ".")
+}
+
+// importFromBuildList finds the module and directory in the build list
+// that provide the package with the given import path.
+func importFromBuildList(path string) (m module.Version, dir string, err error) {
+	var mods []module.Version
+	var dirs []string
+	for _, mod := range buildList {
+		if !pathInModule(path, mod.Path) {
+			continue
+		}
+		root, err := moduleDir(mod)
+		if err != nil {
+			return module.Version{}, "", err
+		}
+		if dir, ok := dirInModule(path, mod.Path, root); ok {
+			mods = append(mods, mod)
+			dirs = append(dirs, dir)
+		}
+	}
+	switch len(mods) {
+	case 0:
+		return module.Version{}, "", &ImportMissingError{path}
+	case 1:
+		return mods[0], dirs[0], nil
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ambiguous import: found %s in multiple modules:", path)
+	for i, m := range mods {
+		fmt.Fprintf(&buf, "\n\t%s", m.Path)
+		if m.Version != "" {
+			fmt.Fprintf(&buf, " %s", m.Version)
+		}
+		fmt.Fprintf(&buf, " (%s)", dirs[i])
+	}
+	return module.Version{}, "", fmt.Errorf("%s", buf.String())
+}
+
+// moduleDir returns the root directory of the module m,
+// downloading it to the module cache if necessary.
+func moduleDir(m module.Version) (string, error) {
+	if m == Target {
+		return ModRoot, nil
+	}
+	if dir, ok := modDirs[m]; ok {
+		return dir, nil
+	}
+	var dir string
+	var err error
+	if r := Replacement(m); r.Path != "" {
+		if r.Version == "" {
+			dir = r.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(ModRoot, dir)
+			}
+		} else {
+			dir, err = modfetch.Download(r)
+		}
+	} else {
+		dir, err = modfetch.Download(m)
+	}
+	if err != nil {
+		return "", err
+	}
+	modDirs[m] = dir
+	return dir, nil
+}
+
+// dirInModule locates the directory that would hold the package
+// with the given import path if it were in the module with path
+// mpath and root mdir. It reports whether that directory contains
+// Go source files and is not part of a nested module.
+func dirInModule(path, mpath, mdir string) (dir string, ok bool) {
+	if path == mpath {
+		dir = mdir
+	} else {
+		dir = filepath.Join(mdir, filepath.FromSlash(path[len(mpath)+1:]))
+	}
+	if !hasGoFiles(dir) {
+		return "", false
+	}
+	// A directory containing its own go.mod, or below one
+	// (up to but not including mdir), belongs to another module.
+	for d := dir; d != mdir && len(d) > len(mdir); d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return "", false
+		}
+	}
+	return dir, true
+}
+
+// hasGoFiles reports whether dir contains any files with names ending in .go.
+func hasGoFiles(dir string) bool {
+	fis, _ := ioutil.ReadDir(dir)
+	for _, fi := range fis {
+		if !fi.IsDir() && strings.HasSuffix(fi.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// queryPackage looks for a module providing the package with the given
+// import path, trying the path itself and then each of its parents
+// as module paths, and returns the latest version of the first
+// module found to contain the package.
+func queryPackage(path string) (module.Version, error) {
+	for p := path; p != "." && p != "/"; p = pathDir(p) {
+		if module.CheckPath(p) != nil {
+			continue
+		}
+		if cfg.BuildV {
+			fmt.Fprintf(os.Stderr, "go: finding %s latest\n", p)
+		}
+		v, err := latestVersion(p)
+		if err != nil {
+			if _, ok := err.(*modfetch.NotFoundError); ok {
+				continue
+			}
+			return module.Version{}, err
+		}
+		m := module.Version{Path: p, Version: v}
+		root, err := moduleDir(m)
+		if err != nil {
+			return module.Version{}, err
+		}
+		if _, ok := dirInModule(path, p, root); ok {
+			return m, nil
+		}
+	}
+	return module.Version{}, &ImportMissingError{path}
+}
+
+// pathDir is like path.Dir but returns "." for a path without slashes.
+func pathDir(p string) string {
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		return p[:i]
+	}
+	return "."
+}
+
+// latestVersion returns the latest known version of the module path:
+// the highest release version if there is one, otherwise the highest
+// pre-release version. Excluded versions are never chosen.
+func latestVersion(path string) (string, error) {
+	repo, err := modfetch.Lookup(path)
+	if err != nil {
+		return "", err
+	}
+	versions, err := repo.Versions()
+	if err != nil {
+		return "", err
+	}
+	var latest, latestPre string
+	for _, v := range versions {
+		if excluded[module.Version{Path: path, Version: v}] {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			latest = v
+		} else {
+			latestPre = v
+		}
+	}
+	if latest == "" {
+		latest = latestPre
+	}
+	if latest == "" {
+		return "", &modfetch.NotFoundError{Path: path, Err: fmt.Errorf("no versions available")}
+	}
+	return latest, nil
+}
+
+// DirImportPath returns the effective import path for dir,
+// provided it is within the main module, or else returns the empty string.
+func DirImportPath(dir string) string {
+	if ModRoot == "" {
+		return ""
+	}
+	readModFile()
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base.Cwd, dir)
+	}
+	dir = filepath.Clean(dir)
+	if dir == ModRoot {
+		return Target.Path
+	}
+	if !str.HasFilePathPrefix(dir, ModRoot) {
+		return ""
+	}
+	for d := dir; d != ModRoot; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			// In a nested module.
+			return ""
+		}
+	}
+	return Target.Path + "/" + filepath.ToSlash(dir[len(ModRoot)+1:])
+}
+
+// PackageModuleInfo returns information about the module
+// providing the package with the given import path.
+func PackageModuleInfo(path string) *modinfo.ModulePublic {
+	m, ok := pkgModule[path]
+	if !ok {
+		return nil
+	}
+	return moduleInfo(m)
+}
+
+// moduleInfo returns information about the module m in the build list.
+func moduleInfo(m module.Version) *modinfo.ModulePublic {
+	if m == Target {
+		return &modinfo.ModulePublic{
+			Path:  m.Path,
+			Main:  true,
+			Dir:   ModRoot,
+			GoMod: filepath.Join(ModRoot, "go.mod"),
+		}
+	}
+
+	info := &modinfo.ModulePublic{
+		Path:     m.Path,
+		Version:  m.Version,
+		Indirect: isIndirect(m.Path),
+	}
+	complete := func(mi *modinfo.ModulePublic) {
+		if mi.Version == "" {
+			return
+		}
+		if repo, err := modfetch.Lookup(mi.Path); err == nil {
+			if rev, err := repo.Stat(mi.Version); err == nil && !rev.Time.IsZero() {
+				t := rev.Time
+				mi.Time = &t
+			}
+		}
+		if dir, err := modfetch.DownloadDir(module.Version{Path: mi.Path, Version: mi.Version}); err == nil {
+			if _, err := os.Stat(dir); err == nil {
+				mi.Dir = dir
+			}
+		}
+		if file, err := modfetch.CachePath(module.Version{Path: mi.Path, Version: mi.Version}, "mod"); err == nil {
+			mi.GoMod = file
+		}
+	}
+
+	if r := Replacement(m); r.Path != "" {
+		info.Replace = &modinfo.ModulePublic{
+			Path:    r.Path,
+			Version: r.Version,
+		}
+		if r.Version == "" {
+			dir := r.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(ModRoot, dir)
+			}
+			info.Replace.Dir = dir
+			info.Replace.GoMod = filepath.Join(dir, "go.mod")
+		} else {
+			complete(info.Replace)
+		}
+		info.Dir = info.Replace.Dir
+		info.GoMod = info.Replace.GoMod
+		return info
+	}
+	complete(info)
+	return info
+}
@@ -0,0 +1,470 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modload resolves packages using modules
+// instead of $GOPATH/src.
+package modload
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modfile"
+	"cmd/go/internal/module"
+	"cmd/go/internal/mvs"
+	"cmd/go/internal/semver"
+	"cmd/go/internal/str"
+)
+
+var (
+	initialized bool
+
+	// CmdModInit is set by 'go mod -init' to enable module mode
+	// outside GOPATH/src even though no go.mod exists yet.
+	CmdModInit bool
+
+	ModRoot  string        // directory containing the main module's go.mod, if any
+	modFile  *modfile.File // parsed go.mod of the main module
+	modData  []byte        // go.mod content as read from disk
+	excluded map[module.Version]bool
+	Target   module.Version // the main module
+
+	gopath string // first GOPATH entry
+
+	buildList []module.Version // current build list; buildList[0] == Target
+)
+
+func init() {
+	load.ModInit = Init
+	load.ModLookup = Lookup
+	load.ModPackageModuleInfo = PackageModuleInfo
+	load.ModImportPaths = ImportPaths
+	load.ModDirImportPath = DirImportPath
+	load.ModBinDir = BinDir
+}
+
+// Enabled reports whether modules are (or must be) enabled.
+func Enabled() bool {
+	Init()
+	return cfg.ModulesEnabled
+}
+
+// HasModRoot reports whether a main module is present.
+func HasModRoot() bool {
+	Init()
+	return ModRoot != ""
+}
+
+// Init determines whether module mode is enabled, locates the root
+// of the current module (if any), and sets environment variables
+// for the module download cache.
+//
+// Module mode is controlled by $GO111MODULE:
+// "off" disables it, "on" always enables it, and the
+// default ("auto" or empty) enables it when the current directory
+// is outside GOPATH/src and GOROOT and inside a tree containing a go.mod file.
+func Init() {
+	if initialized {
+		return
+	}
+	initialized = true
+
+	env := os.Getenv("GO111MODULE")
+	switch env {
+	default:
+		base.Fatalf("go: unknown environment setting GO111MODULE=%s", env)
+	case "", "auto", "on", "off":
+		// ok
+	}
+	if env == "off" {
+		return
+	}
+
+	// GOROOT is treated like a GOPATH entry: the standard library
+	// and commands are always built in GOPATH mode by default.
+	cwd := base.Cwd
+	inGOPATH := str.HasFilePathPrefix(cwd, cfg.GOROOT)
+	for _, p := range filepath.SplitList(cfg.BuildContext.GOPATH) {
+		if p != "" && str.HasFilePathPrefix(cwd, filepath.Join(p, "src")) {
+			inGOPATH = true
+		}
+	}
+	root := findModuleRoot(cwd)
+	if CmdModInit {
+		// The main module will be created in the current directory.
+		root = ""
+	}
+	if env != "on" && (inGOPATH || root == "" && !CmdModInit) {
+		return
+	}
+
+	list := filepath.SplitList(cfg.BuildContext.GOPATH)
+	if len(list) == 0 || list[0] == "" {
+		base.Fatalf("missing $GOPATH")
+	}
+	gopath = list[0]
+	if _, err := os.Stat(filepath.Join(gopath, "go.mod")); err == nil {
+		base.Fatalf("$GOPATH/go.mod exists but should not")
+	}
+
+	cfg.ModulesEnabled = true
+	modfetch.PkgMod = filepath.Join(gopath, "pkg", "mod")
+	if root != "" {
+		ModRoot = root
+		modfetch.GoSumFile = filepath.Join(root, "go.sum")
+		base.AtExit(writeAtExit)
+	}
+}
+
+// findModuleRoot returns the closest directory at or above dir
+// containing a go.mod file, or the empty string if there is none.
+func findModuleRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mustHaveModRoot reports a fatal error if there is no main module.
+func mustHaveModRoot() {
+	Init()
+	if !cfg.ModulesEnabled {
+		base.Fatalf("go: modules disabled inside GOPATH/src by GO111MODULE=auto; see 'go help modules'")
+	}
+	if ModRoot == "" {
+		base.Fatalf("go: cannot find main module; see 'go help modules'")
+	}
+}
+
+// BinDir returns the directory in which 'go install' places commands.
+func BinDir() string {
+	Init()
+	return filepath.Join(gopath, "bin")
+}
+
+// readModFile loads the go.mod file of the main module.
+func readModFile() {
+	if modFile != nil {
+		return
+	}
+	mustHaveModRoot()
+	file := filepath.Join(ModRoot, "go.mod")
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	f, err := modfile.Parse(file, data)
+	if err != nil {
+		base.Fatalf("go: errors parsing go.mod:\n%s", err)
+	}
+	if f.Module == nil {
+		base.Fatalf("go: no module declaration in go.mod.\n\tRun 'go mod -module=path' to set the module path.")
+	}
+	modFile = f
+	modData = data
+	Target = f.Module.Mod
+	excluded = make(map[module.Version]bool)
+	for _, x := range f.Exclude {
+		excluded[x.Mod] = true
+	}
+}
+
+// InitMod creates a go.mod file for the main module in the
+// current directory, with the given module path.
+func InitMod(path string) {
+	Init()
+	if !cfg.ModulesEnabled {
+		base.Fatalf("go: modules disabled inside GOPATH/src by GO111MODULE=auto; see 'go help modules'")
+	}
+	if _, err := os.Stat(filepath.Join(base.Cwd, "go.mod")); err == nil {
+		base.Fatalf("go: %s already exists", filepath.Join(base.Cwd, "go.mod"))
+	}
+	if path == "" {
+		path = guessModulePath(base.Cwd)
+		if path == "" {
+			base.Fatalf("go: cannot determine module path for source directory %s (outside GOPATH, no import comments)\n\tRun 'go mod -init -module=path' to set the module path explicitly.", base.Cwd)
+		}
+	}
+	if ModRoot == "" {
+		base.AtExit(writeAtExit)
+	}
+	ModRoot = base.Cwd
+	modfetch.GoSumFile = filepath.Join(ModRoot, "go.sum")
+	modFile = new(modfile.File)
+	modFile.AddModuleStmt(path)
+	Target = modFile.Module.Mod
+	excluded = make(map[module.Version]bool)
+	modData = nil
+	fmt.Fprintf(os.Stderr, "go: creating new go.mod: module %s\n", path)
+}
+
+// guessModulePath returns a plausible module path for the source
+// directory dir: the directory's location in GOPATH/src, or the import
+// comment of a package in it, if any.
+func guessModulePath(dir string) string {
+	for _, p := range filepath.SplitList(cfg.BuildContext.GOPATH) {
+		src := filepath.Join(p, "src")
+		if p != "" && str.HasFilePathPrefix(dir, src) && dir != src {
+			return filepath.ToSlash(dir[len(src)+1:])
+		}
+	}
+	if bp, err := cfg.BuildContext.ImportDir(dir, build.ImportComment); err == nil && bp.ImportComment != "" {
+		return bp.ImportComment
+	}
+	return ""
+}
+
+// LoadBuildList loads and returns the build list from go.mod.
+// The loading of the build list happens automatically in ImportPaths:
+// LoadBuildList need only be called if ImportPaths is not
+// (typically in commands that care about the module but
+// no particular package).
+func LoadBuildList() []module.Version {
+	if buildList != nil {
+		return buildList
+	}
+	readModFile()
+	list, err := mvs.BuildList(Target, Reqs())
+	if err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	buildList = list
+	return buildList
+}
+
+// BuildList returns the module build list,
+// typically constructed by a previous call to
+// LoadBuildList or ImportPaths.
+// The caller must not modify the returned list.
+func BuildList() []module.Version {
+	return buildList
+}
+
+// SetBuildList sets the module build list.
+// The caller is responsible for ensuring that the list is valid.
+// SetBuildList does not retain a reference to the original list.
+func SetBuildList(list []module.Version) {
+	buildList = append([]module.Version{}, list...)
+}
+
+// Reqs returns the current module requirement graph.
+// Future calls to SetBuildList do not affect the operation
+// of the returned Reqs.
+func Reqs() mvs.Reqs {
+	readModFile()
+	var direct []module.Version
+	for _, r := range modFile.Require {
+		direct = append(direct, excludeUpgrade(r.Mod))
+	}
+	return &mvsReqs{direct: direct}
+}
+
+// mvsReqs implements mvs.Reqs for module semantic versions,
+// applying the replace and exclude directives of the main module.
+type mvsReqs struct {
+	direct []module.Version // requirements of Target
+}
+
+func (r *mvsReqs) Required(mod module.Version) ([]module.Version, error) {
+	if mod == Target {
+		return r.direct, nil
+	}
+	data, path, err := goModData(mod)
+	if err != nil {
+		return nil, err
+	}
+	f, err := modfile.Parse(path+"@"+mod.Version+"/go.mod", data)
+	if err != nil {
+		return nil, err
+	}
+	if f.Module != nil && f.Module.Mod.Path != path {
+		return nil, fmt.Errorf("parsing go.mod for %v: unexpected module path %q", mod, f.Module.Mod.Path)
+	}
+	var list []module.Version
+	for _, req := range f.Require {
+		list = append(list, excludeUpgrade(req.Mod))
+	}
+	return list, nil
+}
+
+func (*mvsReqs) Max(v1, v2 string) string {
+	if v1 != "" && semver.Compare(v1, v2) == -1 {
+		return v2
+	}
+	return v1
+}
+
+// goModData returns the go.mod content for mod, after applying
+// any replacement, along with the module path it should declare.
+func goModData(mod module.Version) (data []byte, path string, err error) {
+	if r := Replacement(mod); r.Path != "" {
+		if r.Version == "" {
+			dir := r.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(ModRoot, dir)
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+			if os.IsNotExist(err) {
+				// A replacement directory without go.mod
+				// has no requirements.
+				return nil, mod.Path, nil
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			// A directory replacement may declare either the
+			// original module path or none at all.
+			if p := modfile.ModulePath(data); p != "" && p != mod.Path {
+				return nil, "", fmt.Errorf("replacement directory %s declares module path %q, not %q", r.Path, p, mod.Path)
+			}
+			return data, mod.Path, nil
+		}
+		data, err := modfetch.GoMod(r.Path, r.Version)
+		return data, r.Path, err
+	}
+	data, err = modfetch.GoMod(mod.Path, mod.Version)
+	return data, mod.Path, err
+}
+
+// Replacement returns the replacement for mod, if any, from go.mod.
+// If there is no replacement for mod, Replacement returns
+// a module.Version with Path == "".
+func Replacement(mod module.Version) module.Version {
+	if modFile == nil {
+		return module.Version{}
+	}
+	var found *modfile.Replace
+	for _, r := range modFile.Replace {
+		if r.Old.Path == mod.Path && (r.Old.Version == "" || r.Old.Version == mod.Version) {
+			found = r // keep going; last one wins
+		}
+	}
+	if found == nil {
+		return module.Version{}
+	}
+	return found.New
+}
+
+// excludeUpgrade returns mod, or, if mod is excluded by the main module,
+// the next higher version of mod that is not excluded.
+// If no such version exists, the excluded version is returned
+// unchanged, and loading it will fail with a clear error.
+func excludeUpgrade(mod module.Version) module.Version {
+	if !excluded[mod] {
+		return mod
+	}
+	repo, err := modfetch.Lookup(mod.Path)
+	if err != nil {
+		return mod
+	}
+	versions, err := repo.Versions()
+	if err != nil {
+		return mod
+	}
+	for _, v := range versions {
+		if semver.Compare(v, mod.Version) > 0 && !excluded[module.Version{Path: mod.Path, Version: v}] {
+			return module.Version{Path: mod.Path, Version: v}
+		}
+	}
+	return mod
+}
+
+var wroteAtExit bool
+
+// writeAtExit writes go.mod and go.sum, if they need updating,
+// as the go command exits.
+func writeAtExit() {
+	if wroteAtExit {
+		return
+	}
+	wroteAtExit = true
+	WriteGoMod()
+	modfetch.WriteGoSum()
+}
+
+// WriteGoMod writes the current build list back to go.mod,
+// if it has changed.
+func WriteGoMod() {
+	if modFile == nil {
+		return
+	}
+	if buildList != nil {
+		// Record in go.mod any module whose selected version
+		// is not implied by the explicit requirements,
+		// such as modules added to satisfy missing imports.
+		min, err := mvs.Req(Target, buildList, directPaths(buildList), Reqs())
+		if err != nil {
+			base.Fatalf("go: %v", err)
+		}
+		indirect := make(map[string]bool)
+		for _, r := range modFile.Require {
+			indirect[r.Mod.Path] = r.Indirect
+		}
+		var list []*modfile.Require
+		for _, m := range min {
+			list = append(list, &modfile.Require{Mod: m, Indirect: indirect[m.Path]})
+		}
+		modFile.SetRequire(list)
+	}
+
+	new := modFile.Format()
+	if bytes.Equal(new, modData) {
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(ModRoot, "go.mod"), new, 0666); err != nil {
+		base.Fatalf("go: %v", err)
+	}
+	modData = new
+}
+
+// directPaths returns the module paths listed explicitly in go.mod
+// that also appear in the build list.
+func directPaths(list []module.Version) []string {
+	inList := make(map[string]bool)
+	for _, m := range list {
+		inList[m.Path] = true
+	}
+	var paths []string
+	for _, r := range modFile.Require {
+		if inList[r.Mod.Path] {
+			paths = append(paths, r.Mod.Path)
+		}
+	}
+	return paths
+}
+
+// isIndirect reports whether go.mod marks the requirement on path as indirect.
+func isIndirect(path string) bool {
+	if modFile == nil {
+		return false
+	}
+	for _, r := range modFile.Require {
+		if r.Mod.Path == path {
+			return r.Indirect
+		}
+	}
+	return false
+}
+
+// pathInModule reports whether the import path is within
+// the module with path mpath.
+func pathInModule(path, mpath string) bool {
+	return path == mpath || strings.HasPrefix(path, mpath+"/")
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cmd/go/internal/load"
+	"cmd/go/internal/modinfo"
+)
+
+// ListModules returns information about the modules named by args,
+// for 'go list -m'. With no arguments it describes the main module.
+// The pattern "all" names the whole build list, and other patterns
+// (possibly containing "...") are matched against module paths.
+func ListModules(args []string) []*modinfo.ModulePublic {
+	LoadBuildList()
+	if len(args) == 0 {
+		return []*modinfo.ModulePublic{moduleInfo(buildList[0])}
+	}
+
+	var mods []*modinfo.ModulePublic
+	for _, arg := range args {
+		if arg == "all" {
+			for _, m := range buildList {
+				mods = append(mods, moduleInfo(m))
+			}
+			continue
+		}
+		if strings.Contains(arg, "...") {
+			match := load.MatchPattern(arg)
+			matched := false
+			for _, m := range buildList {
+				if match(m.Path) {
+					matched = true
+					mods = append(mods, moduleInfo(m))
+				}
+			}
+			if !matched {
+				fmt.Fprintf(os.Stderr, "warning: pattern %q matched no module dependencies\n", arg)
+			}
+			continue
+		}
+		matched := false
+		for _, m := range buildList {
+			if m.Path == arg {
+				matched = true
+				mods = append(mods, moduleInfo(m))
+				break
+			}
+		}
+		if !matched {
+			mods = append(mods, &modinfo.ModulePublic{
+				Path:  arg,
+				Error: &modinfo.ModuleError{Err: fmt.Sprintf("module %q is not a known dependency", arg)},
+			})
+		}
+	}
+	return mods
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modload
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cmd/go/internal/base"
+	"cmd/go/internal/cfg"
+	"cmd/go/internal/load"
+	"cmd/go/internal/module"
+)
+
+// ImportPaths returns the import paths to use for the given
+// command-line patterns, which have already been cleaned by
+// load.ImportPathsNoDotExpansion. The meta-pattern "all" and
+// patterns containing "..." are matched against the packages
+// in the main module and in the modules of the build list.
+func ImportPaths(args []string) []string {
+	LoadBuildList()
+
+	var out []string
+	for _, a := range args {
+		var pkgs []string
+		switch {
+		case a == "all":
+			pkgs = AllPackages()
+		case strings.Contains(a, "...") && build.IsLocalImport(a):
+			pkgs = matchLocalPackages(a)
+		case strings.Contains(a, "..."):
+			pkgs = matchPackages(a)
+		default:
+			out = append(out, a)
+			continue
+		}
+		if len(pkgs) == 0 {
+			fmt.Fprintf(os.Stderr, "warning: %q matched no packages\n", a)
+		}
+		out = append(out, pkgs...)
+	}
+	return out
+}
+
+// matchLocalPackages returns the import paths of the packages
+// matching a local pattern (beginning with ./ or ../) containing "...".
+// The directory part of the pattern must lie within the main module.
+func matchLocalPackages(pattern string) []string {
+	i := strings.Index(pattern, "...")
+	dir, rest := pattern[:i], pattern[i:]
+	j := strings.LastIndex(dir, "/")
+	dir, rest = dir[:j], dir[j+1:]+rest
+	prefix := DirImportPath(dir)
+	if prefix == "" {
+		abs, _ := filepath.Abs(dir)
+		base.Fatalf("go: pattern %s refers to directory %s outside main module", pattern, abs)
+	}
+	return matchPackages(prefix + "/" + rest)
+}
+
+// matchPackages returns the import paths of the packages in the
+// main module, the modules of the build list, and the standard
+// library that match pattern.
+func matchPackages(pattern string) []string {
+	match := load.MatchPattern(pattern)
+	treeCanMatch := load.TreeCanMatchPattern(pattern)
+	have := make(map[string]bool)
+	var pkgs []string
+
+	walk := func(root, importPathRoot string) {
+		root = filepath.Clean(root)
+		filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.IsDir() {
+				return nil
+			}
+			name := importPathRoot
+			if path == root && name == "" {
+				// The root of the standard library is not a package.
+				return nil
+			}
+			if path != root {
+				// Avoid .foo, _foo, testdata, and vendor directory trees.
+				elem := fi.Name()
+				if strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") || elem == "testdata" || elem == "vendor" {
+					return filepath.SkipDir
+				}
+				// Stop at nested modules.
+				if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+					return filepath.SkipDir
+				}
+				name = pathpkgJoin(importPathRoot, filepath.ToSlash(path[len(root)+1:]))
+			}
+			if !treeCanMatch(name) {
+				return filepath.SkipDir
+			}
+			if have[name] || !match(name) {
+				return nil
+			}
+			if _, err := cfg.BuildContext.ImportDir(path, 0); err != nil {
+				if _, noGo := err.(*build.NoGoError); noGo {
+					return nil
+				}
+			}
+			have[name] = true
+			pkgs = append(pkgs, name)
+			return nil
+		})
+	}
+
+	if isStandardImportPath(pattern) && !pathInModule(pattern, Target.Path) {
+		walk(cfg.GOROOTsrc, "")
+	}
+	for _, mod := range buildList {
+		if !treeCanMatch(mod.Path) {
+			continue
+		}
+		root, err := moduleDir(mod)
+		if err != nil {
+			base.Errorf("go: %v", err)
+			continue
+		}
+		walk(root, mod.Path)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// pathpkgJoin joins import path elements, treating an empty
+// root as the root of the standard library.
+func pathpkgJoin(root, elem string) string {
+	if root == "" {
+		return elem
+	}
+	return root + "/" + elem
+}
+
+// AllPackages returns the packages of the main module together with
+// the packages outside the standard library that they import,
+// directly or indirectly, including imports of tests of the main
+// module's packages.
+func AllPackages() []string {
+	LoadBuildList()
+	var queue []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if seen[path] || path == "C" || build.IsLocalImport(path) {
+			return
+		}
+		if isStandardImportPath(path) && !pathInModule(path, Target.Path) {
+			return
+		}
+		seen[path] = true
+		queue = append(queue, path)
+	}
+	for _, path := range matchPackages(Target.Path + "/...") {
+		add(path)
+	}
+
+	var pkgs []string
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		dir, err := Lookup(path)
+		if err != nil {
+			base.Errorf("go: %v", err)
+			continue
+		}
+		bp, err := cfg.BuildContext.ImportDir(dir, 0)
+		if err != nil {
+			if _, noGo := err.(*build.NoGoError); noGo {
+				continue
+			}
+		}
+		pkgs = append(pkgs, path)
+		for _, imp := range bp.Imports {
+			add(imp)
+		}
+		if pathInModule(path, Target.Path) {
+			for _, imp := range bp.TestImports {
+				add(imp)
+			}
+			for _, imp := range bp.XTestImports {
+				add(imp)
+			}
+		}
+	}
+	base.ExitIfErrors()
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// usedModules returns the modules in the build list that provide
+// some package in AllPackages, along with the main module.
+func usedModules() map[module.Version]bool {
+	used := map[module.Version]bool{Target: true}
+	for _, path := range AllPackages() {
+		if m, ok := pkgModule[path]; ok {
+			used[m] = true
+		}
+	}
+	return used
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mvs implements Minimal Version Selection.
+// See https://research.swtch.com/vgo-mvs.
+package mvs
+
+import (
+	"fmt"
+	"sort"
+
+	"cmd/go/internal/module"
+)
+
+// A Reqs is the requirement graph on which Minimal Version Selection (MVS) operates.
+//
+// The version strings are opaque except for the special version "none"
+// (see the documentation for module.Version). In particular, MVS does not
+// assume that the version strings are semantic versions; instead, the Max method
+// gives access to the comparison operation.
+type Reqs interface {
+	// Required returns the module versions explicitly required by m itself.
+	// The caller must not modify the returned list.
+	Required(m module.Version) ([]module.Version, error)
+
+	// Max returns the maximum of v1 and v2 (it returns either v1 or v2).
+	//
+	// For all versions v, Max(v, "none") must be v,
+	// and for the target passed as the first argument to MVS functions,
+	// Max(target, v) must be target.
+	//
+	// Note that v1 < v2 can be written Max(v1, v2) != v1
+	// and similarly v1 <= v2 can be written Max(v1, v2) == v2.
+	Max(v1, v2 string) string
+}
+
+// A MissingModuleError reports that a module in the requirement
+// graph could not be loaded.
+type MissingModuleError struct {
+	Module module.Version
+	Err    error
+}
+
+func (e *MissingModuleError) Error() string {
+	return fmt.Sprintf("missing module: %v: %v", e.Module, e.Err)
+}
+
+// BuildList returns the build list for the target module.
+// The first element is the target itself, followed by the
+// selected version of every other module in the requirement graph,
+// sorted by module path.
+func BuildList(target module.Version, reqs Reqs) ([]module.Version, error) {
+	// Explore the requirement graph breadth-first,
+	// recording the maximum version required of each module path.
+	min := map[string]string{target.Path: target.Version}
+	seen := map[module.Version]bool{target: true}
+	queue := []module.Version{target}
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		required, err := reqs.Required(m)
+		if err != nil {
+			return nil, &MissingModuleError{m, err}
+		}
+		for _, r := range required {
+			if r.Version == "none" {
+				continue
+			}
+			if v, ok := min[r.Path]; !ok || reqs.Max(v, r.Version) != v {
+				min[r.Path] = r.Version
+			}
+			if !seen[r] {
+				seen[r] = true
+				queue = append(queue, r)
+			}
+		}
+	}
+
+	// Construct the list by traversing the graph again,
+	// this time following only the selected version of each module.
+	// A module reachable only from versions that were not selected
+	// (m@v1 requires n@v1, but m@v2 was chosen and does not)
+	// is not part of the build.
+	list := []module.Version{target}
+	listed := map[string]bool{target.Path: true}
+	for i := 0; i < len(list); i++ {
+		required, err := reqs.Required(list[i])
+		if err != nil {
+			return nil, &MissingModuleError{list[i], err}
+		}
+		for _, r := range required {
+			if r.Version == "none" || listed[r.Path] {
+				continue
+			}
+			listed[r.Path] = true
+			list = append(list, module.Version{Path: r.Path, Version: min[r.Path]})
+		}
+	}
+	tail := list[1:]
+	sort.Slice(tail, func(i, j int) bool {
+		return tail[i].Path < tail[j].Path
+	})
+	return list, nil
+}
+
+// Req returns the minimal requirement list for the target module
+// that results in the given build list, with the constraint that all
+// module paths listed in base must appear in the returned list.
+func Req(target module.Version, list []module.Version, base []string, reqs Reqs) ([]module.Version, error) {
+	// Compute postorder of the requirement graph below the build list,
+	// caching the requirements of each module version along the way.
+	var postorder []module.Version
+	reqCache := make(map[module.Version][]module.Version)
+	var walk func(module.Version) error
+	walk = func(m module.Version) error {
+		if _, ok := reqCache[m]; ok {
+			return nil
+		}
+		required, err := reqs.Required(m)
+		if err != nil {
+			return &MissingModuleError{m, err}
+		}
+		reqCache[m] = required
+		for _, r := range required {
+			if r.Version == "none" {
+				continue
+			}
+			if err := walk(r); err != nil {
+				return err
+			}
+		}
+		postorder = append(postorder, m)
+		return nil
+	}
+	max := make(map[string]string)
+	for _, m := range list {
+		max[m.Path] = m.Version
+		if m.Path == target.Path {
+			continue
+		}
+		if err := walk(m); err != nil {
+			return nil, err
+		}
+	}
+
+	// have records the module versions implied by the requirements chosen so far.
+	have := make(map[module.Version]bool)
+	var mark func(module.Version)
+	mark = func(m module.Version) {
+		if have[m] {
+			return
+		}
+		have[m] = true
+		for _, r := range reqCache[m] {
+			mark(r)
+		}
+	}
+
+	// The modules named in base are always listed.
+	// Walking the postorder in reverse then considers each module
+	// before anything it requires, so that a module is listed
+	// only if nothing already listed implies its selected version.
+	var min []module.Version
+	for _, path := range base {
+		m := module.Version{Path: path, Version: max[path]}
+		min = append(min, m)
+		mark(m)
+	}
+	for i := len(postorder) - 1; i >= 0; i-- {
+		m := postorder[i]
+		if max[m.Path] != m.Version {
+			// Older version, not selected.
+			continue
+		}
+		if !have[m] {
+			min = append(min, m)
+			mark(m)
+		}
+	}
+	sort.Slice(min, func(i, j int) bool {
+		return min[i].Path < min[j].Path
+	})
+	return min, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mvs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cmd/go/internal/module"
+)
+
+// reqsMap implements Reqs with a map from "path version" to requirements,
+// where versions are compared as plain strings.
+type reqsMap map[module.Version][]module.Version
+
+func (r reqsMap) Max(v1, v2 string) string {
+	if v1 == "none" || v2 == "" {
+		return v2
+	}
+	if v2 == "none" || v1 == "" {
+		return v1
+	}
+	if v1 < v2 {
+		return v2
+	}
+	return v1
+}
+
+func (r reqsMap) Required(m module.Version) ([]module.Version, error) {
+	rr, ok := r[m]
+	if !ok {
+		return nil, fmt.Errorf("unknown module %v", m)
+	}
+	return rr, nil
+}
+
+// parseGraph parses lines of the form "A1: B2 C1" into a reqsMap,
+// where each module version is a single upper-case letter
+// followed by a version number.
+func parseGraph(text string) reqsMap {
+	reqs := make(reqsMap)
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		i := strings.Index(line, ":")
+		m := mv(strings.TrimSpace(line[:i]))
+		list := []module.Version{}
+		for _, f := range strings.Fields(line[i+1:]) {
+			list = append(list, mv(f))
+		}
+		reqs[m] = list
+	}
+	return reqs
+}
+
+func mv(s string) module.Version {
+	return module.Version{Path: s[:1], Version: s[1:]}
+}
+
+func mvList(s string) []module.Version {
+	var list []module.Version
+	for _, f := range strings.Fields(s) {
+		list = append(list, mv(f))
+	}
+	return list
+}
+
+var buildListTests = []struct {
+	name  string
+	graph string
+	want  string
+}{
+	{
+		name: "blog",
+		graph: `
+			A: B1 C2
+			B1: D3
+			C1: D2
+			C2: D4
+			C3: D5
+			C4: G1
+			D2: E1
+			D3: E2
+			D4: E2 F1
+			D5: E2
+			G1: C4
+			E1:
+			E2:
+			E3:
+			F1:
+		`,
+		want: "A B1 C2 D4 E2 F1",
+	},
+	{
+		name: "unselected",
+		graph: `
+			A: B1 C1
+			B1: D1
+			C1: B2
+			B2:
+			D1:
+		`,
+		want: "A B2 C1",
+	},
+	{
+		name: "cycle",
+		graph: `
+			A: B1
+			B1: C1
+			C1: B2
+			B2: C1
+		`,
+		want: "A B2 C1",
+	},
+}
+
+func TestBuildList(t *testing.T) {
+	for _, tt := range buildListTests {
+		reqs := parseGraph(tt.graph)
+		list, err := BuildList(mv("A"), reqs)
+		if err != nil {
+			t.Errorf("%s: BuildList: %v", tt.name, err)
+			continue
+		}
+		if want := mvList(tt.want); !reflect.DeepEqual(list, want) {
+			t.Errorf("%s: BuildList = %v, want %v", tt.name, list, want)
+		}
+	}
+}
+
+func TestBuildListMissing(t *testing.T) {
+	reqs := parseGraph(`
+		A: B1
+	`)
+	_, err := BuildList(mv("A"), reqs)
+	if _, ok := err.(*MissingModuleError); !ok {
+		t.Fatalf("BuildList = %v, want *MissingModuleError", err)
+	}
+}
+
+func TestReq(t *testing.T) {
+	reqs := parseGraph(`
+		A: B1 C1 D1
+		B1: D1
+		C1: D2
+		D1:
+		D2:
+		E1:
+	`)
+	list, err := BuildList(mv("A"), reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	min, err := Req(mv("A"), list, nil, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mvList("B1 C1"); !reflect.DeepEqual(min, want) {
+		t.Errorf("Req = %v, want %v", min, want)
+	}
+
+	min, err = Req(mv("A"), list, []string{"D"}, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mvList("B1 C1 D2"); !reflect.DeepEqual(min, want) {
+		t.Errorf("Req with base D = %v, want %v", min, want)
+	}
+}
@@ -249,10 +249,28 @@ const testFlag2 = `
 	-failfast
 	    Do not start new tests after the first test failure.
 
+	-fuzz regexp
+	    Run the fuzz target matching the regular expression. When specified,
+	    the command line argument must match exactly one package, and regexp
+	    must match exactly one fuzz target within that package. The compiler
+	    instruments the package with coverage counters, which guide the
+	    fuzzer; -cover is not needed. Seed inputs are read from
+	    testdata/fuzz/<FuzzTargetName> and from F.Add; inputs that cause
+	    a failure are minimized and written to that directory.
+	    Interesting inputs found while fuzzing are kept in the build cache.
+	    By default, fuzzing runs until it finds a failure, and the -timeout
+	    flag does not apply to it.
+
+	-fuzztime t
+	    Run enough iterations of the fuzz target to take t, specified as a
+	    time.Duration (for example, -fuzztime 1h30s).
+	    The default is to run forever.
+
 	-list regexp
-	    List tests, benchmarks, or examples matching the regular expression.
-	    No tests, benchmarks or examples will be run. This will only
-	    list top-level tests. No subtest or subbenchmarks will be shown.
+	    List tests, benchmarks, fuzz targets, or examples matching the regular
+	    expression. No tests, benchmarks, fuzz targets, or examples will be
+	    run. This will only list top-level tests. No subtest or subbenchmarks
+	    will be shown.
 
 	-parallel n
 	    Allow parallel execution of test functions that call t.Parallel.
@@ -429,6 +447,15 @@ A benchmark function is one named BenchmarkXxx and should have the signature,
 
 	func BenchmarkXxx(b *testing.B) { ... }
 
+A fuzz target is one named FuzzXxx and should have the signature,
+
+	func FuzzXxx(f *testing.F) { ... }
+
+A fuzz target adds seed inputs with f.Add and calls f.Fuzz with a function
+whose first parameter is a *testing.T and whose other parameters are the
+values to fuzz. Without the -fuzz flag, the function is run once for each
+seed input, including those stored in testdata/fuzz/FuzzXxx.
+
 An example function is similar to a test function but, instead of using
 *testing.T to report success or failure, prints output to os.Stdout.
 If the last comment in the function starts with "Output:" then the output
@@ -476,6 +503,7 @@ See the documentation of the testing package for more information.
 
 var (
 	testC            bool            // -c flag
+	testFuzz         string          // -fuzz flag
 	testCover        bool            // -cover flag
 	testCoverMode    string          // -covermode flag
 	testCoverPaths   []string        // -coverpkg flag
@@ -548,6 +576,15 @@ func runTest(cmd *base.Command, args []string) {
 	if testProfile != "" && len(pkgs) != 1 {
 		base.Fatalf("cannot use %s flag with multiple packages", testProfile)
 	}
+	if testFuzz != "" && len(pkgs) != 1 {
+		base.Fatalf("cannot use -fuzz flag with multiple packages")
+	}
+	if testFuzz != "" {
+		// Fuzzing is guided by the coverage counters the compiler
+		// inserts into the package under test.
+		p := pkgs[0]
+		p.Internal.Gcflags = append(p.Internal.Gcflags[:len(p.Internal.Gcflags):len(p.Internal.Gcflags)], "-d=libfuzzer")
+	}
 	initCoverProfile()
 	defer closeCoverProfile()
 
@@ -562,6 +599,11 @@ func runTest(cmd *base.Command, args []string) {
 		// Let it have one century (almost) before we kill it.
 		testKillTimeout = 100 * 365 * 24 * time.Hour
 	}
+	if testFuzz != "" {
+		// The -timeout flag does not apply to fuzzing, which runs until
+		// it finds a failure or -fuzztime elapses.
+		testKillTimeout = 100 * 365 * 24 * time.Hour
+	}
 
 	// show passing test output (after buffering) with -v flag.
 	// must buffer because tests are running in parallel, and
@@ -573,6 +615,12 @@ func runTest(cmd *base.Command, args []string) {
 		testC = true
 	}
 
+	// Tell the fuzzer where to keep the interesting inputs it generates,
+	// so that later runs can start from them.
+	if dir := cache.DefaultDir(); testFuzz != "" && dir != "off" {
+		testArgs = append(testArgs, "-test.fuzzcachedir="+filepath.Join(dir, "fuzz", pkgs[0].ImportPath))
+	}
+
 	// Read testcache expiration time, if present.
 	// (We implement go clean -testcache by writing an expiration date
 	// instead of searching out and deleting test result cache entries.)
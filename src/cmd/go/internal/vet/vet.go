@@ -15,7 +15,7 @@ import (
 var CmdVet = &base.Command{
 	Run:         runVet,
 	CustomFlags: true,
-	UsageLine:   "vet [-n] [-x] [build flags] [vet flags] [packages]",
+	UsageLine:   "vet [-n] [-x] [-vettool prog] [build flags] [vet flags] [packages]",
 	Short:       "report likely mistakes in packages",
 	Long: `
 Vet runs the Go vet command on the packages named by the import paths.
@@ -26,6 +26,17 @@ For more about specifying packages, see 'go help packages'.
 The -n flag prints commands that would be executed.
 The -x flag prints commands as they are executed.
 
+The -vettool=prog flag selects a different analysis tool with alternative
+or additional checks, typically a program written using the
+go/analysis/multichecker or go/analysis/unitchecker package.
+For example, a checker in the current module could be run using:
+
+  go build -o mychecker ./cmd/mychecker
+  go vet -vettool=./mychecker ./...
+
+Only the flags that the tool reports in response to its -flags flag
+are accepted and passed to it.
+
 The build flags supported by go vet are those that control package resolution
 and execution, such as -n, -x, -v, -tags, and -toolexec.
 For more about these flags, see 'go help build'.
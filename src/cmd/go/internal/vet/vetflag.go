@@ -5,9 +5,13 @@
 package vet
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"cmd/go/internal/base"
@@ -15,70 +19,114 @@ import (
 	"cmd/go/internal/work"
 )
 
-const cmd = "vet"
+// go vet flag processing
+//
+// We query the flags of the tool specified by -vettool and accept any
+// of those flags plus any flag valid for 'go build'. The tool must
+// support -flags, which prints a description of its flags in JSON to
+// stdout.
 
-// vetFlagDefn is the set of flags we process.
-var vetFlagDefn = []*cmdflag.Defn{
-	// Note: Some flags, in particular -tags and -v, are known to
-	// vet but also defined as build flags. This works fine, so we
-	// don't define them here but use AddBuildFlags to init them.
-	// However some, like -x, are known to the build but not
-	// to vet. We handle them in vetFlags.
+// vetTool specifies the vet command to run.
+// Any tool that supports the vet command-line protocol may be
+// supplied; see go/analysis/unitchecker for one implementation.
+//
+// The default behavior (vetTool=="") runs 'go tool vet'.
+var vetTool string // -vettool
 
-	// local.
-	{Name: "all", BoolVar: new(bool)},
-	{Name: "asmdecl", BoolVar: new(bool)},
-	{Name: "assign", BoolVar: new(bool)},
-	{Name: "atomic", BoolVar: new(bool)},
-	{Name: "bool", BoolVar: new(bool)},
-	{Name: "buildtags", BoolVar: new(bool)},
-	{Name: "cgocall", BoolVar: new(bool)},
-	{Name: "composites", BoolVar: new(bool)},
-	{Name: "copylocks", BoolVar: new(bool)},
-	{Name: "httpresponse", BoolVar: new(bool)},
-	{Name: "lostcancel", BoolVar: new(bool)},
-	{Name: "methods", BoolVar: new(bool)},
-	{Name: "nilfunc", BoolVar: new(bool)},
-	{Name: "printf", BoolVar: new(bool)},
-	{Name: "printfuncs"},
-	{Name: "rangeloops", BoolVar: new(bool)},
-	{Name: "shadow", BoolVar: new(bool)},
-	{Name: "shadowstrict", BoolVar: new(bool)},
-	{Name: "shift", BoolVar: new(bool)},
-	{Name: "source", BoolVar: new(bool)},
-	{Name: "structtags", BoolVar: new(bool)},
-	{Name: "tests", BoolVar: new(bool)},
-	{Name: "unreachable", BoolVar: new(bool)},
-	{Name: "unsafeptr", BoolVar: new(bool)},
-	{Name: "unusedfuncs"},
-	{Name: "unusedresult", BoolVar: new(bool)},
-	{Name: "unusedstringmethods"},
+func init() {
+	// Extract -vettool by ad hoc flag processing:
+	// its value is needed even before we can declare
+	// the flags available during main flag processing.
+	for i, arg := range os.Args {
+		if arg == "-vettool" || arg == "--vettool" {
+			if i+1 < len(os.Args) {
+				vetTool = os.Args[i+1]
+			}
+			break
+		} else if strings.HasPrefix(arg, "-vettool=") ||
+			strings.HasPrefix(arg, "--vettool=") {
+			vetTool = arg[strings.IndexByte(arg, '=')+1:]
+			break
+		}
+	}
 }
 
-var vetTool string
+// vetFlags processes the command line, splitting it at the first non-flag
+// into the list of flags and list of packages.
+func vetFlags(args []string) (passToVet, packageNames []string) {
+	// Query the vet command for its flags.
+	tool := vetTool
+	if tool != "" {
+		var err error
+		tool, err = filepath.Abs(tool)
+		if err != nil {
+			base.Fatalf("%v", err)
+		}
+	} else {
+		tool = base.Tool("vet")
+	}
+	out := new(bytes.Buffer)
+	vetcmd := exec.Command(tool, "-flags")
+	vetcmd.Stdout = out
+	if err := vetcmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "go vet: can't execute %s -flags: %v\n", tool, err)
+		os.Exit(2)
+	}
+	var analysisFlags []struct {
+		Name  string
+		Bool  bool
+		Usage string
+	}
+	if err := json.Unmarshal(out.Bytes(), &analysisFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "go vet: can't unmarshal JSON from %s -flags: %v\n", tool, err)
+		os.Exit(2)
+	}
 
-// add build flags to vetFlagDefn.
-func init() {
+	// Add vet's flags to vetFlagDefn.
+	//
+	// Some flags, in particular -tags and -v, are known to vet but
+	// also defined as build flags. This works fine, so we don't
+	// define them here but use AddBuildFlags to init them.
+	// However some, like -x, are known to the build but not to vet.
+	isVetFlag := make(map[string]bool, len(analysisFlags))
+	var vetFlagDefn []*cmdflag.Defn
+	for _, f := range analysisFlags {
+		isVetFlag[f.Name] = true
+		if f.Bool {
+			vetFlagDefn = append(vetFlagDefn, &cmdflag.Defn{Name: f.Name, BoolVar: new(bool)})
+		} else {
+			vetFlagDefn = append(vetFlagDefn, &cmdflag.Defn{Name: f.Name})
+		}
+	}
+
+	// Add build flags to vetFlagDefn.
 	var cmd base.Command
 	work.AddBuildFlags(&cmd)
-	cmd.Flag.StringVar(&vetTool, "vettool", "", "path to vet tool binary") // for cmd/vet tests; undocumented for now
+	cmd.Flag.StringVar(&vetTool, "vettool", "", "path to vet tool binary")
 	cmd.Flag.VisitAll(func(f *flag.Flag) {
+		if isVetFlag[f.Name] {
+			// Defined by vet; passed through below.
+			for _, defn := range vetFlagDefn {
+				if defn.Name == f.Name {
+					defn.BoolVar = nil
+					defn.Value = f.Value
+				}
+			}
+			return
+		}
 		vetFlagDefn = append(vetFlagDefn, &cmdflag.Defn{
 			Name:  f.Name,
 			Value: f.Value,
 		})
 	})
-}
 
-// vetFlags processes the command line, splitting it at the first non-flag
-// into the list of flags and list of packages.
-func vetFlags(args []string) (passToVet, packageNames []string) {
+	// Process args.
 	for i := 0; i < len(args); i++ {
 		if !strings.HasPrefix(args[i], "-") {
 			return args[:i], args[i:]
 		}
 
-		f, value, extraWord := cmdflag.Parse(cmd, vetFlagDefn, args, i)
+		f, value, extraWord := cmdflag.Parse("vet", vetFlagDefn, args, i)
 		if f == nil {
 			fmt.Fprintf(os.Stderr, "vet: flag %q not defined\n", args[i])
 			fmt.Fprintf(os.Stderr, "Run \"go help vet\" for more information\n")
@@ -88,17 +136,16 @@ func vetFlags(args []string) (passToVet, packageNames []string) {
 			if err := f.Value.Set(value); err != nil {
 				base.Fatalf("invalid flag argument for -%s: %v", f.Name, err)
 			}
-			switch f.Name {
-			// Flags known to the build but not to vet, so must be dropped.
-			case "x", "n", "vettool", "compiler":
-				if extraWord {
-					args = append(args[:i], args[i+2:]...)
-					extraWord = false
-				} else {
-					args = append(args[:i], args[i+1:]...)
-				}
-				i--
+		}
+		if !isVetFlag[f.Name] {
+			// Flags known to the build but not to vet must be dropped.
+			if extraWord {
+				args = append(args[:i], args[i+2:]...)
+				extraWord = false
+			} else {
+				args = append(args[:i], args[i+1:]...)
 			}
+			i--
 		}
 		if extraWord {
 			i++
@@ -420,6 +420,11 @@ func InstallPackages(args []string, forGet bool) {
 	pkgs := pkgsFilter(load.PackagesForBuild(args))
 
 	for _, p := range pkgs {
+		if p.Target == "" && p.Module != nil && p.Name != "main" {
+			// Non-main packages in modules are built
+			// into the build cache but never installed.
+			continue
+		}
 		if p.Target == "" && (!p.Standard || p.ImportPath != "unsafe") {
 			switch {
 			case p.Internal.GobinSubdir:
@@ -286,6 +286,9 @@ func (b *Builder) buildActionID(a *Action) cache.ActionID {
 	for _, file := range inputFiles {
 		fmt.Fprintf(h, "file %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
 	}
+	for _, file := range p.EmbedFiles {
+		fmt.Fprintf(h, "embed %s %s\n", file, b.fileHash(filepath.Join(p.Dir, file)))
+	}
 	for _, a1 := range a.Deps {
 		p1 := a1.Package
 		if p1 != nil {
@@ -538,9 +541,28 @@ func (b *Builder) build(a *Action) (err error) {
 		fmt.Fprintf(&icfg, "packagefile %s=%s\n", p1.ImportPath, a1.built)
 	}
 
+	// Prepare Go embed config if needed.
+	var embedcfg []byte
+	if len(p.Internal.Embed) > 0 {
+		var embed struct {
+			Patterns map[string][]string
+			Files    map[string]string
+		}
+		embed.Patterns = p.Internal.Embed
+		embed.Files = make(map[string]string)
+		for _, file := range p.EmbedFiles {
+			embed.Files[file] = filepath.Join(p.Dir, file)
+		}
+		js, err := json.MarshalIndent(&embed, "", "\t")
+		if err != nil {
+			return fmt.Errorf("marshal embedcfg: %v", err)
+		}
+		embedcfg = js
+	}
+
 	// Compile Go.
 	objpkg := objdir + "_pkg_.a"
-	ofile, out, err := BuildToolchain.gc(b, a, objpkg, icfg.Bytes(), len(sfiles) > 0, gofiles)
+	ofile, out, err := BuildToolchain.gc(b, a, objpkg, icfg.Bytes(), embedcfg, len(sfiles) > 0, gofiles)
 	if len(out) > 0 {
 		b.showOutput(a, a.Package.Dir, a.Package.Desc(), b.processOutput(out))
 		if err != nil {
@@ -738,13 +760,18 @@ func (b *Builder) loadCachedVet(a *Action) bool {
 }
 
 type vetConfig struct {
-	Compiler    string
-	Dir         string
-	GoFiles     []string
-	ImportMap   map[string]string
-	PackageFile map[string]string
-	Standard    map[string]bool
-	ImportPath  string
+	ID          string            // package ID (example: "fmt [fmt.test]")
+	Compiler    string            // compiler name (gc, gccgo)
+	Dir         string            // directory containing package
+	ImportPath  string            // canonical import path ("package path")
+	GoFiles     []string          // absolute names of Go source files
+	NonGoFiles  []string          // absolute names of assembly and other source files
+	ImportMap   map[string]string // map import path in source code to package path
+	PackageFile map[string]string // map package path to .a file with export data
+	Standard    map[string]bool   // map package path to whether it's in the standard library
+	PackageVetx map[string]string // map package path to vetx data from earlier vet run
+	VetxOnly    bool              // only compute vetx data; don't report detected problems
+	VetxOutput  string            // write vetx data to this output file
 
 	SucceedOnTypecheckFailure bool
 }
@@ -755,9 +782,11 @@ func buildVetConfig(a *Action, gofiles []string) {
 	// so that we can reformat them relative to the directory
 	// in which the go command is invoked.
 	vcfg := &vetConfig{
+		ID:          a.Package.ImportPath,
 		Compiler:    cfg.BuildToolchainName,
 		Dir:         a.Package.Dir,
 		GoFiles:     mkAbsFiles(a.Package.Dir, gofiles),
+		NonGoFiles:  mkAbsFiles(a.Package.Dir, a.Package.SFiles),
 		ImportPath:  a.Package.ImportPath,
 		ImportMap:   make(map[string]string),
 		PackageFile: make(map[string]string),
@@ -806,14 +835,51 @@ var VetFlags []string
 func (b *Builder) vet(a *Action) error {
 	// a.Deps[0] is the build of the package being vetted.
 	// a.Deps[1] is the build of the "fmt" package.
+	// a.Deps[2:] are the vet actions for the package's imports.
+
+	a.Failed = false // vet of a dependency may have failed but we can still succeed
+
+	if a.Deps[0].Failed {
+		// The build of the package failed; there is nothing to vet.
+		return nil
+	}
 
 	vcfg := a.Deps[0].vetCfg
 	if vcfg == nil {
 		// Vet config should only be missing if the build failed.
-		if !a.Deps[0].Failed {
-			return fmt.Errorf("vet config not found")
+		return fmt.Errorf("vet config not found")
+	}
+
+	vcfg.VetxOnly = a.VetxOnly
+	vcfg.VetxOutput = a.Objdir + "vet.out"
+	vcfg.PackageVetx = make(map[string]string)
+
+	tool := VetTool
+	h := cache.NewHash("vet " + a.Package.ImportPath)
+	if tool == "" {
+		tool = base.Tool("vet")
+		fmt.Fprintf(h, "vet %q\n", b.toolID("vet"))
+	} else {
+		fmt.Fprintf(h, "vettool %s\n", b.fileHash(tool))
+	}
+	fmt.Fprintf(h, "vetflags %q\n", VetFlags)
+	fmt.Fprintf(h, "pkg %q\n", a.Deps[0].actionID)
+	for _, a1 := range a.Deps {
+		if a1.Mode == "vet" && a1.built != "" {
+			fmt.Fprintf(h, "vetout %q %s\n", a1.Package.ImportPath, b.fileHash(a1.built))
+			vcfg.PackageVetx[a1.Package.ImportPath] = a1.built
+		}
+	}
+	key := cache.ActionID(h.Sum())
+
+	// Facts-only runs report nothing, so their output can be reused.
+	if vcfg.VetxOnly && !cfg.BuildA {
+		if c := cache.Default(); c != nil {
+			if file, err := cachedFile(c, key); err == nil {
+				a.built = file
+				return nil
+			}
 		}
-		return nil
 	}
 
 	if vcfg.ImportMap["fmt"] == "" {
@@ -849,11 +915,34 @@ func (b *Builder) vet(a *Action) error {
 	}
 
 	p := a.Package
-	tool := VetTool
-	if tool == "" {
-		tool = base.Tool("vet")
+	if err := b.run(a, p.Dir, p.ImportPath, env, cfg.BuildToolexec, tool, VetFlags, a.Objdir+"vet.cfg"); err != nil {
+		return err
+	}
+
+	// TODO: Also cache the diagnostics of complete vet runs.
+	if f, err := os.Open(vcfg.VetxOutput); err == nil {
+		a.built = vcfg.VetxOutput
+		if c := cache.Default(); c != nil {
+			c.Put(key, f)
+		}
+		f.Close()
+	}
+	return nil
+}
+
+// cachedFile returns the name of the file holding the cached output
+// for the action id, or an error if there is none.
+func cachedFile(c *cache.Cache, id cache.ActionID) (string, error) {
+	entry, err := c.Get(id)
+	if err != nil {
+		return "", err
+	}
+	out := c.OutputFile(entry.OutputID)
+	info, err := os.Stat(out)
+	if err != nil || info.Size() != entry.Size {
+		return "", fmt.Errorf("not in cache")
 	}
-	return b.run(a, p.Dir, p.ImportPath, env, cfg.BuildToolexec, tool, VetFlags, a.Objdir+"vet.cfg")
+	return out, nil
 }
 
 // linkActionID computes the action ID for a link action.
@@ -1764,7 +1853,7 @@ func mkAbs(dir, f string) string {
 type toolchain interface {
 	// gc runs the compiler in a specific directory on a set of files
 	// and returns the name of the generated output file.
-	gc(b *Builder, a *Action, archive string, importcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error)
+	gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error)
 	// cc runs the toolchain's C compiler in a directory on a C file
 	// to produce an output file.
 	cc(b *Builder, a *Action, ofile, cfile string) error
@@ -1801,7 +1890,7 @@ func (noToolchain) linker() string {
 	return ""
 }
 
-func (noToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error) {
+func (noToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, asmhdr bool, gofiles []string) (ofile string, out []byte, err error) {
 	return "", nil, noCompiler()
 }
 
@@ -2457,7 +2546,7 @@ func (b *Builder) swigDoIntSize(objdir string) (intsize string, err error) {
 
 	p := load.GoFilesPackage(srcs)
 
-	if _, _, e := BuildToolchain.gc(b, &Action{Mode: "swigDoIntSize", Package: p, Objdir: objdir}, "", nil, false, srcs); e != nil {
+	if _, _, e := BuildToolchain.gc(b, &Action{Mode: "swigDoIntSize", Package: p, Objdir: objdir}, "", nil, nil, false, srcs); e != nil {
 		return "32", nil
 	}
 	return "64", nil
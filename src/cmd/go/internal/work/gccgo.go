@@ -51,9 +51,12 @@ func checkGccgoBin() {
 	os.Exit(2)
 }
 
-func (tools gccgoToolchain) gc(b *Builder, a *Action, archive string, importcfg []byte, asmhdr bool, gofiles []string) (ofile string, output []byte, err error) {
+func (tools gccgoToolchain) gc(b *Builder, a *Action, archive string, importcfg, embedcfg []byte, asmhdr bool, gofiles []string) (ofile string, output []byte, err error) {
 	p := a.Package
 	objdir := a.Objdir
+	if embedcfg != nil {
+		return "", nil, fmt.Errorf("%s: gccgo does not support //go:embed", p.ImportPath)
+	}
 	out := "_go_.o"
 	ofile = objdir + out
 	gcargs := []string{"-g"}
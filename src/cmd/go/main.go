@@ -27,6 +27,9 @@ import (
 	"cmd/go/internal/get"
 	"cmd/go/internal/help"
 	"cmd/go/internal/list"
+	"cmd/go/internal/modcmd"
+	"cmd/go/internal/modfetch"
+	"cmd/go/internal/modload"
 	"cmd/go/internal/run"
 	"cmd/go/internal/test"
 	"cmd/go/internal/tool"
@@ -48,6 +51,7 @@ func init() {
 		get.CmdGet,
 		work.CmdInstall,
 		list.CmdList,
+		modcmd.CmdMod,
 		run.CmdRun,
 		test.CmdTest,
 		tool.CmdTool,
@@ -60,7 +64,9 @@ func init() {
 		help.HelpEnvironment,
 		help.HelpFileType,
 		help.HelpGopath,
+		modfetch.HelpGoproxy,
 		help.HelpImportPath,
+		modload.HelpModules,
 		help.HelpPackages,
 		test.HelpTestflag,
 		test.HelpTestfunc,
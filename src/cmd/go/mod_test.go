@@ -0,0 +1,275 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests for module-aware mode.
+
+package main_test
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// modProxyFiles describes the module versions served by the test proxy.
+// Each entry maps path@version to the files in that module version,
+// which must include go.mod.
+var modProxyFiles = map[string]map[string]string{
+	"example.com/words@v1.0.0": {
+		"go.mod":   "module example.com/words\n",
+		"words.go": "package words\n\nfunc Hello() string { return \"hello\" }\n",
+	},
+	"example.com/words@v1.1.0": {
+		"go.mod":   "module example.com/words\n",
+		"words.go": "package words\n\nfunc Hello() string { return \"hi\" }\n",
+	},
+	"example.com/greet@v1.0.0": {
+		"go.mod":   "module example.com/greet\n\nrequire example.com/words v1.0.0\n",
+		"greet.go": "package greet\n\nimport \"example.com/words\"\n\nfunc Greet(who string) string { return words.Hello() + \", \" + who }\n",
+	},
+	"example.com/greet@v1.1.0": {
+		"go.mod":   "module example.com/greet\n\nrequire example.com/words v1.1.0\n",
+		"greet.go": "package greet\n\nimport \"example.com/words\"\n\nfunc Greet(who string) string { return words.Hello() + \", \" + who + \"!\" }\n",
+	},
+}
+
+// modTestgo returns a testgoData for a module test, with an empty
+// GOPATH, module mode turned on, and a file:// module proxy in
+// the temporary directory serving modProxyFiles.
+// The main module is in the directory "m", which is current.
+func modTestgo(t *testing.T) *testgoData {
+	tg := testgo(t)
+	tg.makeTempdir()
+	tg.setenv("GOPATH", tg.path("gopath"))
+	tg.setenv("GO111MODULE", "on")
+	tg.setenv("GOPROXY", "file://"+filepath.ToSlash(tg.path("proxy")))
+	tg.setenv("GOCACHE", tg.path("cache"))
+
+	var paths []string
+	for pv := range modProxyFiles {
+		paths = append(paths, pv)
+	}
+	sort.Strings(paths)
+	for _, pv := range paths {
+		i := strings.Index(pv, "@")
+		path, vers := pv[:i], pv[i+1:]
+		dir := tg.path(filepath.Join("proxy", filepath.FromSlash(path), "@v"))
+		tg.must(os.MkdirAll(dir, 0777))
+		files := modProxyFiles[pv]
+
+		f, err := os.Create(filepath.Join(dir, vers+".zip"))
+		tg.must(err)
+		z := zip.NewWriter(f)
+		var names []string
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			w, err := z.Create(pv + "/" + name)
+			tg.must(err)
+			_, err = w.Write([]byte(files[name]))
+			tg.must(err)
+		}
+		tg.must(z.Close())
+		tg.must(f.Close())
+
+		tg.must(ioutil.WriteFile(filepath.Join(dir, vers+".mod"), []byte(files["go.mod"]), 0666))
+		info := `{"Version":"` + vers + `","Time":"2018-02-14T00:00:00Z"}`
+		tg.must(ioutil.WriteFile(filepath.Join(dir, vers+".info"), []byte(info), 0666))
+		list, _ := ioutil.ReadFile(filepath.Join(dir, "list"))
+		tg.must(ioutil.WriteFile(filepath.Join(dir, "list"), append(list, vers+"\n"...), 0666))
+	}
+
+	tg.tempFile("m/go.mod", "module example.com/m\n")
+	tg.tempFile("m/main.go", `package main
+
+		import (
+			"fmt"
+
+			"example.com/greet"
+		)
+
+		func main() { fmt.Println(greet.Greet("gopher")) }
+	`)
+	tg.cd(tg.path("m"))
+	return tg
+}
+
+func (tg *testgoData) wantFileMatch(file, re, msg string) {
+	tg.t.Helper()
+	data, err := ioutil.ReadFile(tg.path(file))
+	tg.must(err)
+	if !regexp.MustCompile(re).Match(data) {
+		tg.t.Fatalf("%s: %s does not match %q:\n%s", msg, file, re, data)
+	}
+}
+
+func TestModBuildAddsRequirements(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.run("run", "main.go")
+	tg.grepStdout(`^hi, gopher!$`, "expected latest greet and words")
+	tg.grepStderr(`go: found example.com/greet in example.com/greet v1.1.0`, "expected message about added requirement")
+	tg.wantFileMatch("m/go.mod", `(?m)^require example.com/greet v1.1.0$`, "go.mod should require latest greet")
+	tg.wantFileMatch("m/go.sum", `(?m)^example.com/greet v1.1.0 h1:`, "go.sum should record greet zip")
+	tg.wantFileMatch("m/go.sum", `(?m)^example.com/words v1.1.0/go.mod h1:`, "go.sum should record words go.mod")
+
+	tg.run("list", "-m", "all")
+	tg.grepStdout(`(?m)^example.com/m$`, "missing main module")
+	tg.grepStdout(`(?m)^example.com/greet v1.1.0$`, "missing greet")
+	tg.grepStdout(`(?m)^example.com/words v1.1.0$`, "missing words")
+
+	tg.run("list", "-f", "{{.ImportPath}} {{.Module.Path}} {{.Module.Version}}", "example.com/words")
+	tg.grepStdout(`^example.com/words example.com/words v1.1.0$`, "wrong module for package")
+
+	tg.run("mod", "-graph")
+	tg.grepStdout(`(?m)^example.com/greet@v1.1.0 example.com/words@v1.1.0$`, "missing graph edge")
+
+	tg.run("mod", "-verify")
+	tg.grepStdout(`all modules verified`, "expected verification to succeed")
+}
+
+func TestModGetVersion(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.run("get", "-d", "example.com/greet@v1.0.0")
+	tg.wantFileMatch("m/go.mod", `(?m)^require example.com/greet v1.0.0$`, "go.mod should require greet v1.0.0")
+	tg.run("run", "main.go")
+	tg.grepStdout(`^hello, gopher$`, "expected greet v1.0.0")
+
+	// Requiring a newer words upgrades it despite greet's requirement.
+	tg.run("get", "-d", "example.com/words@v1.1.0")
+	tg.run("list", "-m", "example.com/words")
+	tg.grepStdout(`^example.com/words v1.1.0$`, "expected words v1.1.0")
+
+	tg.run("get", "-d", "example.com/words@none")
+	tg.run("list", "-m", "example.com/words")
+	tg.grepStdout(`^example.com/words v1.0.0$`, "expected words v1.0.0 after dropping requirement")
+
+	tg.runFail("get", "-d", "example.com/greet@v1.2.0")
+	tg.grepStderr(`example.com/greet@v1.2.0`, "expected error about missing version")
+}
+
+func TestModOfflineCache(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.run("build", "-o", tg.path("m.exe"), ".")
+
+	// With the proxy disabled, the module cache is enough.
+	tg.setenv("GOPROXY", "off")
+	tg.run("build", "-o", tg.path("m.exe"), ".")
+
+	// The download cache can itself serve as a proxy.
+	tg.must(os.Remove(tg.path("m/go.sum")))
+	cache := tg.path("gopath/pkg/mod/cache/download")
+	tg.setenv("GOPATH", tg.path("gopath2"))
+	tg.setenv("GOPROXY", "file://"+filepath.ToSlash(cache))
+	tg.run("run", "main.go")
+	tg.grepStdout(`^hi, gopher!$`, "expected build from cache proxy")
+
+	// A module not in the cache cannot be found offline.
+	tg.setenv("GOPATH", tg.path("gopath3"))
+	tg.setenv("GOPROXY", "off")
+	tg.runFail("build", ".")
+	tg.grepStderr(`example.com/greet`, "expected failure to find greet")
+}
+
+func TestModCacheReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on Windows")
+	}
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.run("build", "-o", tg.path("m.exe"), ".")
+	file := tg.path("gopath/pkg/mod/example.com/words@v1.1.0/words.go")
+	fi, err := os.Stat(file)
+	tg.must(err)
+	if fi.Mode()&0222 != 0 {
+		t.Errorf("%s is writable: mode %v", file, fi.Mode())
+	}
+	fi, err = os.Stat(filepath.Dir(file))
+	tg.must(err)
+	if fi.Mode()&0222 != 0 {
+		t.Errorf("%s is writable: mode %v", filepath.Dir(file), fi.Mode())
+	}
+	tmps, _ := filepath.Glob(tg.path("gopath/pkg/mod/example.com/*.tmp-*"))
+	if len(tmps) > 0 {
+		t.Errorf("temporary extraction directories left behind: %v", tmps)
+	}
+
+	tg.run("clean", "-modcache")
+	tg.mustNotExist(tg.path("gopath/pkg/mod"))
+}
+
+func TestModChecksumMismatch(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.tempFile("m/go.mod", "module example.com/m\n\nrequire example.com/greet v1.1.0\n")
+	tg.tempFile("m/go.sum", "example.com/greet v1.1.0 h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n")
+	tg.runFail("build", ".")
+	tg.grepStderr(`checksum mismatch`, "expected checksum mismatch")
+}
+
+func TestModReplaceExclude(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.tempFile("words/go.mod", "module example.com/words\n")
+	tg.tempFile("words/words.go", "package words\n\nfunc Hello() string { return \"howdy\" }\n")
+	tg.tempFile("m/go.mod", `module example.com/m
+
+require example.com/greet v1.1.0
+
+replace example.com/words => ../words
+`)
+	tg.run("run", "main.go")
+	tg.grepStdout(`^howdy, gopher!$`, "expected replacement directory")
+	tg.run("list", "-m", "example.com/words")
+	tg.grepStdout(`^example.com/words v1.1.0 => ../words$`, "expected replacement in list -m")
+
+	tg.tempFile("m/go.mod", `module example.com/m
+
+require example.com/greet v1.0.0
+
+exclude example.com/words v1.0.0
+`)
+	tg.run("list", "-m", "example.com/words")
+	tg.grepStdout(`^example.com/words v1.1.0$`, "expected exclude to skip words v1.0.0")
+}
+
+func TestModInit(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.tempFile("n/n.go", "package n // import \"example.com/n\"\n")
+	tg.cd(tg.path("n"))
+	tg.run("mod", "-init")
+	tg.grepStderr(`go: creating new go.mod: module example.com/n`, "expected module path from import comment")
+	tg.wantFileMatch("n/go.mod", `^module example.com/n\n$`, "unexpected go.mod")
+	tg.runFail("mod", "-init")
+	tg.grepStderr(`already exists`, "expected error for existing go.mod")
+}
+
+func TestModBadGO111MODULE(t *testing.T) {
+	tg := modTestgo(t)
+	defer tg.cleanup()
+
+	tg.setenv("GO111MODULE", "bogus")
+	tg.run("version")
+	tg.run("env", "GOPATH")
+	tg.runFail("list", ".")
+	tg.grepStderr(`unknown environment setting GO111MODULE=bogus`, "expected error for bad GO111MODULE")
+}
@@ -60,6 +60,8 @@ const (
 	SDWARFRANGE
 	SDWARFLOC
 	SDWARFMISC
+	// Coverage counters inserted by the compiler for fuzzing
+	SLIBFUZZER_EXTRA_COUNTER
 	// Update cmd/link/internal/sym/AbiSymKindToSymKind for new SymKind values.
 
 )
@@ -1327,6 +1327,24 @@ func (ctxt *Link) dodata() {
 	ctxt.Syms.Lookup("runtime.end", 0).Sect = sect
 	checkdatsize(ctxt, datsize, sym.SNOPTRBSS)
 
+	// Coverage counters for fuzzing, delimited by internal/fuzz._counters
+	// and internal/fuzz._ecounters.
+	if len(data[sym.SLIBFUZZER_EXTRA_COUNTER]) > 0 {
+		sect := addsection(ctxt.Arch, &Segdata, ".go.fuzzcntrs", 06)
+		sect.Align = dataMaxAlign[sym.SLIBFUZZER_EXTRA_COUNTER]
+		datsize = Rnd(datsize, int64(sect.Align))
+		sect.Vaddr = uint64(datsize)
+		for _, s := range data[sym.SLIBFUZZER_EXTRA_COUNTER] {
+			datsize = aligndatsize(datsize, s)
+			s.Sect = sect
+			s.Value = int64(uint64(datsize) - sect.Vaddr)
+			datsize += s.Size
+		}
+		sect.Length = uint64(datsize) - sect.Vaddr
+		ctxt.Syms.Lookup("runtime.end", 0).Sect = sect
+		checkdatsize(ctxt, datsize, sym.SLIBFUZZER_EXTRA_COUNTER)
+	}
+
 	if len(data[sym.STLSBSS]) > 0 {
 		var sect *sym.Section
 		if ctxt.IsELF && (ctxt.LinkMode == LinkExternal || !*FlagD) {
@@ -1983,6 +2001,7 @@ func (ctxt *Link) address() {
 	var noptr *sym.Section
 	var bss *sym.Section
 	var noptrbss *sym.Section
+	var fuzzCounters *sym.Section
 	for i, s := range Segdata.Sections {
 		if ctxt.IsELF && s.Name == ".tbss" {
 			continue
@@ -2006,6 +2025,9 @@ func (ctxt *Link) address() {
 		if s.Name == ".noptrbss" {
 			noptrbss = s
 		}
+		if s.Name == ".go.fuzzcntrs" {
+			fuzzCounters = s
+		}
 	}
 
 	Segdata.Filelen = bss.Vaddr - Segdata.Vaddr
@@ -2119,6 +2141,19 @@ func (ctxt *Link) address() {
 	ctxt.xdefine("runtime.noptrbss", sym.SNOPTRBSS, int64(noptrbss.Vaddr))
 	ctxt.xdefine("runtime.enoptrbss", sym.SNOPTRBSS, int64(noptrbss.Vaddr+noptrbss.Length))
 	ctxt.xdefine("runtime.end", sym.SBSS, int64(Segdata.Vaddr+Segdata.Length))
+
+	if s := ctxt.Syms.ROLookup("internal/fuzz._counters", 0); s != nil && s.Attr.Special() {
+		// Without instrumented packages both markers sit at the
+		// end of noptrbss and delimit an empty range.
+		sect, start, end := noptrbss, noptrbss.Vaddr+noptrbss.Length, noptrbss.Vaddr+noptrbss.Length
+		if fuzzCounters != nil {
+			sect, start, end = fuzzCounters, fuzzCounters.Vaddr, fuzzCounters.Vaddr+fuzzCounters.Length
+		}
+		ctxt.xdefine("internal/fuzz._counters", sym.SLIBFUZZER_EXTRA_COUNTER, int64(start))
+		ctxt.xdefine("internal/fuzz._ecounters", sym.SLIBFUZZER_EXTRA_COUNTER, int64(end))
+		s.Sect = sect
+		ctxt.Syms.Lookup("internal/fuzz._ecounters", 0).Sect = sect
+	}
 }
 
 // add a trampoline with symbol s (to be laid down after the current function)
@@ -348,6 +348,12 @@ func (ctxt *Link) symtab() {
 	ctxt.xdefine("runtime.noptrbss", sym.SNOPTRBSS, 0)
 	ctxt.xdefine("runtime.enoptrbss", sym.SNOPTRBSS, 0)
 	ctxt.xdefine("runtime.end", sym.SBSS, 0)
+	if s := ctxt.Syms.ROLookup("internal/fuzz._counters", 0); s != nil && s.Attr.Reachable() {
+		// The fuzzing engine finds the compiler-inserted coverage
+		// counters between these two markers.
+		ctxt.xdefine("internal/fuzz._counters", sym.SLIBFUZZER_EXTRA_COUNTER, 0)
+		ctxt.xdefine("internal/fuzz._ecounters", sym.SLIBFUZZER_EXTRA_COUNTER, 0)
+	}
 	ctxt.xdefine("runtime.epclntab", sym.SRODATA, 0)
 	ctxt.xdefine("runtime.esymtab", sym.SRODATA, 0)
 
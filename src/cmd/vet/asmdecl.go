@@ -9,10 +9,12 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/analysis"
 	"go/ast"
 	"go/build"
 	"go/token"
 	"go/types"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -118,48 +120,57 @@ var (
 	ppc64Suff    = re(`([BHWD])(ZU|Z|U|BR)?$`)
 )
 
-func asmCheck(pkg *Package) {
-	if !vet("asmdecl") {
-		return
-	}
+var asmdeclAnalyzer = &analysis.Analyzer{
+	Name:             "asmdecl",
+	Doc:              "check assembly against Go declarations",
+	RunDespiteErrors: true,
+	Run:              runAsmdecl,
+}
 
+func runAsmdecl(pass *analysis.Pass) (interface{}, error) {
 	// No work if no assembly files.
-	if !pkg.hasFileWithSuffix(".s") {
-		return
+	var sfiles []string
+	for _, fname := range pass.OtherFiles {
+		if strings.HasSuffix(fname, ".s") {
+			sfiles = append(sfiles, fname)
+		}
+	}
+	if sfiles == nil {
+		return nil, nil
 	}
 
 	// Gather declarations. knownFunc[name][arch] is func description.
 	knownFunc := make(map[string]map[string]*asmFunc)
 
-	for _, f := range pkg.files {
-		if f.file != nil {
-			for _, decl := range f.file.Decls {
-				if decl, ok := decl.(*ast.FuncDecl); ok && decl.Body == nil {
-					knownFunc[decl.Name.Name] = f.asmParseDecl(decl)
-				}
+	pkg := newPackage(pass)
+	for _, file := range pass.Files {
+		f := pkg.newFile(file)
+		for _, decl := range file.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok && decl.Body == nil {
+				knownFunc[decl.Name.Name] = f.asmParseDecl(decl)
 			}
 		}
 	}
 
 Files:
-	for _, f := range pkg.files {
-		if !strings.HasSuffix(f.name, ".s") {
-			continue
+	for _, fname := range sfiles {
+		content, tf, err := readFile(pass.Fset, fname)
+		if err != nil {
+			return nil, err
 		}
-		Println("Checking file", f.name)
 
 		// Determine architecture from file name if possible.
 		var arch string
 		var archDef *asmArch
 		for _, a := range arches {
-			if strings.HasSuffix(f.name, "_"+a.name+".s") {
+			if strings.HasSuffix(fname, "_"+a.name+".s") {
 				arch = a.name
 				archDef = a
 				break
 			}
 		}
 
-		lines := strings.SplitAfter(string(f.content), "\n")
+		lines := strings.SplitAfter(string(content), "\n")
 		var (
 			fn                 *asmFunc
 			fnName             string
@@ -173,7 +184,7 @@ Files:
 			if fn != nil && fn.vars["ret"] != nil && !haveRetArg && len(retLine) > 0 {
 				v := fn.vars["ret"]
 				for _, line := range retLine {
-					f.Badf(token.NoPos, "%s:%d: [%s] %s: RET without writing to %d-byte ret+%d(FP)", f.name, line, arch, fnName, v.size, v.off)
+					pass.Reportf(lineStart(tf, content, line), "[%s] %s: RET without writing to %d-byte ret+%d(FP)", arch, fnName, v.size, v.off)
 				}
 			}
 			retLine = nil
@@ -182,7 +193,7 @@ Files:
 			lineno++
 
 			badf := func(format string, args ...interface{}) {
-				f.Badf(token.NoPos, "%s:%d: [%s] %s: %s", f.name, lineno, arch, fnName, fmt.Sprintf(format, args...))
+				pass.Reportf(lineStart(tf, content, lineno), "[%s] %s: %s", arch, fnName, fmt.Sprintf(format, args...))
 			}
 
 			if arch == "" {
@@ -225,7 +236,7 @@ Files:
 						}
 					}
 					if arch == "" {
-						f.Warnf(token.NoPos, "%s: cannot determine architecture for assembly file", f.name)
+						fmt.Fprintf(os.Stderr, "%s: cannot determine architecture for assembly file\n", fname)
 						continue Files
 					}
 				}
@@ -233,8 +244,8 @@ Files:
 				if pkgName := strings.TrimSpace(m[1]); pkgName != "" {
 					pathParts := strings.Split(pkgName, "∕")
 					pkgName = pathParts[len(pathParts)-1]
-					if pkgName != f.pkg.path {
-						f.Warnf(token.NoPos, "%s:%d: [%s] cannot check cross-package assembly function: %s is in package %s", f.name, lineno, arch, fnName, pkgName)
+					if pkgName != pass.Pkg.Name() {
+						fmt.Fprintf(os.Stderr, "%s:%d: [%s] cannot check cross-package assembly function: %s is in package %s\n", fname, lineno, arch, fnName, pkgName)
 						fn = nil
 						fnName = ""
 						continue
@@ -356,6 +367,7 @@ Files:
 		}
 		flushRet()
 	}
+	return nil, nil
 }
 
 func asmKindForType(t types.Type, size int) asmKind {
@@ -491,6 +503,14 @@ func (f *File) asmParseDecl(decl *ast.FuncDecl) map[string]*asmFunc {
 		argnum := 0
 		for _, fld := range list {
 			t := f.pkg.types[fld.Type].Type
+
+			// The type checker records no type for the ... of a
+			// variadic parameter; use the slice type it denotes.
+			if t == nil {
+				if ell, ok := fld.Type.(*ast.Ellipsis); ok {
+					t = types.NewSlice(f.pkg.types[ell.Elt].Type)
+				}
+			}
 			align := int(arch.sizes.Alignof(t))
 			size := int(arch.sizes.Sizeof(t))
 			offset += -offset & (align - 1)
@@ -9,16 +9,20 @@ This file contains the code to check for useless assignments.
 package main
 
 import (
+	"fmt"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"reflect"
 )
 
-func init() {
-	register("assign",
-		"check for useless assignments",
-		checkAssignStmt,
-		assignStmt)
+var assignAnalyzer = &analysis.Analyzer{
+	Name:             "assign",
+	Doc:              "check for useless assignments",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkAssignStmt, assignStmt),
 }
 
 // TODO: should also check for assignments to struct fields inside methods
@@ -46,7 +50,18 @@ func checkAssignStmt(f *File, node ast.Node) {
 		le := f.gofmt(lhs)
 		re := f.gofmt(rhs)
 		if le == re {
-			f.Badf(stmt.Pos(), "self-assignment of %s to %s", re, le)
+			d := analysis.Diagnostic{
+				Pos:     stmt.Pos(),
+				Message: fmt.Sprintf("self-assignment of %s to %s", re, le),
+			}
+			if len(stmt.Lhs) == 1 {
+				// The whole statement is useless; offer to delete it.
+				d.SuggestedFixes = []analysis.SuggestedFix{{
+					Message:   "Remove self-assignment",
+					TextEdits: []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.End()}},
+				}}
+			}
+			f.pass.Report(d)
 		}
 	}
 }
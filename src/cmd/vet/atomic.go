@@ -5,16 +5,19 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("atomic",
-		"check for common mistaken usages of the sync/atomic package",
-		checkAtomicAssignment,
-		assignStmt)
+var atomicAnalyzer = &analysis.Analyzer{
+	Name:             "atomic",
+	Doc:              "check for common mistaken usages of the sync/atomic package",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkAtomicAssignment, assignStmt),
 }
 
 // checkAtomicAssignment walks the assignment statement checking for common
@@ -7,16 +7,19 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("bool",
-		"check for mistakes involving boolean operators",
-		checkBool,
-		binaryExpr)
+var boolAnalyzer = &analysis.Analyzer{
+	Name:             "bool",
+	Doc:              "check for mistakes involving boolean operators",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkBool, binaryExpr),
 }
 
 func checkBool(f *File, n ast.Node) {
@@ -6,8 +6,10 @@ package main
 
 import (
 	"bytes"
-	"fmt"
-	"os"
+	"go/analysis"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
 	"strings"
 	"unicode"
 )
@@ -18,34 +20,56 @@ var (
 	plusBuild  = []byte("+build")
 )
 
-// checkBuildTag checks that build tags are in the correct location and well-formed.
-func checkBuildTag(f *File) {
-	if !vet("buildtags") {
-		return
+var buildtagAnalyzer = &analysis.Analyzer{
+	Name:             "buildtags",
+	Doc:              "check that +build tags are valid",
+	RunDespiteErrors: true,
+	Run:              runBuildTag,
+}
+
+func runBuildTag(pass *analysis.Pass) (interface{}, error) {
+	for _, f := range pass.Files {
+		tf := pass.Fset.File(f.Pos())
+		content, err := ioutil.ReadFile(tf.Name())
+		if err != nil {
+			return nil, err
+		}
+		checkBuildTag(pass, tf, content, f)
 	}
+	for _, name := range pass.OtherFiles {
+		content, tf, err := readFile(pass.Fset, name)
+		if err != nil {
+			return nil, err
+		}
+		checkBuildTag(pass, tf, content, nil)
+	}
+	return nil, nil
+}
+
+// checkBuildTag checks that build tags are in the correct location and well-formed.
+// The file is the syntax tree of tf, or nil if it is not a Go file.
+func checkBuildTag(pass *analysis.Pass, tf *token.File, content []byte, file *ast.File) {
 	// badf is like File.Badf, but it uses a line number instead of
 	// token.Pos.
 	badf := func(line int, format string, args ...interface{}) {
-		msg := fmt.Sprintf(format, args)
-		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", f.name, line, msg)
-		setExit(1)
+		pass.Reportf(lineStart(tf, content, line), format, args...)
 	}
 
 	// we must look at the raw lines, as build tags may appear in non-Go
 	// files such as assembly files.
-	lines := bytes.SplitAfter(f.content, nl)
+	lines := bytes.SplitAfter(content, nl)
 
 	// lineWithComment reports whether a line corresponds to a comment in
 	// the source file. If the source file wasn't Go, the function always
 	// returns true.
 	lineWithComment := func(line int) bool {
-		if f.file == nil {
+		if file == nil {
 			// Current source file is not Go, so be conservative.
 			return true
 		}
-		for _, group := range f.file.Comments {
-			startLine := f.fset.Position(group.Pos()).Line
-			endLine := f.fset.Position(group.End()).Line
+		for _, group := range file.Comments {
+			startLine := pass.Fset.Position(group.Pos()).Line
+			endLine := pass.Fset.Position(group.End()).Line
 			if startLine <= line && line <= endLine {
 				return true
 			}
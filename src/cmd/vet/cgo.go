@@ -12,16 +12,19 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("cgocall",
-		"check for types that may not be passed to cgo calls",
-		checkCgoCall,
-		callExpr)
+var cgocallAnalyzer = &analysis.Analyzer{
+	Name:             "cgocall",
+	Doc:              "check for types that may not be passed to cgo calls",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkCgoCall, callExpr),
 }
 
 func checkCgoCall(f *File, node ast.Node) {
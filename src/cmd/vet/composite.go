@@ -8,19 +8,25 @@ package main
 
 import (
 	"cmd/vet/internal/whitelist"
-	"flag"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/types"
 	"strings"
 )
 
-var compositeWhiteList = flag.Bool("compositewhitelist", true, "use composite white list; for testing only")
+var compositeAnalyzer = &analysis.Analyzer{
+	Name:             "composites",
+	Doc:              "check that composite literals of types from imported packages use field-keyed elements",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkUnkeyedLiteral, compositeLit),
+}
+
+var compositeWhiteList = true
 
 func init() {
-	register("composites",
-		"check that composite literals of types from imported packages use field-keyed elements",
-		checkUnkeyedLiteral,
-		compositeLit)
+	compositeAnalyzer.Flags.BoolVar(&compositeWhiteList, "whitelist", compositeWhiteList, "use composite white list; for testing only")
 }
 
 // checkUnkeyedLiteral checks if a composite literal is a struct literal with
@@ -34,7 +40,7 @@ func checkUnkeyedLiteral(f *File, node ast.Node) {
 		return
 	}
 	typeName := typ.String()
-	if *compositeWhiteList && whitelist.UnkeyedLiteral[typeName] {
+	if compositeWhiteList && whitelist.UnkeyedLiteral[typeName] {
 		// skip whitelisted types
 		return
 	}
@@ -9,16 +9,19 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("copylocks",
-		"check that locks are not passed by value",
-		checkCopyLocks,
-		funcDecl, rangeStmt, funcLit, callExpr, assignStmt, genDecl, compositeLit, returnStmt)
+var copylockAnalyzer = &analysis.Analyzer{
+	Name:             "copylocks",
+	Doc:              "check that locks are not passed by value",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkCopyLocks, funcDecl, rangeStmt, funcLit, callExpr, assignStmt, genDecl, compositeLit, returnStmt),
 }
 
 // checkCopyLocks checks whether node might
@@ -7,15 +7,18 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 )
 
-func init() {
-	register("unreachable",
-		"check for unreachable code",
-		checkUnreachable,
-		funcDecl, funcLit)
+var unreachableAnalyzer = &analysis.Analyzer{
+	Name:             "unreachable",
+	Doc:              "check for unreachable code",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkUnreachable, funcDecl, funcLit),
 }
 
 type deadState struct {
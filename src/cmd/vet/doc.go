@@ -30,6 +30,19 @@ If any flags are explicitly set to true, only those tests are run. Conversely, i
 any flag is explicitly set to false, only those tests are disabled.  Thus -printf=true
 runs the printf check, -printf=false runs all checks except the printf check.
 
+Each check is an analysis.Analyzer (see package go/analysis), and flags
+specific to a check are spelled -check.flag, as in -printf.funcs.
+The older spellings such as -printfuncs are still accepted.
+
+The -flags flag prints a JSON description of vet's flags, the -json flag
+prints diagnostics in JSON form, and "go tool vet help" describes the checks.
+
+Custom checks may be run in place of vet's own by building a program
+that calls go/analysis/multichecker.Main (or unitchecker.Main) with the
+desired analyzers and naming it with the -vettool flag:
+
+	go vet -vettool=$(which mychecker) package/path/name
+
 By default vet uses the object files generated by 'go install some/pkg' to typecheck the code.
 If the -source flag is provided, vet uses only source code.
 
@@ -133,6 +146,15 @@ If the function name ends with an 'f', the function is assumed to take
 a format descriptor string in the manner of fmt.Printf. If not, vet
 complains about arguments that look like format descriptor strings.
 
+Functions that pass their arguments through to one of these, such as
+
+	func wrapf(format string, args ...interface{}) {
+		fmt.Printf(format, args...)
+	}
+
+are detected automatically, including in imported packages, and their
+calls are checked in the same way.
+
 It also checks for errors such as using a Writer as the first argument of
 Printf.
 
@@ -190,8 +212,8 @@ Flag: -unusedresult
 
 Calls to well-known functions and methods that return a value that is
 discarded.  By default, this includes functions like fmt.Errorf and
-fmt.Sprintf and methods like String and Error. The flags -unusedfuncs
-and -unusedstringmethods control the set.
+fmt.Sprintf and methods like String and Error. The flags -unusedresult.funcs
+and -unusedresult.stringmethods control the set.
 
 Other flags
 
@@ -201,12 +223,14 @@ These flags configure the behavior of vet:
 		Enable all non-experimental checks.
 	-v
 		Verbose mode
-	-printfuncs
+	-printf.funcs
 		A comma-separated list of print-like function names
 		to supplement the standard list.
 		For more information, see the discussion of the -printf flag.
-	-shadowstrict
+	-shadow.strict
 		Whether to be strict about shadowing; can be noisy.
+	-json
+		Emit diagnostics, and any suggested fixes, in JSON form.
 
 Using vet directly
 
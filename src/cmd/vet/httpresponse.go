@@ -8,14 +8,18 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/types"
 )
 
-func init() {
-	register("httpresponse",
-		"check errors are checked before using an http Response",
-		checkHTTPResponse, callExpr)
+var httpresponseAnalyzer = &analysis.Analyzer{
+	Name:             "httpresponse",
+	Doc:              "check errors are checked before using an http Response",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkHTTPResponse, callExpr),
 }
 
 func checkHTTPResponse(f *File, node ast.Node) {
@@ -7,16 +7,19 @@ package main
 import (
 	"cmd/vet/internal/cfg"
 	"fmt"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/types"
 	"strconv"
 )
 
-func init() {
-	register("lostcancel",
-		"check for failure to call cancelation function returned by context.WithCancel",
-		checkLostCancel,
-		funcDecl, funcLit)
+var lostcancelAnalyzer = &analysis.Analyzer{
+	Name:             "lostcancel",
+	Doc:              "check for failure to call cancelation function returned by context.WithCancel",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkLostCancel, funcDecl, funcLit),
 }
 
 const debugLostCancel = false
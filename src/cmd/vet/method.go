@@ -8,16 +8,19 @@ package main
 
 import (
 	"fmt"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/printer"
 	"strings"
 )
 
-func init() {
-	register("methods",
-		"check that canonically named methods are canonically defined",
-		checkCanonicalMethod,
-		funcDecl, interfaceType)
+var methodsAnalyzer = &analysis.Analyzer{
+	Name:             "methods",
+	Doc:              "check that canonically named methods are canonically defined",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkCanonicalMethod, funcDecl, interfaceType),
 }
 
 type MethodSig struct {
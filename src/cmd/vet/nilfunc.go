@@ -10,16 +10,19 @@ A useless comparison is one like f == nil as opposed to f() == nil.
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("nilfunc",
-		"check for comparisons between functions and nil",
-		checkNilFuncComparison,
-		binaryExpr)
+var nilfuncAnalyzer = &analysis.Analyzer{
+	Name:             "nilfunc",
+	Doc:              "check for comparisons between functions and nil",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkNilFuncComparison, binaryExpr),
 }
 
 func checkNilFuncComparison(f *File, node ast.Node) {
@@ -8,8 +8,9 @@ package main
 
 import (
 	"bytes"
-	"flag"
 	"fmt"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/constant"
 	"go/token"
@@ -20,42 +21,157 @@ import (
 	"unicode/utf8"
 )
 
-var printfuncs = flag.String("printfuncs", "", "comma-separated list of print function names to check")
+var printfAnalyzer = &analysis.Analyzer{
+	Name:             "printf",
+	Doc:              "check printf-like invocations",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	FactTypes:        []analysis.Fact{new(isWrapper)},
+	Run:              runPrintf,
+}
+
+// printfuncs holds the names set by the -printf.funcs flag.
+var printfuncs stringSetFlag
 
 func init() {
-	register("printf",
-		"check printf-like invocations",
-		checkFmtPrintfCall,
-		funcDecl, callExpr)
+	printfAnalyzer.Flags.Var(&printfuncs, "funcs", "comma-separated list of print function names to check")
 }
 
-func initPrintFlags() {
-	if *printfuncs == "" {
-		return
-	}
-	for _, name := range strings.Split(*printfuncs, ",") {
-		if len(name) == 0 {
-			flag.Usage()
-		}
-
+// isUserPrint reports whether name, the unqualified name of a function
+// or method, was listed in the -printf.funcs flag.
+func isUserPrint(name string) bool {
+	for fn := range printfuncs {
 		// Backwards compatibility: skip optional first argument
 		// index after the colon.
-		if colon := strings.LastIndex(name, ":"); colon > 0 {
-			name = name[:colon]
+		if colon := strings.LastIndex(fn, ":"); colon > 0 {
+			fn = fn[:colon]
+		}
+		if strings.EqualFold(fn, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func runPrintf(pass *analysis.Pass) (interface{}, error) {
+	findPrintfWrappers(pass)
+	walk(pass, checkFmtPrintfCall, funcDecl, callExpr)
+	return nil, nil
+}
+
+// printfKind describes how a print function or wrapper treats its
+// arguments.
+type printfKind int
+
+const (
+	kindNone   printfKind = iota
+	kindPrint             // like fmt.Print: no format string
+	kindPrintf            // like fmt.Printf: format string, then arguments
+	kindErrorf            // like fmt.Errorf: kindPrintf that also accepts %w
+)
+
+// isWrapper is a fact recording that a function is a print wrapper:
+// it passes its final ...interface{} parameter, and for a printf
+// wrapper the format string parameter before it, unchanged to a
+// print function or to another wrapper. Exporting the fact lets calls
+// to the wrapper be checked in the packages that import it.
+type isWrapper struct {
+	Kind printfKind
+}
+
+func (*isWrapper) AFact() {}
+
+func (f *isWrapper) String() string {
+	switch f.Kind {
+	case kindPrint:
+		return "printWrapper"
+	case kindPrintf:
+		return "printfWrapper"
+	case kindErrorf:
+		return "errorfWrapper"
+	}
+	return "wrapper"
+}
+
+// findPrintfWrappers exports an isWrapper fact for each function or
+// method declared in the package that is a print wrapper.
+func findPrintfWrappers(pass *analysis.Pass) {
+	type candidate struct {
+		fn     *types.Func
+		body   *ast.BlockStmt
+		format *types.Var // format string parameter, or nil
+		args   *types.Var // final ...interface{} parameter
+	}
+	var candidates []candidate
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Body == nil {
+				continue
+			}
+			fn, ok := pass.TypesInfo.Defs[decl.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			sig := fn.Type().(*types.Signature)
+			if !sig.Variadic() {
+				continue
+			}
+			params := sig.Params()
+			args := params.At(params.Len() - 1)
+			if it, ok := args.Type().(*types.Slice).Elem().(*types.Interface); !ok || !it.Empty() {
+				continue
+			}
+			var format *types.Var
+			if params.Len() >= 2 {
+				if p := params.At(params.Len() - 2); p.Type() == types.Typ[types.String] {
+					format = p
+				}
+			}
+			candidates = append(candidates, candidate{fn, decl.Body, format, args})
 		}
+	}
 
-		isPrint[strings.ToLower(name)] = true
+	// A wrapper may call another wrapper declared later in the
+	// package, so repeat until no new wrappers are found.
+	for changed := true; changed; {
+		changed = false
+		for _, c := range candidates {
+			if pass.ImportObjectFact(c.fn, new(isWrapper)) {
+				continue
+			}
+			kind := kindNone
+			ast.Inspect(c.body, func(n ast.Node) bool {
+				if kind != kindNone {
+					return false
+				}
+				call, ok := n.(*ast.CallExpr)
+				if !ok || !call.Ellipsis.IsValid() || !isParam(pass, call.Args[len(call.Args)-1], c.args) {
+					return true
+				}
+				switch k := printfKindOf(pass, call); k {
+				case kindPrint:
+					kind = k
+				case kindPrintf, kindErrorf:
+					if len(call.Args) >= 2 && isParam(pass, call.Args[len(call.Args)-2], c.format) {
+						kind = k
+					}
+				}
+				return true
+			})
+			if kind != kindNone {
+				pass.ExportObjectFact(c.fn, &isWrapper{Kind: kind})
+				changed = true
+			}
+		}
 	}
 }
 
-// TODO(rsc): Incorporate user-defined printf wrappers again.
-// The general plan is to allow vet of one package P to output
-// additional information to supply to later vets of packages
-// importing P. Then vet of P can record a list of printf wrappers
-// and the later vet using P.Printf will find it in the list and check it.
-// That's not ready for Go 1.10.
-// When that does happen, uncomment the user-defined printf
-// wrapper tests in testdata/print.go.
+// isParam reports whether e refers to the parameter v.
+func isParam(pass *analysis.Pass, e ast.Expr, v *types.Var) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && v != nil && pass.TypesInfo.Uses[id] == v
+}
 
 // isPrint records the print functions.
 // If a key ends in 'f' then it is assumed to be a formatted print.
@@ -205,32 +321,78 @@ func checkFmtPrintfCall(f *File, node ast.Node) {
 		return
 	}
 
-	// Construct name like pkg.Printf or pkg.Type.Printf for lookup.
-	var name string
+	name, kind := printfNameAndKind(f.pass, call)
+	switch kind {
+	case kindPrintf, kindErrorf:
+		f.checkPrintf(call, name, kind)
+	case kindPrint:
+		f.checkPrint(call, name)
+	}
+}
+
+// printfNameAndKind returns the unqualified name of the function or
+// method called by call, and its kind, which is kindNone if it is
+// neither a print function nor a print wrapper.
+func printfNameAndKind(pass *analysis.Pass, call *ast.CallExpr) (string, printfKind) {
+	fn, name := calledFunc(pass, call)
+	if name == "" {
+		return "", kindNone
+	}
+	shortName := name[strings.LastIndex(name, ".")+1:]
+
+	var fact isWrapper
+	if fn != nil && pass.ImportObjectFact(fn, &fact) {
+		return shortName, fact.Kind
+	}
+	// Otherwise look it up by name, or by unqualified name
+	// for use with -printf.funcs.
+	if !isPrint[name] && !isUserPrint(shortName) {
+		return shortName, kindNone
+	}
+	switch {
+	case name == "fmt.Errorf":
+		return shortName, kindErrorf
+	case strings.HasSuffix(name, "f"):
+		return shortName, kindPrintf
+	}
+	return shortName, kindPrint
+}
+
+// printfKindOf returns the kind of the function called by call.
+func printfKindOf(pass *analysis.Pass, call *ast.CallExpr) printfKind {
+	_, kind := printfNameAndKind(pass, call)
+	return kind
+}
+
+// calledFunc returns the function or method called by call, if it is
+// statically known, and its name qualified like pkg.Printf or
+// pkg.Type.Printf for lookup in isPrint. The name is empty if it
+// cannot be determined.
+func calledFunc(pass *analysis.Pass, call *ast.CallExpr) (*types.Func, string) {
 	switch x := call.Fun.(type) {
 	case *ast.Ident:
-		if fn, ok := f.pkg.uses[x].(*types.Func); ok {
+		if fn, ok := pass.TypesInfo.Uses[x].(*types.Func); ok {
 			var pkg string
-			if fn.Pkg() == nil || fn.Pkg() == f.pkg.typesPkg {
-				pkg = vcfg.ImportPath
+			if fn.Pkg() == nil || fn.Pkg() == pass.Pkg {
+				pkg = pass.Pkg.Path()
 			} else {
 				pkg = fn.Pkg().Path()
 			}
-			name = pkg + "." + x.Name
-			break
+			return fn, pkg + "." + x.Name
 		}
 
 	case *ast.SelectorExpr:
 		// Check for "fmt.Printf".
 		if id, ok := x.X.(*ast.Ident); ok {
-			if pkgName, ok := f.pkg.uses[id].(*types.PkgName); ok {
-				name = pkgName.Imported().Path() + "." + x.Sel.Name
-				break
+			if pkgName, ok := pass.TypesInfo.Uses[id].(*types.PkgName); ok {
+				fn, _ := pass.TypesInfo.Uses[x.Sel].(*types.Func)
+				return fn, pkgName.Imported().Path() + "." + x.Sel.Name
 			}
 		}
 
 		// Check for t.Logf where t is a *testing.T.
-		if sel := f.pkg.selectors[x]; sel != nil {
+		if sel := pass.TypesInfo.Selections[x]; sel != nil {
+			fn, _ := sel.Obj().(*types.Func)
 			recv := sel.Recv()
 			if p, ok := recv.(*types.Pointer); ok {
 				recv = p.Elem()
@@ -238,34 +400,16 @@ func checkFmtPrintfCall(f *File, node ast.Node) {
 			if named, ok := recv.(*types.Named); ok {
 				obj := named.Obj()
 				var pkg string
-				if obj.Pkg() == nil || obj.Pkg() == f.pkg.typesPkg {
-					pkg = vcfg.ImportPath
+				if obj.Pkg() == nil || obj.Pkg() == pass.Pkg {
+					pkg = pass.Pkg.Path()
 				} else {
 					pkg = obj.Pkg().Path()
 				}
-				name = pkg + "." + obj.Name() + "." + x.Sel.Name
-				break
+				return fn, pkg + "." + obj.Name() + "." + x.Sel.Name
 			}
 		}
 	}
-	if name == "" {
-		return
-	}
-
-	shortName := name[strings.LastIndex(name, ".")+1:]
-
-	_, ok = isPrint[name]
-	if !ok {
-		// Next look up just "printf", for use with -printfuncs.
-		_, ok = isPrint[strings.ToLower(shortName)]
-	}
-	if ok {
-		if strings.HasSuffix(name, "f") {
-			f.checkPrintf(call, shortName)
-		} else {
-			f.checkPrint(call, shortName)
-		}
-	}
+	return nil, ""
 }
 
 // isStringer returns true if the provided declaration is a "String() string"
@@ -277,9 +421,20 @@ func isStringer(f *File, d *ast.FuncDecl) bool {
 }
 
 // isFormatter reports whether t satisfies fmt.Formatter.
-// Unlike fmt.Stringer, it's impossible to satisfy fmt.Formatter without importing fmt.
+// Unlike fmt.Stringer, it's impossible to satisfy fmt.Formatter without
+// importing fmt, so it suffices to look for a method
+// Format(fmt.State, rune).
 func (f *File) isFormatter(t types.Type) bool {
-	return formatterType != nil && types.Implements(t, formatterType)
+	obj, _, _ := types.LookupFieldOrMethod(t, false, nil, "Format")
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	return sig.Params().Len() == 2 &&
+		sig.Results().Len() == 0 &&
+		isNamedType(sig.Params().At(0).Type(), "fmt", "State") &&
+		types.Identical(sig.Params().At(1).Type(), types.Typ[types.Rune])
 }
 
 // formatState holds the parsed representation of a printf directive such as "%3.*[4]d".
@@ -301,10 +456,11 @@ type formatState struct {
 }
 
 // checkPrintf checks a call to a formatted print routine such as Printf.
-func (f *File) checkPrintf(call *ast.CallExpr, name string) {
+// The name is the unqualified name of the function, such as Errorf.
+func (f *File) checkPrintf(call *ast.CallExpr, name string, kind printfKind) {
 	format, idx := formatString(f, call)
 	if idx < 0 {
-		if *verbose {
+		if verbose() {
 			f.Warn(call.Pos(), "can't check non-constant format in call to", name)
 		}
 		return
@@ -321,6 +477,7 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string) {
 	argNum := firstArg
 	maxArgNum := firstArg
 	anyIndex := false
+	anyW := false
 	for i, w := 0, 0; i < len(format); i += w {
 		w = 1
 		if format[i] != '%' {
@@ -334,6 +491,17 @@ func (f *File) checkPrintf(call *ast.CallExpr, name string) {
 		if !f.okPrintfArg(call, state) { // One error per format is enough.
 			return
 		}
+		if state.verb == 'w' {
+			if kind != kindErrorf {
+				f.Badf(call.Pos(), "%s does not support error-wrapping directive %%w", name)
+				return
+			}
+			if anyW {
+				f.Badf(call.Pos(), "%s call has more than one error-wrapping directive %%w", name)
+				return
+			}
+			anyW = true
+		}
 		if state.hasIndex {
 			anyIndex = true
 		}
@@ -507,6 +675,7 @@ const (
 	argFloat
 	argComplex
 	argPointer
+	argError
 	anyType printfArgType = ^0
 )
 
@@ -549,6 +718,7 @@ var printVerbs = []printVerb{
 	{'T', "-", anyType},
 	{'U', "-#", argRune | argInt},
 	{'v', allFlags, anyType},
+	{'w', allFlags, argError},
 	{'x', sharpNumFlag, argRune | argInt | argString | argPointer},
 	{'X', sharpNumFlag, argRune | argInt | argString | argPointer},
 }
@@ -21,13 +21,18 @@ See: https://golang.org/doc/go_faq.html#closures_and_goroutines
 
 package main
 
-import "go/ast"
+import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
+	"go/ast"
+)
 
-func init() {
-	register("rangeloops",
-		"check that loop variables are used correctly",
-		checkLoop,
-		rangeStmt, forStmt)
+var rangeloopAnalyzer = &analysis.Analyzer{
+	Name:             "rangeloops",
+	Doc:              "check that loop variables are used correctly",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkLoop, rangeStmt, forStmt),
 }
 
 // checkLoop walks the body of the provided loop statement, checking whether
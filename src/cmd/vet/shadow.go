@@ -32,19 +32,46 @@ package main
 
 import (
 	"flag"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-var strictShadowing = flag.Bool("shadowstrict", false, "whether to be strict about shadowing; can be noisy")
+var shadowAnalyzer = &analysis.Analyzer{
+	Name:             "shadow",
+	Doc:              "check for shadowed variables (experimental; must be set explicitly)",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              runShadow,
+}
+
+var strictShadowing bool
 
 func init() {
-	register("shadow",
-		"check for shadowed variables (experimental; must be set explicitly)",
-		checkShadow,
-		assignStmt, genDecl)
-	experimental["shadow"] = true
+	shadowAnalyzer.Flags.BoolVar(&strictShadowing, "strict", false, "whether to be strict about shadowing; can be noisy")
+}
+
+func runShadow(pass *analysis.Pass) (interface{}, error) {
+	if !shadowEnabled() {
+		return nil, nil
+	}
+	walk(pass, checkShadow, assignStmt, genDecl)
+	return nil, nil
+}
+
+// shadowEnabled reports whether the shadow check was requested
+// explicitly by its flag. Being experimental, it is not enabled
+// by -all or by default.
+func shadowEnabled() bool {
+	enabled := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "shadow" {
+			enabled = f.Value.String() == "true"
+		}
+	})
+	return enabled
 }
 
 func checkShadow(f *File, node ast.Node) {
@@ -59,7 +86,7 @@ func checkShadow(f *File, node ast.Node) {
 // Span stores the minimum range of byte positions in the file in which a
 // given variable (types.Object) is mentioned. It is lexically defined: it spans
 // from the beginning of its first mention to the end of its last mention.
-// A variable is considered shadowed (if *strictShadowing is off) only if the
+// A variable is considered shadowed (if strictShadowing is off) only if the
 // shadowing variable is declared within the span of the shadowed variable.
 // In other words, if a variable is shadowed but not used after the shadowed
 // variable is declared, it is inconsequential and not worth complaining about.
@@ -86,10 +113,25 @@ func (s Span) contains(pos token.Pos) bool {
 	return s.min <= pos && pos < s.max
 }
 
+// shadowSpans returns the spans of the package's objects,
+// computing them on first use.
+func (pkg *Package) shadowSpans() map[types.Object]Span {
+	if pkg.spans == nil {
+		pkg.spans = make(map[types.Object]Span)
+		for id, obj := range pkg.defs {
+			pkg.growSpan(id, obj)
+		}
+		for id, obj := range pkg.uses {
+			pkg.growSpan(id, obj)
+		}
+	}
+	return pkg.spans
+}
+
 // growSpan expands the span for the object to contain the instance represented
 // by the identifier.
 func (pkg *Package) growSpan(ident *ast.Ident, obj types.Object) {
-	if *strictShadowing {
+	if strictShadowing {
 		return // No need
 	}
 	pos := ident.Pos()
@@ -222,7 +264,7 @@ func checkShadowing(f *File, ident *ast.Ident) {
 	if shadowed.Parent() == types.Universe {
 		return
 	}
-	if *strictShadowing {
+	if strictShadowing {
 		// The shadowed identifier must appear before this one to be an instance of shadowing.
 		if shadowed.Pos() > ident.Pos() {
 			return
@@ -230,7 +272,7 @@ func checkShadowing(f *File, ident *ast.Ident) {
 	} else {
 		// Don't complain if the span of validity of the shadowed identifier doesn't include
 		// the shadowing identifier.
-		span, ok := f.pkg.spans[shadowed]
+		span, ok := f.pkg.shadowSpans()[shadowed]
 		if !ok {
 			f.Badf(ident.Pos(), "internal error: no range for %q", ident.Name)
 			return
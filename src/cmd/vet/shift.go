@@ -9,20 +9,26 @@ This file contains the code to check for suspicious shifts.
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/constant"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("shift",
-		"check for useless shifts",
-		checkShift,
-		binaryExpr, assignStmt)
+var shiftAnalyzer = &analysis.Analyzer{
+	Name:             "shift",
+	Doc:              "check for useless shifts",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkShift, binaryExpr, assignStmt, ifStmt, switchStmt),
 }
 
 func checkShift(f *File, node ast.Node) {
+	// Find unreachable code first; the walk visits enclosing
+	// if and switch statements before the nodes they contain.
+	f.updateDead(node)
 	if f.dead[node] {
 		// Skip shift checks on unreachable nodes.
 		return
@@ -80,9 +86,9 @@ func checkLongShift(f *File, node ast.Node, x, y ast.Expr) {
 	case types.Uint64, types.Int64:
 		size = 64
 	case types.Int, types.Uint:
-		size = uintBitSize
+		size = 8 * f.pass.TypesSizes.Sizeof(types.Typ[types.Uint])
 	case types.Uintptr:
-		size = uintptrBitSize
+		size = 8 * f.pass.TypesSizes.Sizeof(types.Typ[types.Uintptr])
 	default:
 		return
 	}
@@ -91,8 +97,3 @@ func checkLongShift(f *File, node ast.Node, x, y ast.Expr) {
 		f.Badf(node.Pos(), "%s (%d bits) too small for shift of %d", ident, size, amt)
 	}
 }
-
-var (
-	uintBitSize    = 8 * archSizes.Sizeof(types.Typ[types.Uint])
-	uintptrBitSize = 8 * archSizes.Sizeof(types.Typ[types.Uintptr])
-)
@@ -8,6 +8,8 @@ package main
 
 import (
 	"errors"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"reflect"
@@ -15,11 +17,12 @@ import (
 	"strings"
 )
 
-func init() {
-	register("structtags",
-		"check that struct field tags have canonical format and apply to exported fields as needed",
-		checkStructFieldTags,
-		structType)
+var structtagAnalyzer = &analysis.Analyzer{
+	Name:             "structtags",
+	Doc:              "check that struct field tags have canonical format and apply to exported fields as needed",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkStructFieldTags, structType),
 }
 
 // checkStructFieldTags checks all the field tags of a struct, including checking for duplicates.
@@ -5,6 +5,8 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/types"
 	"strings"
@@ -12,11 +14,12 @@ import (
 	"unicode/utf8"
 )
 
-func init() {
-	register("tests",
-		"check for common mistaken usages of tests/documentation examples",
-		checkTestFunctions,
-		funcDecl)
+var testsAnalyzer = &analysis.Analyzer{
+	Name:             "tests",
+	Doc:              "check for common mistaken usages of tests/documentation examples",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkTestFunctions, funcDecl),
 }
 
 func isExampleSuffix(s string) bool {
@@ -61,25 +64,32 @@ func lookup(name string, scopes []*types.Scope) types.Object {
 
 func extendedScope(f *File) []*types.Scope {
 	scopes := []*types.Scope{f.pkg.typesPkg.Scope()}
-	if f.basePkg != nil {
-		scopes = append(scopes, f.basePkg.typesPkg.Scope())
-	} else {
-		// If basePkg is not specified (e.g. when checking a single file) try to
-		// find it among imports.
-		pkgName := f.pkg.typesPkg.Name()
-		if strings.HasSuffix(pkgName, "_test") {
-			basePkgName := strings.TrimSuffix(pkgName, "_test")
-			for _, p := range f.pkg.typesPkg.Imports() {
-				if p.Name() == basePkgName {
-					scopes = append(scopes, p.Scope())
-					break
-				}
+	// For an external test package, find the package
+	// under test among the imports.
+	pkgName := f.pkg.typesPkg.Name()
+	if strings.HasSuffix(pkgName, "_test") {
+		basePkgName := strings.TrimSuffix(pkgName, "_test")
+		for _, p := range f.pkg.typesPkg.Imports() {
+			if p.Name() == basePkgName {
+				scopes = append(scopes, p.Scope())
+				break
 			}
 		}
 	}
 	return scopes
 }
 
+// hasNonTestFiles reports whether the package being checked
+// includes any Go files other than tests.
+func hasNonTestFiles(pass *analysis.Pass) bool {
+	for _, file := range pass.Files {
+		if !strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
 func checkExample(fn *ast.FuncDecl, f *File, report reporter) {
 	fnName := fn.Name.Name
 	if params := fn.Type.Params; len(params.List) != 0 {
@@ -89,7 +99,8 @@ func checkExample(fn *ast.FuncDecl, f *File, report reporter) {
 		report("%s should return nothing", fnName)
 	}
 
-	if filesRun && !includesNonTest {
+	scopes := extendedScope(f)
+	if len(scopes) == 1 && !hasNonTestFiles(f.pass) {
 		// The coherence checks between a test and the package it tests
 		// will report false positives if no non-test files have
 		// been provided.
@@ -105,7 +116,7 @@ func checkExample(fn *ast.FuncDecl, f *File, report reporter) {
 		exName = strings.TrimPrefix(fnName, "Example")
 		elems  = strings.SplitN(exName, "_", 3)
 		ident  = elems[0]
-		obj    = lookup(ident, extendedScope(f))
+		obj    = lookup(ident, scopes)
 	)
 	if ident != "" && obj == nil {
 		// Check ExampleFoo and ExampleBadFoo.
@@ -164,7 +175,7 @@ type reporter func(format string, args ...interface{})
 // malformed names, wrong signatures and examples documenting nonexistent
 // identifiers.
 func checkTestFunctions(f *File, node ast.Node) {
-	if !strings.HasSuffix(f.name, "_test.go") {
+	if !strings.HasSuffix(f.fset.File(f.file.Pos()).Name(), "_test.go") {
 		return
 	}
 
@@ -8,32 +8,20 @@ package main
 
 import (
 	"go/ast"
-	"go/build"
-	"go/importer"
 	"go/token"
 	"go/types"
 )
 
-// stdImporter is the importer we use to import packages.
-// It is shared so that all packages are imported by the same importer.
-var stdImporter types.Importer
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
 
-var (
-	errorType     *types.Interface
-	stringerType  *types.Interface // possibly nil
-	formatterType *types.Interface // possibly nil
-)
-
-func inittypes() {
-	errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
-
-	if typ := importType("fmt", "Stringer"); typ != nil {
-		stringerType = typ.Underlying().(*types.Interface)
-	}
-	if typ := importType("fmt", "Formatter"); typ != nil {
-		formatterType = typ.Underlying().(*types.Interface)
-	}
-}
+// stringerType is the interface type of fmt.Stringer.
+// It is constructed here so that checking does not depend on
+// the package under analysis importing fmt.
+var stringerType = types.NewInterface([]*types.Func{
+	types.NewFunc(token.NoPos, nil, "String", types.NewSignature(nil, nil,
+		types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.String])),
+		false)),
+}, nil).Complete()
 
 // isNamedType reports whether t is the named type path.name.
 func isNamedType(t types.Type, path, name string) bool {
@@ -45,72 +33,6 @@ func isNamedType(t types.Type, path, name string) bool {
 	return obj.Name() == name && obj.Pkg() != nil && obj.Pkg().Path() == path
 }
 
-// importType returns the type denoted by the qualified identifier
-// path.name, and adds the respective package to the imports map
-// as a side effect. In case of an error, importType returns nil.
-func importType(path, name string) types.Type {
-	pkg, err := stdImporter.Import(path)
-	if err != nil {
-		// This can happen if the package at path hasn't been compiled yet.
-		warnf("import failed: %v", err)
-		return nil
-	}
-	if obj, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
-		return obj.Type()
-	}
-	warnf("invalid type name %q", name)
-	return nil
-}
-
-func (pkg *Package) check(fs *token.FileSet, astFiles []*ast.File) []error {
-	if stdImporter == nil {
-		if *source {
-			stdImporter = importer.For("source", nil)
-		} else {
-			stdImporter = importer.Default()
-		}
-		inittypes()
-	}
-	pkg.defs = make(map[*ast.Ident]types.Object)
-	pkg.uses = make(map[*ast.Ident]types.Object)
-	pkg.selectors = make(map[*ast.SelectorExpr]*types.Selection)
-	pkg.spans = make(map[types.Object]Span)
-	pkg.types = make(map[ast.Expr]types.TypeAndValue)
-
-	var allErrors []error
-	config := types.Config{
-		// We use the same importer for all imports to ensure that
-		// everybody sees identical packages for the given paths.
-		Importer: stdImporter,
-		// By providing a Config with our own error function, it will continue
-		// past the first error. We collect them all for printing later.
-		Error: func(e error) {
-			allErrors = append(allErrors, e)
-		},
-
-		Sizes: archSizes,
-	}
-	info := &types.Info{
-		Selections: pkg.selectors,
-		Types:      pkg.types,
-		Defs:       pkg.defs,
-		Uses:       pkg.uses,
-	}
-	typesPkg, err := config.Check(pkg.path, fs, astFiles, info)
-	if len(allErrors) == 0 && err != nil {
-		allErrors = append(allErrors, err)
-	}
-	pkg.typesPkg = typesPkg
-	// update spans
-	for id, obj := range pkg.defs {
-		pkg.growSpan(id, obj)
-	}
-	for id, obj := range pkg.uses {
-		pkg.growSpan(id, obj)
-	}
-	return allErrors
-}
-
 // matchArgType reports an error if printf verb t is not appropriate
 // for operand arg.
 //
@@ -142,6 +64,10 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 	if f.isFormatter(typ) {
 		return true
 	}
+	// %w accepts only errors.
+	if t == argError {
+		return types.ConvertibleTo(typ, errorType)
+	}
 	// If we can use a string, might arg (dynamically) implement the Stringer or Error interface?
 	if t&argString != 0 && isConvertibleToString(typ) {
 		return true
@@ -188,7 +114,7 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 		// Ugly, but dealing with an edge case: a known pointer to an invalid type,
 		// probably something from a failed import.
 		if typ.Elem().String() == "invalid type" {
-			if *verbose {
+			if verbose() {
 				f.Warnf(arg.Pos(), "printf argument %v is pointer to invalid or unknown type", f.gofmt(arg))
 			}
 			return true // special case
@@ -257,7 +183,7 @@ func (f *File) matchArgTypeInternal(t printfArgType, typ types.Type, arg ast.Exp
 			return false
 
 		case types.Invalid:
-			if *verbose {
+			if verbose() {
 				f.Warnf(arg.Pos(), "printf argument %v has invalid or unknown type", f.gofmt(arg))
 			}
 			return true // Probably a type check problem.
@@ -278,7 +204,7 @@ func isConvertibleToString(typ types.Type) bool {
 	if types.ConvertibleTo(typ, errorType) {
 		return true // via .Error()
 	}
-	if stringerType != nil && types.ConvertibleTo(typ, stringerType) {
+	if types.ConvertibleTo(typ, stringerType) {
 		return true // via .String()
 	}
 	return false
@@ -309,5 +235,3 @@ func (f *File) matchStructArgType(t printfArgType, typ *types.Struct, arg ast.Ex
 	}
 	return true
 }
-
-var archSizes = types.SizesFor("gc", build.Default.GOARCH)
@@ -7,16 +7,19 @@
 package main
 
 import (
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
 )
 
-func init() {
-	register("unsafeptr",
-		"check for misuse of unsafe.Pointer",
-		checkUnsafePointer,
-		callExpr)
+var unsafeptrAnalyzer = &analysis.Analyzer{
+	Name:             "unsafeptr",
+	Doc:              "check for misuse of unsafe.Pointer",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkUnsafePointer, callExpr),
 }
 
 func checkUnsafePointer(f *File, node ast.Node) {
@@ -8,26 +8,31 @@
 package main
 
 import (
-	"flag"
+	"go/analysis"
+	"go/analysis/passes/inspect"
 	"go/ast"
 	"go/token"
 	"go/types"
-	"strings"
 )
 
-var unusedFuncsFlag = flag.String("unusedfuncs",
-	"errors.New,fmt.Errorf,fmt.Sprintf,fmt.Sprint,sort.Reverse",
-	"comma-separated list of functions whose results must be used")
+var unusedresultAnalyzer = &analysis.Analyzer{
+	Name:             "unusedresult",
+	Doc:              "check for unused result of calls to functions in -unusedresult.funcs list and methods in -unusedresult.stringmethods list",
+	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	RunDespiteErrors: true,
+	Run:              run(checkUnusedResult, exprStmt),
+}
 
-var unusedStringMethodsFlag = flag.String("unusedstringmethods",
-	"Error,String",
-	"comma-separated list of names of methods of type func() string whose results must be used")
+var unusedFuncs, unusedStringMethods stringSetFlag
 
 func init() {
-	register("unusedresult",
-		"check for unused result of calls to functions in -unusedfuncs list and methods in -unusedstringmethods list",
-		checkUnusedResult,
-		exprStmt)
+	unusedFuncs.Set("errors.New,fmt.Errorf,fmt.Sprintf,fmt.Sprint,sort.Reverse")
+	unusedresultAnalyzer.Flags.Var(&unusedFuncs, "funcs",
+		"comma-separated list of functions whose results must be used")
+
+	unusedStringMethods.Set("Error,String")
+	unusedresultAnalyzer.Flags.Var(&unusedStringMethods, "stringmethods",
+		"comma-separated list of names of methods of type func() string whose results must be used")
 }
 
 // func() string
@@ -35,24 +40,6 @@ var sigNoArgsStringResult = types.NewSignature(nil, nil,
 	types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.String])),
 	false)
 
-var unusedFuncs = make(map[string]bool)
-var unusedStringMethods = make(map[string]bool)
-
-func initUnusedFlags() {
-	commaSplit := func(s string, m map[string]bool) {
-		if s != "" {
-			for _, name := range strings.Split(s, ",") {
-				if len(name) == 0 {
-					flag.Usage()
-				}
-				m[name] = true
-			}
-		}
-	}
-	commaSplit(*unusedFuncsFlag, unusedFuncs)
-	commaSplit(*unusedStringMethodsFlag, unusedStringMethods)
-}
-
 func checkUnusedResult(f *File, n ast.Node) {
 	call, ok := unparen(n.(*ast.ExprStmt).X).(*ast.CallExpr)
 	if !ok {
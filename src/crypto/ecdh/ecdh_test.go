@@ -0,0 +1,233 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// Check that PublicKey and PrivateKey implement the interfaces documented in
+// crypto.PublicKey and crypto.PrivateKey.
+var _ interface {
+	Equal(x crypto.PublicKey) bool
+} = &ecdh.PublicKey{}
+var _ interface {
+	Public() crypto.PublicKey
+	Equal(x crypto.PrivateKey) bool
+} = &ecdh.PrivateKey{}
+
+var curves = []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.P521(), ecdh.X25519()}
+
+func TestECDH(t *testing.T) {
+	for _, curve := range curves {
+		t.Run(fmt.Sprint(curve), func(t *testing.T) {
+			aliceKey, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bobKey, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			alicePubKey, err := curve.NewPublicKey(aliceKey.PublicKey().Bytes())
+			if err != nil {
+				t.Error(err)
+			}
+			if !alicePubKey.Equal(aliceKey.PublicKey()) {
+				t.Error("encoded and decoded public keys are different")
+			}
+			if !alicePubKey.Equal(aliceKey.Public()) {
+				t.Error("encoded and decoded public keys are different")
+			}
+
+			alicePrivKey, err := curve.NewPrivateKey(aliceKey.Bytes())
+			if err != nil {
+				t.Error(err)
+			}
+			if !alicePrivKey.Equal(aliceKey) {
+				t.Error("encoded and decoded private keys are different")
+			}
+			if !alicePrivKey.PublicKey().Equal(alicePubKey) {
+				t.Error("public key derived from the decoded private key is different")
+			}
+
+			bobSecret, err := bobKey.ECDH(aliceKey.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			aliceSecret, err := aliceKey.ECDH(bobKey.PublicKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(bobSecret, aliceSecret) {
+				t.Error("two ECDH computations came out different")
+			}
+		})
+	}
+}
+
+func TestX25519(t *testing.T) {
+	// Test vectors from RFC 7748, Section 6.1.
+	alicePriv := hexDecode(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	alicePub := hexDecode(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	bobPriv := hexDecode(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	bobPub := hexDecode(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	shared := hexDecode(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	aliceKey, err := ecdh.X25519().NewPrivateKey(alicePriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aliceKey.PublicKey().Bytes(); !bytes.Equal(got, alicePub) {
+		t.Errorf("Alice's public key = %x, want %x", got, alicePub)
+	}
+	bobKey, err := ecdh.X25519().NewPrivateKey(bobPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bobKey.PublicKey().Bytes(); !bytes.Equal(got, bobPub) {
+		t.Errorf("Bob's public key = %x, want %x", got, bobPub)
+	}
+	secret, err := aliceKey.ECDH(bobKey.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secret, shared) {
+		t.Errorf("shared secret = %x, want %x", secret, shared)
+	}
+}
+
+func TestX25519LowOrderPoint(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero, err := ecdh.X25519().NewPublicKey(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := key.ECDH(zero); err == nil {
+		t.Error("ECDH with the zero point succeeded")
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	for _, curve := range curves {
+		t.Run(fmt.Sprint(curve), func(t *testing.T) {
+			key, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			priv, pub := key.Bytes(), key.PublicKey().Bytes()
+
+			for _, bad := range [][]byte{
+				nil,
+				priv[:len(priv)-1],
+				append(priv, 0),
+			} {
+				if _, err := curve.NewPrivateKey(bad); err == nil {
+					t.Errorf("NewPrivateKey(%x) succeeded", bad)
+				}
+			}
+			for _, bad := range [][]byte{
+				nil,
+				pub[:len(pub)-1],
+				append(pub, 0),
+			} {
+				if _, err := curve.NewPublicKey(bad); err == nil {
+					t.Errorf("NewPublicKey(%x) succeeded", bad)
+				}
+			}
+			if curve == ecdh.X25519() {
+				return
+			}
+
+			// The zero scalar, the order and all ones are out of range.
+			if _, err := curve.NewPrivateKey(make([]byte, len(priv))); err == nil {
+				t.Error("NewPrivateKey accepted the zero scalar")
+			}
+			ones := bytes.Repeat([]byte{0xff}, len(priv))
+			if _, err := curve.NewPrivateKey(ones); err == nil {
+				t.Error("NewPrivateKey accepted an out of range scalar")
+			}
+			// The point at infinity, a compressed point and a point
+			// not on the curve are rejected.
+			if _, err := curve.NewPublicKey([]byte{0}); err == nil {
+				t.Error("NewPublicKey accepted the point at infinity")
+			}
+			compressed := append([]byte{2 | pub[len(pub)-1]&1}, pub[1:1+len(priv)]...)
+			if _, err := curve.NewPublicKey(compressed); err == nil {
+				t.Error("NewPublicKey accepted a compressed point")
+			}
+			offCurve := append([]byte(nil), pub...)
+			offCurve[len(offCurve)-1] ^= 1
+			if _, err := curve.NewPublicKey(offCurve); err == nil {
+				t.Error("NewPublicKey accepted a point not on the curve")
+			}
+		})
+	}
+}
+
+func TestMismatchedCurves(t *testing.T) {
+	for _, a := range curves {
+		for _, b := range curves {
+			if a == b {
+				continue
+			}
+			privA, err := a.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			privB, err := b.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := privA.ECDH(privB.PublicKey()); err == nil {
+				t.Errorf("ECDH between %v and %v succeeded", a, b)
+			}
+			if privA.PublicKey().Equal(privB.PublicKey()) || privA.Equal(privB) {
+				t.Errorf("keys on %v and %v are equal", a, b)
+			}
+		}
+	}
+}
+
+func hexDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func BenchmarkECDH(b *testing.B) {
+	for _, curve := range curves {
+		b.Run(fmt.Sprint(curve), func(b *testing.B) {
+			key, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				b.Fatal(err)
+			}
+			peer, err := curve.GenerateKey(rand.Reader)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := key.ECDH(peer.PublicKey()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
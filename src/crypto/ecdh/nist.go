@@ -0,0 +1,109 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// nistCurve implements Curve for a NIST curve using crypto/elliptic.
+type nistCurve struct {
+	name  string
+	curve elliptic.Curve
+}
+
+func (c *nistCurve) String() string {
+	return c.name
+}
+
+// scalarSize returns the length of encoded private keys and of
+// shared secrets.
+func (c *nistCurve) scalarSize() int {
+	return (c.curve.Params().BitSize + 7) / 8
+}
+
+func (c *nistCurve) GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	priv, x, y, err := elliptic.GenerateKey(c.curve, rand)
+	if err != nil {
+		return nil, err
+	}
+	return c.newPrivateKey(priv, x, y), nil
+}
+
+func (c *nistCurve) NewPrivateKey(key []byte) (*PrivateKey, error) {
+	if len(key) != c.scalarSize() {
+		return nil, errors.New("crypto/ecdh: invalid private key size")
+	}
+	k := new(big.Int).SetBytes(key)
+	if k.Sign() == 0 || k.Cmp(c.curve.Params().N) >= 0 {
+		return nil, errors.New("crypto/ecdh: invalid private key")
+	}
+	x, y := c.curve.ScalarBaseMult(key)
+	return c.newPrivateKey(append([]byte(nil), key...), x, y), nil
+}
+
+func (c *nistCurve) newPrivateKey(key []byte, x, y *big.Int) *PrivateKey {
+	return &PrivateKey{
+		curve:      c,
+		privateKey: key,
+		publicKey: &PublicKey{
+			curve:     c,
+			publicKey: elliptic.Marshal(c.curve, x, y),
+		},
+	}
+}
+
+func (c *nistCurve) NewPublicKey(key []byte) (*PublicKey, error) {
+	// Unmarshal rejects compressed points and the point at infinity,
+	// as well as points not on the curve.
+	if x, _ := elliptic.Unmarshal(c.curve, key); x == nil {
+		return nil, errors.New("crypto/ecdh: invalid public key")
+	}
+	return &PublicKey{curve: c, publicKey: append([]byte(nil), key...)}, nil
+}
+
+func (c *nistCurve) ecdh(local *PrivateKey, remote *PublicKey) ([]byte, error) {
+	x, y := elliptic.Unmarshal(c.curve, remote.publicKey)
+	x, y = c.curve.ScalarMult(x, y, local.privateKey)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		// The curves have prime order, so this can only happen
+		// with invalid keys.
+		return nil, errors.New("crypto/ecdh: ECDH result is the point at infinity")
+	}
+	shared := make([]byte, c.scalarSize())
+	xBytes := x.Bytes()
+	copy(shared[len(shared)-len(xBytes):], xBytes)
+	return shared, nil
+}
+
+var (
+	p256 = &nistCurve{"P-256", elliptic.P256()}
+	p384 = &nistCurve{"P-384", elliptic.P384()}
+	p521 = &nistCurve{"P-521", elliptic.P521()}
+)
+
+// P256 returns a Curve which implements NIST P-256 (FIPS 186-3, section D.2.3),
+// also known as secp256r1 or prime256v1.
+//
+// Multiple invocations of this function will return the same value, which can
+// be used for equality checks and switch statements.
+func P256() Curve { return p256 }
+
+// P384 returns a Curve which implements NIST P-384 (FIPS 186-3, section D.2.4),
+// also known as secp384r1.
+//
+// Multiple invocations of this function will return the same value, which can
+// be used for equality checks and switch statements.
+func P384() Curve { return p384 }
+
+// P521 returns a Curve which implements NIST P-521 (FIPS 186-3, section D.2.5),
+// also known as secp521r1.
+//
+// Multiple invocations of this function will return the same value, which can
+// be used for equality checks and switch statements.
+func P521() Curve { return p521 }
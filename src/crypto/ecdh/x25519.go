@@ -0,0 +1,83 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"errors"
+	"io"
+
+	"golang_org/x/crypto/curve25519"
+)
+
+const (
+	x25519PublicKeySize    = 32
+	x25519PrivateKeySize   = 32
+	x25519SharedSecretSize = 32
+)
+
+var (
+	errMismatchedCurves = errors.New("crypto/ecdh: private key and public key curves do not match")
+	errX25519LowOrder   = errors.New("crypto/ecdh: bad X25519 remote ECDH input: low order point")
+)
+
+// x25519Curve implements Curve for X25519.
+type x25519Curve struct{}
+
+var x25519 = &x25519Curve{}
+
+// X25519 returns a Curve which implements the X25519 function over Curve25519
+// (RFC 7748, Section 5).
+//
+// Multiple invocations of this function will return the same value, so it can
+// be used for equality checks and switch statements.
+func X25519() Curve { return x25519 }
+
+func (c *x25519Curve) String() string {
+	return "X25519"
+}
+
+func (c *x25519Curve) GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	key := make([]byte, x25519PrivateKeySize)
+	if _, err := io.ReadFull(rand, key); err != nil {
+		return nil, err
+	}
+	return c.newPrivateKey(key), nil
+}
+
+func (c *x25519Curve) NewPrivateKey(key []byte) (*PrivateKey, error) {
+	if len(key) != x25519PrivateKeySize {
+		return nil, errors.New("crypto/ecdh: invalid private key size")
+	}
+	return c.newPrivateKey(append([]byte(nil), key...)), nil
+}
+
+func (c *x25519Curve) newPrivateKey(key []byte) *PrivateKey {
+	var priv, pub [32]byte
+	copy(priv[:], key)
+	curve25519.ScalarBaseMult(&pub, &priv)
+	return &PrivateKey{
+		curve:      c,
+		privateKey: key,
+		publicKey:  &PublicKey{curve: c, publicKey: pub[:]},
+	}
+}
+
+func (c *x25519Curve) NewPublicKey(key []byte) (*PublicKey, error) {
+	if len(key) != x25519PublicKeySize {
+		return nil, errors.New("crypto/ecdh: invalid public key")
+	}
+	return &PublicKey{curve: c, publicKey: append([]byte(nil), key...)}, nil
+}
+
+func (c *x25519Curve) ecdh(local *PrivateKey, remote *PublicKey) ([]byte, error) {
+	var priv, pub, out [x25519SharedSecretSize]byte
+	copy(priv[:], local.privateKey)
+	copy(pub[:], remote.publicKey)
+	curve25519.ScalarMult(&out, &priv, &pub)
+	if out == [x25519SharedSecretSize]byte{} {
+		return nil, errX25519LowOrder
+	}
+	return out[:], nil
+}
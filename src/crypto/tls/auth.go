@@ -0,0 +1,166 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// This file contains the signature handling of the TLS 1.3 CertificateVerify
+// message. See RFC 8446, Section 4.4.3.
+
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+var signaturePadding = []byte{
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+}
+
+// signedMessageTLS13 returns the digest that is signed in a TLS 1.3
+// CertificateVerify message, given the hash of the signature scheme, the
+// context string and the current transcript. If sigHash is zero, as for
+// Ed25519, the message itself is returned.
+func signedMessageTLS13(sigHash crypto.Hash, context string, transcript hash.Hash) []byte {
+	if sigHash == 0 {
+		b := make([]byte, 0, len(signaturePadding)+len(context)+transcript.Size())
+		b = append(b, signaturePadding...)
+		b = append(b, context...)
+		return transcript.Sum(b)
+	}
+	h := sigHash.New()
+	h.Write(signaturePadding)
+	io.WriteString(h, context)
+	h.Write(transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+// signatureSchemeTLS13 returns the hash of a signature scheme permitted in
+// TLS 1.3, and whether it is an RSA-PSS scheme. TLS 1.3 forbids PKCS #1
+// v1.5 and SHA-1 signatures in CertificateVerify messages. The hash is zero
+// for Ed25519, which signs the message without pre-hashing.
+func signatureSchemeTLS13(sigAlg SignatureScheme) (sigHash crypto.Hash, isPSS bool, err error) {
+	switch sigAlg {
+	case PSSWithSHA256:
+		return crypto.SHA256, true, nil
+	case PSSWithSHA384:
+		return crypto.SHA384, true, nil
+	case PSSWithSHA512:
+		return crypto.SHA512, true, nil
+	case ECDSAWithP256AndSHA256:
+		return crypto.SHA256, false, nil
+	case ECDSAWithP384AndSHA384:
+		return crypto.SHA384, false, nil
+	case ECDSAWithP521AndSHA512:
+		return crypto.SHA512, false, nil
+	case Ed25519:
+		return crypto.Hash(0), false, nil
+	}
+	return 0, false, fmt.Errorf("tls: unsupported signature algorithm %#04x for TLS 1.3", uint16(sigAlg))
+}
+
+// pickSignatureSchemeTLS13 selects a signature scheme for the given public
+// key from the peer's list of supported algorithms, following the peer's
+// preference order.
+func pickSignatureSchemeTLS13(pub crypto.PublicKey, peerAlgs []SignatureScheme) (SignatureScheme, error) {
+	var candidates []SignatureScheme
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		candidates = []SignatureScheme{PSSWithSHA256, PSSWithSHA384, PSSWithSHA512}
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			candidates = []SignatureScheme{ECDSAWithP256AndSHA256}
+		case elliptic.P384():
+			candidates = []SignatureScheme{ECDSAWithP384AndSHA384}
+		case elliptic.P521():
+			candidates = []SignatureScheme{ECDSAWithP521AndSHA512}
+		}
+	case ed25519.PublicKey:
+		candidates = []SignatureScheme{Ed25519}
+	default:
+		return 0, fmt.Errorf("tls: unsupported certificate key type %T for TLS 1.3", pub)
+	}
+	for _, sigAlg := range peerAlgs {
+		if isSupportedSignatureAlgorithm(sigAlg, candidates) {
+			return sigAlg, nil
+		}
+	}
+	return 0, errors.New("tls: peer doesn't support any of the certificate's signature algorithms")
+}
+
+// signHandshakeTLS13 signs the transcript for a CertificateVerify message.
+func signHandshakeTLS13(rand io.Reader, key crypto.Signer, sigAlg SignatureScheme, context string, transcript hash.Hash) ([]byte, error) {
+	sigHash, isPSS, err := signatureSchemeTLS13(sigAlg)
+	if err != nil {
+		return nil, err
+	}
+	var opts crypto.SignerOpts = sigHash
+	if isPSS {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
+	}
+	return key.Sign(rand, signedMessageTLS13(sigHash, context, transcript), opts)
+}
+
+// verifyHandshakeTLS13 checks the signature of a CertificateVerify message
+// against the peer's public key and the transcript.
+func verifyHandshakeTLS13(pub crypto.PublicKey, sigAlg SignatureScheme, context string, transcript hash.Hash, sig []byte) error {
+	sigHash, isPSS, err := signatureSchemeTLS13(sigAlg)
+	if err != nil {
+		return err
+	}
+	digest := signedMessageTLS13(sigHash, context, transcript)
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if !isPSS {
+			return errors.New("tls: RSA keys require RSA-PSS signatures in TLS 1.3")
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
+		if err := rsa.VerifyPSS(pub, sigHash, digest, sig, opts); err != nil {
+			return err
+		}
+	case *ecdsa.PublicKey:
+		if isPSS || sigAlg == Ed25519 {
+			return fmt.Errorf("tls: ECDSA key used with signature algorithm %#04x", uint16(sigAlg))
+		}
+		ecdsaSig := new(ecdsaSignature)
+		if _, err := asn1.Unmarshal(sig, ecdsaSig); err != nil {
+			return err
+		}
+		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
+			return errors.New("tls: ECDSA signature contained zero or negative values")
+		}
+		if !ecdsa.Verify(pub, digest, ecdsaSig.R, ecdsaSig.S) {
+			return errors.New("tls: ECDSA verification failure")
+		}
+	case ed25519.PublicKey:
+		if sigAlg != Ed25519 {
+			return fmt.Errorf("tls: Ed25519 key used with signature algorithm %#04x", uint16(sigAlg))
+		}
+		if !ed25519.Verify(pub, digest, sig) {
+			return errors.New("tls: Ed25519 verification failure")
+		}
+	default:
+		return fmt.Errorf("tls: unsupported certificate key type %T", pub)
+	}
+	return nil
+}
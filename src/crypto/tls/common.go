@@ -26,6 +26,7 @@ const (
 	VersionTLS10 = 0x0301
 	VersionTLS11 = 0x0302
 	VersionTLS12 = 0x0303
+	VersionTLS13 = 0x0304
 )
 
 const (
@@ -37,6 +38,10 @@ const (
 
 	minVersion = VersionTLS10
 	maxVersion = VersionTLS12
+
+	// maxSessionTicketLifetime is the maximum allowed lifetime of a TLS 1.3
+	// session ticket, and the lifetime we set for tickets we send.
+	maxSessionTicketLifetime = 7 * 24 * time.Hour
 )
 
 // TLS record types.
@@ -51,19 +56,22 @@ const (
 
 // TLS handshake message types.
 const (
-	typeHelloRequest       uint8 = 0
-	typeClientHello        uint8 = 1
-	typeServerHello        uint8 = 2
-	typeNewSessionTicket   uint8 = 4
-	typeCertificate        uint8 = 11
-	typeServerKeyExchange  uint8 = 12
-	typeCertificateRequest uint8 = 13
-	typeServerHelloDone    uint8 = 14
-	typeCertificateVerify  uint8 = 15
-	typeClientKeyExchange  uint8 = 16
-	typeFinished           uint8 = 20
-	typeCertificateStatus  uint8 = 22
-	typeNextProtocol       uint8 = 67 // Not IANA assigned
+	typeHelloRequest        uint8 = 0
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeServerKeyExchange   uint8 = 12
+	typeCertificateRequest  uint8 = 13
+	typeServerHelloDone     uint8 = 14
+	typeCertificateVerify   uint8 = 15
+	typeClientKeyExchange   uint8 = 16
+	typeFinished            uint8 = 20
+	typeCertificateStatus   uint8 = 22
+	typeKeyUpdate           uint8 = 24
+	typeNextProtocol        uint8 = 67  // Not IANA assigned
+	typeMessageHash         uint8 = 254 // synthetic message
 )
 
 // TLS compression types.
@@ -73,16 +81,23 @@ const (
 
 // TLS extension numbers
 const (
-	extensionServerName          uint16 = 0
-	extensionStatusRequest       uint16 = 5
-	extensionSupportedCurves     uint16 = 10
-	extensionSupportedPoints     uint16 = 11
-	extensionSignatureAlgorithms uint16 = 13
-	extensionALPN                uint16 = 16
-	extensionSCT                 uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
-	extensionSessionTicket       uint16 = 35
-	extensionNextProtoNeg        uint16 = 13172 // not IANA assigned
-	extensionRenegotiationInfo   uint16 = 0xff01
+	extensionServerName              uint16 = 0
+	extensionStatusRequest           uint16 = 5
+	extensionSupportedCurves         uint16 = 10
+	extensionSupportedPoints         uint16 = 11
+	extensionSignatureAlgorithms     uint16 = 13
+	extensionALPN                    uint16 = 16
+	extensionSCT                     uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
+	extensionSessionTicket           uint16 = 35
+	extensionPreSharedKey            uint16 = 41
+	extensionSupportedVersions       uint16 = 43
+	extensionCookie                  uint16 = 44
+	extensionPSKModes                uint16 = 45
+	extensionCertificateAuthorities  uint16 = 47
+	extensionKeyShare                uint16 = 51
+	extensionQUICTransportParameters uint16 = 57
+	extensionNextProtoNeg            uint16 = 13172 // not IANA assigned
+	extensionRenegotiationInfo       uint16 = 0xff01
 )
 
 // TLS signaling cipher suite values
@@ -101,6 +116,26 @@ const (
 	X25519    CurveID = 29
 )
 
+// TLS 1.3 Key Share. See https://tools.ietf.org/html/rfc8446#section-4.2.8.
+type keyShare struct {
+	group CurveID
+	data  []byte
+}
+
+// TLS 1.3 PSK Key Exchange Modes. See
+// https://tools.ietf.org/html/rfc8446#section-4.2.9.
+const (
+	pskModePlain uint8 = 0
+	pskModeDHE   uint8 = 1
+)
+
+// TLS 1.3 PSK Identity. Can be a Session Ticket, or a reference to a saved
+// session. See https://tools.ietf.org/html/rfc8446#section-4.2.11.
+type pskIdentity struct {
+	label               []byte
+	obfuscatedTicketAge uint32
+}
+
 // TLS Elliptic Curve Point Formats
 // https://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-9
 const (
@@ -129,15 +164,27 @@ const (
 
 // Signature algorithms for TLS 1.2 (See RFC 5246, section A.4.1)
 const (
-	signatureRSA   uint8 = 1
-	signatureECDSA uint8 = 3
+	signatureRSA     uint8 = 1
+	signatureECDSA   uint8 = 3
+	signatureEd25519 uint8 = 7 // RFC 8422, Section 5.1.3
 )
 
 // supportedSignatureAlgorithms contains the signature and hash algorithms that
-// the code advertises as supported in a TLS 1.2 ClientHello and in a TLS 1.2
-// CertificateRequest. The two fields are merged to match with TLS 1.3.
+// the code advertises as supported in a ClientHello and in a CertificateRequest.
+// The two fields are merged to match with TLS 1.3. The RSA-PSS schemes are
+// preferred, as TLS 1.3 requires them for RSA keys and they may also be used
+// in TLS 1.2 (RFC 8446, Section 4.2.3). Ed25519 keys are used with the ECDSA
+// certificate types in TLS 1.2 (RFC 8422).
 // Note that in TLS 1.2, the ECDSA algorithms are not constrained to P-256, etc.
+//
+// The list is sent in every recorded client handshake and in the TLS 1.2
+// CertificateRequest, so changing it requires updating the testdata/Client-*
+// and testdata/Server-TLSv12-ClientAuth* recordings with -update.
 var supportedSignatureAlgorithms = []SignatureScheme{
+	PSSWithSHA256,
+	PSSWithSHA384,
+	PSSWithSHA512,
+	Ed25519,
 	PKCS1WithSHA256,
 	ECDSAWithP256AndSHA256,
 	PKCS1WithSHA384,
@@ -148,6 +195,23 @@ var supportedSignatureAlgorithms = []SignatureScheme{
 	ECDSAWithSHA1,
 }
 
+// helloRetryRequestRandom is set as the Random value of a ServerHello
+// to signal that the message is actually a HelloRetryRequest.
+var helloRetryRequestRandom = []byte{ // See RFC 8446, Section 4.1.3.
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+const (
+	// downgradeCanaryTLS12 or downgradeCanaryTLS11 is embedded in the server
+	// random as a downgrade protection if the server would be capable of
+	// negotiating a higher version. See RFC 8446, Section 4.1.3.
+	downgradeCanaryTLS12 = "DOWNGRD\x01"
+	downgradeCanaryTLS11 = "DOWNGRD\x00"
+)
+
 // ConnectionState records basic TLS details about the connection.
 type ConnectionState struct {
 	Version                     uint16                // TLS version used by the connection (e.g. VersionTLS12)
@@ -198,6 +262,13 @@ type ClientSessionState struct {
 	masterSecret       []byte                // MasterSecret generated by client on a full handshake
 	serverCertificates []*x509.Certificate   // Certificate chain presented by the server
 	verifiedChains     [][]*x509.Certificate // Certificate chains we built for verification
+
+	// TLS 1.3 fields. In TLS 1.3, masterSecret holds the resumption
+	// master secret of the connection that received the ticket.
+	receivedAt time.Time // When the session ticket was received from the server
+	nonce      []byte    // Ticket nonce sent by the server, to derive PSK
+	useBy      time.Time // Expiration of the ticket lifetime as set by the server
+	ageAdd     uint32    // Random obfuscation factor for sending the ticket age
 }
 
 // ClientSessionCache is a cache of ClientSessionState objects that can be used
@@ -232,6 +303,9 @@ const (
 	ECDSAWithP384AndSHA384 SignatureScheme = 0x0503
 	ECDSAWithP521AndSHA512 SignatureScheme = 0x0603
 
+	// EdDSA algorithms.
+	Ed25519 SignatureScheme = 0x0807
+
 	// Legacy signature and hash algorithms for TLS 1.2.
 	ECDSAWithSHA1 SignatureScheme = 0x0203
 )
@@ -417,6 +491,21 @@ type Config struct {
 	// be considered but the verifiedChains argument will always be nil.
 	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
 
+	// VerifyConnection, if not nil, is called during the handshake after
+	// normal certificate verification and after VerifyPeerCertificate, by
+	// either a TLS client or server, before the Finished message is sent.
+	// Unlike VerifyPeerCertificate, it is also called for resumed
+	// sessions, and it receives the connection state negotiated so far,
+	// including the peer's certificates and any verified chains (which are
+	// preserved across resumption). TLSUnique and ExportKeyingMaterial are
+	// not yet available. If it returns a non-nil error, the handshake is
+	// aborted with a bad_certificate alert and that error results.
+	//
+	// VerifyConnection is called regardless of InsecureSkipVerify and
+	// ClientAuth, so it can be used to replace or extend normal
+	// verification, for example to pin public keys.
+	VerifyConnection func(ConnectionState) error
+
 	// RootCAs defines the set of root certificate authorities
 	// that clients use when verifying server certificates.
 	// If RootCAs is nil, TLS uses the host's root CA set.
@@ -448,8 +537,10 @@ type Config struct {
 	// This should be used only for testing.
 	InsecureSkipVerify bool
 
-	// CipherSuites is a list of supported cipher suites. If CipherSuites
-	// is nil, TLS uses a list of suites supported by the implementation.
+	// CipherSuites is a list of supported cipher suites for TLS versions up
+	// to TLS 1.2. If CipherSuites is nil, TLS uses a list of suites
+	// supported by the implementation. The TLS 1.3 cipher suites are not
+	// configurable.
 	CipherSuites []uint16
 
 	// PreferServerCipherSuites controls whether the server selects the
@@ -483,6 +574,9 @@ type Config struct {
 	// MaxVersion contains the maximum SSL/TLS version that is acceptable.
 	// If zero, then the maximum version supported by this package is used,
 	// which is currently TLS 1.2.
+	//
+	// TLS 1.3 is only negotiated if MaxVersion is explicitly set to
+	// VersionTLS13 or higher.
 	MaxVersion uint16
 
 	// CurvePreferences contains the elliptic curves that will be used in
@@ -564,6 +658,7 @@ func (c *Config) Clone() *Config {
 		GetClientCertificate:        c.GetClientCertificate,
 		GetConfigForClient:          c.GetConfigForClient,
 		VerifyPeerCertificate:       c.VerifyPeerCertificate,
+		VerifyConnection:            c.VerifyConnection,
 		RootCAs:                     c.RootCAs,
 		NextProtos:                  c.NextProtos,
 		ServerName:                  c.ServerName,
@@ -695,11 +790,27 @@ func (c *Config) curvePreferences() []CurveID {
 	return c.CurvePreferences
 }
 
+// supportedVersions returns the protocol versions to advertise in the
+// supported_versions extension, in preference order.
+func (c *Config) supportedVersions() []uint16 {
+	var versions []uint16
+	for _, v := range []uint16{VersionTLS13, VersionTLS12, VersionTLS11, VersionTLS10, VersionSSL30} {
+		if v >= c.minVersion() && v <= c.maxVersion() {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
 // mutualVersion returns the protocol version to use given the advertised
-// version of the peer.
+// version of the peer. It is used for the legacy version negotiation:
+// TLS 1.3 can only be negotiated with the supported_versions extension.
 func (c *Config) mutualVersion(vers uint16) (uint16, bool) {
 	minVersion := c.minVersion()
 	maxVersion := c.maxVersion()
+	if maxVersion > VersionTLS12 {
+		maxVersion = VersionTLS12
+	}
 
 	if vers < minVersion {
 		return 0, false
@@ -774,14 +885,25 @@ func (c *Config) BuildNameToCertificate() {
 	}
 }
 
-// writeKeyLog logs client random and master secret if logging was enabled by
+// Key log labels, in the NSS key log format. See
+// https://developer.mozilla.org/en-US/docs/Mozilla/Projects/NSS/Key_Log_Format.
+const (
+	keyLogLabelTLS12           = "CLIENT_RANDOM"
+	keyLogLabelClientHandshake = "CLIENT_HANDSHAKE_TRAFFIC_SECRET"
+	keyLogLabelServerHandshake = "SERVER_HANDSHAKE_TRAFFIC_SECRET"
+	keyLogLabelClientTraffic   = "CLIENT_TRAFFIC_SECRET_0"
+	keyLogLabelServerTraffic   = "SERVER_TRAFFIC_SECRET_0"
+)
+
+// writeKeyLog logs client random and a secret, the master secret up to
+// TLS 1.2 or a traffic secret in TLS 1.3, if logging was enabled by
 // setting c.KeyLogWriter.
-func (c *Config) writeKeyLog(clientRandom, masterSecret []byte) error {
+func (c *Config) writeKeyLog(label string, clientRandom, secret []byte) error {
 	if c.KeyLogWriter == nil {
 		return nil
 	}
 
-	logLine := []byte(fmt.Sprintf("CLIENT_RANDOM %x %x\n", clientRandom, masterSecret))
+	logLine := []byte(fmt.Sprintf("%s %x %x\n", label, clientRandom, secret))
 
 	writerMutex.Lock()
 	_, err := c.KeyLogWriter.Write(logLine)
@@ -799,9 +921,13 @@ type Certificate struct {
 	Certificate [][]byte
 	// PrivateKey contains the private key corresponding to the public key
 	// in Leaf. For a server, this must implement crypto.Signer and/or
-	// crypto.Decrypter, with an RSA or ECDSA PublicKey. For a client
-	// (performing client authentication), this must be a crypto.Signer
-	// with an RSA or ECDSA PublicKey.
+	// crypto.Decrypter, with an RSA, ECDSA or Ed25519 PublicKey. For a
+	// client (performing client authentication), this must be a
+	// crypto.Signer with an RSA, ECDSA or Ed25519 PublicKey.
+	//
+	// Ed25519 keys can be used with TLS 1.3, and by a server with TLS 1.2
+	// if the client supports it (RFC 8422). Ed25519 client certificates
+	// require TLS 1.3.
 	PrivateKey crypto.PrivateKey
 	// OCSPStaple contains an optional OCSP response which will be served
 	// to clients that request it.
@@ -987,14 +1113,29 @@ func isSupportedSignatureAlgorithm(sigAlg SignatureScheme, supportedSignatureAlg
 	return false
 }
 
+// isRSAPSS reports whether sigAlg is one of the rsa_pss_rsae_* schemes, which
+// sign with RSA-PSS using a salt as long as the hash (RFC 8446, Section 4.2.3).
+func isRSAPSS(sigAlg SignatureScheme) bool {
+	switch sigAlg {
+	case PSSWithSHA256, PSSWithSHA384, PSSWithSHA512:
+		return true
+	default:
+		return false
+	}
+}
+
 // signatureFromSignatureScheme maps a signature algorithm to the underlying
-// signature method (without hash function).
+// signature method (without hash function). The RSA-PSS schemes map to
+// signatureRSA, as they use the same keys; see isRSAPSS for the padding.
 func signatureFromSignatureScheme(signatureAlgorithm SignatureScheme) uint8 {
 	switch signatureAlgorithm {
-	case PKCS1WithSHA1, PKCS1WithSHA256, PKCS1WithSHA384, PKCS1WithSHA512:
+	case PKCS1WithSHA1, PKCS1WithSHA256, PKCS1WithSHA384, PKCS1WithSHA512,
+		PSSWithSHA256, PSSWithSHA384, PSSWithSHA512:
 		return signatureRSA
 	case ECDSAWithSHA1, ECDSAWithP256AndSHA256, ECDSAWithP384AndSHA384, ECDSAWithP521AndSHA512:
 		return signatureECDSA
+	case Ed25519:
+		return signatureEd25519
 	default:
 		return 0
 	}
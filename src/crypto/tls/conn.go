@@ -56,6 +56,9 @@ type Conn struct {
 	secureRenegotiation bool
 	// ekm is a closure for exporting keying material.
 	ekm func(label string, context []byte, length int) ([]byte, bool)
+	// resumptionSecret is the resumption_master_secret for handling
+	// NewSessionTicket messages. nil if config.SessionTicketsDisabled.
+	resumptionSecret []byte
 
 	// clientFinishedIsFirst is true if the client sent the first Finished
 	// message during the most recent handshake. This is recorded because
@@ -101,6 +104,8 @@ type Conn struct {
 	// in Conn.Write.
 	activeCall int32
 
+	quic *quicState // nil for non-QUIC connections
+
 	tmp [16]byte
 }
 
@@ -154,6 +159,9 @@ type halfConn struct {
 	nextCipher interface{} // next encryption state
 	nextMac    macFunction // next MAC algorithm
 
+	level         QUICEncryptionLevel // current QUIC encryption level
+	trafficSecret []byte              // current TLS 1.3 traffic secret
+
 	// used to save allocating a new buffer for each MAC.
 	inDigestBuf, outDigestBuf []byte
 }
@@ -187,6 +195,21 @@ func (hc *halfConn) changeCipherSpec() error {
 	return nil
 }
 
+// setTrafficSecret sets the TLS 1.3 encryption state derived from the
+// given traffic secret. Unlike prepareCipherSpec, the new state takes
+// effect immediately, since TLS 1.3 has no ChangeCipherSpec.
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, level QUICEncryptionLevel, secret []byte) {
+	hc.trafficSecret = secret
+	hc.level = level
+	key, iv := suite.trafficKey(secret)
+	hc.version = VersionTLS13
+	hc.cipher = suite.aead(key, iv)
+	hc.mac = nil
+	for i := range hc.seq {
+		hc.seq[i] = 0
+	}
+}
+
 // incSeq increments the sequence number.
 func (hc *halfConn) incSeq() {
 	for i := 7; i >= 0; i-- {
@@ -300,16 +323,36 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 				nonce = hc.seq[:]
 			}
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			n := len(payload) - c.Overhead()
-			hc.additionalData[11] = byte(n >> 8)
-			hc.additionalData[12] = byte(n)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				n := len(payload) - c.Overhead()
+				hc.additionalData[11] = byte(n >> 8)
+				hc.additionalData[12] = byte(n)
+				additionalData = hc.additionalData[:]
+			}
 			var err error
-			payload, err = c.Open(payload[:0], nonce, payload, hc.additionalData[:])
+			payload, err = c.Open(payload[:0], nonce, payload, additionalData)
 			if err != nil {
 				return false, 0, alertBadRecordMAC
 			}
+			if hc.version == VersionTLS13 {
+				// Strip the padding and recover the real content
+				// type, which is stored back in the record header.
+				// See RFC 8446, Section 5.2.
+				i := len(payload) - 1
+				for i >= 0 && payload[i] == 0 {
+					i--
+				}
+				if i < 0 {
+					return false, 0, alertUnexpectedMessage
+				}
+				b.data[0] = payload[i]
+				payload = payload[:i]
+			}
 			b.resize(recordHeaderLen + explicitIVLen + len(payload))
 		case cbcMode:
 			blockSize := c.BlockSize()
@@ -407,6 +450,14 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 			c.XORKeyStream(payload, payload)
 		case aead:
 			payloadLen := len(b.data) - recordHeaderLen - explicitIVLen
+			if hc.version == VersionTLS13 {
+				// Append the real content type and disguise the
+				// record as application data. See RFC 8446, Section 5.2.
+				b.resize(len(b.data) + 1)
+				b.data[len(b.data)-1] = b.data[0]
+				b.data[0] = byte(recordTypeApplicationData)
+				payloadLen++
+			}
 			b.resize(len(b.data) + c.Overhead())
 			nonce := b.data[recordHeaderLen : recordHeaderLen+explicitIVLen]
 			if len(nonce) == 0 {
@@ -415,12 +466,21 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 			payload := b.data[recordHeaderLen+explicitIVLen:]
 			payload = payload[:payloadLen]
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			hc.additionalData[11] = byte(payloadLen >> 8)
-			hc.additionalData[12] = byte(payloadLen)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				n := len(b.data) - recordHeaderLen
+				b.data[3] = byte(n >> 8)
+				b.data[4] = byte(n)
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				hc.additionalData[11] = byte(payloadLen >> 8)
+				hc.additionalData[12] = byte(payloadLen)
+				additionalData = hc.additionalData[:]
+			}
 
-			c.Seal(payload[:0], nonce, payload, hc.additionalData[:])
+			c.Seal(payload[:0], nonce, payload, additionalData)
 		case cbcMode:
 			blockSize := c.BlockSize()
 			if explicitIVLen > 0 {
@@ -571,7 +631,8 @@ func (c *Conn) readRecord(want recordType) error {
 		c.sendAlert(alertInternalError)
 		return c.in.setErrorLocked(errors.New("tls: unknown record type requested"))
 	case recordTypeHandshake, recordTypeChangeCipherSpec:
-		if c.handshakeComplete {
+		// TLS 1.3 post-handshake messages may span several records.
+		if c.handshakeComplete && !(want == recordTypeHandshake && c.vers == VersionTLS13) {
 			c.sendAlert(alertInternalError)
 			return c.in.setErrorLocked(errors.New("tls: handshake or ChangeCipherSpec requested while not in handshake"))
 		}
@@ -614,9 +675,15 @@ Again:
 
 	vers := uint16(b.data[1])<<8 | uint16(b.data[2])
 	n := int(b.data[3])<<8 | int(b.data[4])
-	if c.haveVers && vers != c.vers {
+	expectedVers := c.vers
+	if expectedVers == VersionTLS13 {
+		// TLS 1.3 records carry the TLS 1.2 version number.
+		// See RFC 8446, Section 5.1.
+		expectedVers = VersionTLS12
+	}
+	if c.haveVers && vers != expectedVers {
 		c.sendAlert(alertProtocolVersion)
-		msg := fmt.Sprintf("received record with version %x when expecting version %x", vers, c.vers)
+		msg := fmt.Sprintf("received record with version %x when expecting version %x", vers, expectedVers)
 		return c.in.setErrorLocked(c.newRecordHeaderError(msg))
 	}
 	if n > maxCiphertext {
@@ -646,11 +713,31 @@ Again:
 
 	// Process message.
 	b, c.rawInput = c.in.splitBlock(b, recordHeaderLen+n)
+
+	if c.vers == VersionTLS13 && typ == recordTypeChangeCipherSpec && !c.handshakeComplete {
+		// A TLS 1.3 peer may send an unencrypted ChangeCipherSpec
+		// record during the handshake for middlebox compatibility.
+		// It must be ignored. See RFC 8446, Section 5.
+		ok := n == 1 && b.data[recordHeaderLen] == 1
+		c.in.freeBlock(b)
+		if !ok {
+			return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+		}
+		goto Again
+	}
+	if c.in.version == VersionTLS13 && c.in.cipher != nil && typ != recordTypeApplicationData {
+		c.in.freeBlock(b)
+		return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+	}
+
 	ok, off, alertValue := c.in.decrypt(b)
 	if !ok {
 		c.in.freeBlock(b)
 		return c.in.setErrorLocked(c.sendAlert(alertValue))
 	}
+	// TLS 1.3 records carry their real type inside the encryption,
+	// and decrypt stores it back in the header.
+	typ = recordType(b.data[0])
 	b.off = off
 	data := b.data[b.off:]
 	if len(data) > maxPlaintext {
@@ -720,7 +807,10 @@ Again:
 
 	case recordTypeHandshake:
 		// TODO(rsc): Should at least pick off connection close.
-		if typ != want && !(c.isClient && c.config.Renegotiation != RenegotiateNever) {
+		// In TLS 1.3, handshake messages may be sent after the
+		// handshake, and are handled by handlePostHandshakeMessage.
+		if typ != want && !(c.isClient && c.config.Renegotiation != RenegotiateNever) &&
+			!(c.vers == VersionTLS13 && c.handshakeComplete) {
 			return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
 		}
 		c.hand.Write(data)
@@ -734,6 +824,12 @@ Again:
 
 // sendAlert sends a TLS alert message.
 func (c *Conn) sendAlertLocked(err alert) error {
+	if c.quic != nil {
+		// QUIC carries alerts in CONNECTION_CLOSE frames, which are
+		// sent by the QUIC implementation when the handshake fails.
+		return c.out.setErrorLocked(&net.OpError{Op: "local error", Err: err})
+	}
+
 	switch err {
 	case alertNoRenegotiation, alertCloseNotify:
 		c.tmp[0] = alertLevelWarning
@@ -810,6 +906,9 @@ func (c *Conn) maxPayloadSizeForWrite(typ recordType, explicitIVLen int) int {
 			payloadBytes -= macSize
 		case cipher.AEAD:
 			payloadBytes -= ciph.Overhead()
+			if c.vers == VersionTLS13 {
+				payloadBytes-- // encrypted content type
+			}
 		case cbcMode:
 			blockSize := ciph.BlockSize()
 			// The payload must fit in a multiple of blockSize, with
@@ -863,6 +962,14 @@ func (c *Conn) flush() (int, error) {
 // writeRecordLocked writes a TLS record with the given type and payload to the
 // connection and updates the record layer state.
 func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
+	if c.quic != nil {
+		if typ != recordTypeHandshake {
+			return 0, errors.New("tls: internal error: sending non-handshake message to QUIC transport")
+		}
+		c.quicWriteCryptoData(c.out.level, data)
+		return len(data), nil
+	}
+
 	b := c.out.newBlock()
 	defer c.out.freeBlock(b)
 
@@ -902,6 +1009,10 @@ func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
 			// Some TLS servers fail if the record version is
 			// greater than TLS 1.0 for the initial ClientHello.
 			vers = VersionTLS10
+		} else if vers == VersionTLS13 {
+			// TLS 1.3 froze the record layer version at TLS 1.2.
+			// See RFC 8446, Section 5.1.
+			vers = VersionTLS12
 		}
 		b.data[1] = byte(vers >> 8)
 		b.data[2] = byte(vers)
@@ -944,17 +1055,28 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (int, error) {
 	return c.writeRecordLocked(typ, data)
 }
 
-// readHandshake reads the next handshake message from
-// the record layer.
-func (c *Conn) readHandshake() (interface{}, error) {
-	for c.hand.Len() < 4 {
+// readHandshakeBytes reads handshake data until c.hand contains at least n bytes.
+func (c *Conn) readHandshakeBytes(n int) error {
+	if c.quic != nil {
+		return c.quicReadHandshakeBytes(n)
+	}
+	for c.hand.Len() < n {
 		if err := c.in.err; err != nil {
-			return nil, err
+			return err
 		}
 		if err := c.readRecord(recordTypeHandshake); err != nil {
-			return nil, err
+			return err
 		}
 	}
+	return nil
+}
+
+// readHandshake reads the next handshake message from
+// the record layer.
+func (c *Conn) readHandshake() (interface{}, error) {
+	if err := c.readHandshakeBytes(4); err != nil {
+		return nil, err
+	}
 
 	data := c.hand.Bytes()
 	n := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
@@ -962,13 +1084,8 @@ func (c *Conn) readHandshake() (interface{}, error) {
 		c.sendAlertLocked(alertInternalError)
 		return nil, c.in.setErrorLocked(fmt.Errorf("tls: handshake message of length %d bytes exceeds maximum of %d bytes", n, maxHandshake))
 	}
-	for c.hand.Len() < 4+n {
-		if err := c.in.err; err != nil {
-			return nil, err
-		}
-		if err := c.readRecord(recordTypeHandshake); err != nil {
-			return nil, err
-		}
+	if err := c.readHandshakeBytes(4 + n); err != nil {
+		return nil, err
 	}
 	data = c.hand.Next(4 + n)
 	var m handshakeMessage
@@ -980,12 +1097,26 @@ func (c *Conn) readHandshake() (interface{}, error) {
 	case typeServerHello:
 		m = new(serverHelloMsg)
 	case typeNewSessionTicket:
-		m = new(newSessionTicketMsg)
+		if c.vers == VersionTLS13 {
+			m = new(newSessionTicketMsgTLS13)
+		} else {
+			m = new(newSessionTicketMsg)
+		}
+	case typeEncryptedExtensions:
+		m = new(encryptedExtensionsMsg)
 	case typeCertificate:
-		m = new(certificateMsg)
+		if c.vers == VersionTLS13 {
+			m = new(certificateMsgTLS13)
+		} else {
+			m = new(certificateMsg)
+		}
 	case typeCertificateRequest:
-		m = &certificateRequestMsg{
-			hasSignatureAndHash: c.vers >= VersionTLS12,
+		if c.vers == VersionTLS13 {
+			m = new(certificateRequestMsgTLS13)
+		} else {
+			m = &certificateRequestMsg{
+				hasSignatureAndHash: c.vers >= VersionTLS12,
+			}
 		}
 	case typeCertificateStatus:
 		m = new(certificateStatusMsg)
@@ -1003,6 +1134,8 @@ func (c *Conn) readHandshake() (interface{}, error) {
 		m = new(nextProtoMsg)
 	case typeFinished:
 		m = new(finishedMsg)
+	case typeKeyUpdate:
+		m = new(keyUpdateMsg)
 	default:
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
@@ -1121,6 +1254,80 @@ func (c *Conn) handleRenegotiation() error {
 	return c.handshakeErr
 }
 
+// verifyConnection calls Config.VerifyConnection, if set, with the
+// parameters negotiated so far. The handshake state machines call it once
+// the peer's certificates have been verified and before sending Finished,
+// and send an alert if it rejects the connection.
+func (c *Conn) verifyConnection() error {
+	if c.config.VerifyConnection == nil {
+		return nil
+	}
+	if err := c.config.VerifyConnection(c.negotiatedStateLocked()); err != nil {
+		c.sendAlert(alertBadCertificate)
+		return err
+	}
+	return nil
+}
+
+// handlePostHandshakeMessage processes a handshake message that arrived
+// after the handshake completed. Up to TLS 1.2, it indicates the start
+// of a renegotiation.
+func (c *Conn) handlePostHandshakeMessage() error {
+	if c.vers != VersionTLS13 {
+		return c.handleRenegotiation()
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	switch msg := msg.(type) {
+	case *newSessionTicketMsgTLS13:
+		return c.handleNewSessionTicket(msg)
+	case *keyUpdateMsg:
+		return c.handleKeyUpdate(msg)
+	default:
+		c.sendAlert(alertUnexpectedMessage)
+		return fmt.Errorf("tls: received unexpected handshake message of type %T", msg)
+	}
+}
+
+// handleKeyUpdate processes a TLS 1.3 KeyUpdate message, updating the
+// receiving keys and, if requested, the sending keys.
+func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
+	if c.quic != nil {
+		// QUIC has its own key update mechanism. See RFC 9001, Section 6.
+		c.sendAlert(alertUnexpectedMessage)
+		return c.in.setErrorLocked(errors.New("tls: received unexpected key update message"))
+	}
+
+	suite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if suite == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+
+	newSecret := suite.nextTrafficSecret(c.in.trafficSecret)
+	c.in.setTrafficSecret(suite, QUICEncryptionLevelApplication, newSecret)
+
+	if keyUpdate.updateRequested {
+		c.out.Lock()
+		defer c.out.Unlock()
+
+		msg := &keyUpdateMsg{}
+		if _, err := c.writeRecordLocked(recordTypeHandshake, msg.marshal()); err != nil {
+			// Surface the error at the next write.
+			c.out.setErrorLocked(err)
+			return nil
+		}
+
+		newSecret := suite.nextTrafficSecret(c.out.trafficSecret)
+		c.out.setTrafficSecret(suite, QUICEncryptionLevelApplication, newSecret)
+	}
+
+	return nil
+}
+
 // Read can be made to time out and return a net.Error with Timeout() == true
 // after a fixed time limit; see SetDeadline and SetReadDeadline.
 func (c *Conn) Read(b []byte) (n int, err error) {
@@ -1145,10 +1352,11 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 				// Soft error, like EAGAIN
 				return 0, err
 			}
-			if c.hand.Len() > 0 {
+			for c.hand.Len() > 0 {
 				// We received handshake bytes, indicating the
-				// start of a renegotiation.
-				if err := c.handleRenegotiation(); err != nil {
+				// start of a renegotiation, or, in TLS 1.3, a
+				// post-handshake message.
+				if err := c.handlePostHandshakeMessage(); err != nil {
 					return 0, err
 				}
 			}
@@ -1288,6 +1496,18 @@ func (c *Conn) Handshake() error {
 		panic("handshake should have had a result.")
 	}
 
+	if c.quic != nil {
+		if c.handshakeErr == nil {
+			c.quicHandshakeComplete()
+			// Provide the 1-RTT read secret now that the handshake is complete.
+			// The QUIC layer must not decrypt 1-RTT packets prior to completing
+			// the handshake (RFC 9001, Section 5.7).
+			c.quicSetReadSecret(QUICEncryptionLevelApplication, c.cipherSuite, c.in.trafficSecret)
+		}
+		close(c.quic.blockedc)
+		close(c.quic.signalc)
+	}
+
 	return c.handshakeErr
 }
 
@@ -1295,38 +1515,51 @@ func (c *Conn) Handshake() error {
 func (c *Conn) ConnectionState() ConnectionState {
 	c.handshakeMutex.Lock()
 	defer c.handshakeMutex.Unlock()
+	return c.connectionStateLocked()
+}
 
-	var state ConnectionState
-	state.HandshakeComplete = c.handshakeComplete
-	state.ServerName = c.serverName
+func (c *Conn) connectionStateLocked() ConnectionState {
+	if !c.handshakeComplete {
+		return ConnectionState{ServerName: c.serverName}
+	}
 
-	if c.handshakeComplete {
-		state.Version = c.vers
-		state.NegotiatedProtocol = c.clientProtocol
-		state.DidResume = c.didResume
-		state.NegotiatedProtocolIsMutual = !c.clientProtocolFallback
-		state.CipherSuite = c.cipherSuite
-		state.PeerCertificates = c.peerCertificates
-		state.VerifiedChains = c.verifiedChains
-		state.SignedCertificateTimestamps = c.scts
-		state.OCSPResponse = c.ocspResponse
-		if !c.didResume {
-			if c.clientFinishedIsFirst {
-				state.TLSUnique = c.clientFinished[:]
-			} else {
-				state.TLSUnique = c.serverFinished[:]
-			}
-		}
-		if c.config.Renegotiation != RenegotiateNever {
-			state.ExportKeyingMaterial = noExportedKeyingMaterial
+	state := c.negotiatedStateLocked()
+	state.HandshakeComplete = true
+	if !c.didResume && c.vers != VersionTLS13 {
+		if c.clientFinishedIsFirst {
+			state.TLSUnique = c.clientFinished[:]
 		} else {
-			state.ExportKeyingMaterial = c.ekm
+			state.TLSUnique = c.serverFinished[:]
 		}
 	}
+	if c.config.Renegotiation != RenegotiateNever {
+		state.ExportKeyingMaterial = noExportedKeyingMaterial
+	} else {
+		state.ExportKeyingMaterial = c.ekm
+	}
 
 	return state
 }
 
+// negotiatedStateLocked returns the parameters negotiated so far in the
+// current handshake. It leaves out TLSUnique and ExportKeyingMaterial,
+// which are only available once the Finished messages have been exchanged.
+func (c *Conn) negotiatedStateLocked() ConnectionState {
+	return ConnectionState{
+		Version:                     c.vers,
+		HandshakeComplete:           c.handshakeComplete,
+		DidResume:                   c.didResume,
+		CipherSuite:                 c.cipherSuite,
+		NegotiatedProtocol:          c.clientProtocol,
+		NegotiatedProtocolIsMutual:  !c.clientProtocolFallback,
+		ServerName:                  c.serverName,
+		PeerCertificates:            c.peerCertificates,
+		VerifiedChains:              c.verifiedChains,
+		SignedCertificateTimestamps: c.scts,
+		OCSPResponse:                c.ocspResponse,
+	}
+}
+
 // OCSPResponse returns the stapled OCSP response from the TLS server, if
 // any. (Only valid for client connections.)
 func (c *Conn) OCSPResponse() []byte {
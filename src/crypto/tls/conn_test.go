@@ -6,6 +6,7 @@ package tls
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net"
 	"testing"
@@ -284,3 +285,109 @@ func TestHairpinInClose(t *testing.T) {
 	// This call should not deadlock.
 	tlsConn.Close()
 }
+
+func TestTLS13RecordLayer(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x42}, 48)
+	payload := []byte("TLS 1.3 record payload")
+	for _, suite := range cipherSuitesTLS13 {
+		var in, out halfConn
+		trafficSecret := secret[:suite.hash.Size()]
+		out.setTrafficSecret(suite, QUICEncryptionLevelInitial, trafficSecret)
+		in.setTrafficSecret(suite, QUICEncryptionLevelInitial, trafficSecret)
+
+		for i := 0; i < 2; i++ {
+			b := out.newBlock()
+			b.resize(recordHeaderLen + len(payload))
+			b.data[0] = byte(recordTypeHandshake)
+			b.data[1], b.data[2] = 3, 3
+			b.data[3] = byte(len(payload) >> 8)
+			b.data[4] = byte(len(payload))
+			copy(b.data[recordHeaderLen:], payload)
+			out.encrypt(b, 0)
+
+			if recordType(b.data[0]) != recordTypeApplicationData {
+				t.Errorf("suite %x: encrypted record has type %d, want %d", suite.id, b.data[0], recordTypeApplicationData)
+			}
+			if bytes.Contains(b.data, payload) {
+				t.Errorf("suite %x: encrypted record contains the plaintext", suite.id)
+			}
+
+			ok, prefixLen, _ := in.decrypt(b)
+			if !ok {
+				t.Fatalf("suite %x: failed to decrypt record %d", suite.id, i)
+			}
+			if recordType(b.data[0]) != recordTypeHandshake {
+				t.Errorf("suite %x: decrypted record has type %d, want %d", suite.id, b.data[0], recordTypeHandshake)
+			}
+			if got := b.data[prefixLen:]; !bytes.Equal(got, payload) {
+				t.Errorf("suite %x: got payload %q, want %q", suite.id, got, payload)
+			}
+		}
+	}
+}
+
+func TestTLS13KeyUpdate(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- func() error {
+			sconn, err := ln.Accept()
+			if err != nil {
+				return err
+			}
+			defer sconn.Close()
+			server := Server(sconn, testConfigTLS13())
+			if err := server.Handshake(); err != nil {
+				return err
+			}
+
+			// Update the sending keys and request that the client
+			// update its own.
+			server.out.Lock()
+			msg := &keyUpdateMsg{updateRequested: true}
+			_, err = server.writeRecordLocked(recordTypeHandshake, msg.marshal())
+			if err == nil {
+				suite := cipherSuiteTLS13ByID(server.cipherSuite)
+				server.out.setTrafficSecret(suite, QUICEncryptionLevelInitial, suite.nextTrafficSecret(server.out.trafficSecret))
+			}
+			server.out.Unlock()
+			if err != nil {
+				return err
+			}
+
+			if _, err := server.Write([]byte("ping")); err != nil {
+				return err
+			}
+			buf := make([]byte, len("pong"))
+			if _, err := io.ReadFull(server, buf); err != nil {
+				return err
+			}
+			if string(buf) != "pong" {
+				return fmt.Errorf("server read %q, want %q", buf, "pong")
+			}
+			return nil
+		}()
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := Client(conn, testConfigTLS13())
+	buf := make([]byte, len("ping"))
+	if _, err := io.ReadFull(client, buf); err != nil {
+		t.Fatalf("client read failed: %s", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("client read %q, want %q", buf, "ping")
+	}
+	if _, err := client.Write([]byte("pong")); err != nil {
+		t.Fatalf("client write failed: %s", err)
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatalf("server: %s", err)
+	}
+}
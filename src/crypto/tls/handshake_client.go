@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -17,6 +18,7 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type clientHandshakeState struct {
@@ -29,26 +31,35 @@ type clientHandshakeState struct {
 	session      *ClientSessionState
 }
 
-func makeClientHello(config *Config) (*clientHelloMsg, error) {
+// makeClientHello returns the ClientHello to send and, if TLS 1.3 is
+// offered, the ECDHE parameters of its key share.
+func makeClientHello(config *Config) (*clientHelloMsg, ecdheParameters, error) {
 	if len(config.ServerName) == 0 && !config.InsecureSkipVerify {
-		return nil, errors.New("tls: either ServerName or InsecureSkipVerify must be specified in the tls.Config")
+		return nil, nil, errors.New("tls: either ServerName or InsecureSkipVerify must be specified in the tls.Config")
 	}
 
 	nextProtosLength := 0
 	for _, proto := range config.NextProtos {
 		if l := len(proto); l == 0 || l > 255 {
-			return nil, errors.New("tls: invalid NextProtos value")
+			return nil, nil, errors.New("tls: invalid NextProtos value")
 		} else {
 			nextProtosLength += 1 + l
 		}
 	}
 
 	if nextProtosLength > 0xffff {
-		return nil, errors.New("tls: NextProtos values too large")
+		return nil, nil, errors.New("tls: NextProtos values too large")
+	}
+
+	vers := config.maxVersion()
+	if vers > VersionTLS12 {
+		// TLS 1.3 is offered in the supported_versions extension, and
+		// the legacy version field is frozen at TLS 1.2.
+		vers = VersionTLS12
 	}
 
 	hello := &clientHelloMsg{
-		vers:                         config.maxVersion(),
+		vers:                         vers,
 		compressionMethods:           []uint8{compressionNone},
 		random:                       make([]byte, 32),
 		ocspStapling:                 true,
@@ -81,14 +92,33 @@ NextCipherSuite:
 
 	_, err := io.ReadFull(config.rand(), hello.random)
 	if err != nil {
-		return nil, errors.New("tls: short read from Rand: " + err.Error())
+		return nil, nil, errors.New("tls: short read from Rand: " + err.Error())
 	}
 
 	if hello.vers >= VersionTLS12 {
 		hello.supportedSignatureAlgorithms = supportedSignatureAlgorithms
 	}
 
-	return hello, nil
+	var params ecdheParameters
+	if config.maxVersion() >= VersionTLS13 {
+		hello.supportedVersions = config.supportedVersions()
+		hello.cipherSuites = append(append([]uint16(nil), defaultCipherSuitesTLS13...), hello.cipherSuites...)
+
+		// Only one key share is sent, for the most preferred group.
+		// If the server prefers another, it will send a HelloRetryRequest.
+		curveID := config.curvePreferences()[0]
+		if _, ok := curveForCurveID(curveID); curveID != X25519 && !ok {
+			return nil, nil, errors.New("tls: CurvePreferences includes unsupported curve")
+		}
+		params, err = generateECDHEParameters(config.rand(), curveID)
+		if err != nil {
+			return nil, nil, err
+		}
+		hello.keyShares = []keyShare{{group: curveID, data: params.PublicKey()}}
+		hello.pskModes = []uint8{pskModeDHE}
+	}
+
+	return hello, params, nil
 }
 
 func (c *Conn) clientHandshake() error {
@@ -100,7 +130,7 @@ func (c *Conn) clientHandshake() error {
 	// need to be reset.
 	c.didResume = false
 
-	hello, err := makeClientHello(c.config)
+	hello, ecdheParams, err := makeClientHello(c.config)
 	if err != nil {
 		return err
 	}
@@ -112,10 +142,19 @@ func (c *Conn) clientHandshake() error {
 	var session *ClientSessionState
 	var cacheKey string
 	sessionCache := c.config.ClientSessionCache
-	if c.config.SessionTicketsDisabled {
+	if c.config.SessionTicketsDisabled || c.quic != nil {
+		// Session resumption is not supported for QUIC connections,
+		// which would also need to remember the transport parameters.
 		sessionCache = nil
 	}
 
+	if c.quic != nil {
+		hello.quicTransportParameters, err = c.quicGetTransportParameters()
+		if err != nil {
+			return err
+		}
+	}
+
 	if sessionCache != nil {
 		hello.ticketSupported = true
 	}
@@ -141,13 +180,37 @@ func (c *Conn) clientHandshake() error {
 
 			versOk := candidateSession.vers >= c.config.minVersion() &&
 				candidateSession.vers <= c.config.maxVersion()
+			// TLS 1.3 tickets expire at the end of the lifetime set
+			// by the server. See RFC 8446, Section 4.6.1.
+			if candidateSession.vers == VersionTLS13 && c.config.time().After(candidateSession.useBy) {
+				versOk = false
+			}
 			if versOk && cipherSuiteOk {
 				session = candidateSession
 			}
 		}
 	}
 
-	if session != nil {
+	var earlySecret, binderKey []byte
+	if session != nil && session.vers == VersionTLS13 {
+		// In TLS 1.3, the ticket is offered as a pre-shared key
+		// identity, authenticated by a binder over the ClientHello.
+		// See RFC 8446, Section 4.2.11.
+		suite := cipherSuiteTLS13ByID(session.cipherSuite)
+		ticketAge := uint32(c.config.time().Sub(session.receivedAt) / time.Millisecond)
+		hello.pskIdentities = []pskIdentity{{
+			label:               session.sessionTicket,
+			obfuscatedTicketAge: ticketAge + session.ageAdd,
+		}}
+		hello.pskBinders = [][]byte{make([]byte, suite.hash.Size())}
+
+		psk := suite.expandLabel(session.masterSecret, "resumption", session.nonce, suite.hash.Size())
+		earlySecret = suite.extract(psk, nil)
+		binderKey = suite.deriveSecret(earlySecret, resumptionBinderLabel, nil)
+		transcript := suite.hash.New()
+		transcript.Write(hello.marshalWithoutBinders())
+		hello.updateBinders([][]byte{suite.finishedHash(binderKey, transcript)})
+	} else if session != nil {
 		hello.sessionTicket = session.sessionTicket
 		// A random session ID is used to detect when the
 		// server accepted the ticket and is resuming a session
@@ -158,10 +221,58 @@ func (c *Conn) clientHandshake() error {
 		}
 	}
 
+	// send ClientHello
+	if _, err := c.writeRecord(recordTypeHandshake, hello.marshal()); err != nil {
+		return err
+	}
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	serverHello, ok := msg.(*serverHelloMsg)
+	if !ok {
+		c.sendAlert(alertUnexpectedMessage)
+		return unexpectedMessageError(serverHello, msg)
+	}
+
+	if err := c.pickTLSVersion(serverHello); err != nil {
+		return err
+	}
+
+	if c.vers == VersionTLS13 {
+		hs := &clientHandshakeStateTLS13{
+			c:           c,
+			serverHello: serverHello,
+			hello:       hello,
+			ecdheParams: ecdheParams,
+			session:     session,
+			earlySecret: earlySecret,
+			binderKey:   binderKey,
+		}
+
+		// In TLS 1.3, session tickets are delivered after the
+		// handshake, and cached by handleNewSessionTicket.
+		return hs.handshake()
+	}
+
+	// If we negotiated a version lower than the one we support, check
+	// for the server downgrade canaries. See RFC 8446, Section 4.1.3.
+	maxVers := c.config.maxVersion()
+	tls12Downgrade := string(serverHello.random[24:]) == downgradeCanaryTLS12
+	tls11Downgrade := string(serverHello.random[24:]) == downgradeCanaryTLS11
+	if maxVers >= VersionTLS13 && c.vers <= VersionTLS12 && (tls12Downgrade || tls11Downgrade) ||
+		maxVers == VersionTLS12 && c.vers <= VersionTLS11 && tls11Downgrade {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: downgrade attempt detected, possibly due to a MitM attack or a broken middlebox")
+	}
+
 	hs := &clientHandshakeState{
-		c:       c,
-		hello:   hello,
-		session: session,
+		c:           c,
+		serverHello: serverHello,
+		hello:       hello,
+		session:     session,
 	}
 
 	if err = hs.handshake(); err != nil {
@@ -178,31 +289,12 @@ func (c *Conn) clientHandshake() error {
 }
 
 // Does the handshake, either a full one or resumes old session.
-// Requires hs.c, hs.hello, and, optionally, hs.session to be set.
+// Requires hs.c, hs.hello, hs.serverHello, and, optionally, hs.session to
+// be set.
 func (hs *clientHandshakeState) handshake() error {
 	c := hs.c
 
-	// send ClientHello
-	if _, err := c.writeRecord(recordTypeHandshake, hs.hello.marshal()); err != nil {
-		return err
-	}
-
-	msg, err := c.readHandshake()
-	if err != nil {
-		return err
-	}
-
-	var ok bool
-	if hs.serverHello, ok = msg.(*serverHelloMsg); !ok {
-		c.sendAlert(alertUnexpectedMessage)
-		return unexpectedMessageError(hs.serverHello, msg)
-	}
-
-	if err = hs.pickTLSVersion(); err != nil {
-		return err
-	}
-
-	if err = hs.pickCipherSuite(); err != nil {
+	if err := hs.pickCipherSuite(); err != nil {
 		return err
 	}
 
@@ -236,6 +328,10 @@ func (hs *clientHandshakeState) handshake() error {
 			return err
 		}
 		c.clientFinishedIsFirst = false
+		c.didResume = true
+		if err := c.verifyConnection(); err != nil {
+			return err
+		}
 		if err := hs.sendFinished(c.clientFinished[:]); err != nil {
 			return err
 		}
@@ -265,22 +361,30 @@ func (hs *clientHandshakeState) handshake() error {
 	}
 
 	c.ekm = ekmFromMasterSecret(c.vers, hs.suite, hs.masterSecret, hs.hello.random, hs.serverHello.random)
-	c.didResume = isResume
 	c.handshakeComplete = true
 
 	return nil
 }
 
-func (hs *clientHandshakeState) pickTLSVersion() error {
-	vers, ok := hs.c.config.mutualVersion(hs.serverHello.vers)
+func (c *Conn) pickTLSVersion(serverHello *serverHelloMsg) error {
+	peerVersion := serverHello.vers
+	vers, ok := c.config.mutualVersion(peerVersion)
+	if serverHello.supportedVersion != 0 {
+		// TLS 1.3 can only be selected with the supported_versions
+		// extension, which must not be used to select older versions.
+		// See RFC 8446, Section 4.2.1.
+		peerVersion = serverHello.supportedVersion
+		vers = peerVersion
+		ok = peerVersion == VersionTLS13 && c.config.maxVersion() >= VersionTLS13
+	}
 	if !ok || vers < VersionTLS10 {
 		// TLS 1.0 is the minimum version supported as a client.
-		hs.c.sendAlert(alertProtocolVersion)
-		return fmt.Errorf("tls: server selected unsupported protocol version %x", hs.serverHello.vers)
+		c.sendAlert(alertProtocolVersion)
+		return fmt.Errorf("tls: server selected unsupported protocol version %x", peerVersion)
 	}
 
-	hs.c.vers = vers
-	hs.c.haveVers = true
+	c.vers = vers
+	c.haveVers = true
 
 	return nil
 }
@@ -351,7 +455,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 		}
 
 		switch certs[0].PublicKey.(type) {
-		case *rsa.PublicKey, *ecdsa.PublicKey:
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
 			break
 		default:
 			c.sendAlert(alertUnsupportedCertificate)
@@ -402,6 +506,10 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 		}
 	}
 
+	if err := c.verifyConnection(); err != nil {
+		return err
+	}
+
 	keyAgreement := hs.suite.ka(c.vers)
 
 	skx, ok := msg.(*serverKeyExchangeMsg)
@@ -485,6 +593,8 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 			signatureType = signatureECDSA
 		case *rsa.PublicKey:
 			signatureType = signatureRSA
+		case ed25519.PublicKey:
+			signatureType = signatureEd25519
 		default:
 			c.sendAlert(alertInternalError)
 			return fmt.Errorf("tls: failed to sign handshake with client certificate: unknown client certificate key type: %T", key)
@@ -492,7 +602,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 
 		// SignatureAndHashAlgorithm was introduced in TLS 1.2.
 		if certVerify.hasSignatureAndHash {
-			certVerify.signatureAlgorithm, err = hs.finishedHash.selectClientCertSignatureAlgorithm(certReq.supportedSignatureAlgorithms, signatureType)
+			certVerify.signatureAlgorithm, err = hs.finishedHash.selectClientCertSignatureAlgorithm(certReq.supportedSignatureAlgorithms, signatureType, key.Public())
 			if err != nil {
 				c.sendAlert(alertInternalError)
 				return err
@@ -503,7 +613,11 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 			c.sendAlert(alertInternalError)
 			return err
 		}
-		certVerify.signature, err = key.Sign(c.config.rand(), digest, hashFunc)
+		var signOpts crypto.SignerOpts = hashFunc
+		if isRSAPSS(certVerify.signatureAlgorithm) {
+			signOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hashFunc}
+		}
+		certVerify.signature, err = key.Sign(c.config.rand(), digest, signOpts)
 		if err != nil {
 			c.sendAlert(alertInternalError)
 			return err
@@ -516,7 +630,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	}
 
 	hs.masterSecret = masterFromPreMasterSecret(c.vers, hs.suite, preMasterSecret, hs.hello.random, hs.serverHello.random)
-	if err := c.config.writeKeyLog(hs.hello.random, hs.masterSecret); err != nil {
+	if err := c.config.writeKeyLog(keyLogLabelTLS12, hs.hello.random, hs.masterSecret); err != nil {
 		c.sendAlert(alertInternalError)
 		return errors.New("tls: failed to write to key log: " + err.Error())
 	}
@@ -801,6 +915,7 @@ findCert:
 			switch {
 			case rsaAvail && x509Cert.PublicKeyAlgorithm == x509.RSA:
 			case ecdsaAvail && x509Cert.PublicKeyAlgorithm == x509.ECDSA:
+			case ecdsaAvail && x509Cert.PublicKeyAlgorithm == x509.Ed25519 && certReq.hasSignatureAndHash:
 			default:
 				continue findCert
 			}
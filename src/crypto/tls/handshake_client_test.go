@@ -745,6 +745,49 @@ func TestClientResumption(t *testing.T) {
 	testResumeState("WithoutSessionCache", false)
 }
 
+func TestTLS13Resumption(t *testing.T) {
+	serverConfig := testConfigTLS13()
+	clientConfig := testConfigTLS13()
+	clientConfig.ServerName = "example.golang"
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(32)
+
+	serverState, clientState, err := testHandshakeTLS13(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("first handshake failed: %s", err)
+	}
+	if serverState.DidResume || clientState.DidResume {
+		t.Fatal("first handshake resumed a session")
+	}
+	if _, ok := clientConfig.ClientSessionCache.Get(clientSessionCacheKey(nil, clientConfig)); !ok {
+		t.Fatal("no session ticket was stored after the first handshake")
+	}
+
+	serverState, clientState, err = testHandshakeTLS13(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("second handshake failed: %s", err)
+	}
+	if !serverState.DidResume || !clientState.DidResume {
+		t.Errorf("second handshake did not resume: server %v, client %v", serverState.DidResume, clientState.DidResume)
+	}
+	if serverState.Version != VersionTLS13 || clientState.Version != VersionTLS13 {
+		t.Errorf("got versions %x and %x, want %x", serverState.Version, clientState.Version, VersionTLS13)
+	}
+	if len(clientState.PeerCertificates) != 1 {
+		t.Errorf("resumed session has %d peer certificates, want 1", len(clientState.PeerCertificates))
+	}
+
+	// Rotating the server's ticket keys makes the ticket undecryptable,
+	// and the server must fall back to a full handshake.
+	serverConfig.SetSessionTicketKeys([][32]byte{{1}})
+	serverState, clientState, err = testHandshakeTLS13(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("third handshake failed: %s", err)
+	}
+	if serverState.DidResume || clientState.DidResume {
+		t.Error("resumed a session with an unknown ticket key")
+	}
+}
+
 func TestLRUClientSessionCache(t *testing.T) {
 	// Initialize cache of capacity 4.
 	cache := NewLRUClientSessionCache(4)
@@ -1241,6 +1284,109 @@ func TestVerifyPeerCertificate(t *testing.T) {
 	}
 }
 
+func TestVerifyConnection(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		testVerifyConnection(t, version)
+	}
+}
+
+func testVerifyConnection(t *testing.T, version uint16) {
+	issuer, err := x509.ParseCertificate(testRSACertificateIssuer)
+	if err != nil {
+		panic(err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(issuer)
+	now := func() time.Time { return time.Unix(1476984729, 0) }
+	sentinelErr := errors.New("TestVerifyConnection")
+
+	var clientStates, serverStates []ConnectionState
+	var clientReject, serverReject bool
+
+	serverConfig := testConfigTLS13()
+	serverConfig.MaxVersion = version
+	serverConfig.ClientAuth = RequireAndVerifyClientCert
+	serverConfig.ClientCAs = rootCAs
+	serverConfig.Time = now
+	serverConfig.VerifyConnection = func(cs ConnectionState) error {
+		serverStates = append(serverStates, cs)
+		if serverReject {
+			return sentinelErr
+		}
+		return nil
+	}
+
+	clientConfig := testConfigTLS13()
+	clientConfig.MaxVersion = version
+	clientConfig.ServerName = "example.golang"
+	clientConfig.InsecureSkipVerify = false
+	clientConfig.RootCAs = rootCAs
+	clientConfig.Time = now
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(32)
+	clientConfig.VerifyConnection = func(cs ConnectionState) error {
+		clientStates = append(clientStates, cs)
+		if clientReject {
+			return sentinelErr
+		}
+		return nil
+	}
+
+	checkStates := func(name string, didResume bool) {
+		for side, states := range map[string][]ConnectionState{"client": clientStates, "server": serverStates} {
+			if len(states) != 1 {
+				t.Errorf("%x/%s: %s called VerifyConnection %d times, want 1", version, name, side, len(states))
+				continue
+			}
+			cs := states[0]
+			// VerifyConnection runs before Finished is sent.
+			if cs.HandshakeComplete || cs.Version != version || cs.DidResume != didResume || cs.CipherSuite == 0 {
+				t.Errorf("%x/%s: %s got HandshakeComplete %v, Version %x, DidResume %v, CipherSuite %x; want false, %x, %v, non-zero",
+					version, name, side, cs.HandshakeComplete, cs.Version, cs.DidResume, cs.CipherSuite, version, didResume)
+			}
+			if len(cs.PeerCertificates) != 1 || len(cs.VerifiedChains) == 0 {
+				t.Errorf("%x/%s: %s got %d peer certificates and %d verified chains",
+					version, name, side, len(cs.PeerCertificates), len(cs.VerifiedChains))
+			}
+		}
+		clientStates, serverStates = nil, nil
+	}
+
+	if _, _, err := testHandshakeTLS13(t, clientConfig, serverConfig); err != nil {
+		t.Fatalf("%x: first handshake failed: %s", version, err)
+	}
+	checkStates("full", false)
+
+	if _, _, err := testHandshakeTLS13(t, clientConfig, serverConfig); err != nil {
+		t.Fatalf("%x: resumed handshake failed: %s", version, err)
+	}
+	checkStates("resumed", true)
+
+	// Resumed sessions can be rejected too.
+	serverReject = true
+	_, _, err = testHandshakeTLS13(t, clientConfig, serverConfig)
+	if err == nil || !strings.Contains(err.Error(), sentinelErr.Error()) {
+		t.Errorf("%x: got error %v, want the server's VerifyConnection error", version, err)
+	}
+	if len(serverStates) != 1 || !serverStates[0].DidResume {
+		t.Errorf("%x: server VerifyConnection was not called for a resumed session", version)
+	}
+	serverReject = false
+	clientStates, serverStates = nil, nil
+
+	// The server may notice the client closing the connection first, in
+	// which case testHandshakeTLS13 reports the server's error.
+	clientReject = true
+	_, _, err = testHandshakeTLS13(t, clientConfig, serverConfig)
+	if err == nil {
+		t.Errorf("%x: handshake succeeded although the client's VerifyConnection failed", version)
+	} else if strings.HasPrefix(err.Error(), "client:") && !strings.Contains(err.Error(), sentinelErr.Error()) {
+		t.Errorf("%x: got error %v, want the client's VerifyConnection error", version, err)
+	}
+	if len(clientStates) != 1 {
+		t.Errorf("%x: client called VerifyConnection %d times, want 1", version, len(clientStates))
+	}
+}
+
 // brokenConn wraps a net.Conn and causes all Writes after a certain number to
 // fail with brokenConnErr.
 type brokenConn struct {
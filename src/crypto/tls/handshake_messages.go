@@ -28,6 +28,13 @@ type clientHelloMsg struct {
 	secureRenegotiation          []byte
 	secureRenegotiationSupported bool
 	alpnProtocols                []string
+	supportedVersions            []uint16
+	cookie                       []byte
+	keyShares                    []keyShare
+	pskModes                     []uint8
+	pskIdentities                []pskIdentity
+	pskBinders                   [][]byte
+	quicTransportParameters      []byte
 }
 
 func (m *clientHelloMsg) equal(i interface{}) bool {
@@ -53,7 +60,14 @@ func (m *clientHelloMsg) equal(i interface{}) bool {
 		eqSignatureAlgorithms(m.supportedSignatureAlgorithms, m1.supportedSignatureAlgorithms) &&
 		m.secureRenegotiationSupported == m1.secureRenegotiationSupported &&
 		bytes.Equal(m.secureRenegotiation, m1.secureRenegotiation) &&
-		eqStrings(m.alpnProtocols, m1.alpnProtocols)
+		eqStrings(m.alpnProtocols, m1.alpnProtocols) &&
+		eqUint16s(m.supportedVersions, m1.supportedVersions) &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		eqKeyShares(m.keyShares, m1.keyShares) &&
+		bytes.Equal(m.pskModes, m1.pskModes) &&
+		eqPSKIdentities(m.pskIdentities, m1.pskIdentities) &&
+		eqByteSlices(m.pskBinders, m1.pskBinders) &&
+		bytes.Equal(m.quicTransportParameters, m1.quicTransportParameters)
 }
 
 func (m *clientHelloMsg) marshal() []byte {
@@ -109,6 +123,39 @@ func (m *clientHelloMsg) marshal() []byte {
 	if m.scts {
 		numExtensions++
 	}
+	if len(m.supportedVersions) > 0 {
+		extensionsLength += 1 + 2*len(m.supportedVersions)
+		numExtensions++
+	}
+	if len(m.cookie) > 0 {
+		extensionsLength += 2 + len(m.cookie)
+		numExtensions++
+	}
+	if m.quicTransportParameters != nil {
+		extensionsLength += len(m.quicTransportParameters)
+		numExtensions++
+	}
+	if len(m.keyShares) > 0 {
+		extensionsLength += 2
+		for _, ks := range m.keyShares {
+			extensionsLength += 4 + len(ks.data)
+		}
+		numExtensions++
+	}
+	if len(m.pskModes) > 0 {
+		extensionsLength += 1 + len(m.pskModes)
+		numExtensions++
+	}
+	if len(m.pskIdentities) > 0 {
+		extensionsLength += 2 + 2
+		for _, psk := range m.pskIdentities {
+			extensionsLength += 2 + len(psk.label) + 4
+		}
+		for _, binder := range m.pskBinders {
+			extensionsLength += 1 + len(binder)
+		}
+		numExtensions++
+	}
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
 		length += 2 + extensionsLength
@@ -291,12 +338,167 @@ func (m *clientHelloMsg) marshal() []byte {
 		// zero uint16 for the zero-length extension_data
 		z = z[4:]
 	}
+	if len(m.supportedVersions) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		l := 1 + 2*len(m.supportedVersions)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(l - 1)
+		z = z[5:]
+		for _, vers := range m.supportedVersions {
+			z[0] = byte(vers >> 8)
+			z[1] = byte(vers)
+			z = z[2:]
+		}
+	}
+	if len(m.cookie) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.2
+		z[0] = byte(extensionCookie >> 8)
+		z[1] = byte(extensionCookie)
+		l := 2 + len(m.cookie)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.cookie) >> 8)
+		z[5] = byte(len(m.cookie))
+		copy(z[6:], m.cookie)
+		z = z[6+len(m.cookie):]
+	}
+	if m.quicTransportParameters != nil {
+		// https://www.rfc-editor.org/rfc/rfc9001#section-8.2
+		z[0] = byte(extensionQUICTransportParameters >> 8)
+		z[1] = byte(extensionQUICTransportParameters)
+		l := len(m.quicTransportParameters)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		copy(z[4:], m.quicTransportParameters)
+		z = z[4+l:]
+	}
+	if len(m.keyShares) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		lengths := z[2:]
+		z = z[6:]
+
+		sharesLength := 0
+		for _, ks := range m.keyShares {
+			z[0] = byte(ks.group >> 8)
+			z[1] = byte(ks.group)
+			z[2] = byte(len(ks.data) >> 8)
+			z[3] = byte(len(ks.data))
+			copy(z[4:], ks.data)
+			z = z[4+len(ks.data):]
+			sharesLength += 4 + len(ks.data)
+		}
+
+		lengths[2] = byte(sharesLength >> 8)
+		lengths[3] = byte(sharesLength)
+		sharesLength += 2
+		lengths[0] = byte(sharesLength >> 8)
+		lengths[1] = byte(sharesLength)
+	}
+	if len(m.pskModes) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.9
+		z[0] = byte(extensionPSKModes >> 8)
+		z[1] = byte(extensionPSKModes)
+		l := 1 + len(m.pskModes)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.pskModes))
+		copy(z[5:], m.pskModes)
+		z = z[5+len(m.pskModes):]
+	}
+	if len(m.pskIdentities) > 0 {
+		// The pre_shared_key extension must be the last extension,
+		// as the binders sign the ClientHello up to them.
+		// https://tools.ietf.org/html/rfc8446#section-4.2.11
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		lengths := z[2:]
+		z = z[6:]
+
+		identitiesLength := 0
+		for _, psk := range m.pskIdentities {
+			z[0] = byte(len(psk.label) >> 8)
+			z[1] = byte(len(psk.label))
+			copy(z[2:], psk.label)
+			z = z[2+len(psk.label):]
+			z[0] = byte(psk.obfuscatedTicketAge >> 24)
+			z[1] = byte(psk.obfuscatedTicketAge >> 16)
+			z[2] = byte(psk.obfuscatedTicketAge >> 8)
+			z[3] = byte(psk.obfuscatedTicketAge)
+			z = z[4:]
+			identitiesLength += 2 + len(psk.label) + 4
+		}
+		lengths[2] = byte(identitiesLength >> 8)
+		lengths[3] = byte(identitiesLength)
+
+		bindersLength := 0
+		for _, binder := range m.pskBinders {
+			bindersLength += 1 + len(binder)
+		}
+		z[0] = byte(bindersLength >> 8)
+		z[1] = byte(bindersLength)
+		z = z[2:]
+		for _, binder := range m.pskBinders {
+			z[0] = byte(len(binder))
+			copy(z[1:], binder)
+			z = z[1+len(binder):]
+		}
+
+		l := 2 + identitiesLength + 2 + bindersLength
+		lengths[0] = byte(l >> 8)
+		lengths[1] = byte(l)
+	}
 
 	m.raw = x
 
 	return x
 }
 
+// bindersLength returns the length of the binders list at the end of the
+// marshaled message, including its length prefix.
+func (m *clientHelloMsg) bindersLength() int {
+	length := 2
+	for _, binder := range m.pskBinders {
+		length += 1 + len(binder)
+	}
+	return length
+}
+
+// marshalWithoutBinders returns the ClientHello through the
+// PreSharedKeyExtension.identities field, according to RFC 8446, Section
+// 4.2.11.2. Note that m.pskBinders must be set to slices of the correct length.
+func (m *clientHelloMsg) marshalWithoutBinders() []byte {
+	fullMessage := m.marshal()
+	return fullMessage[:len(fullMessage)-m.bindersLength()]
+}
+
+// updateBinders updates the m.pskBinders field, if necessary updating the
+// cached marshaled representation. The supplied binders must have the same
+// length as the current m.pskBinders.
+func (m *clientHelloMsg) updateBinders(pskBinders [][]byte) {
+	if len(pskBinders) != len(m.pskBinders) {
+		panic("tls: internal error: pskBinders length mismatch")
+	}
+	for i := range m.pskBinders {
+		if len(pskBinders[i]) != len(m.pskBinders[i]) {
+			panic("tls: internal error: pskBinders length mismatch")
+		}
+	}
+	m.pskBinders = pskBinders
+	if m.raw != nil {
+		z := m.raw[len(m.raw)-m.bindersLength()+2:]
+		for _, binder := range m.pskBinders {
+			z[0] = byte(len(binder))
+			copy(z[1:], binder)
+			z = z[1+len(binder):]
+		}
+	}
+}
+
 func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	if len(data) < 42 {
 		return false
@@ -347,6 +549,13 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 	m.supportedSignatureAlgorithms = nil
 	m.alpnProtocols = nil
 	m.scts = false
+	m.supportedVersions = nil
+	m.cookie = nil
+	m.keyShares = nil
+	m.pskModes = nil
+	m.pskIdentities = nil
+	m.pskBinders = nil
+	m.quicTransportParameters = nil
 
 	if len(data) == 0 {
 		// ClientHello is optionally followed by extension data
@@ -496,6 +705,115 @@ func (m *clientHelloMsg) unmarshal(data []byte) bool {
 			if length != 0 {
 				return false
 			}
+		case extensionSupportedVersions:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.1
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if l%2 == 1 || length != l+1 {
+				return false
+			}
+			d := data[1:length]
+			for len(d) > 0 {
+				m.supportedVersions = append(m.supportedVersions, uint16(d[0])<<8|uint16(d[1]))
+				d = d[2:]
+			}
+		case extensionCookie:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.2
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
+		case extensionQUICTransportParameters:
+			// https://www.rfc-editor.org/rfc/rfc9001#section-8.2
+			m.quicTransportParameters = data[:length:length]
+		case extensionKeyShare:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.8
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if length != l+2 {
+				return false
+			}
+			d := data[2:length]
+			for len(d) > 0 {
+				if len(d) < 4 {
+					return false
+				}
+				group := CurveID(d[0])<<8 | CurveID(d[1])
+				shareLen := int(d[2])<<8 | int(d[3])
+				d = d[4:]
+				if shareLen == 0 || len(d) < shareLen {
+					return false
+				}
+				m.keyShares = append(m.keyShares, keyShare{group: group, data: d[:shareLen]})
+				d = d[shareLen:]
+			}
+		case extensionPSKModes:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.9
+			if length < 1 {
+				return false
+			}
+			l := int(data[0])
+			if length != l+1 {
+				return false
+			}
+			m.pskModes = data[1:length]
+		case extensionPreSharedKey:
+			// https://tools.ietf.org/html/rfc8446#section-4.2.11
+			if len(data) != length {
+				// The pre_shared_key extension must be last.
+				return false
+			}
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			d := data[2:]
+			if l == 0 || len(d) < l {
+				return false
+			}
+			identities := d[:l]
+			d = d[l:]
+			for len(identities) > 0 {
+				if len(identities) < 2 {
+					return false
+				}
+				labelLen := int(identities[0])<<8 | int(identities[1])
+				identities = identities[2:]
+				if labelLen == 0 || len(identities) < labelLen+4 {
+					return false
+				}
+				psk := pskIdentity{label: identities[:labelLen]}
+				identities = identities[labelLen:]
+				psk.obfuscatedTicketAge = uint32(identities[0])<<24 | uint32(identities[1])<<16 |
+					uint32(identities[2])<<8 | uint32(identities[3])
+				identities = identities[4:]
+				m.pskIdentities = append(m.pskIdentities, psk)
+			}
+			if len(d) < 2 {
+				return false
+			}
+			l = int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if l == 0 || len(d) != l {
+				return false
+			}
+			for len(d) > 0 {
+				binderLen := int(d[0])
+				d = d[1:]
+				if binderLen == 0 || len(d) < binderLen {
+					return false
+				}
+				m.pskBinders = append(m.pskBinders, d[:binderLen])
+				d = d[binderLen:]
+			}
 		}
 		data = data[length:]
 	}
@@ -518,6 +836,16 @@ type serverHelloMsg struct {
 	secureRenegotiation          []byte
 	secureRenegotiationSupported bool
 	alpnProtocol                 string
+
+	// TLS 1.3 extensions.
+	supportedVersion        uint16
+	serverShare             keyShare
+	selectedIdentityPresent bool
+	selectedIdentity        uint16
+
+	// HelloRetryRequest extensions.
+	cookie        []byte
+	selectedGroup CurveID
 }
 
 func (m *serverHelloMsg) equal(i interface{}) bool {
@@ -547,7 +875,14 @@ func (m *serverHelloMsg) equal(i interface{}) bool {
 		m.ticketSupported == m1.ticketSupported &&
 		m.secureRenegotiationSupported == m1.secureRenegotiationSupported &&
 		bytes.Equal(m.secureRenegotiation, m1.secureRenegotiation) &&
-		m.alpnProtocol == m1.alpnProtocol
+		m.alpnProtocol == m1.alpnProtocol &&
+		m.supportedVersion == m1.supportedVersion &&
+		m.serverShare.group == m1.serverShare.group &&
+		bytes.Equal(m.serverShare.data, m1.serverShare.data) &&
+		m.selectedIdentityPresent == m1.selectedIdentityPresent &&
+		m.selectedIdentity == m1.selectedIdentity &&
+		bytes.Equal(m.cookie, m1.cookie) &&
+		m.selectedGroup == m1.selectedGroup
 }
 
 func (m *serverHelloMsg) marshal() []byte {
@@ -593,6 +928,26 @@ func (m *serverHelloMsg) marshal() []byte {
 		extensionsLength += 2 + sctLen
 		numExtensions++
 	}
+	if m.supportedVersion != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if m.serverShare.group != 0 {
+		extensionsLength += 4 + len(m.serverShare.data)
+		numExtensions++
+	}
+	if m.selectedIdentityPresent {
+		extensionsLength += 2
+		numExtensions++
+	}
+	if len(m.cookie) > 0 {
+		extensionsLength += 2 + len(m.cookie)
+		numExtensions++
+	}
+	if m.selectedGroup != 0 {
+		extensionsLength += 2
+		numExtensions++
+	}
 
 	if numExtensions > 0 {
 		extensionsLength += 4 * numExtensions
@@ -688,6 +1043,61 @@ func (m *serverHelloMsg) marshal() []byte {
 			z = z[len(sct)+2:]
 		}
 	}
+	if m.supportedVersion != 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.1
+		z[0] = byte(extensionSupportedVersions >> 8)
+		z[1] = byte(extensionSupportedVersions)
+		z[3] = 2
+		z[4] = byte(m.supportedVersion >> 8)
+		z[5] = byte(m.supportedVersion)
+		z = z[6:]
+	}
+	if m.serverShare.group != 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		l := 4 + len(m.serverShare.data)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(m.serverShare.group >> 8)
+		z[5] = byte(m.serverShare.group)
+		z[6] = byte(len(m.serverShare.data) >> 8)
+		z[7] = byte(len(m.serverShare.data))
+		copy(z[8:], m.serverShare.data)
+		z = z[8+len(m.serverShare.data):]
+	}
+	if m.selectedIdentityPresent {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.11
+		z[0] = byte(extensionPreSharedKey >> 8)
+		z[1] = byte(extensionPreSharedKey)
+		z[3] = 2
+		z[4] = byte(m.selectedIdentity >> 8)
+		z[5] = byte(m.selectedIdentity)
+		z = z[6:]
+	}
+	if len(m.cookie) > 0 {
+		// https://tools.ietf.org/html/rfc8446#section-4.2.2
+		z[0] = byte(extensionCookie >> 8)
+		z[1] = byte(extensionCookie)
+		l := 2 + len(m.cookie)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(len(m.cookie) >> 8)
+		z[5] = byte(len(m.cookie))
+		copy(z[6:], m.cookie)
+		z = z[6+len(m.cookie):]
+	}
+	if m.selectedGroup != 0 {
+		// In a HelloRetryRequest, the key_share extension only
+		// carries the selected group.
+		// https://tools.ietf.org/html/rfc8446#section-4.2.8
+		z[0] = byte(extensionKeyShare >> 8)
+		z[1] = byte(extensionKeyShare)
+		z[3] = 2
+		z[4] = byte(m.selectedGroup >> 8)
+		z[5] = byte(m.selectedGroup)
+		z = z[6:]
+	}
 
 	m.raw = x
 
@@ -720,6 +1130,12 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 	m.scts = nil
 	m.ticketSupported = false
 	m.alpnProtocol = ""
+	m.supportedVersion = 0
+	m.serverShare = keyShare{}
+	m.selectedIdentityPresent = false
+	m.selectedIdentity = 0
+	m.cookie = nil
+	m.selectedGroup = 0
 
 	if len(data) == 0 {
 		// ServerHello is optionally followed by extension data
@@ -827,6 +1243,41 @@ func (m *serverHelloMsg) unmarshal(data []byte) bool {
 				m.scts = append(m.scts, d[:sctLen])
 				d = d[sctLen:]
 			}
+		case extensionSupportedVersions:
+			if length != 2 {
+				return false
+			}
+			m.supportedVersion = uint16(data[0])<<8 | uint16(data[1])
+		case extensionKeyShare:
+			if length == 2 {
+				// HelloRetryRequest, carrying only the group.
+				m.selectedGroup = CurveID(data[0])<<8 | CurveID(data[1])
+				break
+			}
+			if length < 4 {
+				return false
+			}
+			shareLen := int(data[2])<<8 | int(data[3])
+			if shareLen == 0 || length != shareLen+4 {
+				return false
+			}
+			m.serverShare.group = CurveID(data[0])<<8 | CurveID(data[1])
+			m.serverShare.data = data[4:length]
+		case extensionPreSharedKey:
+			if length != 2 {
+				return false
+			}
+			m.selectedIdentityPresent = true
+			m.selectedIdentity = uint16(data[0])<<8 | uint16(data[1])
+		case extensionCookie:
+			if length < 2 {
+				return false
+			}
+			l := int(data[0])<<8 | int(data[1])
+			if l == 0 || length != l+2 {
+				return false
+			}
+			m.cookie = data[2:length]
 		}
 		data = data[length:]
 	}
@@ -1491,23 +1942,686 @@ func (m *newSessionTicketMsg) unmarshal(data []byte) bool {
 	return true
 }
 
-type helloRequestMsg struct {
+type encryptedExtensionsMsg struct {
+	raw                     []byte
+	alpnProtocol            string
+	quicTransportParameters []byte
 }
 
-func (*helloRequestMsg) marshal() []byte {
-	return []byte{typeHelloRequest, 0, 0, 0}
+func (m *encryptedExtensionsMsg) equal(i interface{}) bool {
+	m1, ok := i.(*encryptedExtensionsMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.alpnProtocol == m1.alpnProtocol &&
+		bytes.Equal(m.quicTransportParameters, m1.quicTransportParameters)
 }
 
-func (*helloRequestMsg) unmarshal(data []byte) bool {
-	return len(data) == 4
+func (m *encryptedExtensionsMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.3.1
+	extensionsLength := 0
+	alpnLen := len(m.alpnProtocol)
+	if alpnLen > 0 {
+		if alpnLen >= 256 {
+			panic("invalid ALPN protocol")
+		}
+		extensionsLength += 4 + 2 + 1 + alpnLen
+	}
+	if m.quicTransportParameters != nil {
+		extensionsLength += 4 + len(m.quicTransportParameters)
+	}
+
+	length := 2 + extensionsLength
+	x := make([]byte, 4+length)
+	x[0] = typeEncryptedExtensions
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[4] = uint8(extensionsLength >> 8)
+	x[5] = uint8(extensionsLength)
+	z := x[6:]
+	if alpnLen > 0 {
+		z[0] = byte(extensionALPN >> 8)
+		z[1] = byte(extensionALPN & 0xff)
+		l := 2 + 1 + alpnLen
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		l -= 2
+		z[4] = byte(l >> 8)
+		z[5] = byte(l)
+		l -= 1
+		z[6] = byte(l)
+		copy(z[7:], m.alpnProtocol)
+		z = z[7+alpnLen:]
+	}
+	if m.quicTransportParameters != nil {
+		// https://www.rfc-editor.org/rfc/rfc9001#section-8.2
+		z[0] = byte(extensionQUICTransportParameters >> 8)
+		z[1] = byte(extensionQUICTransportParameters)
+		l := len(m.quicTransportParameters)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		copy(z[4:], m.quicTransportParameters)
+	}
+
+	m.raw = x
+	return x
 }
 
-func eqUint16s(x, y []uint16) bool {
-	if len(x) != len(y) {
+func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
+	m.raw = data
+	m.alpnProtocol = ""
+	m.quicTransportParameters = nil
+
+	if len(data) < 6 {
 		return false
 	}
-	for i, v := range x {
-		if y[i] != v {
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+	extensionsLength := int(data[4])<<8 | int(data[5])
+	data = data[6:]
+	if extensionsLength != len(data) {
+		return false
+	}
+
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
+			return false
+		}
+
+		switch extension {
+		case extensionALPN:
+			d := data[:length]
+			if len(d) < 3 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			if l != len(d)-2 {
+				return false
+			}
+			d = d[2:]
+			l = int(d[0])
+			if l != len(d)-1 {
+				return false
+			}
+			d = d[1:]
+			if len(d) == 0 {
+				// ALPN protocols must not be empty.
+				return false
+			}
+			m.alpnProtocol = string(d)
+		case extensionQUICTransportParameters:
+			// https://www.rfc-editor.org/rfc/rfc9001#section-8.2
+			m.quicTransportParameters = data[:length:length]
+		}
+		data = data[length:]
+	}
+
+	return true
+}
+
+// certificateMsgTLS13 is the TLS 1.3 Certificate message, which adds a
+// request context and per-certificate extensions. The OCSP staple and
+// SCTs are carried in the extensions of the leaf certificate.
+type certificateMsgTLS13 struct {
+	raw          []byte
+	certificates [][]byte
+	ocspStaple   []byte
+	scts         [][]byte
+}
+
+func (m *certificateMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqByteSlices(m.certificates, m1.certificates) &&
+		bytes.Equal(m.ocspStaple, m1.ocspStaple) &&
+		eqByteSlices(m.scts, m1.scts)
+}
+
+func (m *certificateMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.4.2
+	leafExtensionsLength := 0
+	if len(m.ocspStaple) > 0 {
+		leafExtensionsLength += 4 + 1 + 3 + len(m.ocspStaple)
+	}
+	sctLen := 0
+	if len(m.scts) > 0 {
+		for _, sct := range m.scts {
+			sctLen += 2 + len(sct)
+		}
+		leafExtensionsLength += 4 + 2 + sctLen
+	}
+
+	certificatesLength := 0
+	for i, cert := range m.certificates {
+		certificatesLength += 3 + len(cert) + 2
+		if i == 0 {
+			certificatesLength += leafExtensionsLength
+		}
+	}
+
+	length := 1 + 3 + certificatesLength
+	x := make([]byte, 4+length)
+	x[0] = typeCertificate
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	// x[4] is the empty certificate_request_context.
+	x[5] = uint8(certificatesLength >> 16)
+	x[6] = uint8(certificatesLength >> 8)
+	x[7] = uint8(certificatesLength)
+
+	z := x[8:]
+	for i, cert := range m.certificates {
+		z[0] = uint8(len(cert) >> 16)
+		z[1] = uint8(len(cert) >> 8)
+		z[2] = uint8(len(cert))
+		copy(z[3:], cert)
+		z = z[3+len(cert):]
+
+		if i != 0 {
+			// No extensions.
+			z = z[2:]
+			continue
+		}
+		z[0] = uint8(leafExtensionsLength >> 8)
+		z[1] = uint8(leafExtensionsLength)
+		z = z[2:]
+		if len(m.ocspStaple) > 0 {
+			z[0] = byte(extensionStatusRequest >> 8)
+			z[1] = byte(extensionStatusRequest)
+			l := 1 + 3 + len(m.ocspStaple)
+			z[2] = byte(l >> 8)
+			z[3] = byte(l)
+			z[4] = statusTypeOCSP
+			z[5] = byte(len(m.ocspStaple) >> 16)
+			z[6] = byte(len(m.ocspStaple) >> 8)
+			z[7] = byte(len(m.ocspStaple))
+			copy(z[8:], m.ocspStaple)
+			z = z[8+len(m.ocspStaple):]
+		}
+		if len(m.scts) > 0 {
+			z[0] = byte(extensionSCT >> 8)
+			z[1] = byte(extensionSCT)
+			l := 2 + sctLen
+			z[2] = byte(l >> 8)
+			z[3] = byte(l)
+			z[4] = byte(sctLen >> 8)
+			z[5] = byte(sctLen)
+			z = z[6:]
+			for _, sct := range m.scts {
+				z[0] = byte(len(sct) >> 8)
+				z[1] = byte(len(sct))
+				copy(z[2:], sct)
+				z = z[2+len(sct):]
+			}
+		}
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *certificateMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+	m.certificates = nil
+	m.ocspStaple = nil
+	m.scts = nil
+
+	if len(data) < 8 {
+		return false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+	contextLen := int(data[4])
+	data = data[5:]
+	if len(data) < contextLen+3 {
+		return false
+	}
+	data = data[contextLen:]
+	certificatesLength := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	data = data[3:]
+	if certificatesLength != len(data) {
+		return false
+	}
+
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return false
+		}
+		certLen := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+		data = data[3:]
+		if certLen == 0 || len(data) < certLen+2 {
+			return false
+		}
+		m.certificates = append(m.certificates, data[:certLen])
+		data = data[certLen:]
+
+		extensionsLength := int(data[0])<<8 | int(data[1])
+		data = data[2:]
+		if len(data) < extensionsLength {
+			return false
+		}
+		extensions := data[:extensionsLength]
+		data = data[extensionsLength:]
+
+		for len(extensions) > 0 {
+			if len(extensions) < 4 {
+				return false
+			}
+			extension := uint16(extensions[0])<<8 | uint16(extensions[1])
+			length := int(extensions[2])<<8 | int(extensions[3])
+			extensions = extensions[4:]
+			if len(extensions) < length {
+				return false
+			}
+			d := extensions[:length]
+			extensions = extensions[length:]
+
+			if len(m.certificates) > 1 {
+				// Only the leaf certificate extensions are used.
+				continue
+			}
+			switch extension {
+			case extensionStatusRequest:
+				if len(d) < 4 || d[0] != statusTypeOCSP {
+					return false
+				}
+				l := int(d[1])<<16 | int(d[2])<<8 | int(d[3])
+				if l == 0 || l != len(d)-4 {
+					return false
+				}
+				m.ocspStaple = d[4:]
+			case extensionSCT:
+				if len(d) < 2 {
+					return false
+				}
+				l := int(d[0])<<8 | int(d[1])
+				d = d[2:]
+				if l == 0 || l != len(d) {
+					return false
+				}
+				for len(d) > 0 {
+					if len(d) < 2 {
+						return false
+					}
+					sctLen := int(d[0])<<8 | int(d[1])
+					d = d[2:]
+					if sctLen == 0 || len(d) < sctLen {
+						return false
+					}
+					m.scts = append(m.scts, d[:sctLen])
+					d = d[sctLen:]
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// certificateRequestMsgTLS13 is the TLS 1.3 CertificateRequest message,
+// which carries its parameters in extensions. Post-handshake
+// authentication is not supported, so the request context is empty.
+type certificateRequestMsgTLS13 struct {
+	raw                          []byte
+	supportedSignatureAlgorithms []SignatureScheme
+	certificateAuthorities       [][]byte
+}
+
+func (m *certificateRequestMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*certificateRequestMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		eqSignatureAlgorithms(m.supportedSignatureAlgorithms, m1.supportedSignatureAlgorithms) &&
+		eqByteSlices(m.certificateAuthorities, m1.certificateAuthorities)
+}
+
+func (m *certificateRequestMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.3.2
+	extensionsLength := 0
+	if len(m.supportedSignatureAlgorithms) > 0 {
+		extensionsLength += 4 + 2 + 2*len(m.supportedSignatureAlgorithms)
+	}
+	casLength := 0
+	if len(m.certificateAuthorities) > 0 {
+		for _, ca := range m.certificateAuthorities {
+			casLength += 2 + len(ca)
+		}
+		extensionsLength += 4 + 2 + casLength
+	}
+
+	length := 1 + 2 + extensionsLength
+	x := make([]byte, 4+length)
+	x[0] = typeCertificateRequest
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	// x[4] is the empty certificate_request_context.
+	x[5] = uint8(extensionsLength >> 8)
+	x[6] = uint8(extensionsLength)
+
+	z := x[7:]
+	if len(m.supportedSignatureAlgorithms) > 0 {
+		z[0] = byte(extensionSignatureAlgorithms >> 8)
+		z[1] = byte(extensionSignatureAlgorithms)
+		l := 2 + 2*len(m.supportedSignatureAlgorithms)
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		l -= 2
+		z[4] = byte(l >> 8)
+		z[5] = byte(l)
+		z = z[6:]
+		for _, sigAlgo := range m.supportedSignatureAlgorithms {
+			z[0] = byte(sigAlgo >> 8)
+			z[1] = byte(sigAlgo)
+			z = z[2:]
+		}
+	}
+	if len(m.certificateAuthorities) > 0 {
+		z[0] = byte(extensionCertificateAuthorities >> 8)
+		z[1] = byte(extensionCertificateAuthorities)
+		l := 2 + casLength
+		z[2] = byte(l >> 8)
+		z[3] = byte(l)
+		z[4] = byte(casLength >> 8)
+		z[5] = byte(casLength)
+		z = z[6:]
+		for _, ca := range m.certificateAuthorities {
+			z[0] = byte(len(ca) >> 8)
+			z[1] = byte(len(ca))
+			copy(z[2:], ca)
+			z = z[2+len(ca):]
+		}
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *certificateRequestMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+	m.supportedSignatureAlgorithms = nil
+	m.certificateAuthorities = nil
+
+	if len(data) < 7 {
+		return false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+	contextLen := int(data[4])
+	data = data[5:]
+	if len(data) < contextLen+2 {
+		return false
+	}
+	data = data[contextLen:]
+	extensionsLength := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+	if extensionsLength != len(data) {
+		return false
+	}
+
+	for len(data) != 0 {
+		if len(data) < 4 {
+			return false
+		}
+		extension := uint16(data[0])<<8 | uint16(data[1])
+		length := int(data[2])<<8 | int(data[3])
+		data = data[4:]
+		if len(data) < length {
+			return false
+		}
+		d := data[:length]
+		data = data[length:]
+
+		switch extension {
+		case extensionSignatureAlgorithms:
+			if len(d) < 2 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if l == 0 || l%2 == 1 || l != len(d) {
+				return false
+			}
+			for len(d) > 0 {
+				m.supportedSignatureAlgorithms = append(m.supportedSignatureAlgorithms, SignatureScheme(d[0])<<8|SignatureScheme(d[1]))
+				d = d[2:]
+			}
+		case extensionCertificateAuthorities:
+			if len(d) < 2 {
+				return false
+			}
+			l := int(d[0])<<8 | int(d[1])
+			d = d[2:]
+			if l == 0 || l != len(d) {
+				return false
+			}
+			for len(d) > 0 {
+				if len(d) < 2 {
+					return false
+				}
+				caLen := int(d[0])<<8 | int(d[1])
+				d = d[2:]
+				if caLen == 0 || len(d) < caLen {
+					return false
+				}
+				m.certificateAuthorities = append(m.certificateAuthorities, d[:caLen])
+				d = d[caLen:]
+			}
+		}
+	}
+
+	return true
+}
+
+type newSessionTicketMsgTLS13 struct {
+	raw      []byte
+	lifetime uint32
+	ageAdd   uint32
+	nonce    []byte
+	label    []byte
+}
+
+func (m *newSessionTicketMsgTLS13) equal(i interface{}) bool {
+	m1, ok := i.(*newSessionTicketMsgTLS13)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.lifetime == m1.lifetime &&
+		m.ageAdd == m1.ageAdd &&
+		bytes.Equal(m.nonce, m1.nonce) &&
+		bytes.Equal(m.label, m1.label)
+}
+
+func (m *newSessionTicketMsgTLS13) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.6.1
+	length := 4 + 4 + 1 + len(m.nonce) + 2 + len(m.label) + 2
+	x := make([]byte, 4+length)
+	x[0] = typeNewSessionTicket
+	x[1] = uint8(length >> 16)
+	x[2] = uint8(length >> 8)
+	x[3] = uint8(length)
+	x[4] = uint8(m.lifetime >> 24)
+	x[5] = uint8(m.lifetime >> 16)
+	x[6] = uint8(m.lifetime >> 8)
+	x[7] = uint8(m.lifetime)
+	x[8] = uint8(m.ageAdd >> 24)
+	x[9] = uint8(m.ageAdd >> 16)
+	x[10] = uint8(m.ageAdd >> 8)
+	x[11] = uint8(m.ageAdd)
+	x[12] = uint8(len(m.nonce))
+	z := x[13:]
+	copy(z, m.nonce)
+	z = z[len(m.nonce):]
+	z[0] = uint8(len(m.label) >> 8)
+	z[1] = uint8(len(m.label))
+	copy(z[2:], m.label)
+	// No extensions: the final two bytes are zero.
+
+	m.raw = x
+	return x
+}
+
+func (m *newSessionTicketMsgTLS13) unmarshal(data []byte) bool {
+	m.raw = data
+
+	if len(data) < 13 {
+		return false
+	}
+	length := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if length != len(data)-4 {
+		return false
+	}
+	m.lifetime = uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7])
+	m.ageAdd = uint32(data[8])<<24 | uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+	nonceLen := int(data[12])
+	data = data[13:]
+	if len(data) < nonceLen+2 {
+		return false
+	}
+	m.nonce = data[:nonceLen]
+	data = data[nonceLen:]
+	labelLen := int(data[0])<<8 | int(data[1])
+	data = data[2:]
+	if labelLen == 0 || len(data) < labelLen+2 {
+		return false
+	}
+	m.label = data[:labelLen]
+	data = data[labelLen:]
+
+	// Extensions, such as early_data, are not used.
+	extensionsLength := int(data[0])<<8 | int(data[1])
+	return extensionsLength == len(data)-2
+}
+
+type keyUpdateMsg struct {
+	raw             []byte
+	updateRequested bool
+}
+
+func (m *keyUpdateMsg) equal(i interface{}) bool {
+	m1, ok := i.(*keyUpdateMsg)
+	if !ok {
+		return false
+	}
+
+	return bytes.Equal(m.raw, m1.raw) &&
+		m.updateRequested == m1.updateRequested
+}
+
+func (m *keyUpdateMsg) marshal() []byte {
+	if m.raw != nil {
+		return m.raw
+	}
+
+	// See https://tools.ietf.org/html/rfc8446#section-4.6.3
+	x := []byte{typeKeyUpdate, 0, 0, 1, 0}
+	if m.updateRequested {
+		x[4] = 1
+	}
+
+	m.raw = x
+	return x
+}
+
+func (m *keyUpdateMsg) unmarshal(data []byte) bool {
+	m.raw = data
+
+	if len(data) != 5 || data[1] != 0 || data[2] != 0 || data[3] != 1 {
+		return false
+	}
+	switch data[4] {
+	case 0:
+		m.updateRequested = false
+	case 1:
+		m.updateRequested = true
+	default:
+		return false
+	}
+	return true
+}
+
+type helloRequestMsg struct {
+}
+
+func (*helloRequestMsg) marshal() []byte {
+	return []byte{typeHelloRequest, 0, 0, 0}
+}
+
+func (*helloRequestMsg) unmarshal(data []byte) bool {
+	return len(data) == 4
+}
+
+func eqUint16s(x, y []uint16) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i, v := range x {
+		if y[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func eqKeyShares(x, y []keyShare) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].group != y[i].group || !bytes.Equal(x[i].data, y[i].data) {
+			return false
+		}
+	}
+	return true
+}
+
+func eqPSKIdentities(x, y []pskIdentity) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i].obfuscatedTicketAge != y[i].obfuscatedTicketAge || !bytes.Equal(x[i].label, y[i].label) {
 			return false
 		}
 	}
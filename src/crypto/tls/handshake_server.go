@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -49,6 +50,14 @@ func (c *Conn) serverHandshake() error {
 		return err
 	}
 
+	if c.vers == VersionTLS13 {
+		hs := serverHandshakeStateTLS13{
+			c:           c,
+			clientHello: hs.clientHello,
+		}
+		return hs.handshake()
+	}
+
 	// For an overview of TLS handshaking, see https://tools.ietf.org/html/rfc5246#section-7.3
 	c.buffering = true
 	if isResume {
@@ -77,7 +86,6 @@ func (c *Conn) serverHandshake() error {
 		if err := hs.readFinished(nil); err != nil {
 			return err
 		}
-		c.didResume = true
 	} else {
 		// The client didn't include a session ticket, or it wasn't
 		// valid so we do a full handshake.
@@ -135,6 +143,19 @@ func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
 		}
 	}
 
+	// TLS 1.3 is only negotiated with the supported_versions extension,
+	// and the rest of the ClientHello is processed by
+	// serverHandshakeStateTLS13. See RFC 8446, Section 4.2.1.
+	if c.config.maxVersion() >= VersionTLS13 && c.config.minVersion() <= VersionTLS13 {
+		for _, v := range hs.clientHello.supportedVersions {
+			if v == VersionTLS13 {
+				c.vers = VersionTLS13
+				c.haveVers = true
+				return false, nil
+			}
+		}
+	}
+
 	c.vers, ok = c.config.mutualVersion(hs.clientHello.vers)
 	if !ok {
 		c.sendAlert(alertProtocolVersion)
@@ -187,6 +208,16 @@ Curves:
 		return false, err
 	}
 
+	// If we could have negotiated TLS 1.3, signal the downgrade in the
+	// server random. See RFC 8446, Section 4.1.3.
+	if c.config.maxVersion() >= VersionTLS13 {
+		if c.vers == VersionTLS12 {
+			copy(hs.hello.random[24:], downgradeCanaryTLS12)
+		} else {
+			copy(hs.hello.random[24:], downgradeCanaryTLS11)
+		}
+	}
+
 	if len(hs.clientHello.secureRenegotiation) != 0 {
 		c.sendAlert(alertHandshakeFailure)
 		return false, errors.New("tls: initial handshake had non-empty renegotiation extension")
@@ -227,6 +258,11 @@ Curves:
 		switch priv.Public().(type) {
 		case *ecdsa.PublicKey:
 			hs.ecdsaOk = true
+		case ed25519.PublicKey:
+			// Ed25519 is used with the ECDSA cipher suites in TLS 1.2,
+			// if the client supports it (RFC 8422, Section 5.1.3).
+			hs.ecdsaOk = c.vers >= VersionTLS12 &&
+				isSupportedSignatureAlgorithm(Ed25519, hs.clientHello.supportedSignatureAlgorithms)
 		case *rsa.PublicKey:
 			hs.rsaSignOk = true
 		default:
@@ -291,9 +327,13 @@ func (hs *serverHandshakeState) checkForResumption() bool {
 		return false
 	}
 
-	var ok bool
 	var sessionTicket = append([]uint8{}, hs.clientHello.sessionTicket...)
-	if hs.sessionState, ok = c.decryptTicket(sessionTicket); !ok {
+	plaintext, usedOldKey := c.decryptTicket(sessionTicket)
+	if plaintext == nil {
+		return false
+	}
+	hs.sessionState = &sessionState{usedOldKey: usedOldKey}
+	if ok := hs.sessionState.unmarshal(plaintext); !ok {
 		return false
 	}
 
@@ -339,6 +379,7 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	// that we're doing a resumption.
 	hs.hello.sessionId = hs.clientHello.sessionId
 	hs.hello.ticketSupported = hs.sessionState.usedOldKey
+	c.cipherSuite = hs.suite.id
 	hs.finishedHash = newFinishedHash(c.vers, hs.suite)
 	hs.finishedHash.discardHandshakeBuffer()
 	hs.finishedHash.Write(hs.clientHello.marshal())
@@ -348,11 +389,17 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	}
 
 	if len(hs.sessionState.certificates) > 0 {
-		if _, err := hs.processCertsFromClient(hs.sessionState.certificates); err != nil {
+		hs.certsFromClient = hs.sessionState.certificates
+		if _, err := c.processCertsFromClient(hs.sessionState.certificates); err != nil {
 			return err
 		}
 	}
 
+	c.didResume = true
+	if err := c.verifyConnection(); err != nil {
+		return err
+	}
+
 	hs.masterSecret = hs.sessionState.masterSecret
 
 	return nil
@@ -367,6 +414,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 
 	hs.hello.ticketSupported = hs.clientHello.ticketSupported && !c.config.SessionTicketsDisabled
 	hs.hello.cipherSuite = hs.suite.id
+	c.cipherSuite = hs.suite.id
 
 	hs.finishedHash = newFinishedHash(hs.c.vers, hs.suite)
 	if c.config.ClientAuth == NoClientCert {
@@ -472,7 +520,8 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			}
 		}
 
-		pub, err = hs.processCertsFromClient(certMsg.certificates)
+		hs.certsFromClient = certMsg.certificates
+		pub, err = c.processCertsFromClient(certMsg.certificates)
 		if err != nil {
 			return err
 		}
@@ -497,7 +546,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		return err
 	}
 	hs.masterSecret = masterFromPreMasterSecret(c.vers, hs.suite, preMasterSecret, hs.clientHello.random, hs.hello.random)
-	if err := c.config.writeKeyLog(hs.clientHello.random, hs.masterSecret); err != nil {
+	if err := c.config.writeKeyLog(keyLogLabelTLS12, hs.clientHello.random, hs.masterSecret); err != nil {
 		c.sendAlert(alertInternalError)
 		return err
 	}
@@ -572,7 +621,26 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			if digest, hashFunc, err = hs.finishedHash.hashForClientCertificate(sigType, signatureAlgorithm, hs.masterSecret); err != nil {
 				break
 			}
+			if isRSAPSS(signatureAlgorithm) {
+				opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+				err = rsa.VerifyPSS(key, hashFunc, digest, certVerify.signature, opts)
+				break
+			}
 			err = rsa.VerifyPKCS1v15(key, hashFunc, digest, certVerify.signature)
+		case ed25519.PublicKey:
+			if sigType != signatureEd25519 {
+				err = errors.New("tls: bad signature type for client's Ed25519 certificate")
+				break
+			}
+			var signed []byte
+			if signed, _, err = hs.finishedHash.hashForClientCertificate(sigType, signatureAlgorithm, hs.masterSecret); err != nil {
+				break
+			}
+			if !ed25519.Verify(key, signed, certVerify.signature) {
+				err = errors.New("tls: Ed25519 verification failure")
+			}
+		default:
+			err = fmt.Errorf("tls: unsupported client certificate key type %T", key)
 		}
 		if err != nil {
 			c.sendAlert(alertBadCertificate)
@@ -582,6 +650,10 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		hs.finishedHash.Write(certVerify.marshal())
 	}
 
+	if err := c.verifyConnection(); err != nil {
+		return err
+	}
+
 	hs.finishedHash.discardHandshakeBuffer()
 
 	return nil
@@ -671,7 +743,7 @@ func (hs *serverHandshakeState) sendSessionTicket() error {
 		masterSecret: hs.masterSecret,
 		certificates: hs.certsFromClient,
 	}
-	m.ticket, err = c.encryptTicket(&state)
+	m.ticket, err = c.encryptTicket(state.marshal())
 	if err != nil {
 		return err
 	}
@@ -698,7 +770,6 @@ func (hs *serverHandshakeState) sendFinished(out []byte) error {
 		return err
 	}
 
-	c.cipherSuite = hs.suite.id
 	copy(out, finished.verifyData)
 
 	return nil
@@ -707,10 +778,7 @@ func (hs *serverHandshakeState) sendFinished(out []byte) error {
 // processCertsFromClient takes a chain of client certificates either from a
 // Certificates message or from a sessionState and verifies them. It returns
 // the public key of the leaf certificate.
-func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
-	c := hs.c
-
-	hs.certsFromClient = certificates
+func (c *Conn) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
 	certs := make([]*x509.Certificate, len(certificates))
 	var err error
 	for i, asn1Data := range certificates {
@@ -754,7 +822,7 @@ func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (c
 
 	var pub crypto.PublicKey
 	switch key := certs[0].PublicKey.(type) {
-	case *ecdsa.PublicKey, *rsa.PublicKey:
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
 		pub = key
 	default:
 		c.sendAlert(alertUnsupportedCertificate)
@@ -815,23 +883,28 @@ func (hs *serverHandshakeState) clientHelloInfo() *ClientHelloInfo {
 		return hs.cachedClientHelloInfo
 	}
 
-	var supportedVersions []uint16
-	if hs.clientHello.vers > VersionTLS12 {
-		supportedVersions = suppVersArray[:]
-	} else if hs.clientHello.vers >= VersionSSL30 {
-		supportedVersions = suppVersArray[VersionTLS12-hs.clientHello.vers:]
+	hs.cachedClientHelloInfo = clientHelloInfo(hs.c, hs.clientHello)
+	return hs.cachedClientHelloInfo
+}
+
+func clientHelloInfo(c *Conn, clientHello *clientHelloMsg) *ClientHelloInfo {
+	supportedVersions := clientHello.supportedVersions
+	if len(supportedVersions) == 0 {
+		if clientHello.vers > VersionTLS12 {
+			supportedVersions = suppVersArray[:]
+		} else if clientHello.vers >= VersionSSL30 {
+			supportedVersions = suppVersArray[VersionTLS12-clientHello.vers:]
+		}
 	}
 
-	hs.cachedClientHelloInfo = &ClientHelloInfo{
-		CipherSuites:      hs.clientHello.cipherSuites,
-		ServerName:        hs.clientHello.serverName,
-		SupportedCurves:   hs.clientHello.supportedCurves,
-		SupportedPoints:   hs.clientHello.supportedPoints,
-		SignatureSchemes:  hs.clientHello.supportedSignatureAlgorithms,
-		SupportedProtos:   hs.clientHello.alpnProtocols,
+	return &ClientHelloInfo{
+		CipherSuites:      clientHello.cipherSuites,
+		ServerName:        clientHello.serverName,
+		SupportedCurves:   clientHello.supportedCurves,
+		SupportedPoints:   clientHello.supportedPoints,
+		SignatureSchemes:  clientHello.supportedSignatureAlgorithms,
+		SupportedProtos:   clientHello.alpnProtocols,
 		SupportedVersions: supportedVersions,
-		Conn:              hs.c.conn,
+		Conn:              c.conn,
 	}
-
-	return hs.cachedClientHelloInfo
 }
@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/md5"
 	"crypto/rsa"
@@ -112,11 +113,20 @@ func md5SHA1Hash(slices [][]byte) []byte {
 // hashForServerKeyExchange hashes the given slices and returns their digest
 // and the identifier of the hash function used. The signatureAlgorithm argument
 // is only used for >= TLS 1.2 and identifies the hash function to use.
+// Ed25519 signatures are not pre-hashed, so for them the concatenation of the
+// slices is returned, with a zero hash function.
 func hashForServerKeyExchange(sigType uint8, signatureAlgorithm SignatureScheme, version uint16, slices ...[]byte) ([]byte, crypto.Hash, error) {
 	if version >= VersionTLS12 {
 		if !isSupportedSignatureAlgorithm(signatureAlgorithm, supportedSignatureAlgorithms) {
 			return nil, crypto.Hash(0), errors.New("tls: unsupported hash function used by peer")
 		}
+		if signatureAlgorithm == Ed25519 {
+			var signed []byte
+			for _, slice := range slices {
+				signed = append(signed, slice...)
+			}
+			return signed, crypto.Hash(0), nil
+		}
 		hashFunc, err := lookupTLSHash(signatureAlgorithm)
 		if err != nil {
 			return nil, crypto.Hash(0), err
@@ -128,6 +138,9 @@ func hashForServerKeyExchange(sigType uint8, signatureAlgorithm SignatureScheme,
 		digest := h.Sum(nil)
 		return digest, hashFunc, nil
 	}
+	if sigType == signatureEd25519 {
+		return nil, crypto.Hash(0), errors.New("tls: Ed25519 signatures require TLS 1.2")
+	}
 	if sigType == signatureECDSA {
 		return sha1Hash(slices), crypto.SHA1, nil
 	}
@@ -137,7 +150,7 @@ func hashForServerKeyExchange(sigType uint8, signatureAlgorithm SignatureScheme,
 // pickTLS12HashForSignature returns a TLS 1.2 hash identifier for signing a
 // ServerKeyExchange given the signature type being used and the client's
 // advertised list of supported signature and hash combinations.
-func pickTLS12HashForSignature(sigType uint8, clientList []SignatureScheme) (SignatureScheme, error) {
+func pickTLS12HashForSignature(sigType uint8, pub crypto.PublicKey, clientList []SignatureScheme) (SignatureScheme, error) {
 	if len(clientList) == 0 {
 		// If the client didn't specify any signature_algorithms
 		// extension then we can assume that it supports SHA1. See
@@ -156,7 +169,7 @@ func pickTLS12HashForSignature(sigType uint8, clientList []SignatureScheme) (Sig
 		if signatureFromSignatureScheme(sigAlg) != sigType {
 			continue
 		}
-		if isSupportedSignatureAlgorithm(sigAlg, supportedSignatureAlgorithms) {
+		if isSupportedSignatureAlgorithm(sigAlg, supportedSignatureAlgorithms) && keyFitsSignatureScheme(pub, sigAlg) {
 			return sigAlg, nil
 		}
 	}
@@ -164,6 +177,23 @@ func pickTLS12HashForSignature(sigType uint8, clientList []SignatureScheme) (Sig
 	return 0, errors.New("tls: client doesn't support any common hash functions")
 }
 
+// keyFitsSignatureScheme reports whether pub is large enough to sign with
+// sigAlg. An RSA-PSS signature with a salt as long as the hash needs an
+// encoded message of at least twice the hash size plus two bytes, which
+// small RSA keys cannot hold; those keys fall back to PKCS #1 v1.5.
+func keyFitsSignatureScheme(pub crypto.PublicKey, sigAlg SignatureScheme) bool {
+	rsaKey, ok := pub.(*rsa.PublicKey)
+	if !ok || !isRSAPSS(sigAlg) {
+		return true
+	}
+	sigHash, _, err := signatureSchemeTLS13(sigAlg)
+	if err != nil {
+		return false
+	}
+	emLen := (rsaKey.N.BitLen() - 1 + 7) / 8
+	return emLen >= 2*sigHash.Size()+2
+}
+
 func curveForCurveID(id CurveID) (elliptic.Curve, bool) {
 	switch id {
 	case CurveP256:
@@ -247,32 +277,40 @@ NextCandidate:
 	serverECDHParams[3] = byte(len(ecdhePublic))
 	copy(serverECDHParams[4:], ecdhePublic)
 
+	priv, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
+	}
+	// Ed25519 keys are used with the ECDSA cipher suites.
+	sigType := ka.sigType
+	if _, ok := priv.Public().(ed25519.PublicKey); ok && sigType == signatureECDSA {
+		sigType = signatureEd25519
+	}
+
 	var signatureAlgorithm SignatureScheme
 
 	if ka.version >= VersionTLS12 {
 		var err error
-		signatureAlgorithm, err = pickTLS12HashForSignature(ka.sigType, clientHello.supportedSignatureAlgorithms)
+		signatureAlgorithm, err = pickTLS12HashForSignature(sigType, priv.Public(), clientHello.supportedSignatureAlgorithms)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	digest, hashFunc, err := hashForServerKeyExchange(ka.sigType, signatureAlgorithm, ka.version, clientHello.random, hello.random, serverECDHParams)
+	digest, hashFunc, err := hashForServerKeyExchange(sigType, signatureAlgorithm, ka.version, clientHello.random, hello.random, serverECDHParams)
 	if err != nil {
 		return nil, err
 	}
 
-	priv, ok := cert.PrivateKey.(crypto.Signer)
-	if !ok {
-		return nil, errors.New("tls: certificate private key does not implement crypto.Signer")
-	}
 	var sig []byte
-	switch ka.sigType {
+	switch sigType {
 	case signatureECDSA:
 		_, ok := priv.Public().(*ecdsa.PublicKey)
 		if !ok {
 			return nil, errors.New("tls: ECDHE ECDSA requires an ECDSA server key")
 		}
+	case signatureEd25519:
+		// The key type was checked above.
 	case signatureRSA:
 		_, ok := priv.Public().(*rsa.PublicKey)
 		if !ok {
@@ -281,7 +319,11 @@ NextCandidate:
 	default:
 		return nil, errors.New("tls: unknown ECDHE signature algorithm")
 	}
-	sig, err = priv.Sign(config.rand(), digest, hashFunc)
+	var signOpts crypto.SignerOpts = hashFunc
+	if isRSAPSS(signatureAlgorithm) {
+		signOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hashFunc}
+	}
+	sig, err = priv.Sign(config.rand(), digest, signOpts)
 	if err != nil {
 		return nil, errors.New("tls: failed to sign ECDHE parameters: " + err.Error())
 	}
@@ -376,13 +418,22 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 		}
 	}
 
+	// Ed25519 keys are used with the ECDSA cipher suites.
+	sigType := ka.sigType
+	if _, ok := cert.PublicKey.(ed25519.PublicKey); ok && sigType == signatureECDSA {
+		sigType = signatureEd25519
+	}
+
 	var signatureAlgorithm SignatureScheme
 	if ka.version >= VersionTLS12 {
 		// handle SignatureAndHashAlgorithm
 		signatureAlgorithm = SignatureScheme(sig[0])<<8 | SignatureScheme(sig[1])
-		if signatureFromSignatureScheme(signatureAlgorithm) != ka.sigType {
+		if signatureFromSignatureScheme(signatureAlgorithm) != sigType {
 			return errServerKeyExchange
 		}
+		if !isSupportedSignatureAlgorithm(signatureAlgorithm, clientHello.supportedSignatureAlgorithms) {
+			return errors.New("tls: server used a signature algorithm that was not offered")
+		}
 		sig = sig[2:]
 		if len(sig) < 2 {
 			return errServerKeyExchange
@@ -394,11 +445,11 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 	}
 	sig = sig[2:]
 
-	digest, hashFunc, err := hashForServerKeyExchange(ka.sigType, signatureAlgorithm, ka.version, clientHello.random, serverHello.random, serverECDHParams)
+	digest, hashFunc, err := hashForServerKeyExchange(sigType, signatureAlgorithm, ka.version, clientHello.random, serverHello.random, serverECDHParams)
 	if err != nil {
 		return err
 	}
-	switch ka.sigType {
+	switch sigType {
 	case signatureECDSA:
 		pubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
 		if !ok {
@@ -414,11 +465,22 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 		if !ecdsa.Verify(pubKey, digest, ecdsaSig.R, ecdsaSig.S) {
 			return errors.New("tls: ECDSA verification failure")
 		}
+	case signatureEd25519:
+		if !ed25519.Verify(cert.PublicKey.(ed25519.PublicKey), digest, sig) {
+			return errors.New("tls: Ed25519 verification failure")
+		}
 	case signatureRSA:
 		pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
 		if !ok {
 			return errors.New("tls: ECDHE RSA requires a RSA server public key")
 		}
+		if isRSAPSS(signatureAlgorithm) {
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+			if err := rsa.VerifyPSS(pubKey, hashFunc, digest, sig, opts); err != nil {
+				return err
+			}
+			break
+		}
 		if err := rsa.VerifyPKCS1v15(pubKey, hashFunc, digest, sig); err != nil {
 			return err
 		}
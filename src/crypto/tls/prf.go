@@ -311,9 +311,9 @@ func (h finishedHash) serverSum(masterSecret []byte) []byte {
 
 // selectClientCertSignatureAlgorithm returns a SignatureScheme to sign a
 // client's CertificateVerify with, or an error if none can be found.
-func (h finishedHash) selectClientCertSignatureAlgorithm(serverList []SignatureScheme, sigType uint8) (SignatureScheme, error) {
+func (h finishedHash) selectClientCertSignatureAlgorithm(serverList []SignatureScheme, sigType uint8, pub crypto.PublicKey) (SignatureScheme, error) {
 	for _, v := range serverList {
-		if signatureFromSignatureScheme(v) == sigType && isSupportedSignatureAlgorithm(v, supportedSignatureAlgorithms) {
+		if signatureFromSignatureScheme(v) == sigType && isSupportedSignatureAlgorithm(v, supportedSignatureAlgorithms) && keyFitsSignatureScheme(pub, v) {
 			return v, nil
 		}
 	}
@@ -321,12 +321,21 @@ func (h finishedHash) selectClientCertSignatureAlgorithm(serverList []SignatureS
 }
 
 // hashForClientCertificate returns a digest, hash function, and TLS 1.2 hash
-// id suitable for signing by a TLS client certificate.
+// id suitable for signing by a TLS client certificate. Ed25519 signatures are
+// not pre-hashed, so for them the handshake messages are returned, with a
+// zero hash function.
 func (h finishedHash) hashForClientCertificate(sigType uint8, signatureAlgorithm SignatureScheme, masterSecret []byte) ([]byte, crypto.Hash, error) {
 	if (h.version == VersionSSL30 || h.version >= VersionTLS12) && h.buffer == nil {
 		panic("a handshake hash for a client-certificate was requested after discarding the handshake buffer")
 	}
 
+	if sigType == signatureEd25519 {
+		if h.version < VersionTLS12 || signatureAlgorithm != Ed25519 {
+			return nil, 0, errors.New("tls: Ed25519 signatures require TLS 1.2")
+		}
+		return append([]byte(nil), h.buffer...), crypto.Hash(0), nil
+	}
+
 	if h.version == VersionSSL30 {
 		if sigType != signatureRSA {
 			return nil, 0, errors.New("tls: unsupported signature type for client certificate")
@@ -7,7 +7,10 @@ package x509
 import (
 	"encoding/pem"
 	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"runtime"
+	"sync"
 )
 
 // CertPool is a set of certificates.
@@ -15,6 +18,12 @@ type CertPool struct {
 	bySubjectKeyId map[string][]int
 	byName         map[string][]int
 	certs          []*Certificate
+
+	// lazy, if not nil, is called the first time the pool is used to
+	// populate it. See NewLazyCertPool.
+	lazy     func(*CertPool) error
+	lazyOnce sync.Once
+	lazyErr  error
 }
 
 // NewCertPool returns a new, empty CertPool.
@@ -25,7 +34,95 @@ func NewCertPool() *CertPool {
 	}
 }
 
+// NewLazyCertPool returns a CertPool that is populated by calling load the
+// first time the pool is used, rather than when it is created. load is
+// passed a new, empty CertPool to add certificates to, and is called at most
+// once. If load returns an error, the pool keeps any certificates that were
+// added before the error, and the error is returned by Load and by any
+// Certificate.Verify call that uses the pool.
+func NewLazyCertPool(load func(pool *CertPool) error) *CertPool {
+	if load == nil {
+		panic("x509: nil load function passed to NewLazyCertPool")
+	}
+	p := NewCertPool()
+	p.lazy = load
+	return p
+}
+
+// NewCertPoolFromFile returns a CertPool that contains the PEM encoded
+// certificates in the named file, such as a CA bundle. The file is not read
+// until the pool is first used, see NewLazyCertPool. It is an error for the
+// file to contain no certificates.
+func NewCertPoolFromFile(file string) *CertPool {
+	return NewLazyCertPool(func(pool *CertPool) error {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return errors.New("x509: no certificates found in " + file)
+		}
+		return nil
+	})
+}
+
+// NewCertPoolFromDir returns a CertPool that contains the PEM encoded
+// certificates in the files of the named directory, such as /etc/ssl/certs.
+// The directory is not read until the pool is first used, see
+// NewLazyCertPool. Files which can't be read or contain no certificates are
+// skipped, but it is an error for the directory to contain no certificates
+// at all.
+func NewCertPoolFromDir(dir string) *CertPool {
+	return NewLazyCertPool(func(pool *CertPool) error {
+		ok, err := pool.appendCertsFromDir(dir)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("x509: no certificates found in " + dir)
+		}
+		return nil
+	})
+}
+
+// appendCertsFromDir appends the PEM encoded certificates in each file of
+// dir to s and reports whether any were found. Files which can't be read are
+// skipped.
+func (s *CertPool) appendCertsFromDir(dir string) (ok bool, err error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	for _, fi := range fis {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err == nil && s.AppendCertsFromPEM(data) {
+			ok = true
+		}
+	}
+	return ok, nil
+}
+
+// Load populates a pool created by NewLazyCertPool, NewCertPoolFromFile or
+// NewCertPoolFromDir if it hasn't been used yet, and returns any error from
+// doing so. It's not necessary to call Load before using the pool, but it
+// allows loading errors to be detected early. For other pools, Load does
+// nothing and returns nil.
+func (s *CertPool) Load() error {
+	if s == nil || s.lazy == nil {
+		return nil
+	}
+	s.lazyOnce.Do(func() {
+		// Load into a separate pool so that load can call methods
+		// like AddCert without recursing into Load.
+		p := NewCertPool()
+		s.lazyErr = s.lazy(p)
+		s.bySubjectKeyId, s.byName, s.certs = p.bySubjectKeyId, p.byName, p.certs
+	})
+	return s.lazyErr
+}
+
 func (s *CertPool) copy() *CertPool {
+	s.Load()
 	p := &CertPool{
 		bySubjectKeyId: make(map[string][]int, len(s.bySubjectKeyId)),
 		byName:         make(map[string][]int, len(s.byName)),
@@ -113,6 +210,7 @@ func (s *CertPool) AddCert(cert *Certificate) {
 	if cert == nil {
 		panic("adding nil Certificate to CertPool")
 	}
+	s.Load()
 
 	// Check that the certificate isn't being added twice.
 	if s.contains(cert) {
@@ -162,6 +260,7 @@ func (s *CertPool) AppendCertsFromPEM(pemCerts []byte) (ok bool) {
 // Subjects returns a list of the DER-encoded subjects of
 // all of the certificates in the pool.
 func (s *CertPool) Subjects() [][]byte {
+	s.Load()
 	res := make([][]byte, len(s.certs))
 	for i, c := range s.certs {
 		res[i] = c.RawSubject
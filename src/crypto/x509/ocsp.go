@@ -0,0 +1,623 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+// This file implements the Online Certificate Status Protocol, as specified
+// in RFC 6960. Only single-certificate requests and responses signed with a
+// certificate's key are supported, as used in the profile of RFC 5019.
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+)
+
+var (
+	oidOCSPBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// ocspHashOIDs lists the hash functions that can identify a certificate in
+// an OCSP request or response.
+var ocspHashOIDs = []struct {
+	hash crypto.Hash
+	oid  asn1.ObjectIdentifier
+}{
+	{crypto.SHA1, oidSHA1},
+	{crypto.SHA256, oidSHA256},
+	{crypto.SHA384, oidSHA384},
+	{crypto.SHA512, oidSHA512},
+}
+
+func ocspHashFromOID(oid asn1.ObjectIdentifier) crypto.Hash {
+	for _, h := range ocspHashOIDs {
+		if oid.Equal(h.oid) {
+			return h.hash
+		}
+	}
+	return 0
+}
+
+func ocspOIDFromHash(hash crypto.Hash) (asn1.ObjectIdentifier, bool) {
+	for _, h := range ocspHashOIDs {
+		if hash == h.hash {
+			return h.oid, true
+		}
+	}
+	return nil, false
+}
+
+// The certificate statuses of an OCSP response, see RFC 6960, Section 4.2.1.
+const (
+	// OCSPGood means that the certificate is not revoked.
+	OCSPGood = iota
+	// OCSPRevoked means that the certificate has been revoked.
+	OCSPRevoked
+	// OCSPUnknown means that the responder doesn't know about the
+	// certificate.
+	OCSPUnknown
+)
+
+// OCSPResponseStatus is the status of an OCSP response as a whole, see
+// RFC 6960, Section 4.2.1. Responses with a status other than OCSPSuccess
+// are not signed and carry no certificate status.
+type OCSPResponseStatus int
+
+const (
+	OCSPSuccess           OCSPResponseStatus = 0
+	OCSPMalformed         OCSPResponseStatus = 1
+	OCSPInternalError     OCSPResponseStatus = 2
+	OCSPTryLater          OCSPResponseStatus = 3
+	OCSPSignatureRequired OCSPResponseStatus = 5
+	OCSPUnauthorized      OCSPResponseStatus = 6
+)
+
+func (s OCSPResponseStatus) String() string {
+	switch s {
+	case OCSPSuccess:
+		return "success"
+	case OCSPMalformed:
+		return "malformed request"
+	case OCSPInternalError:
+		return "internal error"
+	case OCSPTryLater:
+		return "try later"
+	case OCSPSignatureRequired:
+		return "signature required"
+	case OCSPUnauthorized:
+		return "unauthorized"
+	}
+	return "unknown OCSP response status: " + fmt.Sprint(int(s))
+}
+
+// OCSPResponseError is returned when parsing an OCSP response whose status
+// is not OCSPSuccess.
+type OCSPResponseError struct {
+	Status OCSPResponseStatus
+}
+
+func (e OCSPResponseError) Error() string {
+	return "x509: OCSP responder returned an error: " + e.Status.String()
+}
+
+// RFC 6960, Section 4.1.1
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspSingleRequest struct {
+	Cert       ocspCertID
+	Extensions []pkix.Extension `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspTBSRequest struct {
+	Version       int           `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName asn1.RawValue `asn1:"explicit,tag:1,optional"`
+	RequestList   []ocspSingleRequest
+	Extensions    []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+	// The optional signature is not supported.
+}
+
+// RFC 6960, Section 4.2.1
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    ocspResponseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Raw            asn1.RawContent
+	Version        int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID asn1.RawValue
+	ProducedAt     time.Time `asn1:"generalized"`
+	Responses      []ocspSingleResponse
+	Extensions     []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked    ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// Tags of the ResponderID choice, see RFC 6960, Section 4.2.1.
+const (
+	ocspResponderIDByName = 1
+	ocspResponderIDByKey  = 2
+)
+
+// OCSPRequest represents an OCSP request for the status of a single
+// certificate, see RFC 6960.
+type OCSPRequest struct {
+	HashAlgorithm  crypto.Hash
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+// Marshal marshals the OCSP request to ASN.1 DER encoded form.
+func (req *OCSPRequest) Marshal() ([]byte, error) {
+	hashOID, ok := ocspOIDFromHash(req.HashAlgorithm)
+	if !ok {
+		return nil, errors.New("x509: unsupported OCSP hash function")
+	}
+	return asn1.Marshal(ocspRequest{
+		TBSRequest: ocspTBSRequest{
+			RequestList: []ocspSingleRequest{{
+				Cert: ocspCertID{
+					HashAlgorithm: pkix.AlgorithmIdentifier{
+						Algorithm:  hashOID,
+						Parameters: asn1.NullRawValue,
+					},
+					NameHash:      req.IssuerNameHash,
+					IssuerKeyHash: req.IssuerKeyHash,
+					SerialNumber:  req.SerialNumber,
+				},
+			}},
+		},
+	})
+}
+
+// ParseOCSPRequest parses an OCSP request in DER form. Only requests for a
+// single certificate, without a signature, are supported.
+func ParseOCSPRequest(der []byte) (*OCSPRequest, error) {
+	var req ocspRequest
+	if rest, err := asn1.Unmarshal(der, &req); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after OCSP request")
+	}
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, errors.New("x509: OCSP request must contain exactly one certificate")
+	}
+	certID := req.TBSRequest.RequestList[0].Cert
+	hash := ocspHashFromOID(certID.HashAlgorithm.Algorithm)
+	if hash == 0 {
+		return nil, errors.New("x509: unsupported OCSP hash function")
+	}
+	return &OCSPRequest{
+		HashAlgorithm:  hash,
+		IssuerNameHash: certID.NameHash,
+		IssuerKeyHash:  certID.IssuerKeyHash,
+		SerialNumber:   certID.SerialNumber,
+	}, nil
+}
+
+// CreateOCSPRequest returns an OCSP request in DER form for the status of
+// cert, which was issued by issuer. The issuer is identified by hashes
+// computed with hash, or with SHA-1 if hash is zero, as most responders
+// only support SHA-1.
+func CreateOCSPRequest(cert, issuer *Certificate, hash crypto.Hash) ([]byte, error) {
+	if hash == 0 {
+		hash = crypto.SHA1
+	}
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, hash)
+	if err != nil {
+		return nil, err
+	}
+	req := &OCSPRequest{
+		HashAlgorithm:  hash,
+		IssuerNameHash: nameHash,
+		IssuerKeyHash:  keyHash,
+		SerialNumber:   cert.SerialNumber,
+	}
+	return req.Marshal()
+}
+
+// ocspIssuerHashes returns the hashes of the subject and of the public key
+// of issuer, which identify it in an OCSP CertID.
+func ocspIssuerHashes(issuer *Certificate, hash crypto.Hash) (nameHash, keyHash []byte, err error) {
+	if _, ok := ocspOIDFromHash(hash); !ok || !hash.Available() {
+		return nil, nil, errors.New("x509: unsupported OCSP hash function")
+	}
+
+	// The key hash covers the value of the subjectPublicKey BIT STRING,
+	// without the tag and length.
+	var spki publicKeyInfo
+	if rest, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, nil, err
+	} else if len(rest) != 0 {
+		return nil, nil, errors.New("x509: trailing data after public key")
+	}
+
+	h := hash.New()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash = h.Sum(nil)
+
+	h.Reset()
+	h.Write(issuer.RawSubject)
+	nameHash = h.Sum(nil)
+
+	return nameHash, keyHash, nil
+}
+
+// OCSPResponse represents the status of a single certificate in an OCSP
+// response, see RFC 6960.
+type OCSPResponse struct {
+	// Status is one of OCSPGood, OCSPRevoked or OCSPUnknown.
+	Status                                        int
+	SerialNumber                                  *big.Int
+	ProducedAt, ThisUpdate, NextUpdate, RevokedAt time.Time
+	// RevocationReason is the CRL reason code of a revocation, see RFC 5280,
+	// Section 5.3.1.
+	RevocationReason int
+	// Certificate is the certificate of a delegated responder that signed
+	// the response, if it was included.
+	Certificate *Certificate
+
+	// TBSResponseData contains the raw bytes of the signed response.
+	TBSResponseData    []byte
+	Signature          []byte
+	SignatureAlgorithm SignatureAlgorithm
+
+	// IssuerHash is the hash used to identify the issuer of the
+	// certificate. When creating a response, zero means SHA-1.
+	IssuerHash crypto.Hash
+
+	// RawResponderName is the DER encoded name of the responder, if it
+	// identified itself by name.
+	RawResponderName []byte
+	// ResponderKeyHash is the SHA-1 hash of the responder's public key, if
+	// it identified itself by key.
+	ResponderKeyHash []byte
+
+	// Extensions contains the raw extensions of the single response. When
+	// creating a response, Extensions is ignored, see ExtraExtensions.
+	Extensions []pkix.Extension
+
+	// ExtraExtensions contains extensions to be copied, raw, into the
+	// single response of a created response.
+	ExtraExtensions []pkix.Extension
+}
+
+// CheckSignatureFrom checks that the signature of resp is valid for the
+// public key of issuer.
+func (resp *OCSPResponse) CheckSignatureFrom(issuer *Certificate) error {
+	return issuer.CheckSignature(resp.SignatureAlgorithm, resp.TBSResponseData, resp.Signature)
+}
+
+// ParseOCSPResponse parses an OCSP response in DER form, which must contain
+// a single certificate status. If issuer is not nil, the response must be
+// signed by it, or by a delegated responder certificate that it issued, and
+// identify it as the issuer of the certificate.
+//
+// Responses with an error status result in an OCSPResponseError.
+func ParseOCSPResponse(der []byte, issuer *Certificate) (*OCSPResponse, error) {
+	return ParseOCSPResponseForCert(der, nil, issuer)
+}
+
+// ParseOCSPResponseForCert is like ParseOCSPResponse, but if cert is not nil
+// it returns the status of cert from a response that may contain several.
+func ParseOCSPResponseForCert(der []byte, cert, issuer *Certificate) (*OCSPResponse, error) {
+	var resp ocspResponse
+	if rest, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after OCSP response")
+	}
+	if status := OCSPResponseStatus(resp.Status); status != OCSPSuccess {
+		return nil, OCSPResponseError{status}
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, errors.New("x509: unsupported OCSP response type")
+	}
+
+	var basic ocspBasicResponse
+	if rest, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after OCSP basic response")
+	}
+	data := &basic.TBSResponseData
+
+	var single *ocspSingleResponse
+	switch {
+	case cert != nil:
+		for i := range data.Responses {
+			if data.Responses[i].CertID.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				single = &data.Responses[i]
+				break
+			}
+		}
+		if single == nil {
+			return nil, errors.New("x509: OCSP response doesn't contain the certificate")
+		}
+	case len(data.Responses) == 1:
+		single = &data.Responses[0]
+	default:
+		return nil, errors.New("x509: OCSP response must contain exactly one certificate")
+	}
+
+	out := &OCSPResponse{
+		SerialNumber:       single.CertID.SerialNumber,
+		ProducedAt:         data.ProducedAt,
+		ThisUpdate:         single.ThisUpdate,
+		NextUpdate:         single.NextUpdate,
+		TBSResponseData:    data.Raw,
+		Signature:          basic.Signature.RightAlign(),
+		SignatureAlgorithm: getSignatureAlgorithmFromAI(basic.SignatureAlgorithm),
+		IssuerHash:         ocspHashFromOID(single.CertID.HashAlgorithm.Algorithm),
+		Extensions:         single.Extensions,
+	}
+
+	switch {
+	case bool(single.Good):
+		out.Status = OCSPGood
+	case bool(single.Unknown):
+		out.Status = OCSPUnknown
+	default:
+		out.Status = OCSPRevoked
+		out.RevokedAt = single.Revoked.RevocationTime
+		out.RevocationReason = int(single.Revoked.Reason)
+	}
+
+	rawID := data.RawResponderID
+	if rawID.Class != asn1.ClassContextSpecific || !rawID.IsCompound {
+		return nil, errors.New("x509: invalid OCSP responder ID")
+	}
+	switch rawID.Tag {
+	case ocspResponderIDByName:
+		var name asn1.RawValue
+		if rest, err := asn1.Unmarshal(rawID.Bytes, &name); err != nil || len(rest) != 0 {
+			return nil, errors.New("x509: invalid OCSP responder name")
+		}
+		out.RawResponderName = name.FullBytes
+	case ocspResponderIDByKey:
+		var keyHash []byte
+		if rest, err := asn1.Unmarshal(rawID.Bytes, &keyHash); err != nil || len(rest) != 0 {
+			return nil, errors.New("x509: invalid OCSP responder key hash")
+		}
+		out.ResponderKeyHash = keyHash
+	default:
+		return nil, errors.New("x509: invalid OCSP responder ID")
+	}
+
+	if len(basic.Certificates) > 0 {
+		// Only the responder certificate is used, and any other
+		// certificates in the response are ignored.
+		responder, err := ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		out.Certificate = responder
+	}
+
+	if issuer != nil {
+		if out.IssuerHash == 0 {
+			return nil, errors.New("x509: unsupported OCSP hash function")
+		}
+		nameHash, keyHash, err := ocspIssuerHashes(issuer, out.IssuerHash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(single.CertID.NameHash, nameHash) || !bytes.Equal(single.CertID.IssuerKeyHash, keyHash) {
+			return nil, errors.New("x509: OCSP response is for a certificate from a different issuer")
+		}
+		if err := out.checkSignatureFromIssuer(issuer); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// checkSignatureFromIssuer checks that resp was signed by issuer, either
+// directly or through a delegated responder certificate (RFC 6960, Section
+// 4.2.2.2).
+func (resp *OCSPResponse) checkSignatureFromIssuer(issuer *Certificate) error {
+	signer := issuer
+	if resp.Certificate != nil && !bytes.Equal(resp.Certificate.Raw, issuer.Raw) {
+		if err := resp.Certificate.CheckSignatureFrom(issuer); err != nil {
+			return errors.New("x509: bad signature on OCSP responder certificate: " + err.Error())
+		}
+		authorized := false
+		for _, usage := range resp.Certificate.ExtKeyUsage {
+			if usage == ExtKeyUsageOCSPSigning {
+				authorized = true
+				break
+			}
+		}
+		if !authorized {
+			return errors.New("x509: OCSP responder certificate is not authorized for OCSP signing")
+		}
+		signer = resp.Certificate
+	}
+	if err := resp.CheckSignatureFrom(signer); err != nil {
+		return errors.New("x509: bad signature on OCSP response: " + err.Error())
+	}
+	return nil
+}
+
+// CreateOCSPResponse returns an OCSP response in DER form, for the
+// certificate of issuer described by template, signed by priv. Any
+// randomness needed for the signature is read from rand.
+//
+// The fields of template used are Status, SerialNumber, ThisUpdate,
+// NextUpdate, RevokedAt, RevocationReason, Certificate, IssuerHash,
+// SignatureAlgorithm, ProducedAt and ExtraExtensions. If ProducedAt is zero,
+// the current time is used.
+//
+// The responder is identified by the hash of the public key of
+// responderCert, which is issuer unless a delegated responder is used. Such a
+// responder's certificate should be included as template.Certificate.
+func CreateOCSPResponse(rand io.Reader, issuer, responderCert *Certificate, template *OCSPResponse, priv crypto.Signer) ([]byte, error) {
+	if template.SerialNumber == nil {
+		return nil, errors.New("x509: no SerialNumber given")
+	}
+	issuerHash := template.IssuerHash
+	if issuerHash == 0 {
+		issuerHash = crypto.SHA1
+	}
+	hashOID, ok := ocspOIDFromHash(issuerHash)
+	if !ok {
+		return nil, errors.New("x509: unsupported OCSP hash function")
+	}
+	nameHash, keyHash, err := ocspIssuerHashes(issuer, issuerHash)
+	if err != nil {
+		return nil, err
+	}
+
+	single := ocspSingleResponse{
+		CertID: ocspCertID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  hashOID,
+				Parameters: asn1.NullRawValue,
+			},
+			NameHash:      nameHash,
+			IssuerKeyHash: keyHash,
+			SerialNumber:  template.SerialNumber,
+		},
+		ThisUpdate: template.ThisUpdate.UTC(),
+		Extensions: template.ExtraExtensions,
+	}
+	if !template.NextUpdate.IsZero() {
+		single.NextUpdate = template.NextUpdate.UTC()
+	}
+	switch template.Status {
+	case OCSPGood:
+		single.Good = true
+	case OCSPUnknown:
+		single.Unknown = true
+	case OCSPRevoked:
+		single.Revoked = ocspRevokedInfo{
+			RevocationTime: template.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(template.RevocationReason),
+		}
+	default:
+		return nil, errors.New("x509: invalid OCSP certificate status")
+	}
+
+	// The responder key hash is always SHA-1 (RFC 6960, Section 4.2.1).
+	_, responderKeyHash, err := ocspIssuerHashes(responderCert, crypto.SHA1)
+	if err != nil {
+		return nil, err
+	}
+	responderKeyHashBytes, err := asn1.Marshal(responderKeyHash)
+	if err != nil {
+		return nil, err
+	}
+
+	producedAt := template.ProducedAt
+	if producedAt.IsZero() {
+		producedAt = time.Now()
+	}
+
+	data := ocspResponseData{
+		RawResponderID: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        ocspResponderIDByKey,
+			IsCompound: true,
+			Bytes:      responderKeyHashBytes,
+		},
+		ProducedAt: producedAt.UTC().Truncate(time.Second),
+		Responses:  []ocspSingleResponse{single},
+	}
+	dataBytes, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := dataBytes
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+
+	var signerOpts crypto.SignerOpts
+	signerOpts = hashFunc
+	if template.SignatureAlgorithm != 0 && template.SignatureAlgorithm.isRSAPSS() {
+		signerOpts = &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       hashFunc,
+		}
+	}
+
+	signature, err := priv.Sign(rand, digest, signerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Raw = dataBytes
+	basic := ocspBasicResponse{
+		TBSResponseData:    data,
+		SignatureAlgorithm: signatureAlgorithm,
+		Signature:          asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	}
+	if template.Certificate != nil {
+		basic.Certificates = []asn1.RawValue{{FullBytes: template.Certificate.Raw}}
+	}
+	basicBytes, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(ocspResponse{
+		Status: asn1.Enumerated(OCSPSuccess),
+		Response: ocspResponseBytes{
+			ResponseType: oidOCSPBasicResponse,
+			Response:     basicBytes,
+		},
+	})
+}
@@ -0,0 +1,348 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package x509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOCSPRequest(t *testing.T) {
+	issuer, issuerKey, err := generateCert("Issuer", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 1234, false, issuer, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []crypto.Hash{0, crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		der, err := CreateOCSPRequest(leaf, issuer, hash)
+		if err != nil {
+			t.Errorf("%v: failed to create request: %s", hash, err)
+			continue
+		}
+		req, err := ParseOCSPRequest(der)
+		if err != nil {
+			t.Errorf("%v: failed to parse request: %s", hash, err)
+			continue
+		}
+		wantHash := hash
+		if wantHash == 0 {
+			wantHash = crypto.SHA1
+		}
+		if req.HashAlgorithm != wantHash {
+			t.Errorf("%v: HashAlgorithm = %v, want %v", hash, req.HashAlgorithm, wantHash)
+		}
+		if req.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+			t.Errorf("%v: SerialNumber = %v, want %v", hash, req.SerialNumber, leaf.SerialNumber)
+		}
+		h := wantHash.New()
+		h.Write(issuer.RawSubject)
+		if nameHash := h.Sum(nil); !bytes.Equal(req.IssuerNameHash, nameHash) {
+			t.Errorf("%v: IssuerNameHash = %x, want %x", hash, req.IssuerNameHash, nameHash)
+		}
+		if len(req.IssuerKeyHash) != wantHash.Size() {
+			t.Errorf("%v: IssuerKeyHash has length %d, want %d", hash, len(req.IssuerKeyHash), wantHash.Size())
+		}
+
+		remarshaled, err := req.Marshal()
+		if err != nil {
+			t.Errorf("%v: failed to marshal request: %s", hash, err)
+		} else if !bytes.Equal(remarshaled, der) {
+			t.Errorf("%v: marshaled request = %x, want %x", hash, remarshaled, der)
+		}
+	}
+
+	if _, err := CreateOCSPRequest(leaf, issuer, crypto.MD5); err == nil {
+		t.Error("created a request with MD5")
+	}
+}
+
+func TestOCSPIssuerKeyHash(t *testing.T) {
+	issuer, _, err := generateCert("Issuer", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key hash is over the public key point, not over the whole
+	// SubjectPublicKeyInfo.
+	pub := issuer.PublicKey.(*ecdsa.PublicKey)
+	want := sha1.Sum(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	_, keyHash, err := ocspIssuerHashes(issuer, crypto.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(keyHash, want[:]) {
+		t.Errorf("key hash = %x, want %x", keyHash, want)
+	}
+}
+
+func TestOCSPResponse(t *testing.T) {
+	issuer, issuerKey, err := generateCert("Issuer", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 1234, false, issuer, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thisUpdate := time.Date(2018, 7, 1, 12, 0, 0, 0, time.UTC)
+	extraExtension := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{5, 0}}
+	tests := []OCSPResponse{
+		{Status: OCSPGood},
+		{Status: OCSPUnknown, IssuerHash: crypto.SHA256},
+		{Status: OCSPRevoked, RevokedAt: thisUpdate.Add(-time.Hour), RevocationReason: 1 /* keyCompromise */},
+		{Status: OCSPRevoked, RevokedAt: thisUpdate.Add(-time.Hour), ExtraExtensions: []pkix.Extension{extraExtension}},
+	}
+	for i, template := range tests {
+		template.SerialNumber = leaf.SerialNumber
+		template.ThisUpdate = thisUpdate
+		template.NextUpdate = thisUpdate.Add(24 * time.Hour)
+		template.ProducedAt = thisUpdate
+
+		der, err := CreateOCSPResponse(rand.Reader, issuer, issuer, &template, issuerKey)
+		if err != nil {
+			t.Errorf("#%d: failed to create response: %s", i, err)
+			continue
+		}
+		resp, err := ParseOCSPResponseForCert(der, leaf, issuer)
+		if err != nil {
+			t.Errorf("#%d: failed to parse response: %s", i, err)
+			continue
+		}
+
+		wantHash := template.IssuerHash
+		if wantHash == 0 {
+			wantHash = crypto.SHA1
+		}
+		if resp.Status != template.Status {
+			t.Errorf("#%d: Status = %d, want %d", i, resp.Status, template.Status)
+		}
+		if resp.SerialNumber.Cmp(leaf.SerialNumber) != 0 {
+			t.Errorf("#%d: SerialNumber = %v, want %v", i, resp.SerialNumber, leaf.SerialNumber)
+		}
+		if !resp.ProducedAt.Equal(template.ProducedAt) || !resp.ThisUpdate.Equal(template.ThisUpdate) || !resp.NextUpdate.Equal(template.NextUpdate) {
+			t.Errorf("#%d: got times %v, %v, %v; want %v, %v, %v", i,
+				resp.ProducedAt, resp.ThisUpdate, resp.NextUpdate,
+				template.ProducedAt, template.ThisUpdate, template.NextUpdate)
+		}
+		if !resp.RevokedAt.Equal(template.RevokedAt) {
+			t.Errorf("#%d: RevokedAt = %v, want %v", i, resp.RevokedAt, template.RevokedAt)
+		}
+		if resp.RevocationReason != template.RevocationReason {
+			t.Errorf("#%d: RevocationReason = %d, want %d", i, resp.RevocationReason, template.RevocationReason)
+		}
+		if resp.IssuerHash != wantHash {
+			t.Errorf("#%d: IssuerHash = %v, want %v", i, resp.IssuerHash, wantHash)
+		}
+		if resp.SignatureAlgorithm != ECDSAWithSHA256 {
+			t.Errorf("#%d: SignatureAlgorithm = %v, want %v", i, resp.SignatureAlgorithm, ECDSAWithSHA256)
+		}
+		if _, keyHash, _ := ocspIssuerHashes(issuer, crypto.SHA1); !bytes.Equal(resp.ResponderKeyHash, keyHash) {
+			t.Errorf("#%d: ResponderKeyHash = %x, want %x", i, resp.ResponderKeyHash, keyHash)
+		}
+		if len(template.ExtraExtensions) > 0 && !oidInExtensions(extraExtension.Id, resp.Extensions) {
+			t.Errorf("#%d: extra extension is missing", i)
+		}
+	}
+}
+
+func TestOCSPResponseErrors(t *testing.T) {
+	issuer, issuerKey, err := generateCert("Issuer", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherIssuer, _, err := generateCert("Other Issuer", 2, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 1234, false, issuer, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLeaf, _, err := generateCert("other.example.com", 5678, false, issuer, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &OCSPResponse{
+		Status:       OCSPGood,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now(),
+	}
+	der, err := CreateOCSPResponse(rand.Reader, issuer, issuer, template, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOCSPResponse(der, issuer); err != nil {
+		t.Errorf("failed to parse response: %s", err)
+	}
+	if _, err := ParseOCSPResponse(der, otherIssuer); err == nil {
+		t.Error("response verified against the wrong issuer")
+	}
+	if _, err := ParseOCSPResponseForCert(der, otherLeaf, issuer); err == nil {
+		t.Error("response was used for the wrong certificate")
+	}
+
+	// A response signed by a different key with the right issuer hashes.
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := CreateOCSPResponse(rand.Reader, issuer, issuer, template, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseOCSPResponse(forged, issuer); err == nil {
+		t.Error("response with a bad signature verified")
+	}
+	// Without an issuer, nothing is verified.
+	if _, err := ParseOCSPResponse(forged, nil); err != nil {
+		t.Errorf("failed to parse response without verifying it: %s", err)
+	}
+
+	// A tryLater response, which has no body.
+	_, err = ParseOCSPResponse([]byte{0x30, 0x03, 0x0a, 0x01, 0x03}, issuer)
+	if respErr, ok := err.(OCSPResponseError); !ok || respErr.Status != OCSPTryLater {
+		t.Errorf("got error %v, want OCSPResponseError with status %v", err, OCSPTryLater)
+	}
+}
+
+func TestOCSPDelegatedResponder(t *testing.T) {
+	issuer, issuerKey, err := generateCert("Issuer", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 1234, false, issuer, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responderKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createResponder := func(usage []ExtKeyUsage) *Certificate {
+		template := &Certificate{
+			SerialNumber: big.NewInt(99),
+			Subject:      pkix.Name{CommonName: "OCSP Responder"},
+			NotBefore:    time.Unix(1000, 0),
+			NotAfter:     time.Unix(100000, 0),
+			ExtKeyUsage:  usage,
+		}
+		der, err := CreateCertificate(rand.Reader, template, issuer, &responderKey.PublicKey, issuerKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+
+	for _, test := range []struct {
+		name  string
+		usage []ExtKeyUsage
+		ok    bool
+	}{
+		{"OCSPSigning", []ExtKeyUsage{ExtKeyUsageOCSPSigning}, true},
+		{"ServerAuth", []ExtKeyUsage{ExtKeyUsageServerAuth}, false},
+	} {
+		responder := createResponder(test.usage)
+		template := &OCSPResponse{
+			Status:       OCSPGood,
+			SerialNumber: leaf.SerialNumber,
+			ThisUpdate:   time.Now(),
+			Certificate:  responder,
+		}
+		der, err := CreateOCSPResponse(rand.Reader, issuer, responder, template, responderKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ParseOCSPResponse(der, issuer)
+		if test.ok {
+			if err != nil {
+				t.Errorf("%s: failed to parse response: %s", test.name, err)
+			} else if !resp.Certificate.Equal(responder) {
+				t.Errorf("%s: responder certificate is missing", test.name)
+			}
+		} else if err == nil {
+			t.Errorf("%s: response from unauthorized responder verified", test.name)
+		}
+	}
+}
+
+func TestVerifyCheckRevocationOCSP(t *testing.T) {
+	root, rootKey, err := generateCert("Root", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 2, false, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stand-in for an OCSP responder, which revokes every certificate.
+	respond := func(req *OCSPRequest) ([]byte, error) {
+		return CreateOCSPResponse(rand.Reader, root, root, &OCSPResponse{
+			Status:       OCSPRevoked,
+			SerialNumber: req.SerialNumber,
+			IssuerHash:   req.HashAlgorithm,
+			ThisUpdate:   time.Unix(2000, 0),
+			RevokedAt:    time.Unix(1500, 0),
+		}, rootKey)
+	}
+	checkOCSP := func(cert, issuer *Certificate) error {
+		reqDER, err := CreateOCSPRequest(cert, issuer, crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		req, err := ParseOCSPRequest(reqDER)
+		if err != nil {
+			return err
+		}
+		respDER, err := respond(req)
+		if err != nil {
+			return err
+		}
+		resp, err := ParseOCSPResponseForCert(respDER, cert, issuer)
+		if err != nil {
+			return err
+		}
+		if resp.Status != OCSPGood {
+			return errors.New("OCSP status is not good")
+		}
+		return nil
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	opts := VerifyOptions{
+		Roots:       roots,
+		CurrentTime: time.Unix(2000, 0),
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		t.Fatalf("failed to verify without revocation checking: %s", err)
+	}
+	opts.CheckRevocation = checkOCSP
+	_, err = leaf.Verify(opts)
+	if err == nil || !strings.Contains(err.Error(), "OCSP status is not good") {
+		t.Errorf("got error %v, want a revocation error", err)
+	}
+}
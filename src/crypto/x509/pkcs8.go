@@ -5,12 +5,16 @@
 package x509
 
 import (
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"math/big"
 )
 
 // pkcs8 reflects an ASN.1, PKCS#8 PrivateKey. See
@@ -23,8 +27,13 @@ type pkcs8 struct {
 	// optional attributes omitted.
 }
 
-// ParsePKCS8PrivateKey parses an unencrypted, PKCS#8 private key.
-// See RFC 5208.
+// ParsePKCS8PrivateKey parses an unencrypted private key in PKCS#8, ASN.1 DER form.
+//
+// It returns a *rsa.PrivateKey, a *ecdsa.PrivateKey, an ed25519.PrivateKey (not
+// a pointer), or a *ecdh.PrivateKey (for X25519). More types might be supported
+// in the future.
+//
+// See RFC 5208 and RFC 8410.
 func ParsePKCS8PrivateKey(der []byte) (key interface{}, err error) {
 	var privKey pkcs8
 	if _, err := asn1.Unmarshal(der, &privKey); err != nil {
@@ -50,16 +59,40 @@ func ParsePKCS8PrivateKey(der []byte) (key interface{}, err error) {
 		}
 		return key, nil
 
+	case privKey.Algo.Algorithm.Equal(oidPublicKeyEd25519):
+		if len(privKey.Algo.Parameters.FullBytes) != 0 {
+			return nil, errors.New("x509: invalid Ed25519 private key parameters")
+		}
+		var curvePrivateKey []byte
+		if _, err := asn1.Unmarshal(privKey.PrivateKey, &curvePrivateKey); err != nil {
+			return nil, fmt.Errorf("x509: invalid Ed25519 private key: %v", err)
+		}
+		if l := len(curvePrivateKey); l != ed25519.SeedSize {
+			return nil, fmt.Errorf("x509: invalid Ed25519 private key length: %d", l)
+		}
+		return ed25519.NewKeyFromSeed(curvePrivateKey), nil
+
+	case privKey.Algo.Algorithm.Equal(oidPublicKeyX25519):
+		if len(privKey.Algo.Parameters.FullBytes) != 0 {
+			return nil, errors.New("x509: invalid X25519 private key parameters")
+		}
+		var curvePrivateKey []byte
+		if _, err := asn1.Unmarshal(privKey.PrivateKey, &curvePrivateKey); err != nil {
+			return nil, fmt.Errorf("x509: invalid X25519 private key: %v", err)
+		}
+		return ecdh.X25519().NewPrivateKey(curvePrivateKey)
+
 	default:
 		return nil, fmt.Errorf("x509: PKCS#8 wrapping contained private key with unknown algorithm: %v", privKey.Algo.Algorithm)
 	}
 }
 
 // MarshalPKCS8PrivateKey converts a private key to PKCS#8 encoded form.
-// The following key types are supported: *rsa.PrivateKey, *ecdsa.PrivateKey.
+// The following key types are supported: *rsa.PrivateKey, *ecdsa.PrivateKey,
+// ed25519.PrivateKey (not a pointer), and *ecdh.PrivateKey.
 // Unsupported key types result in an error.
 //
-// See RFC 5208.
+// See RFC 5208 and RFC 8410.
 func MarshalPKCS8PrivateKey(key interface{}) ([]byte, error) {
 	var privKey pkcs8
 
@@ -93,9 +126,64 @@ func MarshalPKCS8PrivateKey(key interface{}) ([]byte, error) {
 			return nil, errors.New("x509: failed to marshal EC private key while building PKCS#8: " + err.Error())
 		}
 
+	case ed25519.PrivateKey:
+		privKey.Algo = pkix.AlgorithmIdentifier{
+			Algorithm: oidPublicKeyEd25519,
+		}
+		curvePrivateKey, err := asn1.Marshal(k.Seed())
+		if err != nil {
+			return nil, fmt.Errorf("x509: failed to marshal private key: %v", err)
+		}
+		privKey.PrivateKey = curvePrivateKey
+
+	case *ecdh.PrivateKey:
+		if k.Curve() == ecdh.X25519() {
+			privKey.Algo = pkix.AlgorithmIdentifier{
+				Algorithm: oidPublicKeyX25519,
+			}
+			curvePrivateKey, err := asn1.Marshal(k.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("x509: failed to marshal private key: %v", err)
+			}
+			privKey.PrivateKey = curvePrivateKey
+			break
+		}
+
+		// NIST curve keys are encoded like ECDSA keys, as an
+		// ECPrivateKey structure.
+		ecKey, err := ecdsaKeyFromECDH(k)
+		if err != nil {
+			return nil, err
+		}
+		return MarshalPKCS8PrivateKey(ecKey)
+
 	default:
 		return nil, fmt.Errorf("x509: unknown key type while marshalling PKCS#8: %T", key)
 	}
 
 	return asn1.Marshal(privKey)
 }
+
+// ecdsaKeyFromECDH converts a *ecdh.PrivateKey on a NIST curve to the
+// equivalent *ecdsa.PrivateKey.
+func ecdsaKeyFromECDH(k *ecdh.PrivateKey) (*ecdsa.PrivateKey, error) {
+	var curve elliptic.Curve
+	switch k.Curve() {
+	case ecdh.P256():
+		curve = elliptic.P256()
+	case ecdh.P384():
+		curve = elliptic.P384()
+	case ecdh.P521():
+		curve = elliptic.P521()
+	default:
+		return nil, errors.New("x509: unknown curve while marshalling to PKCS#8")
+	}
+	x, y := elliptic.Unmarshal(curve, k.PublicKey().Bytes())
+	if x == nil {
+		return nil, errors.New("x509: invalid ECDH public key")
+	}
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		D:         new(big.Int).SetBytes(k.Bytes()),
+	}, nil
+}
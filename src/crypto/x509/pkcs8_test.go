@@ -6,8 +6,11 @@ package x509
 
 import (
 	"bytes"
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"encoding/hex"
 	"reflect"
@@ -39,6 +42,13 @@ var pkcs8P384PrivateKeyHex = `3081b6020100301006072a8648ce3d020106052b8104002204
 // expected and the Go test will fail to recreate it exactly.
 var pkcs8P521PrivateKeyHex = `3081ee020100301006072a8648ce3d020106052b810400230481d63081d3020101044200cfe0b87113a205cf291bb9a8cd1a74ac6c7b2ebb8199aaa9a5010d8b8012276fa3c22ac913369fa61beec2a3b8b4516bc049bde4fb3b745ac11b56ab23ac52e361a1818903818600040138f75acdd03fbafa4f047a8e4b272ba9d555c667962b76f6f232911a5786a0964e5edea6bd21a6f8725720958de049c6e3e6661c1c91b227cebee916c0319ed6ca003db0a3206d372229baf9dd25d868bf81140a518114803ce40c1855074d68c4e9dab9e65efba7064c703b400f1767f217dac82715ac1f6d88c74baf47a7971de4ea`
 
+// From RFC 8410, Section 7.
+var pkcs8Ed25519PrivateKeyHex = `302e020100300506032b657004220420d4ee72dbf913584ad5b6d8f1f769f8ad3afe7c28cbf1d4fbe097a88f44755842`
+
+// Generated using:
+//   openssl genpkey -algorithm x25519 -outform der
+var pkcs8X25519PrivateKeyHex = `302e020100300506032b656e04220420a0febd3e1aa45e83de2ec4083b38ce1367eac0a418a9f3f2fe3ec50895535b7e`
+
 func TestPKCS8(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -75,6 +85,16 @@ func TestPKCS8(t *testing.T) {
 			keyType: reflect.TypeOf(&ecdsa.PrivateKey{}),
 			curve:   elliptic.P521(),
 		},
+		{
+			name:    "Ed25519 private key",
+			keyHex:  pkcs8Ed25519PrivateKeyHex,
+			keyType: reflect.TypeOf(ed25519.PrivateKey{}),
+		},
+		{
+			name:    "X25519 private key",
+			keyHex:  pkcs8X25519PrivateKeyHex,
+			keyType: reflect.TypeOf(&ecdh.PrivateKey{}),
+		},
 	}
 
 	for _, test := range tests {
@@ -107,3 +127,43 @@ func TestPKCS8(t *testing.T) {
 		}
 	}
 }
+
+func TestPKCS8ECDH(t *testing.T) {
+	for _, curve := range []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.P521()} {
+		key, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		derBytes, err := MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Errorf("%v: failed to marshal into PKCS#8: %s", curve, err)
+			continue
+		}
+		privKey, err := ParsePKCS8PrivateKey(derBytes)
+		if err != nil {
+			t.Errorf("%v: failed to decode PKCS#8: %s", curve, err)
+			continue
+		}
+		ecKey, ok := privKey.(*ecdsa.PrivateKey)
+		if !ok {
+			t.Errorf("%v: decoded PKCS#8 returned unexpected key type: %T", curve, privKey)
+			continue
+		}
+		pub := elliptic.Marshal(ecKey.Curve, ecKey.X, ecKey.Y)
+		if !bytes.Equal(pub, key.PublicKey().Bytes()) {
+			t.Errorf("%v: decoded PKCS#8 has public key %x, want %x", curve, pub, key.PublicKey().Bytes())
+		}
+	}
+}
+
+func TestPKCS8Ed25519InvalidLength(t *testing.T) {
+	derBytes, _ := hex.DecodeString(pkcs8Ed25519PrivateKeyHex)
+	// Truncate the seed, fixing up the lengths.
+	derBytes = derBytes[:len(derBytes)-1]
+	derBytes[1]--
+	derBytes[len(derBytes)-32]--
+	derBytes[len(derBytes)-32+2]--
+	if _, err := ParsePKCS8PrivateKey(derBytes); err == nil {
+		t.Error("ParsePKCS8PrivateKey accepted a truncated Ed25519 seed")
+	}
+}
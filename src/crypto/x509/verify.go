@@ -58,6 +58,9 @@ const (
 	// CANotAuthorizedForExtKeyUsage results when an intermediate or root
 	// certificate does not permit a requested extended key usage.
 	CANotAuthorizedForExtKeyUsage
+	// Revoked results when VerifyOptions.CheckRevocation rejects a
+	// certificate, for example because it has been revoked.
+	Revoked
 )
 
 // CertificateInvalidError results when an odd error occurs. Users of this
@@ -66,6 +69,9 @@ type CertificateInvalidError struct {
 	Cert   *Certificate
 	Reason InvalidReason
 	Detail string
+	// Err is the error returned by VerifyOptions.CheckRevocation
+	// when Reason is Revoked, and nil otherwise.
+	Err error
 }
 
 func (e CertificateInvalidError) Error() string {
@@ -88,10 +94,14 @@ func (e CertificateInvalidError) Error() string {
 		return "x509: issuer has name constraints but leaf doesn't have a SAN extension"
 	case UnconstrainedName:
 		return "x509: issuer has name constraints but leaf contains unknown or unconstrained name: " + e.Detail
+	case Revoked:
+		return "x509: certificate failed revocation check: " + e.Detail
 	}
 	return "x509: unknown error"
 }
 
+func (e CertificateInvalidError) Unwrap() error { return e.Err }
+
 // HostnameError results when the set of authorized names doesn't match the
 // requested name.
 type HostnameError struct {
@@ -193,6 +203,21 @@ type VerifyOptions struct {
 	// certificates from consuming excessive amounts of CPU time when
 	// validating.
 	MaxConstraintComparisions int
+	// CheckRevocation, if not nil, is called for every certificate in a
+	// candidate chain, except the root, together with the certificate that
+	// issued it. If it returns an error, chains containing that certificate
+	// are rejected. It is typically implemented with a CRL (see
+	// ParseRevocationList) or an OCSP response for the certificate.
+	CheckRevocation func(cert, issuer *Certificate) error
+
+	// VerifyChain, if not nil, is called for each candidate chain that
+	// passes all other checks, except CheckRevocation, which is applied
+	// afterwards. The chain runs from the leaf to the root and must not be
+	// modified. If VerifyChain returns an error, the chain is discarded,
+	// and if every chain is discarded, Verify returns the first such error
+	// unchanged. It can be used to add policy checks, such as pinning
+	// public keys, without reimplementing chain building.
+	VerifyChain func(chain []*Certificate) error
 }
 
 const (
@@ -504,18 +529,18 @@ func (c *Certificate) checkNameConstraints(count *int,
 
 	*count += excludedValue.Len()
 	if *count > maxConstraintComparisons {
-		return CertificateInvalidError{c, TooManyConstraints, ""}
+		return CertificateInvalidError{c, TooManyConstraints, "", nil}
 	}
 
 	for i := 0; i < excludedValue.Len(); i++ {
 		constraint := excludedValue.Index(i).Interface()
 		match, err := match(parsedName, constraint)
 		if err != nil {
-			return CertificateInvalidError{c, CANotAuthorizedForThisName, err.Error()}
+			return CertificateInvalidError{c, CANotAuthorizedForThisName, err.Error(), nil}
 		}
 
 		if match {
-			return CertificateInvalidError{c, CANotAuthorizedForThisName, fmt.Sprintf("%s %q is excluded by constraint %q", nameType, name, constraint)}
+			return CertificateInvalidError{c, CANotAuthorizedForThisName, fmt.Sprintf("%s %q is excluded by constraint %q", nameType, name, constraint), nil}
 		}
 	}
 
@@ -523,7 +548,7 @@ func (c *Certificate) checkNameConstraints(count *int,
 
 	*count += permittedValue.Len()
 	if *count > maxConstraintComparisons {
-		return CertificateInvalidError{c, TooManyConstraints, ""}
+		return CertificateInvalidError{c, TooManyConstraints, "", nil}
 	}
 
 	ok := true
@@ -532,7 +557,7 @@ func (c *Certificate) checkNameConstraints(count *int,
 
 		var err error
 		if ok, err = match(parsedName, constraint); err != nil {
-			return CertificateInvalidError{c, CANotAuthorizedForThisName, err.Error()}
+			return CertificateInvalidError{c, CANotAuthorizedForThisName, err.Error(), nil}
 		}
 
 		if ok {
@@ -541,7 +566,7 @@ func (c *Certificate) checkNameConstraints(count *int,
 	}
 
 	if !ok {
-		return CertificateInvalidError{c, CANotAuthorizedForThisName, fmt.Sprintf("%s %q is not permitted by any constraint", nameType, name)}
+		return CertificateInvalidError{c, CANotAuthorizedForThisName, fmt.Sprintf("%s %q is not permitted by any constraint", nameType, name), nil}
 	}
 
 	return nil
@@ -557,7 +582,7 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 	if len(currentChain) > 0 {
 		child := currentChain[len(currentChain)-1]
 		if !bytes.Equal(child.RawIssuer, c.RawSubject) {
-			return CertificateInvalidError{c, NameMismatch, ""}
+			return CertificateInvalidError{c, NameMismatch, "", nil}
 		}
 	}
 
@@ -566,7 +591,7 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 		now = time.Now()
 	}
 	if now.Before(c.NotBefore) || now.After(c.NotAfter) {
-		return CertificateInvalidError{c, Expired, ""}
+		return CertificateInvalidError{c, Expired, "", nil}
 	}
 
 	maxConstraintComparisons := opts.MaxConstraintComparisions
@@ -590,7 +615,7 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 			// the CN as a hostname. Chains modern enough to be
 			// using name constraints should not be depending on
 			// CNs.
-			return CertificateInvalidError{c, NameConstraintsWithoutSANs, ""}
+			return CertificateInvalidError{c, NameConstraintsWithoutSANs, "", nil}
 		}
 
 		err := forEachSAN(sanExtension, func(tag int, data []byte) error {
@@ -679,13 +704,13 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 	// encryption key could only be used for Diffie-Hellman key agreement.
 
 	if certType == intermediateCertificate && (!c.BasicConstraintsValid || !c.IsCA) {
-		return CertificateInvalidError{c, NotAuthorizedToSign, ""}
+		return CertificateInvalidError{c, NotAuthorizedToSign, "", nil}
 	}
 
 	if c.BasicConstraintsValid && c.MaxPathLen >= 0 {
 		numIntermediates := len(currentChain) - 1
 		if numIntermediates > c.MaxPathLen {
-			return CertificateInvalidError{c, TooManyIntermediates, ""}
+			return CertificateInvalidError{c, TooManyIntermediates, "", nil}
 		}
 	}
 
@@ -729,6 +754,12 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 	if len(c.Raw) == 0 {
 		return nil, errNotParsed
 	}
+	if err := opts.Roots.Load(); err != nil {
+		return nil, err
+	}
+	if err := opts.Intermediates.Load(); err != nil {
+		return nil, err
+	}
 	if opts.Intermediates != nil {
 		for _, intermediate := range opts.Intermediates.certs {
 			if len(intermediate.Raw) == 0 {
@@ -739,7 +770,10 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 
 	// Use Windows's own verification and chain building.
 	if opts.Roots == nil && runtime.GOOS == "windows" {
-		return c.systemVerify(&opts)
+		if chains, err = c.systemVerify(&opts); err != nil {
+			return nil, err
+		}
+		return checkChains(chains, &opts)
 	}
 
 	if opts.Roots == nil {
@@ -775,24 +809,91 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 		keyUsages = []ExtKeyUsage{ExtKeyUsageServerAuth}
 	}
 
-	// If any key usage is acceptable then we're done.
+	// If any key usage is acceptable then there's nothing to filter.
+	anyKeyUsage := false
 	for _, usage := range keyUsages {
 		if usage == ExtKeyUsageAny {
-			return candidateChains, nil
+			anyKeyUsage = true
+			break
 		}
 	}
 
-	for _, candidate := range candidateChains {
-		if checkChainForKeyUsage(candidate, keyUsages) {
-			chains = append(chains, candidate)
+	if anyKeyUsage {
+		chains = candidateChains
+	} else {
+		for _, candidate := range candidateChains {
+			if checkChainForKeyUsage(candidate, keyUsages) {
+				chains = append(chains, candidate)
+			}
 		}
 	}
 
 	if len(chains) == 0 {
-		return nil, CertificateInvalidError{c, IncompatibleUsage, ""}
+		return nil, CertificateInvalidError{c, IncompatibleUsage, "", nil}
 	}
 
-	return chains, nil
+	return checkChains(chains, &opts)
+}
+
+// checkChains applies opts.VerifyChain and opts.CheckRevocation to chains,
+// which have passed all other checks, and returns the chains which remain.
+func checkChains(chains [][]*Certificate, opts *VerifyOptions) ([][]*Certificate, error) {
+	if opts.VerifyChain != nil {
+		var good [][]*Certificate
+		var firstErr error
+		for _, chain := range chains {
+			if err := opts.VerifyChain(chain); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			good = append(good, chain)
+		}
+		if len(good) == 0 {
+			return nil, firstErr
+		}
+		chains = good
+	}
+
+	return checkChainsForRevocation(chains, opts)
+}
+
+// checkChainsForRevocation returns the chains which pass
+// opts.CheckRevocation, or the first error if none do. Each certificate and
+// issuer pair is only checked once.
+func checkChainsForRevocation(chains [][]*Certificate, opts *VerifyOptions) ([][]*Certificate, error) {
+	if opts.CheckRevocation == nil {
+		return chains, nil
+	}
+
+	type certPair struct{ cert, issuer *Certificate }
+	checked := make(map[certPair]error)
+	var good [][]*Certificate
+	var firstErr error
+nextChain:
+	for _, chain := range chains {
+		for i := 0; i < len(chain)-1; i++ {
+			pair := certPair{chain[i], chain[i+1]}
+			err, ok := checked[pair]
+			if !ok {
+				err = opts.CheckRevocation(pair.cert, pair.issuer)
+				checked[pair] = err
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = CertificateInvalidError{pair.cert, Revoked, err.Error(), err}
+				}
+				continue nextChain
+			}
+		}
+		good = append(good, chain)
+	}
+
+	if len(good) == 0 {
+		return nil, firstErr
+	}
+	return good, nil
 }
 
 func appendToFreshChain(chain []*Certificate, cert *Certificate) []*Certificate {
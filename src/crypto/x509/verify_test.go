@@ -5,10 +5,20 @@
 package x509
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509/pkix"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -1707,3 +1717,308 @@ UNhY4JhezH9gQYqvDMWrWDAbBgNVHSMEFDASgBArF29S5Bnqw7de8GzGA1nfMAoG
 CCqGSM49BAMCA0gAMEUCIQClA3d4tdrDu9Eb5ZBpgyC+fU1xTZB0dKQHz6M5fPZA
 2AIgN96lM+CPGicwhN24uQI6flOsO3H0TJ5lNzBYLtnQtlc=
 -----END CERTIFICATE-----`
+
+// generateCert creates a certificate for cn with a fresh P-256 key, signed by
+// issuer, or self-signed if issuer is nil.
+func generateCert(cn string, serial int64, isCA bool, issuer *Certificate, issuerKey crypto.Signer) (*Certificate, crypto.Signer, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		SubjectKeyId: []byte(cn),
+		NotBefore:    time.Unix(1000, 0),
+		NotAfter:     time.Unix(100000, 0),
+		ExtKeyUsage:  []ExtKeyUsage{ExtKeyUsageServerAuth},
+	}
+	if isCA {
+		template.KeyUsage = KeyUsageCertSign | KeyUsageCRLSign
+		template.BasicConstraintsValid = true
+		template.IsCA = true
+	} else {
+		template.DNSNames = []string{cn}
+	}
+	if issuer == nil {
+		issuer, issuerKey = template, priv
+	}
+	der, err := CreateCertificate(rand.Reader, template, issuer, priv.Public(), issuerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, priv, nil
+}
+
+func TestVerifyCheckRevocation(t *testing.T) {
+	root, rootKey, err := generateCert("Root", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inter, interKey, err := generateCert("Intermediate", 2, true, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 3, false, inter, interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := generateCert("other.example.com", 4, false, inter, interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The intermediate revokes leaf.
+	crlDER, err := CreateRevocationList(rand.Reader, &RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Unix(1500, 0),
+		NextUpdate: time.Unix(5000, 0),
+		RevokedCertificates: []RevocationListEntry{
+			{SerialNumber: leaf.SerialNumber, RevocationTime: time.Unix(1500, 0), ReasonCode: 1},
+		},
+	}, inter, interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseRevocationList(crlDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	checkCRL := func(cert, issuer *Certificate) error {
+		calls = append(calls, cert.Subject.CommonName+"/"+issuer.Subject.CommonName)
+		if !bytes.Equal(issuer.RawSubject, crl.RawIssuer) {
+			return nil
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			return err
+		}
+		for _, entry := range crl.RevokedCertificates {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return fmt.Errorf("serial %v revoked with reason %d", entry.SerialNumber, entry.ReasonCode)
+			}
+		}
+		return nil
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	intermediates := NewCertPool()
+	intermediates.AddCert(inter)
+	opts := VerifyOptions{
+		Roots:           roots,
+		Intermediates:   intermediates,
+		CurrentTime:     time.Unix(2000, 0),
+		CheckRevocation: checkCRL,
+	}
+
+	chains, err := other.Verify(opts)
+	if err != nil {
+		t.Fatalf("failed to verify unrevoked certificate: %s", err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 3 {
+		t.Errorf("got %d chains, want one of length 3", len(chains))
+	}
+	want := []string{"other.example.com/Intermediate", "Intermediate/Root"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("CheckRevocation called with %q, want %q", calls, want)
+	}
+
+	_, err = leaf.Verify(opts)
+	invalidErr, ok := err.(CertificateInvalidError)
+	if !ok || invalidErr.Reason != Revoked || invalidErr.Cert != leaf {
+		t.Fatalf("verifying revoked certificate: got error %v, want a Revoked CertificateInvalidError", err)
+	}
+	if !strings.Contains(err.Error(), "reason 1") {
+		t.Errorf("error %q doesn't include the hook's error", err)
+	}
+}
+
+func TestVerifyCheckRevocationUnwrap(t *testing.T) {
+	root, rootKey, err := generateCert("Root", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 2, false, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errRevoked := errors.New("revoked")
+	roots := NewCertPool()
+	roots.AddCert(root)
+	opts := VerifyOptions{
+		Roots:       roots,
+		CurrentTime: time.Unix(2000, 0),
+		CheckRevocation: func(cert, issuer *Certificate) error {
+			return fmt.Errorf("checking %s: %w", cert.Subject.CommonName, errRevoked)
+		},
+	}
+	_, err = leaf.Verify(opts)
+	if !errors.Is(err, errRevoked) {
+		t.Errorf("Verify returned %v, want an error wrapping errRevoked", err)
+	}
+	var invalidErr CertificateInvalidError
+	if !errors.As(err, &invalidErr) || invalidErr.Reason != Revoked {
+		t.Errorf("Verify returned %v, want a Revoked CertificateInvalidError", err)
+	}
+}
+
+func TestVerifyChainCallback(t *testing.T) {
+	root, rootKey, err := generateCert("Root", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two intermediates with the same name and key ID, so that there are
+	// two candidate chains for the leaf.
+	inter1, interKey, err := generateCert("Intermediate", 2, true, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inter2Template := *inter1
+	inter2Template.SerialNumber = big.NewInt(3)
+	inter2DER, err := CreateCertificate(rand.Reader, &inter2Template, root, interKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inter2, err := ParseCertificate(inter2DER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 4, false, inter1, interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	intermediates := NewCertPool()
+	intermediates.AddCert(inter1)
+	intermediates.AddCert(inter2)
+	opts := VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Unix(2000, 0),
+	}
+	chains, err := leaf.Verify(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 2 {
+		t.Fatalf("got %d chains, want 2", len(chains))
+	}
+
+	// Reject chains through the first intermediate.
+	var calls int
+	opts.VerifyChain = func(chain []*Certificate) error {
+		calls++
+		if len(chain) != 3 || !chain[0].Equal(leaf) || !chain[2].Equal(root) {
+			t.Errorf("unexpected chain %v", chain)
+		}
+		if chain[1].Equal(inter1) {
+			return errors.New("pinned intermediate not found")
+		}
+		return nil
+	}
+	chains, err = leaf.Verify(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("VerifyChain was called %d times, want 2", calls)
+	}
+	if len(chains) != 1 || !chains[0][1].Equal(inter2) {
+		t.Errorf("got chains %v, want only the chain through the second intermediate", chains)
+	}
+
+	// Reject all chains.
+	sentinel := errors.New("policy says no")
+	opts.VerifyChain = func(chain []*Certificate) error { return sentinel }
+	if _, err := leaf.Verify(opts); err != sentinel {
+		t.Errorf("got error %v, want %v", err, sentinel)
+	}
+}
+
+func TestLazyCertPool(t *testing.T) {
+	root, rootKey, err := generateCert("Root", 1, true, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _, err := generateCert("leaf.example.com", 2, false, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "x509-lazy-pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rootPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})
+	bundle := filepath.Join(dir, "bundle.pem")
+	if err := ioutil.WriteFile(bundle, rootPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	loads := 0
+	counted := NewLazyCertPool(func(pool *CertPool) error {
+		loads++
+		pool.AddCert(root)
+		return nil
+	})
+	if loads != 0 {
+		t.Fatal("NewLazyCertPool called load immediately")
+	}
+
+	tests := []struct {
+		name  string
+		pool  *CertPool
+		valid bool
+	}{
+		{"NewLazyCertPool", counted, true},
+		{"NewCertPoolFromFile", NewCertPoolFromFile(bundle), true},
+		{"NewCertPoolFromDir", NewCertPoolFromDir(dir), true},
+		{"NewCertPoolFromFile/missing", NewCertPoolFromFile(filepath.Join(dir, "missing")), false},
+		{"NewCertPoolFromFile/empty", NewCertPoolFromFile(filepath.Join(dir, "README")), false},
+		{"NewCertPoolFromDir/missing", NewCertPoolFromDir(filepath.Join(dir, "missing")), false},
+		{"NewCertPoolFromDir/empty", NewCertPoolFromDir(empty), false},
+	}
+	for _, test := range tests {
+		for i := 0; i < 2; i++ {
+			_, err := leaf.Verify(VerifyOptions{
+				Roots:       test.pool,
+				CurrentTime: time.Unix(2000, 0),
+			})
+			if test.valid && err != nil {
+				t.Errorf("%s: verification failed: %s", test.name, err)
+			} else if !test.valid && err == nil {
+				t.Errorf("%s: verification succeeded with a pool that failed to load", test.name)
+			}
+		}
+		if loadErr := test.pool.Load(); (loadErr == nil) != test.valid {
+			t.Errorf("%s: Load returned %v", test.name, loadErr)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("load was called %d times, want 1", loads)
+	}
+
+	// Adding to a lazy pool keeps the loaded certificates.
+	pool := NewCertPoolFromFile(bundle)
+	pool.AddCert(leaf)
+	if n := len(pool.Subjects()); n != 2 {
+		t.Errorf("pool has %d certificates, want 2", n)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/dsa"
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
 	_ "crypto/sha1"
@@ -47,11 +49,12 @@ type pkixPublicKey struct {
 // ParsePKIXPublicKey parses a DER encoded public key. These values are
 // typically found in PEM blocks with "BEGIN PUBLIC KEY".
 //
-// Supported key types include RSA, DSA, and ECDSA. Unknown key
-// types result in an error.
+// Supported key types include RSA, DSA, ECDSA, Ed25519, and X25519.
+// Unknown key types result in an error.
 //
 // On success, pub will be of type *rsa.PublicKey, *dsa.PublicKey,
-// or *ecdsa.PublicKey.
+// *ecdsa.PublicKey, ed25519.PublicKey (not a pointer), or
+// *ecdh.PublicKey (for X25519).
 func ParsePKIXPublicKey(derBytes []byte) (pub interface{}, err error) {
 	var pki publicKeyInfo
 	if rest, err := asn1.Unmarshal(derBytes, &pki); err != nil {
@@ -60,7 +63,7 @@ func ParsePKIXPublicKey(derBytes []byte) (pub interface{}, err error) {
 		return nil, errors.New("x509: trailing data after ASN.1 of public-key")
 	}
 	algo := getPublicKeyAlgorithmFromOID(pki.Algorithm.Algorithm)
-	if algo == UnknownPublicKeyAlgorithm {
+	if algo == UnknownPublicKeyAlgorithm && !pki.Algorithm.Algorithm.Equal(oidPublicKeyX25519) {
 		return nil, errors.New("x509: unknown public key algorithm")
 	}
 	return parsePublicKey(algo, &pki)
@@ -93,8 +96,28 @@ func marshalPublicKey(pub interface{}) (publicKeyBytes []byte, publicKeyAlgorith
 			return
 		}
 		publicKeyAlgorithm.Parameters.FullBytes = paramBytes
+	case ed25519.PublicKey:
+		publicKeyBytes = pub
+		publicKeyAlgorithm.Algorithm = oidPublicKeyEd25519
+	case *ecdh.PublicKey:
+		publicKeyBytes = pub.Bytes()
+		if pub.Curve() == ecdh.X25519() {
+			publicKeyAlgorithm.Algorithm = oidPublicKeyX25519
+			break
+		}
+		oid, ok := oidFromECDHCurve(pub.Curve())
+		if !ok {
+			return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: unsupported elliptic curve")
+		}
+		publicKeyAlgorithm.Algorithm = oidPublicKeyECDSA
+		var paramBytes []byte
+		paramBytes, err = asn1.Marshal(oid)
+		if err != nil {
+			return
+		}
+		publicKeyAlgorithm.Parameters.FullBytes = paramBytes
 	default:
-		return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: only RSA and ECDSA public keys supported")
+		return nil, pkix.AlgorithmIdentifier{}, errors.New("x509: only RSA, ECDSA, Ed25519, and X25519 public keys supported")
 	}
 
 	return publicKeyBytes, publicKeyAlgorithm, nil
@@ -189,6 +212,7 @@ const (
 	SHA256WithRSAPSS
 	SHA384WithRSAPSS
 	SHA512WithRSAPSS
+	PureEd25519
 )
 
 func (algo SignatureAlgorithm) isRSAPSS() bool {
@@ -216,12 +240,14 @@ const (
 	RSA
 	DSA
 	ECDSA
+	Ed25519
 )
 
 var publicKeyAlgoName = [...]string{
-	RSA:   "RSA",
-	DSA:   "DSA",
-	ECDSA: "ECDSA",
+	RSA:     "RSA",
+	DSA:     "DSA",
+	ECDSA:   "ECDSA",
+	Ed25519: "Ed25519",
 }
 
 func (algo PublicKeyAlgorithm) String() string {
@@ -280,6 +306,11 @@ func (algo PublicKeyAlgorithm) String() string {
 //
 // ecdsa-with-SHA512 OBJECT IDENTIFIER ::= { iso(1) member-body(2)
 //    us(840) ansi-X9-62(10045) signatures(4) ecdsa-with-SHA2(3) 4 }
+//
+//
+// RFC 8410 3 Curve25519 and Curve448 Algorithm Identifiers
+//
+// id-Ed25519   OBJECT IDENTIFIER ::= { 1 3 101 112 }
 
 var (
 	oidSignatureMD2WithRSA      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 2}
@@ -295,6 +326,7 @@ var (
 	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
 	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
 	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidSignatureEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
 
 	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
 	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
@@ -331,6 +363,7 @@ var signatureAlgorithmDetails = []struct {
 	{ECDSAWithSHA256, "ECDSA-SHA256", oidSignatureECDSAWithSHA256, ECDSA, crypto.SHA256},
 	{ECDSAWithSHA384, "ECDSA-SHA384", oidSignatureECDSAWithSHA384, ECDSA, crypto.SHA384},
 	{ECDSAWithSHA512, "ECDSA-SHA512", oidSignatureECDSAWithSHA512, ECDSA, crypto.SHA512},
+	{PureEd25519, "Ed25519", oidSignatureEd25519, Ed25519, crypto.Hash(0) /* no pre-hashing */},
 }
 
 // pssParameters reflects the parameters in an AlgorithmIdentifier that
@@ -454,10 +487,17 @@ func getSignatureAlgorithmFromAI(ai pkix.AlgorithmIdentifier) SignatureAlgorithm
 //
 // id-ecPublicKey OBJECT IDENTIFIER ::= {
 //       iso(1) member-body(2) us(840) ansi-X9-62(10045) keyType(2) 1 }
+//
+// RFC 8410, Section 3
+//
+// id-X25519    OBJECT IDENTIFIER ::= { 1 3 101 110 }
+// id-Ed25519   OBJECT IDENTIFIER ::= { 1 3 101 112 }
 var (
-	oidPublicKeyRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
-	oidPublicKeyDSA   = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
-	oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPublicKeyRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidPublicKeyDSA     = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 1}
+	oidPublicKeyECDSA   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidPublicKeyX25519  = asn1.ObjectIdentifier{1, 3, 101, 110}
+	oidPublicKeyEd25519 = oidSignatureEd25519
 )
 
 func getPublicKeyAlgorithmFromOID(oid asn1.ObjectIdentifier) PublicKeyAlgorithm {
@@ -468,6 +508,8 @@ func getPublicKeyAlgorithmFromOID(oid asn1.ObjectIdentifier) PublicKeyAlgorithm
 		return DSA
 	case oid.Equal(oidPublicKeyECDSA):
 		return ECDSA
+	case oid.Equal(oidPublicKeyEd25519):
+		return Ed25519
 	}
 	return UnknownPublicKeyAlgorithm
 }
@@ -524,6 +566,19 @@ func oidFromNamedCurve(curve elliptic.Curve) (asn1.ObjectIdentifier, bool) {
 	return nil, false
 }
 
+func oidFromECDHCurve(curve ecdh.Curve) (asn1.ObjectIdentifier, bool) {
+	switch curve {
+	case ecdh.P256():
+		return oidNamedCurveP256, true
+	case ecdh.P384():
+		return oidNamedCurveP384, true
+	case ecdh.P521():
+		return oidNamedCurveP521, true
+	}
+
+	return nil, false
+}
+
 // KeyUsage represents the set of actions that are valid for a given key. It's
 // a bitmap of the KeyUsage* constants.
 type KeyUsage int
@@ -879,21 +934,24 @@ func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey
 		}
 	}
 
+	var digest []byte
 	switch hashType {
 	case crypto.Hash(0):
-		return ErrUnsupportedAlgorithm
+		// Only Ed25519 signs the message without pre-hashing.
+		if pubKeyAlgo != Ed25519 {
+			return ErrUnsupportedAlgorithm
+		}
 	case crypto.MD5:
 		return InsecureAlgorithmError(algo)
+	default:
+		if !hashType.Available() {
+			return ErrUnsupportedAlgorithm
+		}
+		h := hashType.New()
+		h.Write(signed)
+		digest = h.Sum(nil)
 	}
 
-	if !hashType.Available() {
-		return ErrUnsupportedAlgorithm
-	}
-	h := hashType.New()
-
-	h.Write(signed)
-	digest := h.Sum(nil)
-
 	switch pub := publicKey.(type) {
 	case *rsa.PublicKey:
 		if pubKeyAlgo != RSA {
@@ -938,6 +996,14 @@ func checkSignature(algo SignatureAlgorithm, signed, signature []byte, publicKey
 			return errors.New("x509: ECDSA verification failure")
 		}
 		return
+	case ed25519.PublicKey:
+		if pubKeyAlgo != Ed25519 {
+			return signaturePublicKeyAlgoMismatchError(pubKeyAlgo, pub)
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return errors.New("x509: Ed25519 verification failure")
+		}
+		return
 	}
 	return ErrUnsupportedAlgorithm
 }
@@ -1075,8 +1141,28 @@ func parsePublicKey(algo PublicKeyAlgorithm, keyData *publicKeyInfo) (interface{
 			Y:     y,
 		}
 		return pub, nil
+	case Ed25519:
+		// RFC 8410, Section 3
+		// > For all of the OIDs, the parameters MUST be absent.
+		if len(keyData.Algorithm.Parameters.FullBytes) != 0 {
+			return nil, errors.New("x509: Ed25519 key encoded with illegal parameters")
+		}
+		if len(asn1Data) != ed25519.PublicKeySize {
+			return nil, errors.New("x509: wrong Ed25519 public key size")
+		}
+		pub := make([]byte, ed25519.PublicKeySize)
+		copy(pub, asn1Data)
+		return ed25519.PublicKey(pub), nil
 	default:
-		return nil, nil
+		if !keyData.Algorithm.Algorithm.Equal(oidPublicKeyX25519) {
+			return nil, nil
+		}
+		// X25519 keys may only be used for key agreement, so they
+		// have no PublicKeyAlgorithm of their own.
+		if len(keyData.Algorithm.Parameters.FullBytes) != 0 {
+			return nil, errors.New("x509: X25519 key encoded with illegal parameters")
+		}
+		return ecdh.X25519().NewPublicKey(asn1Data)
 	}
 }
 
@@ -1643,6 +1729,8 @@ var (
 	oidExtensionCertificatePolicies   = []int{2, 5, 29, 32}
 	oidExtensionNameConstraints       = []int{2, 5, 29, 30}
 	oidExtensionCRLDistributionPoints = []int{2, 5, 29, 31}
+	oidExtensionCRLNumber             = []int{2, 5, 29, 20}
+	oidExtensionReasonCode            = []int{2, 5, 29, 21}
 	oidExtensionAuthorityInfoAccess   = []int{1, 3, 6, 1, 5, 5, 7, 1, 1}
 )
 
@@ -1996,8 +2084,12 @@ func signingParamsForPublicKey(pub interface{}, requestedSigAlgo SignatureAlgori
 			err = errors.New("x509: unknown elliptic curve")
 		}
 
+	case ed25519.PublicKey:
+		pubType = Ed25519
+		sigAlgo.Algorithm = oidSignatureEd25519
+
 	default:
-		err = errors.New("x509: only RSA and ECDSA keys supported")
+		err = errors.New("x509: only RSA, ECDSA and Ed25519 keys supported")
 	}
 
 	if err != nil {
@@ -2016,7 +2108,7 @@ func signingParamsForPublicKey(pub interface{}, requestedSigAlgo SignatureAlgori
 				return
 			}
 			sigAlgo.Algorithm, hashFunc = details.oid, details.hash
-			if hashFunc == 0 {
+			if hashFunc == 0 && pubType != Ed25519 {
 				err = errors.New("x509: cannot sign with hash function requested")
 				return
 			}
@@ -2081,7 +2173,8 @@ var emptyASN1Subject = []byte{0x30, 0}
 // The returned slice is the certificate in DER encoding.
 //
 // All keys types that are implemented via crypto.Signer are supported (This
-// includes *rsa.PublicKey and *ecdsa.PublicKey.)
+// includes *rsa.PublicKey, *ecdsa.PublicKey and ed25519.PublicKey.) The
+// signee's key pub may also be an *ecdh.PublicKey.
 //
 // The AuthorityKeyId will be taken from the SubjectKeyId of parent, if any,
 // unless the resulting certificate is self-signed. Otherwise the value from
@@ -2145,9 +2238,12 @@ func CreateCertificate(rand io.Reader, template, parent *Certificate, pub, priv
 
 	c.Raw = tbsCertContents
 
-	h := hashFunc.New()
-	h.Write(tbsCertContents)
-	digest := h.Sum(nil)
+	digest := tbsCertContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
 
 	var signerOpts crypto.SignerOpts
 	signerOpts = hashFunc
@@ -2249,9 +2345,12 @@ func (c *Certificate) CreateCRL(rand io.Reader, priv interface{}, revokedCerts [
 		return
 	}
 
-	h := hashFunc.New()
-	h.Write(tbsCertListContents)
-	digest := h.Sum(nil)
+	digest := tbsCertListContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
 
 	var signature []byte
 	signature, err = key.Sign(rand, digest, hashFunc)
@@ -2266,6 +2365,312 @@ func (c *Certificate) CreateCRL(rand io.Reader, priv interface{}, revokedCerts [
 	})
 }
 
+// RevocationListEntry represents an entry in the revokedCertificates
+// sequence of a CRL.
+type RevocationListEntry struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+
+	// ReasonCode is the CRL reason code of the revocation, as defined in
+	// RFC 5280, Section 5.3.1. When creating a CRL, zero (unspecified)
+	// omits the reason code extension.
+	ReasonCode int
+
+	// Extensions contains raw X.509 extensions of the entry. When parsing
+	// CRLs, this can be used to extract extensions that are not parsed by
+	// this package. When marshaling CRLs, the Extensions field is ignored,
+	// see ExtraExtensions.
+	Extensions []pkix.Extension
+
+	// ExtraExtensions contains extensions to be copied, raw, into any
+	// marshaled CRL entry. Values override any extensions that would
+	// otherwise be produced based on the other fields.
+	ExtraExtensions []pkix.Extension
+}
+
+// RevocationList represents a Certificate Revocation List (CRL) as specified
+// by RFC 5280.
+type RevocationList struct {
+	Raw                  []byte // Complete ASN.1 DER content (tbsCertList, signature algorithm and signature).
+	RawTBSRevocationList []byte // Certificate list part of raw ASN.1 DER content.
+	RawIssuer            []byte // DER encoded Issuer.
+
+	Issuer             pkix.Name
+	AuthorityKeyId     []byte
+	Signature          []byte
+	SignatureAlgorithm SignatureAlgorithm
+
+	RevokedCertificates []RevocationListEntry
+
+	// Number is used to populate the X.509 v2 cRLNumber extension in the
+	// CRL, which should be a monotonically increasing sequence number for
+	// a given CRL scope and CRL issuer.
+	Number     *big.Int
+	ThisUpdate time.Time
+	NextUpdate time.Time
+
+	// Extensions contains raw X.509 extensions. When parsing CRLs, this
+	// can be used to extract extensions that are not parsed by this
+	// package. When marshaling CRLs, the Extensions field is ignored, see
+	// ExtraExtensions.
+	Extensions []pkix.Extension
+
+	// ExtraExtensions contains extensions to be copied, raw, into any
+	// marshaled CRL. Values override any extensions that would otherwise
+	// be produced based on the other fields.
+	ExtraExtensions []pkix.Extension
+}
+
+// tbsCertificateList is like pkix.TBSCertificateList, but keeps the issuer
+// as raw DER so that it matches the subject of the issuing certificate byte
+// for byte.
+type tbsCertificateList struct {
+	Raw                 asn1.RawContent
+	Version             int `asn1:"optional,default:0"`
+	Signature           pkix.AlgorithmIdentifier
+	Issuer              asn1.RawValue
+	ThisUpdate          time.Time
+	NextUpdate          time.Time                 `asn1:"optional"`
+	RevokedCertificates []pkix.RevokedCertificate `asn1:"optional"`
+	Extensions          []pkix.Extension          `asn1:"tag:0,optional,explicit"`
+}
+
+type certificateList struct {
+	Raw                asn1.RawContent
+	TBSCertList        tbsCertificateList
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// CreateRevocationList creates a new X.509 v2 Certificate Revocation List,
+// according to RFC 5280, based on template.
+//
+// The CRL is signed by priv which should be the private key associated with
+// the public key in the issuer certificate.
+//
+// The issuer may not be nil, and the crlSign bit must be set in KeyUsage in
+// order to use it as a CRL issuer.
+//
+// The issuer distinguished name CRL field and authority key identifier
+// extension are populated using the issuer certificate. issuer must have
+// SubjectKeyId set.
+func CreateRevocationList(rand io.Reader, template *RevocationList, issuer *Certificate, priv crypto.Signer) ([]byte, error) {
+	if template == nil {
+		return nil, errors.New("x509: template can not be nil")
+	}
+	if issuer == nil {
+		return nil, errors.New("x509: issuer can not be nil")
+	}
+	if issuer.KeyUsage&KeyUsageCRLSign == 0 {
+		return nil, errors.New("x509: issuer must have the crlSign key usage bit set")
+	}
+	if len(issuer.SubjectKeyId) == 0 {
+		return nil, errors.New("x509: issuer certificate doesn't contain a subject key identifier")
+	}
+	if template.ThisUpdate.IsZero() || template.NextUpdate.IsZero() {
+		return nil, errors.New("x509: template must set ThisUpdate and NextUpdate")
+	}
+	if template.NextUpdate.Before(template.ThisUpdate) {
+		return nil, errors.New("x509: template.ThisUpdate is after template.NextUpdate")
+	}
+	if template.Number == nil {
+		return nil, errors.New("x509: template contains nil Number field")
+	}
+	// RFC 5280, Section 5.2.3: CRL numbers are non-negative and at most 20
+	// octets long.
+	if template.Number.Sign() < 0 || template.Number.BitLen() > 20*8-1 {
+		return nil, errors.New("x509: CRL number must be a non-negative integer of at most 20 octets")
+	}
+
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var revokedCerts []pkix.RevokedCertificate
+	for _, entry := range template.RevokedCertificates {
+		if entry.SerialNumber == nil {
+			return nil, errors.New("x509: revoked certificate entry contains nil SerialNumber")
+		}
+		rc := pkix.RevokedCertificate{
+			SerialNumber: entry.SerialNumber,
+			// Force revocation times to UTC per RFC 5280.
+			RevocationTime: entry.RevocationTime.UTC(),
+		}
+		if entry.ReasonCode != 0 && !oidInExtensions(oidExtensionReasonCode, entry.ExtraExtensions) {
+			reasonBytes, err := asn1.Marshal(asn1.Enumerated(entry.ReasonCode))
+			if err != nil {
+				return nil, err
+			}
+			rc.Extensions = append(rc.Extensions, pkix.Extension{
+				Id:    oidExtensionReasonCode,
+				Value: reasonBytes,
+			})
+		}
+		rc.Extensions = append(rc.Extensions, entry.ExtraExtensions...)
+		revokedCerts = append(revokedCerts, rc)
+	}
+
+	var extensions []pkix.Extension
+	if !oidInExtensions(oidExtensionAuthorityKeyId, template.ExtraExtensions) {
+		aki, err := asn1.Marshal(authKeyId{Id: issuer.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionAuthorityKeyId, Value: aki})
+	}
+	if !oidInExtensions(oidExtensionCRLNumber, template.ExtraExtensions) {
+		crlNum, err := asn1.Marshal(template.Number)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionCRLNumber, Value: crlNum})
+	}
+	extensions = append(extensions, template.ExtraExtensions...)
+
+	asn1Issuer, err := subjectBytes(issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	tbsCertList := tbsCertificateList{
+		Version:             1, // v2
+		Signature:           signatureAlgorithm,
+		Issuer:              asn1.RawValue{FullBytes: asn1Issuer},
+		ThisUpdate:          template.ThisUpdate.UTC(),
+		NextUpdate:          template.NextUpdate.UTC(),
+		RevokedCertificates: revokedCerts,
+		Extensions:          extensions,
+	}
+
+	tbsCertListContents, err := asn1.Marshal(tbsCertList)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := tbsCertListContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+
+	var signerOpts crypto.SignerOpts
+	signerOpts = hashFunc
+	if template.SignatureAlgorithm != 0 && template.SignatureAlgorithm.isRSAPSS() {
+		signerOpts = &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       hashFunc,
+		}
+	}
+
+	signature, err := priv.Sign(rand, digest, signerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	tbsCertList.Raw = tbsCertListContents
+	return asn1.Marshal(certificateList{
+		TBSCertList:        tbsCertList,
+		SignatureAlgorithm: signatureAlgorithm,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
+	})
+}
+
+// ParseRevocationList parses a X509 v2 Certificate Revocation List from the
+// given ASN.1 DER data.
+func ParseRevocationList(der []byte) (*RevocationList, error) {
+	var crl certificateList
+	if rest, err := asn1.Unmarshal(der, &crl); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after CRL")
+	}
+	tbs := &crl.TBSCertList
+	if tbs.Version > 1 {
+		return nil, fmt.Errorf("x509: unsupported CRL version %d", tbs.Version+1)
+	}
+
+	rl := &RevocationList{
+		Raw:                  crl.Raw,
+		RawTBSRevocationList: tbs.Raw,
+		RawIssuer:            tbs.Issuer.FullBytes,
+		Signature:            crl.SignatureValue.RightAlign(),
+		SignatureAlgorithm:   getSignatureAlgorithmFromAI(crl.SignatureAlgorithm),
+		ThisUpdate:           tbs.ThisUpdate,
+		NextUpdate:           tbs.NextUpdate,
+		Extensions:           tbs.Extensions,
+	}
+
+	var issuer pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(tbs.Issuer.FullBytes, &issuer); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509: trailing data after X.509 CRL issuer")
+	}
+	rl.Issuer.FillFromRDNSequence(&issuer)
+
+	for _, e := range tbs.Extensions {
+		switch {
+		case e.Id.Equal(oidExtensionAuthorityKeyId):
+			var a authKeyId
+			if rest, err := asn1.Unmarshal(e.Value, &a); err != nil {
+				return nil, err
+			} else if len(rest) != 0 {
+				return nil, errors.New("x509: trailing data after X.509 authority key-id")
+			}
+			rl.AuthorityKeyId = a.Id
+		case e.Id.Equal(oidExtensionCRLNumber):
+			number := new(big.Int)
+			if rest, err := asn1.Unmarshal(e.Value, &number); err != nil {
+				return nil, err
+			} else if len(rest) != 0 {
+				return nil, errors.New("x509: trailing data after X.509 CRL number")
+			}
+			rl.Number = number
+		}
+	}
+
+	for _, rc := range tbs.RevokedCertificates {
+		entry := RevocationListEntry{
+			SerialNumber:   rc.SerialNumber,
+			RevocationTime: rc.RevocationTime,
+			Extensions:     rc.Extensions,
+		}
+		for _, e := range rc.Extensions {
+			if !e.Id.Equal(oidExtensionReasonCode) {
+				continue
+			}
+			var reason asn1.Enumerated
+			if rest, err := asn1.Unmarshal(e.Value, &reason); err != nil {
+				return nil, err
+			} else if len(rest) != 0 {
+				return nil, errors.New("x509: trailing data after X.509 CRL reason code")
+			}
+			entry.ReasonCode = int(reason)
+		}
+		rl.RevokedCertificates = append(rl.RevokedCertificates, entry)
+	}
+
+	return rl, nil
+}
+
+// CheckSignatureFrom verifies that the signature on rl is a valid signature
+// from parent.
+func (rl *RevocationList) CheckSignatureFrom(parent *Certificate) error {
+	if parent.Version == 3 && !parent.BasicConstraintsValid ||
+		parent.BasicConstraintsValid && !parent.IsCA {
+		return ConstraintViolationError{}
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&KeyUsageCRLSign == 0 {
+		return ConstraintViolationError{}
+	}
+	if parent.PublicKeyAlgorithm == UnknownPublicKeyAlgorithm {
+		return ErrUnsupportedAlgorithm
+	}
+	return parent.CheckSignature(rl.SignatureAlgorithm, rl.RawTBSRevocationList, rl.Signature)
+}
+
 // CertificateRequest represents a PKCS #10, certificate signature request.
 type CertificateRequest struct {
 	Raw                      []byte // Complete ASN.1 DER content (CSR, signature algorithm and signature).
@@ -2410,7 +2815,7 @@ func parseCSRExtensions(rawAttributes []asn1.RawValue) ([]pkix.Extension, error)
 // The returned slice is the certificate request in DER encoding.
 //
 // All keys types that are implemented via crypto.Signer are supported (This
-// includes *rsa.PublicKey and *ecdsa.PublicKey.)
+// includes *rsa.PublicKey, *ecdsa.PublicKey and ed25519.PublicKey.)
 func CreateCertificateRequest(rand io.Reader, template *CertificateRequest, priv interface{}) (csr []byte, err error) {
 	key, ok := priv.(crypto.Signer)
 	if !ok {
@@ -2557,12 +2962,24 @@ func CreateCertificateRequest(rand io.Reader, template *CertificateRequest, priv
 	}
 	tbsCSR.Raw = tbsCSRContents
 
-	h := hashFunc.New()
-	h.Write(tbsCSRContents)
-	digest := h.Sum(nil)
+	digest := tbsCSRContents
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+
+	var signerOpts crypto.SignerOpts
+	signerOpts = hashFunc
+	if template.SignatureAlgorithm != 0 && template.SignatureAlgorithm.isRSAPSS() {
+		signerOpts = &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       hashFunc,
+		}
+	}
 
 	var signature []byte
-	signature, err = key.Sign(rand, digest, hashFunc)
+	signature, err = key.Sign(rand, digest, signerOpts)
 	if err != nil {
 		return
 	}
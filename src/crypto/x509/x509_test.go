@@ -7,7 +7,9 @@ package x509
 import (
 	"bytes"
 	"crypto/dsa"
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -77,6 +79,95 @@ func TestParsePKIXPublicKey(t *testing.T) {
 	}
 }
 
+func TestParsePKIXPublicKeyEd25519(t *testing.T) {
+	block, _ := pem.Decode([]byte(pemEd25519Key))
+	pub, err := ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse Ed25519 public key: %s", err)
+	}
+	ed25519Pub, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		t.Fatalf("Value returned from ParsePKIXPublicKey was not an Ed25519 public key")
+	}
+	want, _ := hex.DecodeString("19bf44096984cdfe8541bac167dc3b96c85086aa30b6b6cb0c5c38ad703166e1")
+	if !bytes.Equal(ed25519Pub, want) {
+		t.Errorf("Ed25519 public key = %x, want %x", ed25519Pub, want)
+	}
+
+	pubBytes2, err := MarshalPKIXPublicKey(ed25519Pub)
+	if err != nil {
+		t.Fatalf("Failed to marshal Ed25519 public key for the second time: %s", err)
+	}
+	if !bytes.Equal(pubBytes2, block.Bytes) {
+		t.Errorf("Reserialization of public key didn't match. got %x, want %x", pubBytes2, block.Bytes)
+	}
+}
+
+func TestParsePKIXPublicKeyX25519(t *testing.T) {
+	block, _ := pem.Decode([]byte(pemX25519Key))
+	pub, err := ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse X25519 public key: %s", err)
+	}
+	x25519Pub, ok := pub.(*ecdh.PublicKey)
+	if !ok || x25519Pub.Curve() != ecdh.X25519() {
+		t.Fatalf("Value returned from ParsePKIXPublicKey was not an X25519 public key")
+	}
+	privBytes, _ := hex.DecodeString(pkcs8X25519PrivateKeyHex)
+	priv, err := ParsePKCS8PrivateKey(privBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !x25519Pub.Equal(priv.(*ecdh.PrivateKey).Public()) {
+		t.Errorf("X25519 public key doesn't match the private key")
+	}
+
+	pubBytes2, err := MarshalPKIXPublicKey(x25519Pub)
+	if err != nil {
+		t.Fatalf("Failed to marshal X25519 public key for the second time: %s", err)
+	}
+	if !bytes.Equal(pubBytes2, block.Bytes) {
+		t.Errorf("Reserialization of public key didn't match. got %x, want %x", pubBytes2, block.Bytes)
+	}
+}
+
+func TestMarshalPKIXPublicKeyECDH(t *testing.T) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalPKIXPublicKey(priv.PublicKey())
+	if err != nil {
+		t.Fatalf("Failed to marshal ECDH public key: %s", err)
+	}
+	pub, err := ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatalf("Failed to parse ECDH public key: %s", err)
+	}
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		t.Fatalf("Value returned from ParsePKIXPublicKey was %T, want *ecdsa.PublicKey", pub)
+	}
+	if got := elliptic.Marshal(ecdsaPub.Curve, ecdsaPub.X, ecdsaPub.Y); !bytes.Equal(got, priv.PublicKey().Bytes()) {
+		t.Errorf("Parsed public key = %x, want %x", got, priv.PublicKey().Bytes())
+	}
+}
+
+// pemEd25519Key is the example from RFC 8410, Section 4.
+var pemEd25519Key = `
+-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAGb9ECWmEzf6FQbrBZ9w7lshQhqowtrbLDFw4rXAxZuE=
+-----END PUBLIC KEY-----
+`
+
+// pemX25519Key is the public key of pkcs8X25519PrivateKeyHex, generated
+// with "openssl pkey -pubout".
+var pemX25519Key = `
+-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VuAyEAvc5Xti1q1J6XMECfPbvct+z2dN7AxsV1rZekmXaNBk8=
+-----END PUBLIC KEY-----
+`
+
 var pemPublicKey = `-----BEGIN PUBLIC KEY-----
 MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA3VoPN9PKUjKFLMwOge6+
 wnDi8sbETGIx2FKXGgqtAKpzmem53kRGEQg8WeqRmp12wgp74TGpkEXsGae7RS1k
@@ -496,6 +587,16 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 		t.Fatalf("Failed to generate ECDSA key: %s", err)
 	}
 
+	ed25519Pub, ed25519Priv, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %s", err)
+	}
+
+	x25519Priv, err := ecdh.X25519().GenerateKey(random)
+	if err != nil {
+		t.Fatalf("Failed to generate X25519 key: %s", err)
+	}
+
 	tests := []struct {
 		name      string
 		pub, priv interface{}
@@ -509,6 +610,10 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 		{"RSAPSS/RSAPSS", &testPrivateKey.PublicKey, testPrivateKey, true, SHA256WithRSAPSS},
 		{"ECDSA/RSAPSS", &ecdsaPriv.PublicKey, testPrivateKey, false, SHA256WithRSAPSS},
 		{"RSAPSS/ECDSA", &testPrivateKey.PublicKey, ecdsaPriv, false, ECDSAWithSHA384},
+		{"Ed25519", ed25519Pub, ed25519Priv, true, PureEd25519},
+		{"Ed25519/RSA", ed25519Pub, testPrivateKey, false, SHA256WithRSA},
+		{"ECDSA/Ed25519", &ecdsaPriv.PublicKey, ed25519Priv, false, PureEd25519},
+		{"X25519/Ed25519", x25519Priv.PublicKey(), ed25519Priv, false, PureEd25519},
 	}
 
 	testExtKeyUsage := []ExtKeyUsage{ExtKeyUsageClientAuth, ExtKeyUsageServerAuth}
@@ -995,6 +1100,80 @@ func TestRSAPSSSelfSigned(t *testing.T) {
 	}
 }
 
+func TestRSAPSSChain(t *testing.T) {
+	rootKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %s", err)
+	}
+	rootTemplate := &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "PSS Root"},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		KeyUsage:              KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    SHA384WithRSAPSS,
+	}
+	rootDER, err := CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create root: %s", err)
+	}
+	root, err := ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatalf("failed to parse root: %s", err)
+	}
+	if root.SignatureAlgorithm != SHA384WithRSAPSS {
+		t.Errorf("root signature algorithm = %v, want %v", root.SignatureAlgorithm, SHA384WithRSAPSS)
+	}
+
+	leafTemplate := &Certificate{
+		SerialNumber:       big.NewInt(2),
+		Subject:            pkix.Name{CommonName: "leaf.example.com"},
+		DNSNames:           []string{"leaf.example.com"},
+		NotBefore:          time.Unix(1000, 0),
+		NotAfter:           time.Unix(100000, 0),
+		ExtKeyUsage:        []ExtKeyUsage{ExtKeyUsageServerAuth},
+		SignatureAlgorithm: SHA256WithRSAPSS,
+	}
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate, root, &testPrivateKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create leaf: %s", err)
+	}
+	leaf, err := ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("failed to parse leaf: %s", err)
+	}
+	if leaf.SignatureAlgorithm != SHA256WithRSAPSS {
+		t.Errorf("leaf signature algorithm = %v, want %v", leaf.SignatureAlgorithm, SHA256WithRSAPSS)
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	opts := VerifyOptions{
+		DNSName:     "leaf.example.com",
+		Roots:       roots,
+		CurrentTime: time.Unix(2000, 0),
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		t.Errorf("failed to verify PSS-signed chain: %s", err)
+	}
+
+	// A PKCS #1 v1.5 signature doesn't verify as PSS.
+	leafTemplate.SignatureAlgorithm = SHA256WithRSA
+	pkcs1DER, err := CreateCertificate(rand.Reader, leafTemplate, root, &testPrivateKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create leaf: %s", err)
+	}
+	pkcs1Leaf, err := ParseCertificate(pkcs1DER)
+	if err != nil {
+		t.Fatalf("failed to parse leaf: %s", err)
+	}
+	if err := root.CheckSignature(SHA256WithRSAPSS, pkcs1Leaf.RawTBSCertificate, pkcs1Leaf.Signature); err == nil {
+		t.Error("PKCS #1 v1.5 signature verified as PSS")
+	}
+}
+
 const pemCertificate = `-----BEGIN CERTIFICATE-----
 MIIDATCCAemgAwIBAgIRAKQkkrFx1T/dgB/Go/xBM5swDQYJKoZIhvcNAQELBQAw
 EjEQMA4GA1UEChMHQWNtZSBDbzAeFw0xNjA4MTcyMDM2MDdaFw0xNzA4MTcyMDM2
@@ -1064,6 +1243,170 @@ func TestCRLCreation(t *testing.T) {
 	}
 }
 
+func TestCreateRevocationList(t *testing.T) {
+	issuerTemplate := &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CRL Issuer", Organization: []string{"Σ Acme Co"}},
+		SubjectKeyId:          []byte{1, 2, 3, 4},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	issuerDER, err := CreateCertificate(rand.Reader, issuerTemplate, issuerTemplate, &testPrivateKey.PublicKey, testPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := ParseCertificate(issuerDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc := time.FixedZone("Oz/Atlantis", int((2 * time.Hour).Seconds()))
+	extraExtension := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{5, 0}}
+	template := &RevocationList{
+		Number:     big.NewInt(7),
+		ThisUpdate: time.Unix(2000, 0).In(loc),
+		NextUpdate: time.Unix(3000, 0),
+		RevokedCertificates: []RevocationListEntry{
+			{SerialNumber: big.NewInt(2), RevocationTime: time.Unix(1500, 0).In(loc)},
+			{SerialNumber: big.NewInt(3), RevocationTime: time.Unix(1600, 0), ReasonCode: 1 /* keyCompromise */},
+			{
+				SerialNumber:    big.NewInt(4),
+				RevocationTime:  time.Unix(1700, 0),
+				ExtraExtensions: []pkix.Extension{extraExtension},
+			},
+		},
+		ExtraExtensions: []pkix.Extension{extraExtension},
+	}
+
+	der, err := CreateRevocationList(rand.Reader, template, issuer, testPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %s", err)
+	}
+	crl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatalf("failed to parse CRL: %s", err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		t.Errorf("signature check failed: %s", err)
+	}
+	if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+		t.Errorf("RawIssuer = %x, want %x", crl.RawIssuer, issuer.RawSubject)
+	}
+	if crl.Issuer.CommonName != "CRL Issuer" {
+		t.Errorf("Issuer.CommonName = %q, want %q", crl.Issuer.CommonName, "CRL Issuer")
+	}
+	if !bytes.Equal(crl.AuthorityKeyId, issuer.SubjectKeyId) {
+		t.Errorf("AuthorityKeyId = %x, want %x", crl.AuthorityKeyId, issuer.SubjectKeyId)
+	}
+	if crl.Number == nil || crl.Number.Cmp(template.Number) != 0 {
+		t.Errorf("Number = %v, want %v", crl.Number, template.Number)
+	}
+	if !crl.ThisUpdate.Equal(template.ThisUpdate) || !crl.NextUpdate.Equal(template.NextUpdate) {
+		t.Errorf("got update times %v, %v; want %v, %v", crl.ThisUpdate, crl.NextUpdate, template.ThisUpdate, template.NextUpdate)
+	}
+	if crl.SignatureAlgorithm != SHA256WithRSA {
+		t.Errorf("SignatureAlgorithm = %v, want %v", crl.SignatureAlgorithm, SHA256WithRSA)
+	}
+	if !oidInExtensions(extraExtension.Id, crl.Extensions) {
+		t.Error("CRL extra extension is missing")
+	}
+	if len(crl.RevokedCertificates) != len(template.RevokedCertificates) {
+		t.Fatalf("got %d revoked certificates, want %d", len(crl.RevokedCertificates), len(template.RevokedCertificates))
+	}
+	for i, got := range crl.RevokedCertificates {
+		want := template.RevokedCertificates[i]
+		if got.SerialNumber.Cmp(want.SerialNumber) != 0 {
+			t.Errorf("#%d: SerialNumber = %v, want %v", i, got.SerialNumber, want.SerialNumber)
+		}
+		if !got.RevocationTime.Equal(want.RevocationTime) || got.RevocationTime.Location() != time.UTC {
+			t.Errorf("#%d: RevocationTime = %v, want %v in UTC", i, got.RevocationTime, want.RevocationTime)
+		}
+		if got.ReasonCode != want.ReasonCode {
+			t.Errorf("#%d: ReasonCode = %d, want %d", i, got.ReasonCode, want.ReasonCode)
+		}
+	}
+	if !oidInExtensions(extraExtension.Id, crl.RevokedCertificates[2].Extensions) {
+		t.Error("CRL entry extra extension is missing")
+	}
+
+	// The CRL can also be parsed and checked with the older API.
+	certList, err := ParseDERCRL(der)
+	if err != nil {
+		t.Fatalf("ParseDERCRL failed: %s", err)
+	}
+	if err := issuer.CheckCRLSignature(certList); err != nil {
+		t.Errorf("CheckCRLSignature failed: %s", err)
+	}
+
+	// A CRL from a different key doesn't verify.
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherDER, err := CreateRevocationList(rand.Reader, template, issuer, otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCRL, err := ParseRevocationList(otherDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := otherCRL.CheckSignatureFrom(issuer); err == nil {
+		t.Error("CRL signed by a different key verified")
+	}
+}
+
+func TestCreateRevocationListInvalid(t *testing.T) {
+	issuer := &Certificate{
+		KeyUsage:     KeyUsageCRLSign,
+		SubjectKeyId: []byte{1, 2, 3},
+		RawSubject:   []byte{0x30, 0},
+	}
+	valid := RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Unix(1000, 0),
+		NextUpdate: time.Unix(2000, 0),
+	}
+	if _, err := CreateRevocationList(rand.Reader, &valid, issuer, testPrivateKey); err != nil {
+		t.Fatalf("failed to create valid CRL: %s", err)
+	}
+
+	noCRLSign := *issuer
+	noCRLSign.KeyUsage = KeyUsageCertSign
+	noSKID := *issuer
+	noSKID.SubjectKeyId = nil
+	tests := []struct {
+		name     string
+		modify   func(*RevocationList)
+		issuer   *Certificate
+		expected string
+	}{
+		{"no CRLSign", nil, &noCRLSign, "crlSign"},
+		{"no SubjectKeyId", nil, &noSKID, "subject key identifier"},
+		{"nil Number", func(rl *RevocationList) { rl.Number = nil }, issuer, "nil Number"},
+		{"negative Number", func(rl *RevocationList) { rl.Number = big.NewInt(-1) }, issuer, "CRL number"},
+		{"long Number", func(rl *RevocationList) { rl.Number = new(big.Int).Lsh(big.NewInt(1), 160) }, issuer, "CRL number"},
+		{"no NextUpdate", func(rl *RevocationList) { rl.NextUpdate = time.Time{} }, issuer, "NextUpdate"},
+		{"NextUpdate before ThisUpdate", func(rl *RevocationList) { rl.NextUpdate = time.Unix(500, 0) }, issuer, "after"},
+		{"nil entry SerialNumber", func(rl *RevocationList) {
+			rl.RevokedCertificates = []RevocationListEntry{{RevocationTime: time.Unix(1000, 0)}}
+		}, issuer, "nil SerialNumber"},
+	}
+	for _, test := range tests {
+		template := valid
+		if test.modify != nil {
+			test.modify(&template)
+		}
+		_, err := CreateRevocationList(rand.Reader, &template, test.issuer, testPrivateKey)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: got error %v, want one containing %q", test.name, err, test.expected)
+		}
+	}
+}
+
 func fromBase64(in string) []byte {
 	out := make([]byte, base64.StdEncoding.DecodedLen(len(in)))
 	n, err := base64.StdEncoding.Decode(out, []byte(in))
@@ -1154,15 +1497,22 @@ func TestCreateCertificateRequest(t *testing.T) {
 		t.Fatalf("Failed to generate ECDSA key: %s", err)
 	}
 
+	_, ed25519Priv, err := ed25519.GenerateKey(random)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %s", err)
+	}
+
 	tests := []struct {
 		name    string
 		priv    interface{}
 		sigAlgo SignatureAlgorithm
 	}{
 		{"RSA", testPrivateKey, SHA1WithRSA},
+		{"RSAPSS", testPrivateKey, SHA256WithRSAPSS},
 		{"ECDSA-256", ecdsa256Priv, ECDSAWithSHA1},
 		{"ECDSA-384", ecdsa384Priv, ECDSAWithSHA1},
 		{"ECDSA-521", ecdsa521Priv, ECDSAWithSHA1},
+		{"Ed25519", ed25519Priv, PureEd25519},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,127 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package embed_test
+
+import (
+	"embed"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+//go:embed testdata/h*.txt
+//go:embed testdata/ken.txt testdata/sub
+var global embed.FS
+
+//go:embed testdata/hello.txt
+var helloString string
+
+//go:embed testdata/hello.txt
+var helloBytes []byte
+
+//go:embed testdata
+var testdata embed.FS
+
+//go:embed testdata/*
+var star embed.FS
+
+var empty embed.FS
+
+func testFiles(t *testing.T, f embed.FS, name, data string) {
+	t.Helper()
+	d, err := f.ReadFile(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(d) != data {
+		t.Errorf("read %v = %q, want %q", name, d, data)
+	}
+}
+
+func testDir(t *testing.T, f embed.FS, name string, expect ...string) {
+	t.Helper()
+	list, err := f.ReadDir(name)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var names []string
+	for _, fi := range list {
+		name := fi.Name()
+		if fi.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("readdir %v = %v, want %v", name, names, expect)
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	testFiles(t, global, "testdata/hello.txt", "hello, world\n")
+	testFiles(t, global, "testdata/ken.txt", "If a program is too slow, it must have a loop.\n")
+	testFiles(t, global, "testdata/sub/dir/b.txt", "b\n")
+
+	testDir(t, global, ".", "testdata/")
+	testDir(t, global, "testdata", "hello.txt", "ken.txt", "sub/")
+	testDir(t, global, "testdata/sub", "a.txt", "dir/")
+	testDir(t, global, "testdata/sub/dir", "b.txt")
+
+	if err := fstest.TestFS(global, "testdata/hello.txt", "testdata/ken.txt", "testdata/sub/a.txt", "testdata/sub/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if helloString != "hello, world\n" {
+		t.Errorf("helloString = %q, want %q", helloString, "hello, world\n")
+	}
+	if string(helloBytes) != "hello, world\n" {
+		t.Errorf("helloBytes = %q, want %q", helloBytes, "hello, world\n")
+	}
+}
+
+func TestWritableBytes(t *testing.T) {
+	// The []byte must be writable, and independent of the string.
+	b := helloBytes[0]
+	helloBytes[0] = 'H'
+	defer func() { helloBytes[0] = b }()
+	if helloString[0] != 'h' {
+		t.Errorf("writing helloBytes modified helloString")
+	}
+}
+
+func TestHidden(t *testing.T) {
+	// A directory pattern skips hidden files; a glob does not.
+	testDir(t, testdata, "testdata", "hello.txt", "ken.txt", "sub/")
+	testDir(t, star, "testdata", ".hidden/", "hello.txt", "ken.txt", "sub/")
+	testFiles(t, star, "testdata/.hidden/fortune.txt", "hidden\n")
+
+	if err := fstest.TestFS(star, "testdata/.hidden/fortune.txt", "testdata/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, name := range []string{"missing.txt", "testdata/missing.txt", "/testdata/hello.txt", "testdata/../testdata/hello.txt", "testdata/"} {
+		if _, err := global.ReadFile(name); !os.IsNotExist(err) {
+			t.Errorf("ReadFile(%q): err = %v, want not exist", name, err)
+		}
+	}
+	if _, err := global.ReadFile("testdata"); err == nil {
+		t.Errorf("ReadFile of directory succeeded")
+	}
+	if _, err := global.ReadDir("testdata/hello.txt"); err == nil {
+		t.Errorf("ReadDir of file succeeded")
+	}
+	if _, err := empty.ReadFile("testdata/hello.txt"); !os.IsNotExist(err) {
+		t.Errorf("ReadFile on empty FS: err = %v, want not exist", err)
+	}
+	testDir(t, empty, ".")
+	if _, err := global.Open("testdata/"); !os.IsNotExist(err) {
+		t.Errorf("Open(%q): err = %v, want not exist", "testdata/", err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysis defines the interface between a modular static
+// analysis and an analysis driver program.
+//
+// Background
+//
+// A static analysis is a function that inspects a package of Go code and
+// reports a set of diagnostics (typically mistakes in the code), and
+// perhaps produces other results as well, such as suggested refactorings
+// or other facts. An analysis that reports mistakes is informally called a
+// "checker". For example, the printf checker reports mistakes in
+// fmt.Printf format strings.
+//
+// A "modular" analysis is one that inspects one package at a time but can
+// save information from a lower-level package and use it when inspecting a
+// higher-level package, analogous to separate compilation in a toolchain.
+// The printf checker is modular: when it discovers that a function such as
+// log.Fatalf delegates to fmt.Printf, it records this fact, and checks
+// calls to that function too, including calls made from another package.
+//
+// By implementing a common interface, checkers from a variety of sources
+// can be easily selected, incorporated, and reused in a wide range of
+// driver programs including command-line tools (such as vet), text
+// editors and IDEs, build and code review systems, and batch pipelines
+// for large code bases.
+//
+// Analyzer
+//
+// The primary type in the API is Analyzer. An Analyzer statically
+// describes an analysis function: its name, documentation, flags,
+// relationship to other analyzers, and of course, its logic.
+//
+// To define an analysis, a user declares a (logically constant) variable
+// of type Analyzer. Here is a typical example from one of the analyzers
+// in the go vet suite:
+//
+//	var Analyzer = &analysis.Analyzer{
+//		Name:     "bool",
+//		Doc:      "check for common mistakes involving boolean operators",
+//		Run:      run,
+//		Requires: []*analysis.Analyzer{inspect.Analyzer},
+//	}
+//
+// An analysis driver is a program such as vet that runs a set of analyses
+// and prints the diagnostics that they report. The driver program must
+// import the list of Analyzers it needs. Typically each Analyzer resides
+// in a separate package. To add a new Analyzer to an existing driver, add
+// another item to the list.
+//
+// The Requires field of an Analyzer lists the analyzers whose results it
+// needs. The driver runs each required analyzer, on the same package,
+// before the analyzer that requires it, and makes its result available in
+// Pass.ResultOf. The graph of requirements must be acyclic.
+//
+// Pass
+//
+// A Pass describes a single unit of work: the application of a particular
+// Analyzer to a particular package of Go code. The Pass provides
+// information to the Analyzer's Run function about the package being
+// analyzed, and provides operations to the Run function for reporting
+// diagnostics and other information back to the driver.
+//
+// The Fset, Files, Pkg, and TypesInfo fields provide the syntax trees,
+// type information, and source positions for a single package of Go code.
+//
+// The OtherFiles field provides the names, but not the contents, of
+// non-Go files such as assembly that are part of this package. Analyzers
+// that need to inspect them must read them from the file system.
+//
+// The Report function emits a diagnostic, a message associated with a
+// source position. For most analyses, diagnostics are their primary
+// result. For convenience, Pass provides a helper method, Reportf, to
+// report a new diagnostic by formatting a string. A Diagnostic may carry
+// SuggestedFixes, edits that a driver may offer to apply to resolve the
+// problem.
+//
+// Modular analysis with Facts
+//
+// To improve efficiency and scalability, large programs are routinely
+// built using separate compilation: units of the program are compiled
+// separately, and recompiled only when one of their dependencies changes;
+// independent modules may be compiled in parallel. The same technique may
+// be applied to static analyses, for the same benefits. Such analyses are
+// described as "modular".
+//
+// A Fact is a serializable piece of information that an Analyzer derives
+// about a named package-level object or about a package, and that is
+// made available to the same Analyzer when it later analyzes packages
+// that import the first one, directly or indirectly.
+//
+// An Analyzer that uses facts must declare their types in FactTypes:
+//
+//	var Analyzer = &analysis.Analyzer{
+//		Name:      "printf",
+//		FactTypes: []analysis.Fact{new(isWrapper)},
+//		...
+//	}
+//
+//	type isWrapper struct{} // => *types.Func f “is a printf wrapper”
+//
+// Each fact type must be a pointer type that is encodable by encoding/gob
+// and must have an AFact method. The driver serializes the facts exported
+// by the analysis of one package and makes them available, through the
+// Pass's ImportObjectFact and ImportPackageFact functions, to the analysis
+// of each package that depends on it. Facts may be associated only with
+// objects that are accessible from outside their package: package-level
+// objects, and the fields and methods of package-level types.
+//
+// Drivers
+//
+// The go/analysis/unitchecker package provides a driver that analyzes a
+// single compilation unit described by a configuration file written by
+// the go command, as in 'go vet -vettool=prog'. The
+// go/analysis/multichecker package provides a driver for a standalone
+// program that may additionally be applied directly to directories and
+// files of Go source.
+//
+package analysis
@@ -0,0 +1,315 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package checker defines the implementation of the analysis drivers:
+// it applies a graph of analyzers to a single type-checked package and
+// prints the resulting diagnostics.
+package checker
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/analysis"
+	"go/analysis/internal/facts"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// A Package holds the syntax and type information of a package
+// to be analyzed.
+type Package struct {
+	Fset       *token.FileSet
+	Files      []*ast.File
+	OtherFiles []string // names of non-Go files of the package
+	Types      *types.Package
+	TypesInfo  *types.Info
+	TypesSizes types.Sizes
+	IllTyped   bool // the package has parse or type errors
+}
+
+// A Result holds the outcome of applying one of the requested
+// analyzers to a package.
+type Result struct {
+	Analyzer    *analysis.Analyzer
+	Diagnostics []analysis.Diagnostic
+	Err         error
+}
+
+// An action represents one unit of analysis work: the application of
+// one analyzer to the package. Actions form a DAG that mirrors the
+// Requires graph of the analyzers.
+type action struct {
+	a       *analysis.Analyzer
+	deps    []*action
+	done    bool
+	skipped bool // not run because of errors in the package or a prerequisite
+	result  interface{}
+	diags   []analysis.Diagnostic
+	err     error
+}
+
+// Run applies the analyzers, and the analyzers they require, to pkg,
+// and returns the results of the requested analyzers in order.
+//
+// Facts are imported from and exported to set, which must have been
+// decoded for pkg.Types. If factsOnly is set, only the analyzers that
+// use facts are run and their diagnostics are discarded; the purpose of
+// such a run is only to compute the facts for the analysis of the
+// packages that import pkg.
+//
+// Analyzers that do not set RunDespiteErrors are skipped, along with
+// the analyzers that require them, if pkg is ill-typed.
+func Run(pkg *Package, analyzers []*analysis.Analyzer, set *facts.Set, factsOnly bool) []Result {
+	if factsOnly {
+		var keep []*analysis.Analyzer
+		for _, a := range analyzers {
+			if len(a.FactTypes) > 0 {
+				keep = append(keep, a)
+			}
+		}
+		analyzers = keep
+	}
+
+	actions := make(map[*analysis.Analyzer]*action)
+	var mkAction func(a *analysis.Analyzer) *action
+	mkAction = func(a *analysis.Analyzer) *action {
+		act, ok := actions[a]
+		if !ok {
+			act = &action{a: a}
+			for _, req := range a.Requires {
+				act.deps = append(act.deps, mkAction(req))
+			}
+			actions[a] = act
+		}
+		return act
+	}
+
+	var results []Result
+	for _, a := range analyzers {
+		act := mkAction(a)
+		execAll(pkg, act, set)
+		if act.skipped {
+			continue
+		}
+		if factsOnly {
+			act.diags = nil
+		}
+		results = append(results, Result{a, act.diags, act.err})
+	}
+	return results
+}
+
+// execAll executes act after its prerequisites.
+func execAll(pkg *Package, act *action, set *facts.Set) {
+	if act.done {
+		return
+	}
+	act.done = true
+
+	// Plumb the output values of the dependencies
+	// into the inputs of this action.
+	inputs := make(map[*analysis.Analyzer]interface{})
+	for _, dep := range act.deps {
+		execAll(pkg, dep, set)
+		if dep.skipped {
+			act.skipped = true
+			return
+		}
+		if dep.err != nil {
+			act.err = fmt.Errorf("failed prerequisite: %v", dep.a)
+			return
+		}
+		inputs[dep.a] = dep.result
+	}
+
+	if pkg.IllTyped && !act.a.RunDespiteErrors {
+		act.skipped = true
+		return
+	}
+
+	// Each analyzer may use only the facts it declares.
+	factTypes := make(map[reflect.Type]bool)
+	for _, f := range act.a.FactTypes {
+		factTypes[reflect.TypeOf(f)] = true
+	}
+	checkFact := func(fact analysis.Fact) {
+		if !factTypes[reflect.TypeOf(fact)] {
+			panic(fmt.Sprintf("%s: fact type %T not declared by analyzer", act.a, fact))
+		}
+	}
+
+	pass := &analysis.Pass{
+		Analyzer:   act.a,
+		Fset:       pkg.Fset,
+		Files:      pkg.Files,
+		OtherFiles: pkg.OtherFiles,
+		Pkg:        pkg.Types,
+		TypesInfo:  pkg.TypesInfo,
+		TypesSizes: pkg.TypesSizes,
+		ResultOf:   inputs,
+		Report:     func(d analysis.Diagnostic) { act.diags = append(act.diags, d) },
+		ImportObjectFact: func(obj types.Object, fact analysis.Fact) bool {
+			checkFact(fact)
+			return set.ImportObjectFact(obj, fact)
+		},
+		ImportPackageFact: func(pkg *types.Package, fact analysis.Fact) bool {
+			checkFact(fact)
+			return set.ImportPackageFact(pkg, fact)
+		},
+		ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+			checkFact(fact)
+			set.ExportObjectFact(obj, fact)
+		},
+		ExportPackageFact: func(fact analysis.Fact) {
+			checkFact(fact)
+			set.ExportPackageFact(fact)
+		},
+	}
+
+	act.result, act.err = act.a.Run(pass)
+	if act.err == nil && act.a.ResultType != nil {
+		if got, want := reflect.TypeOf(act.result), act.a.ResultType; got != want {
+			act.err = fmt.Errorf("internal error: on package %s, analyzer %s returned a result of type %v, but declared ResultType %v",
+				pkg.Types.Path(), act.a, got, want)
+		}
+	}
+}
+
+// PrintPlain prints the diagnostics of the results to standard error
+// in the form "file:line: message", and any errors in the form
+// "progname: package: analyzer: error". It reports whether it
+// printed anything.
+func PrintPlain(progname string, pkg *Package, results []Result) bool {
+	type diag struct {
+		posn token.Position
+		msg  string
+	}
+	var diags []diag
+	printed := false
+	for _, res := range results {
+		if res.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s: %s: %v\n", progname, pkg.Types.Path(), res.Analyzer, res.Err)
+			printed = true
+			continue
+		}
+		for _, d := range res.Diagnostics {
+			diags = append(diags, diag{pkg.Fset.Position(d.Pos), d.Message})
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		x, y := diags[i].posn, diags[j].posn
+		if x.Filename != y.Filename {
+			return x.Filename < y.Filename
+		}
+		return x.Offset < y.Offset
+	})
+	for _, d := range diags {
+		// Do not print columns. Because the pos often points to the start of an
+		// expression instead of the inner part with the actual error, the
+		// precision can mislead.
+		if d.posn.IsValid() {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", d.posn.Filename, d.posn.Line, d.msg)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\n", d.msg)
+		}
+		printed = true
+	}
+	return printed
+}
+
+// A JSONTree is a mapping from package ID to analysis name to result.
+// Each result is either a jsonError or a list of jsonDiagnostic.
+type JSONTree map[string]map[string]interface{}
+
+// A jsonTextEdit describes the replacement of a portion of a file.
+// Start and End are zero-based half-open indices into the original byte
+// sequence of the file, and New is the new text.
+type jsonTextEdit struct {
+	Filename string `json:"filename"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	New      string `json:"new"`
+}
+
+// A jsonSuggestedFix describes an edit that should be applied as a whole or not
+// at all. It might contain multiple TextEdits/text_edits if the SuggestedFix
+// consists of multiple non-contiguous edits.
+type jsonSuggestedFix struct {
+	Message string         `json:"message"`
+	Edits   []jsonTextEdit `json:"edits"`
+}
+
+// A jsonDiagnostic can be used to encode and decode analysis.Diagnostics to and
+// from JSON.
+type jsonDiagnostic struct {
+	Category       string             `json:"category,omitempty"`
+	Posn           string             `json:"posn"`
+	Message        string             `json:"message"`
+	SuggestedFixes []jsonSuggestedFix `json:"suggested_fixes,omitempty"`
+}
+
+type jsonError struct {
+	Err string `json:"error"`
+}
+
+// Add adds the result of analysis of the package with the given ID
+// to the tree.
+func (tree JSONTree) Add(fset *token.FileSet, id string, res Result) {
+	var v interface{}
+	if res.Err != nil {
+		v = jsonError{res.Err.Error()}
+	} else if len(res.Diagnostics) > 0 {
+		var diagnostics []jsonDiagnostic
+		for _, f := range res.Diagnostics {
+			var fixes []jsonSuggestedFix
+			for _, fix := range f.SuggestedFixes {
+				var edits []jsonTextEdit
+				for _, edit := range fix.TextEdits {
+					end := edit.End
+					if !end.IsValid() {
+						end = edit.Pos
+					}
+					edits = append(edits, jsonTextEdit{
+						Filename: fset.Position(edit.Pos).Filename,
+						Start:    fset.Position(edit.Pos).Offset,
+						End:      fset.Position(end).Offset,
+						New:      string(edit.NewText),
+					})
+				}
+				fixes = append(fixes, jsonSuggestedFix{
+					Message: fix.Message,
+					Edits:   edits,
+				})
+			}
+			diagnostics = append(diagnostics, jsonDiagnostic{
+				Category:       f.Category,
+				Posn:           fset.Position(f.Pos).String(),
+				Message:        f.Message,
+				SuggestedFixes: fixes,
+			})
+		}
+		v = diagnostics
+	}
+	if v != nil {
+		m, ok := tree[id]
+		if !ok {
+			m = make(map[string]interface{})
+			tree[id] = m
+		}
+		m[res.Analyzer.Name] = v
+	}
+}
+
+// Print writes the tree to standard output as indented JSON.
+func (tree JSONTree) Print() {
+	data, err := json.MarshalIndent(tree, "", "\t")
+	if err != nil {
+		panic(fmt.Sprintf("internal error: JSON marshaling failed: %v", err))
+	}
+	fmt.Printf("%s\n", data)
+}
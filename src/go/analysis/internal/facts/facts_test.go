@@ -0,0 +1,141 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package facts_test
+
+import (
+	"encoding/gob"
+	"fmt"
+	"go/analysis/internal/facts"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+type myFact struct {
+	S string
+}
+
+func (f *myFact) String() string { return fmt.Sprintf("myFact(%s)", f.S) }
+func (f *myFact) AFact()         {}
+
+func init() {
+	gob.Register(new(myFact))
+}
+
+var files = map[string]string{
+	"a": `package a
+		type T int
+		func (T) M() {}
+		type S struct{ F int }
+		type I interface{ N() }
+		var V int
+		func f() {}`,
+	"b": `package b
+		import "a"
+		var B = a.V`,
+	"c": `package c
+		import "b"
+		var C = b.B`,
+}
+
+// load type-checks the named packages from source. Each call creates
+// new objects, as if the packages had been loaded by a separate
+// process from export data.
+func load(t *testing.T, paths ...string) map[string]*types.Package {
+	pkgs := make(map[string]*types.Package)
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if p := pkgs[path]; p != nil {
+				return p, nil
+			}
+			return nil, fmt.Errorf("can't find %q", path)
+		}),
+	}
+	for _, path := range paths {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path+".go", files[path], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg, err := conf.Check(path, fset, []*ast.File{f}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs[path] = pkg
+	}
+	return pkgs
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+func TestEncodeDecode(t *testing.T) {
+	encoded := make(map[string][]byte)
+	read := func(path string) ([]byte, error) { return encoded[path], nil }
+
+	// Analyze a, exporting facts about its objects.
+	a := load(t, "a")["a"]
+	set, err := facts.Decode(a, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	T := a.Scope().Lookup("T").Type().(*types.Named)
+	S := a.Scope().Lookup("S").Type().Underlying().(*types.Struct)
+	I := a.Scope().Lookup("I").Type().Underlying().(*types.Interface)
+	set.ExportObjectFact(a.Scope().Lookup("V"), &myFact{"V"})
+	set.ExportObjectFact(T.Method(0), &myFact{"T.M"})
+	set.ExportObjectFact(S.Field(0), &myFact{"S.F"})
+	set.ExportObjectFact(I.Method(0), &myFact{"I.N"})
+	set.ExportObjectFact(a.Scope().Lookup("f"), &myFact{"f"})
+	set.ExportPackageFact(&myFact{"package a"})
+	encoded["a"] = set.Encode()
+
+	// Analyze b, which imports a, and re-export everything.
+	b := load(t, "a", "b")["b"]
+	set, err = facts.Decode(b, read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded["b"] = set.Encode()
+
+	// Analyze c, which imports a only indirectly. Facts about a
+	// are still available because they were re-exported by b.
+	pkgs := load(t, "a", "b", "c")
+	set, err = facts.Decode(pkgs["c"], read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = pkgs["a"]
+	T = a.Scope().Lookup("T").Type().(*types.Named)
+	S = a.Scope().Lookup("S").Type().Underlying().(*types.Struct)
+	I = a.Scope().Lookup("I").Type().Underlying().(*types.Interface)
+	for _, test := range []struct {
+		obj  types.Object
+		want string
+	}{
+		{a.Scope().Lookup("V"), "V"},
+		{T.Method(0), "T.M"},
+		{S.Field(0), "S.F"},
+		{I.Method(0), "I.N"},
+		{a.Scope().Lookup("T"), ""},
+		{a.Scope().Lookup("f"), "f"},
+	} {
+		var fact myFact
+		ok := set.ImportObjectFact(test.obj, &fact)
+		if ok != (test.want != "") || fact.S != test.want {
+			t.Errorf("ImportObjectFact(%v) = %v, %t; want %q", test.obj, fact, ok, test.want)
+		}
+	}
+	var fact myFact
+	if !set.ImportPackageFact(a, &fact) || fact.S != "package a" {
+		t.Errorf("ImportPackageFact(a) = %v; want package a", fact)
+	}
+	if set.ImportPackageFact(pkgs["b"], &fact) {
+		t.Errorf("ImportPackageFact(b) unexpectedly succeeded")
+	}
+}
@@ -0,0 +1,339 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package multichecker defines the main function for an analysis driver
+// with several analyzers. This package makes it easy for anyone to build
+// an analysis tool containing just the analyzers they need.
+//
+// The resulting program may be run directly on directories or files:
+//
+//	$ prog [flags] directory...
+//	$ prog [flags] files...   # must be a single package
+//
+// Each directory is walked recursively, and the package found in each
+// directory, along with its external test package, if any, is analyzed.
+// Imported packages are type-checked from their installed export data,
+// or from source with the -source flag. When run this way, each package
+// is analyzed independently: facts are not propagated from one package
+// to another.
+//
+// The program may also be run by a build system, as in
+// 'go vet -vettool=prog', in which case it analyzes the single
+// compilation unit described by a configuration file, as described by
+// package go/analysis/unitchecker.
+package multichecker
+
+import (
+	"flag"
+	"fmt"
+	"go/analysis"
+	"go/analysis/internal/analysisflags"
+	"go/analysis/internal/checker"
+	"go/analysis/internal/facts"
+	"go/analysis/unitchecker"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	verbose = flag.Bool("v", false, "verbose")
+	source  = flag.Bool("source", false, "import from source instead of compiled object files")
+	tags    = flag.String("tags", "", "space-separated list of build tags to apply when parsing")
+)
+
+// Main is the main function of an analysis tool with several
+// analyzers. It parses the command line, runs the analyzers that are
+// enabled, prints their diagnostics, and exits.
+func Main(analyzers ...*analysis.Analyzer) {
+	progname := filepath.Base(os.Args[0])
+	log.SetFlags(0)
+	log.SetPrefix(progname + ": ")
+
+	if err := analysis.Validate(analyzers); err != nil {
+		log.Fatal(err)
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", progname)
+		fmt.Fprintf(os.Stderr, "\t%s [flags] directory...\n", progname)
+		fmt.Fprintf(os.Stderr, "\t%s [flags] files... # Must be a single package\n", progname)
+		fmt.Fprintf(os.Stderr, "\t%s help [name]      # Describe the analyzers and their flags\n", progname)
+		fmt.Fprintf(os.Stderr, "By default, all analyzers are run.\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	analyzers = analysisflags.Parse(analyzers, true)
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+	}
+	if args[0] == "help" {
+		analysisflags.Help(progname, analyzers, args[1:])
+		os.Exit(0)
+	}
+
+	// Special case for "go vet" passing an explicit configuration:
+	// single argument ending in vet.cfg.
+	if len(args) == 1 && strings.HasSuffix(args[0], ".cfg") {
+		unitchecker.Run(args[0], analyzers)
+		panic("unreachable")
+	}
+
+	d := &driver{
+		progname:  progname,
+		analyzers: analyzers,
+		json:      make(checker.JSONTree),
+	}
+	if *source {
+		d.importer = importer.For("source", nil)
+	} else {
+		d.importer = importer.Default()
+	}
+	// Accept space-separated tags because that matches
+	// the go command's other subcommands.
+	// Accept commas because go tool vet traditionally has.
+	d.tags = strings.Fields(strings.Replace(*tags, ",", " ", -1))
+
+	var dirsRun, filesRun bool
+	for _, name := range args {
+		fi, err := os.Stat(name)
+		if err != nil {
+			d.warnf("error walking tree: %s", err)
+			continue
+		}
+		if fi.IsDir() {
+			dirsRun = true
+		} else {
+			filesRun = true
+		}
+	}
+	if dirsRun && filesRun {
+		flag.Usage()
+	}
+	if dirsRun {
+		for _, name := range args {
+			filepath.Walk(name, d.visit)
+		}
+	} else if !d.doFiles(args) {
+		d.warnf("no files checked")
+	}
+
+	if analysisflags.JSON {
+		d.json.Print()
+	}
+	os.Exit(d.exitCode)
+}
+
+// A driver holds the state of a standalone run.
+type driver struct {
+	progname  string
+	analyzers []*analysis.Analyzer
+	importer  types.Importer // shared so that all packages see the same imports
+	tags      []string
+	json      checker.JSONTree
+	exitCode  int
+}
+
+// warnf reports a problem that is not a diagnostic.
+func (d *driver) warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, d.progname+": "+format+"\n", args...)
+	d.exitCode = 1
+}
+
+func (d *driver) visit(path string, f os.FileInfo, err error) error {
+	if err != nil {
+		d.warnf("walk error: %s", err)
+		return err
+	}
+	// One package per directory. Ignore the files themselves.
+	if !f.IsDir() {
+		return nil
+	}
+	d.doDir(path)
+	return nil
+}
+
+// doDir analyzes the single package found in the directory, if there is one,
+// plus a test package, if there is one.
+func (d *driver) doDir(dir string) {
+	context := build.Default
+	if len(context.BuildTags) != 0 {
+		d.warnf("build tags %s previously set", context.BuildTags)
+	}
+	context.BuildTags = append(d.tags, context.BuildTags...)
+
+	bp, err := context.ImportDir(dir, 0)
+	if err != nil {
+		// If it's just that there are no go source files, that's fine.
+		if _, nogo := err.(*build.NoGoError); nogo {
+			return
+		}
+		// Non-fatal: we are doing a recursive walk and there may be other directories.
+		d.warnf("cannot process directory %s: %s", dir, err)
+		return
+	}
+	var names []string
+	names = append(names, bp.GoFiles...)
+	names = append(names, bp.CgoFiles...)
+	names = append(names, bp.TestGoFiles...) // These are also in the "foo" package.
+	names = append(names, bp.SFiles...)
+	prefixDirectory(dir, names)
+	path := bp.ImportPath
+	if path == "." {
+		path = "" // outside GOPATH; use the package name
+	}
+	base := d.doPackage(path, names, nil)
+	// Is there also a "foo_test" package? If so, do that one as well.
+	if len(bp.XTestGoFiles) > 0 {
+		names = bp.XTestGoFiles
+		prefixDirectory(dir, names)
+		var xpath string
+		if path != "" {
+			xpath = path + "_test"
+		}
+		d.doPackage(xpath, names, func(imp string) *types.Package {
+			// The external test package sees the package under test,
+			// including its in-package tests. The import path may be
+			// spelled differently from path, for instance if the
+			// package is vendored, so compare directories.
+			if base == nil {
+				return nil
+			}
+			if path == "" || imp != path {
+				abs, err := filepath.Abs(dir)
+				if err != nil {
+					return nil
+				}
+				ip, err := context.Import(imp, abs, build.FindOnly)
+				if err != nil || ip.Dir != abs {
+					return nil
+				}
+			}
+			return base
+		})
+	}
+}
+
+// doFiles analyzes the single package constructed from the named files.
+// It reports whether any files were checked.
+func (d *driver) doFiles(names []string) bool {
+	return d.doPackage("", names, nil) != nil
+}
+
+// doPackage analyzes the single package constructed from the named files,
+// whose import path is path, or the package name if path is empty.
+// The override function, if not nil, supplies imported packages in
+// preference to the driver's importer.
+// It returns the type-checked package, or nil if no Go files were checked.
+func (d *driver) doPackage(path string, names []string, override func(string) *types.Package) *types.Package {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	var otherFiles []string
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".go") {
+			otherFiles = append(otherFiles, name)
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			// Warn but continue to next package.
+			d.warnf("%s", err)
+			return nil
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	if path == "" {
+		path = files[0].Name.Name
+	}
+	if *verbose {
+		for _, name := range names {
+			fmt.Println("Checking file", name)
+		}
+	}
+
+	var typeErrs []error
+	imp := d.importer
+	if override != nil {
+		imp = importerFunc(func(path string) (*types.Package, error) {
+			if pkg := override(path); pkg != nil {
+				return pkg, nil
+			}
+			return d.importer.Import(path)
+		})
+	}
+	tc := &types.Config{
+		Importer: imp,
+		// By providing a Config with our own error function, it will continue
+		// past the first error. We collect them all for printing later.
+		Error: func(err error) { typeErrs = append(typeErrs, err) },
+		Sizes: types.SizesFor("gc", build.Default.GOARCH),
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	pkg, _ := tc.Check(path, fset, files, info)
+	if *verbose {
+		for _, err := range typeErrs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+
+	// Facts are not propagated between packages in this mode,
+	// but analyzers may still use them within the package.
+	set, err := facts.Decode(pkg, func(string) ([]byte, error) { return nil, nil })
+	if err != nil {
+		d.warnf("%v", err)
+		return pkg
+	}
+	cpkg := &checker.Package{
+		Fset:       fset,
+		Files:      files,
+		OtherFiles: otherFiles,
+		Types:      pkg,
+		TypesInfo:  info,
+		TypesSizes: tc.Sizes,
+		IllTyped:   len(typeErrs) > 0,
+	}
+	results := checker.Run(cpkg, d.analyzers, set, false)
+	if analysisflags.JSON {
+		for _, res := range results {
+			d.json.Add(fset, path, res)
+		}
+	} else if checker.PrintPlain(d.progname, cpkg, results) {
+		d.exitCode = 1
+	}
+	return pkg
+}
+
+// prefixDirectory places the directory name on the beginning of each name in the list.
+func prefixDirectory(directory string, names []string) {
+	if directory != "." {
+		for i, name := range names {
+			names[i] = filepath.Join(directory, name)
+		}
+	}
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
@@ -0,0 +1,326 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package unitchecker defines the main function for an analysis
+// driver that analyzes a single compilation unit during a build.
+// It is invoked by a build system such as "go vet":
+//
+//	$ go vet -vettool=$(which vet)
+//
+// It supports the following command-line protocol:
+//
+//	-V=full         describe executable               (to the build tool)
+//	-flags          describe flags                    (to the build tool)
+//	foo.cfg         description of compilation unit (from the build tool)
+//
+// If you need a standalone tool, use go/analysis/multichecker,
+// which supports this mode but can also load packages
+// from source using go/build.
+package unitchecker
+
+// The vet.cfg protocol between the go command and the tool is
+// intentionally minimal: the go command runs the tool once per package,
+// in dependency order, with a configuration file describing the files
+// and the compiled dependencies of the package, along with the facts
+// ("vetx" files) the tool wrote for each dependency. Facts flow between
+// packages only through these files, so the tool needs no memory of
+// its own between runs, and the go command can cache the facts for
+// each package like any other build output.
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/analysis"
+	"go/analysis/internal/analysisflags"
+	"go/analysis/internal/checker"
+	"go/analysis/internal/facts"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Config describes a compilation unit to be analyzed.
+// It is provided to the tool in a JSON-encoded file
+// whose name ends with ".cfg".
+type Config struct {
+	ID                        string // e.g. "fmt [fmt.test]"
+	Compiler                  string
+	Dir                       string
+	ImportPath                string
+	GoFiles                   []string
+	NonGoFiles                []string
+	ImportMap                 map[string]string
+	PackageFile               map[string]string
+	Standard                  map[string]bool
+	PackageVetx               map[string]string
+	VetxOnly                  bool
+	VetxOutput                string
+	SucceedOnTypecheckFailure bool
+}
+
+// Main is the main function of a vet-like analysis tool that must be
+// invoked by a build system to analyze a single package.
+//
+// The protocol required by 'go vet -vettool=...' is that the tool must support:
+//
+//	-flags          describe flags in JSON
+//	-V=full         describe executable for build caching
+//	foo.cfg         perform separate modular analyze on the single
+//	                unit described by a JSON config file foo.cfg.
+//
+func Main(analyzers ...*analysis.Analyzer) {
+	progname := filepath.Base(os.Args[0])
+	log.SetFlags(0)
+	log.SetPrefix(progname + ": ")
+
+	if err := analysis.Validate(analyzers); err != nil {
+		log.Fatal(err)
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `%[1]s is a tool for static analysis of Go programs.
+
+Usage of %[1]s:
+	%.16[1]s unit.cfg	# execute analysis specified by config file
+	%.16[1]s help    	# general help
+	%.16[1]s help name	# help on specific analyzer and its flags
+`, progname)
+		os.Exit(1)
+	}
+
+	analyzers = analysisflags.Parse(analyzers, true)
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+	}
+	if args[0] == "help" {
+		analysisflags.Help(progname, analyzers, args[1:])
+		os.Exit(0)
+	}
+	if len(args) != 1 || !strings.HasSuffix(args[0], ".cfg") {
+		log.Fatalf("invalid arguments; %s must be run by a build tool, as in 'go vet -vettool=%s'", progname, os.Args[0])
+	}
+	Run(args[0], analyzers)
+}
+
+// Run reads the *.cfg file, runs the analysis,
+// and calls os.Exit with an appropriate error code.
+// It assumes flags have already been set.
+func Run(configFile string, analyzers []*analysis.Analyzer) {
+	cfg, err := readConfig(configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	pkg, results, err := run(fset, cfg, analyzers)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if pkg == nil {
+		// Nothing to report: either a facts-only run,
+		// or a type error the go command asked us to ignore.
+		os.Exit(0)
+	}
+
+	// In VetxOnly mode, the analysis is run only for facts.
+	if !cfg.VetxOnly {
+		if analysisflags.JSON {
+			// JSON output
+			tree := make(checker.JSONTree)
+			for _, res := range results {
+				tree.Add(fset, cfg.ID, res)
+			}
+			tree.Print()
+		} else if checker.PrintPlain(progname(), pkg, results) {
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+func progname() string {
+	return filepath.Base(os.Args[0])
+}
+
+func readConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(Config)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("cannot decode JSON config file %s: %v", filename, err)
+	}
+	if len(cfg.GoFiles) == 0 {
+		// The go command disallows packages with no files.
+		// The only exception is unsafe, but the go command
+		// doesn't call vet on it.
+		return nil, fmt.Errorf("package has no files: %s", cfg.ImportPath)
+	}
+	return cfg, nil
+}
+
+// run analyzes the package described by cfg. It returns a nil
+// package, and no results, if there is nothing to report.
+func run(fset *token.FileSet, cfg *Config, analyzers []*analysis.Analyzer) (*checker.Package, []checker.Result, error) {
+	// If the package has no analyzers that use facts and we are
+	// only asked for facts, there is nothing to do. Don't even
+	// type-check: write an empty facts file and exit.
+	if cfg.VetxOnly && !usesFacts(analyzers) {
+		return nil, nil, writeVetx(cfg, nil)
+	}
+
+	// Load, parse, typecheck.
+	var files []*ast.File
+	for _, name := range cfg.GoFiles {
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			if cfg.SucceedOnTypecheckFailure || cfg.VetxOnly {
+				// Silently succeed; let the compiler
+				// report parse errors.
+				return nil, nil, writeVetx(cfg, nil)
+			}
+			return nil, nil, err
+		}
+		files = append(files, f)
+	}
+	compilerImporter := importer.For(cfg.Compiler, func(path string) (io.ReadCloser, error) {
+		// path is a resolved package path, not an import path.
+		file, ok := cfg.PackageFile[path]
+		if !ok {
+			if cfg.Compiler == "gccgo" && cfg.Standard[path] {
+				return nil, nil // fall back to default gccgo lookup
+			}
+			return nil, fmt.Errorf("no package file for %q", path)
+		}
+		return os.Open(file)
+	})
+	imp := importerFunc(func(importPath string) (*types.Package, error) {
+		if importPath == "unsafe" {
+			return compilerImporter.Import("unsafe")
+		}
+		path, ok := cfg.ImportMap[importPath] // resolve vendoring, etc
+		if !ok {
+			return nil, fmt.Errorf("can't resolve import %q", importPath)
+		}
+		return compilerImporter.Import(path)
+	})
+	var typeErrs []error
+	tc := &types.Config{
+		Importer: imp,
+		Sizes:    types.SizesFor("gc", build.Default.GOARCH), // vet has always used the gc sizes
+		Error:    func(err error) { typeErrs = append(typeErrs, err) },
+	}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	pkg, _ := tc.Check(cfg.ImportPath, fset, files, info)
+	if len(typeErrs) > 0 {
+		if cfg.SucceedOnTypecheckFailure || cfg.VetxOnly {
+			// Silently succeed; let the compiler
+			// report type errors. Facts for the
+			// package are simply unavailable.
+			return nil, nil, writeVetx(cfg, nil)
+		}
+		for _, err := range typeErrs {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		// This message could be silenced, and we could just exit,
+		// but it might be helpful at least at first to make clear that the
+		// above errors are coming from vet and not the compiler
+		// (they often look like compiler errors, such as "declared but not used").
+		return nil, nil, fmt.Errorf("typecheck failures")
+	}
+
+	// Read the facts of the dependencies.
+	set, err := facts.Decode(pkg, func(path string) ([]byte, error) {
+		if vetx, ok := cfg.PackageVetx[path]; ok {
+			return ioutil.ReadFile(vetx)
+		}
+		return nil, nil // no .vetx file, no facts
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cpkg := &checker.Package{
+		Fset:       fset,
+		Files:      files,
+		OtherFiles: cfg.NonGoFiles,
+		Types:      pkg,
+		TypesInfo:  info,
+		TypesSizes: tc.Sizes,
+	}
+	results := checker.Run(cpkg, analyzers, set, cfg.VetxOnly)
+
+	if err := writeVetx(cfg, set); err != nil {
+		return nil, nil, err
+	}
+	return cpkg, results, nil
+}
+
+// writeVetx writes the facts of set, or no facts if set is nil,
+// to the facts file of the configuration, if any.
+func writeVetx(cfg *Config, set *facts.Set) error {
+	if cfg.VetxOutput == "" {
+		return nil
+	}
+	var data []byte
+	if set != nil {
+		data = set.Encode()
+	}
+	if err := ioutil.WriteFile(cfg.VetxOutput, data, 0666); err != nil {
+		return fmt.Errorf("failed to write analysis facts: %v", err)
+	}
+	return nil
+}
+
+// usesFacts reports whether any of the analyzers, or the analyzers
+// they require, use facts.
+func usesFacts(analyzers []*analysis.Analyzer) bool {
+	seen := make(map[*analysis.Analyzer]bool)
+	var visit func(a *analysis.Analyzer) bool
+	visit = func(a *analysis.Analyzer) bool {
+		if seen[a] {
+			return false
+		}
+		seen[a] = true
+		if len(a.FactTypes) > 0 {
+			return true
+		}
+		for _, req := range a.Requires {
+			if visit(req) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, a := range analyzers {
+		if visit(a) {
+			return true
+		}
+	}
+	return false
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
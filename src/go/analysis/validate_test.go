@@ -0,0 +1,61 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+type testFact struct{}
+
+func (*testFact) AFact() {}
+
+type nonPointerFact struct{}
+
+func (nonPointerFact) AFact() {}
+
+func TestValidate(t *testing.T) {
+	run := func(*Pass) (interface{}, error) { return nil, nil }
+
+	dependsOnSelf := &Analyzer{Name: "dependsOnSelf", Doc: "doc", Run: run}
+	dependsOnSelf.Requires = append(dependsOnSelf.Requires, dependsOnSelf)
+
+	inCycleA := &Analyzer{Name: "inCycleA", Doc: "doc", Run: run}
+	inCycleB := &Analyzer{Name: "inCycleB", Doc: "doc", Run: run, Requires: []*Analyzer{inCycleA}}
+	inCycleA.Requires = append(inCycleA.Requires, inCycleB)
+
+	a := &Analyzer{Name: "a", Doc: "doc", Run: run}
+	b := &Analyzer{Name: "b", Doc: "doc", Run: run, Requires: []*Analyzer{a}}
+	c := &Analyzer{Name: "c", Doc: "doc", Run: run, Requires: []*Analyzer{a, b}}
+	facts := &Analyzer{Name: "facts", Doc: "doc", Run: run, FactTypes: []Fact{new(testFact)}}
+
+	for _, test := range []struct {
+		analyzers []*Analyzer
+		wantErr   string
+	}{
+		{[]*Analyzer{a, b, c, facts}, ""},
+		{[]*Analyzer{c}, ""},
+		{[]*Analyzer{dependsOnSelf}, "cycle detected involving dependsOnSelf"},
+		{[]*Analyzer{inCycleA}, "cycle detected involving inCycleA"},
+		{[]*Analyzer{a, a}, "duplicate analyzer: a"},
+		{[]*Analyzer{{Name: "bad name", Doc: "doc", Run: run}}, "invalid analyzer name"},
+		{[]*Analyzer{{Name: "undoc", Run: run}}, "is undocumented"},
+		{[]*Analyzer{{Name: "norun", Doc: "doc"}}, "has nil Run"},
+		{[]*Analyzer{{Name: "nonptr", Doc: "doc", Run: run, FactTypes: []Fact{nonPointerFact{}}}}, "is not a pointer"},
+		{[]*Analyzer{facts, {Name: "facts2", Doc: "doc", Run: run, FactTypes: []Fact{new(testFact)}}}, "registered by two analyzers"},
+	} {
+		err := Validate(test.analyzers)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("Validate(%v): unexpected error: %v", test.analyzers, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("Validate(%v) = %v, want error containing %q", test.analyzers, err, test.wantErr)
+		}
+	}
+}
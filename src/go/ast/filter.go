@@ -70,6 +70,10 @@ func fieldName(x Expr) *Ident {
 		}
 	case *StarExpr:
 		return fieldName(t.X)
+	case *IndexExpr:
+		return fieldName(t.X)
+	case *IndexListExpr:
+		return fieldName(t.X)
 	}
 	return nil
 }
@@ -83,9 +87,10 @@ func filterFieldList(fields *FieldList, filter Filter, export bool) (removedFiel
 	for _, f := range list {
 		keepField := false
 		if len(f.Names) == 0 {
-			// anonymous field
+			// anonymous field, or embedded type element
+			// (such as ~int | ~string) in an interface
 			name := fieldName(f.Type)
-			keepField = name != nil && filter(name.Name)
+			keepField = name == nil && isTypeElem(f.Type) || name != nil && filter(name.Name)
 		} else {
 			n := len(f.Names)
 			f.Names = filterIdentList(f.Names, filter)
@@ -109,6 +114,22 @@ func filterFieldList(fields *FieldList, filter Filter, export bool) (removedFiel
 	return
 }
 
+// isTypeElem reports whether x is a type element of an interface
+// that is not simply an embedded type name, such as a union or ~T.
+func isTypeElem(x Expr) bool {
+	switch x := x.(type) {
+	case *UnaryExpr:
+		return x.Op == token.TILDE
+	case *BinaryExpr:
+		return x.Op == token.OR
+	case *ArrayType, *StructType, *FuncType, *InterfaceType, *MapType, *ChanType:
+		return true
+	case *ParenExpr:
+		return isTypeElem(x.X)
+	}
+	return false
+}
+
 func filterCompositeLit(lit *CompositeLit, filter Filter, export bool) {
 	n := len(lit.Elts)
 	lit.Elts = filterExprList(lit.Elts, filter, export)
@@ -190,6 +190,21 @@ func (ctxt *Context) openFile(path string) (io.ReadCloser, error) {
 	return f, nil
 }
 
+// readEmbeds reads the named Go source file and returns the patterns
+// listed in its //go:embed directives.
+func (ctxt *Context) readEmbeds(filename string) ([]embedPattern, error) {
+	f, err := ctxt.openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %v", filename, err)
+	}
+	return readEmbeds(filename, data)
+}
+
 // isFile determines whether path is a file by trying to open it.
 // It reuses openFile instead of adding another function to the
 // list in Context.
@@ -425,6 +440,18 @@ type Package struct {
 	XTestGoFiles   []string                    // _test.go files outside package
 	XTestImports   []string                    // import paths from XTestGoFiles
 	XTestImportPos map[string][]token.Position // line information for XTestImports
+
+	// //go:embed patterns found in Go source files.
+	// For example, if a source file says
+	//	//go:embed a* b.c
+	// then the list will contain those two strings as separate entries.
+	// (See package embed for more details about //go:embed.)
+	EmbedPatterns        []string                    // patterns from GoFiles, CgoFiles
+	EmbedPatternPos      map[string][]token.Position // line information for EmbedPatterns
+	TestEmbedPatterns    []string                    // patterns from TestGoFiles
+	TestEmbedPatternPos  map[string][]token.Position // line information for TestEmbedPatterns
+	XTestEmbedPatterns   []string                    // patterns from XTestGoFiles
+	XTestEmbedPatternPos map[string][]token.Position // line information for XTestEmbedPatterns
 }
 
 // IsCommand reports whether the package is considered a
@@ -730,6 +757,9 @@ Found:
 	imported := make(map[string][]token.Position)
 	testImported := make(map[string][]token.Position)
 	xTestImported := make(map[string][]token.Position)
+	embedPos := make(map[string][]token.Position)
+	testEmbedPos := make(map[string][]token.Position)
+	xTestEmbedPos := make(map[string][]token.Position)
 	allTags := make(map[string]bool)
 	fset := token.NewFileSet()
 	for _, d := range dirs {
@@ -848,6 +878,7 @@ Found:
 
 		// Record imports and information about cgo.
 		isCgo := false
+		isEmbed := false
 		for _, decl := range pf.Decls {
 			d, ok := decl.(*ast.GenDecl)
 			if !ok {
@@ -870,6 +901,9 @@ Found:
 				} else {
 					imported[path] = append(imported[path], fset.Position(spec.Pos()))
 				}
+				if path == "embed" {
+					isEmbed = true
+				}
 				if path == "C" {
 					if isTest {
 						badFile(fmt.Errorf("use of cgo in test %s not supported", filename))
@@ -888,6 +922,24 @@ Found:
 				}
 			}
 		}
+		if isEmbed {
+			// The directives follow the imports, which is
+			// as far as matchFile read the file.
+			embeds, err := ctxt.readEmbeds(filename)
+			if err != nil {
+				badFile(err)
+				continue
+			}
+			m := embedPos
+			if isXTest {
+				m = xTestEmbedPos
+			} else if isTest {
+				m = testEmbedPos
+			}
+			for _, e := range embeds {
+				m[e.pattern] = append(m[e.pattern], e.pos)
+			}
+		}
 		if isCgo {
 			allTags["cgo"] = true
 			if ctxt.CgoEnabled {
@@ -918,6 +970,9 @@ Found:
 	p.Imports, p.ImportPos = cleanImports(imported)
 	p.TestImports, p.TestImportPos = cleanImports(testImported)
 	p.XTestImports, p.XTestImportPos = cleanImports(xTestImported)
+	p.EmbedPatterns, p.EmbedPatternPos = cleanImports(embedPos)
+	p.TestEmbedPatterns, p.TestEmbedPatternPos = cleanImports(testEmbedPos)
+	p.XTestEmbedPatterns, p.XTestEmbedPatternPos = cleanImports(xTestEmbedPos)
 
 	// add the .S files only if we are using cgo
 	// (which means gcc will compile them).
@@ -34,7 +34,7 @@ import (
 //
 var pkgDeps = map[string][]string{
 	// L0 is the lowest level, core, nearly unavoidable packages.
-	"errors":                  {},
+	"errors":                  {"runtime", "internal/reflectlite"},
 	"io":                      {"errors", "sync", "sync/atomic"},
 	"runtime":                 {"unsafe", "runtime/internal/atomic", "runtime/internal/sys", "internal/cpu", "internal/bytealg"},
 	"runtime/internal/sys":    {},
@@ -45,6 +45,8 @@ var pkgDeps = map[string][]string{
 	"unsafe":                  {},
 	"internal/cpu":            {},
 	"internal/bytealg":        {"unsafe", "internal/cpu"},
+	"internal/reflectlite":    {"runtime", "unsafe"},
+	"internal/oserror":        {"errors"},
 
 	"L0": {
 		"errors",
@@ -56,6 +58,8 @@ var pkgDeps = map[string][]string{
 		"unsafe",
 		"internal/cpu",
 		"internal/bytealg",
+		"internal/reflectlite",
+		"internal/oserror",
 	},
 
 	// L1 adds simple functions and strings processing,
@@ -156,9 +160,10 @@ var pkgDeps = map[string][]string{
 		"syscall",
 	},
 
-	"internal/poll":    {"L0", "internal/race", "syscall", "time", "unicode/utf16", "unicode/utf8", "internal/syscall/windows"},
+	"internal/poll":    {"L0", "internal/race", "syscall", "time", "unicode/utf16", "unicode/utf8", "internal/syscall/unix", "internal/syscall/windows"},
 	"internal/testlog": {"L0"},
-	"os":               {"L1", "os", "syscall", "time", "internal/poll", "internal/syscall/windows", "internal/syscall/unix", "internal/testlog"},
+	"io/fs":            {"L0", "internal/oserror", "path", "sort", "time", "unicode/utf8"},
+	"os":               {"L1", "os", "syscall", "time", "io/fs", "internal/poll", "internal/syscall/windows", "internal/syscall/unix", "internal/testlog"},
 	"path/filepath":    {"L2", "os", "syscall", "internal/syscall/windows"},
 	"io/ioutil":        {"L2", "os", "path/filepath", "time"},
 	"os/exec":          {"L2", "os", "context", "path/filepath", "syscall"},
@@ -167,6 +172,7 @@ var pkgDeps = map[string][]string{
 	// OS enables basic operating system functionality,
 	// but not direct use of package syscall, nor os/signal.
 	"OS": {
+		"io/fs",
 		"io/ioutil",
 		"os",
 		"os/exec",
@@ -186,7 +192,8 @@ var pkgDeps = map[string][]string{
 	"runtime/trace":  {"L0", "context", "fmt"},
 	"text/tabwriter": {"L2"},
 
-	"testing":          {"L2", "flag", "fmt", "internal/race", "os", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
+	"testing":          {"L2", "flag", "fmt", "internal/race", "os", "reflect", "runtime/debug", "runtime/pprof", "runtime/trace", "time"},
+	"testing/fstest":   {"L2", "fmt", "io/fs", "reflect", "time"},
 	"testing/iotest":   {"L2", "log"},
 	"testing/quick":    {"L2", "flag", "fmt", "reflect", "time"},
 	"internal/testenv": {"L2", "OS", "flag", "testing", "syscall"},
@@ -223,11 +230,27 @@ var pkgDeps = map[string][]string{
 	// Go type checking.
 	"go/constant":               {"L4", "go/token", "math/big"},
 	"go/importer":               {"L4", "go/build", "go/internal/gccgoimporter", "go/internal/gcimporter", "go/internal/srcimporter", "go/token", "go/types"},
-	"go/internal/gcimporter":    {"L4", "OS", "go/build", "go/constant", "go/token", "go/types", "text/scanner"},
+	"go/internal/gcimporter":    {"L4", "OS", "go/ast", "go/build", "go/constant", "go/parser", "go/token", "go/types", "text/scanner"},
 	"go/internal/gccgoimporter": {"L4", "OS", "debug/elf", "go/constant", "go/token", "go/types", "text/scanner"},
 	"go/internal/srcimporter":   {"L4", "OS", "fmt", "go/ast", "go/build", "go/parser", "go/token", "go/types", "path/filepath"},
 	"go/types":                  {"L4", "GOPARSER", "container/heap", "go/constant"},
 
+	// Go analysis.
+	"go/ast/inspector":                   {"go/ast"},
+	"go/analysis":                        {"L4", "OS", "flag", "go/ast", "go/token", "go/types"},
+	"go/analysis/passes/inspect":         {"L4", "go/analysis", "go/ast/inspector"},
+	"go/analysis/internal/facts":         {"L4", "OS", "encoding/gob", "go/analysis", "go/types"},
+	"go/analysis/internal/analysisflags": {"L4", "OS", "crypto/sha256", "flag", "encoding/gob", "encoding/json", "go/analysis"},
+	"go/analysis/internal/checker":       {"L4", "OS", "encoding/json", "go/analysis", "go/analysis/internal/facts", "go/ast", "go/token", "go/types"},
+	"go/analysis/unitchecker": {
+		"L4", "OS", "GOPARSER", "encoding/json", "flag", "go/analysis", "go/analysis/internal/analysisflags",
+		"go/analysis/internal/checker", "go/analysis/internal/facts", "go/build", "go/importer", "go/types",
+	},
+	"go/analysis/multichecker": {
+		"L4", "OS", "GOPARSER", "flag", "go/analysis", "go/analysis/internal/analysisflags", "go/analysis/internal/checker",
+		"go/analysis/internal/facts", "go/analysis/unitchecker", "go/build", "go/importer", "go/types",
+	},
+
 	// One of a kind.
 	"archive/tar":                    {"L4", "OS", "syscall", "os/user"},
 	"archive/zip":                    {"L4", "OS", "compress/flate"},
@@ -246,6 +269,7 @@ var pkgDeps = map[string][]string{
 	"debug/macho":                    {"L4", "OS", "debug/dwarf"},
 	"debug/pe":                       {"L4", "OS", "debug/dwarf"},
 	"debug/plan9obj":                 {"L4", "OS"},
+	"embed":                          {"L4", "OS"},
 	"encoding":                       {"L4"},
 	"encoding/ascii85":               {"L4"},
 	"encoding/asn1":                  {"L4", "math/big"},
@@ -264,8 +288,10 @@ var pkgDeps = map[string][]string{
 	"image/jpeg":                     {"L4", "image/internal/imageutil"},
 	"image/png":                      {"L4", "compress/zlib"},
 	"index/suffixarray":              {"L4", "regexp"},
+	"internal/fuzz":                  {"L4", "OS", "crypto/sha256", "os/signal"},
 	"internal/singleflight":          {"sync"},
 	"internal/trace":                 {"L4", "OS"},
+	"log/slog":                       {"L4", "context", "encoding", "encoding/json"},
 	"math/big":                       {"L4"},
 	"mime":                           {"L4", "OS", "syscall", "internal/syscall/windows/registry"},
 	"mime/quotedprintable":           {"L4"},
@@ -273,7 +299,7 @@ var pkgDeps = map[string][]string{
 	"net/url":                        {"L4"},
 	"plugin":                         {"L0", "OS", "CGO"},
 	"runtime/pprof/internal/profile": {"L4", "OS", "compress/gzip", "regexp"},
-	"testing/internal/testdeps":      {"L4", "internal/testlog", "runtime/pprof", "regexp"},
+	"testing/internal/testdeps":      {"L4", "internal/fuzz", "internal/testlog", "runtime/pprof", "regexp"},
 	"text/scanner":                   {"L4", "OS"},
 	"text/template/parse":            {"L4"},
 
@@ -310,11 +336,12 @@ var pkgDeps = map[string][]string{
 	// do networking portably, it must have a small dependency set: just L0+basic os.
 	"net": {
 		"L0", "CGO",
-		"context", "math/rand", "os", "reflect", "sort", "syscall", "time",
+		"context", "math/rand", "net/netip", "os", "reflect", "sort", "syscall", "time",
 		"internal/nettrace", "internal/poll",
 		"internal/syscall/windows", "internal/singleflight", "internal/race",
 		"golang_org/x/net/dns/dnsmessage", "golang_org/x/net/lif", "golang_org/x/net/route",
 	},
+	"net/netip": {"L1"},
 
 	// NET enables use of basic network-related packages.
 	"NET": {
@@ -350,6 +377,7 @@ var pkgDeps = map[string][]string{
 		"crypto/sha512",
 		"golang_org/x/crypto/chacha20poly1305",
 		"golang_org/x/crypto/curve25519",
+		"golang_org/x/crypto/hkdf",
 		"golang_org/x/crypto/poly1305",
 	},
 
@@ -361,14 +389,20 @@ var pkgDeps = map[string][]string{
 	// Mathematical crypto: dependencies on fmt (L4) and math/big.
 	// We could avoid some of the fmt, but math/big imports fmt anyway.
 	"crypto/dsa":      {"L4", "CRYPTO", "math/big"},
+	"crypto/ecdh":     {"L4", "CRYPTO", "crypto/elliptic", "math/big"},
 	"crypto/ecdsa":    {"L4", "CRYPTO", "crypto/elliptic", "math/big", "encoding/asn1"},
 	"crypto/elliptic": {"L4", "CRYPTO", "math/big"},
 	"crypto/rsa":      {"L4", "CRYPTO", "crypto/rand", "math/big"},
 
+	"crypto/ed25519":                       {"L4", "CRYPTO", "crypto/rand", "crypto/ed25519/internal/edwards25519"},
+	"crypto/ed25519/internal/edwards25519": {"L4", "CRYPTO"},
+
 	"CRYPTO-MATH": {
 		"CRYPTO",
 		"crypto/dsa",
+		"crypto/ecdh",
 		"crypto/ecdsa",
+		"crypto/ed25519",
 		"crypto/elliptic",
 		"crypto/rand",
 		"crypto/rsa",
@@ -379,7 +413,7 @@ var pkgDeps = map[string][]string{
 	// SSL/TLS.
 	"crypto/tls": {
 		"L4", "CRYPTO-MATH", "OS",
-		"container/list", "crypto/x509", "encoding/pem", "net", "syscall",
+		"container/list", "context", "crypto/x509", "encoding/pem", "net", "syscall",
 	},
 	"crypto/x509": {
 		"L4", "CRYPTO-MATH", "OS", "CGO",
@@ -396,6 +430,7 @@ var pkgDeps = map[string][]string{
 	"net/http": {
 		"L4", "NET", "OS",
 		"compress/gzip",
+		"compress/zlib",
 		"container/list",
 		"context",
 		"crypto/rand",
@@ -410,22 +445,27 @@ var pkgDeps = map[string][]string{
 		"mime/multipart",
 		"net/http/httptrace",
 		"net/http/internal",
+		"net/http/internal/quic",
 		"runtime/debug",
 		"syscall/js",
 	},
-	"net/http/internal":  {"L4"},
-	"net/http/httptrace": {"context", "crypto/tls", "internal/nettrace", "net", "reflect", "time"},
+	"net/http/internal": {"L4"},
+	"net/http/internal/quic": {
+		"L4", "NET", "CRYPTO",
+		"context", "crypto/rand", "crypto/tls",
+	},
+	"net/http/httptrace": {"context", "crypto/tls", "internal/nettrace", "net", "net/textproto", "reflect", "time"},
 
 	// HTTP-using packages.
 	"expvar":             {"L4", "OS", "encoding/json", "net/http"},
 	"net/http/cgi":       {"L4", "NET", "OS", "crypto/tls", "net/http", "regexp"},
-	"net/http/cookiejar": {"L4", "NET", "net/http"},
+	"net/http/cookiejar": {"L4", "NET", "encoding/json", "net/http"},
 	"net/http/fcgi":      {"L4", "NET", "OS", "context", "net/http", "net/http/cgi"},
 	"net/http/httptest": {
-		"L4", "NET", "OS", "crypto/tls", "flag", "net/http", "net/http/internal", "crypto/x509",
+		"L4", "NET", "OS", "context", "crypto/tls", "flag", "net/http", "net/http/internal", "crypto/x509",
 		"golang_org/x/net/http/httpguts",
 	},
-	"net/http/httputil": {"L4", "NET", "OS", "context", "net/http", "net/http/internal"},
+	"net/http/httputil": {"L4", "NET", "OS", "context", "golang_org/x/net/http/httpguts", "mime", "net/http", "net/http/internal"},
 	"net/http/pprof":    {"L4", "OS", "html/template", "net/http", "runtime/pprof", "runtime/trace"},
 	"net/rpc":           {"L4", "NET", "encoding/gob", "html/template", "net/http"},
 	"net/rpc/jsonrpc":   {"L4", "NET", "encoding/json", "net/rpc"},
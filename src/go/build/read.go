@@ -6,8 +6,14 @@ package build
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
+	"fmt"
+	"go/token"
 	"io"
+	"strconv"
+	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -245,3 +251,166 @@ func readImports(f io.Reader, reportSyntaxError bool, imports *[]string) ([]byte
 
 	return r.buf, r.err
 }
+
+// An embedPattern is a pattern listed in a //go:embed directive.
+type embedPattern struct {
+	pattern string
+	pos     token.Position
+}
+
+var goEmbed = []byte("//go:embed")
+
+// readEmbeds returns the patterns listed in the //go:embed directives
+// of the Go source file data. Like other compiler directives, a
+// //go:embed directive must start at the beginning of a line; comments
+// inside string and rune literals and /* */ comments are not directives.
+func readEmbeds(filename string, data []byte) ([]embedPattern, error) {
+	var list []embedPattern
+	line, lineStart := 1, 0
+	startLine := true
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		default:
+			startLine = false
+		case '\n':
+			line++
+			lineStart = i + 1
+			startLine = true
+		case '"', '\'', '`':
+			startLine = false
+			j := i + 1
+			for ; j < len(data) && data[j] != c; j++ {
+				if c != '`' && data[j] == '\\' {
+					j++
+				} else if c != '`' && data[j] == '\n' {
+					break // unterminated; leave the error to the parser
+				}
+			}
+			if c == '`' {
+				if n := bytes.Count(data[i:j], []byte("\n")); n > 0 {
+					line += n
+					lineStart = i + bytes.LastIndexByte(data[i:j], '\n') + 1
+				}
+			}
+			if j < len(data) && data[j] == '\n' {
+				j-- // process the newline in the next iteration
+			}
+			i = j
+		case '/':
+			if i+1 >= len(data) {
+				break
+			}
+			switch data[i+1] {
+			default:
+				startLine = false
+			case '*':
+				j := bytes.Index(data[i+2:], []byte("*/"))
+				if j < 0 {
+					j = len(data)
+				} else {
+					j += i + 2 + 1
+				}
+				if n := bytes.Count(data[i:j], []byte("\n")); n > 0 {
+					line += n
+					lineStart = i + bytes.LastIndexByte(data[i:j], '\n') + 1
+				}
+				startLine = false
+				i = j
+			case '/':
+				j := bytes.IndexByte(data[i:], '\n')
+				if j < 0 {
+					j = len(data)
+				} else {
+					j += i
+				}
+				text := data[i:j]
+				if startLine && bytes.HasPrefix(text, goEmbed) && len(text) > len(goEmbed) && (text[len(goEmbed)] == ' ' || text[len(goEmbed)] == '\t') {
+					pos := token.Position{
+						Filename: filename,
+						Offset:   i + len(goEmbed),
+						Line:     line,
+						Column:   utf8.RuneCount(data[lineStart:i+len(goEmbed)]) + 1,
+					}
+					patterns, err := parseGoEmbed(string(text[len(goEmbed):]), pos)
+					if err != nil {
+						return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+					}
+					list = append(list, patterns...)
+				}
+				i = j - 1 // process the newline in the next iteration
+			}
+		}
+	}
+	return list, nil
+}
+
+// parseGoEmbed parses the text following "//go:embed" to extract the
+// patterns. Patterns are separated by spaces and may be written as Go
+// double-quoted or raw strings. The pattern positions are computed
+// relative to pos, the position of the start of args.
+func parseGoEmbed(args string, pos token.Position) ([]embedPattern, error) {
+	trimBytes := func(n int) {
+		pos.Offset += n
+		pos.Column += utf8.RuneCountInString(args[:n])
+		args = args[n:]
+	}
+	trimSpace := func() {
+		trim := strings.TrimLeftFunc(args, unicode.IsSpace)
+		trimBytes(len(args) - len(trim))
+	}
+
+	var list []embedPattern
+	for trimSpace(); args != ""; trimSpace() {
+		var pattern string
+		patternPos := pos
+	Switch:
+		switch args[0] {
+		default:
+			i := len(args)
+			for j, c := range args {
+				if unicode.IsSpace(c) {
+					i = j
+					break
+				}
+			}
+			pattern = args[:i]
+			trimBytes(i)
+
+		case '`':
+			i := strings.Index(args[1:], "`")
+			if i < 0 {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+			pattern = args[1 : 1+i]
+			trimBytes(1 + i + 1)
+
+		case '"':
+			i := 1
+			for ; i < len(args); i++ {
+				if args[i] == '\\' {
+					i++
+					continue
+				}
+				if args[i] == '"' {
+					q, err := strconv.Unquote(args[:i+1])
+					if err != nil {
+						return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args[:i+1])
+					}
+					pattern = q
+					trimBytes(i + 1)
+					break Switch
+				}
+			}
+			return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+		}
+
+		if args != "" {
+			r, _ := utf8.DecodeRuneInString(args)
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid quoted string in //go:embed: %s", args)
+			}
+		}
+		list = append(list, embedPattern{pattern, patternPos})
+	}
+	return list, nil
+}
@@ -5,6 +5,7 @@
 package build
 
 import (
+	"fmt"
 	"io"
 	"strings"
 	"testing"
@@ -224,3 +225,70 @@ func TestReadFailuresIgnored(t *testing.T) {
 	}
 	testRead(t, tests, func(r io.Reader) ([]byte, error) { return readImports(r, false, nil) })
 }
+
+var readEmbedTests = []struct {
+	in, out string
+}{
+	{
+		"package p\n",
+		"",
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n//go:embed x y z\nvar files embed.FS",
+		`test:4:12:x
+		 test:4:14:y
+		 test:4:16:z`,
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n//go:embed x \"\\x79\" `z`\nvar files embed.FS",
+		`test:4:12:x
+		 test:4:14:y
+		 test:4:21:z`,
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n//go:embed x y\n//go:embed z\nvar files embed.FS",
+		`test:4:12:x
+		 test:4:14:y
+		 test:5:12:z`,
+	},
+	{
+		"package p\nimport \"embed\"\nvar i int\n\t //go:embed x y\n\t //go:embed z\n\t var files embed.FS",
+		"",
+	},
+	{
+		"package p\nimport \"embed\"\nvar s = \"//go:embed x\"\nvar r = `\n//go:embed y\n`\n/*\n//go:embed z\n*/\nvar i int // go:embed w",
+		"",
+	},
+	{
+		"package p\nimport \"embed\"\nvar r = `a\nb`\n/* c\n*/ //go:embed x\n//go:embed y\nvar files embed.FS",
+		`test:7:12:y`,
+	},
+	{
+		"package p\nimport \"embed\"\n//go:embed x \"y\nvar files embed.FS",
+		"error",
+	},
+}
+
+func TestReadEmbed(t *testing.T) {
+	for i, tt := range readEmbedTests {
+		embeds, err := readEmbeds("test", []byte(tt.in))
+		if tt.out == "error" {
+			if err == nil {
+				t.Errorf("#%d: readEmbeds succeeded, want error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: %v", i, err)
+			continue
+		}
+		var got []string
+		for _, e := range embeds {
+			got = append(got, fmt.Sprintf("%s:%d:%d:%s", e.pos.Filename, e.pos.Line, e.pos.Column, e.pattern))
+		}
+		want := strings.Fields(tt.out)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("#%d: readEmbeds:\nhave %q\nwant %q", i, got, want)
+		}
+	}
+}
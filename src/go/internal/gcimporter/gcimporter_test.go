@@ -551,6 +551,51 @@ func TestIssue25596(t *testing.T) {
 	importPkg(t, "./testdata/issue25596")
 }
 
+func TestGenerics(t *testing.T) {
+	skipSpecialPlatforms(t)
+
+	// This package only handles gc export data.
+	if runtime.Compiler != "gc" {
+		t.Skipf("gc-built packages not available (compiler = %s)", runtime.Compiler)
+	}
+
+	// On windows, we have to set the -D option for the compiler to avoid having a drive
+	// letter and an illegal ':' in the import path - just skip it (see also issue #3483).
+	if runtime.GOOS == "windows" {
+		t.Skip("avoid dealing with relative paths/drive letters on windows")
+	}
+
+	if f := compile(t, "testdata", "generics.go"); f != "" {
+		defer os.Remove(f)
+	}
+
+	pkg := importPkg(t, "./testdata/generics")
+	scope := pkg.Scope()
+
+	sum, ok := lookupObj(t, scope, "Sum").(*types.Func)
+	if !ok {
+		t.Fatalf("Sum is not a function")
+	}
+	if n := sum.Type().(*types.Signature).TypeParams().Len(); n != 1 {
+		t.Errorf("Sum has %d type parameters, want 1", n)
+	}
+
+	list, ok := lookupObj(t, scope, "List").Type().(*types.Named)
+	if !ok {
+		t.Fatalf("List is not a defined type")
+	}
+	if n := list.TypeParams().Len(); n != 1 {
+		t.Errorf("List has %d type parameters, want 1", n)
+	}
+	if n := list.NumMethods(); n != 2 {
+		t.Errorf("List has %d methods, want 2", n)
+	}
+
+	if _, ok := lookupObj(t, scope, "Number").Type().Underlying().(*types.Interface); !ok {
+		t.Errorf("Number is not an interface")
+	}
+}
+
 func importPkg(t *testing.T, path string) *types.Package {
 	pkg, err := Import(make(map[string]*types.Package), path, ".", nil)
 	if err != nil {
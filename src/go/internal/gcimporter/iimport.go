@@ -10,11 +10,15 @@ package gcimporter
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"go/ast"
 	"go/constant"
+	"go/parser"
 	"go/token"
 	"go/types"
 	"io"
 	"sort"
+	"strconv"
 )
 
 type intReader struct {
@@ -130,19 +134,42 @@ func iImportData(fset *token.FileSet, imports map[string]*types.Package, data []
 
 	localpkg := pkgList[0]
 
-	names := make([]string, 0, len(p.pkgIndex[localpkg]))
-	for name := range p.pkgIndex[localpkg] {
-		names = append(names, name)
+	// Skip the inline body index.
+	for nPkgs := r.uint64(); nPkgs > 0; nPkgs-- {
+		r.uint64()
+		for nSyms := r.uint64(); nSyms > 0; nSyms-- {
+			r.uint64()
+			r.uint64()
+		}
 	}
-	sort.Strings(names)
-	for _, name := range names {
-		p.doDecl(localpkg, name)
+
+	generics := p.genericFiles(r)
+
+	// The source of generic declarations may refer to any
+	// declaration in the index.
+	declPkgs := []*types.Package{localpkg}
+	if len(generics) > 0 {
+		declPkgs = pkgList
+	}
+	for _, pkg := range declPkgs {
+		names := make([]string, 0, len(p.pkgIndex[pkg]))
+		for name := range p.pkgIndex[pkg] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p.doDecl(pkg, name)
+		}
 	}
 
 	for _, typ := range p.interfaceList {
 		typ.Complete()
 	}
 
+	for _, g := range generics {
+		checkGenerics(fset, imports, g)
+	}
+
 	// record all referenced packages as imports
 	list := append(([]*types.Package)(nil), pkgList[1:]...)
 	sort.Sort(byPath(list))
@@ -211,6 +238,121 @@ func (p *iimporter) pkgAt(off uint64) *types.Package {
 	return nil
 }
 
+// A genericFile is the source of a file declaring generic functions
+// or types, as recorded in the export data by the compiler.
+type genericFile struct {
+	pkg      *types.Package
+	filename string
+	src      string
+	imports  []string // resolved paths of the import decls ("" if unknown)
+}
+
+// genericFiles reads the source of the files declaring generics,
+// grouped by package.
+func (p *iimporter) genericFiles(r *intReader) [][]genericFile {
+	var list [][]genericFile
+	for nFiles := r.uint64(); nFiles > 0; nFiles-- {
+		f := genericFile{pkg: p.pkgAt(r.uint64())}
+		f.filename = p.stringAt(r.uint64())
+		f.src = p.stringAt(r.uint64())
+		for nImports := r.uint64(); nImports > 0; nImports-- {
+			f.imports = append(f.imports, p.stringAt(r.uint64()))
+		}
+
+		if n := len(list); n > 0 && list[n-1][0].pkg == f.pkg {
+			list[n-1] = append(list[n-1], f)
+		} else {
+			list = append(list, []genericFile{f})
+		}
+	}
+	return list
+}
+
+// checkGenerics type-checks the generic declarations of the files of
+// a package into that package, unless they are present already. The
+// files' other declarations are part of the export data, if needed.
+func checkGenerics(fset *token.FileSet, imports map[string]*types.Package, files []genericFile) {
+	pkg := files[0].pkg
+	var list []*ast.File
+	for _, f := range files {
+		file, err := parser.ParseFile(fset, f.filename, f.src, 0)
+		if err != nil {
+			errorf("%v", err)
+		}
+
+		var decls []ast.Decl
+		i := 0
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				var specs []ast.Spec
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ImportSpec:
+						if i < len(f.imports) && f.imports[i] != "" {
+							spec.Path.Value = strconv.Quote(f.imports[i])
+							specs = append(specs, spec)
+						}
+						i++
+					case *ast.TypeSpec:
+						if spec.TypeParams != nil && pkg.Scope().Lookup(spec.Name.Name) != nil {
+							return // checked already
+						}
+						// generic types and constraint interfaces
+						if _, ok := spec.Type.(*ast.InterfaceType); spec.TypeParams != nil || ok && pkg.Scope().Lookup(spec.Name.Name) == nil {
+							specs = append(specs, spec)
+						}
+					}
+				}
+				if len(specs) > 0 {
+					decl.Specs = specs
+					decls = append(decls, decl)
+				}
+			case *ast.FuncDecl:
+				if decl.Type.TypeParams != nil && pkg.Scope().Lookup(decl.Name.Name) != nil {
+					return // checked already
+				}
+				if decl.Type.TypeParams != nil || decl.Recv != nil && isGenericRecv(decl.Recv.List[0].Type) {
+					decls = append(decls, decl)
+				}
+			}
+		}
+		file.Decls = decls
+		list = append(list, file)
+	}
+
+	// Errors are ignored: the package was compiled successfully,
+	// but the declarations that the generic ones do not refer to
+	// are missing.
+	conf := types.Config{
+		Importer: importsMap(imports),
+		Error:    func(error) {},
+	}
+	types.NewChecker(&conf, fset, pkg, nil).Files(list)
+}
+
+func isGenericRecv(x ast.Expr) bool {
+	if star, ok := x.(*ast.StarExpr); ok {
+		x = star.X
+	}
+	switch x.(type) {
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return true
+	}
+	return false
+}
+
+// importsMap implements types.Importer for packages that have been
+// imported already.
+type importsMap map[string]*types.Package
+
+func (m importsMap) Import(path string) (*types.Package, error) {
+	if pkg := m[path]; pkg != nil {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("can't find import: %q", path)
+}
+
 func (p *iimporter) typAt(off uint64, base *types.Named) types.Type {
 	if t, ok := p.typCache[off]; ok && (base == nil || !isInterface(t)) {
 		return t
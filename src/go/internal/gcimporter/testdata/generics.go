@@ -0,0 +1,37 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package generics
+
+import "strings"
+
+type Number interface {
+	~int | ~float64
+}
+
+var sep = ","
+
+func Sum[T Number](list ...T) T {
+	var s T
+	for _, x := range list {
+		s += x
+	}
+	return s
+}
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T) { l.items = append(l.items, v) }
+
+func (l *List[T]) Join(f func(T) string) string {
+	var s []string
+	for _, v := range l.items {
+		s = append(s, f(v))
+	}
+	return strings.Join(s, sep)
+}
+
+func Join(list []string) string { return strings.Join(list, sep) }
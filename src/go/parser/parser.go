@@ -206,6 +206,21 @@ func (p *parser) resolve(x ast.Expr) {
 	p.tryResolve(x, true)
 }
 
+// unresolve undoes the resolution of ident so that it can be declared.
+// It is used for identifiers that turn out to be type parameter names
+// only after they have been resolved as operands or types.
+func (p *parser) unresolve(ident *ast.Ident) {
+	if ident.Obj == unresolved {
+		for i := len(p.unresolved) - 1; i >= 0; i-- {
+			if p.unresolved[i] == ident {
+				p.unresolved = append(p.unresolved[:i], p.unresolved[i+1:]...)
+				break
+			}
+		}
+	}
+	ident.Obj = nil
+}
+
 // ----------------------------------------------------------------------------
 // Parsing support
 
@@ -651,26 +666,118 @@ func (p *parser) parseTypeName() ast.Expr {
 	return ident
 }
 
-func (p *parser) parseArrayType() ast.Expr {
+// parseArrayType parses an array or slice type after the opening '['
+// at lbrack. If len is nil, the array length (if any) is parsed as well.
+func (p *parser) parseArrayType(lbrack token.Pos, len ast.Expr) ast.Expr {
 	if p.trace {
 		defer un(trace(p, "ArrayType"))
 	}
 
+	if len == nil {
+		p.exprLev++
+		// always permit ellipsis for more fault-tolerant parsing
+		if p.tok == token.ELLIPSIS {
+			len = &ast.Ellipsis{Ellipsis: p.pos}
+			p.next()
+		} else if p.tok != token.RBRACK {
+			len = p.parseRhs()
+		}
+		p.exprLev--
+	}
+	p.expect(token.RBRACK)
+	elt := p.parseType()
+
+	return &ast.ArrayType{Lbrack: lbrack, Len: len, Elt: elt}
+}
+
+// parseArrayFieldOrTypeInstance parses what follows the name x of a
+// field or parameter if the name is followed by a '['. The result is
+// either the name and an array or slice type, as in "x [N]E", or a nil
+// name and an instantiated type, as in "x[P1, P2]". In the latter case
+// x is resolved.
+func (p *parser) parseArrayFieldOrTypeInstance(x *ast.Ident) (*ast.Ident, ast.Expr) {
+	if p.trace {
+		defer un(trace(p, "ArrayFieldOrTypeInstance"))
+	}
+
+	lbrack := p.expect(token.LBRACK)
+	var args []ast.Expr
+	if p.tok != token.RBRACK {
+		p.exprLev++
+		for {
+			if p.tok == token.ELLIPSIS {
+				// always permit ellipsis for more fault-tolerant parsing
+				args = append(args, &ast.Ellipsis{Ellipsis: p.pos})
+				p.next()
+			} else {
+				args = append(args, p.parseRhsOrType())
+			}
+			if p.tok != token.COMMA {
+				break
+			}
+			p.next()
+			if p.tok == token.RBRACK {
+				break
+			}
+		}
+		p.exprLev--
+	}
+	rbrack := p.expect(token.RBRACK)
+
+	if len(args) == 0 {
+		// x []E
+		elt := p.parseType()
+		return x, &ast.ArrayType{Lbrack: lbrack, Elt: elt}
+	}
+
+	if len(args) == 1 {
+		if elt := p.tryType(); elt != nil {
+			// x [N]E
+			return x, &ast.ArrayType{Lbrack: lbrack, Len: args[0], Elt: elt}
+		}
+	}
+
+	// x[P1, P2, ...]
+	p.resolve(x)
+	return nil, packIndexExpr(x, lbrack, args, rbrack)
+}
+
+// parseTypeInstance parses the type argument list following the
+// generic type typ. If typ is an identifier, it is resolved.
+func (p *parser) parseTypeInstance(typ ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "TypeInstance"))
+	}
+
+	p.resolve(typ)
 	lbrack := p.expect(token.LBRACK)
 	p.exprLev++
-	var len ast.Expr
-	// always permit ellipsis for more fault-tolerant parsing
-	if p.tok == token.ELLIPSIS {
-		len = &ast.Ellipsis{Ellipsis: p.pos}
+	var list []ast.Expr
+	for p.tok != token.RBRACK && p.tok != token.EOF {
+		list = append(list, p.parseType())
+		if !p.atComma("type argument list", token.RBRACK) {
+			break
+		}
 		p.next()
-	} else if p.tok != token.RBRACK {
-		len = p.parseRhs()
 	}
 	p.exprLev--
-	p.expect(token.RBRACK)
-	elt := p.parseType()
+	rbrack := p.expectClosing(token.RBRACK, "type argument list")
 
-	return &ast.ArrayType{Lbrack: lbrack, Len: len, Elt: elt}
+	if len(list) == 0 {
+		p.errorExpected(rbrack, "type argument list")
+		return &ast.IndexExpr{X: typ, Lbrack: lbrack, Index: &ast.BadExpr{From: lbrack + 1, To: rbrack}, Rbrack: rbrack}
+	}
+
+	return packIndexExpr(typ, lbrack, list, rbrack)
+}
+
+// packIndexExpr returns an IndexExpr for a single index and an
+// IndexListExpr for multiple indices.
+func packIndexExpr(x ast.Expr, lbrack token.Pos, indices []ast.Expr, rbrack token.Pos) ast.Expr {
+	if len(indices) == 1 {
+		return &ast.IndexExpr{X: x, Lbrack: lbrack, Index: indices[0], Rbrack: rbrack}
+	}
+	return &ast.IndexListExpr{X: x, Lbrack: lbrack, Indices: indices, Rbrack: rbrack}
 }
 
 func (p *parser) makeIdentList(list []ast.Expr) []*ast.Ident {
@@ -699,15 +806,20 @@ func (p *parser) parseFieldDecl(scope *ast.Scope) *ast.Field {
 	// 1st FieldDecl
 	// A type name used as an anonymous field looks like a field identifier.
 	var list []ast.Expr
+	var typ ast.Expr
 	for {
-		list = append(list, p.parseVarType(false))
-		if p.tok != token.COMMA {
+		var x ast.Expr
+		x, typ = p.parseVarTypeOrArrayField(false)
+		list = append(list, x)
+		if typ != nil || p.tok != token.COMMA {
 			break
 		}
 		p.next()
 	}
 
-	typ := p.tryVarType(false)
+	if typ == nil {
+		typ = p.tryVarType(false)
+	}
 
 	// analyze case
 	var idents []*ast.Ident
@@ -720,7 +832,7 @@ func (p *parser) parseFieldDecl(scope *ast.Scope) *ast.Field {
 		if n := len(list); n > 1 {
 			p.errorExpected(p.pos, "type")
 			typ = &ast.BadExpr{From: p.pos, To: p.pos}
-		} else if !isTypeName(deref(typ)) {
+		} else if !isTypeName(unindex(deref(typ))) {
 			p.errorExpected(typ.Pos(), "anonymous field")
 			typ = &ast.BadExpr{From: typ.Pos(), To: p.safePos(typ.End())}
 		}
@@ -809,6 +921,30 @@ func (p *parser) parseVarType(isParam bool) ast.Expr {
 	return typ
 }
 
+// parseVarTypeOrArrayField is like parseVarType but it also accepts a
+// field or parameter name followed by an array or slice type, which
+// cannot be told apart from an instantiated type until after the
+// closing ']'. In that case the name is returned as x and the array
+// type as typ; otherwise typ is nil.
+// If x is an identifier, it is not resolved.
+func (p *parser) parseVarTypeOrArrayField(isParam bool) (x, typ ast.Expr) {
+	if p.tok != token.IDENT {
+		return p.parseVarType(isParam), nil
+	}
+	x = p.parseTypeName()
+	if p.tok == token.LBRACK {
+		if ident, isIdent := x.(*ast.Ident); isIdent {
+			name, t := p.parseArrayFieldOrTypeInstance(ident)
+			if name != nil {
+				return name, t
+			}
+			return t, nil
+		}
+		return p.parseTypeInstance(x), nil
+	}
+	return x, nil
+}
+
 func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params []*ast.Field) {
 	if p.trace {
 		defer un(trace(p, "ParameterList"))
@@ -817,9 +953,12 @@ func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params [
 	// 1st ParameterDecl
 	// A list of identifiers looks like a list of type names.
 	var list []ast.Expr
+	var typ ast.Expr
 	for {
-		list = append(list, p.parseVarType(ellipsisOk))
-		if p.tok != token.COMMA {
+		var x ast.Expr
+		x, typ = p.parseVarTypeOrArrayField(ellipsisOk)
+		list = append(list, x)
+		if typ != nil || p.tok != token.COMMA {
 			break
 		}
 		p.next()
@@ -829,7 +968,10 @@ func (p *parser) parseParameterList(scope *ast.Scope, ellipsisOk bool) (params [
 	}
 
 	// analyze case
-	if typ := p.tryVarType(ellipsisOk); typ != nil {
+	if typ == nil {
+		typ = p.tryVarType(ellipsisOk)
+	}
+	if typ != nil {
 		// IdentifierList Type
 		idents := p.makeIdentList(list)
 		field := &ast.Field{Names: idents, Type: typ}
@@ -913,6 +1055,110 @@ func (p *parser) parseSignature(scope *ast.Scope) (params, results *ast.FieldLis
 	return
 }
 
+// parseTypeParams parses a type parameter list and declares the type
+// parameters in scope.
+func (p *parser) parseTypeParams(scope *ast.Scope) *ast.FieldList {
+	if p.trace {
+		defer un(trace(p, "TypeParams"))
+	}
+
+	lbrack := p.expect(token.LBRACK)
+	var list []*ast.Field
+	if p.tok != token.RBRACK {
+		list = p.parseTypeParameterList(scope, nil, nil)
+	} else {
+		p.error(p.pos, "empty type parameter list")
+	}
+	rbrack := p.expect(token.RBRACK)
+
+	return &ast.FieldList{Opening: lbrack, List: list, Closing: rbrack}
+}
+
+// parseTypeParameterList parses the type parameter declarations up to
+// the closing ']'. If name0 is not nil, it is the already parsed name
+// of the first type parameter, and typ0, if not nil, its constraint.
+// The type parameters are declared in scope, which is also used for
+// resolving the constraints so that they may refer to type parameters.
+func (p *parser) parseTypeParameterList(scope *ast.Scope, name0 *ast.Ident, typ0 ast.Expr) (list []*ast.Field) {
+	if p.trace {
+		defer un(trace(p, "TypeParameterList"))
+	}
+
+	old := p.topScope
+	p.topScope = scope
+	for {
+		var idents []*ast.Ident
+		typ := typ0
+		if name0 != nil {
+			idents = []*ast.Ident{name0}
+			if typ == nil {
+				for p.tok == token.COMMA {
+					p.next()
+					idents = append(idents, p.parseIdent())
+				}
+			}
+			name0, typ0 = nil, nil
+		} else {
+			idents = p.parseIdentList()
+		}
+		field := &ast.Field{Names: idents}
+		p.declare(field, nil, scope, ast.Typ, idents...)
+		if typ == nil {
+			typ = p.parseConstraint()
+		}
+		field.Type = typ
+		list = append(list, field)
+		if !p.atComma("type parameter list", token.RBRACK) {
+			break
+		}
+		p.next()
+		if p.tok == token.RBRACK {
+			break
+		}
+	}
+	p.topScope = old
+
+	return
+}
+
+func (p *parser) parseConstraint() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "Constraint"))
+	}
+
+	switch p.tok {
+	case token.COMMA, token.RBRACK:
+		pos := p.pos
+		p.errorExpected(pos, "type constraint")
+		return &ast.BadExpr{From: pos, To: pos}
+	}
+	return p.parseTypeElem(nil)
+}
+
+// declareRecvTypeParams declares the type parameters of a generic
+// receiver type, as in "func (r *T[P, Q]) m()", in scope. It reports
+// whether the receiver declared any type parameters.
+func (p *parser) declareRecvTypeParams(recv *ast.FieldList, scope *ast.Scope) bool {
+	if recv == nil || len(recv.List) != 1 {
+		return false
+	}
+	field := recv.List[0]
+	var indices []ast.Expr
+	switch t := unparen(deref(unparen(field.Type))).(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+	for _, x := range indices {
+		if ident, isIdent := x.(*ast.Ident); isIdent {
+			p.unresolve(ident)
+			p.declare(field, nil, scope, ast.Typ, ident)
+		}
+	}
+	return len(indices) > 0
+}
+
 func (p *parser) parseFuncType() (*ast.FuncType, *ast.Scope) {
 	if p.trace {
 		defer un(trace(p, "FuncType"))
@@ -941,9 +1187,14 @@ func (p *parser) parseMethodSpec(scope *ast.Scope) *ast.Field {
 		params, results := p.parseSignature(scope)
 		typ = &ast.FuncType{Func: token.NoPos, Params: params, Results: results}
 	} else {
-		// embedded interface
+		// embedded interface or type element
 		typ = x
-		p.resolve(typ)
+		if p.tok == token.LBRACK {
+			typ = p.parseTypeInstance(typ)
+		} else {
+			p.resolve(typ)
+		}
+		typ = p.parseTypeElem(typ)
 	}
 	p.expectSemi() // call before accessing p.linecomment
 
@@ -962,8 +1213,24 @@ func (p *parser) parseInterfaceType() *ast.InterfaceType {
 	lbrace := p.expect(token.LBRACE)
 	scope := ast.NewScope(nil) // interface scope
 	var list []*ast.Field
-	for p.tok == token.IDENT {
-		list = append(list, p.parseMethodSpec(scope))
+L:
+	for {
+		switch p.tok {
+		case token.IDENT:
+			list = append(list, p.parseMethodSpec(scope))
+		case token.TILDE:
+			typ := p.parseTypeElem(nil)
+			p.expectSemi() // call before accessing p.linecomment
+			list = append(list, &ast.Field{Type: typ, Comment: p.lineComment})
+		default:
+			t := p.tryType()
+			if t == nil {
+				break L
+			}
+			typ := p.parseTypeElem(t)
+			p.expectSemi() // call before accessing p.linecomment
+			list = append(list, &ast.Field{Type: typ, Comment: p.lineComment})
+		}
 	}
 	rbrace := p.expect(token.RBRACE)
 
@@ -977,6 +1244,47 @@ func (p *parser) parseInterfaceType() *ast.InterfaceType {
 	}
 }
 
+// parseTypeElem parses a type element of the form T1 | ~T2 | ...
+// If x is not nil, it is the already parsed (and resolved) first term.
+func (p *parser) parseTypeElem(x ast.Expr) ast.Expr {
+	if p.trace {
+		defer un(trace(p, "TypeElem"))
+	}
+
+	if x == nil {
+		x = p.parseTypeTerm()
+	}
+	for p.tok == token.OR {
+		pos := p.pos
+		p.next()
+		y := p.parseTypeTerm()
+		x = &ast.BinaryExpr{X: x, OpPos: pos, Op: token.OR, Y: y}
+	}
+	return x
+}
+
+func (p *parser) parseTypeTerm() ast.Expr {
+	if p.trace {
+		defer un(trace(p, "TypeTerm"))
+	}
+
+	if p.tok == token.TILDE {
+		pos := p.pos
+		p.next()
+		typ := p.parseType()
+		return &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: typ}
+	}
+
+	typ := p.tryType()
+	if typ == nil {
+		pos := p.pos
+		p.errorExpected(pos, "~ term or type")
+		p.advance(exprEnd)
+		return &ast.BadExpr{From: pos, To: p.pos}
+	}
+	return typ
+}
+
 func (p *parser) parseMapType() *ast.MapType {
 	if p.trace {
 		defer un(trace(p, "MapType"))
@@ -1020,9 +1328,14 @@ func (p *parser) parseChanType() *ast.ChanType {
 func (p *parser) tryIdentOrType() ast.Expr {
 	switch p.tok {
 	case token.IDENT:
-		return p.parseTypeName()
+		typ := p.parseTypeName()
+		if p.tok == token.LBRACK {
+			typ = p.parseTypeInstance(typ)
+		}
+		return typ
 	case token.LBRACK:
-		return p.parseArrayType()
+		lbrack := p.expect(token.LBRACK)
+		return p.parseArrayType(lbrack, nil)
 	case token.STRUCT:
 		return p.parseStructType()
 	case token.MUL:
@@ -1209,16 +1522,34 @@ func (p *parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
 	p.exprLev++
 	var index [N]ast.Expr
 	var colons [N - 1]token.Pos
+	var args []ast.Expr // type arguments of an instantiation
 	if p.tok != token.COLON {
-		index[0] = p.parseRhs()
+		// the index may be a type argument
+		index[0] = p.parseRhsOrType()
 	}
 	ncolons := 0
-	for p.tok == token.COLON && ncolons < len(colons) {
-		colons[ncolons] = p.pos
-		ncolons++
-		p.next()
-		if p.tok != token.COLON && p.tok != token.RBRACK && p.tok != token.EOF {
-			index[ncolons] = p.parseRhs()
+	switch p.tok {
+	case token.COLON:
+		// slice expression; the low index must not be a type
+		if index[0] != nil {
+			index[0] = p.checkExpr(index[0])
+		}
+		for p.tok == token.COLON && ncolons < len(colons) {
+			colons[ncolons] = p.pos
+			ncolons++
+			p.next()
+			if p.tok != token.COLON && p.tok != token.RBRACK && p.tok != token.EOF {
+				index[ncolons] = p.parseRhs()
+			}
+		}
+	case token.COMMA:
+		// instantiation with multiple type arguments
+		args = append(args, index[0])
+		for p.tok == token.COMMA {
+			p.next()
+			if p.tok != token.RBRACK && p.tok != token.EOF {
+				args = append(args, p.parseType())
+			}
 		}
 	}
 	p.exprLev--
@@ -1243,6 +1574,10 @@ func (p *parser) parseIndexOrSlice(x ast.Expr) ast.Expr {
 		return &ast.SliceExpr{X: x, Lbrack: lbrack, Low: index[0], High: index[1], Max: index[2], Slice3: slice3, Rbrack: rbrack}
 	}
 
+	if len(args) > 0 {
+		return packIndexExpr(x, lbrack, args, rbrack)
+	}
+
 	return &ast.IndexExpr{X: x, Lbrack: lbrack, Index: index[0], Rbrack: rbrack}
 }
 
@@ -1373,6 +1708,7 @@ func (p *parser) checkExpr(x ast.Expr) ast.Expr {
 		panic("unreachable")
 	case *ast.SelectorExpr:
 	case *ast.IndexExpr:
+	case *ast.IndexListExpr:
 	case *ast.SliceExpr:
 	case *ast.TypeAssertExpr:
 		// If t.Type == nil we have a type assertion of the form
@@ -1414,6 +1750,8 @@ func isLiteralType(x ast.Expr) bool {
 	case *ast.SelectorExpr:
 		_, isIdent := t.X.(*ast.Ident)
 		return isIdent
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return isTypeName(unindex(t))
 	case *ast.ArrayType:
 	case *ast.StructType:
 	case *ast.MapType:
@@ -1423,6 +1761,17 @@ func isLiteralType(x ast.Expr) bool {
 	return true
 }
 
+// If x is of the form T[P1, P2, ...], unindex returns T, otherwise it returns x.
+func unindex(x ast.Expr) ast.Expr {
+	switch t := x.(type) {
+	case *ast.IndexExpr:
+		x = t.X
+	case *ast.IndexListExpr:
+		x = t.X
+	}
+	return x
+}
+
 // If x is of the form *T, deref returns T, otherwise it returns x.
 func deref(x ast.Expr) ast.Expr {
 	if p, isPtr := x.(*ast.StarExpr); isPtr {
@@ -1458,13 +1807,16 @@ func (p *parser) checkExprOrType(x ast.Expr) ast.Expr {
 	return x
 }
 
+// If x is not nil, it is the already parsed operand.
 // If lhs is set and the result is an identifier, it is not resolved.
-func (p *parser) parsePrimaryExpr(lhs bool) ast.Expr {
+func (p *parser) parsePrimaryExpr(x ast.Expr, lhs bool) ast.Expr {
 	if p.trace {
 		defer un(trace(p, "PrimaryExpr"))
 	}
 
-	x := p.parseOperand(lhs)
+	if x == nil {
+		x = p.parseOperand(lhs)
+	}
 L:
 	for {
 		switch p.tok {
@@ -1496,7 +1848,10 @@ L:
 			}
 			x = p.parseCallOrConversion(p.checkExprOrType(x))
 		case token.LBRACE:
-			if isLiteralType(x) && (p.exprLev >= 0 || !isTypeName(x)) {
+			// An index expression could be an instantiated type; just
+			// like a type name it can't start a composite literal at
+			// the outermost level of a control clause.
+			if isLiteralType(x) && (p.exprLev >= 0 || !isTypeName(unindex(x))) {
 				if lhs {
 					p.resolve(x)
 				}
@@ -1580,7 +1935,7 @@ func (p *parser) parseUnaryExpr(lhs bool) ast.Expr {
 		return &ast.StarExpr{Star: pos, X: p.checkExprOrType(x)}
 	}
 
-	return p.parsePrimaryExpr(lhs)
+	return p.parsePrimaryExpr(nil, lhs)
 }
 
 func (p *parser) tokPrec() (token.Token, int) {
@@ -1591,13 +1946,17 @@ func (p *parser) tokPrec() (token.Token, int) {
 	return tok, tok.Precedence()
 }
 
+// If x is not nil, it is the already parsed first unary expression.
 // If lhs is set and the result is an identifier, it is not resolved.
-func (p *parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
+// If check is not set, the operands may be types.
+func (p *parser) parseBinaryExpr(x ast.Expr, lhs bool, prec1 int, check bool) ast.Expr {
 	if p.trace {
 		defer un(trace(p, "BinaryExpr"))
 	}
 
-	x := p.parseUnaryExpr(lhs)
+	if x == nil {
+		x = p.parseUnaryExpr(lhs)
+	}
 	for {
 		op, oprec := p.tokPrec()
 		if oprec < prec1 {
@@ -1608,8 +1967,11 @@ func (p *parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
 			p.resolve(x)
 			lhs = false
 		}
-		y := p.parseBinaryExpr(false, oprec+1)
-		x = &ast.BinaryExpr{X: p.checkExpr(x), OpPos: pos, Op: op, Y: p.checkExpr(y)}
+		y := p.parseBinaryExpr(nil, false, oprec+1, check)
+		if check {
+			x, y = p.checkExpr(x), p.checkExpr(y)
+		}
+		x = &ast.BinaryExpr{X: x, OpPos: pos, Op: op, Y: y}
 	}
 }
 
@@ -1622,7 +1984,7 @@ func (p *parser) parseExpr(lhs bool) ast.Expr {
 		defer un(trace(p, "Expression"))
 	}
 
-	return p.parseBinaryExpr(lhs, token.LowestPrec+1)
+	return p.parseBinaryExpr(nil, lhs, token.LowestPrec+1, true)
 }
 
 func (p *parser) parseRhs() ast.Expr {
@@ -2376,17 +2738,120 @@ func (p *parser) parseTypeSpec(doc *ast.CommentGroup, _ token.Token, _ int) ast.
 	// (Global identifiers are resolved in a separate phase after parsing.)
 	spec := &ast.TypeSpec{Doc: doc, Name: ident}
 	p.declare(spec, nil, p.topScope, ast.Typ, ident)
-	if p.tok == token.ASSIGN {
-		spec.Assign = p.pos
+	if p.tok == token.LBRACK {
+		// array or slice type, or type parameter list
+		lbrack := p.pos
 		p.next()
+		if p.tok == token.IDENT {
+			// We may have an array length expression or the first
+			// type parameter, possibly followed by its constraint.
+			// A name followed by '[' can't be an array length (which
+			// must be constant), so it must be a type parameter with
+			// an array or slice constraint.
+			p.exprLev++
+			x := ast.Expr(p.parseIdent())
+			if p.tok != token.LBRACK {
+				x = p.parseBinaryExpr(p.parsePrimaryExpr(x, true), true, token.LowestPrec+1, false)
+			}
+			p.exprLev--
+			if pname, ptype := extractName(x, p.tok == token.COMMA); pname != nil && (ptype != nil || p.tok != token.RBRACK) {
+				p.unresolve(pname)
+				p.openScope()
+				list := p.parseTypeParameterList(p.topScope, pname, ptype)
+				rbrack := p.expect(token.RBRACK)
+				spec.TypeParams = &ast.FieldList{Opening: lbrack, List: list, Closing: rbrack}
+				p.parseTypeSpecType(spec)
+				p.closeScope()
+			} else {
+				p.resolve(x)
+				spec.Type = p.parseArrayType(lbrack, p.checkExpr(x))
+			}
+		} else {
+			spec.Type = p.parseArrayType(lbrack, nil)
+		}
+	} else {
+		p.parseTypeSpecType(spec)
 	}
-	spec.Type = p.parseType()
 	p.expectSemi() // call before accessing p.linecomment
 	spec.Comment = p.lineComment
 
 	return spec
 }
 
+func (p *parser) parseTypeSpecType(spec *ast.TypeSpec) {
+	if p.tok == token.ASSIGN {
+		spec.Assign = p.pos
+		p.next()
+	}
+	spec.Type = p.parseType()
+}
+
+// extractName splits the expression x into (name, expr) if syntactically
+// x can be written as name expr. The split only happens if expr is a type
+// element (per the isTypeElem predicate) or if force is set.
+// If x is just a name, the result is (name, nil). If the split succeeds,
+// the result is (name, expr). Otherwise the result is (nil, x).
+// Examples:
+//
+//	x           force    name    expr
+//	------------------------------------
+//	P*[]int     T/F      P       *[]int
+//	P*E         T        P       *E
+//	P*E         F        nil     P*E
+//	P([]int)    T/F      P       ([]int)
+//	P(E)        T        P       (E)
+//	P(E)        F        nil     P(E)
+//	P*E|F|~G    T/F      P       *E|F|~G
+//	P*E|F|G     T        P       *E|F|G
+//	P*E|F|G     F        nil     P*E|F|G
+//
+func extractName(x ast.Expr, force bool) (*ast.Ident, ast.Expr) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return x, nil
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.MUL:
+			if name, _ := x.X.(*ast.Ident); name != nil && (force || isTypeElem(x.Y)) {
+				// x = name *x.Y
+				return name, &ast.StarExpr{Star: x.OpPos, X: x.Y}
+			}
+		case token.OR:
+			if name, lhs := extractName(x.X, force || isTypeElem(x.Y)); name != nil && lhs != nil {
+				// x = name lhs|x.Y
+				op := *x
+				op.X = lhs
+				return name, &op
+			}
+		}
+	case *ast.CallExpr:
+		if name, _ := x.Fun.(*ast.Ident); name != nil {
+			if len(x.Args) == 1 && x.Ellipsis == token.NoPos && (force || isTypeElem(x.Args[0])) {
+				// x = name (x.Args[0])
+				return name, &ast.ParenExpr{Lparen: x.Lparen, X: x.Args[0], Rparen: x.Rparen}
+			}
+		}
+	}
+	return nil, x
+}
+
+// isTypeElem reports whether x is a (possibly parenthesized) type element
+// expression. The result is false if x could be a type element or an
+// ordinary (value) expression.
+func isTypeElem(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.ArrayType, *ast.StructType, *ast.FuncType, *ast.InterfaceType, *ast.MapType, *ast.ChanType:
+		return true
+	case *ast.BinaryExpr:
+		return isTypeElem(x.X) || isTypeElem(x.Y)
+	case *ast.UnaryExpr:
+		return x.Op == token.TILDE
+	case *ast.ParenExpr:
+		return isTypeElem(x.X)
+	}
+	return false
+}
+
 func (p *parser) parseGenDecl(keyword token.Token, f parseSpecFunction) *ast.GenDecl {
 	if p.trace {
 		defer un(trace(p, "GenDecl("+keyword.String()+")"))
@@ -2431,10 +2896,27 @@ func (p *parser) parseFuncDecl() *ast.FuncDecl {
 	if p.tok == token.LPAREN {
 		recv = p.parseParameters(scope, false)
 	}
+	generic := p.declareRecvTypeParams(recv, scope)
 
 	ident := p.parseIdent()
 
+	var tparams *ast.FieldList
+	if p.tok == token.LBRACK {
+		tparams = p.parseTypeParams(scope)
+		if recv != nil {
+			p.error(tparams.Opening, "method must have no type parameters")
+		}
+		generic = true
+	}
+
+	// Type parameters are declared in the function scope; they must
+	// be visible when resolving the parameter and result types.
+	old := p.topScope
+	if generic {
+		p.topScope = scope
+	}
 	params, results := p.parseSignature(scope)
+	p.topScope = old
 
 	var body *ast.BlockStmt
 	if p.tok == token.LBRACE {
@@ -2447,9 +2929,10 @@ func (p *parser) parseFuncDecl() *ast.FuncDecl {
 		Recv: recv,
 		Name: ident,
 		Type: &ast.FuncType{
-			Func:    pos,
-			Params:  params,
-			Results: results,
+			Func:       pos,
+			TypeParams: tparams,
+			Params:     params,
+			Results:    results,
 		},
 		Body: body,
 	}
@@ -48,6 +48,17 @@ var valids = []string{
 	`package p; var _ = map[*P]int{&P{}:0, {}:1}`,
 	`package p; type T = int`,
 	`package p; type (T = p.T; _ = struct{}; x = *T)`,
+	`package p; type T[P any] struct { next *T[P]; val P }`,
+	`package p; type T[P1, P2 any, P3 interface{ ~int | ~string }] []P1`,
+	`package p; type T[P *C,] struct{}`,
+	`package p; type T [N]int; type S [N * M]int`,
+	`package p; type _ struct { T[int]; *p.T[int, string]; x [N]int; y []T[int] }`,
+	`package p; func f[P any, Q ~[]P](x P, y ...Q) {}`,
+	`package p; func (x *T[P]) m(y P) T[P] { return T[P]{} }`,
+	`package p; func _(T[P], []int, p.T[P, Q]) {}`,
+	`package p; func _() { _ = f[int]; _ = f[int, string](0, ""); _ = T[int]{}; _ = a[i] }`,
+	`package p; func _() { switch x[i] {}; if x == (T[int]{}) {}; for range x[i] {} }`,
+	`package p; type I interface { m(); E; ~int | string; C[int] }`,
 }
 
 func TestValid(t *testing.T) {
@@ -124,6 +135,11 @@ var invalids = []string{
 	`package p; func _()(x, y, z ... /* ERROR "expected '\)', found '...'" */ int){}`,
 	`package p; func _()(... /* ERROR "expected type, found '...'" */ int){}`,
 
+	// type parameters
+	`package p; func f[] /* ERROR "empty type parameter list" */ () {}`,
+	`package p; func f[P] /* ERROR "expected type constraint" */ () {}`,
+	`package p; func (T) m[ /* ERROR "method must have no type parameters" */ P any]() {}`,
+
 	// issue 13475
 	`package p; func f() { if true {} else ; /* ERROR "expected if statement or block" */ }`,
 	`package p; func f() { if true {} else defer /* ERROR "expected if statement or block" */ f() }`,
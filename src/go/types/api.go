@@ -173,6 +173,20 @@ type Info struct {
 	// Invariant: Uses[id].Pos() != id.Pos()
 	Uses map[*ast.Ident]Object
 
+	// Instances maps identifiers denoting generic types or functions to
+	// their type arguments and instantiated type.
+	//
+	// For example, Instances maps the identifier for 'T' in the type
+	// instantiation T[int, string] to the type arguments [int, string]
+	// and the resulting instantiated *Named type. Given a generic
+	// function func F[A any](A), Instances maps the identifier for 'F'
+	// in the call expression F(int(1)) to the inferred type arguments
+	// [int] and the resulting instantiated *Signature.
+	//
+	// Invariant: Instantiating Uses[id].Type() with Instances[id].TypeArgs
+	// results in an equivalent of Instances[id].Type.
+	Instances map[*ast.Ident]Instance
+
 	// Implicits maps nodes to their implicitly declared objects, if any.
 	// The following node and object types may appear:
 	//
@@ -196,7 +210,9 @@ type Info struct {
 	// function scopes which in turn enclose statement and function literal scopes.
 	// Note that even though package-level functions are declared in the package
 	// scope, the function scopes are embedded in the file scope of the file
-	// containing the function declaration.
+	// containing the function declaration. The type parameters of generic
+	// functions and types are declared in (unrecorded) scopes enclosing
+	// the respective function scope or type expression.
 	//
 	// The following node types may appear in Scopes:
 	//
@@ -221,6 +237,15 @@ type Info struct {
 	InitOrder []*Initializer
 }
 
+// Instance reports the type arguments and instantiated type for type and
+// function instantiations. For type instantiations, Type is of dynamic
+// type *Named. For function instantiations, Type is of dynamic type
+// *Signature.
+type Instance struct {
+	TypeArgs *TypeList
+	Type     Type
+}
+
 // TypeOf returns the type of expression e, or nil if not found.
 // Precondition: the Types, Uses and Defs maps are populated.
 //
@@ -243,6 +243,32 @@ func TestTypesInfo(t *testing.T) {
 			`<-ch`,
 			`(string, bool)`,
 		},
+
+		// generic functions and types
+		{`package g0; func f[T any](x T) T { return x }; var _ = f(1)`,
+			`f`,
+			`func(x int) int`,
+		},
+		{`package g1; func f[T any](x T) T { return x }; var _ = f[string]`,
+			`f[string]`,
+			`func(x string) string`,
+		},
+		{`package g2; func f[T any](x T) T { return x }`,
+			`x`,
+			`T`,
+		},
+		{`package g3; type T[P any] struct{ f P }; var x T[int]`,
+			`T[int]`,
+			`g3.T[int]`,
+		},
+		{`package g4; type T[P any] struct{ f P }; var y T[int]; var x = y.f`,
+			`y.f`,
+			`int`,
+		},
+		{`package g5; func f[K comparable, V any](m map[K]V) []K { return nil }; var m map[string]bool; var _ = f(m)`,
+			`f(m)`,
+			`[]string`,
+		},
 	}
 
 	for _, test := range tests {
@@ -269,6 +295,73 @@ func TestTypesInfo(t *testing.T) {
 	}
 }
 
+func TestInstanceInfo(t *testing.T) {
+	var tests = []struct {
+		src   string
+		name  string
+		targs []string
+		typ   string
+	}{
+		{`package p0; func f[T any](T) {}; func _() { f(42) }`,
+			`f`,
+			[]string{`int`},
+			`func(int)`,
+		},
+		{`package p1; func f[A, B any](A, *B) {}; func _() { f[int](0, new(string)) }`,
+			`f`,
+			[]string{`int`, `string`},
+			`func(int, *string)`,
+		},
+		{`package p2; func f[A, B any](A, B) {}; var _ = f[int, bool]`,
+			`f`,
+			[]string{`int`, `bool`},
+			`func(int, bool)`,
+		},
+		{`package p3; type T[P any] struct{}; var _ T[int]`,
+			`T`,
+			[]string{`int`},
+			`p3.T[int]`,
+		},
+		{`package p4; type T[P, Q any] struct{}; var _ T[int, T[bool, string]]`,
+			`T`,
+			[]string{`int`, `p4.T[bool, string]`},
+			`p4.T[int, p4.T[bool, string]]`,
+		},
+	}
+
+	for _, test := range tests {
+		info := Info{Instances: make(map[*ast.Ident]Instance)}
+		name := mustTypecheck(t, "InstanceInfo", test.src, &info)
+
+		// look for the (outermost) instance of test.name
+		var inst Instance
+		found := false
+		for id, x := range info.Instances {
+			if id.Name == test.name && (!found || len(x.Type.String()) > len(inst.Type.String())) {
+				inst = x
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("package %s: no instance found for %s", name, test.name)
+			continue
+		}
+
+		if got := inst.TypeArgs.Len(); got != len(test.targs) {
+			t.Errorf("package %s: got %d type arguments; want %d", name, got, len(test.targs))
+			continue
+		}
+		for i, targ := range test.targs {
+			if got := inst.TypeArgs.At(i).String(); got != targ {
+				t.Errorf("package %s, type argument %d: got %s; want %s", name, i, got, targ)
+			}
+		}
+		if got := inst.Type.String(); got != test.typ {
+			t.Errorf("package %s: got type %s; want %s", name, got, test.typ)
+		}
+	}
+}
+
 func TestImplicitsInfo(t *testing.T) {
 	testenv.MustHaveGoBuild(t)
 
@@ -82,7 +82,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		// of S and the respective parameter passing rules apply."
 		S := x.typ
 		var T Type
-		if s, _ := S.Underlying().(*Slice); s != nil {
+		if s, _ := coreType(S).(*Slice); s != nil {
 			T = s.elem
 		} else {
 			check.invalidArg(x.pos(), "%s is not a slice", x)
@@ -139,7 +139,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		mode := invalid
 		var typ Type
 		var val constant.Value
-		switch typ = implicitArrayDeref(x.typ.Underlying()); t := typ.(type) {
+		switch typ = implicitArrayDeref(under(x.typ)); t := typ.(type) {
 		case *Basic:
 			if isString(t) && id == _Len {
 				if x.mode == constant_ {
@@ -172,6 +172,26 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 			if id == _Len {
 				mode = value
 			}
+
+		case *Interface:
+			// x may be a type parameter: len(x)/cap(x) are valid if they
+			// are valid for each specific type in x's type set
+			if tpar, _ := x.typ.(*TypeParam); tpar != nil {
+				if tpar.underIs(func(u Type) bool {
+					switch t := implicitArrayDeref(u).(type) {
+					case *Basic:
+						return isString(t) && id == _Len
+					case *Array, *Slice, *Chan:
+						return true
+					case *Map:
+						return id == _Len
+					}
+					return false
+				}) {
+					mode = value
+					typ = tpar // for recordBuiltinType
+				}
+			}
 		}
 
 		if mode == invalid && typ != Typ[Invalid] {
@@ -188,7 +208,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 
 	case _Close:
 		// close(c)
-		c, _ := x.typ.Underlying().(*Chan)
+		c, _ := coreType(x.typ).(*Chan)
 		if c == nil {
 			check.invalidArg(x.pos(), "%s is not a channel", x)
 			return
@@ -298,7 +318,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 	case _Copy:
 		// copy(x, y []T) int
 		var dst Type
-		if t, _ := x.typ.Underlying().(*Slice); t != nil {
+		if t, _ := coreType(x.typ).(*Slice); t != nil {
 			dst = t.elem
 		}
 
@@ -308,9 +328,9 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 			return
 		}
 		var src Type
-		switch t := y.typ.Underlying().(type) {
+		switch t := coreString(y.typ).(type) {
 		case *Basic:
-			if isString(y.typ) {
+			if isString(t) {
 				src = universeByte
 			}
 		case *Slice:
@@ -335,7 +355,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 
 	case _Delete:
 		// delete(m, k)
-		m, _ := x.typ.Underlying().(*Map)
+		m, _ := coreType(x.typ).(*Map)
 		if m == nil {
 			check.invalidArg(x.pos(), "%s is not a map", x)
 			return
@@ -422,13 +442,13 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 		// make(T, n, m)
 		// (no argument evaluated yet)
 		arg0 := call.Args[0]
-		T := check.typ(arg0)
+		T := check.varType(arg0)
 		if T == Typ[Invalid] {
 			return
 		}
 
 		var min int // minimum number of arguments
-		switch T.Underlying().(type) {
+		switch coreType(T).(type) {
 		case *Slice:
 			min = 2
 		case *Map, *Chan:
@@ -461,7 +481,7 @@ func (check *Checker) builtin(x *operand, call *ast.CallExpr, id builtinId) (_ b
 	case _New:
 		// new(T)
 		// (no argument evaluated yet)
-		T := check.typ(call.Args[0])
+		T := check.varType(call.Args[0])
 		if T == Typ[Invalid] {
 			return
 		}
@@ -664,7 +684,7 @@ func makeSig(res Type, args ...Type) *Signature {
 //
 func implicitArrayDeref(typ Type) Type {
 	if p, ok := typ.(*Pointer); ok {
-		if a, ok := p.base.Underlying().(*Array); ok {
+		if a, ok := under(p.base).(*Array); ok {
 			return a
 		}
 	}
@@ -11,8 +11,89 @@ import (
 	"go/token"
 )
 
+// funcInst type-checks a function instantiation e of the form f[T1, ..., Tn]
+// which is not called, and returns the result in x. The operand x must be
+// the evaluation of the generic function f. Missing type arguments are
+// inferred from the constraints, if possible.
+func (check *Checker) funcInst(x *operand, e ast.Expr, fun ast.Expr, xlist []ast.Expr) {
+	targs := check.typeList(xlist)
+	if targs == nil {
+		x.mode = invalid
+		x.expr = e
+		return
+	}
+	assert(len(targs) == len(xlist))
+
+	// check number of type arguments (got) vs number of type parameters (want)
+	sig := x.typ.(*Signature)
+	got, want := len(targs), sig.TypeParams().Len()
+	if got > want {
+		check.errorf(xlist[want].Pos(), "got %d type arguments but %s has %d type parameters", got, x.expr, want)
+		x.mode = invalid
+		x.expr = e
+		return
+	}
+
+	if got < want {
+		targs = check.infer(e.Pos(), sig.TypeParams().list(), targs, nil, nil)
+		if targs == nil {
+			// error was already reported
+			x.mode = invalid
+			x.expr = e
+			return
+		}
+		got = len(targs)
+	}
+	assert(got == want)
+
+	// instantiate function signature
+	res := check.instantiateSignature(e.Pos(), sig, targs, xlist)
+	check.recordInstance(fun, targs, res)
+	x.typ = res
+	x.mode = value
+	x.expr = e
+}
+
+// instantiateSignature instantiates the generic signature typ with the
+// type arguments targs. The type arguments are verified against their
+// constraints once all involved types are set up; errors are reported
+// at the position of the respective type argument in xlist, if any,
+// or at pos otherwise.
+func (check *Checker) instantiateSignature(pos token.Pos, typ *Signature, targs []Type, xlist []ast.Expr) *Signature {
+	inst := instance(typ, targs, check.ctxt).(*Signature)
+	assert(len(xlist) <= len(targs))
+
+	check.later(func() {
+		tparams := typ.TypeParams().list()
+		if i, err := check.verify(tparams, targs, check.ctxt); err != nil {
+			// best position for error reporting
+			pos := pos
+			if i < len(xlist) {
+				pos = xlist[i].Pos()
+			}
+			check.softErrorf(pos, "%s", err)
+		}
+	})
+
+	return inst
+}
+
 func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
-	check.exprOrType(x, e.Fun)
+	var xlist []ast.Expr // explicit type arguments of a generic function, if any
+	if base, indices := unpackIndexExpr(e.Fun); base != nil {
+		if check.indexExpr(x, e.Fun, base, indices) {
+			// Delay function instantiation to argument checking,
+			// where we combine type and value arguments for type
+			// inference.
+			assert(x.mode == value)
+			xlist = indices
+		} else if x.mode != invalid {
+			// record the type of the (regular) index expression
+			check.recordTypeAndValue(e.Fun, x.mode, x.typ, x.val)
+		}
+	} else {
+		check.exprOrType(x, e.Fun)
+	}
 
 	switch x.mode {
 	case invalid:
@@ -25,6 +106,12 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 		// conversion
 		T := x.typ
 		x.mode = invalid
+		if isGeneric(T) {
+			check.errorf(e.Fun.Pos(), "cannot use generic type %s without instantiation", T)
+			check.use(e.Args...)
+			x.expr = e
+			return conversion
+		}
 		switch n := len(e.Args); n {
 		case 0:
 			check.errorf(e.Rparen, "missing argument in conversion to %s", T)
@@ -53,7 +140,7 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 
 	default:
 		// function/method call
-		sig, _ := x.typ.Underlying().(*Signature)
+		sig, _ := coreType(x.typ).(*Signature)
 		if sig == nil {
 			check.invalidOp(x.pos(), "cannot call non-function %s", x)
 			x.mode = invalid
@@ -61,7 +148,34 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 			return statement
 		}
 
+		// evaluate type arguments, if any
+		var targs []Type
+		if xlist != nil {
+			targs = check.typeList(xlist)
+			if targs == nil {
+				check.use(e.Args...)
+				x.mode = invalid
+				x.expr = e
+				return statement
+			}
+			assert(len(targs) == len(xlist))
+
+			// check number of type arguments (got) vs number of type parameters (want)
+			got, want := len(targs), sig.TypeParams().Len()
+			if got > want {
+				check.errorf(xlist[want].Pos(), "got %d type arguments but %s has %d type parameters", got, x.expr, want)
+				check.use(e.Args...)
+				x.mode = invalid
+				x.expr = e
+				return statement
+			}
+		}
+
 		arg, n, _ := unpack(func(x *operand, i int) { check.multiExpr(x, e.Args[i]) }, len(e.Args), false)
+		if arg != nil && sig.TypeParams().Len() > 0 {
+			// generic function: infer missing type arguments and instantiate
+			sig, arg = check.genericCall(e, sig, targs, xlist, arg, n)
+		}
 		if arg != nil {
 			check.arguments(x, e, sig, arg, n)
 		} else {
@@ -87,6 +201,62 @@ func (check *Checker) call(x *operand, e *ast.CallExpr) exprKind {
 	}
 }
 
+// genericCall evaluates the n arguments provided by arg for the call of a
+// generic function with signature sig, infers the missing type arguments
+// (the provided type arguments are targs), and instantiates sig. It returns
+// the instantiated signature and a getter for the evaluated arguments. If
+// inference fails, the result getter is nil; errors are reported.
+func (check *Checker) genericCall(call *ast.CallExpr, sig *Signature, targs []Type, xlist []ast.Expr, arg getter, n int) (*Signature, getter) {
+	// evaluate all arguments
+	args := make([]*operand, n)
+	for i := range args {
+		var x operand
+		arg(&x, i)
+		args[i] = &x
+	}
+	argsGetter := func(x *operand, i int) { *x = *args[i] }
+
+	// collect the parameter types corresponding to the arguments;
+	// argument count errors are reported when checking the arguments
+	nparams := sig.params.Len()
+	var params []Type
+	for i := range args {
+		var typ Type
+		switch {
+		case sig.variadic && i >= nparams-1:
+			typ = sig.params.vars[nparams-1].typ
+			if !call.Ellipsis.IsValid() {
+				if s, _ := typ.(*Slice); s != nil {
+					typ = s.elem
+				}
+			}
+		case i < nparams:
+			typ = sig.params.vars[i].typ
+		}
+		if typ == nil {
+			break // too many arguments
+		}
+		params = append(params, typ)
+	}
+
+	targs = check.infer(call.Rparen, sig.TypeParams().list(), targs, params, args[:len(params)])
+	if targs == nil {
+		return sig, nil // error reported by infer
+	}
+
+	// compute result signature
+	fun, _ := unpackIndexExpr(call.Fun)
+	if fun == nil {
+		fun = call.Fun
+	}
+	rsig := check.instantiateSignature(call.Pos(), sig, targs, xlist)
+	check.recordInstance(fun, targs, rsig)
+	// update the recorded type of call.Fun to the instantiated function type
+	check.recordTypeAndValue(call.Fun, value, rsig, nil)
+
+	return rsig, argsGetter
+}
+
 // use type-checks each argument.
 // Useful to make sure expressions are evaluated
 // (and variables are "used") in the presence of other errors.
@@ -288,7 +458,7 @@ func (check *Checker) argument(fun ast.Expr, sig *Signature, i int, x *operand,
 			check.errorf(ellipsis, "can only use ... with matching parameter")
 			return
 		}
-		if _, ok := x.typ.Underlying().(*Slice); !ok && x.typ != Typ[UntypedNil] { // see issue #18268
+		if _, ok := coreType(x.typ).(*Slice); !ok && x.typ != Typ[UntypedNil] { // see issue #18268
 			check.errorf(x.pos(), "cannot use %s as parameter of type %s", x, typ)
 			return
 		}
@@ -367,6 +537,10 @@ func (check *Checker) selector(x *operand, e *ast.SelectorExpr) {
 	if x.mode == invalid {
 		goto Error
 	}
+	check.nonGeneric(x)
+	if x.mode == invalid {
+		goto Error
+	}
 
 	obj, index, indirect = LookupFieldOrMethod(x.typ, x.mode == variable, check.pkg, sel)
 	if obj == nil {
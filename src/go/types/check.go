@@ -78,6 +78,7 @@ type Checker struct {
 	*Info
 	objMap map[Object]*declInfo   // maps package-level object to declaration info
 	impMap map[importKey]*Package // maps (import path, source directory) to (complete or fake) package
+	ctxt   *Context               // context for de-duplicating instances
 
 	// information collected during type-checking of a set of package files
 	// (initialized by Files, valid only for the duration of check.Files;
@@ -192,6 +193,7 @@ func NewChecker(conf *Config, fset *token.FileSet, pkg *Package, info *Info) *Ch
 		Info:   info,
 		objMap: make(map[Object]*declInfo),
 		impMap: make(map[importKey]*Package),
+		ctxt:   NewContext(),
 	}
 }
 
@@ -293,7 +295,7 @@ func (check *Checker) recordTypeAndValue(x ast.Expr, mode operandMode, typ Type,
 	assert(typ != nil)
 	if mode == constant_ {
 		assert(val != nil)
-		assert(typ == Typ[Invalid] || isConstType(typ))
+		assert(typ == Typ[Invalid] || allBasic(typ, IsConstType))
 	}
 	if m := check.Types; m != nil {
 		m[x] = TypeAndValue{mode, typ, val}
@@ -359,6 +361,27 @@ func (check *Checker) recordUse(id *ast.Ident, obj Object) {
 	}
 }
 
+// recordInstance records the instantiation of the generic type or function
+// denoted by the (possibly qualified) identifier expr.
+func (check *Checker) recordInstance(expr ast.Expr, targs []Type, typ Type) {
+	m := check.Instances
+	if m == nil {
+		return
+	}
+	var ident *ast.Ident
+	switch e := unparen(expr).(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	default:
+		return // nothing to record
+	}
+	assert(ident != nil)
+	assert(typ != nil)
+	m[ident] = Instance{NewTypeList(targs), typ}
+}
+
 func (check *Checker) recordImplicit(node ast.Node, obj Object) {
 	assert(node != nil)
 	assert(obj != nil)
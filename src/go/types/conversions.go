@@ -15,6 +15,15 @@ func (check *Checker) conversion(x *operand, T Type) {
 
 	var ok bool
 	switch {
+	case constArg && isTypeParam(T):
+		// x is convertible to T if it is representable by each
+		// specific type in the type set of T (and there must be
+		// at least one). The result is not a constant.
+		ok = T.(*TypeParam).underIs(func(u Type) bool {
+			t, _ := u.(*Basic)
+			return t != nil && isConstType(t) && representableConst(x.val, check.conf, t, nil)
+		})
+		x.mode = value // type parameters are not constant types
 	case constArg && isConstType(T):
 		// constant conversion
 		switch t := T.Underlying().(*Basic); {
@@ -55,7 +64,7 @@ func (check *Checker) conversion(x *operand, T Type) {
 		// - Keep untyped nil for untyped nil arguments.
 		// - For integer to string conversions, keep the argument type.
 		//   (See also the TODO below.)
-		if IsInterface(T) || constArg && !isConstType(T) {
+		if isNonTypeParamInterface(T) || constArg && !isConstType(T) {
 			final = Default(x.typ)
 		} else if isInteger(x.typ) && isString(T) {
 			final = x.typ
@@ -82,19 +91,53 @@ func (x *operand) convertibleTo(conf *Config, T Type) bool {
 		return true
 	}
 
-	// "x's type and T have identical underlying types if tags are ignored"
+	// generic cases
+	// (generic operands cannot be constants, so we can ignore x.val)
 	V := x.typ
-	Vu := V.Underlying()
-	Tu := T.Underlying()
+	Vp, _ := V.(*TypeParam)
+	Tp, _ := T.(*TypeParam)
+	switch {
+	case Vp != nil && Tp != nil:
+		// each specific type in V's type set must be convertible
+		// to each specific type in T's type set
+		x := *x // don't clobber outer x
+		return Vp.is(func(V *term) bool {
+			if V == nil {
+				return false // no specific types
+			}
+			x.typ = V.typ
+			return Tp.is(func(T *term) bool {
+				return T != nil && x.convertibleTo(conf, T.typ)
+			})
+		})
+	case Vp != nil:
+		x := *x // don't clobber outer x
+		return Vp.is(func(V *term) bool {
+			if V == nil {
+				return false // no specific types
+			}
+			x.typ = V.typ
+			return x.convertibleTo(conf, T)
+		})
+	case Tp != nil:
+		return Tp.is(func(T *term) bool {
+			return T != nil && x.convertibleTo(conf, T.typ)
+		})
+	}
+
+	// "x's type and T have identical underlying types if tags are ignored"
+	Vu := under(V)
+	Tu := under(T)
 	if IdenticalIgnoreTags(Vu, Tu) {
 		return true
 	}
 
 	// "x's type and T are unnamed pointer types and their pointer base types
-	// have identical underlying types if tags are ignored"
+	// have identical underlying types if tags are ignored
+	// and their base types are not type parameters"
 	if V, ok := V.(*Pointer); ok {
 		if T, ok := T.(*Pointer); ok {
-			if IdenticalIgnoreTags(V.base.Underlying(), T.base.Underlying()) {
+			if IdenticalIgnoreTags(under(V.base), under(T.base)) && !isTypeParam(V.base) && !isTypeParam(T.base) {
 				return true
 			}
 		}
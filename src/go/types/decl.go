@@ -189,7 +189,7 @@ func (check *Checker) objDecl(obj Object, def *Named, path []*TypeName) {
 		check.varDecl(obj, d.lhs, d.typ, d.init)
 	case *TypeName:
 		// invalid recursive types are detected via path
-		check.typeDecl(obj, d.typ, def, path, d.alias)
+		check.typeDecl(obj, d.typ, d.tparams, def, path, d.alias)
 	case *Func:
 		// functions may be recursive - no need to track dependencies
 		check.funcDecl(obj, d)
@@ -290,7 +290,7 @@ func (check *Checker) varDecl(obj *Var, lhs []*Var, typ, init ast.Expr) {
 
 	// determine type, if any
 	if typ != nil {
-		obj.typ = check.typ(typ)
+		obj.typ = check.varType(typ)
 		// We cannot spread the type to all lhs variables if there
 		// are more than one since that would mark them as checked
 		// (see Checker.objDecl) and the assignment of init exprs,
@@ -354,7 +354,7 @@ func underlying(typ Type) Type {
 		if n == nil {
 			break
 		}
-		typ = n.underlying
+		typ = n.resolve().underlying
 	}
 	return typ
 }
@@ -365,11 +365,16 @@ func (n *Named) setUnderlying(typ Type) {
 	}
 }
 
-func (check *Checker) typeDecl(obj *TypeName, typ ast.Expr, def *Named, path []*TypeName, alias bool) {
+func (check *Checker) typeDecl(obj *TypeName, typ ast.Expr, tparams *ast.FieldList, def *Named, path []*TypeName, alias bool) {
 	assert(obj.typ == nil)
 
 	if alias {
 
+		if tparams != nil {
+			check.errorf(tparams.Pos(), "generic type cannot be alias")
+			// ignore type parameters and continue
+		}
+
 		obj.typ = Typ[Invalid]
 		obj.typ = check.typExpr(typ, nil, append(path, obj))
 
@@ -379,8 +384,31 @@ func (check *Checker) typeDecl(obj *TypeName, typ ast.Expr, def *Named, path []*
 		def.setUnderlying(named)
 		obj.typ = named // make sure recursive type declarations terminate
 
+		if tparams != nil {
+			// Instances of named are not expanded (cached) until the
+			// declaration, including its methods, is fully set up.
+			named.incomplete = true
+			defer func() {
+				named.incomplete = false
+			}()
+
+			// The type parameters are declared in their own scope
+			// which encloses the type expression.
+			check.scope = NewScope(check.scope, tparams.Pos(), typ.End(), "type parameters")
+			defer func() {
+				check.scope = check.scope.Parent()
+			}()
+			check.collectTypeParams(&named.tparams, tparams)
+		}
+
 		// determine underlying type of named
-		check.typExpr(typ, named, append(path, obj))
+		rhs := check.typExpr(typ, named, append(path, obj))
+
+		// spec: "In a type definition the given type cannot be a type parameter."
+		if isTypeParam(rhs) {
+			check.errorf(typ.Pos(), "cannot use a type parameter as RHS in type declaration")
+			named.underlying = Typ[Invalid]
+		}
 
 		// The underlying type of named may be itself a named type that is
 		// incomplete:
@@ -426,7 +454,7 @@ func (check *Checker) addMethodDecls(obj *TypeName) {
 	// and field names must be distinct."
 	base, _ := obj.typ.(*Named) // shouldn't fail but be conservative
 	if base != nil {
-		if t, _ := base.underlying.(*Struct); t != nil {
+		if t, _ := under(base).(*Struct); t != nil {
 			for _, fld := range t.fields {
 				if fld.name != "_" {
 					assert(mset.insert(fld) == nil)
@@ -484,6 +512,10 @@ func (check *Checker) funcDecl(obj *Func, decl *declInfo) {
 		check.errorf(fdecl.Pos(), "func init must have no arguments and no return values")
 		// ok to continue
 	}
+	if sig.recv == nil && (obj.name == "init" || obj.name == "main" && check.pkg.name == "main") && sig.tparams.Len() > 0 {
+		check.errorf(fdecl.Type.TypeParams.Pos(), "func %s must have no type parameters", obj.name)
+		// ok to continue
+	}
 
 	// function body must be type-checked after global declarations
 	// (functions implemented elsewhere have no body)
@@ -611,7 +643,11 @@ func (check *Checker) declStmt(decl ast.Decl) {
 				// the innermost containing block."
 				scopePos := s.Name.Pos()
 				check.declare(check.scope, s.Name, obj, scopePos)
-				check.typeDecl(obj, s.Type, nil, nil, s.Assign.IsValid())
+				if s.TypeParams != nil {
+					check.errorf(s.TypeParams.Pos(), "generic type cannot be declared inside a function")
+					// ignore type parameters and continue
+				}
+				check.typeDecl(obj, s.Type, nil, nil, nil, s.Assign.IsValid())
 
 			default:
 				check.invalidAST(s.Pos(), "const, type, or var declaration expected")
@@ -24,7 +24,8 @@ func unreachable() {
 }
 
 func (check *Checker) qualifier(pkg *Package) string {
-	if pkg != check.pkg {
+	// check may be nil when instantiating outside of type-checking
+	if check == nil || pkg != check.pkg {
 		return pkg.path
 	}
 	return ""
@@ -59,10 +59,10 @@ the type (and constant value, if any) is recorded via Info.Types, if present.
 type opPredicates map[token.Token]func(Type) bool
 
 var unaryOpPredicates = opPredicates{
-	token.ADD: isNumeric,
-	token.SUB: isNumeric,
-	token.XOR: isInteger,
-	token.NOT: isBoolean,
+	token.ADD: allNumeric,
+	token.SUB: allNumeric,
+	token.XOR: allInteger,
+	token.NOT: allBoolean,
 }
 
 func (check *Checker) op(m opPredicates, x *operand, op token.Token) bool {
@@ -94,7 +94,13 @@ func (check *Checker) unary(x *operand, e *ast.UnaryExpr, op token.Token) {
 		return
 
 	case token.ARROW:
-		typ, ok := x.typ.Underlying().(*Chan)
+		u := coreType(x.typ)
+		if u == nil {
+			check.invalidOp(x.pos(), "cannot receive from %s (no core type)", x)
+			x.mode = invalid
+			return
+		}
+		typ, ok := u.(*Chan)
 		if !ok {
 			check.invalidOp(x.pos(), "cannot receive from non-channel %s", x)
 			x.mode = invalid
@@ -448,7 +454,7 @@ func (check *Checker) updateExprType(x ast.Expr, typ Type, final bool) {
 		// If x is the lhs of a shift, its final type must be integer.
 		// We already know from the shift check that it is representable
 		// as an integer if it is a constant.
-		if !isInteger(typ) {
+		if !allInteger(typ) {
 			check.invalidOp(x.Pos(), "shifted operand %s (type %s) must be integer", x, typ)
 			return
 		}
@@ -498,7 +504,24 @@ func (check *Checker) convertUntyped(x *operand, target Type) {
 	}
 
 	// typed target
-	switch t := target.Underlying().(type) {
+	if tpar, _ := target.(*TypeParam); tpar != nil {
+		// x must be convertible to each specific type in tpar's
+		// type set (there must be at least one)
+		if !tpar.underIs(func(u Type) bool {
+			return u != nil && check.untypedConvertible(x, u)
+		}) {
+			goto Error
+		}
+		if x.isNil() {
+			// keep nil untyped - see comment for interfaces, below
+			target = Typ[UntypedNil]
+		}
+		x.typ = target
+		check.updateExprType(x.expr, target, true)
+		return
+	}
+
+	switch t := under(target).(type) {
 	case *Basic:
 		if x.mode == constant_ {
 			check.representable(x, t)
@@ -572,6 +595,31 @@ Error:
 	x.mode = invalid
 }
 
+// untypedConvertible reports whether the untyped operand x can be
+// converted to the (typed) underlying type u of a type set term.
+// Unlike convertUntyped, it has no side effects.
+func (check *Checker) untypedConvertible(x *operand, u Type) bool {
+	switch u := u.(type) {
+	case *Basic:
+		if x.mode == constant_ {
+			return representableConst(x.val, check.conf, u, nil)
+		}
+		switch x.typ.(*Basic).kind {
+		case UntypedBool:
+			return isBoolean(u)
+		case UntypedInt, UntypedRune, UntypedFloat, UntypedComplex:
+			return isNumeric(u)
+		case UntypedNil:
+			return hasNil(u)
+		}
+	case *Interface:
+		return x.isNil() || u.Empty()
+	case *Pointer, *Signature, *Slice, *Map, *Chan:
+		return x.isNil()
+	}
+	return false
+}
+
 func (check *Checker) comparison(x, y *operand, op token.Token) {
 	// spec: "In any comparison, the first operand must be assignable
 	// to the type of the second operand, or vice versa."
@@ -584,7 +632,7 @@ func (check *Checker) comparison(x, y *operand, op token.Token) {
 			defined = Comparable(x.typ) || x.isNil() && hasNil(y.typ) || y.isNil() && hasNil(x.typ)
 		case token.LSS, token.LEQ, token.GTR, token.GEQ:
 			// spec: The ordering operators <, <=, >, and >= apply to operands that are ordered."
-			defined = isOrdered(x.typ)
+			defined = allOrdered(x.typ)
 		default:
 			unreachable()
 		}
@@ -632,7 +680,7 @@ func (check *Checker) shift(x, y *operand, e *ast.BinaryExpr, op token.Token) {
 		xval = constant.ToInt(x.val)
 	}
 
-	if isInteger(x.typ) || untypedx && xval != nil && xval.Kind() == constant.Int {
+	if allInteger(x.typ) || untypedx && xval != nil && xval.Kind() == constant.Int {
 		// The lhs is of integer type or an untyped constant representable
 		// as an integer. Nothing to do.
 	} else {
@@ -646,7 +694,7 @@ func (check *Checker) shift(x, y *operand, e *ast.BinaryExpr, op token.Token) {
 	// integer type or be an untyped constant representable by a value of
 	// type uint."
 	switch {
-	case isUnsigned(y.typ):
+	case allUnsigned(y.typ):
 		// nothing to do
 	case isUntyped(y.typ):
 		check.convertUntyped(y, Typ[Uint])
@@ -744,19 +792,19 @@ func (check *Checker) shift(x, y *operand, e *ast.BinaryExpr, op token.Token) {
 }
 
 var binaryOpPredicates = opPredicates{
-	token.ADD: func(typ Type) bool { return isNumeric(typ) || isString(typ) },
-	token.SUB: isNumeric,
-	token.MUL: isNumeric,
-	token.QUO: isNumeric,
-	token.REM: isInteger,
-
-	token.AND:     isInteger,
-	token.OR:      isInteger,
-	token.XOR:     isInteger,
-	token.AND_NOT: isInteger,
-
-	token.LAND: isBoolean,
-	token.LOR:  isBoolean,
+	token.ADD: allNumericOrString,
+	token.SUB: allNumeric,
+	token.MUL: allNumeric,
+	token.QUO: allNumeric,
+	token.REM: allInteger,
+
+	token.AND:     allInteger,
+	token.OR:      allInteger,
+	token.XOR:     allInteger,
+	token.AND_NOT: allInteger,
+
+	token.LAND: allBoolean,
+	token.LOR:  allBoolean,
 }
 
 // The binary expression e may be nil. It's passed in for better error messages only.
@@ -812,7 +860,7 @@ func (check *Checker) binary(x *operand, e *ast.BinaryExpr, lhs, rhs ast.Expr, o
 
 	if op == token.QUO || op == token.REM {
 		// check for zero divisor
-		if (x.mode == constant_ || isInteger(x.typ)) && y.mode == constant_ && constant.Sign(y.val) == 0 {
+		if (x.mode == constant_ || allInteger(x.typ)) && y.mode == constant_ && constant.Sign(y.val) == 0 {
 			check.invalidOp(y.pos(), "division by zero")
 			x.mode = invalid
 			return
@@ -1059,7 +1107,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 					// We have an "open" [...]T array type.
 					// Create a new ArrayType with unknown length (-1)
 					// and finish setting it up after analyzing the literal.
-					typ = &Array{len: -1, elem: check.typ(atyp.Elt)}
+					typ = &Array{len: -1, elem: check.varType(atyp.Elt)}
 					base = typ
 					break
 				}
@@ -1070,7 +1118,11 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 		case hint != nil:
 			// no composite literal type present - use hint (element type of enclosing type)
 			typ = hint
-			base, _ = deref(typ.Underlying()) // *T implies &T{}
+			base, _ = deref(coreType(typ)) // *T implies &T{}
+			if base == nil {
+				check.errorf(e.Pos(), "invalid composite literal element type %s: no core type", typ)
+				goto Error
+			}
 
 		default:
 			// TODO(gri) provide better error messages depending on context
@@ -1078,7 +1130,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 			goto Error
 		}
 
-		switch utyp := base.Underlying().(type) {
+		switch utyp := coreType(base).(type) {
 		case *Struct:
 			if len(e.Elts) == 0 {
 				break
@@ -1194,7 +1246,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 					duplicate := false
 					// if the key is of interface type, the type is also significant when checking for duplicates
 					xkey := keyVal(x.val)
-					if _, ok := utyp.key.Underlying().(*Interface); ok {
+					if IsInterface(utyp.key) {
 						for _, vtyp := range visited[xkey] {
 							if Identical(vtyp, x.typ) {
 								duplicate = true
@@ -1245,76 +1297,15 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 	case *ast.SelectorExpr:
 		check.selector(x, e)
 
-	case *ast.IndexExpr:
-		check.expr(x, e.X)
-		if x.mode == invalid {
-			check.use(e.Index)
-			goto Error
-		}
-
-		valid := false
-		length := int64(-1) // valid if >= 0
-		switch typ := x.typ.Underlying().(type) {
-		case *Basic:
-			if isString(typ) {
-				valid = true
-				if x.mode == constant_ {
-					length = int64(len(constant.StringVal(x.val)))
-				}
-				// an indexed string always yields a byte value
-				// (not a constant) even if the string and the
-				// index are constant
-				x.mode = value
-				x.typ = universeByte // use 'byte' name
-			}
-
-		case *Array:
-			valid = true
-			length = typ.len
-			if x.mode != variable {
-				x.mode = value
-			}
-			x.typ = typ.elem
-
-		case *Pointer:
-			if typ, _ := typ.base.Underlying().(*Array); typ != nil {
-				valid = true
-				length = typ.len
-				x.mode = variable
-				x.typ = typ.elem
-			}
-
-		case *Slice:
-			valid = true
-			x.mode = variable
-			x.typ = typ.elem
-
-		case *Map:
-			var key operand
-			check.expr(&key, e.Index)
-			check.assignment(&key, typ.key, "map index")
-			if x.mode == invalid {
-				goto Error
-			}
-			x.mode = mapindex
-			x.typ = typ.elem
-			x.expr = e
-			return expression
-		}
-
-		if !valid {
-			check.invalidOp(x.pos(), "cannot index %s", x)
-			goto Error
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		base, indices := unpackIndexExpr(e)
+		if check.indexExpr(x, e, base, indices) {
+			check.funcInst(x, e, base, indices)
 		}
-
-		if e.Index == nil {
-			check.invalidAST(e.Pos(), "missing index for %s", x)
+		if x.mode == invalid {
 			goto Error
 		}
 
-		check.index(e.Index, length)
-		// ok to continue
-
 	case *ast.SliceExpr:
 		check.expr(x, e.X)
 		if x.mode == invalid {
@@ -1324,7 +1315,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 
 		valid := false
 		length := int64(-1) // valid if >= 0
-		switch typ := x.typ.Underlying().(type) {
+		switch typ := coreString(x.typ).(type) {
 		case *Basic:
 			if isString(typ) {
 				if e.Slice3 {
@@ -1352,7 +1343,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 			x.typ = &Slice{elem: typ.elem}
 
 		case *Pointer:
-			if typ, _ := typ.base.Underlying().(*Array); typ != nil {
+			if typ, _ := under(typ.base).(*Array); typ != nil {
 				valid = true
 				length = typ.len
 				x.typ = &Slice{elem: typ.elem}
@@ -1421,7 +1412,12 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 		if x.mode == invalid {
 			goto Error
 		}
-		xtyp, _ := x.typ.Underlying().(*Interface)
+		// TODO(gri) we may want to permit type assertions on type parameter values at some point
+		if isTypeParam(x.typ) {
+			check.invalidOp(x.pos(), "cannot use type assertion on type parameter value %s", x)
+			goto Error
+		}
+		xtyp, _ := under(x.typ).(*Interface)
 		if xtyp == nil {
 			check.invalidOp(x.pos(), "%s is not an interface", x)
 			goto Error
@@ -1431,7 +1427,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 			check.invalidAST(e.Pos(), "use of .(type) outside type switch")
 			goto Error
 		}
-		T := check.typ(e.Type)
+		T := check.varType(e.Type)
 		if T == Typ[Invalid] {
 			goto Error
 		}
@@ -1450,7 +1446,7 @@ func (check *Checker) exprInternal(x *operand, e ast.Expr, hint Type) exprKind {
 		case typexpr:
 			x.typ = &Pointer{base: x.typ}
 		default:
-			if typ, ok := x.typ.Underlying().(*Pointer); ok {
+			if typ, ok := under(x.typ).(*Pointer); ok {
 				x.mode = variable
 				x.typ = typ.base
 			} else {
@@ -1574,6 +1570,7 @@ func (check *Checker) multiExpr(x *operand, e ast.Expr) {
 	var msg string
 	switch x.mode {
 	default:
+		check.nonGeneric(x)
 		return
 	case novalue:
 		msg = "%s used as value"
@@ -1597,6 +1594,7 @@ func (check *Checker) exprWithHint(x *operand, e ast.Expr, hint Type) {
 	var msg string
 	switch x.mode {
 	default:
+		check.nonGeneric(x)
 		return
 	case novalue:
 		msg = "%s used as value"
@@ -1609,6 +1607,30 @@ func (check *Checker) exprWithHint(x *operand, e ast.Expr, hint Type) {
 	x.mode = invalid
 }
 
+// nonGeneric reports an error and sets x.mode to invalid if x denotes
+// a generic function or type that is not instantiated.
+func (check *Checker) nonGeneric(x *operand) {
+	if x.mode == invalid || x.mode == novalue {
+		return
+	}
+	var what string
+	switch t := x.typ.(type) {
+	case *Named:
+		if isGeneric(t) {
+			what = "type"
+		}
+	case *Signature:
+		if t.tparams != nil {
+			what = "function"
+		}
+	}
+	if what != "" {
+		check.errorf(x.pos(), "cannot use generic %s %s without instantiation", what, x.expr)
+		x.mode = invalid
+		x.typ = Typ[Invalid]
+	}
+}
+
 // exprOrType typechecks expression or type e and initializes x with the expression value or type.
 // If an error occurred, x.mode is set to invalid.
 //
@@ -31,6 +31,9 @@ var testExprs = []testEntry{
 	dup("(x)"),
 	dup("x.f"),
 	dup("a[i]"),
+	dup("f[T]"),
+	dup("f[T, int]"),
+	dup("m[K, V]"),
 
 	dup("s[:]"),
 	dup("s[i:]"),
@@ -57,6 +60,8 @@ var testExprs = []testEntry{
 	dup("x.(interface{})"),
 	dup("x.(interface{m(); n(x int); E})"),
 	dup("x.(interface{m(); n(x int) T; E; F})"),
+	dup("x.(interface{~int | ~float64})"),
+	dup("x.(T[int])"),
 
 	dup("x.(map[K]V)"),
 
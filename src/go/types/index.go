@@ -0,0 +1,134 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements typechecking of index expressions.
+
+package types
+
+import (
+	"go/ast"
+	"go/constant"
+)
+
+// unpackIndexExpr returns the indexed expression and the indices of the
+// index expression e of the form x[i] or x[i, j, ...]. If e is not an
+// index expression, the result is (nil, nil).
+func unpackIndexExpr(e ast.Expr) (x ast.Expr, indices []ast.Expr) {
+	switch e := e.(type) {
+	case *ast.IndexExpr:
+		return e.X, []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		return e.X, e.Indices
+	}
+	return nil, nil
+}
+
+// indexExpr type-checks the index expression e of the form base[indices...]
+// and returns the result in x. If e is an instantiation of a generic type,
+// x is the instantiated type. If e is a function instantiation, the result
+// is true and x is the generic function; the function is not instantiated
+// (the caller is responsible for that).
+func (check *Checker) indexExpr(x *operand, e ast.Expr, base ast.Expr, indices []ast.Expr) (isFuncInst bool) {
+	check.exprOrType(x, base)
+	x.expr = e
+
+	switch x.mode {
+	case invalid:
+		check.use(indices...)
+		return false
+
+	case typexpr:
+		// type instantiation
+		x.mode = invalid
+		x.typ = check.varType(e)
+		if x.typ != Typ[Invalid] {
+			x.mode = typexpr
+		}
+		return false
+
+	case value:
+		if sig, _ := x.typ.(*Signature); sig != nil && sig.TypeParams().Len() > 0 {
+			// function instantiation
+			return true
+		}
+	}
+
+	// x should not be generic at this point, but be safe and check
+	check.nonGeneric(x)
+	if x.mode == invalid {
+		check.use(indices...)
+		return false
+	}
+
+	if len(indices) != 1 {
+		check.errorf(indices[1].Pos(), "unexpected comma; expecting ]")
+		check.use(indices...)
+		x.mode = invalid
+		return false
+	}
+	index := indices[0]
+
+	valid := false
+	length := int64(-1) // valid if >= 0
+	switch typ := coreString(x.typ).(type) {
+	case *Basic:
+		if isString(typ) {
+			valid = true
+			if x.mode == constant_ {
+				length = int64(len(constant.StringVal(x.val)))
+			}
+			// an indexed string always yields a byte value
+			// (not a constant) even if the string and the
+			// index are constant
+			x.mode = value
+			x.typ = universeByte // use 'byte' name
+		}
+
+	case *Array:
+		valid = true
+		length = typ.len
+		if x.mode != variable {
+			x.mode = value
+		}
+		x.typ = typ.elem
+
+	case *Pointer:
+		if typ, _ := under(typ.base).(*Array); typ != nil {
+			valid = true
+			length = typ.len
+			x.mode = variable
+			x.typ = typ.elem
+		}
+
+	case *Slice:
+		valid = true
+		x.mode = variable
+		x.typ = typ.elem
+
+	case *Map:
+		var key operand
+		check.expr(&key, index)
+		check.assignment(&key, typ.key, "map index")
+		// ok to continue even if indexing failed - map element type is known
+		x.mode = mapindex
+		x.typ = typ.elem
+		return false
+	}
+
+	if !valid {
+		check.invalidOp(x.pos(), "cannot index %s", x)
+		x.mode = invalid
+		return false
+	}
+
+	if index == nil {
+		check.invalidAST(e.Pos(), "missing index for %s", x)
+		x.mode = invalid
+		return false
+	}
+
+	check.index(index, length)
+	// ok to continue
+	return false
+}
@@ -0,0 +1,445 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements type parameter inference.
+
+package types
+
+import "go/token"
+
+// infer attempts to infer the complete set of type arguments for generic
+// function instantiation/call based on the given type parameters tparams,
+// type arguments targs, function parameter types params, and function
+// arguments args, if any. There must be at least one type parameter, no
+// more type arguments than type parameters, and params and args must match
+// in number (incl. zero).
+// If successful, infer returns the complete list of type arguments, one
+// for each type parameter. Otherwise the result is nil and appropriate
+// errors will be reported.
+//
+// Inference proceeds as follows:
+//
+//   Starting with given type arguments
+//   1) apply FTI (function type inference) with typed arguments,
+//   2) apply CTI (constraint type inference),
+//   3) apply FTI with untyped function arguments,
+//   4) apply CTI.
+//
+// The process stops as soon as all type arguments are known or an error occurs.
+func (check *Checker) infer(pos token.Pos, tparams []*TypeParam, targs []Type, params []Type, args []*operand) (result []Type) {
+	if trace {
+		check.trace(pos, "-- inferring %d type arguments for %s", len(tparams), tparams)
+		check.indent++
+		defer func() {
+			check.indent--
+			check.trace(pos, "=> %s", result)
+		}()
+	}
+
+	// There must be at least one type parameter, and no more type arguments than type parameters.
+	n := len(tparams)
+	assert(n > 0 && len(targs) <= n)
+
+	// Function parameters and arguments must match in number.
+	assert(len(params) == len(args))
+
+	// If we already have all type arguments, we're done.
+	if len(targs) == n {
+		return targs
+	}
+	// len(targs) < n
+
+	// Rename type parameters to avoid conflicts in recursive instantiation
+	// scenarios: in a recursive call of a generic function f, the type of
+	// an argument may be one of f's own type parameters.
+	//
+	//   func f[P any](x P) { f(x) }
+	//
+	// Unifying the parameter type P with the argument type P would not
+	// infer anything. With renaming, we unify P' with P and infer P' = P.
+	tparams, params = check.renameTParams(tparams, params)
+
+	// Make sure we have a "full" list of type arguments, some of which may
+	// be nil (unknown). Make a copy so as to not clobber the incoming slice.
+	if len(targs) < n {
+		targs2 := make([]Type, n)
+		copy(targs2, targs)
+		targs = targs2
+	}
+	// len(targs) == n
+
+	// Unify parameter and argument types for generic parameters with typed
+	// arguments and collect the indices of generic parameters with untyped
+	// arguments. Terminology: generic parameter = function parameter with a
+	// type-parameterized type.
+	u := newUnifier(false, tparams, targs)
+
+	errorf := func(kind string, tpar, targ Type, arg *operand) {
+		// provide a better error message if we can
+		targs := u.inferred()
+		if targs[0] == nil {
+			// The first type parameter couldn't be inferred.
+			// If none of them could be inferred, don't try
+			// to provide the inferred type in the error msg.
+			allFailed := true
+			for _, targ := range targs {
+				if targ != nil {
+					allFailed = false
+					break
+				}
+			}
+			if allFailed {
+				check.errorf(arg.pos(), "%s %s of %s does not match %s (cannot infer %s)", kind, targ, arg.expr, tpar, typeParamsString(tparams))
+				return
+			}
+		}
+		smap := makeSubstMap(tparams, targs)
+		inferred := subst(tpar, smap, check.ctxt)
+		if inferred != tpar {
+			check.errorf(arg.pos(), "%s %s of %s does not match inferred type %s for %s", kind, targ, arg.expr, inferred, tpar)
+		} else {
+			check.errorf(arg.pos(), "%s %s of %s does not match %s", kind, targ, arg.expr, tpar)
+		}
+	}
+
+	// indices of the generic parameters with untyped arguments - save for later
+	var indices []int
+	for i, arg := range args {
+		par := params[i]
+		// If we permit bidirectional unification, this conditional code needs to be
+		// executed even if par.typ is not parameterized since the argument may be a
+		// generic function (for which we want to infer its type arguments).
+		if isParameterized(tparams, par) {
+			if arg.mode == invalid {
+				// An error was reported earlier. Ignore this targ
+				// and continue, we may still be able to infer all
+				// targs resulting in fewer follow-on errors.
+				continue
+			}
+			if targ := arg.typ; isTyped(targ) {
+				// If we permit bidirectional unification, and targ is
+				// a generic function, we need to initialize u.y with
+				// the respective type parameters of targ.
+				if !u.unify(par, targ) {
+					errorf("type", par, targ, arg)
+					return nil
+				}
+			} else if _, ok := par.(*TypeParam); ok {
+				// Since default types are all basic (i.e., non-composite) types, an
+				// untyped argument will never match a composite parameter type; the
+				// only parameter type it can possibly match against is a *TypeParam.
+				// Thus, for untyped arguments we only need to look at parameter types
+				// that are single type parameters.
+				indices = append(indices, i)
+			}
+		}
+	}
+
+	// If we've got all type arguments, we're done.
+	if u.unknowns() == 0 {
+		return check.finishInference(pos, tparams, u.inferred())
+	}
+
+	// See how far we get with constraint type inference.
+	// Note that even if we don't have any type arguments, constraint type inference
+	// may produce results for constraints that explicitly specify a type.
+	if !check.inferFromConstraints(pos, tparams, u) {
+		return nil
+	}
+
+	// Use any untyped arguments to infer additional type arguments.
+	for _, i := range indices {
+		tpar := params[i].(*TypeParam) // is type parameter by construction of indices
+		// Only consider untyped arguments for which the corresponding type
+		// parameter doesn't have an inferred type yet.
+		if j := u.index(tpar); j >= 0 && u.at(j) == nil {
+			u.set(j, Default(args[i].typ))
+		}
+	}
+
+	// Again, follow up with constraint type inference.
+	if !check.inferFromConstraints(pos, tparams, u) {
+		return nil
+	}
+
+	return check.finishInference(pos, tparams, u.inferred())
+}
+
+// renameTParams renames the type parameters in tparams with new type
+// parameters with the same names and (renamed) constraints, and applies
+// the renaming to the parameter types params.
+func (check *Checker) renameTParams(tparams []*TypeParam, params []Type) ([]*TypeParam, []Type) {
+	tparams2 := make([]*TypeParam, len(tparams))
+	for i, tparam := range tparams {
+		tname := NewTypeName(tparam.obj.pos, tparam.obj.pkg, tparam.obj.name, nil)
+		tparams2[i] = NewTypeParam(tname, nil)
+		tparams2[i].index = tparam.index // == i
+	}
+
+	smap := makeRenameMap(tparams, tparams2)
+	for i, tparam := range tparams {
+		tparams2[i].bound = subst(tparam.bound, smap, check.ctxt)
+	}
+
+	params2 := make([]Type, len(params))
+	for i, par := range params {
+		params2[i] = subst(par, smap, check.ctxt)
+	}
+	return tparams2, params2
+}
+
+// inferFromConstraints infers missing type arguments from the core types of
+// the type parameters' constraints, and verifies that inferred type arguments
+// match those core types. It reports whether inference was successful.
+func (check *Checker) inferFromConstraints(pos token.Pos, tparams []*TypeParam, u *unifier) bool {
+	// Repeat until no more type arguments are inferred:
+	// each newly inferred type argument may enable further
+	// inference via the core types of other constraints.
+	for {
+		nn := u.unknowns()
+		for i, tpar := range tparams {
+			core, single := coreTerm(tpar)
+			if core == nil {
+				continue
+			}
+			if tx := u.at(i); tx != nil {
+				// The corresponding type argument tx is known. In this case,
+				// if the core type has a tilde, the type argument's underlying
+				// type must match the core type, otherwise the type argument
+				// and the core type must match.
+				// If tx is an (external) type parameter, don't consider its
+				// underlying type (which is an interface). The unifier will
+				// use the type parameter's core type automatically.
+				if core.tilde && !isTypeParam(tx) {
+					tx = under(tx)
+				}
+				if !u.unify(tx, core.typ) {
+					check.errorf(pos, "%s does not match %s", tpar, core.typ)
+					return false
+				}
+			} else if single && !core.tilde {
+				// The corresponding type argument tx is unknown and there's
+				// a single specific type and no tilde. In this case the
+				// type argument must be that single type.
+				u.set(i, core.typ)
+			}
+		}
+		if u.unknowns() == nn {
+			break // no progress
+		}
+	}
+	return true
+}
+
+// finishInference resolves references to type parameters among the inferred
+// type arguments and reports an error if not all type arguments could be
+// inferred. The result is the list of inferred type arguments, or nil.
+func (check *Checker) finishInference(pos token.Pos, tparams []*TypeParam, inferred []Type) []Type {
+	// The inferred types may refer to (renamed) type parameters; for instance
+	// if P was inferred as []Q, and Q as int. Substitute repeatedly until no
+	// more changes occur. Each iteration resolves at least one more level of
+	// references unless there is a cycle, so n iterations are sufficient.
+	for k := 0; k < len(tparams); k++ {
+		smap := makeSubstMap(tparams, inferred)
+		changed := false
+		for i, targ := range inferred {
+			if targ != nil {
+				if t := subst(targ, smap, check.ctxt); t != targ {
+					inferred[i] = t
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	// Once nothing changes anymore, we may still have type parameters left;
+	// e.g., a constraint with core type *P may match a type parameter Q but
+	// we don't have any type arguments to fill in for *P or Q.
+	for i, targ := range inferred {
+		if targ == nil || isParameterized(tparams, targ) {
+			obj := tparams[i].obj
+			check.errorf(pos, "cannot infer %s (%s)", obj.name, check.fset.Position(obj.pos))
+			return nil
+		}
+	}
+	return inferred
+}
+
+// typeParamsString produces a string of the type parameter names
+// in list suitable for human consumption.
+func typeParamsString(list []*TypeParam) string {
+	// common cases
+	n := len(list)
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return list[0].obj.name
+	case 2:
+		return list[0].obj.name + " and " + list[1].obj.name
+	}
+
+	// general case (n > 2)
+	var s string
+	for i, tname := range list[:n-1] {
+		if i > 0 {
+			s += ", "
+		}
+		s += tname.obj.name
+	}
+	s += ", and " + list[n-1].obj.name
+	return s
+}
+
+// isParameterized reports whether typ contains any of the type parameters of tparams.
+func isParameterized(tparams []*TypeParam, typ Type) bool {
+	w := tpWalker{
+		seen:    make(map[Type]bool),
+		tparams: tparams,
+	}
+	return w.isParameterized(typ)
+}
+
+type tpWalker struct {
+	seen    map[Type]bool
+	tparams []*TypeParam
+}
+
+func (w *tpWalker) isParameterized(typ Type) (res bool) {
+	// detect cycles
+	if x, ok := w.seen[typ]; ok {
+		return x
+	}
+	w.seen[typ] = false
+	defer func() {
+		w.seen[typ] = res
+	}()
+
+	switch t := typ.(type) {
+	case nil, *Basic: // TODO(gri) should nil be handled here?
+		break
+
+	case *Array:
+		return w.isParameterized(t.elem)
+
+	case *Slice:
+		return w.isParameterized(t.elem)
+
+	case *Struct:
+		for _, fld := range t.fields {
+			if w.isParameterized(fld.typ) {
+				return true
+			}
+		}
+
+	case *Pointer:
+		return w.isParameterized(t.base)
+
+	case *Tuple:
+		n := t.Len()
+		for i := 0; i < n; i++ {
+			if w.isParameterized(t.At(i).typ) {
+				return true
+			}
+		}
+
+	case *Signature:
+		// t.tparams may not be nil if we are looking at a signature
+		// of a generic function type (or an interface method) that is
+		// part of the type we're testing. We don't care about these type
+		// parameters.
+		// Similarly, the receiver of a method may declare (rather then
+		// use) type parameters, we don't care about those either.
+		// Thus, we only need to look at the input and result parameters.
+		return w.isParameterized(t.params) || w.isParameterized(t.results)
+
+	case *Interface:
+		for _, m := range t.methods {
+			if w.isParameterized(m.typ) {
+				return true
+			}
+		}
+		for _, e := range t.embeddeds {
+			if w.isParameterized(e) {
+				return true
+			}
+		}
+
+	case *Union:
+		for _, term := range t.terms {
+			if w.isParameterized(term.typ) {
+				return true
+			}
+		}
+
+	case *Map:
+		return w.isParameterized(t.key) || w.isParameterized(t.elem)
+
+	case *Chan:
+		return w.isParameterized(t.elem)
+
+	case *Named:
+		for _, targ := range t.targs.list() {
+			if w.isParameterized(targ) {
+				return true
+			}
+		}
+
+	case *TypeParam:
+		// t must be one of w.tparams
+		return tparamIndex(w.tparams, t) >= 0
+
+	default:
+		unreachable()
+	}
+
+	return false
+}
+
+// tparamIndex returns the index of the type parameter tpar in list,
+// or -1 if tpar is not in list.
+func tparamIndex(list []*TypeParam, tpar *TypeParam) int {
+	for i, p := range list {
+		if p == tpar {
+			return i
+		}
+	}
+	return -1
+}
+
+// If the type parameter has a single specific type S, coreTerm returns (S, true).
+// Otherwise, if tpar has a core type T, it returns a term corresponding to that
+// core type and false. In that case, if any term of tpar has a tilde, the core
+// term has a tilde. In all other cases coreTerm returns (nil, false).
+func coreTerm(tpar *TypeParam) (*term, bool) {
+	n := 0
+	var single *term // valid if n == 1
+	var tilde bool
+	tpar.is(func(t *term) bool {
+		if t == nil {
+			assert(n == 0)
+			return false // no terms
+		}
+		n++
+		single = t
+		if t.tilde {
+			tilde = true
+		}
+		return true
+	})
+	if n == 1 {
+		return single, true
+	}
+	if typ := coreType(tpar); typ != nil {
+		// A core type is always an underlying type.
+		// If any term of tpar has a tilde, we don't
+		// have a precise core type and we must return
+		// a tilde as well.
+		return &term{tilde, typ}, false
+	}
+	return nil, false
+}
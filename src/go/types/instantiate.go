@@ -0,0 +1,316 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements instantiation of generic types
+// through substitution of type parameters by type arguments.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Instantiate instantiates the type orig with the given type arguments targs.
+// orig must be a *Named or a *Signature type. If there is no error, the
+// resulting Type is an instantiated type of the same kind (either a *Named or
+// a *Signature). Methods attached to a *Named type are also instantiated, and
+// associated with a new *Func that has the same position as the original
+// method, but nil function scope.
+//
+// If ctxt is non-nil, it may be used to de-duplicate the instance against
+// previous instances with the same identity.
+//
+// If validate is set, Instantiate verifies that the number of type arguments
+// and parameters match, and that the type arguments satisfy their
+// corresponding type constraints. If verification fails, the resulting error
+// may wrap an *ArgumentError indicating which type argument did not satisfy
+// its corresponding type parameter constraint, and why.
+//
+// If validate is not set, Instantiate does not verify the type argument count
+// or whether the type arguments satisfy their constraints. Instantiate is
+// guaranteed to not return an error, but may panic.
+func Instantiate(ctxt *Context, orig Type, targs []Type, validate bool) (Type, error) {
+	if ctxt == nil {
+		ctxt = NewContext()
+	}
+	if validate {
+		var tparams []*TypeParam
+		switch t := orig.(type) {
+		case *Named:
+			tparams = t.TypeParams().list()
+		case *Signature:
+			tparams = t.TypeParams().list()
+		}
+		if len(targs) != len(tparams) {
+			return nil, fmt.Errorf("got %d type arguments but %s has %d type parameters", len(targs), orig, len(tparams))
+		}
+		var check *Checker
+		if i, err := check.verify(tparams, targs, ctxt); err != nil {
+			return nil, &ArgumentError{i, err}
+		}
+	}
+
+	return instance(orig, targs, ctxt), nil
+}
+
+// An ArgumentError holds an error associated with an argument index.
+type ArgumentError struct {
+	Index int
+	Err   error
+}
+
+func (e *ArgumentError) Error() string { return e.Err.Error() }
+
+// instance creates a type or function instance using the given original type
+// typ and arguments targs. For Named types the resulting instance will be
+// unexpanded. The context ctxt must not be nil.
+func instance(orig Type, targs []Type, ctxt *Context) Type {
+	switch orig := orig.(type) {
+	case *Named:
+		h := ctxt.instanceHash(orig, targs)
+		if inst := ctxt.lookup(h, orig, targs); inst != nil {
+			return inst
+		}
+		inst := &Named{obj: orig.obj, orig: orig, targs: NewTypeList(targs), ctxt: ctxt}
+		return ctxt.update(h, orig, targs, inst)
+
+	case *Signature:
+		tparams := orig.TypeParams()
+		if tparams.Len() == 0 {
+			return orig // nothing to do (minor optimization)
+		}
+		sig := subst(orig, makeSubstMap(tparams.list(), targs), ctxt).(*Signature)
+		// If the signature doesn't use its type parameters, subst
+		// will not make a copy. In that case, make a copy now (so
+		// we can set tparams to nil w/o causing side-effects).
+		if sig == orig {
+			copy := *sig
+			sig = &copy
+		}
+		// After instantiating a generic signature, it is not generic
+		// anymore; we need to set tparams to nil.
+		sig.tparams = nil
+		return sig
+	}
+
+	panic(fmt.Sprintf("cannot instantiate %v", orig))
+}
+
+// resolve expands the underlying type and methods of an instantiated
+// type t and returns t. For non-instantiated types, resolve is a no-op.
+// The result is not cached while the declaration of t's origin type is
+// being type-checked.
+func (t *Named) resolve() *Named {
+	if t.orig == nil {
+		return t // not an instance
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.expanded {
+		return t
+	}
+
+	orig := t.orig
+	complete := !orig.incomplete
+	ctxt := t.ctxt
+	if ctxt == nil {
+		ctxt = NewContext()
+	}
+
+	tparams := orig.tparams.list()
+	targs := t.targs.list()
+	if len(tparams) != len(targs) {
+		// error reported elsewhere
+		t.underlying = Typ[Invalid]
+		t.methods = nil
+		t.expanded = complete
+		return t
+	}
+	smap := makeSubstMap(tparams, targs)
+
+	// expand underlying type
+	switch u := orig.underlying.(type) {
+	case nil:
+		// origin type not set up yet
+		t.underlying = nil
+		complete = false
+	case *Named:
+		// forward chain during setup
+		t.underlying = subst(u, smap, ctxt)
+		complete = false
+	case *Interface:
+		iface := subst(u, smap, ctxt).(*Interface)
+		if iface == u {
+			// make a copy so we can adjust the method receivers
+			iface = &Interface{methods: u.methods, embeddeds: u.embeddeds, implicit: u.implicit, comparable: u.comparable}
+		}
+		// methods of named interfaces have the named type as receiver
+		methods := append([]*Func(nil), iface.methods...)
+		replaceRecvType(methods, orig, t)
+		iface.methods = methods
+		iface.allMethods = nil
+		iface.Complete()
+		replaceRecvType(iface.allMethods, orig, t)
+		t.underlying = iface
+	default:
+		t.underlying = subst(u, smap, ctxt)
+	}
+
+	// expand methods
+	methods := make([]*Func, len(orig.methods))
+	for i, m := range orig.methods {
+		methods[i] = t.expandMethod(m, ctxt)
+	}
+	t.methods = methods
+
+	t.expanded = complete
+	return t
+}
+
+// expandMethod returns the method m of t's origin type instantiated
+// with the type arguments of t.
+func (t *Named) expandMethod(m *Func, ctxt *Context) *Func {
+	sig, _ := m.typ.(*Signature)
+	if sig == nil {
+		return m // method not set up yet
+	}
+
+	var newsig Signature
+	if rparams := sig.rparams.list(); len(rparams) == t.targs.Len() {
+		newsig = *subst(sig, makeSubstMap(rparams, t.targs.list()), ctxt).(*Signature)
+	} else {
+		newsig = *sig // error reported elsewhere
+	}
+	newsig.scope = nil
+	newsig.rparams = nil
+	if recv := sig.recv; recv != nil {
+		var rtyp Type = t
+		if _, isPtr := deref(recv.typ); isPtr {
+			rtyp = NewPointer(t)
+		}
+		newsig.recv = NewParam(recv.pos, recv.pkg, recv.name, rtyp)
+	}
+	return NewFunc(m.pos, m.pkg, m.name, &newsig)
+}
+
+// verify checks that the type arguments targs satisfy the constraints of
+// the corresponding type parameters tparams. If verification fails, verify
+// returns the index of the offending type argument and an error.
+// check may be nil.
+func (check *Checker) verify(tparams []*TypeParam, targs []Type, ctxt *Context) (int, error) {
+	smap := makeSubstMap(tparams, targs)
+	for i, tpar := range tparams {
+		// The type parameter bound is parameterized with the same type parameters
+		// as the instantiated type; before we can use it for bounds checking we
+		// need to instantiate it with the type arguments with which we instantiated
+		// the parameterized type.
+		bound := subst(tpar.bound, smap, ctxt)
+		if err := check.implements(targs[i], bound); err != nil {
+			return i, err
+		}
+	}
+	return -1, nil
+}
+
+// implements checks if V implements T and reports an error if it doesn't.
+// check may be nil.
+func (check *Checker) implements(V, T Type) error {
+	Vu := under(V)
+	Tu := under(T)
+	if Vu == Typ[Invalid] || Tu == Typ[Invalid] {
+		return nil // avoid follow-on errors
+	}
+	if p, _ := Vu.(*Pointer); p != nil && under(p.base) == Typ[Invalid] {
+		return nil // avoid follow-on errors
+	}
+
+	errorf := func(format string, args ...interface{}) error {
+		return errors.New(check.sprintf(format, args...))
+	}
+
+	Ti, _ := Tu.(*Interface)
+	if Ti == nil {
+		return errorf("%s does not implement %s (%s is not an interface)", V, T, T)
+	}
+
+	// Every type satisfies the empty interface.
+	if Ti.Empty() {
+		return nil
+	}
+	// T is not the empty interface (i.e., the type set of T is restricted)
+
+	// An interface V with an empty type set satisfies any interface.
+	// (The empty set is a subset of any set.)
+	Vi, _ := Vu.(*Interface)
+	if Vi != nil && Vi.typeSet().isEmpty() {
+		return nil
+	}
+	// type set of V is not empty
+
+	// No type with non-empty type set satisfies the empty type set.
+	if Ti.typeSet().isEmpty() {
+		return errorf("cannot implement %s (empty type set)", T)
+	}
+
+	// V must implement T's methods, if any.
+	if m, wrong := MissingMethod(V, Ti, true); m != nil /* !Implements(V, Ti) */ {
+		if wrong {
+			return errorf("%s does not implement %s (wrong type for method %s)", V, T, m.name)
+		}
+		return errorf("%s does not implement %s (missing method %s)", V, T, m.name)
+	}
+
+	// If T is comparable, V must be comparable.
+	// Remember as a pending error and report only if we don't have a more specific error.
+	var pending error
+	if Ti.IsComparable() && !comparable(V, false, nil) {
+		pending = errorf("%s does not implement comparable", V)
+	}
+
+	// V must also be in the set of types of T, if any.
+	// Constraints with empty type sets were already excluded above.
+	if !Ti.typeSet().hasTerms() {
+		return pending // nothing to do
+	}
+
+	// If V is itself an interface, each of its possible types must be in the set
+	// of T types (i.e., the V type set must be a subset of the T type set).
+	// Interfaces V with empty type sets were already excluded above.
+	if Vi != nil {
+		if !Vi.typeSet().subsetOf(Ti.typeSet()) {
+			// TODO(gri) report which type is missing
+			return errorf("%s does not implement %s", V, T)
+		}
+		return pending
+	}
+
+	// Otherwise, V's type must be included in the iface type set.
+	var alt Type
+	if Ti.typeSet().is(func(t *term) bool {
+		if !t.includes(V) {
+			// If V ∉ t.typ but V ∈ ~t.typ then remember this type
+			// so we can suggest it as an alternative in the error
+			// message.
+			if alt == nil && !t.tilde && Identical(t.typ, under(t.typ)) {
+				tt := *t
+				tt.tilde = true
+				if tt.includes(V) {
+					alt = t.typ
+				}
+			}
+			return true
+		}
+		return false
+	}) {
+		if alt != nil {
+			return errorf("%s does not implement %s (possibly missing ~ for %s in constraint %s)", V, T, alt, T)
+		}
+		return errorf("%s does not implement %s", V, T)
+	}
+
+	return pending
+}
@@ -326,6 +326,12 @@ typenameLoop:
 	// doesn't matter for the purpose of determining the under-
 	// lying interface.)
 	if decl := check.objMap[tname]; decl != nil {
+		if decl.tparams != nil {
+			// A generic interface must be instantiated before it can
+			// be embedded; the error is reported when type-checking
+			// the embedded type.
+			return nil
+		}
 		switch typ := unparen(decl.typ).(type) {
 		case *ast.Ident:
 			// type tname T
@@ -41,7 +41,7 @@ func LookupFieldOrMethod(T Type, addressable bool, pkg *Package, name string) (o
 	// pointer type but discard the result if it is a method since we would
 	// not have found it for T (see also issue 8590).
 	if t, _ := T.(*Named); t != nil {
-		if p, _ := t.underlying.(*Pointer); p != nil {
+		if p, _ := under(t).(*Pointer); p != nil {
 			obj, index, indirect = lookupFieldOrMethod(p, false, pkg, name)
 			if _, ok := obj.(*Func); ok {
 				return nil, nil, false
@@ -108,6 +108,7 @@ func lookupFieldOrMethod(T Type, addressable bool, pkg *Package, name string) (o
 					seen = make(map[*Named]bool)
 				}
 				seen[named] = true
+				named.resolve() // expand instantiated types
 
 				// look for a matching attached method
 				if i, m := lookupMethod(named.methods, pkg, name); m != nil {
@@ -170,6 +171,18 @@ func lookupFieldOrMethod(T Type, addressable bool, pkg *Package, name string) (o
 					obj = m
 					indirect = e.indirect
 				}
+
+			case *TypeParam:
+				// the methods of a type parameter are the methods of its constraint
+				if i, m := lookupMethod(t.iface().allMethods, pkg, name); m != nil {
+					assert(m.typ != nil)
+					index = concat(e.index, i)
+					if obj != nil || e.multiples {
+						return nil, index, false // collision
+					}
+					obj = m
+					indirect = e.indirect
+				}
 			}
 		}
 
@@ -255,7 +268,7 @@ func MissingMethod(V Type, T *Interface, static bool) (method *Func, wrongType b
 
 	// TODO(gri) Consider using method sets here. Might be more efficient.
 
-	if ityp, _ := V.Underlying().(*Interface); ityp != nil {
+	if ityp, _ := under(V).(*Interface); ityp != nil {
 		// TODO(gri) allMethods is sorted - can do this more efficiently
 		for _, m := range T.allMethods {
 			_, obj := lookupMethod(ityp.allMethods, m.pkg, m.name)
@@ -116,6 +116,7 @@ func NewMethodSet(T Type) *MethodSet {
 					seen = make(map[*Named]bool)
 				}
 				seen[named] = true
+				named.resolve() // expand instantiated types
 
 				mset = mset.add(named.methods, e.index, e.indirect, e.multiples)
 
@@ -143,6 +144,9 @@ func NewMethodSet(T Type) *MethodSet {
 
 			case *Interface:
 				mset = mset.add(t.allMethods, e.index, true, e.multiples)
+
+			case *TypeParam:
+				mset = mset.add(t.iface().allMethods, e.index, true, e.multiples)
 			}
 		}
 
@@ -246,6 +246,8 @@ func (obj *TypeName) IsAlias() bool {
 		return obj.pkg != nil || t.name != obj.name || t == universeByte || t == universeRune
 	case *Named:
 		return obj != t.obj
+	case *TypeParam:
+		return obj != t.obj
 	default:
 		return true
 	}
@@ -414,13 +416,20 @@ func writeObject(buf *bytes.Buffer, obj Object, qf Qualifier) {
 		// We have a type object: Don't print anything more for
 		// basic types since there's no more information (names
 		// are the same; see also comment in TypeName.IsAlias).
-		if _, ok := typ.(*Basic); ok {
+		switch t := typ.(type) {
+		case *Basic:
 			return
+		case *Named:
+			if t.TypeParams().Len() > 0 {
+				writeTParamList(buf, t.TypeParams().list(), qf, nil)
+			}
 		}
 		if tname.IsAlias() {
 			buf.WriteString(" =")
+		} else if t, _ := typ.(*TypeParam); t != nil {
+			typ = t.bound
 		} else {
-			typ = typ.Underlying()
+			typ = under(typ)
 		}
 	}
 
@@ -8,6 +8,7 @@ package types
 
 import (
 	"bytes"
+	"fmt"
 	"go/ast"
 	"go/constant"
 	"go/token"
@@ -213,13 +214,29 @@ func (x *operand) assignableTo(conf *Config, T Type, reason *string) bool {
 		return true
 	}
 
-	Vu := V.Underlying()
-	Tu := T.Underlying()
+	Vu := under(V)
+	Tu := under(T)
+	Vp, _ := V.(*TypeParam)
+	Tp, _ := T.(*TypeParam)
 
 	// x is an untyped value representable by a value of type T
 	// TODO(gri) This is borrowing from checker.convertUntyped and
 	//           checker.representable. Need to clean up.
 	if isUntyped(Vu) {
+		assert(Vp == nil)
+		if Tp != nil {
+			// T is a type parameter: x is assignable to T if it is
+			// representable by each specific type in the type set of T.
+			return Tp.is(func(t *term) bool {
+				if t == nil {
+					return false
+				}
+				// A term may be a tilde term but the underlying
+				// type of an untyped value doesn't change so we
+				// don't need to do anything special.
+				return x.assignableTo(conf, t.typ, reason)
+			})
+		}
 		switch t := Tu.(type) {
 		case *Basic:
 			if x.isNil() && t.kind == UnsafePointer {
@@ -243,12 +260,13 @@ func (x *operand) assignableTo(conf *Config, T Type, reason *string) bool {
 
 	// x's type V and T have identical underlying types
 	// and at least one of V or T is not a named type
-	if Identical(Vu, Tu) && (!isNamed(V) || !isNamed(T)) {
+	// and neither V nor T is a type parameter.
+	if Identical(Vu, Tu) && (!isNamed(V) || !isNamed(T)) && Vp == nil && Tp == nil {
 		return true
 	}
 
-	// T is an interface type and x implements T
-	if Ti, ok := Tu.(*Interface); ok {
+	// T is an interface type, but not a type parameter, and x implements T
+	if Ti, ok := Tu.(*Interface); ok && Tp == nil {
 		if m, wrongType := MissingMethod(x.typ, Ti, true); m != nil /* Implements(x.typ, Ti) */ {
 			if reason != nil {
 				if wrongType {
@@ -271,5 +289,57 @@ func (x *operand) assignableTo(conf *Config, T Type, reason *string) bool {
 		}
 	}
 
+	// optimization: if we don't have type parameters, we're done
+	if Vp == nil && Tp == nil {
+		return false
+	}
+
+	errorf := func(format string, args ...interface{}) {
+		if reason != nil {
+			msg := fmt.Sprintf(format, args...)
+			if *reason != "" {
+				msg += "\n\t" + *reason
+			}
+			*reason = msg
+		}
+	}
+
+	// x's type V is not a named type and T is a type parameter, and
+	// x is assignable to each specific type in T's type set.
+	if !isNamed(V) && Tp != nil {
+		ok := false
+		Tp.is(func(T *term) bool {
+			if T == nil {
+				return false // no specific types
+			}
+			if ok = x.assignableTo(conf, T.typ, reason); !ok {
+				errorf("cannot assign %s to %s (in %s)", x.typ, T.typ, Tp)
+				return false
+			}
+			return true
+		})
+		return ok
+	}
+
+	// x's type V is a type parameter and T is not a named type,
+	// and values x' of each specific type in V's type set are
+	// assignable to T.
+	if Vp != nil && !isNamed(T) {
+		x := *x // don't clobber outer x
+		ok := false
+		Vp.is(func(V *term) bool {
+			if V == nil {
+				return false // no specific types
+			}
+			x.typ = V.typ
+			if ok = x.assignableTo(conf, T, reason); !ok {
+				errorf("cannot assign %s (in %s) to %s", V.typ, Vp, T)
+				return false
+			}
+			return true
+		})
+		return ok
+	}
+
 	return false
 }
@@ -8,12 +8,14 @@ package types
 
 import "sort"
 
+// isNamed reports whether typ has a name: basic types,
+// defined types, and type parameters are named.
 func isNamed(typ Type) bool {
-	if _, ok := typ.(*Basic); ok {
-		return ok
+	switch typ.(type) {
+	case *Basic, *Named, *TypeParam:
+		return true
 	}
-	_, ok := typ.(*Named)
-	return ok
+	return false
 }
 
 func isBoolean(typ Type) bool {
@@ -71,41 +73,113 @@ func isConstType(typ Type) bool {
 	return ok && t.info&IsConstType != 0
 }
 
+// The allX predicates below report whether t is an X.
+// If t is a type parameter the result is true if isX is true
+// for all types in t's type set.
+// If t is a type parameter without specific type terms,
+// the result is false.
+
+func allBoolean(typ Type) bool  { return allBasic(typ, IsBoolean) }
+func allInteger(typ Type) bool  { return allBasic(typ, IsInteger) }
+func allUnsigned(typ Type) bool { return allBasic(typ, IsUnsigned) }
+func allNumeric(typ Type) bool  { return allBasic(typ, IsNumeric) }
+func allString(typ Type) bool   { return allBasic(typ, IsString) }
+func allOrdered(typ Type) bool  { return allBasic(typ, IsOrdered) }
+func allNumericOrString(typ Type) bool {
+	return allBasic(typ, IsNumeric|IsString)
+}
+
+// allBasic reports whether under(t) is a basic type with properties
+// info, or, if t is a type parameter, whether all types in t's type
+// set are basic types with properties info.
+func allBasic(t Type, info BasicInfo) bool {
+	if tpar, _ := t.(*TypeParam); tpar != nil {
+		return tpar.is(func(t *term) bool { return t != nil && isBasic(t.typ, info) })
+	}
+	return isBasic(t, info)
+}
+
+// isBasic reports whether under(t) is a basic type with (some of
+// the) properties specified by info.
+func isBasic(t Type, info BasicInfo) bool {
+	u, _ := under(t).(*Basic)
+	return u != nil && u.info&info != 0
+}
+
 // IsInterface reports whether typ is an interface type.
 func IsInterface(typ Type) bool {
 	_, ok := typ.Underlying().(*Interface)
 	return ok
 }
 
+// isNonTypeParamInterface reports whether typ is an interface type but not a type parameter.
+func isNonTypeParamInterface(typ Type) bool {
+	return !isTypeParam(typ) && IsInterface(typ)
+}
+
+// isTypeParam reports whether typ is a type parameter.
+func isTypeParam(typ Type) bool {
+	_, ok := typ.(*TypeParam)
+	return ok
+}
+
+// isGeneric reports whether a type is a generic, uninstantiated type
+// (generic signatures are not included).
+func isGeneric(typ Type) bool {
+	// A parameterized type is only generic if it doesn't have an instantiation already.
+	named, _ := typ.(*Named)
+	return named != nil && named.orig == nil && named.tparams.Len() > 0
+}
+
 // Comparable reports whether values of type T are comparable.
 func Comparable(T Type) bool {
-	switch t := T.Underlying().(type) {
+	return comparable(T, true, nil)
+}
+
+// comparable reports whether values of type T are comparable.
+// If dynamic is set, non-type parameter interfaces are always comparable.
+func comparable(T Type, dynamic bool, seen map[Type]bool) bool {
+	if seen[T] {
+		return true
+	}
+	if seen == nil {
+		seen = make(map[Type]bool)
+	}
+	seen[T] = true
+
+	switch t := under(T).(type) {
 	case *Basic:
 		// assume invalid types to be comparable
 		// to avoid follow-up errors
 		return t.kind != UntypedNil
-	case *Pointer, *Interface, *Chan:
+	case *Pointer, *Chan:
 		return true
 	case *Struct:
 		for _, f := range t.fields {
-			if !Comparable(f.typ) {
+			if !comparable(f.typ, dynamic, seen) {
 				return false
 			}
 		}
 		return true
 	case *Array:
-		return Comparable(t.elem)
+		return comparable(t.elem, dynamic, seen)
+	case *Interface:
+		return dynamic && !isTypeParam(T) || t.typeSet().isComparable()
 	}
 	return false
 }
 
 // hasNil reports whether a type includes the nil value.
 func hasNil(typ Type) bool {
-	switch t := typ.Underlying().(type) {
+	switch t := under(typ).(type) {
 	case *Basic:
 		return t.kind == UnsafePointer
-	case *Slice, *Pointer, *Signature, *Interface, *Map, *Chan:
+	case *Slice, *Pointer, *Signature, *Map, *Chan:
 		return true
+	case *Interface:
+		return !isTypeParam(typ) || t.typeSet().underIs(func(u Type) bool {
+			return u != nil && hasNil(u)
+		})
 	}
 	return false
 }
@@ -209,10 +283,50 @@ func identical(x, y Type, cmpTags bool, p *ifacePair) bool {
 		// and result values, corresponding parameter and result types are identical,
 		// and either both functions are variadic or neither is. Parameter and result
 		// names are not required to match.
+		// Type parameters are considered identical modulo renaming.
 		if y, ok := y.(*Signature); ok {
+			if x.tparams.Len() != y.tparams.Len() {
+				return false
+			}
+
+			// In the case of generic signatures, we substitute x's
+			// type parameters for y's before comparing.
+			yparams := y.params
+			yresults := y.results
+
+			if x.tparams.Len() > 0 {
+				xtparams := x.tparams.list()
+				ytparams := y.tparams.list()
+
+				targs := make([]Type, len(xtparams))
+				for i, tpar := range xtparams {
+					targs[i] = tpar
+				}
+				smap := makeSubstMap(ytparams, targs)
+				ctxt := NewContext()
+
+				// Constraints must be pair-wise identical, after substitution.
+				for i, xtpar := range xtparams {
+					ybound := subst(ytparams[i].bound, smap, ctxt)
+					if !identical(xtpar.bound, ybound, cmpTags, p) {
+						return false
+					}
+				}
+
+				yparams = subst(y.params, smap, ctxt).(*Tuple)
+				yresults = subst(y.results, smap, ctxt).(*Tuple)
+			}
+
 			return x.variadic == y.variadic &&
-				identical(x.params, y.params, cmpTags, p) &&
-				identical(x.results, y.results, cmpTags, p)
+				identical(x.params, yparams, cmpTags, p) &&
+				identical(x.results, yresults, cmpTags, p)
+		}
+
+	case *Union:
+		if y, _ := y.(*Union); y != nil {
+			xset, _ := computeUnionTerms(x, nil)
+			yset, _ := computeUnionTerms(y, nil)
+			return xset.equal(yset)
 		}
 
 	case *Interface:
@@ -220,6 +334,11 @@ func identical(x, y Type, cmpTags bool, p *ifacePair) bool {
 		// the same names and identical function types. Lower-case method names from
 		// different packages are always different. The order of the methods is irrelevant.
 		if y, ok := y.(*Interface); ok {
+			xset := x.typeSet()
+			yset := y.typeSet()
+			if xset.comparable != yset.comparable || !xset.terms.equal(yset.terms) {
+				return false
+			}
 			a := x.allMethods
 			b := y.allMethods
 			if len(a) == len(b) {
@@ -281,11 +400,28 @@ func identical(x, y Type, cmpTags bool, p *ifacePair) bool {
 
 	case *Named:
 		// Two named types are identical if their type names originate
-		// in the same type declaration.
+		// in the same type declaration; instantiated types must also
+		// have identical type arguments.
 		if y, ok := y.(*Named); ok {
-			return x.obj == y.obj
+			if x.obj != y.obj {
+				return false
+			}
+			xargs := x.targs.list()
+			yargs := y.targs.list()
+			if len(xargs) != len(yargs) {
+				return false
+			}
+			for i, xa := range xargs {
+				if !identical(xa, yargs[i], cmpTags, p) {
+					return false
+				}
+			}
+			return true
 		}
 
+	case *TypeParam:
+		// nothing to do (x and y being equal is caught in the very beginning of this function)
+
 	case nil:
 
 	default:
@@ -24,6 +24,10 @@ type declInfo struct {
 	fdecl *ast.FuncDecl // func declaration, or nil
 	alias bool          // type alias declaration
 
+	// The tparams field holds the type parameters of a generic
+	// type declaration; it is nil for other declarations.
+	tparams *ast.FieldList
+
 	// The deps field tracks initialization expression dependencies.
 	deps objSet // lazily initialized
 }
@@ -386,7 +390,7 @@ func (check *Checker) collectObjects() {
 
 					case *ast.TypeSpec:
 						obj := NewTypeName(s.Name.Pos(), pkg, s.Name.Name, nil)
-						check.declarePkgObj(s.Name, obj, &declInfo{file: fileScope, typ: s.Type, alias: s.Assign.IsValid()})
+						check.declarePkgObj(s.Name, obj, &declInfo{file: fileScope, typ: s.Type, alias: s.Assign.IsValid(), tparams: s.TypeParams})
 
 					default:
 						check.invalidAST(s.Pos(), "unknown ast.Spec node %T", s)
@@ -457,11 +461,13 @@ func (check *Checker) collectObjects() {
 		fdecl := check.objMap[f].fdecl
 		if list := fdecl.Recv.List; len(list) > 0 {
 			// f is a method
-			// receiver may be of the form T or *T, possibly with parentheses
+			// receiver may be of the form T or *T, possibly with parentheses,
+			// and T may be followed by a list of receiver type parameters
 			typ := unparen(list[0].Type)
 			if ptr, _ := typ.(*ast.StarExpr); ptr != nil {
 				typ = unparen(ptr.X)
 			}
+			typ = recvBaseTypeExpr(typ)
 			if base, _ := typ.(*ast.Ident); base != nil {
 				// base is a potential base type name; determine
 				// "underlying" defined type and associate f with it
@@ -557,13 +563,34 @@ func (a inSourceOrder) Less(i, j int) bool { return a[i].order() < a[j].order()
 func (a inSourceOrder) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 
 // processDelayed processes all delayed actions pushed after top.
+// Actions are processed in the order in which they were pushed,
+// so that actions that depend on earlier ones (such as verifying
+// type arguments against interfaces whose embedded elements are
+// collected in a delayed action) see fully set up types.
 func (check *Checker) processDelayed(top int) {
-	for len(check.delayed) > top {
-		i := len(check.delayed) - 1
-		f := check.delayed[i]
-		check.delayed = check.delayed[:i]
-		f() // may append to check.delayed
+	// If each delayed action pushes a new action, the
+	// stack will continue to grow during this loop.
+	// However, it is only processing functions (which
+	// are processed in a delayed fashion) that may
+	// add more actions (such as nested functions), so
+	// this is a sufficiently bounded process.
+	for i := top; i < len(check.delayed); i++ {
+		check.delayed[i]() // may append to check.delayed
+	}
+	assert(top <= len(check.delayed)) // stack must not have shrunk
+	check.delayed = check.delayed[:top]
+}
+
+// recvBaseTypeExpr returns the receiver base type expression of a
+// (possibly generic) receiver type expression T, T[P], or T[P, Q].
+func recvBaseTypeExpr(typ ast.Expr) ast.Expr {
+	switch x := typ.(type) {
+	case *ast.IndexExpr:
+		return unparen(x.X)
+	case *ast.IndexListExpr:
+		return unparen(x.X)
 	}
+	return typ
 }
 
 // unusedImports checks for unused imports.
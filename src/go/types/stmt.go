@@ -345,7 +345,12 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 			return
 		}
 
-		tch, ok := ch.typ.Underlying().(*Chan)
+		u := coreType(ch.typ)
+		if u == nil {
+			check.invalidOp(s.Arrow, "cannot send to %s: no core type", &ch)
+			return
+		}
+		tch, ok := u.(*Chan)
 		if !ok {
 			check.invalidOp(s.Arrow, "cannot send to non-chan type %s", ch.typ)
 			return
@@ -605,7 +610,12 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 		if x.mode == invalid {
 			return
 		}
-		xtyp, _ := x.typ.Underlying().(*Interface)
+		// TODO(gri) we may want to permit type switches on type parameter values at some point
+		if isTypeParam(x.typ) {
+			check.errorf(x.pos(), "cannot use type switch on type parameter value %s", &x)
+			return
+		}
+		xtyp, _ := under(x.typ).(*Interface)
 		if xtyp == nil {
 			check.errorf(x.pos(), "%s is not an interface", &x)
 			return
@@ -746,7 +756,7 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 		// determine key/value types
 		var key, val Type
 		if x.mode != invalid {
-			switch typ := x.typ.Underlying().(type) {
+			switch typ := coreType(x.typ).(type) {
 			case *Basic:
 				if isString(typ) {
 					key = Typ[Int]
@@ -759,7 +769,7 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 				key = Typ[Int]
 				val = typ.elem
 			case *Pointer:
-				if typ, _ := typ.base.Underlying().(*Array); typ != nil {
+				if typ, _ := under(typ.base).(*Array); typ != nil {
 					key = Typ[Int]
 					val = typ.elem
 				}
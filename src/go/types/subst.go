@@ -0,0 +1,323 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements type parameter substitution.
+
+package types
+
+// A substMap maps type parameters to the types substituted for them.
+type substMap map[*TypeParam]Type
+
+// makeSubstMap creates a new substitution map mapping tpars[i] to targs[i].
+// If targs[i] is nil, tpars[i] is not substituted.
+func makeSubstMap(tpars []*TypeParam, targs []Type) substMap {
+	assert(len(tpars) == len(targs))
+	proj := make(substMap, len(tpars))
+	for i, tpar := range tpars {
+		if targs[i] != nil {
+			proj[tpar] = targs[i]
+		}
+	}
+	return proj
+}
+
+// makeRenameMap is like makeSubstMap, but creates a map used to rename
+// type parameters in from with the type parameters in to.
+func makeRenameMap(from, to []*TypeParam) substMap {
+	assert(len(from) == len(to))
+	proj := make(substMap, len(from))
+	for i, tpar := range from {
+		proj[tpar] = to[i]
+	}
+	return proj
+}
+
+func (m substMap) empty() bool {
+	return len(m) == 0
+}
+
+func (m substMap) lookup(tpar *TypeParam) Type {
+	if t := m[tpar]; t != nil {
+		return t
+	}
+	return tpar
+}
+
+// subst returns the type typ with its type parameters tparams replaced by
+// the corresponding type arguments in smap. Instances of generic types
+// created in the process are canonicalized through ctxt.
+// If no substitution takes place, the result is typ.
+func subst(typ Type, smap substMap, ctxt *Context) Type {
+	if smap.empty() {
+		return typ
+	}
+
+	// common cases
+	switch t := typ.(type) {
+	case *Basic:
+		return typ // nothing to do
+	case *TypeParam:
+		return smap.lookup(t)
+	}
+
+	// general case
+	s := subster{smap: smap, ctxt: ctxt}
+	return s.typ(typ)
+}
+
+type subster struct {
+	smap substMap
+	ctxt *Context
+}
+
+func (s *subster) typ(typ Type) Type {
+	switch t := typ.(type) {
+	case nil:
+		// Call typOrNil if it's possible that typ is nil.
+		panic("nil typ")
+
+	case *Basic:
+		// nothing to do
+
+	case *Array:
+		elem := s.typOrNil(t.elem)
+		if elem != t.elem {
+			return &Array{len: t.len, elem: elem}
+		}
+
+	case *Slice:
+		elem := s.typOrNil(t.elem)
+		if elem != t.elem {
+			return &Slice{elem: elem}
+		}
+
+	case *Struct:
+		if fields, copied := s.varList(t.fields); copied {
+			return &Struct{fields: fields, tags: t.tags}
+		}
+
+	case *Pointer:
+		base := s.typ(t.base)
+		if base != t.base {
+			return &Pointer{base: base}
+		}
+
+	case *Tuple:
+		return s.tuple(t)
+
+	case *Signature:
+		// Preserve the receiver: it is handled during *Interface and *Named
+		// type substitution. Naively substituting in the receiver would lead
+		// to an infinite recursion.
+		params := s.tuple(t.params)
+		results := s.tuple(t.results)
+		if params != t.params || results != t.results {
+			return &Signature{
+				rparams: t.rparams,
+				tparams: t.tparams,
+				// instantiated signatures have a nil scope
+				recv:     t.recv,
+				params:   params,
+				results:  results,
+				variadic: t.variadic,
+			}
+		}
+
+	case *Union:
+		terms, copied := s.termlist(t.terms)
+		if copied {
+			// term list substitution may introduce duplicate terms (unlikely but possible).
+			// This is ok; lazy type set computation will determine the actual type set
+			// in normal form.
+			return &Union{terms}
+		}
+
+	case *Interface:
+		methods, mcopied := s.funcList(t.methods)
+		embeddeds, ecopied := s.typeList(t.embeddeds)
+		if mcopied || ecopied {
+			if !mcopied {
+				methods = append([]*Func(nil), methods...)
+			}
+			iface := &Interface{methods: methods, embeddeds: embeddeds, implicit: t.implicit, comparable: t.comparable}
+			replaceRecvType(iface.methods, t, iface)
+			iface.Complete()
+			return iface
+		}
+
+	case *Map:
+		key := s.typ(t.key)
+		elem := s.typ(t.elem)
+		if key != t.key || elem != t.elem {
+			return &Map{key: key, elem: elem}
+		}
+
+	case *Chan:
+		elem := s.typ(t.elem)
+		if elem != t.elem {
+			return &Chan{dir: t.dir, elem: elem}
+		}
+
+	case *Named:
+		if t.targs.Len() == 0 {
+			return t // type is not parameterized
+		}
+
+		// already instantiated
+		// For each (existing) type argument targ, determine if it needs
+		// to be substituted; i.e., if it is or contains a type parameter
+		// that has a type argument for it.
+		var newTArgs []Type
+		for i, targ := range t.targs.list() {
+			newTArg := s.typ(targ)
+			if newTArg != targ {
+				if newTArgs == nil {
+					newTArgs = make([]Type, t.targs.Len())
+					copy(newTArgs, t.targs.list())
+				}
+				newTArgs[i] = newTArg
+			}
+		}
+		if newTArgs == nil {
+			return t // nothing to substitute
+		}
+		return instance(t.orig, newTArgs, s.ctxt)
+
+	case *TypeParam:
+		return s.smap.lookup(t)
+
+	default:
+		unreachable()
+	}
+
+	return typ
+}
+
+// typOrNil is like typ but if the argument is nil it is replaced with Typ[Invalid].
+// A nil type may appear in pathological cases such as type T[P any] []func(_ T([]_))
+// where an array/slice element is accessed before it is set up.
+func (s *subster) typOrNil(typ Type) Type {
+	if typ == nil {
+		return Typ[Invalid]
+	}
+	return s.typ(typ)
+}
+
+func (s *subster) tuple(t *Tuple) *Tuple {
+	if t != nil {
+		if vars, copied := s.varList(t.vars); copied {
+			return &Tuple{vars: vars}
+		}
+	}
+	return t
+}
+
+func (s *subster) varList(in []*Var) (out []*Var, copied bool) {
+	out = in
+	for i, v := range in {
+		if w := s.var_(v); w != v {
+			if !copied {
+				// first variable that got substituted => allocate new out slice
+				// and copy all variables
+				new := make([]*Var, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = w
+		}
+	}
+	return
+}
+
+func (s *subster) var_(v *Var) *Var {
+	if v != nil {
+		if typ := s.typ(v.typ); typ != v.typ {
+			copy := *v
+			copy.typ = typ
+			return &copy
+		}
+	}
+	return v
+}
+
+func (s *subster) func_(f *Func) *Func {
+	if f != nil {
+		if typ := s.typ(f.typ); typ != f.typ {
+			copy := *f
+			copy.typ = typ
+			return &copy
+		}
+	}
+	return f
+}
+
+func (s *subster) funcList(in []*Func) (out []*Func, copied bool) {
+	out = in
+	for i, f := range in {
+		if g := s.func_(f); g != f {
+			if !copied {
+				// first function that got substituted => allocate new out slice
+				// and copy all functions
+				new := make([]*Func, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = g
+		}
+	}
+	return
+}
+
+func (s *subster) typeList(in []Type) (out []Type, copied bool) {
+	out = in
+	for i, t := range in {
+		if u := s.typ(t); u != t {
+			if !copied {
+				// first type that got substituted => allocate new out slice
+				// and copy all types
+				new := make([]Type, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = u
+		}
+	}
+	return
+}
+
+func (s *subster) termlist(in []*Term) (out []*Term, copied bool) {
+	out = in
+	for i, t := range in {
+		if u := s.typ(t.typ); u != t.typ {
+			if !copied {
+				// first term that got substituted => allocate new out slice
+				// and copy all terms
+				new := make([]*Term, len(in))
+				copy(new, out)
+				out = new
+				copied = true
+			}
+			out[i] = NewTerm(t.tilde, u)
+		}
+	}
+	return
+}
+
+// replaceRecvType updates any function receivers that have type old to have
+// type new. It modifies the methods in place; they must not be shared with
+// other interfaces.
+func replaceRecvType(in []*Func, old, new Type) {
+	for i, method := range in {
+		if sig, _ := method.typ.(*Signature); sig != nil && sig.recv != nil && sig.recv.typ == old {
+			copy := *sig
+			copy.recv = NewParam(sig.recv.pos, sig.recv.pkg, sig.recv.name, new)
+			m := *method
+			m.typ = &copy
+			in[i] = &m
+		}
+	}
+}
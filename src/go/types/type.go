@@ -4,7 +4,10 @@
 
 package types
 
-import "sort"
+import (
+	"sort"
+	"sync"
+)
 
 // A Type represents a type of Go.
 // All types implement the Type interface.
@@ -199,11 +202,13 @@ type Signature struct {
 	// and store it in the Func Object) because when type-checking a function
 	// literal we call the general type checker which returns a general Type.
 	// We then unpack the *Signature and use the scope for the literal body.
-	scope    *Scope // function scope, present for package-local signatures
-	recv     *Var   // nil if not a method
-	params   *Tuple // (incoming) parameters from left to right; or nil
-	results  *Tuple // (outgoing) results from left to right; or nil
-	variadic bool   // true if the last parameter's type is of the form ...T (or string, for append built-in only)
+	scope    *Scope         // function scope, present for package-local signatures
+	recv     *Var           // nil if not a method
+	rparams  *TypeParamList // receiver type parameters from left to right, or nil
+	tparams  *TypeParamList // type parameters from left to right, or nil
+	params   *Tuple         // (incoming) parameters from left to right; or nil
+	results  *Tuple         // (outgoing) results from left to right; or nil
+	variadic bool           // true if the last parameter's type is of the form ...T (or string, for append built-in only)
 }
 
 // NewSignature returns a new function type for the given receiver, parameters,
@@ -220,7 +225,29 @@ func NewSignature(recv *Var, params, results *Tuple, variadic bool) *Signature {
 			panic("types.NewSignature: variadic parameter must be of unnamed slice type")
 		}
 	}
-	return &Signature{nil, recv, params, results, variadic}
+	return &Signature{recv: recv, params: params, results: results, variadic: variadic}
+}
+
+// NewSignatureType creates a new function type for the given receiver,
+// receiver type parameters, type parameters, parameters, and results. If
+// variadic is set, params must hold at least one parameter and the last
+// parameter must be of unnamed slice type. If recv is non-nil, typeParams
+// must be empty. If recvTypeParams is non-empty, recv must be non-nil.
+func NewSignatureType(recv *Var, recvTypeParams, typeParams []*TypeParam, params, results *Tuple, variadic bool) *Signature {
+	sig := NewSignature(recv, params, results, variadic)
+	if len(recvTypeParams) != 0 {
+		if recv == nil {
+			panic("types.NewSignatureType: function with receiver type parameters must have a receiver")
+		}
+		sig.rparams = bindTParams(recvTypeParams)
+	}
+	if len(typeParams) != 0 {
+		if recv != nil {
+			panic("types.NewSignatureType: function with type parameters cannot have a receiver")
+		}
+		sig.tparams = bindTParams(typeParams)
+	}
+	return sig
 }
 
 // Recv returns the receiver of signature s (if a method), or nil if a
@@ -231,6 +258,12 @@ func NewSignature(recv *Var, params, results *Tuple, variadic bool) *Signature {
 // contain methods whose receiver type is a different interface.
 func (s *Signature) Recv() *Var { return s.recv }
 
+// TypeParams returns the type parameters of signature s, or nil.
+func (s *Signature) TypeParams() *TypeParamList { return s.tparams }
+
+// RecvTypeParams returns the receiver type parameters of signature s, or nil.
+func (s *Signature) RecvTypeParams() *TypeParamList { return s.rparams }
+
 // Params returns the parameters of signature s, or nil.
 func (s *Signature) Params() *Tuple { return s.params }
 
@@ -246,6 +279,10 @@ type Interface struct {
 	embeddeds []Type  // ordered list of explicitly embedded types
 
 	allMethods []*Func // ordered list of methods declared with or embedded in this interface (TODO(gri): replace with mset)
+
+	implicit   bool     // interface is wrapper for type set literal (non-interface T, ~T, or A|B)
+	comparable bool     // interface is the predeclared comparable interface or embeds it directly
+	tset       *typeSet // type set described by this interface, computed lazily
 }
 
 // emptyInterface represents the empty (completed) interface
@@ -272,9 +309,8 @@ func NewInterface(methods []*Func, embeddeds []*Named) *Interface {
 }
 
 // NewInterface2 returns a new (incomplete) interface for the given methods and embedded types.
-// Each embedded type must have an underlying type of interface type (this property is not
-// verified for defined types, which may be in the process of being set up and which don't
-// have a valid underlying type yet).
+// An embedded type is either an interface, a union (*Union), or, for interfaces that are
+// used as type constraints only, any other type.
 // NewInterface2 takes ownership of the provided methods and may modify their types by setting
 // missing receivers. To compute the method set of the interface, Complete must be called.
 func NewInterface2(methods []*Func, embeddeds []Type) *Interface {
@@ -297,15 +333,6 @@ func NewInterface2(methods []*Func, embeddeds []Type) *Interface {
 	sort.Sort(byUniqueMethodName(methods))
 
 	if len(embeddeds) > 0 {
-		// All embedded types should be interfaces; however, defined types
-		// may not yet be fully resolved. Only verify that non-defined types
-		// are interfaces. This matches the behavior of the code before the
-		// fix for #25301 (issue #25596).
-		for _, t := range embeddeds {
-			if _, ok := t.(*Named); !ok && !IsInterface(t) {
-				panic("embedded type is not an interface")
-			}
-		}
 		sort.Stable(byUniqueTypeName(embeddeds))
 	}
 
@@ -341,7 +368,30 @@ func (t *Interface) NumMethods() int { return len(t.allMethods) }
 func (t *Interface) Method(i int) *Func { return t.allMethods[i] }
 
 // Empty returns true if t is the empty interface.
-func (t *Interface) Empty() bool { return len(t.allMethods) == 0 }
+// The empty interface has no methods and places no restriction on
+// the set of types implementing it.
+func (t *Interface) Empty() bool { return len(t.allMethods) == 0 && t.typeSet().isAll() }
+
+// IsComparable reports whether each type in interface t's type set is comparable.
+func (t *Interface) IsComparable() bool { return t.typeSet().isComparable() }
+
+// IsMethodSet reports whether the interface t is fully described by its method
+// set; that is, whether t may be used as an ordinary interface type rather than
+// only as a type constraint.
+func (t *Interface) IsMethodSet() bool {
+	ts := t.typeSet()
+	return !ts.comparable && ts.terms.isAll()
+}
+
+// IsImplicit reports whether the interface t is a wrapper for a type set
+// literal used as a constraint, such as the ~int in [P ~int].
+func (t *Interface) IsImplicit() bool { return t.implicit }
+
+// MarkImplicit marks the interface t as implicit, meaning this interface
+// corresponds to a constraint literal such as ~T or A|B without explicit
+// interface embedding. MarkImplicit should be called before any concurrent
+// use of implicit interfaces.
+func (t *Interface) MarkImplicit() { t.implicit = true }
 
 // Complete computes the interface's method set. It must be called by users of
 // NewInterface after the interface's embedded types are fully defined and
@@ -355,7 +405,10 @@ func (t *Interface) Complete() *Interface {
 	var allMethods []*Func
 	allMethods = append(allMethods, t.methods...)
 	for _, et := range t.embeddeds {
-		it := et.Underlying().(*Interface)
+		it, _ := et.Underlying().(*Interface)
+		if it == nil {
+			continue // type element, not an embedded interface
+		}
 		it.Complete()
 		for _, tm := range it.allMethods {
 			// Make a copy of the method and adjust its receiver type.
@@ -420,11 +473,21 @@ func (c *Chan) Dir() ChanDir { return c.dir }
 // Elem returns the element type of channel c.
 func (c *Chan) Elem() Type { return c.elem }
 
-// A Named represents a named type.
+// A Named represents a named (defined) type.
 type Named struct {
-	obj        *TypeName // corresponding declared object
-	underlying Type      // possibly a *Named during setup; never a *Named once set up completely
-	methods    []*Func   // methods declared for this type (not the method set of this type)
+	obj        *TypeName      // corresponding declared object; for instances, the object of the origin type
+	orig       *Named         // original, uninstantiated type; nil if t is not an instance
+	underlying Type           // possibly a *Named during setup; never a *Named once set up completely
+	tparams    *TypeParamList // type parameters, or nil
+	targs      *TypeList      // type arguments (after instantiation), or nil
+	methods    []*Func        // methods declared for this type (not the method set of this type)
+
+	// Instances are expanded lazily: their underlying type and
+	// methods are computed from the origin type when first needed.
+	mu         sync.Mutex
+	ctxt       *Context // context used to create this instance; or nil
+	expanded   bool     // set if underlying and methods of the instance are computed
+	incomplete bool     // set while the declaration of the (origin) type is type-checked
 }
 
 // NewNamed returns a new named type for the given type name, underlying type, and associated methods.
@@ -442,16 +505,48 @@ func NewNamed(obj *TypeName, underlying Type, methods []*Func) *Named {
 }
 
 // Obj returns the type name for the named type t.
+// For instantiated types, this is the type name of the origin type.
 func (t *Named) Obj() *TypeName { return t.obj }
 
+// Origin returns the generic type from which the named type t is
+// instantiated. If t is not an instantiated type, the result is t.
+func (t *Named) Origin() *Named {
+	if t.orig != nil {
+		return t.orig
+	}
+	return t
+}
+
+// TypeParams returns the type parameters of the named type t, or nil.
+// The result is non-nil for an (originally) generic type even if it is instantiated.
+func (t *Named) TypeParams() *TypeParamList { return t.Origin().tparams }
+
+// SetTypeParams sets the type parameters of the named type t.
+// t must not have type arguments.
+func (t *Named) SetTypeParams(tparams []*TypeParam) {
+	if t.orig != nil {
+		panic("types.Named.SetTypeParams: type is an instance")
+	}
+	t.tparams = bindTParams(tparams)
+}
+
+// TypeArgs returns the type arguments used to instantiate the named type t.
+func (t *Named) TypeArgs() *TypeList { return t.targs }
+
 // NumMethods returns the number of explicit methods whose receiver is named type t.
-func (t *Named) NumMethods() int { return len(t.methods) }
+func (t *Named) NumMethods() int { return len(t.resolve().methods) }
 
 // Method returns the i'th method of named type t for 0 <= i < t.NumMethods().
-func (t *Named) Method(i int) *Func { return t.methods[i] }
+// For an instantiated type, the method's signature is instantiated with the
+// type arguments of t.
+func (t *Named) Method(i int) *Func { return t.resolve().methods[i] }
 
 // SetUnderlying sets the underlying type and marks t as complete.
+// t must not have type arguments.
 func (t *Named) SetUnderlying(underlying Type) {
+	if t.orig != nil {
+		panic("types.Named.SetUnderlying: type is an instance")
+	}
 	if underlying == nil {
 		panic("types.Named.SetUnderlying: underlying type must not be nil")
 	}
@@ -462,7 +557,11 @@ func (t *Named) SetUnderlying(underlying Type) {
 }
 
 // AddMethod adds method m unless it is already in the method list.
+// t must not have type arguments.
 func (t *Named) AddMethod(m *Func) {
+	if t.orig != nil {
+		panic("types.Named.AddMethod: type is an instance")
+	}
 	if i, _ := lookupMethod(t.methods, m.pkg, m.name); i < 0 {
 		t.methods = append(t.methods, m)
 	}
@@ -480,7 +579,7 @@ func (s *Signature) Underlying() Type { return s }
 func (t *Interface) Underlying() Type { return t }
 func (m *Map) Underlying() Type       { return m }
 func (c *Chan) Underlying() Type      { return c }
-func (t *Named) Underlying() Type     { return t.underlying }
+func (t *Named) Underlying() Type     { return t.resolve().underlying }
 
 func (b *Basic) String() string     { return TypeString(b, nil) }
 func (a *Array) String() string     { return TypeString(a, nil) }
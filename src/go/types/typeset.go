@@ -0,0 +1,214 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import "bytes"
+
+// A typeSet describes the set of types an interface represents
+// in addition to its method set (Interface.allMethods).
+type typeSet struct {
+	terms      termlist // type terms of the type set
+	comparable bool     // invariant: !comparable || terms.isAll()
+}
+
+// topTypeSet is the type set of an interface without type terms
+// and without the comparable restriction: the set of all types.
+var topTypeSet = typeSet{terms: allTermlist}
+
+// isAll reports whether s has no type restrictions.
+func (s *typeSet) isAll() bool { return !s.comparable && s.terms.isAll() }
+
+// isEmpty reports whether s is the empty set.
+func (s *typeSet) isEmpty() bool { return s.terms.isEmpty() }
+
+// hasTerms reports whether the type set has specific type terms.
+func (s *typeSet) hasTerms() bool { return !s.terms.isEmpty() && !s.terms.isAll() }
+
+// subsetOf reports whether s1 ⊆ s2.
+func (s1 *typeSet) subsetOf(s2 *typeSet) bool { return s1.terms.subsetOf(s2.terms) }
+
+// isComparable reports whether each type in the set is comparable.
+func (s *typeSet) isComparable() bool {
+	if s.terms.isAll() {
+		return s.comparable
+	}
+	return s.is(func(t *term) bool {
+		return t != nil && comparable(t.typ, false, nil)
+	})
+}
+
+// is calls f with the specific type terms of s and reports whether
+// all calls to f returned true. If there are no specific terms, is
+// returns the result of f(nil).
+func (s *typeSet) is(f func(*term) bool) bool {
+	if !s.hasTerms() {
+		return f(nil)
+	}
+	for _, t := range s.terms {
+		assert(t.typ != nil)
+		if !f(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// underIs calls f with the underlying types of the specific type terms
+// of s and reports whether all calls to f returned true. If there are
+// no specific terms, underIs returns the result of f(nil).
+func (s *typeSet) underIs(f func(Type) bool) bool {
+	if !s.hasTerms() {
+		return f(nil)
+	}
+	for _, t := range s.terms {
+		assert(t.typ != nil)
+		// x == under(x) for ~x terms
+		u := t.typ
+		if !t.tilde {
+			u = under(u)
+		}
+		if debug {
+			assert(Identical(u, under(u)))
+		}
+		if !f(u) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *typeSet) String() string {
+	switch {
+	case s.isEmpty():
+		return "∅"
+	case s.isAll():
+		return "𝓤"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	if s.comparable {
+		buf.WriteString("comparable")
+	}
+	if !s.terms.isAll() {
+		if s.comparable {
+			buf.WriteString("; ")
+		}
+		buf.WriteString(s.terms.String())
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// typeSet returns the type set for interface t.
+func (t *Interface) typeSet() *typeSet {
+	if t.tset != nil {
+		return t.tset
+	}
+	if len(t.embeddeds) == 0 && !t.comparable {
+		// common case: no type terms
+		return &topTypeSet
+	}
+	ts, _ := computeTypeSet(t, nil)
+	return ts
+}
+
+// computeTypeSet computes the type set of the interface ityp from its
+// embedded elements. Interfaces are set up incrementally while type-
+// checking; the result is only cached (and complete is only set) if all
+// embedded elements were complete. The seen map protects against invalid
+// cyclic embeddings, which are reported elsewhere.
+func computeTypeSet(ityp *Interface, seen map[*Interface]bool) (ts *typeSet, complete bool) {
+	if ityp.tset != nil {
+		return ityp.tset, true
+	}
+	if seen[ityp] {
+		return &topTypeSet, false // cycle (error reported elsewhere)
+	}
+	if seen == nil {
+		seen = make(map[*Interface]bool)
+	}
+	seen[ityp] = true
+
+	complete = ityp.allMethods != nil
+	terms := allTermlist
+	cmp := ityp.comparable
+	for _, e := range ityp.embeddeds {
+		var tl termlist
+		switch u := under(e).(type) {
+		case nil, *Named:
+			// incomplete embedded type
+			complete = false
+			continue
+		case *Interface:
+			ets, ok := computeTypeSet(u, seen)
+			if !ok {
+				complete = false
+			}
+			cmp = cmp || ets.comparable
+			tl = ets.terms
+		case *Union:
+			var ok bool
+			tl, ok = computeUnionTerms(u, seen)
+			if !ok {
+				complete = false
+			}
+		default:
+			if u == Typ[Invalid] {
+				continue
+			}
+			tl = termlist{{false, e}}
+		}
+		terms = terms.intersect(tl)
+	}
+
+	// A comparable type set with type terms is
+	// described by the comparable terms alone.
+	if cmp && !terms.isAll() {
+		var cterms termlist
+		for _, t := range terms {
+			if comparable(t.typ, false, nil) {
+				cterms = append(cterms, t)
+			}
+		}
+		terms = cterms
+		cmp = false
+	}
+
+	ts = &typeSet{terms: terms, comparable: cmp}
+	if complete {
+		ityp.tset = ts
+	}
+	return
+}
+
+// computeUnionTerms computes the normalized term list of the union u.
+// Terms that are (method-free) interfaces contribute their type sets.
+func computeUnionTerms(u *Union, seen map[*Interface]bool) (tl termlist, complete bool) {
+	complete = true
+	for _, t := range u.terms {
+		var terms termlist
+		switch ut := under(t.typ).(type) {
+		case nil, *Named:
+			complete = false
+			continue
+		case *Interface:
+			ts, ok := computeTypeSet(ut, seen)
+			if !ok {
+				complete = false
+			}
+			terms = ts.terms
+		default:
+			if ut == Typ[Invalid] {
+				continue
+			}
+			terms = termlist{(*term)(t)}
+		}
+		// The type set of a union is the union
+		// of the type sets of its terms.
+		tl = tl.union(terms)
+	}
+	return
+}
@@ -155,13 +155,30 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 		//         m() interface{ T }
 		//     }
 		//
+		if t == universeAny {
+			buf.WriteString("any")
+			break
+		}
+		if t.implicit {
+			if len(t.methods) == 0 && len(t.embeddeds) == 1 {
+				writeType(buf, t.embeddeds[0], qf, visited)
+				break
+			}
+			// Something's wrong with the implicit interface.
+			// Print it as such and continue.
+			buf.WriteString("/* implicit */ ")
+		}
 		buf.WriteString("interface{")
 		empty := true
+		if t.comparable {
+			buf.WriteString("comparable")
+			empty = false
+		}
 		if gcCompatibilityMode {
 			// print flattened interface
 			// (useful to compare against gc-generated interfaces)
-			for i, m := range t.allMethods {
-				if i > 0 {
+			for _, m := range t.allMethods {
+				if !empty {
 					buf.WriteString("; ")
 				}
 				buf.WriteString(m.name)
@@ -170,16 +187,16 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 			}
 		} else {
 			// print explicit interface methods and embedded types
-			for i, m := range t.methods {
-				if i > 0 {
+			for _, m := range t.methods {
+				if !empty {
 					buf.WriteString("; ")
 				}
 				buf.WriteString(m.name)
 				writeSignature(buf, m.typ.(*Signature), qf, visited)
 				empty = false
 			}
-			for i, typ := range t.embeddeds {
-				if i > 0 || len(t.methods) > 0 {
+			for _, typ := range t.embeddeds {
+				if !empty {
 					buf.WriteString("; ")
 				}
 				writeType(buf, typ, qf, visited)
@@ -194,6 +211,23 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 		}
 		buf.WriteByte('}')
 
+	case *Union:
+		// Unions only appear as (syntactic) embedded elements
+		// in interfaces and syntactically cannot be empty.
+		if t.Len() == 0 {
+			buf.WriteString("empty union")
+			break
+		}
+		for i, term := range t.terms {
+			if i > 0 {
+				buf.WriteByte('|')
+			}
+			if term.tilde {
+				buf.WriteByte('~')
+			}
+			writeType(buf, term.typ, qf, visited)
+		}
+
 	case *Map:
 		buf.WriteString("map[")
 		writeType(buf, t.key, qf, visited)
@@ -238,6 +272,20 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 			s = obj.name
 		}
 		buf.WriteString(s)
+		if t.targs != nil {
+			// instantiated type
+			writeTypeList(buf, t.targs.list(), qf, visited)
+		} else if t.TypeParams().Len() != 0 {
+			// parameterized type
+			writeTParamList(buf, t.TypeParams().list(), qf, visited)
+		}
+
+	case *TypeParam:
+		s := "?"
+		if t.obj != nil {
+			s = t.obj.name
+		}
+		buf.WriteString(s)
 
 	default:
 		// For externally defined implementations of Type.
@@ -245,6 +293,46 @@ func writeType(buf *bytes.Buffer, typ Type, qf Qualifier, visited []Type) {
 	}
 }
 
+func writeTypeList(buf *bytes.Buffer, list []Type, qf Qualifier, visited []Type) {
+	buf.WriteByte('[')
+	for i, typ := range list {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeType(buf, typ, qf, visited)
+	}
+	buf.WriteByte(']')
+}
+
+func writeTParamList(buf *bytes.Buffer, list []*TypeParam, qf Qualifier, visited []Type) {
+	buf.WriteByte('[')
+	var prev Type
+	for i, tpar := range list {
+		// Determine the type parameter and its constraint.
+		// list is expected to hold type parameter names,
+		// but don't crash if that's not the case.
+		if tpar == nil {
+			buf.WriteString("<nil>")
+			continue
+		}
+		if i > 0 {
+			if tpar.bound != prev {
+				// bound changed - write previous one before advancing
+				buf.WriteByte(' ')
+				writeType(buf, prev, qf, visited)
+			}
+			buf.WriteString(", ")
+		}
+		prev = tpar.bound
+		writeType(buf, tpar, qf, visited)
+	}
+	if prev != nil {
+		buf.WriteByte(' ')
+		writeType(buf, prev, qf, visited)
+	}
+	buf.WriteByte(']')
+}
+
 func writeTuple(buf *bytes.Buffer, tup *Tuple, variadic bool, qf Qualifier, visited []Type) {
 	buf.WriteByte('(')
 	if tup != nil {
@@ -287,6 +375,10 @@ func WriteSignature(buf *bytes.Buffer, sig *Signature, qf Qualifier) {
 }
 
 func writeSignature(buf *bytes.Buffer, sig *Signature, qf Qualifier, visited []Type) {
+	if sig.tparams != nil {
+		writeTParamList(buf, sig.tparams.list(), qf, visited)
+	}
+
 	writeTuple(buf, sig.params, sig.variadic, qf, visited)
 
 	n := sig.results.Len()
@@ -178,14 +178,108 @@ func (check *Checker) typ(e ast.Expr) Type {
 
 // funcType type-checks a function or method type.
 func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast.FuncType) {
+	// Type parameters, including the (implicitly declared) type parameters
+	// of a generic receiver, are declared in their own scope enclosing the
+	// function scope: they are visible in the signature and the body, and
+	// they are not shadowed by parameters with the same name.
+	var rparams []*ast.Ident
+	if recvPar != nil && len(recvPar.List) > 0 {
+		_, _, rparams = check.unpackRecv(recvPar.List[0].Type, true)
+	}
+	var tpScope *Scope // type parameter scope, if any
+	if len(rparams) > 0 || ftyp.TypeParams != nil {
+		outer := check.scope
+		tpScope = NewScope(outer, token.NoPos, token.NoPos, "type parameters")
+		check.scope = tpScope
+		defer func() {
+			check.scope = outer
+		}()
+	}
+
+	var recvBase *Named // generic receiver base type, if any
+	var recvTyp Type    // receiver type of a method with a generic receiver base type, if any
+	if len(rparams) > 0 {
+		// collect generic receiver type parameters
+		tparams := check.declareTypeParams(nil, rparams)
+		sig.rparams = bindTParams(tparams)
+		// The receiver type parameters have the constraints of the
+		// corresponding type parameters of the receiver base type.
+		var recvTParams []*TypeParam
+		ptr, rname, _ := check.unpackRecv(recvPar.List[0].Type, false)
+		if rname != nil {
+			// The receiver base type should be a generic Named type; if not,
+			// an error is reported when type-checking the receiver type below.
+			// Like other receiver type references, this is an indirection
+			// (see Checker.typ).
+			if useCycleMarking {
+				check.push(indir)
+			}
+			if base, _ := check.genericType(rname, nil, false).(*Named); base != nil {
+				recvTParams = base.TypeParams().list()
+				recvBase = base
+			}
+			if useCycleMarking {
+				check.pop()
+			}
+		}
+		if len(tparams) == len(recvTParams) {
+			smap := makeRenameMap(recvTParams, tparams)
+			targs := make([]Type, len(tparams))
+			for i, tpar := range tparams {
+				tpar.bound = subst(recvTParams[i].bound, smap, check.ctxt)
+				targs[i] = tpar
+			}
+			// The receiver type is the receiver base type instantiated
+			// with the receiver type parameters. It is not type-checked
+			// like other types because receiver type parameters may be
+			// blank (_) and thus cannot be looked up.
+			if recvBase != nil && len(recvPar.List) == 1 {
+				inst := instance(recvBase, targs, check.ctxt)
+				check.recordInstance(rname, targs, inst)
+				recvTyp = inst
+				if ptr {
+					recvTyp = NewPointer(inst)
+				}
+			}
+		} else if len(recvTParams) > 0 {
+			check.errorf(recvPar.List[0].Type.Pos(), "got %d type parameters, but receiver base type declares %d", len(tparams), len(recvTParams))
+		}
+	}
+
+	if ftyp.TypeParams != nil {
+		if recvPar != nil {
+			check.errorf(ftyp.TypeParams.Pos(), "methods cannot have type parameters")
+			// ignore type parameters and continue
+		} else {
+			check.collectTypeParams(&sig.tparams, ftyp.TypeParams)
+		}
+	}
+
 	scope := NewScope(check.scope, token.NoPos, token.NoPos, "function")
 	scope.isFunc = true
 	check.recordScope(ftyp, scope)
 
-	recvList, _ := check.collectParams(scope, recvPar, false)
+	var recvList []*Var
+	if recvTyp != nil {
+		recvList = check.collectRecv(scope, recvPar.List[0], recvTyp)
+	} else {
+		recvList, _ = check.collectParams(scope, recvPar, false)
+	}
 	params, variadic := check.collectParams(scope, ftyp.Params, true)
 	results, _ := check.collectParams(scope, ftyp.Results, false)
 
+	// Type parameters and ordinary parameters share the same name space.
+	if tpScope != nil {
+		for _, list := range [][]*Var{recvList, params, results} {
+			for _, par := range list {
+				if alt := tpScope.Lookup(par.name); alt != nil && par.name != "_" {
+					check.errorf(par.pos, "%s redeclared in this block", par.name)
+					check.reportAltDecl(alt)
+				}
+			}
+		}
+	}
+
 	if recvPar != nil {
 		// recv parameter list present (may be empty)
 		// spec: "The receiver is specified via an extra parameter section preceding the
@@ -214,7 +308,7 @@ func (check *Checker) funcType(sig *Signature, recvPar *ast.FieldList, ftyp *ast
 					err = "type not defined in this package"
 				} else {
 					// TODO(gri) This is not correct if the underlying type is unknown yet.
-					switch u := T.underlying.(type) {
+					switch u := under(T).(type) {
 					case *Basic:
 						// unsafe.Pointer is treated like a regular pointer
 						if u.kind == UnsafePointer {
@@ -256,6 +350,10 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 		switch x.mode {
 		case typexpr:
 			typ := x.typ
+			if isGeneric(typ) {
+				check.errorf(x.pos(), "cannot use generic type %s without instantiation", typ)
+				break
+			}
 			def.setUnderlying(typ)
 			return typ
 		case invalid:
@@ -273,6 +371,10 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 		switch x.mode {
 		case typexpr:
 			typ := x.typ
+			if isGeneric(typ) {
+				check.errorf(x.pos(), "cannot use generic type %s without instantiation", typ)
+				break
+			}
 			def.setUnderlying(typ)
 			return typ
 		case invalid:
@@ -283,6 +385,12 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 			check.errorf(x.pos(), "%s is not a type", &x)
 		}
 
+	case *ast.IndexExpr:
+		return check.instantiatedType(e.X, []ast.Expr{e.Index}, def, path)
+
+	case *ast.IndexListExpr:
+		return check.instantiatedType(e.X, e.Indices, def, path)
+
 	case *ast.ParenExpr:
 		return check.typExpr(e.X, def, path)
 
@@ -291,13 +399,13 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 			typ := new(Array)
 			def.setUnderlying(typ)
 			typ.len = check.arrayLength(e.Len)
-			typ.elem = check.typExpr(e.Elt, nil, path)
+			typ.elem = check.varTypExpr(e.Elt, nil, path)
 			return typ
 
 		} else {
 			typ := new(Slice)
 			def.setUnderlying(typ)
-			typ.elem = check.typ(e.Elt)
+			typ.elem = check.varType(e.Elt)
 			return typ
 		}
 
@@ -310,10 +418,13 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 	case *ast.StarExpr:
 		typ := new(Pointer)
 		def.setUnderlying(typ)
-		typ.base = check.typ(e.X)
+		typ.base = check.varType(e.X)
 		return typ
 
 	case *ast.FuncType:
+		if e.TypeParams != nil {
+			check.invalidAST(e.TypeParams.Pos(), "function type must have no type parameters")
+		}
 		typ := new(Signature)
 		def.setUnderlying(typ)
 		check.funcType(typ, nil, e)
@@ -329,8 +440,8 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 		typ := new(Map)
 		def.setUnderlying(typ)
 
-		typ.key = check.typ(e.Key)
-		typ.elem = check.typ(e.Value)
+		typ.key = check.varType(e.Key)
+		typ.elem = check.varType(e.Value)
 
 		// spec: "The comparison operators == and != must be fully defined
 		// for operands of the key type; thus the key type must not be a
@@ -364,7 +475,7 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 		}
 
 		typ.dir = dir
-		typ.elem = check.typ(e.Value)
+		typ.elem = check.varType(e.Value)
 		return typ
 
 	default:
@@ -376,6 +487,278 @@ func (check *Checker) typExprInternal(e ast.Expr, def *Named, path []*TypeName)
 	return typ
 }
 
+// varType type-checks the type expression e and returns its type, or Typ[Invalid].
+// The type must not be an interface with type constraints (an interface with
+// type terms, or one that is or embeds comparable): such interfaces may only
+// be used as type constraints.
+func (check *Checker) varType(e ast.Expr) Type {
+	typ := check.typ(e)
+	check.validVarType(e, typ)
+	return typ
+}
+
+// varTypExpr is like varType but takes the def and path arguments of typExpr.
+func (check *Checker) varTypExpr(e ast.Expr, def *Named, path []*TypeName) Type {
+	typ := check.typExpr(e, def, path)
+	check.validVarType(e, typ)
+	return typ
+}
+
+// validVarType reports an error if typ, the type of e, is a constraint
+// interface. The check is delayed because it requires fully set up
+// interfaces.
+func (check *Checker) validVarType(e ast.Expr, typ Type) {
+	// fast path: only (named) interfaces may be constraint interfaces
+	switch typ.(type) {
+	case *Named, *Interface:
+	default:
+		return
+	}
+	check.later(func() {
+		if t, _ := under(typ).(*Interface); t != nil && !isTypeParam(typ) {
+			tset := t.typeSet()
+			if !tset.isAll() || tset.comparable {
+				if tset.comparable {
+					check.softErrorf(e.Pos(), "cannot use type %s outside a type constraint: interface is (or embeds) comparable", typ)
+				} else {
+					check.softErrorf(e.Pos(), "cannot use type %s outside a type constraint: interface contains type constraints", typ)
+				}
+			}
+		}
+	})
+}
+
+// genericType is like typExpr but the type must be an (uninstantiated)
+// generic type denoted by a (possibly qualified) type name. If reportErr
+// is set, an error is reported if e does not denote a generic type.
+// The result is the generic type, or Typ[Invalid].
+func (check *Checker) genericType(e ast.Expr, path []*TypeName, reportErr bool) Type {
+	var x operand
+	switch e := e.(type) {
+	case *ast.Ident:
+		check.ident(&x, e, nil, path)
+	case *ast.SelectorExpr:
+		check.selector(&x, e)
+	case *ast.ParenExpr:
+		return check.genericType(e.X, path, reportErr)
+	default:
+		if reportErr {
+			check.errorf(e.Pos(), "%s is not a generic type", e)
+		}
+		return Typ[Invalid]
+	}
+
+	switch x.mode {
+	case typexpr:
+		if !isGeneric(x.typ) {
+			if reportErr && x.typ != Typ[Invalid] {
+				check.errorf(x.pos(), "%s is not a generic type", x.typ)
+			}
+			return Typ[Invalid]
+		}
+		check.recordTypeAndValue(e, typexpr, x.typ, nil)
+		return x.typ
+	case invalid:
+		// ignore - error reported before
+	case novalue:
+		if reportErr {
+			check.errorf(x.pos(), "%s used as type", &x)
+		}
+	default:
+		if reportErr {
+			check.errorf(x.pos(), "%s is not a type", &x)
+		}
+	}
+	return Typ[Invalid]
+}
+
+// instantiatedType type-checks the instantiation x[xlist...] of a generic
+// type and returns the (lazily expanded) instance, or Typ[Invalid].
+func (check *Checker) instantiatedType(x ast.Expr, xlist []ast.Expr, def *Named, path []*TypeName) Type {
+	gtyp := check.genericType(x, path, true)
+	if gtyp == Typ[Invalid] {
+		def.setUnderlying(gtyp)
+		return gtyp // error reported before
+	}
+	orig := gtyp.(*Named) // genericType only returns generic *Named types
+
+	// evaluate type arguments
+	targs := check.typeList(xlist)
+	if targs == nil {
+		def.setUnderlying(Typ[Invalid]) // avoid errors later due to lazy instantiation
+		return Typ[Invalid]
+	}
+
+	if n := orig.TypeParams().Len(); len(targs) != n {
+		pos := xlist[len(xlist)-1].End()
+		if len(targs) > n {
+			pos = xlist[n].Pos()
+		}
+		check.errorf(pos, "got %d type arguments for %s, but %s has %d type parameters", len(targs), orig, orig, n)
+		def.setUnderlying(Typ[Invalid])
+		return Typ[Invalid]
+	}
+
+	inst := instance(orig, targs, check.ctxt).(*Named)
+	def.setUnderlying(inst)
+	check.recordInstance(x, targs, inst)
+
+	// Verify the type arguments once all involved types are set up.
+	check.later(func() {
+		if i, err := check.verify(orig.TypeParams().list(), targs, check.ctxt); err != nil {
+			check.softErrorf(xlist[i].Pos(), "%s", err)
+		}
+	})
+
+	return inst
+}
+
+// typeList type-checks the type expressions in list and returns the
+// corresponding types. If an error occurred, the result is nil, but
+// all list elements were type-checked.
+func (check *Checker) typeList(list []ast.Expr) []Type {
+	res := make([]Type, len(list)) // res != nil even if len(list) == 0
+	for i, x := range list {
+		t := check.varType(x)
+		if t == Typ[Invalid] {
+			res = nil
+		}
+		if res != nil {
+			res[i] = t
+		}
+	}
+	return res
+}
+
+// collectTypeParams declares the type parameters of list in the current
+// scope, type-checks their constraints, and sets *dst to the resulting
+// (bound) type parameter list.
+func (check *Checker) collectTypeParams(dst **TypeParamList, list *ast.FieldList) {
+	var tparams []*TypeParam
+	// Declare type parameters up-front, with the empty interface as
+	// type bound. The scope of type parameters starts at the beginning
+	// of the type parameter list (so we can have mutually recursive
+	// constraints).
+	for _, f := range list.List {
+		tparams = check.declareTypeParams(tparams, f.Names)
+	}
+
+	// Set the type parameters before collecting the constraints because
+	// the generic type may be used by the constraints.
+	*dst = bindTParams(tparams)
+
+	index := 0
+	for _, f := range list.List {
+		var bound Type
+		if f.Type != nil {
+			bound = check.bound(f.Type)
+			if isTypeParam(bound) {
+				check.errorf(f.Type.Pos(), "cannot use a type parameter as constraint")
+				bound = Typ[Invalid]
+			}
+		} else {
+			check.invalidAST(f.Pos(), "missing type constraint")
+			bound = Typ[Invalid]
+		}
+		for i := range f.Names {
+			tparams[index+i].bound = bound
+		}
+		index += len(f.Names)
+	}
+}
+
+// declareTypeParams declares a new type parameter for each name in
+// names and appends it to tparams. The type parameters are constrained
+// by the empty interface until their constraints are known.
+func (check *Checker) declareTypeParams(tparams []*TypeParam, names []*ast.Ident) []*TypeParam {
+	for _, name := range names {
+		tname := NewTypeName(name.Pos(), check.pkg, name.Name, nil)
+		tpar := NewTypeParam(tname, &emptyInterface) // sets tname.typ
+		check.declare(check.scope, name, tname, check.scope.pos)
+		tparams = append(tparams, tpar)
+	}
+	return tparams
+}
+
+// bound type-checks the type constraint x. A type set literal of the
+// form ~T or A|B may only appear as constraint; it is wrapped in an
+// implicit interface so that only interface type-checking needs to
+// deal with such type expressions.
+func (check *Checker) bound(x ast.Expr) Type {
+	wrap := false
+	switch op := x.(type) {
+	case *ast.UnaryExpr:
+		wrap = op.Op == token.TILDE
+	case *ast.BinaryExpr:
+		wrap = op.Op == token.OR
+	}
+	if wrap {
+		x = &ast.InterfaceType{Interface: x.Pos(), Methods: &ast.FieldList{List: []*ast.Field{{Type: x}}}}
+		t := check.typ(x)
+		// mark t as implicit interface if all went well
+		if t, _ := t.(*Interface); t != nil {
+			t.implicit = true
+		}
+		return t
+	}
+	return check.typ(x)
+}
+
+// unpackRecv unpacks a receiver type expression and returns its components:
+// ptr indicates whether rtyp is a pointer receiver, rname is the receiver
+// base type name, and tparams are its type parameters, if any. The type
+// parameters are only unpacked if unpackParams is set. If rname is nil,
+// the receiver is unusable (an error is reported elsewhere).
+func (check *Checker) unpackRecv(rtyp ast.Expr, unpackParams bool) (ptr bool, rname *ast.Ident, tparams []*ast.Ident) {
+L: // unpack receiver type
+	// This accepts invalid receivers such as ***T and does not
+	// work for other invalid receivers, but we don't care. The
+	// validity of receiver expressions is checked elsewhere.
+	for {
+		switch t := rtyp.(type) {
+		case *ast.ParenExpr:
+			rtyp = t.X
+		case *ast.StarExpr:
+			ptr = true
+			rtyp = t.X
+		default:
+			break L
+		}
+	}
+
+	// unpack type parameters, if any
+	var indices []ast.Expr
+	switch t := rtyp.(type) {
+	case *ast.IndexExpr:
+		rtyp, indices = t.X, []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		rtyp, indices = t.X, t.Indices
+	}
+	if unpackParams {
+		for _, arg := range indices {
+			var par *ast.Ident
+			switch arg := arg.(type) {
+			case *ast.Ident:
+				par = arg
+			case *ast.BadExpr:
+				// ignore - error already reported by parser
+			default:
+				check.errorf(arg.Pos(), "receiver type parameter %s must be an identifier", arg)
+			}
+			if par == nil {
+				par = &ast.Ident{NamePos: arg.Pos(), Name: "_"}
+			}
+			tparams = append(tparams, par)
+		}
+	}
+
+	// unpack receiver name
+	if name, _ := rtyp.(*ast.Ident); name != nil {
+		rname = name
+	}
+	return
+}
+
 // typeOrNil type-checks the type expression (or nil value) e
 // and returns the typ of e, or nil.
 // If e is neither a type nor nil, typOrNil returns Typ[Invalid].
@@ -445,7 +828,7 @@ func (check *Checker) collectParams(scope *Scope, list *ast.FieldList, variadicO
 				// ignore ... and continue
 			}
 		}
-		typ := check.typ(ftype)
+		typ := check.varType(ftype)
 		// The parser ensures that f.Tag is nil and we don't
 		// care if a constructed AST contains a non-nil tag.
 		if len(field.Names) > 0 {
@@ -483,6 +866,26 @@ func (check *Checker) collectParams(scope *Scope, list *ast.FieldList, variadicO
 	return
 }
 
+// collectRecv declares the receiver parameter of a method with a generic
+// receiver base type in the given scope and returns it. The receiver type
+// recvTyp was computed by the caller.
+func (check *Checker) collectRecv(scope *Scope, field *ast.Field, recvTyp Type) []*Var {
+	check.recordTypeAndValue(field.Type, typexpr, recvTyp, nil)
+	if len(field.Names) == 0 {
+		// anonymous receiver
+		par := NewParam(field.Type.Pos(), check.pkg, "", recvTyp)
+		check.recordImplicit(field, par)
+		return []*Var{par}
+	}
+	var params []*Var
+	for _, name := range field.Names {
+		par := NewParam(name.Pos(), check.pkg, name.Name, recvTyp)
+		check.declare(scope, name, par, scope.pos)
+		params = append(params, par)
+	}
+	return params
+}
+
 func (check *Checker) declareInSet(oset *objset, pos token.Pos, obj Object) bool {
 	if alt := oset.insert(obj); alt != nil {
 		check.errorf(pos, "%s redeclared", obj.Name())
@@ -499,8 +902,8 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 		return
 	}
 
-	// collect embedded interfaces
-	// Only needed for printing and API. Delay collection
+	// collect embedded elements (interfaces and type terms)
+	// Only needed for printing, API, and type sets. Delay collection
 	// to end of type-checking (for package-global interfaces)
 	// when all types are complete. Local interfaces are handled
 	// after each statement (as each statement processes delayed
@@ -523,34 +926,55 @@ func (check *Checker) interfaceType(ityp *Interface, iface *ast.InterfaceType, d
 		}(check.context)
 		check.context = interfaceContext
 
+		addedMethods := false
 		for _, f := range iface.Methods.List {
 			if len(f.Names) == 0 {
-				typ := check.typ(f.Type)
-				// typ should be a named type denoting an interface
-				// (the parser will make sure it's a named type but
-				// constructed ASTs may be wrong).
+				var typ Type
+				switch x := f.Type.(type) {
+				case *ast.UnaryExpr, *ast.BinaryExpr:
+					// type set literal ~T or A|B
+					typ = check.union(x)
+				default:
+					typ = check.typ(x)
+				}
 				if typ == Typ[Invalid] {
 					continue // error reported before
 				}
-				embed, _ := typ.Underlying().(*Interface)
-				if embed == nil {
-					check.errorf(f.Type.Pos(), "%s is not an interface", typ)
+				if isTypeParam(typ) {
+					check.errorf(f.Type.Pos(), "cannot embed a type parameter")
 					continue
 				}
-				// Correct embedded interfaces must be complete -
-				// don't just assert, but report error since this
-				// used to be the underlying cause for issue #18395.
-				if embed.allMethods == nil {
-					check.dump("%v: incomplete embedded interface %s", f.Type.Pos(), typ)
-					unreachable()
+				if embed, _ := under(typ).(*Interface); embed != nil {
+					// Correct embedded interfaces must be complete -
+					// don't just assert, but report error since this
+					// used to be the underlying cause for issue #18395.
+					if embed.allMethods == nil {
+						check.dump("%v: incomplete embedded interface %s", f.Type.Pos(), typ)
+						unreachable()
+					}
+					// The methods of embedded instantiated interfaces
+					// are not known to infoFromTypeLit which works on
+					// the syntax only; add them now.
+					switch f.Type.(type) {
+					case *ast.IndexExpr, *ast.IndexListExpr:
+						for _, m := range embed.allMethods {
+							if i, _ := lookupMethod(ityp.allMethods, m.pkg, m.name); i < 0 {
+								ityp.allMethods = append(ityp.allMethods, m)
+								addedMethods = true
+							}
+						}
+					}
 				}
-				// collect interface
+				// collect embedded element
 				ityp.embeddeds = append(ityp.embeddeds, typ)
 			}
 		}
 		// sort to match NewInterface/NewInterface2
 		// TODO(gri) we may be able to switch to source order
 		sort.Stable(byUniqueTypeName(ityp.embeddeds))
+		if addedMethods {
+			sort.Sort(byUniqueMethodName(ityp.allMethods))
+		}
 	})
 
 	// compute method set
@@ -709,7 +1133,7 @@ func (check *Checker) structType(styp *Struct, e *ast.StructType, path []*TypeNa
 	}
 
 	for _, f := range list.List {
-		typ = check.typExpr(f.Type, nil, path)
+		typ = check.varTypExpr(f.Type, nil, path)
 		tag = check.tag(f.Tag)
 		if len(f.Names) > 0 {
 			// named fields
@@ -730,6 +1154,11 @@ func (check *Checker) structType(styp *Struct, e *ast.StructType, path []*TypeNa
 				continue
 			}
 			t, isPtr := deref(typ)
+			if isTypeParam(t) {
+				check.errorf(pos, "embedded field type cannot be a (pointer to a) type parameter")
+				addInvalid(name, pos)
+				continue
+			}
 			// Because we have a name, typ must be of the form T or *T, where T is the name
 			// of a (named or alias) type, and t (= deref(typ)) must be the type of T.
 			switch t := t.Underlying().(type) {
@@ -778,6 +1207,10 @@ func embeddedFieldIdent(e ast.Expr) *ast.Ident {
 		}
 	case *ast.SelectorExpr:
 		return e.Sel
+	case *ast.IndexExpr:
+		return embeddedFieldIdent(e.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldIdent(e.X)
 	}
 	return nil // invalid embedded field
 }
@@ -0,0 +1,342 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements type unification.
+
+package types
+
+import "bytes"
+
+// The unifier maintains a list of type parameters and a corresponding
+// list of types (initially nil) that are inferred for them. Unification
+// of two types x and y compares them structurally and records the
+// corresponding types for any type parameters of the unifier found in
+// either x or y.
+//
+// If exact unification is requested, two types unify if they are
+// identical modulo the inferred type parameters. With inexact unification,
+// a defined type also unifies with an unnamed type literal if its
+// underlying type unifies with the literal, and channel directions
+// are ignored.
+type unifier struct {
+	exact   bool
+	tparams []*TypeParam // type parameters for which types are inferred
+	types   []Type       // inferred types, indexed like tparams; nil if not yet known
+	depth   int          // recursion depth during unification
+}
+
+// unificationDepthLimit is the maximum recursion depth of unification;
+// it prevents endless recursion for pathological inputs.
+const unificationDepthLimit = 50
+
+// newUnifier returns a new unifier for the type parameters tparams, with
+// initial types targs (which may be shorter than tparams or contain nil
+// entries for unknown types).
+func newUnifier(exact bool, tparams []*TypeParam, targs []Type) *unifier {
+	types := make([]Type, len(tparams))
+	copy(types, targs)
+	return &unifier{exact: exact, tparams: tparams, types: types}
+}
+
+// index returns the index of the type parameter typ in u.tparams,
+// or -1 if typ is not one of them.
+func (u *unifier) index(typ Type) int {
+	if tpar, _ := typ.(*TypeParam); tpar != nil {
+		for i, t := range u.tparams {
+			if t == tpar {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// at returns the type inferred for the i'th type parameter, or nil.
+func (u *unifier) at(i int) Type { return u.types[i] }
+
+// set records typ as the inferred type for the i'th type parameter.
+func (u *unifier) set(i int, typ Type) {
+	assert(typ != nil)
+	u.types[i] = typ
+}
+
+// unknowns returns the number of type parameters for which no type has been inferred yet.
+func (u *unifier) unknowns() int {
+	n := 0
+	for _, t := range u.types {
+		if t == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// inferred returns the list of inferred types (via unification) for the
+// type parameters of u. Entries are nil for type parameters without an
+// inferred type.
+func (u *unifier) inferred() []Type {
+	list := make([]Type, len(u.types))
+	copy(list, u.types)
+	return list
+}
+
+func (u *unifier) String() string {
+	var buf bytes.Buffer
+	for i, tpar := range u.tparams {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(tpar.String())
+		buf.WriteString(": ")
+		WriteType(&buf, u.types[i], nil)
+	}
+	return buf.String()
+}
+
+// unify attempts to unify x and y and reports whether it succeeded.
+func (u *unifier) unify(x, y Type) bool {
+	return u.nify(x, y, nil)
+}
+
+// nifyEq is implemented in terms of nify and reports whether x and y
+// are pointer-identical or unify.
+func (u *unifier) nifyEq(x, y Type, p *ifacePair) bool {
+	return x == y || u.nify(x, y, p)
+}
+
+// nify implements the core unification algorithm which is an
+// adapted version of Checker.identical. For changes to that
+// code the corresponding changes should be made here.
+// Must not be called directly from outside the unifier.
+func (u *unifier) nify(x, y Type, p *ifacePair) bool {
+	u.depth++
+	defer func() { u.depth-- }()
+	if u.depth > unificationDepthLimit {
+		return false
+	}
+
+	if !u.exact {
+		// If exact unification is known to fail because we attempt to
+		// match a type name against an unnamed type literal, consider the
+		// underlying type of the named type.
+		// (We use !isNamed to exclude any type with a name, including
+		// basic types and type parameters; the rest are unnamed types.)
+		if nx, _ := x.(*Named); nx != nil && !isNamed(y) {
+			return u.nify(under(nx), y, p)
+		} else if ny, _ := y.(*Named); ny != nil && !isNamed(x) {
+			return u.nify(x, under(ny), p)
+		}
+	}
+
+	// Cases where at least one of x or y is a type parameter.
+	switch i, j := u.index(x), u.index(y); {
+	case i >= 0 && j >= 0:
+		// both x and y are type parameters
+		if i == j {
+			return true
+		}
+		tx, ty := u.at(i), u.at(j)
+		switch {
+		case tx != nil && ty != nil:
+			// both type parameters have an inferred type; they must match
+			return u.nifyEq(tx, ty, p)
+		case tx != nil:
+			u.set(j, tx)
+		case ty != nil:
+			u.set(i, ty)
+		default:
+			// Neither type is known yet; record y for x. If a type is
+			// inferred for y later, it will be substituted for x when
+			// the final type arguments are computed.
+			u.set(i, y)
+		}
+		return true
+
+	case i >= 0:
+		// x is a type parameter, y is not
+		if tx := u.at(i); tx != nil {
+			return u.nifyEq(tx, y, p)
+		}
+		// otherwise, infer type from y
+		u.set(i, y)
+		return true
+
+	case j >= 0:
+		// y is a type parameter, x is not
+		if ty := u.at(j); ty != nil {
+			return u.nifyEq(x, ty, p)
+		}
+		// otherwise, infer type from x
+		u.set(j, x)
+		return true
+	}
+
+	// For type unification, do not shortcut (x == y) for identical
+	// types. Instead keep comparing them element-wise to unify the
+	// matching (and equal type parameter types). A simple test case
+	// where this matters is: func f[P any](a P) { f(a) } .
+
+	switch x := x.(type) {
+	case *Basic:
+		// Basic types are singletons except for the rune and byte
+		// aliases, thus we cannot solely rely on the x == y check
+		// above. See also comment in TypeName.IsAlias.
+		if y, ok := y.(*Basic); ok {
+			return x.kind == y.kind
+		}
+
+	case *Array:
+		// Two array types are identical if they have identical element types
+		// and the same array length.
+		if y, ok := y.(*Array); ok {
+			// If one or both array lengths are unknown (< 0) due to some error,
+			// assume they are the same to avoid spurious follow-on errors.
+			return (x.len < 0 || y.len < 0 || x.len == y.len) && u.nify(x.elem, y.elem, p)
+		}
+
+	case *Slice:
+		// Two slice types are identical if they have identical element types.
+		if y, ok := y.(*Slice); ok {
+			return u.nify(x.elem, y.elem, p)
+		}
+
+	case *Struct:
+		// Two struct types are identical if they have the same sequence of fields,
+		// and if corresponding fields have the same names, and identical types,
+		// and identical tags. Two embedded fields are considered to have the same
+		// name. Lower-case field names from different packages are always different.
+		if y, ok := y.(*Struct); ok {
+			if x.NumFields() == y.NumFields() {
+				for i, f := range x.fields {
+					g := y.fields[i]
+					if f.embedded != g.embedded ||
+						x.Tag(i) != y.Tag(i) ||
+						!f.sameId(g.pkg, g.name) ||
+						!u.nify(f.typ, g.typ, p) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+
+	case *Pointer:
+		// Two pointer types are identical if they have identical base types.
+		if y, ok := y.(*Pointer); ok {
+			return u.nify(x.base, y.base, p)
+		}
+
+	case *Tuple:
+		// Two tuples types are identical if they have the same number of elements
+		// and corresponding elements have identical types.
+		if y, ok := y.(*Tuple); ok {
+			if x.Len() == y.Len() {
+				if x != nil {
+					for i, v := range x.vars {
+						w := y.vars[i]
+						if !u.nify(v.typ, w.typ, p) {
+							return false
+						}
+					}
+				}
+				return true
+			}
+		}
+
+	case *Signature:
+		// Two function types are identical if they have the same number of parameters
+		// and result values, corresponding parameter and result types are identical,
+		// and either both functions are variadic or neither is. Parameter and result
+		// names are not required to match.
+		// TODO(gri) handle type parameters or document why we can ignore them.
+		if y, ok := y.(*Signature); ok {
+			return x.variadic == y.variadic &&
+				u.nify(x.params, y.params, p) &&
+				u.nify(x.results, y.results, p)
+		}
+
+	case *Interface:
+		// Two interface types are identical if they have the same set of methods with
+		// the same names and identical function types. Lower-case method names from
+		// different packages are always different. The order of the methods is irrelevant.
+		if y, ok := y.(*Interface); ok {
+			xset := x.typeSet()
+			yset := y.typeSet()
+			if xset.comparable != yset.comparable || !xset.terms.equal(yset.terms) {
+				return false
+			}
+			a := x.allMethods
+			b := y.allMethods
+			if len(a) == len(b) {
+				// Interface types are the only types where cycles can occur that are not
+				// "terminated" via named types; and such cycles can only be created via
+				// method parameter types that are anonymous interfaces (directly or
+				// indirectly) embedding the current interface. See the comment in
+				// identical for details.
+				q := &ifacePair{x, y, p}
+				for p != nil {
+					if p.identical(q) {
+						return true // same pair was compared before
+					}
+					p = p.prev
+				}
+				for i, f := range a {
+					g := b[i]
+					if f.Id() != g.Id() || !u.nify(f.typ, g.typ, q) {
+						return false
+					}
+				}
+				return true
+			}
+		}
+
+	case *Map:
+		// Two map types are identical if they have identical key and value types.
+		if y, ok := y.(*Map); ok {
+			return u.nify(x.key, y.key, p) && u.nify(x.elem, y.elem, p)
+		}
+
+	case *Chan:
+		// Two channel types are identical if they have identical value types.
+		// With exact unification, the channel directions must match as well.
+		if y, ok := y.(*Chan); ok {
+			return (!u.exact || x.dir == y.dir) && u.nify(x.elem, y.elem, p)
+		}
+
+	case *Named:
+		// Two named types are identical if their type names originate
+		// in the same type declaration; if they are instantiated, their
+		// type arguments must unify.
+		if y, ok := y.(*Named); ok {
+			if x.obj != y.obj {
+				return false
+			}
+			xargs := x.targs.list()
+			yargs := y.targs.list()
+			if len(xargs) != len(yargs) {
+				return false
+			}
+			for i, xa := range xargs {
+				if !u.nify(xa, yargs[i], p) {
+					return false
+				}
+			}
+			return true
+		}
+
+	case *TypeParam:
+		// Two type parameters (which are not part of the type parameters of the
+		// unifier as those are handled in the beginning of this function)
+		// are identical if they originate in the same declaration.
+		return x == y
+
+	case nil:
+		// avoid a crash in case of nil type
+
+	default:
+		unreachable()
+	}
+
+	return false
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package types
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// A Union represents a union of terms embedded in an interface.
+type Union struct {
+	terms []*Term // list of syntactical terms (not a canonicalized termlist)
+}
+
+// NewUnion returns a new Union type with the given terms.
+// It is an error to create an empty union; they are syntactically not possible.
+func NewUnion(terms []*Term) *Union {
+	if len(terms) == 0 {
+		panic("empty union")
+	}
+	return &Union{terms}
+}
+
+// Len returns the number of terms of union u.
+func (u *Union) Len() int { return len(u.terms) }
+
+// Term returns the i'th term of union u.
+func (u *Union) Term(i int) *Term { return u.terms[i] }
+
+func (u *Union) Underlying() Type { return u }
+func (u *Union) String() string   { return TypeString(u, nil) }
+
+// A Term represents a term in a Union.
+type Term term
+
+// NewTerm returns a new union term.
+func NewTerm(tilde bool, typ Type) *Term { return &Term{tilde, typ} }
+
+// Tilde reports whether the term t has the form ~T.
+func (t *Term) Tilde() bool { return t.tilde }
+
+// Type returns the type of the term t.
+func (t *Term) Type() Type { return t.typ }
+
+func (t *Term) String() string { return (*term)(t).String() }
+
+// Avoid excessive type-checking times due to quadratic termlist operations.
+const maxTermCount = 100
+
+// union type-checks the type set literal uexpr (a type term or a union
+// of type terms, as it may appear in an interface) and returns the
+// corresponding *Union, or Typ[Invalid].
+func (check *Checker) union(uexpr ast.Expr) Type {
+	var terms []*Term
+	var tlist []ast.Expr // the term expressions, for error positions
+	for _, x := range flattenUnion(nil, uexpr) {
+		term := check.typeTerm(x)
+		if term == nil {
+			return Typ[Invalid]
+		}
+		terms = append(terms, term)
+		tlist = append(tlist, x)
+	}
+	if len(terms) > maxTermCount {
+		check.errorf(uexpr.Pos(), "cannot handle more than %d union terms (implementation limitation)", maxTermCount)
+		return Typ[Invalid]
+	}
+
+	u := &Union{terms}
+
+	// Check validity of terms.
+	// Do this check later because it requires types to be set up.
+	// Note: This is a quadratic algorithm, but unions tend to be short.
+	check.later(func() {
+		for i, t := range terms {
+			if t.typ == Typ[Invalid] {
+				continue
+			}
+
+			u := under(t.typ)
+			f, _ := u.(*Interface)
+			if t.tilde {
+				if f != nil {
+					check.errorf(tlist[i].Pos(), "invalid use of ~ (%s is an interface)", t.typ)
+					continue // don't report another error for t
+				}
+
+				if !Identical(u, t.typ) {
+					check.errorf(tlist[i].Pos(), "invalid use of ~ (underlying type of %s is %s)", t.typ, u)
+					continue
+				}
+			}
+
+			// Stand-alone embedded interfaces are not unions, and embedded
+			// interfaces with tilde are excluded above. If we reach here,
+			// we must have at least two terms in the syntactic term list.
+			if f != nil {
+				if len(f.allMethods) != 0 {
+					check.errorf(tlist[i].Pos(), "cannot use %s in union (%s contains methods)", t, t)
+					continue
+				}
+				if t.typ == universeComparable.Type() {
+					check.errorf(tlist[i].Pos(), "cannot use comparable in union")
+					continue
+				}
+				if f.typeSet().comparable {
+					check.errorf(tlist[i].Pos(), "cannot use %s in union (%s embeds comparable)", t, t)
+				}
+				continue
+			}
+
+			// Report overlapping (non-disjoint) terms such as
+			// a|a, a|~a, ~a|~a, and ~a|A (where under(A) == a).
+			if j := overlappingTerm(terms[:i], t); j >= 0 {
+				check.softErrorf(tlist[i].Pos(), "overlapping terms %s and %s", t, terms[j])
+			}
+		}
+	})
+
+	return u
+}
+
+// typeTerm type-checks the union term x and returns the corresponding
+// term, or nil if x is not a valid type term.
+func (check *Checker) typeTerm(x ast.Expr) *Term {
+	tilde := false
+	if op, _ := x.(*ast.UnaryExpr); op != nil && op.Op == token.TILDE {
+		x = op.X
+		tilde = true
+	}
+
+	typ := check.typ(x)
+	// Embedding stand-alone type parameters is not permitted (issue #47127).
+	// We don't need this restriction anymore if we make the underlying type of a type
+	// parameter its constraint interface: if we embed a lone type parameter, we will
+	// simply use its underlying type (like we do for other named, embedded interfaces),
+	// and since the underlying type is an interface the embedding is well defined.
+	if isTypeParam(typ) {
+		check.error(x.Pos(), "term cannot be a type parameter")
+		typ = Typ[Invalid]
+	}
+	return &Term{tilde, typ}
+}
+
+// flattenUnion walks a union type expression of the form A | B | C | ...,
+// extracting each term into list.
+func flattenUnion(list []ast.Expr, x ast.Expr) []ast.Expr {
+	if o, _ := x.(*ast.BinaryExpr); o != nil && o.Op == token.OR {
+		list = flattenUnion(list, o.X)
+		x = o.Y
+	}
+	return append(list, x)
+}
+
+// overlappingTerm reports the index of the term x in xl that
+// overlaps (intersects) with y. Otherwise it returns -1.
+func overlappingTerm(terms []*Term, y *Term) int {
+	assert(!IsInterface(y.typ))
+	for i, x := range terms {
+		if IsInterface(x.typ) {
+			continue
+		}
+		// disjoint requires non-nil, non-top arguments,
+		// and non-interface types as term elements.
+		if debug {
+			if x == nil || x.typ == nil || y == nil || y.typ == nil {
+				panic("empty or top union term")
+			}
+		}
+		if !(*term)(x).disjoint((*term)(y)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -21,9 +21,11 @@ var Universe *Scope
 var Unsafe *Package
 
 var (
-	universeIota *Const
-	universeByte *Basic // uint8 alias, but has name "byte"
-	universeRune *Basic // int32 alias, but has name "rune"
+	universeIota       *Const
+	universeByte       *Basic // uint8 alias, but has name "byte"
+	universeRune       *Basic // int32 alias, but has name "rune"
+	universeAny        Type
+	universeComparable Object
 )
 
 // Typ contains the predeclared *Basic types indexed by their
@@ -83,6 +85,24 @@ func defPredeclaredTypes() {
 	typ := &Named{underlying: NewInterface2([]*Func{err}, nil).Complete()}
 	sig.recv = NewVar(token.NoPos, nil, "", typ)
 	def(NewTypeName(token.NoPos, nil, "error", typ))
+
+	// type any = interface{}
+	// (a distinct empty interface so that it can be printed as "any")
+	def(NewTypeName(token.NoPos, nil, "any", &Interface{allMethods: markComplete}))
+
+	// type comparable interface{} // marked as comparable
+	{
+		obj := NewTypeName(token.NoPos, nil, "comparable", nil)
+		obj.setColor(black)
+		typ := NewNamed(obj, nil, nil)
+
+		// interface{} // marked as comparable
+		ityp := &Interface{allMethods: markComplete, comparable: true}
+		ityp.tset = &typeSet{terms: allTermlist, comparable: true}
+
+		typ.SetUnderlying(ityp)
+		def(obj)
+	}
 }
 
 var predeclaredConsts = [...]struct {
@@ -200,6 +220,8 @@ func init() {
 	universeIota = Universe.Lookup("iota").(*Const)
 	universeByte = Universe.Lookup("byte").(*TypeName).typ.(*Basic)
 	universeRune = Universe.Lookup("rune").(*TypeName).typ.(*Basic)
+	universeAny = Universe.Lookup("any").(*TypeName).typ
+	universeComparable = Universe.Lookup("comparable")
 }
 
 // Objects with names containing blanks are internal and not entered into
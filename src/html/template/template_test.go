@@ -9,6 +9,7 @@ import (
 	. "html/template"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestTemplateClone(t *testing.T) {
@@ -110,6 +111,9 @@ func TestRedefineOtherParsers(t *testing.T) {
 	if _, err := c.root.ParseGlob("*.no.template"); err == nil || !strings.Contains(err.Error(), "Execute") {
 		t.Errorf("ParseGlob: %v\nwanted error about already having Executed", err)
 	}
+	if _, err := c.root.ParseFS(fstest.MapFS{}, "*.no.template"); err == nil || !strings.Contains(err.Error(), "Execute") {
+		t.Errorf("ParseFS: %v\nwanted error about already having Executed", err)
+	}
 	if _, err := c.root.AddParseTree("t1", c.root.Tree); err == nil || !strings.Contains(err.Error(), "Execute") {
 		t.Errorf("AddParseTree: %v\nwanted error about already having Executed", err)
 	}
@@ -155,3 +159,28 @@ func (c *testCase) mustExecute(t *Template, val interface{}, want string) {
 		c.t.Fatalf("template output:\n%s\nwant:\n%s", buf.String(), want)
 	}
 }
+
+func TestParseFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"page.html":        {Data: []byte(`{{template "item.html" .}}`)},
+		"parts/item.html":  {Data: []byte(`<b>{{.}}</b>`)},
+		"parts/other.html": {Data: []byte(`other`)},
+	}
+	tmpl, err := ParseFS(fsys, "page.html", "parts/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Name() != "page.html" {
+		t.Errorf("Name() = %q, want %q", tmpl.Name(), "page.html")
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, "<x>"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "<b>&lt;x&gt;</b>"; got != want {
+		t.Errorf("Execute = %q, want %q", got, want)
+	}
+	if _, err := ParseFS(fsys, "missing/*.html"); err == nil {
+		t.Error("ParseFS with pattern matching no files succeeded")
+	}
+}
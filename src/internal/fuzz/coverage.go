@@ -0,0 +1,89 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import "unsafe"
+
+// _counters and _ecounters mark the start and end, respectively, of the
+// coverage counters the compiler inserts into packages built with
+// -d=libfuzzer. The linker defines them around the section holding
+// the counters.
+var _counters, _ecounters [0]byte
+
+// coverageCounters returns the coverage counters of the running binary,
+// one byte per instrumented basic block or && and || operand. It returns
+// an empty slice if no package was instrumented.
+func coverageCounters() []byte {
+	addr := unsafe.Pointer(&_counters)
+	size := uintptr(unsafe.Pointer(&_ecounters)) - uintptr(addr)
+	return (*[1 << 30]byte)(addr)[:size:size]
+}
+
+// coverage tracks which coverage counters have been reached by the inputs
+// seen so far, and how many times.
+//
+// The counters are reset before each input, so after it runs they hold the
+// number of times the input reached each of them.
+type coverage struct {
+	counters []byte
+	seen     []byte // bitmask of hit-count buckets seen for each counter
+}
+
+func newCoverage(counters []byte) *coverage {
+	return &coverage{counters: counters, seen: make([]byte, len(counters))}
+}
+
+// enabled reports whether any coverage counters are available.
+func (c *coverage) enabled() bool {
+	return len(c.counters) > 0
+}
+
+// reset zeroes the counters. It must be called before running an input.
+func (c *coverage) reset() {
+	for i := range c.counters {
+		c.counters[i] = 0
+	}
+}
+
+// update records the hit counts of the input that ran since the last
+// reset, and reports whether that input reached a counter, or a hit count
+// bucket of a counter, that no earlier input had reached.
+func (c *coverage) update() bool {
+	found := false
+	for i, n := range c.counters {
+		b := bucket(n)
+		if b&^c.seen[i] != 0 {
+			c.seen[i] |= b
+			found = true
+		}
+	}
+	return found
+}
+
+// bucket maps the number of times a counter was hit by one input to one of
+// eight bits, so that inputs that only change a loop's iteration count a
+// little are not considered interesting. Counters wrap around at 256.
+func bucket(n byte) byte {
+	switch {
+	case n == 0:
+		return 0
+	case n == 1:
+		return 1 << 0
+	case n == 2:
+		return 1 << 1
+	case n == 3:
+		return 1 << 2
+	case n <= 7:
+		return 1 << 3
+	case n <= 15:
+		return 1 << 4
+	case n <= 31:
+		return 1 << 5
+	case n <= 127:
+		return 1 << 6
+	default:
+		return 1 << 7
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// encVersion1 will be the first line of a file with version 1 encoding.
+var encVersion1 = "go test fuzz v1"
+
+// marshalCorpusFile encodes an arbitrary number of arguments into the file format for the
+// corpus.
+func marshalCorpusFile(vals ...interface{}) []byte {
+	if len(vals) == 0 {
+		panic("must have at least one value to marshal")
+	}
+	b := bytes.NewBuffer([]byte(encVersion1 + "\n"))
+	for _, val := range vals {
+		switch t := val.(type) {
+		case int, int8, int16, int64, uint, uint16, uint32, uint64, bool:
+			fmt.Fprintf(b, "%T(%v)\n", t, t)
+		case float32:
+			fmt.Fprintf(b, "float32(%s)\n", strconv.FormatFloat(float64(t), 'g', -1, 32))
+		case float64:
+			fmt.Fprintf(b, "float64(%s)\n", strconv.FormatFloat(t, 'g', -1, 64))
+		case string:
+			fmt.Fprintf(b, "string(%q)\n", t)
+		case rune: // int32
+			// Invalid runes would be replaced by the quoting, so write
+			// them out as plain integers instead.
+			if utf8.ValidRune(t) {
+				fmt.Fprintf(b, "rune(%q)\n", t)
+			} else {
+				fmt.Fprintf(b, "int32(%d)\n", t)
+			}
+		case byte: // uint8
+			if t < utf8.RuneSelf {
+				fmt.Fprintf(b, "byte(%q)\n", rune(t))
+			} else {
+				fmt.Fprintf(b, "byte('\\x%02x')\n", t)
+			}
+		case []byte: // []uint8
+			fmt.Fprintf(b, "[]byte(%q)\n", t)
+		default:
+			panic(fmt.Sprintf("unsupported type: %T", t))
+		}
+	}
+	return b.Bytes()
+}
+
+// unmarshalCorpusFile decodes corpus bytes into their respective values.
+func unmarshalCorpusFile(b []byte) ([]interface{}, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("cannot unmarshal empty string")
+	}
+	lines := bytes.Split(b, []byte("\n"))
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("must include version and at least one value")
+	}
+	if string(lines[0]) != encVersion1 {
+		return nil, fmt.Errorf("unknown encoding version: %s", lines[0])
+	}
+	var vals []interface{}
+	for _, line := range lines[1:] {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		v, err := parseCorpusValue(string(line))
+		if err != nil {
+			return nil, fmt.Errorf("malformed line %q: %v", line, err)
+		}
+		vals = append(vals, v)
+	}
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("must include version and at least one value")
+	}
+	return vals, nil
+}
+
+// parseCorpusValue parses a single line of the form type(literal).
+func parseCorpusValue(line string) (interface{}, error) {
+	i := strings.Index(line, "(")
+	if i < 0 || !strings.HasSuffix(line, ")") {
+		return nil, fmt.Errorf("expected call expression")
+	}
+	typ, lit := line[:i], line[i+1:len(line)-1]
+	switch typ {
+	case "[]byte":
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	case "string":
+		return strconv.Unquote(lit)
+	case "bool":
+		switch lit {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid bool %s", lit)
+	case "byte", "uint8":
+		if isCharLiteral(lit) {
+			s, err := strconv.Unquote(lit)
+			if err != nil {
+				return nil, err
+			}
+			if len(s) != 1 {
+				return nil, fmt.Errorf("character literal %s does not fit in a byte", lit)
+			}
+			return s[0], nil
+		}
+		u, err := strconv.ParseUint(lit, 0, 8)
+		return uint8(u), err
+	case "rune", "int32":
+		if isCharLiteral(lit) {
+			s, err := strconv.Unquote(lit)
+			if err != nil {
+				return nil, err
+			}
+			r, size := utf8.DecodeRuneInString(s)
+			if size != len(s) {
+				return nil, fmt.Errorf("character literal %s is not a single rune", lit)
+			}
+			return r, nil
+		}
+		n, err := strconv.ParseInt(lit, 0, 32)
+		return int32(n), err
+	case "int":
+		n, err := strconv.ParseInt(lit, 0, 0)
+		return int(n), err
+	case "int8":
+		n, err := strconv.ParseInt(lit, 0, 8)
+		return int8(n), err
+	case "int16":
+		n, err := strconv.ParseInt(lit, 0, 16)
+		return int16(n), err
+	case "int64":
+		return strconv.ParseInt(lit, 0, 64)
+	case "uint":
+		u, err := strconv.ParseUint(lit, 0, 0)
+		return uint(u), err
+	case "uint16":
+		u, err := strconv.ParseUint(lit, 0, 16)
+		return uint16(u), err
+	case "uint32":
+		u, err := strconv.ParseUint(lit, 0, 32)
+		return uint32(u), err
+	case "uint64":
+		return strconv.ParseUint(lit, 0, 64)
+	case "float32":
+		f, err := strconv.ParseFloat(lit, 32)
+		return float32(f), err
+	case "float64":
+		return strconv.ParseFloat(lit, 64)
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+}
+
+func isCharLiteral(lit string) bool {
+	return len(lit) >= 2 && lit[0] == '\'' && lit[len(lit)-1] == '\''
+}
@@ -0,0 +1,152 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalMarshal(t *testing.T) {
+	var tests = []struct {
+		in string
+		ok bool
+	}{
+		{
+			in: "int(1234)",
+			ok: false, // missing version
+		},
+		{
+			in: `go test fuzz v1
+string("a"bcad")`,
+			ok: false, // malformed
+		},
+		{
+			in: `go test fuzz v1
+int()`,
+			ok: false, // empty value
+		},
+		{
+			in: `go test fuzz v1
+uint(-32)`,
+			ok: false, // invalid negative uint
+		},
+		{
+			in: `go test fuzz v1
+int8(1234456)`,
+			ok: false, // int8 too large
+		},
+		{
+			in: `go test fuzz v1
+int(20*5)`,
+			ok: false, // expression in int value
+		},
+		{
+			in: `go test fuzz v1
+int(--5)`,
+			ok: false, // expression in int value
+		},
+		{
+			in: `go test fuzz v1
+bool(0)`,
+			ok: false, // malformed bool
+		},
+		{
+			in: `go test fuzz v1
+byte('aa)`,
+			ok: false, // malformed byte
+		},
+		{
+			in: `go test fuzz v1
+byte('☃')`,
+			ok: false, // byte out of range
+		},
+		{
+			in: `go test fuzz v1
+complex128(1)`,
+			ok: false, // unsupported type
+		},
+		{
+			in: `go test fuzz v1
+string("hello\\xbd\\xb2=\\xbc ⌘")`,
+			ok: true,
+		},
+		{
+			in: `go test fuzz v1
+int(-23)
+int8(-2)
+int64(2342425)
+uint(1)
+uint16(234)
+uint32(352342)
+uint64(123)
+rune('œ')
+byte('K')
+byte('\xff')
+[]byte("hello¿")
+[]byte("a")
+bool(true)
+string("hello\\xbd\\xb2=\\xbc ⌘")
+float64(-12.5)
+float32(2.5)`,
+			ok: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			vals, err := unmarshalCorpusFile([]byte(test.in))
+			if test.ok && err != nil {
+				t.Fatalf("unmarshal unexpected error: %v", err)
+			} else if !test.ok && err == nil {
+				t.Fatalf("unmarshal unexpected success")
+			}
+			if !test.ok {
+				return // skip the rest of the test
+			}
+			newB := marshalCorpusFile(vals...)
+			newVals, err := unmarshalCorpusFile(newB)
+			if err != nil {
+				t.Fatalf("unmarshal unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(vals, newVals) {
+				t.Errorf("values changed after marshal and unmarshal:\n%#v\n%#v", vals, newVals)
+			}
+		})
+	}
+}
+
+func TestMarshalUnmarshalEdgeCases(t *testing.T) {
+	vals := []interface{}{
+		byte(0x80),
+		byte(0),
+		rune(-1),
+		rune(0x10FFFF),
+		int64(math.MinInt64),
+		uint64(math.MaxUint64),
+		math.Inf(1),
+		float32(math.Inf(-1)),
+		[]byte{0xff, 0, '\n'},
+		"",
+	}
+	b := marshalCorpusFile(vals...)
+	got, err := unmarshalCorpusFile(b)
+	if err != nil {
+		t.Fatalf("unmarshal %q: %v", b, err)
+	}
+	if !reflect.DeepEqual(got, vals) {
+		t.Errorf("round trip of %q:\ngot  %#v\nwant %#v", b, got, vals)
+	}
+
+	// NaN is not equal to itself, so check it separately.
+	b = marshalCorpusFile(math.NaN())
+	got, err = unmarshalCorpusFile(b)
+	if err != nil {
+		t.Fatalf("unmarshal %q: %v", b, err)
+	}
+	if f, ok := got[0].(float64); !ok || !math.IsNaN(f) {
+		t.Errorf("round trip of %q: got %v, want NaN", b, got)
+	}
+}
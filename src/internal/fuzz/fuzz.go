@@ -0,0 +1,309 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fuzz provides common fuzzing functionality for tests built with
+// "go test" and for programs that use fuzzing functionality in the testing
+// package.
+package fuzz
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// CorpusEntry represents an individual input for fuzzing.
+//
+// We must use an equivalent type in the testing and testing/internal/testdeps
+// packages, but testing can't import this package directly, and we don't want
+// to export this type from testing. Instead, we use the same struct type and
+// use a type alias (not a defined type) for convenience.
+type CorpusEntry = struct {
+	// Name is the name of the corpus file, if the entry was loaded from the
+	// seed corpus or a cache directory, or the name given to it by the
+	// testing package, for entries added with F.Add.
+	Name string
+
+	// Path is the path of the corpus file, if the entry was loaded from or
+	// written to disk.
+	Path string
+
+	// Values are the arguments to the fuzz function.
+	Values []interface{}
+}
+
+// CoordinateFuzzingOpts is a set of arguments for CoordinateFuzzing.
+// The zero value is valid for each field unless specified otherwise.
+type CoordinateFuzzingOpts struct {
+	// Log is a writer for logging progress messages and warnings.
+	// If nil, ioutil.Discard will be used instead.
+	Log io.Writer
+
+	// Timeout is the amount of wall clock time to spend fuzzing after the
+	// corpus has loaded. If zero, there will be no time limit.
+	Timeout time.Duration
+
+	// MinimizeTimeout is the amount of wall clock time to spend minimizing
+	// a failing input after it is found. If zero, a default of one minute
+	// is used.
+	MinimizeTimeout time.Duration
+
+	// Seed is a list of seed values added by the fuzz target with F.Add and
+	// loaded from the seed corpus directory. If it is empty, fuzzing starts
+	// from the zero values of Types.
+	Seed []CorpusEntry
+
+	// Types is the list of types which make up a corpus entry.
+	// Types must be set and must match values in Seed.
+	Types []reflect.Type
+
+	// CorpusDir is a directory where files containing values that crash the
+	// code being tested may be written. CorpusDir must be set.
+	CorpusDir string
+
+	// CacheDir is a directory containing additional "interesting" values.
+	// The fuzzer may derive new values from these, and may write new values here.
+	// If empty, new values are kept in memory only.
+	CacheDir string
+}
+
+// CoordinateFuzzing runs fn repeatedly with inputs derived from the seed
+// corpus and the cache directory, until fn returns an error, opts.Timeout
+// elapses, or the process receives an interrupt signal.
+//
+// If fn returns an error for some input, CoordinateFuzzing attempts to
+// minimize the input, writes it to opts.CorpusDir, and returns an error
+// describing the failure and the location of the file. Otherwise it
+// returns nil, or an error if the corpus could not be read or written.
+//
+// Inputs are considered interesting when they reach new coverage counters
+// in the packages built with -d=libfuzzer. If no package was, the fuzzer
+// mutates inputs at random without coverage guidance.
+func CoordinateFuzzing(opts CoordinateFuzzingOpts, fn func(CorpusEntry) error) error {
+	return coordinateFuzzing(opts, coverageCounters(), fn)
+}
+
+func coordinateFuzzing(opts CoordinateFuzzingOpts, counters []byte, fn func(CorpusEntry) error) error {
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+	if opts.MinimizeTimeout == 0 {
+		opts.MinimizeTimeout = time.Minute
+	}
+	if len(opts.Seed) == 0 {
+		// Start from the zero values of the arguments.
+		vals := make([]interface{}, len(opts.Types))
+		for i, t := range opts.Types {
+			vals[i] = reflect.Zero(t).Interface()
+		}
+		opts.Seed = []CorpusEntry{{Name: "zero", Values: vals}}
+	}
+	for _, e := range opts.Seed {
+		if err := CheckCorpus(e.Values, opts.Types); err != nil {
+			return err
+		}
+	}
+
+	cached, err := ReadCorpus(opts.CacheDir, opts.Types)
+	if err != nil {
+		return err
+	}
+	corpus := append(append([]CorpusEntry(nil), opts.Seed...), cached...)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	cov := newCoverage(counters)
+	if !cov.enabled() {
+		fmt.Fprintf(opts.Log, "fuzz: warning: coverage instrumentation is not enabled; inputs are mutated without coverage guidance\n")
+	}
+
+	// run runs fn on vals and records its coverage.
+	// It reports whether the input reached new code and, if fn failed,
+	// the error describing the failure.
+	run := func(e CorpusEntry) (interesting bool, err error) {
+		cov.reset()
+		err = fn(e)
+		return cov.update(), err
+	}
+
+	// Gather baseline coverage from the existing corpus. Entries that fail
+	// are reported immediately, without minimization, since they are
+	// already on disk or in the test source.
+	fmt.Fprintf(opts.Log, "fuzz: gathering baseline coverage from %d corpus entries\n", len(corpus))
+	for _, e := range corpus {
+		if _, err := run(e); err != nil {
+			return &crashError{name: e.Name, path: e.Path, err: err}
+		}
+	}
+
+	m := newMutator(time.Now().UnixNano())
+	start := time.Now()
+	var deadline <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+
+	var execs, newInteresting int64
+	logStats := func() {
+		elapsed := time.Since(start)
+		rate := float64(execs) / elapsed.Seconds()
+		fmt.Fprintf(opts.Log, "fuzz: elapsed: %s, execs: %d (%.0f/sec), new interesting: %d (total: %d)\n",
+			elapsed.Round(time.Second), execs, rate, newInteresting, len(corpus))
+	}
+	defer logStats()
+
+	for {
+		select {
+		case <-deadline:
+			return nil
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+			logStats()
+		default:
+		}
+
+		parent := corpus[m.rand(len(corpus))]
+		vals := m.mutate(parent.Values)
+		execs++
+		interesting, err := run(CorpusEntry{Values: vals})
+		if err != nil {
+			fmt.Fprintf(opts.Log, "fuzz: found a failing input; minimizing\n")
+			return failure(opts, vals, fn, err)
+		}
+		if !interesting {
+			continue
+		}
+		e := CorpusEntry{Values: vals}
+		if opts.CacheDir != "" {
+			if e.Path, err = writeToCorpus(vals, opts.CacheDir); err != nil {
+				return err
+			}
+			e.Name = filepath.Base(e.Path)
+		}
+		corpus = append(corpus, e)
+		newInteresting++
+	}
+}
+
+// failure minimizes the failing input vals, writes the result to the seed
+// corpus directory and returns the error to report.
+func failure(opts CoordinateFuzzingOpts, vals []interface{}, fn func(CorpusEntry) error, err error) error {
+	deadline := time.Now().Add(opts.MinimizeTimeout)
+	vals = minimizeInput(vals, func(candidate []interface{}) bool {
+		return fn(CorpusEntry{Values: candidate}) != nil
+	}, func() bool {
+		return time.Now().After(deadline)
+	})
+	// Run the minimized input once more, so that the error reported is
+	// the one produced by the input that is written to disk.
+	if ferr := fn(CorpusEntry{Values: vals}); ferr != nil {
+		err = ferr
+	}
+	path, werr := writeToCorpus(vals, opts.CorpusDir)
+	if werr != nil {
+		return fmt.Errorf("%v\nfuzz: failed to write failing input: %v", err, werr)
+	}
+	return &crashError{name: filepath.Base(path), path: path, err: err}
+}
+
+// crashError describes a failure found by the fuzzer.
+type crashError struct {
+	name string // name of the corpus entry, used to re-run it as a subtest
+	path string // path of the corpus file, if any
+	err  error
+}
+
+func (e *crashError) Error() string {
+	if e.path == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%v\nFailing input written to %s", e.err, e.path)
+}
+
+// CrashName returns the name of the corpus entry that caused the failure,
+// which identifies it as a subtest of the fuzz target.
+func (e *crashError) CrashName() string {
+	return e.name
+}
+
+// ReadCorpus reads the corpus from the provided dir. The returned corpus
+// entries are guaranteed to match the given types. Any malformed files will
+// cause an error to be returned. A missing directory is treated as empty.
+func ReadCorpus(dir string, types []reflect.Type) ([]CorpusEntry, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil // No corpus to read
+	} else if err != nil {
+		return nil, fmt.Errorf("reading seed corpus from testdata: %v", err)
+	}
+	var corpus []CorpusEntry
+	for _, file := range files {
+		// TODO: Consider reading corpus files in subdirectories.
+		if file.IsDir() {
+			continue
+		}
+		filename := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read corpus file: %v", err)
+		}
+		vals, err := unmarshalCorpusFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %q: %v", filename, err)
+		}
+		if err := CheckCorpus(vals, types); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		corpus = append(corpus, CorpusEntry{Name: file.Name(), Path: filename, Values: vals})
+	}
+	return corpus, nil
+}
+
+// CheckCorpus verifies that the types in vals match the expected types
+// provided.
+func CheckCorpus(vals []interface{}, types []reflect.Type) error {
+	if len(vals) != len(types) {
+		return fmt.Errorf("wrong number of values in corpus entry: %d, want %d", len(vals), len(types))
+	}
+	for i := range types {
+		if reflect.TypeOf(vals[i]) != types[i] {
+			return fmt.Errorf("mismatched types in corpus entry: %v, want %v", vals, types)
+		}
+	}
+	return nil
+}
+
+// writeToCorpus writes the given values to a new file in dir, creating dir
+// if necessary. The file name is derived from the hash of its contents, so
+// writing the same input twice results in a single file. writeToCorpus
+// returns the path of the file.
+func writeToCorpus(vals []interface{}, dir string) (path string, err error) {
+	data := marshalCorpusFile(vals...)
+	name := fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+	path = filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		os.Remove(path) // remove partially written file
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCoordinateFuzzing checks that the fuzzer uses coverage feedback to
+// find an input that is unlikely to be found by random mutation alone,
+// minimizes it, and writes it to the corpus directory.
+func TestCoordinateFuzzing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fuzz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Simulate the counters the compiler would insert into a function
+	// that checks its input byte by byte.
+	const magic = "FUZZ"
+	counters := make([]byte, len(magic))
+	fn := func(e CorpusEntry) error {
+		b := e.Values[0].([]byte)
+		for i := 0; i < len(magic); i++ {
+			if i >= len(b) || b[i] != magic[i] {
+				return nil
+			}
+			counters[i]++
+		}
+		return errors.New("found the magic word")
+	}
+
+	opts := CoordinateFuzzingOpts{
+		Timeout:   time.Minute,
+		Seed:      []CorpusEntry{{Name: "seed#0", Values: []interface{}{[]byte("hello, world")}}},
+		Types:     []reflect.Type{reflect.TypeOf([]byte(nil))},
+		CorpusDir: filepath.Join(dir, "testdata"),
+		CacheDir:  filepath.Join(dir, "cache"),
+	}
+	err = coordinateFuzzing(opts, counters, fn)
+	if err == nil {
+		t.Fatal("CoordinateFuzzing did not find the failing input")
+	}
+	if !strings.Contains(err.Error(), "found the magic word") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	corpus, err := ReadCorpus(opts.CorpusDir, opts.Types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corpus) != 1 {
+		t.Fatalf("got %d entries in the corpus directory, want 1", len(corpus))
+	}
+	if got := corpus[0].Values[0].([]byte); !bytes.Equal(got, []byte(magic)) {
+		t.Errorf("minimized input is %q, want %q", got, magic)
+	}
+	if cached, err := ReadCorpus(opts.CacheDir, opts.Types); err != nil || len(cached) == 0 {
+		t.Errorf("no interesting inputs were cached (err: %v)", err)
+	}
+}
+
+func TestCoordinateFuzzingTimeout(t *testing.T) {
+	calls := 0
+	opts := CoordinateFuzzingOpts{
+		Timeout: 100 * time.Millisecond,
+		Types:   []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(0)},
+	}
+	err := CoordinateFuzzing(opts, func(e CorpusEntry) error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CoordinateFuzzing: %v", err)
+	}
+	if calls < 2 {
+		t.Errorf("fuzz function called %d times, want more", calls)
+	}
+}
+
+func TestMinimizeInput(t *testing.T) {
+	vals := []interface{}{[]byte("aaaXbbbYccc"), "hello, world", 7}
+	got := minimizeInput(vals, func(vals []interface{}) bool {
+		return bytes.Contains(vals[0].([]byte), []byte("X")) && strings.Contains(vals[1].(string), ",")
+	}, func() bool { return false })
+	want := []interface{}{[]byte("X"), ",", 7}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("minimizeInput = %#v, want %#v", got, want)
+	}
+	if !reflect.DeepEqual(vals[0], []byte("aaaXbbbYccc")) {
+		t.Errorf("minimizeInput modified its input: %q", vals[0])
+	}
+}
+
+func TestMutatorPreservesTypes(t *testing.T) {
+	m := newMutator(1)
+	vals := []interface{}{
+		[]byte("abc"), "abc", true, byte(1), rune(1), float32(1), float64(1),
+		int(1), int8(1), int16(1), int32(1), int64(1),
+		uint(1), uint16(1), uint32(1), uint64(1),
+	}
+	orig := append([]interface{}(nil), vals...)
+	for i := 0; i < 1000; i++ {
+		mutated := m.mutate(vals)
+		for j := range vals {
+			if reflect.TypeOf(mutated[j]) != reflect.TypeOf(vals[j]) {
+				t.Fatalf("mutate changed type of %T to %T", vals[j], mutated[j])
+			}
+		}
+		vals = mutated
+	}
+	if !reflect.DeepEqual(orig[0], []byte("abc")) {
+		t.Errorf("mutate modified its input: %q", orig[0])
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+// minimizeInput attempts to find a smaller input that still causes fn to
+// fail. It shrinks each []byte and string value in turn, keeping every
+// reduction for which fail still reports true. It gives up early once
+// shouldStop reports true, returning the smallest input found so far.
+func minimizeInput(vals []interface{}, fail func([]interface{}) bool, shouldStop func() bool) []interface{} {
+	vals = append([]interface{}(nil), vals...)
+	for i, v := range vals {
+		switch v := v.(type) {
+		case []byte:
+			vals[i] = minimizeBytes(v, func(b []byte) bool {
+				vals[i] = b
+				return fail(vals)
+			}, shouldStop)
+		case string:
+			vals[i] = string(minimizeBytes([]byte(v), func(b []byte) bool {
+				vals[i] = string(b)
+				return fail(vals)
+			}, shouldStop))
+		}
+	}
+	return vals
+}
+
+// minimizeBytes removes progressively smaller ranges of bytes from v for as
+// long as try keeps reporting that the candidate still fails.
+// The slices passed to try are never modified afterward.
+func minimizeBytes(v []byte, try func([]byte) bool, shouldStop func() bool) []byte {
+	if len(v) == 0 {
+		return v
+	}
+	if try([]byte{}) {
+		return []byte{}
+	}
+	for n := len(v) / 2; n >= 1; n /= 2 {
+		for pos := 0; pos+n <= len(v); {
+			if shouldStop() {
+				return v
+			}
+			candidate := make([]byte, 0, len(v)-n)
+			candidate = append(candidate, v[:pos]...)
+			candidate = append(candidate, v[pos+n:]...)
+			if try(candidate) {
+				v = candidate
+			} else {
+				pos += n
+			}
+		}
+	}
+	return v
+}
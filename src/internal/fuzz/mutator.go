@@ -0,0 +1,316 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// maxMutatedBytes is the largest []byte or string value the mutator will
+// produce by growing its input.
+const maxMutatedBytes = 1 << 20
+
+// interesting8, interesting16 and interesting32 are values that are
+// frequently found at the edges of valid ranges, and therefore frequently
+// trigger bugs.
+var (
+	interesting8  = []int8{-128, -1, 0, 1, 16, 32, 64, 100, 127}
+	interesting16 = []int16{-32768, -129, 128, 255, 256, 512, 1000, 1024, 4096, 32767}
+	interesting32 = []int32{-2147483648, -100663046, -32769, 32768, 65535, 65536, 100663045, 2147483647}
+)
+
+// A mutator produces new inputs from existing ones by applying small random
+// changes to their values.
+type mutator struct {
+	r *rand.Rand
+}
+
+func newMutator(seed int64) *mutator {
+	return &mutator{r: rand.New(rand.NewSource(seed))}
+}
+
+// rand returns a random number in [0, n).
+func (m *mutator) rand(n int) int {
+	return m.r.Intn(n)
+}
+
+// randBigEndian reports whether a multi-byte value should be written in
+// big-endian order, so that interesting values appear in either byte order.
+func (m *mutator) randBigEndian() bool {
+	return m.r.Intn(2) == 0
+}
+
+// chooseLen chooses the length of a range mutation in [1, n]. It favors
+// short ranges.
+func (m *mutator) chooseLen(n int) int {
+	switch x := m.rand(100); {
+	case x < 90:
+		return m.rand(min(8, n)) + 1
+	case x < 99:
+		return m.rand(min(32, n)) + 1
+	default:
+		return m.rand(n) + 1
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// mutate performs several mutations on a copy of vals and returns the
+// result. vals itself is not modified.
+func (m *mutator) mutate(vals []interface{}) []interface{} {
+	vals = append([]interface{}(nil), vals...)
+	n := 1 + m.rand(4)
+	for i := 0; i < n; i++ {
+		j := m.rand(len(vals))
+		vals[j] = m.mutateValue(vals[j])
+	}
+	return vals
+}
+
+// mutateValue returns a mutated copy of v.
+func (m *mutator) mutateValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int:
+		return int(m.mutateInt(int64(v), maxInt))
+	case int8:
+		return int8(m.mutateInt(int64(v), math.MaxInt8))
+	case int16:
+		return int16(m.mutateInt(int64(v), math.MaxInt16))
+	case int32:
+		return int32(m.mutateInt(int64(v), math.MaxInt32))
+	case int64:
+		return m.mutateInt(v, math.MaxInt64)
+	case uint:
+		return uint(m.mutateUInt(uint64(v), maxUint))
+	case uint8:
+		return uint8(m.mutateUInt(uint64(v), math.MaxUint8))
+	case uint16:
+		return uint16(m.mutateUInt(uint64(v), math.MaxUint16))
+	case uint32:
+		return uint32(m.mutateUInt(uint64(v), math.MaxUint32))
+	case uint64:
+		return m.mutateUInt(v, math.MaxUint64)
+	case float32:
+		return float32(m.mutateFloat(float64(v), math.MaxFloat32))
+	case float64:
+		return m.mutateFloat(v, math.MaxFloat64)
+	case bool:
+		return !v
+	case string:
+		return string(m.mutateBytes([]byte(v)))
+	case []byte:
+		return m.mutateBytes(append([]byte(nil), v...))
+	default:
+		panic(fmt.Sprintf("unsupported type: %T", v))
+	}
+}
+
+const (
+	maxUint = uint64(^uint(0))
+	maxInt  = int64(maxUint >> 1)
+)
+
+func (m *mutator) mutateInt(v, maxValue int64) int64 {
+	switch m.rand(4) {
+	case 0:
+		// Add a small number.
+		delta := int64(1 + m.rand(16))
+		if v <= maxValue-delta {
+			return v + delta
+		}
+	case 1:
+		// Subtract a small number.
+		delta := int64(1 + m.rand(16))
+		if v >= -maxValue-1+delta {
+			return v - delta
+		}
+	case 2:
+		// Use an interesting value.
+		iv := int64(interesting32[m.rand(len(interesting32))])
+		if iv <= maxValue && iv >= -maxValue-1 {
+			return iv
+		}
+	}
+	// Flip a random bit within the range of the type.
+	bits := uint(1)
+	for max := maxValue; max > 0; max >>= 1 {
+		bits++
+	}
+	return v ^ int64(1)<<uint(m.rand(int(bits)))
+}
+
+func (m *mutator) mutateUInt(v, maxValue uint64) uint64 {
+	switch m.rand(4) {
+	case 0:
+		delta := uint64(1 + m.rand(16))
+		if v <= maxValue-delta {
+			return v + delta
+		}
+	case 1:
+		delta := uint64(1 + m.rand(16))
+		if v >= delta {
+			return v - delta
+		}
+	case 2:
+		iv := int64(interesting32[m.rand(len(interesting32))])
+		if iv >= 0 && uint64(iv) <= maxValue {
+			return uint64(iv)
+		}
+	}
+	bits := uint(0)
+	for max := maxValue; max > 0; max >>= 1 {
+		bits++
+	}
+	return v ^ uint64(1)<<uint(m.rand(int(bits)))
+}
+
+func (m *mutator) mutateFloat(v, maxValue float64) float64 {
+	var nv float64
+	switch m.rand(5) {
+	case 0:
+		nv = v + float64(m.rand(16)+1)
+	case 1:
+		nv = v - float64(m.rand(16)+1)
+	case 2:
+		nv = v * float64(m.rand(16)+1)
+	case 3:
+		nv = v / float64(m.rand(16)+1)
+	default:
+		nv = -v
+	}
+	if math.Abs(nv) > maxValue {
+		return v
+	}
+	return nv
+}
+
+// mutateBytes applies a single random mutation to b, which it may modify
+// in place, and returns the result.
+func (m *mutator) mutateBytes(b []byte) []byte {
+	for {
+		switch m.rand(10) {
+		case 0:
+			// Remove a range of bytes.
+			if len(b) <= 1 {
+				continue
+			}
+			pos := m.rand(len(b))
+			n := m.chooseLen(len(b) - pos)
+			return append(b[:pos], b[pos+n:]...)
+		case 1:
+			// Insert a range of random bytes.
+			n := m.chooseLen(10)
+			if len(b)+n > maxMutatedBytes {
+				continue
+			}
+			pos := m.rand(len(b) + 1)
+			ins := make([]byte, n)
+			m.r.Read(ins)
+			return append(b[:pos], append(ins, b[pos:]...)...)
+		case 2:
+			// Duplicate a range of bytes.
+			if len(b) <= 1 {
+				continue
+			}
+			src := m.rand(len(b))
+			n := m.chooseLen(len(b) - src)
+			if len(b)+n > maxMutatedBytes {
+				continue
+			}
+			dst := m.rand(len(b) + 1)
+			dup := append([]byte(nil), b[src:src+n]...)
+			return append(b[:dst], append(dup, b[dst:]...)...)
+		case 3:
+			// Copy a range of bytes over another range.
+			if len(b) <= 1 {
+				continue
+			}
+			src := m.rand(len(b))
+			dst := m.rand(len(b))
+			n := m.chooseLen(len(b) - max(src, dst))
+			copy(b[dst:dst+n], b[src:src+n])
+			return b
+		case 4:
+			// Flip a bit.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] ^= 1 << uint(m.rand(8))
+			return b
+		case 5:
+			// Set a byte to a random value.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] = byte(m.rand(256))
+			return b
+		case 6:
+			// Swap two bytes.
+			if len(b) <= 1 {
+				continue
+			}
+			i, j := m.rand(len(b)), m.rand(len(b))
+			b[i], b[j] = b[j], b[i]
+			return b
+		case 7:
+			// Add or subtract a small number from a byte.
+			if len(b) == 0 {
+				continue
+			}
+			pos := m.rand(len(b))
+			delta := byte(m.rand(35) + 1)
+			if m.rand(2) == 0 {
+				b[pos] += delta
+			} else {
+				b[pos] -= delta
+			}
+			return b
+		case 8:
+			// Replace a byte with an interesting value.
+			if len(b) == 0 {
+				continue
+			}
+			b[m.rand(len(b))] = byte(interesting8[m.rand(len(interesting8))])
+			return b
+		case 9:
+			// Replace two or four bytes with an interesting value.
+			if len(b) < 2 {
+				continue
+			}
+			var v uint32
+			n := 2
+			if len(b) >= 4 && m.rand(2) == 0 {
+				n = 4
+				v = uint32(interesting32[m.rand(len(interesting32))])
+			} else {
+				v = uint32(interesting16[m.rand(len(interesting16))])
+			}
+			pos := m.rand(len(b) - n + 1)
+			bigEndian := m.randBigEndian()
+			for i := 0; i < n; i++ {
+				shift := uint(8 * i)
+				if bigEndian {
+					shift = uint(8 * (n - 1 - i))
+				}
+				b[pos+i] = byte(v >> shift)
+			}
+			return b
+		}
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
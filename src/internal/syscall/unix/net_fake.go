@@ -0,0 +1,36 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build js,wasm nacl
+
+package unix
+
+import "syscall"
+
+// The fake network stacks have no raw sockets to read from, so these
+// go through the allocating Sockaddr API.
+
+func RecvfromInet4(fd int, p []byte, flags int, from *syscall.SockaddrInet4) (n int, err error) {
+	n, sa, err := syscall.Recvfrom(fd, p, flags)
+	if sa, ok := sa.(*syscall.SockaddrInet4); ok {
+		*from = *sa
+	}
+	return n, err
+}
+
+func RecvfromInet6(fd int, p []byte, flags int, from *syscall.SockaddrInet6) (n int, err error) {
+	n, sa, err := syscall.Recvfrom(fd, p, flags)
+	if sa, ok := sa.(*syscall.SockaddrInet6); ok {
+		*from = *sa
+	}
+	return n, err
+}
+
+func SendtoInet4(fd int, p []byte, flags int, to *syscall.SockaddrInet4) (err error) {
+	return syscall.Sendto(fd, p, flags, to)
+}
+
+func SendtoInet6(fd int, p []byte, flags int, to *syscall.SockaddrInet6) (err error) {
+	return syscall.Sendto(fd, p, flags, to)
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	. "io/fs"
+	"path"
+	"testing"
+	"testing/fstest"
+)
+
+var globTests = []struct {
+	fs              FS
+	pattern, result string
+}{
+	{fstest.MapFS{"hello.go": {}}, "hello.go", "hello.go"},
+	{fstest.MapFS{"hello.go": {}}, "h?llo.go", "hello.go"},
+	{fstest.MapFS{"dir/hello.go": {}}, "*/hello.go", "dir/hello.go"},
+	{fstest.MapFS{"dir/hello.go": {}}, "dir/h*", "dir/hello.go"},
+	{fstest.MapFS{"a/b/c.go": {}}, "a/[bx]/c.go", "a/b/c.go"},
+}
+
+func TestGlob(t *testing.T) {
+	for _, tt := range globTests {
+		matches, err := Glob(tt.fs, tt.pattern)
+		if err != nil {
+			t.Errorf("Glob error for %q: %s", tt.pattern, err)
+			continue
+		}
+		if !contains(matches, tt.result) {
+			t.Errorf("Glob(%#q) = %#v want %v", tt.pattern, matches, tt.result)
+		}
+	}
+	for _, pattern := range []string{"no_match", "../*/no_match"} {
+		matches, err := Glob(fstest.MapFS{}, pattern)
+		if err != nil {
+			t.Errorf("Glob error for %q: %s", pattern, err)
+			continue
+		}
+		if len(matches) != 0 {
+			t.Errorf("Glob(%#q) = %#v want []", pattern, matches)
+		}
+	}
+}
+
+func TestGlobError(t *testing.T) {
+	fsys := fsOnly{fstest.MapFS{"x/y": {}}}
+	bad := []string{`[]`, `x/[]`, `*/[]`}
+	for _, pattern := range bad {
+		_, err := Glob(fsys, pattern)
+		if err != path.ErrBadPattern {
+			t.Errorf("Glob(fs, %#q) returned err=%v, want path.ErrBadPattern", pattern, err)
+		}
+	}
+}
+
+// contains reports whether vector contains the string s.
+func contains(vector []string, s string) bool {
+	for _, elem := range vector {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
+
+// fsOnly hides the optional methods of an FS implementation,
+// forcing the helpers in this package to use their generic code paths.
+type fsOnly struct{ FS }
@@ -0,0 +1,118 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	. "io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var testFsys = fstest.MapFS{
+	"hello.txt": {
+		Data:    []byte("hello, world"),
+		Mode:    0456,
+		ModTime: time.Now(),
+		Sys:     &sysValue,
+	},
+	"sub/goodbye.txt": {
+		Data: []byte("goodbye, world"),
+	},
+}
+
+var sysValue int
+
+func TestReadFile(t *testing.T) {
+	data, err := ReadFile(fsOnly{testFsys}, "hello.txt")
+	if string(data) != "hello, world" || err != nil {
+		t.Fatalf(`ReadFile(fsOnly{testFsys}, "hello.txt") = %q, %v, want %q, nil`, data, err, "hello, world")
+	}
+
+	data, err = ReadFile(testFsys, "hello.txt")
+	if string(data) != "hello, world" || err != nil {
+		t.Fatalf(`ReadFile(testFsys, "hello.txt") = %q, %v, want %q, nil`, data, err, "hello, world")
+	}
+}
+
+func TestStat(t *testing.T) {
+	check := func(desc string, info FileInfo, err error) {
+		t.Helper()
+		if err != nil || info == nil || info.Mode() != 0456 {
+			infoStr := "<nil>"
+			if info != nil {
+				infoStr = info.Mode().String()
+			}
+			t.Fatalf("Stat(%s) = %v, %v, want Mode:0456, nil", desc, infoStr, err)
+		}
+	}
+
+	// Test that Stat uses the method when present.
+	info, err := Stat(testFsys, "hello.txt")
+	check("testFsys", info, err)
+
+	// Test that Stat uses Open when the method is not present.
+	info, err = Stat(fsOnly{testFsys}, "hello.txt")
+	check("fsOnly", info, err)
+}
+
+func TestReadDir(t *testing.T) {
+	check := func(desc string, dirs []DirEntry, err error) {
+		t.Helper()
+		if err != nil || len(dirs) != 2 || dirs[0].Name() != "hello.txt" || dirs[1].Name() != "sub" {
+			var names []string
+			for _, d := range dirs {
+				names = append(names, d.Name())
+			}
+			t.Errorf("ReadDir(%s) = %v, %v, want %v, nil", desc, names, err, []string{"hello.txt", "sub"})
+		}
+	}
+
+	// Test that ReadDir uses the method when present.
+	dirs, err := ReadDir(testFsys, ".")
+	check("testFsys", dirs, err)
+
+	// Test that ReadDir uses Open when the method is not present.
+	dirs, err = ReadDir(fsOnly{testFsys}, ".")
+	check("fsOnly", dirs, err)
+
+	// Test that ReadDir on Sub of . works (sub_test checks non-trivial subs).
+	sub, err := Sub(testFsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs, err = ReadDir(sub, ".")
+	check("sub(.)", dirs, err)
+}
+
+func TestValidPath(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		ok   bool
+	}{
+		{".", true},
+		{"x", true},
+		{"x/y", true},
+		{"", false},
+		{"..", false},
+		{"/", false},
+		{"x/", false},
+		{"/x", false},
+		{"x/y/", false},
+		{"/x/y", false},
+		{"./", false},
+		{"./.", false},
+		{"x/.", false},
+		{"x/./y", false},
+		{"x/../y", false},
+		{"x//y", false},
+		{`x\`, true},
+		{"x\xff", false},
+	} {
+		if ok := ValidPath(tt.name); ok != tt.ok {
+			t.Errorf("ValidPath(%q) = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
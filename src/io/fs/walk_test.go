@@ -0,0 +1,120 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fs_test
+
+import (
+	. "io/fs"
+	"io/ioutil"
+	"os"
+	pathpkg "path"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var walkTree = fstest.MapFS{
+	"a/x":       {},
+	"a/y":       {},
+	"a/b/c":     {},
+	"a/b/d/e":   {},
+	"a/f":       {},
+	"skip/me":   {},
+	"skip/also": {},
+	"z":         {},
+}
+
+func TestWalkDir(t *testing.T) {
+	var visited []string
+	err := WalkDir(walkTree, ".", func(path string, d DirEntry, err error) error {
+		if err != nil {
+			t.Errorf("walk %s: %v", path, err)
+			return err
+		}
+		if d.IsDir() != (walkTree[path] == nil) {
+			t.Errorf("walk %s: IsDir = %v", path, d.IsDir())
+		}
+		if path != "." && d.Name() != pathpkg.Base(path) {
+			t.Errorf("walk %s: Name = %q", path, d.Name())
+		}
+		visited = append(visited, path)
+		if path == "skip" {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "a", "a/b", "a/b/c", "a/b/d", "a/b/d/e", "a/f", "a/x", "a/y", "skip", "z"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("WalkDir visited %v\n\twant %v", visited, want)
+	}
+}
+
+func TestWalkDirSkipFile(t *testing.T) {
+	// Returning SkipDir for a file skips the rest of its directory.
+	var visited []string
+	err := WalkDir(walkTree, "a", func(path string, d DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		if path == "a/b/c" {
+			return SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "a/b", "a/b/c", "a/f", "a/x", "a/y"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("WalkDir visited %v\n\twant %v", visited, want)
+	}
+}
+
+func TestWalkDirMissingRoot(t *testing.T) {
+	called := false
+	err := WalkDir(walkTree, "missing", func(path string, d DirEntry, err error) error {
+		called = true
+		if path != "missing" || d != nil || !os.IsNotExist(err) {
+			t.Errorf("walk(%q, %v, %v), want walk(\"missing\", nil, not exist)", path, d, err)
+		}
+		return err
+	})
+	if !called {
+		t.Fatal("WalkDir did not call fn for missing root")
+	}
+	if !os.IsNotExist(err) {
+		t.Fatalf("WalkDir = %v, want not exist", err)
+	}
+}
+
+func TestWalkDirOS(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a/b", "c"} {
+		if err := os.MkdirAll(dir+"/"+name, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(dir+"/a/b/file", []byte("x"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var visited []string
+	err := WalkDir(os.DirFS(dir), ".", func(path string, d DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "a", "a/b", "a/b/file", "c"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("WalkDir visited %v\n\twant %v", visited, want)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog_test
+
+import (
+	"log"
+	"log/slog"
+	"os"
+)
+
+// removeTime removes the top-level time attribute, so that the output
+// of the examples is reproducible.
+func removeTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func ExampleTextHandler() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger.Info("hello", "count", 3, slog.Group("request", "method", "GET", "path", "/a b"))
+	// Output:
+	// level=INFO msg=hello count=3 request.method=GET request.path="/a b"
+}
+
+func ExampleJSONHandler() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	logger = logger.With("service", "api").WithGroup("request")
+	logger.Warn("slow request", "method", "GET", "ms", 1500)
+	// Output:
+	// {"level":"WARN","msg":"slow request","service":"api","request":{"method":"GET","ms":1500}}
+}
+
+func ExampleHandlerOptions_level() {
+	var level slog.LevelVar // INFO by default
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: &level, ReplaceAttr: removeTime}))
+	logger.Debug("not printed")
+	level.Set(slog.LevelDebug)
+	logger.Debug("printed")
+	// Output:
+	// level=DEBUG msg=printed
+}
+
+func ExampleNewLogLogger() {
+	h := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime})
+	logger := slog.NewLogLogger(h, slog.LevelWarn)
+	logger.Printf("disk %d%% full", 90)
+	// Output:
+	// level=WARN msg="disk 90% full"
+}
+
+func ExampleSetDefault() {
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.Flags())
+	defer slog.SetDefault(slog.Default())
+
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: removeTime})))
+	log.Printf("from the log package: %d", 42)
+	slog.Info("from slog")
+	// Output:
+	// {"level":"INFO","msg":"from the log package: 42"}
+	// {"level":"INFO","msg":"from slog"}
+}
@@ -0,0 +1,402 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// A Handler handles log records produced by a Logger.
+//
+// A typical handler may print log records to standard error,
+// or write them to a file or database, or perhaps augment them
+// with additional attributes and pass them on to another handler.
+//
+// Any of the Handler's methods may be called concurrently with itself
+// or with other methods. It is the responsibility of the Handler to
+// manage this concurrency.
+//
+// Users of the slog package should not invoke Handler methods directly.
+// They should use the methods of Logger instead.
+type Handler interface {
+	// Enabled reports whether the handler handles records at the given level.
+	// The handler ignores records whose level is lower.
+	// It is called early, before any arguments are processed,
+	// to save effort if the log event should be discarded.
+	// If called from a Logger method, the first argument is the context
+	// passed to that method, or context.Background() if nil was passed
+	// or the method does not take a context.
+	// The context is passed so Enabled can use its values
+	// to make a decision.
+	Enabled(context.Context, Level) bool
+
+	// Handle handles the Record.
+	// It will only be called when Enabled returns true.
+	// The Context argument is as for Enabled.
+	// It is present solely to provide Handlers access to the context's values.
+	// Canceling the context should not affect record processing.
+	//
+	// Handle methods that produce output should observe the following rules:
+	//   - If r.Time is the zero time, ignore the time.
+	//   - If r.PC is zero, ignore it.
+	//   - Attr's values should be resolved.
+	//   - If an Attr's key and value are both the zero value, ignore the Attr.
+	//   - If a group's key is empty, inline the group's Attrs.
+	//   - If a group has no Attrs (even if it has a non-empty key),
+	//     ignore it.
+	Handle(context.Context, Record) error
+
+	// WithAttrs returns a new Handler whose attributes consist of
+	// both the receiver's attributes and the arguments.
+	// The Handler owns the slice: it may retain, modify or discard it.
+	WithAttrs(attrs []Attr) Handler
+
+	// WithGroup returns a new Handler with the given group appended to
+	// the receiver's existing groups.
+	// The keys of all subsequent attributes, whether added by With or in a
+	// Record, should be qualified by the sequence of group names.
+	//
+	// How this qualification happens is up to the Handler, so long as
+	// this Handler's attribute keys differ from those of another Handler
+	// with a different sequence of group names.
+	//
+	// A Handler should treat WithGroup as starting a Group of Attrs that ends
+	// at the end of the log event. That is,
+	//
+	//	logger.WithGroup("s").LogAttrs(ctx, level, msg, slog.Int("a", 1), slog.Int("b", 2))
+	//
+	// should behave like
+	//
+	//	logger.LogAttrs(ctx, level, msg, slog.Group("s", slog.Int("a", 1), slog.Int("b", 2)))
+	//
+	// If the name is empty, WithGroup returns the receiver.
+	WithGroup(name string) Handler
+}
+
+// Keys for "built-in" attributes.
+const (
+	// TimeKey is the key used by the built-in handlers for the time
+	// when the log method is called. The associated Value is a time.Time.
+	TimeKey = "time"
+	// LevelKey is the key used by the built-in handlers for the level
+	// of the log call. The associated value is a Level.
+	LevelKey = "level"
+	// MessageKey is the key used by the built-in handlers for the
+	// message of the log call. The associated value is a string.
+	MessageKey = "msg"
+	// SourceKey is the key used by the built-in handlers for the source file
+	// and line of the log call. The associated value is a *Source.
+	SourceKey = "source"
+)
+
+// HandlerOptions are options for a TextHandler or JSONHandler.
+// A zero HandlerOptions consists entirely of default values.
+type HandlerOptions struct {
+	// AddSource causes the handler to compute the source code position
+	// of the log statement and add a SourceKey attribute to the output.
+	AddSource bool
+
+	// Level reports the minimum record level that will be logged.
+	// The handler discards records with lower levels.
+	// If Level is nil, the handler assumes LevelInfo.
+	// The handler calls Level.Level for each record processed;
+	// to adjust the minimum level dynamically, use a LevelVar.
+	Level Leveler
+
+	// ReplaceAttr is called to rewrite each non-group attribute before it is logged.
+	// The attribute's value has been resolved (see Value.Resolve).
+	// If ReplaceAttr returns a zero Attr, the attribute is discarded.
+	//
+	// The built-in attributes with keys "time", "level", "source", and "msg"
+	// are passed to this function, except that time is omitted
+	// if zero, and source is omitted if AddSource is false.
+	//
+	// The first argument is a list of currently open groups that contain the
+	// Attr. It must not be retained or modified. ReplaceAttr is never called
+	// for Group attributes, only their contents. For example, the attribute
+	// list
+	//
+	//	Int("a", 1), Group("g", Int("b", 2)), Int("c", 3)
+	//
+	// results in consecutive calls to ReplaceAttr with the following arguments:
+	//
+	//	nil, Int("a", 1)
+	//	[]string{"g"}, Int("b", 2)
+	//	nil, Int("c", 3)
+	ReplaceAttr func(groups []string, a Attr) Attr
+}
+
+// defaultHandler is the handler of the default Logger. It formats records
+// as text and hands them to the log package, so that until SetDefault is
+// called, output from the default Logger looks like, and goes to the same
+// place as, output from the log package's standard logger.
+type defaultHandler struct {
+	ch *commonHandler
+	// log.Output, except for testing
+	output func(calldepth int, message string) error
+}
+
+func newDefaultHandler(output func(int, string) error) *defaultHandler {
+	return &defaultHandler{
+		ch:     &commonHandler{},
+		output: output,
+	}
+}
+
+func (*defaultHandler) Enabled(_ context.Context, l Level) bool {
+	return l >= LevelInfo
+}
+
+// Handle collects the level, attributes and message in a string and
+// writes it with the log package's standard logger, whose flags and prefix
+// determine the remainder of the line.
+func (h *defaultHandler) Handle(ctx context.Context, r Record) error {
+	s := &handleState{h: h.ch}
+	s.buf = append(s.buf, r.Level.String()...)
+	s.buf = append(s.buf, ' ')
+	s.buf = append(s.buf, r.Message...)
+	s.sep = " "
+	h.ch.appendNonBuiltIns(s, r)
+	// Skip Handle, Logger.log and the Logger method, so that any file
+	// and line added by the log package is that of the caller.
+	return h.output(4, string(s.buf))
+}
+
+func (h *defaultHandler) WithAttrs(as []Attr) Handler {
+	return &defaultHandler{h.ch.withAttrs(as), h.output}
+}
+
+func (h *defaultHandler) WithGroup(name string) Handler {
+	return &defaultHandler{h.ch.withGroup(name), h.output}
+}
+
+// commonHandler implements the formatting shared by TextHandler and
+// JSONHandler.
+type commonHandler struct {
+	json bool // true => output JSON; false => output text
+	opts HandlerOptions
+	goas []groupOrAttrs
+	mu   *sync.Mutex
+	w    io.Writer
+}
+
+// groupOrAttrs holds either a group name or a list of Attrs
+// added by WithGroup or WithAttrs.
+type groupOrAttrs struct {
+	group string // group name if non-empty
+	attrs []Attr // attrs if non-empty
+}
+
+func newCommonHandler(w io.Writer, opts *HandlerOptions, json bool) *commonHandler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+	return &commonHandler{
+		json: json,
+		w:    w,
+		opts: *opts,
+		mu:   &sync.Mutex{},
+	}
+}
+
+func (h *commonHandler) clone() *commonHandler {
+	h2 := *h
+	// Force appends to allocate a new backing array,
+	// so the two handlers do not share groupOrAttrs.
+	h2.goas = h.goas[:len(h.goas):len(h.goas)]
+	return &h2
+}
+
+// enabled reports whether l is greater than or equal to the
+// minimum level.
+func (h *commonHandler) enabled(l Level) bool {
+	minLevel := LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *commonHandler) withAttrs(as []Attr) *commonHandler {
+	if len(as) == 0 {
+		return h
+	}
+	h2 := h.clone()
+	h2.goas = append(h2.goas, groupOrAttrs{attrs: as})
+	return h2
+}
+
+func (h *commonHandler) withGroup(name string) *commonHandler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.goas = append(h2.goas, groupOrAttrs{group: name})
+	return h2
+}
+
+// handle is the internal implementation of Handler.Handle
+// used by TextHandler and JSONHandler.
+func (h *commonHandler) handle(r Record) error {
+	s := &handleState{h: h}
+	if h.json {
+		s.buf = append(s.buf, '{')
+	}
+	// Built-in attributes. They are not in a group.
+	if !r.Time.IsZero() {
+		// Strip the monotonic reading, to match Attr behavior.
+		s.appendAttr(Time(TimeKey, r.Time.Round(0)))
+	}
+	s.appendAttr(Any(LevelKey, r.Level))
+	if h.opts.AddSource && r.PC != 0 {
+		s.appendAttr(Any(SourceKey, r.source()))
+	}
+	s.appendAttr(String(MessageKey, r.Message))
+	h.appendNonBuiltIns(s, r)
+	if h.json {
+		s.buf = append(s.buf, '}')
+	}
+	s.buf = append(s.buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(s.buf)
+	return err
+}
+
+// appendNonBuiltIns appends the attributes added by WithGroup and
+// WithAttrs, followed by those of the record.
+func (h *commonHandler) appendNonBuiltIns(s *handleState, r Record) {
+	goas := h.goas
+	if r.NumAttrs() == 0 {
+		// If the record has no Attrs, remove groups at the end of the list; they are empty.
+		for len(goas) > 0 && goas[len(goas)-1].group != "" {
+			goas = goas[:len(goas)-1]
+		}
+	}
+	nOpen := 0
+	for _, goa := range goas {
+		if goa.group != "" {
+			s.openGroup(goa.group)
+			nOpen++
+		} else {
+			for _, a := range goa.attrs {
+				s.appendAttr(a)
+			}
+		}
+	}
+	r.Attrs(func(a Attr) bool {
+		s.appendAttr(a)
+		return true
+	})
+	for i := len(goas) - 1; nOpen > 0; i-- {
+		if goas[i].group != "" {
+			s.closeGroup(goas[i].group)
+			nOpen--
+		}
+	}
+}
+
+// handleState holds state for a single call to commonHandler.handle.
+type handleState struct {
+	h      *commonHandler
+	buf    []byte
+	sep    string   // separator to write before next key
+	prefix []byte   // for text: key prefix
+	groups []string // open groups, for ReplaceAttr
+}
+
+// openGroup starts a new group of attributes
+// with the given name.
+func (s *handleState) openGroup(name string) {
+	if s.h.json {
+		s.appendKey(name)
+		s.buf = append(s.buf, '{')
+		s.sep = ""
+	} else {
+		s.prefix = append(s.prefix, name...)
+		s.prefix = append(s.prefix, '.')
+	}
+	s.groups = append(s.groups, name)
+}
+
+// closeGroup ends the group with the given name.
+func (s *handleState) closeGroup(name string) {
+	if s.h.json {
+		s.buf = append(s.buf, '}')
+		s.sep = ","
+	} else {
+		s.prefix = s.prefix[:len(s.prefix)-len(name)-1]
+	}
+	s.groups = s.groups[:len(s.groups)-1]
+}
+
+// appendAttr appends the Attr's key and value.
+// It handles replacement and checking for an empty key.
+func (s *handleState) appendAttr(a Attr) {
+	a.Value = a.Value.Resolve()
+	if rep := s.h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != KindGroup {
+		var gs []string
+		if len(s.groups) > 0 {
+			gs = s.groups
+		}
+		a = rep(gs, a)
+		// The ReplaceAttr function may return an unresolved Attr.
+		a.Value = a.Value.Resolve()
+	}
+	// Elide empty Attrs.
+	if a.isEmpty() {
+		return
+	}
+	if a.Value.Kind() == KindGroup {
+		attrs := a.Value.Group()
+		// Output only non-empty groups.
+		if len(attrs) == 0 {
+			return
+		}
+		// Inline a group with an empty key.
+		if a.Key != "" {
+			s.openGroup(a.Key)
+		}
+		for _, aa := range attrs {
+			s.appendAttr(aa)
+		}
+		if a.Key != "" {
+			s.closeGroup(a.Key)
+		}
+		return
+	}
+	s.appendKey(a.Key)
+	if s.h.json {
+		s.appendJSONValue(a.Value)
+	} else {
+		s.appendTextValue(a.Value)
+	}
+}
+
+func (s *handleState) appendKey(key string) {
+	s.buf = append(s.buf, s.sep...)
+	if s.h.json {
+		s.buf = appendJSONString(s.buf, key)
+		s.buf = append(s.buf, ':')
+		s.sep = ","
+	} else {
+		if len(s.prefix) > 0 {
+			key = string(s.prefix) + key
+		}
+		s.buf = appendTextString(s.buf, key)
+		s.buf = append(s.buf, '=')
+		s.sep = " "
+	}
+}
+
+// appendRFC3339Millis appends t in RFC 3339 format with millisecond
+// precision, which is enough for log lines and easier to read.
+func appendRFC3339Millis(b []byte, t time.Time) []byte {
+	return t.AppendFormat(b, "2006-01-02T15:04:05.000Z07:00")
+}
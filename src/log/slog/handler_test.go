@@ -0,0 +1,309 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2018, 7, 1, 12, 30, 45, 123456789, time.UTC)
+
+type name struct {
+	First, Last string
+}
+
+func (n name) LogValue() Value {
+	return GroupValue(String("first", n.First), String("last", n.Last))
+}
+
+type text string
+
+func (t text) MarshalText() ([]byte, error) {
+	return []byte("text{" + string(t) + "}"), nil
+}
+
+// removeKeys returns a ReplaceAttr function that removes the
+// top-level attributes with the given keys.
+func removeKeys(keys ...string) func([]string, Attr) Attr {
+	return func(groups []string, a Attr) Attr {
+		if len(groups) == 0 {
+			for _, k := range keys {
+				if a.Key == k {
+					return Attr{}
+				}
+			}
+		}
+		return a
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	ctx := context.Background()
+	preAttrs := []Attr{Int("pre", 3), String("x", "y")}
+	attrs := []Attr{String("a", "one"), Int("b", 2), Any("", nil)}
+
+	for _, test := range []struct {
+		name      string
+		replace   func([]string, Attr) Attr
+		addSource bool
+		with      func(Handler) Handler
+		preAttrs  []Attr
+		attrs     []Attr
+		wantText  string
+		wantJSON  string
+	}{
+		{
+			name:     "basic",
+			attrs:    attrs,
+			wantText: "time=2018-07-01T12:30:45.123Z level=INFO msg=message a=one b=2",
+			wantJSON: `{"time":"2018-07-01T12:30:45.123456789Z","level":"INFO","msg":"message","a":"one","b":2}`,
+		},
+		{
+			name:     "empty key",
+			attrs:    append(attrs, Any("", "v")),
+			wantText: `time=2018-07-01T12:30:45.123Z level=INFO msg=message a=one b=2 ""=v`,
+			wantJSON: `{"time":"2018-07-01T12:30:45.123456789Z","level":"INFO","msg":"message","a":"one","b":2,"":"v"}`,
+		},
+		{
+			name:     "cap keys",
+			replace:  upperCaseKey,
+			attrs:    attrs,
+			wantText: "TIME=2018-07-01T12:30:45.123Z LEVEL=INFO MSG=message A=one B=2",
+			wantJSON: `{"TIME":"2018-07-01T12:30:45.123456789Z","LEVEL":"INFO","MSG":"message","A":"one","B":2}`,
+		},
+		{
+			name:     "remove all",
+			replace:  removeAll,
+			attrs:    attrs,
+			wantText: "",
+			wantJSON: `{}`,
+		},
+		{
+			name:     "preformatted",
+			with:     func(h Handler) Handler { return h.WithAttrs(preAttrs) },
+			preAttrs: preAttrs,
+			attrs:    attrs,
+			wantText: "time=2018-07-01T12:30:45.123Z level=INFO msg=message pre=3 x=y a=one b=2",
+			wantJSON: `{"time":"2018-07-01T12:30:45.123456789Z","level":"INFO","msg":"message","pre":3,"x":"y","a":"one","b":2}`,
+		},
+		{
+			name:     "groups",
+			replace:  removeKeys(TimeKey, LevelKey),
+			attrs:    []Attr{Int("a", 1), Group("g", Int("b", 2), Group("h", Int("c", 3)), Int("d", 4)), Int("e", 5)},
+			wantText: "msg=message a=1 g.b=2 g.h.c=3 g.d=4 e=5",
+			wantJSON: `{"msg":"message","a":1,"g":{"b":2,"h":{"c":3},"d":4},"e":5}`,
+		},
+		{
+			name:     "empty group",
+			replace:  removeKeys(TimeKey, LevelKey),
+			attrs:    []Attr{Group("g"), Group("h", Int("a", 1))},
+			wantText: "msg=message h.a=1",
+			wantJSON: `{"msg":"message","h":{"a":1}}`,
+		},
+		{
+			name:     "inline group",
+			replace:  removeKeys(TimeKey, LevelKey),
+			attrs:    []Attr{Int("a", 1), Group("", Int("b", 2), Int("c", 3)), Int("d", 4)},
+			wantText: "msg=message a=1 b=2 c=3 d=4",
+			wantJSON: `{"msg":"message","a":1,"b":2,"c":3,"d":4}`,
+		},
+		{
+			name:    "WithGroup",
+			replace: removeKeys(TimeKey, LevelKey),
+			with: func(h Handler) Handler {
+				return h.WithAttrs([]Attr{Int("p1", 1)}).WithGroup("s").WithAttrs([]Attr{Int("p2", 2)})
+			},
+			attrs:    attrs,
+			wantText: "msg=message p1=1 s.p2=2 s.a=one s.b=2",
+			wantJSON: `{"msg":"message","p1":1,"s":{"p2":2,"a":"one","b":2}}`,
+		},
+		{
+			name:     "WithGroup no attrs",
+			replace:  removeKeys(TimeKey, LevelKey),
+			with:     func(h Handler) Handler { return h.WithGroup("s").WithGroup("t") },
+			wantText: "msg=message",
+			wantJSON: `{"msg":"message"}`,
+		},
+		{
+			name: "GroupValue in ReplaceAttr",
+			replace: func(gs []string, a Attr) Attr {
+				if len(gs) == 1 && a.Key == "b" {
+					return Group("bb", Int("c", 3))
+				}
+				return removeKeys(TimeKey, LevelKey)(gs, a)
+			},
+			attrs:    []Attr{Group("g", Int("a", 1), Int("b", 2))},
+			wantText: "msg=message g.a=1 g.bb.c=3",
+			wantJSON: `{"msg":"message","g":{"a":1,"bb":{"c":3}}}`,
+		},
+		{
+			name:     "resolve",
+			replace:  removeKeys(TimeKey, LevelKey),
+			attrs:    []Attr{Any("name", name{"Ren", "Hoek"})},
+			wantText: "msg=message name.first=Ren name.last=Hoek",
+			wantJSON: `{"msg":"message","name":{"first":"Ren","last":"Hoek"}}`,
+		},
+		{
+			name:     "quoting and marshaling",
+			replace:  removeKeys(TimeKey, LevelKey),
+			attrs:    []Attr{String("k", "x y"), Any("t", text("abc")), Any("err", errors.New("oops")), Duration("d", time.Second)},
+			wantText: `msg=message k="x y" t=text{abc} err=oops d=1s`,
+			wantJSON: `{"msg":"message","k":"x y","t":"text{abc}","err":"oops","d":1000000000}`,
+		},
+	} {
+		r := NewRecord(testTime, LevelInfo, "message", 0)
+		r.AddAttrs(test.attrs...)
+		var buf bytes.Buffer
+		opts := HandlerOptions{ReplaceAttr: test.replace, AddSource: test.addSource}
+		for _, handler := range []struct {
+			name string
+			h    Handler
+			want string
+		}{
+			{"text", NewTextHandler(&buf, &opts), test.wantText},
+			{"json", NewJSONHandler(&buf, &opts), test.wantJSON},
+		} {
+			h := handler.h
+			if test.with != nil {
+				h = test.with(h)
+			}
+			buf.Reset()
+			if err := h.Handle(ctx, r); err != nil {
+				t.Fatalf("%s, %s: %v", test.name, handler.name, err)
+			}
+			got := strings.TrimSuffix(buf.String(), "\n")
+			if got != handler.want {
+				t.Errorf("%s, %s:\ngot  %s\nwant %s", test.name, handler.name, got, handler.want)
+			}
+		}
+	}
+}
+
+func upperCaseKey(_ []string, a Attr) Attr {
+	a.Key = strings.ToUpper(a.Key)
+	return a
+}
+
+func removeAll(_ []string, a Attr) Attr {
+	return Attr{}
+}
+
+func TestHandlerSource(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(NewTextHandler(&buf, &HandlerOptions{AddSource: true}))
+	l.Info("message")
+	if got := buf.String(); !strings.Contains(got, "source=") || !strings.Contains(got, "handler_test.go:") {
+		t.Errorf("got %q, want source location of the caller", got)
+	}
+
+	buf.Reset()
+	l = New(NewJSONHandler(&buf, &HandlerOptions{AddSource: true}))
+	l.Info("message")
+	var m struct {
+		Source Source `json:"source"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(m.Source.File, "handler_test.go") || m.Source.Line == 0 || !strings.HasSuffix(m.Source.Function, "TestHandlerSource") {
+		t.Errorf("got source %+v, want location in TestHandlerSource", m.Source)
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	levelVar := func(l Level) *LevelVar {
+		var al LevelVar
+		al.Set(l)
+		return &al
+	}
+
+	for _, test := range []struct {
+		leveler Leveler
+		want    bool
+	}{
+		{nil, true},
+		{LevelWarn, false},
+		{&LevelVar{}, true}, // defaults to Info
+		{levelVar(LevelWarn), false},
+		{LevelDebug, true},
+		{levelVar(LevelDebug), true},
+	} {
+		h := NewTextHandler(nil, &HandlerOptions{Level: test.leveler})
+		got := h.Enabled(context.Background(), LevelInfo)
+		if got != test.want {
+			t.Errorf("%v: got %t, want %t", test.leveler, got, test.want)
+		}
+	}
+}
+
+func TestTextNeedsQuoting(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want bool
+	}{
+		{"", true},
+		{"ab", false},
+		{"a=b", true},
+		{`"ab"`, true},
+		{"\a\b", true},
+		{"a\tb", true},
+		{"µåπ", false},
+		{"a b", true},
+		{"badutf8\xF6", true},
+	} {
+		got := needsQuoting(test.in)
+		if got != test.want {
+			t.Errorf("%q: got %t, want %t", test.in, got, test.want)
+		}
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"abc",
+		"a\"b\\c",
+		"\n\r\t\x00\x1f",
+		"<html>&",
+		"µåπ",
+		"\u2028\u2029",
+	} {
+		got := string(appendJSONString(nil, s))
+		var back string
+		if err := json.Unmarshal([]byte(got), &back); err != nil {
+			t.Errorf("%q: %s is not valid JSON: %v", s, got, err)
+			continue
+		}
+		if back != s {
+			t.Errorf("%q: round trip through %s gave %q", s, got, back)
+		}
+	}
+	if got, want := string(appendJSONString(nil, "<&>")), `"<&>"`; got != want {
+		t.Errorf("got %s, want %s (HTML characters should not be escaped)", got, want)
+	}
+	if got, want := string(appendJSONString(nil, "x\xffy")), `"x\ufffdy"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestJSONFloat(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewJSONHandler(&buf, &HandlerOptions{ReplaceAttr: removeKeys(TimeKey, LevelKey, MessageKey)})
+	r := NewRecord(time.Time{}, LevelInfo, "", 0)
+	r.AddAttrs(Float64("f", 1.5), Float64("inf", math.Inf(1)), Float64("nan", math.NaN()))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"f":1.5,"inf":"+Inf","nan":"NaN"}`+"\n"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONHandler is a Handler that writes Records to an io.Writer as
+// line-delimited JSON objects.
+type JSONHandler struct {
+	*commonHandler
+}
+
+// NewJSONHandler creates a JSONHandler that writes to w,
+// using the given options.
+// If opts is nil, the default options are used.
+func NewJSONHandler(w io.Writer, opts *HandlerOptions) *JSONHandler {
+	return &JSONHandler{newCommonHandler(w, opts, true)}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+// The handler ignores records whose level is lower.
+func (h *JSONHandler) Enabled(_ context.Context, level Level) bool {
+	return h.commonHandler.enabled(level)
+}
+
+// WithAttrs returns a new JSONHandler whose attributes consists
+// of h's attributes followed by attrs.
+func (h *JSONHandler) WithAttrs(attrs []Attr) Handler {
+	return &JSONHandler{commonHandler: h.commonHandler.withAttrs(attrs)}
+}
+
+// WithGroup returns a new JSONHandler whose subsequent attributes
+// are nested in a JSON object with the given name.
+func (h *JSONHandler) WithGroup(name string) Handler {
+	return &JSONHandler{commonHandler: h.commonHandler.withGroup(name)}
+}
+
+// Handle formats its argument Record as a JSON object on a single line.
+//
+// If the Record's time is zero, the time is omitted.
+// Otherwise, the key is "time"
+// and the value is output as with json.Marshal.
+//
+// The level's key is "level" and its value is the result of calling Level.String.
+//
+// If the AddSource option is set and source information is available,
+// the key is "source", and the value is a record of type Source.
+//
+// The message's key is "msg".
+//
+// To modify these or other attributes, or remove them from the output, use
+// HandlerOptions.ReplaceAttr.
+//
+// Values are formatted as with an encoding/json.Encoder with SetEscapeHTML(false),
+// with two exceptions.
+//
+// First, an Attr whose Value is of type error is formatted as a string, by
+// calling its Error method. Only errors in Attrs receive this special treatment,
+// not errors embedded in structs, slices, maps or other data structures that
+// are processed by the encoding/json package.
+//
+// Second, an encoding failure does not cause Handle to return an error.
+// Instead, the error message is formatted as a string.
+//
+// Each call to Handle results in a single serialized call to io.Writer.Write.
+func (h *JSONHandler) Handle(_ context.Context, r Record) error {
+	return h.commonHandler.handle(r)
+}
+
+func (s *handleState) appendJSONValue(v Value) {
+	switch v.Kind() {
+	case KindString:
+		s.buf = appendJSONString(s.buf, v.String())
+	case KindInt64:
+		s.buf = strconv.AppendInt(s.buf, v.Int64(), 10)
+	case KindUint64:
+		s.buf = strconv.AppendUint(s.buf, v.Uint64(), 10)
+	case KindFloat64:
+		f := v.Float64()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			// JSON has no representation for these values.
+			s.buf = appendJSONString(s.buf, strconv.FormatFloat(f, 'g', -1, 64))
+			return
+		}
+		s.buf = strconv.AppendFloat(s.buf, f, 'g', -1, 64)
+	case KindBool:
+		s.buf = strconv.AppendBool(s.buf, v.Bool())
+	case KindDuration:
+		// Do what json.Marshal does.
+		s.buf = strconv.AppendInt(s.buf, int64(v.Duration()), 10)
+	case KindTime:
+		s.buf = append(s.buf, '"')
+		s.buf = v.Time().AppendFormat(s.buf, time.RFC3339Nano)
+		s.buf = append(s.buf, '"')
+	case KindAny:
+		a := v.Any()
+		_, jm := a.(json.Marshaler)
+		if err, ok := a.(error); ok && !jm {
+			s.buf = appendJSONString(s.buf, err.Error())
+			return
+		}
+		s.buf = appendJSONMarshal(s.buf, a)
+	default:
+		s.buf = appendJSONString(s.buf, v.String())
+	}
+}
+
+func appendJSONMarshal(b []byte, v interface{}) []byte {
+	// Use a json.Encoder to avoid escaping HTML.
+	var bb bytes.Buffer
+	enc := json.NewEncoder(&bb)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return appendJSONString(b, "!ERROR:"+err.Error())
+	}
+	return append(b, bytes.TrimRight(bb.Bytes(), "\n")...)
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s to b as a quoted JSON string.
+// It does not escape HTML characters, but does escape invalid UTF-8
+// and the line and paragraph separators U+2028 and U+2029,
+// as encoding/json does.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				// This encodes bytes < 0x20 except for \t, \n and \r.
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
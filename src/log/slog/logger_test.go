@@ -0,0 +1,244 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// textTimeRE matches the time emitted by a TextHandler.
+const textTimeRE = `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}.\d{3}(Z|[+-]\d{2}:\d{2})`
+
+func checkLogOutput(t *testing.T, got, wantRegexp string) {
+	t.Helper()
+	got = strings.TrimSuffix(got, "\n")
+	wantRegexp = "^" + wantRegexp + "$"
+	matched, err := regexp.MatchString(wantRegexp, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matched {
+		t.Errorf("\ngot  %s\nwant %s", got, wantRegexp)
+	}
+}
+
+func TestLogTextHandler(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+
+	l := New(NewTextHandler(&buf, nil))
+
+	check := func(want string) {
+		t.Helper()
+		if want != "" {
+			want = "time=" + textTimeRE + " " + want
+		}
+		checkLogOutput(t, buf.String(), want)
+		buf.Reset()
+	}
+
+	l.Info("msg", "a", 1, "b", 2)
+	check(`level=INFO msg=msg a=1 b=2`)
+
+	// By default, debug messages are not printed.
+	l.Debug("bg", Int("a", 1), "b", 2)
+	check("")
+
+	l.Warn("w", Duration("dur", 3*time.Second))
+	check(`level=WARN msg=w dur=3s`)
+
+	l.Error("bad", "a", 1)
+	check(`level=ERROR msg=bad a=1`)
+
+	l.Log(ctx, LevelWarn+1, "w", Int("a", 1), String("b", "two"))
+	check(`level=WARN\+1 msg=w a=1 b=two`)
+
+	l.LogAttrs(ctx, LevelInfo+1, "a b c", Int("a", 1), String("b", "two"))
+	check(`level=INFO\+1 msg="a b c" a=1 b=two`)
+
+	l.Info("info", "a", []Attr{Int("i", 1)})
+	check(`level=INFO msg=info a.i=1`)
+
+	l.Info("info", "a", GroupValue(Int("i", 1)))
+	check(`level=INFO msg=info a.i=1`)
+
+	l.Info("odd", "a")
+	check(`level=INFO msg=odd !BADKEY=a`)
+
+	l.Info("missing key", 42)
+	check(`level=INFO msg="missing key" !BADKEY=42`)
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(NewTextHandler(&buf, &HandlerOptions{ReplaceAttr: removeKeys(TimeKey)}))
+	l2 := l.With("a", 1).WithGroup("g").With("b", 2)
+	l2.Info("m", "c", 3)
+	checkLogOutput(t, buf.String(), `level=INFO msg=m a=1 g.b=2 g.c=3`)
+
+	// The original logger is unchanged.
+	buf.Reset()
+	l.Info("m")
+	checkLogOutput(t, buf.String(), `level=INFO msg=m`)
+
+	if l.With() != l || l.WithGroup("") != l {
+		t.Error("With and WithGroup with no arguments should return the receiver")
+	}
+}
+
+func TestDefaultLogger(t *testing.T) {
+	defer saveLogState()()
+	var logbuf bytes.Buffer
+	log.SetOutput(&logbuf)
+	log.SetFlags(0)
+
+	Info("msg", "a", 1)
+	checkLogOutput(t, logbuf.String(), `INFO msg a=1`)
+	logbuf.Reset()
+	Debug("not shown")
+	checkLogOutput(t, logbuf.String(), "")
+	logbuf.Reset()
+	With("x", "y").WithGroup("g").Warn("w", "a", 1)
+	checkLogOutput(t, logbuf.String(), `WARN w x=y g.a=1`)
+	logbuf.Reset()
+
+	// The default handler lets the log package add the location,
+	// which must be that of the caller of the slog function.
+	log.SetFlags(log.Lshortfile)
+	Info("where")
+	checkLogOutput(t, logbuf.String(), `logger_test.go:\d+: INFO where`)
+	logbuf.Reset()
+	Default().Error("where")
+	checkLogOutput(t, logbuf.String(), `logger_test.go:\d+: ERROR where`)
+}
+
+func TestSetDefault(t *testing.T) {
+	defer saveLogState()()
+	var logbuf, slogbuf bytes.Buffer
+	log.SetOutput(&logbuf)
+	log.SetFlags(log.Lshortfile)
+
+	SetDefault(New(NewTextHandler(&slogbuf, &HandlerOptions{AddSource: true, ReplaceAttr: removeKeys(TimeKey)})))
+
+	// Output from the log package goes through the new handler,
+	// with the location of the caller of log.Print.
+	log.Print("hello")
+	checkLogOutput(t, slogbuf.String(), `level=INFO source=.*logger_test.go:\d+ msg=hello`)
+	slogbuf.Reset()
+
+	Info("top", "a", 1)
+	checkLogOutput(t, slogbuf.String(), `level=INFO source=.*logger_test.go:\d+ msg=top a=1`)
+	if logbuf.Len() != 0 {
+		t.Errorf("log output not redirected: %q", logbuf.String())
+	}
+
+	// Setting a Logger with the default handler must not make log write to
+	// itself.
+	SetDefault(New(newDefaultHandler(log.Output)))
+	log.SetOutput(&logbuf)
+	log.SetFlags(0)
+	SetDefault(Default())
+	Info("again")
+	checkLogOutput(t, logbuf.String(), `INFO again`)
+}
+
+// saveLogState saves the state of the log package and the default slog
+// Logger, and returns a function that restores it. The log package's
+// output is restored to os.Stderr, its initial value.
+func saveLogState() func() {
+	flags := log.Flags()
+	prefix := log.Prefix()
+	def := Default()
+	return func() {
+		defaultLogger.Store(def)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+func TestNewLogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf, &HandlerOptions{ReplaceAttr: removeKeys(TimeKey)})
+	ll := NewLogLogger(h, LevelWarn)
+	ll.Printf("hello %d\n", 42)
+	checkLogOutput(t, buf.String(), `level=WARN msg="hello 42"`)
+
+	buf.Reset()
+	ll = NewLogLogger(h, LevelDebug)
+	ll.Print("dropped")
+	checkLogOutput(t, buf.String(), "")
+}
+
+type ctxKey struct{}
+
+// contextHandler adds the value stored under ctxKey in the context
+// to each record.
+type contextHandler struct {
+	Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r Record) error {
+	if v := ctx.Value(ctxKey{}); v != nil {
+		r.AddAttrs(Any("ctx", v))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(as []Attr) Handler {
+	return contextHandler{h.Handler.WithAttrs(as)}
+}
+
+func (h contextHandler) WithGroup(name string) Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func TestContext(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(contextHandler{NewTextHandler(&buf, &HandlerOptions{ReplaceAttr: removeKeys(TimeKey)})})
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+
+	l.InfoContext(ctx, "m")
+	checkLogOutput(t, buf.String(), `level=INFO msg=m ctx=request-1`)
+	buf.Reset()
+
+	// A nil context is treated as context.Background.
+	l.Log(nil, LevelInfo, "m")
+	checkLogOutput(t, buf.String(), `level=INFO msg=m`)
+	buf.Reset()
+
+	// A Logger stored in the context is retrieved with FromContext.
+	ctx = NewContext(ctx, l.With("user", "gopher"))
+	FromContext(ctx).WarnContext(ctx, "m")
+	checkLogOutput(t, buf.String(), `level=WARN msg=m user=gopher ctx=request-1`)
+
+	if FromContext(context.Background()) != Default() {
+		t.Error("FromContext without a Logger should return the default Logger")
+	}
+}
+
+func TestAlloc(t *testing.T) {
+	// The Value representation of common kinds should not allocate.
+	a := testing.AllocsPerRun(100, func() {
+		v := Int64Value(1)
+		_ = v.Int64()
+		v = Float64Value(2)
+		_ = v.Float64()
+		v = BoolValue(true)
+		_ = v.Bool()
+		v = DurationValue(time.Second)
+		_ = v.Duration()
+	})
+	if a != 0 {
+		t.Errorf("got %v allocs, want 0", a)
+	}
+}
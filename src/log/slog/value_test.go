@@ -0,0 +1,273 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelString(t *testing.T) {
+	for _, test := range []struct {
+		in   Level
+		want string
+	}{
+		{0, "INFO"},
+		{LevelError, "ERROR"},
+		{LevelError + 2, "ERROR+2"},
+		{LevelError - 2, "WARN+2"},
+		{LevelWarn, "WARN"},
+		{LevelWarn - 1, "INFO+3"},
+		{LevelInfo, "INFO"},
+		{LevelInfo + 1, "INFO+1"},
+		{LevelInfo - 3, "DEBUG+1"},
+		{LevelDebug, "DEBUG"},
+		{LevelDebug - 2, "DEBUG-2"},
+	} {
+		got := test.in.String()
+		if got != test.want {
+			t.Errorf("%d: got %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestLevelUnmarshalText(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Level
+	}{
+		{"DEBUG", LevelDebug},
+		{"INFO", LevelInfo},
+		{"WARN", LevelWarn},
+		{"ERROR", LevelError},
+		{"debug", LevelDebug},
+		{"iNfo", LevelInfo},
+		{"INFO+87", LevelInfo + 87},
+		{"Error-18", LevelError - 18},
+		{"Error-8", LevelInfo},
+	} {
+		var got Level
+		if err := got.UnmarshalText([]byte(test.in)); err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %s, want %s", test.in, got, test.want)
+		}
+	}
+	for _, in := range []string{"", "FOO", "INFO+", "INFO-x"} {
+		var l Level
+		if err := l.UnmarshalText([]byte(in)); err == nil {
+			t.Errorf("%q: got nil error, want error", in)
+		}
+	}
+}
+
+func TestLevelVar(t *testing.T) {
+	var al LevelVar
+	if got, want := al.Level(), LevelInfo; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	al.Set(LevelWarn)
+	if got, want := al.Level(), LevelWarn; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := al.UnmarshalText([]byte("error+1")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := al.String(), "LevelVar(ERROR+1)"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	var x, y int
+	vals := []Value{
+		{},
+		Int64Value(1),
+		Int64Value(2),
+		Float64Value(3.5),
+		Float64Value(3.7),
+		BoolValue(true),
+		BoolValue(false),
+		TimeValue(testTime),
+		AnyValue(&x),
+		AnyValue(&y),
+		GroupValue(Bool("b", true), Int("i", 3)),
+	}
+	for i, v1 := range vals {
+		for j, v2 := range vals {
+			got := v1.Equal(v2)
+			want := i == j
+			if got != want {
+				t.Errorf("%v.Equal(%v): got %t, want %t", v1, v2, got, want)
+			}
+		}
+	}
+}
+
+func TestValueString(t *testing.T) {
+	for _, test := range []struct {
+		v    Value
+		want string
+	}{
+		{Int64Value(-3), "-3"},
+		{Uint64Value(1), "1"},
+		{Float64Value(.15), "0.15"},
+		{BoolValue(true), "true"},
+		{StringValue("foo"), "foo"},
+		{TimeValue(testTime), "2018-07-01 12:30:45.123456789 +0000 UTC"},
+		{AnyValue(time.Duration(3 * time.Second)), "3s"},
+		{GroupValue(Int("a", 1), Bool("b", true)), "[a=1 b=true]"},
+	} {
+		if got := test.v.String(); got != test.want {
+			t.Errorf("%#v:\ngot  %q\nwant %q", test.v, got, test.want)
+		}
+	}
+}
+
+func TestValueAny(t *testing.T) {
+	for _, want := range []interface{}{
+		nil,
+		LevelDebug + 100,
+		time.UTC, // time.Locations treated specially...
+		KindBool, // ...as are Kinds
+		[]Attr{Int("a", 1)},
+		int64(2),
+		uint64(3),
+		true,
+		time.Minute,
+		time.Time{},
+		3.5,
+		"foo",
+	} {
+		v := AnyValue(want)
+		got := v.Any()
+		if a, ok := want.([]Attr); ok {
+			if !attrsEqual(got.([]Attr), a) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			continue
+		}
+		if got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAnyValueWidth(t *testing.T) {
+	for _, test := range []struct {
+		in   interface{}
+		want Kind
+	}{
+		{int8(-1), KindInt64},
+		{int32(-1), KindInt64},
+		{uint8(1), KindUint64},
+		{uintptr(1), KindUint64},
+		{float32(1.5), KindFloat64},
+		{LevelInfo, KindAny},
+	} {
+		if got := AnyValue(test.in).Kind(); got != test.want {
+			t.Errorf("AnyValue(%T): got kind %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+type replace struct {
+	v Value
+}
+
+func (r *replace) LogValue() Value { return r.v }
+
+type panickingLogValue struct{}
+
+func (panickingLogValue) LogValue() Value { panic("bad") }
+
+func TestLogValue(t *testing.T) {
+	want := "replaced"
+	r := &replace{StringValue(want)}
+	v := AnyValue(r)
+	if g, w := v.Kind(), KindLogValuer; g != w {
+		t.Errorf("got %s, want %s", g, w)
+	}
+	got := v.LogValuer().LogValue().Any()
+	if got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Test Resolve.
+	got = v.Resolve().Any()
+	if got != want {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// Test Resolve max iteration.
+	r.v = AnyValue(r) // create a cycle
+	v = AnyValue(r)
+	got = v.Resolve().Any()
+	if _, ok := got.(error); !ok {
+		t.Errorf("expected error, got %T", got)
+	}
+
+	// Test Resolve group.
+	r = &replace{GroupValue(Int("a", 1), Group("b", Any("c", &replace{StringValue("d")})))}
+	v = AnyValue(r)
+	got2 := v.Resolve().Any().([]Attr)
+	if got2[1].Value.Group()[0].Value.Kind() != KindLogValuer {
+		t.Errorf("Resolve should not resolve group members recursively")
+	}
+
+	// A panicking LogValue method produces an error value.
+	got = AnyValue(panickingLogValue{}).Resolve().Any()
+	if err, ok := got.(error); !ok || !strings.Contains(err.Error(), "LogValue panicked") {
+		t.Errorf("got %v, want error from panicking LogValue", got)
+	}
+}
+
+func TestRecordAttrs(t *testing.T) {
+	as := []Attr{Int("k1", 1), String("k2", "foo"), Int("k3", 3), Group("empty")}
+	r := NewRecord(time.Time{}, 0, "", 0)
+	r.AddAttrs(as...)
+	if got, want := r.NumAttrs(), 3; got != want {
+		t.Errorf("NumAttrs: got %d, want %d", got, want)
+	}
+	var got []Attr
+	r.Attrs(func(a Attr) bool {
+		got = append(got, a)
+		return len(got) < 2 // stop after the second
+	})
+	if !attrsEqual(got, as[:2]) {
+		t.Errorf("got %v, want %v", got, as[:2])
+	}
+
+	// Appending to a copy does not affect the original.
+	r2 := r
+	r2.Add("k4", 4)
+	if r.NumAttrs() != 3 || r2.NumAttrs() != 4 {
+		t.Errorf("got %d and %d attrs, want 3 and 4", r.NumAttrs(), r2.NumAttrs())
+	}
+	r3 := r.Clone()
+	r3.AddAttrs(Int("k5", 5))
+	if r.NumAttrs() != 3 {
+		t.Errorf("Clone shares state with the original")
+	}
+}
+
+func TestAttrString(t *testing.T) {
+	for _, test := range []struct {
+		a    Attr
+		want string
+	}{
+		{Int("a", 1), "a=1"},
+		{String("b", "x y"), "b=x y"},
+		{Group("g", "a", 1, "b", 2), "g=[a=1 b=2]"},
+		{Any("c", fmt.Errorf("oops")), "c=oops"},
+	} {
+		if got := test.a.String(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
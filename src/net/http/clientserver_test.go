@@ -9,7 +9,9 @@ package http_test
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,7 +19,9 @@ import (
 	"net"
 	. "net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"net/http/httputil"
+	"net/textproto"
 	"net/url"
 	"os"
 	"reflect"
@@ -1480,3 +1484,153 @@ func testWriteHeaderAfterWrite(t *testing.T, h2, hijack bool) {
 		t.Errorf("stderr output = %q; want %q", gotLog, wantLog)
 	}
 }
+
+func TestServerContexts_h1(t *testing.T) { testServerContexts(t, h1Mode) }
+func TestServerContexts_h2(t *testing.T) { testServerContexts(t, h2Mode) }
+
+func testServerContexts(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	type baseKey struct{}
+	type connKey struct{}
+	ch := make(chan context.Context, 1)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ch <- r.Context()
+	}), func(ts *httptest.Server) {
+		ts.Config.BaseContext = func(ln net.Listener) context.Context {
+			if ln == nil {
+				t.Error("BaseContext called with nil Listener")
+			}
+			return context.WithValue(context.Background(), baseKey{}, "base")
+		}
+		ts.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+			if got, want := ctx.Value(baseKey{}), "base"; got != want {
+				t.Errorf("in ConnContext, base context key = %#v; want %q", got, want)
+			}
+			if _, ok := c.(*tls.Conn); ok != h2 {
+				t.Errorf("in ConnContext, got conn of type %T", c)
+			}
+			return context.WithValue(ctx, connKey{}, "conn")
+		}
+	})
+	defer cst.close()
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	ctx := <-ch
+	if got, want := ctx.Value(baseKey{}), "base"; got != want {
+		t.Errorf("base context key = %#v; want %q", got, want)
+	}
+	if got, want := ctx.Value(connKey{}), "conn"; got != want {
+		t.Errorf("conn context key = %#v; want %q", got, want)
+	}
+	if got := ctx.Value(ServerContextKey); got != cst.ts.Config {
+		t.Errorf("ServerContextKey = %v; want %v", got, cst.ts.Config)
+	}
+}
+
+func TestInterimResponses_h1(t *testing.T) { testInterimResponses(t, h1Mode) }
+func TestInterimResponses_h2(t *testing.T) { testInterimResponses(t, h2Mode) }
+func testInterimResponses(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		h := w.Header()
+		h.Add("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(StatusEarlyHints)
+		h.Add("Link", "</script.js>; rel=preload; as=script")
+		w.WriteHeader(StatusEarlyHints)
+		h.Set("Content-Length", "5") // not sent with 1xx headers
+		h.Set("X-Final", "yes")
+		w.WriteHeader(StatusOK)
+		w.Write([]byte("hello"))
+	}))
+	defer cst.close()
+
+	type got1xx struct {
+		code  int
+		links []string
+		clen  string
+	}
+	var got []got1xx
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			got = append(got, got1xx{code, header["Link"], header.Get("Content-Length")})
+			return nil
+		},
+	}
+	req, _ := NewRequest("GET", cst.ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	want := []got1xx{
+		{103, []string{"</style.css>; rel=preload; as=style"}, ""},
+		{103, []string{"</style.css>; rel=preload; as=style", "</script.js>; rel=preload; as=script"}, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("1xx responses = %+v\nwant %+v", got, want)
+	}
+	if res.StatusCode != 200 || res.Header.Get("X-Final") != "yes" || len(res.Header["Link"]) != 2 {
+		t.Errorf("final response = %v %v", res.Status, res.Header)
+	}
+	if body, err := ioutil.ReadAll(res.Body); err != nil || string(body) != "hello" {
+		t.Errorf("body = %q, %v; want %q", body, err, "hello")
+	}
+}
+
+func TestInterimResponsesTraceError_h1(t *testing.T) { testInterimResponsesTraceError(t, h1Mode) }
+func TestInterimResponsesTraceError_h2(t *testing.T) { testInterimResponsesTraceError(t, h2Mode) }
+func testInterimResponsesTraceError(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.WriteHeader(StatusEarlyHints)
+		w.WriteHeader(StatusOK)
+	}))
+	defer cst.close()
+
+	traceErr := errors.New("no early hints please")
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			return traceErr
+		},
+	}
+	req, _ := NewRequest("GET", cst.ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := cst.c.Do(req)
+	if err == nil {
+		res.Body.Close()
+	}
+	if err == nil || !strings.Contains(err.Error(), traceErr.Error()) {
+		t.Errorf("Do = %v; want error containing %q", err, traceErr)
+	}
+}
+
+func TestTooManyInterimResponses_h1(t *testing.T) { testTooManyInterimResponses(t, h1Mode) }
+func TestTooManyInterimResponses_h2(t *testing.T) { testTooManyInterimResponses(t, h2Mode) }
+func testTooManyInterimResponses(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		for i := 0; i < 6; i++ {
+			w.WriteHeader(StatusEarlyHints)
+		}
+		w.WriteHeader(StatusOK)
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("Get succeeded, want error for too many 1xx responses")
+	}
+	if !strings.Contains(err.Error(), "too many 1xx informational responses") {
+		t.Errorf("Get = %v, want too many 1xx informational responses error", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"net/http/internal"
+	"time"
+)
+
+// wallClock is the internal.Clock used by Servers and Transports
+// that have no other clock installed.
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+func (wallClock) AfterFunc(d time.Duration, f func()) internal.Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (wallClock) NewTimer(d time.Duration) (internal.Timer, <-chan time.Time) {
+	t := time.NewTimer(d)
+	return t, t.C
+}
+
+// timeSource returns the clock used for srv's timeouts.
+func (srv *Server) timeSource() internal.Clock {
+	if c := internal.ClockOf(srv); c != nil {
+		return c
+	}
+	return wallClock{}
+}
+
+// timeSource returns the clock used for t's timeouts.
+func (t *Transport) timeSource() internal.Clock {
+	if c := internal.ClockOf(t); c != nil {
+		return c
+	}
+	return wallClock{}
+}
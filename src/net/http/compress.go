@@ -0,0 +1,263 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Server-side content coding: decompression of request bodies
+// and gzip compression of responses.
+
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// decompressRequestBody replaces the body of req with one that
+// decompresses it, if req has a gzip or deflate Content-Encoding.
+func decompressRequestBody(req *Request) {
+	ce := req.Header["Content-Encoding"]
+	if len(ce) != 1 {
+		return
+	}
+	var newReader func(io.Reader) (io.ReadCloser, error)
+	switch strings.ToLower(textproto.TrimString(ce[0])) {
+	case "gzip", "x-gzip":
+		newReader = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case "deflate":
+		// RFC 7230 section 4.2.2: "deflate" is the zlib format.
+		newReader = zlib.NewReader
+	default:
+		return
+	}
+	req.Body = &decompressReader{body: req.Body, newReader: newReader}
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+}
+
+// decompressReader wraps a request body so it can lazily create
+// its decompressor on the first call to Read. Creating it reads
+// from the body, which must not happen before the Handler asks for
+// it, so as not to send a premature "100 Continue" response.
+type decompressReader struct {
+	body      io.ReadCloser
+	newReader func(io.Reader) (io.ReadCloser, error)
+	zr        io.ReadCloser // lazily-initialized decompressor
+	zerr      error         // any error from newReader; sticky
+}
+
+func (d *decompressReader) Read(p []byte) (n int, err error) {
+	if d.zr == nil {
+		if d.zerr == nil {
+			d.zr, d.zerr = d.newReader(d.body)
+		}
+		if d.zerr != nil {
+			return 0, d.zerr
+		}
+	}
+	return d.zr.Read(p)
+}
+
+func (d *decompressReader) Close() error {
+	return d.body.Close()
+}
+
+// GzipHandler returns a handler that serves requests with h and, if
+// the request's Accept-Encoding header allows it, compresses the
+// response with gzip.
+//
+// The response is not compressed if it is to a HEAD request, has a
+// status code which does not permit a body or is 206 (Partial
+// Content), already has a Content-Encoding header, or has neither a
+// Content-Type header nor any body. Otherwise the Content-Length and
+// Accept-Ranges headers are removed, a strong ETag is made weak, and
+// the Content-Type, if not set, is detected from the uncompressed
+// body as by the ResponseWriter.
+//
+// GzipHandler adds "Accept-Encoding" to the Vary header of all
+// responses. The ResponseWriter passed to h supports Flush, which
+// flushes the compressed data written so far, and may be used with
+// a ResponseController.
+func GzipHandler(h Handler) Handler {
+	return HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == "HEAD" || !acceptsGzip(r.Header) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{rw: w}
+		// If h panics, the gzip stream is left unterminated so
+		// the client cannot mistake the truncated body for a
+		// complete one.
+		defer gw.release()
+		h.ServeHTTP(gw, r)
+		gw.close()
+	})
+}
+
+// acceptsGzip reports whether the Accept-Encoding values in h
+// allow a gzip response.
+func acceptsGzip(h Header) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, v := range h["Accept-Encoding"] {
+		foreachHeaderElement(v, func(elem string) {
+			coding, q := elem, 1.0
+			if i := strings.IndexByte(elem, ';'); i >= 0 {
+				coding = textproto.TrimString(elem[:i])
+				q = qvalue(elem[i+1:])
+			}
+			switch strings.ToLower(coding) {
+			case "gzip", "x-gzip":
+				gzipQ = q
+			case "*":
+				anyQ = q
+			}
+		})
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
+
+// qvalue returns the value of the "q" parameter in params, the
+// parameters of an element of an Accept-Encoding header, or 1 if
+// there is none. It returns 0 for a malformed value.
+func qvalue(params string) float64 {
+	for _, p := range strings.Split(params, ";") {
+		p = textproto.TrimString(p)
+		if len(p) < 2 || p[0] != 'q' && p[0] != 'Q' || p[1] != '=' {
+			continue
+		}
+		q, err := strconv.ParseFloat(p[2:], 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+var gzipWriterPool sync.Pool // of *gzip.Writer
+
+// gzipResponseWriter is the ResponseWriter passed to the Handler of
+// a GzipHandler. It decides whether to compress the response when the
+// Handler first writes to it, flushes it, or returns.
+type gzipResponseWriter struct {
+	rw          ResponseWriter
+	code        int          // status passed to WriteHeader, or 0
+	wroteHeader bool         // whether rw.WriteHeader has been called
+	zw          *gzip.Writer // non-nil if compressing
+}
+
+func (w *gzipResponseWriter) Header() Header {
+	return w.rw.Header()
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if code >= 100 && code <= 199 && code != StatusSwitchingProtocols {
+		// Informational responses are sent right away.
+		w.rw.WriteHeader(code)
+		return
+	}
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.writeHeader(p)
+	}
+	if w.zw == nil {
+		return w.rw.Write(p)
+	}
+	return w.zw.Write(p)
+}
+
+// Flush implements the Flusher interface.
+func (w *gzipResponseWriter) Flush() {
+	w.FlushError()
+}
+
+// FlushError is like Flush, but returns any error flushing the
+// response, for use by ResponseController.
+func (w *gzipResponseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.writeHeader(nil)
+	}
+	if w.zw != nil {
+		if err := w.zw.Flush(); err != nil {
+			return err
+		}
+	}
+	return NewResponseController(w.rw).Flush()
+}
+
+// Unwrap returns the ResponseWriter passed to the GzipHandler,
+// for use by ResponseController.
+func (w *gzipResponseWriter) Unwrap() ResponseWriter {
+	return w.rw
+}
+
+// writeHeader decides whether to compress the response, given the
+// first data written to it, and writes the header.
+func (w *gzipResponseWriter) writeHeader(p []byte) {
+	w.wroteHeader = true
+	code := w.code
+	if code == 0 {
+		code = StatusOK
+	}
+	h := w.rw.Header()
+	_, haveType := h["Content-Type"]
+	if bodyAllowedForStatus(code) && code != StatusPartialContent &&
+		h.get("Content-Encoding") == "" && (haveType || len(p) > 0) {
+		if !haveType {
+			h.Set("Content-Type", DetectContentType(p))
+		}
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("Etag", "W/"+etag)
+		}
+		h.Set("Content-Encoding", "gzip")
+
+		if zw, ok := gzipWriterPool.Get().(*gzip.Writer); ok {
+			zw.Reset(w.rw)
+			w.zw = zw
+		} else {
+			w.zw = gzip.NewWriter(w.rw)
+		}
+	}
+	w.rw.WriteHeader(code)
+}
+
+// close finishes the response after the Handler returns.
+func (w *gzipResponseWriter) close() {
+	if !w.wroteHeader {
+		if w.code == 0 {
+			// Nothing was written; leave the response
+			// to the server.
+			return
+		}
+		w.writeHeader(nil)
+	}
+	if w.zw != nil {
+		w.zw.Close()
+		w.release()
+	}
+}
+
+// release returns the gzip.Writer, if any, to gzipWriterPool
+// without finishing the stream.
+func (w *gzipResponseWriter) release() {
+	if w.zw != nil {
+		gzipWriterPool.Put(w.zw)
+		w.zw = nil
+	}
+}
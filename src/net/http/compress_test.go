@@ -0,0 +1,292 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	. "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerMaxRequestBodyBytes_h1(t *testing.T) { testServerMaxRequestBodyBytes(t, h1Mode) }
+func TestServerMaxRequestBodyBytes_h2(t *testing.T) { testServerMaxRequestBodyBytes(t, h2Mode) }
+func testServerMaxRequestBodyBytes(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%d %v", len(body), err)
+	}), func(ts *httptest.Server) {
+		ts.Config.MaxRequestBodyBytes = 10
+	})
+	defer cst.close()
+
+	for _, tc := range []struct {
+		body string
+		want string
+	}{
+		{"", "0 <nil>"},
+		{"0123456789", "10 <nil>"},
+		{"0123456789a", "10 http: request body too large"},
+	} {
+		res, err := cst.c.Post(cst.ts.URL, "text/plain", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("POST with %d byte body: handler read %q, want %q", len(tc.body), got, tc.want)
+		}
+	}
+}
+
+func TestServerDecompressRequestBody_h1(t *testing.T) { testServerDecompressRequestBody(t, h1Mode) }
+func TestServerDecompressRequestBody_h2(t *testing.T) { testServerDecompressRequestBody(t, h2Mode) }
+func testServerDecompressRequestBody(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%q %v %q %d", body, err, r.Header.Get("Content-Encoding"), r.ContentLength)
+	}), func(ts *httptest.Server) {
+		ts.Config.DecompressRequestBody = true
+		ts.Config.MaxRequestBodyBytes = 100
+	})
+	defer cst.close()
+
+	const msg = "hello, hello, hello"
+	var gzipped, deflated bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	io.WriteString(zw, msg)
+	zw.Close()
+	fw := zlib.NewWriter(&deflated)
+	io.WriteString(fw, msg)
+	fw.Close()
+	var bomb bytes.Buffer
+	zw = gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 1000))
+	zw.Close()
+
+	for _, tc := range []struct {
+		encoding string
+		body     []byte
+		want     string
+	}{
+		{"", []byte(msg), fmt.Sprintf("%q <nil> \"\" %d", msg, len(msg))},
+		{"gzip", gzipped.Bytes(), fmt.Sprintf("%q <nil> \"\" -1", msg)},
+		{"GZIP", gzipped.Bytes(), fmt.Sprintf("%q <nil> \"\" -1", msg)},
+		{"deflate", deflated.Bytes(), fmt.Sprintf("%q <nil> \"\" -1", msg)},
+		{"br", []byte(msg), fmt.Sprintf("%q <nil> \"br\" %d", msg, len(msg))},
+		{"gzip", []byte(msg), `"" gzip: invalid header "" -1`},
+		{"gzip", bomb.Bytes(), fmt.Sprintf("%q http: request body too large \"\" -1", make([]byte, 100))},
+	} {
+		req, _ := NewRequest("POST", cst.ts.URL, bytes.NewReader(tc.body))
+		if tc.encoding != "" {
+			req.Header.Set("Content-Encoding", tc.encoding)
+		}
+		res, err := cst.c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("POST with Content-Encoding %q: handler got %s, want %s", tc.encoding, got, tc.want)
+		}
+	}
+}
+
+func TestGzipHandler_h1(t *testing.T) { testGzipHandler(t, h1Mode) }
+func TestGzipHandler_h2(t *testing.T) { testGzipHandler(t, h2Mode) }
+func testGzipHandler(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	const page = "<html><body>" + "hello, world! " + "</body></html>"
+	cst := newClientServerTest(t, h2, GzipHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Length", fmt.Sprint(len(page)))
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Etag", `"v1"`)
+			io.WriteString(w, page)
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "identity")
+			io.WriteString(w, page)
+		case "/notmodified":
+			w.WriteHeader(StatusNotModified)
+		case "/empty":
+		case "/status":
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(StatusTeapot)
+		}
+	})), func(tr *Transport) {
+		tr.DisableCompression = true
+	})
+	defer cst.close()
+
+	for _, tc := range []struct {
+		path           string
+		acceptEncoding string
+		gzip           bool   // whether the response should be gzipped
+		status         int    // want status code
+		body           string // want uncompressed body
+		etag           string // want Etag
+	}{
+		{"/page", "gzip", true, 200, page, `W/"v1"`},
+		{"/page", "deflate, gzip;q=0.5", true, 200, page, `W/"v1"`},
+		{"/page", "*", true, 200, page, `W/"v1"`},
+		{"/page", "deflate", false, 200, page, `"v1"`},
+		{"/page", "gzip;q=0", false, 200, page, `"v1"`},
+		{"/page", "gzip;q=0, *", false, 200, page, `"v1"`},
+		{"/page", "", false, 200, page, `"v1"`},
+		{"/encoded", "gzip", false, 200, page, ""},
+		{"/notmodified", "gzip", false, 304, "", ""},
+		{"/empty", "gzip", false, 200, "", ""},
+		{"/status", "gzip", true, StatusTeapot, "", ""},
+	} {
+		req, _ := NewRequest("GET", cst.ts.URL+tc.path, nil)
+		if tc.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		}
+		res, err := cst.c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := fmt.Sprintf("GET %s with Accept-Encoding %q", tc.path, tc.acceptEncoding)
+		if res.StatusCode != tc.status {
+			t.Errorf("%s: status = %d, want %d", name, res.StatusCode, tc.status)
+		}
+		if got := res.Header.Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%s: Vary = %q, want Accept-Encoding", name, got)
+		}
+		if got := res.Header.Get("Etag"); got != tc.etag {
+			t.Errorf("%s: Etag = %q, want %q", name, got, tc.etag)
+		}
+		if gzipped := res.Header.Get("Content-Encoding") == "gzip"; gzipped != tc.gzip {
+			t.Errorf("%s: Content-Encoding = %q, want gzip = %v", name, res.Header.Get("Content-Encoding"), tc.gzip)
+			continue
+		}
+		if tc.gzip {
+			if got := res.Header.Get("Accept-Ranges"); got != "" {
+				t.Errorf("%s: Accept-Ranges = %q, want none", name, got)
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			body, err = ioutil.ReadAll(zr)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+		}
+		if string(body) != tc.body {
+			t.Errorf("%s: body = %q, want %q", name, body, tc.body)
+		}
+		if tc.path == "/page" {
+			if got, want := res.Header.Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+				t.Errorf("%s: Content-Type = %q, want %q", name, got, want)
+			}
+		}
+	}
+}
+
+func TestGzipHandlerFlush_h1(t *testing.T) { testGzipHandlerFlush(t, h1Mode) }
+func TestGzipHandlerFlush_h2(t *testing.T) { testGzipHandlerFlush(t, h2Mode) }
+func testGzipHandlerFlush(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	next := make(chan bool)
+	cst := newClientServerTest(t, h2, GzipHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "event %d\n", i)
+			if err := NewResponseController(w).Flush(); err != nil {
+				t.Error(err)
+				return
+			}
+			<-next
+		}
+	})))
+	defer cst.close()
+
+	req, _ := NewRequest("GET", cst.ts.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ce := res.Header.Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		want := fmt.Sprintf("event %d\n", i)
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(zr, got); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("read %q, want %q", got, want)
+		}
+		next <- true
+	}
+	if rest, err := ioutil.ReadAll(zr); err != nil || len(rest) != 0 {
+		t.Errorf("read %q, %v at end of stream; want EOF", rest, err)
+	}
+}
+
+func TestGzipHandlerPanic(t *testing.T) {
+	h := GzipHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "partial response")
+		panic(ErrAbortHandler)
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	func() {
+		defer func() {
+			if e := recover(); e != ErrAbortHandler {
+				t.Errorf("recovered %v, want ErrAbortHandler", e)
+			}
+		}()
+		h.ServeHTTP(rec, req)
+	}()
+	if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", ce)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadAll(zr); err == nil {
+		t.Errorf("read complete gzip stream %q after handler panic; want truncated stream", got)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build ignore
+
+// This program generates table.go from the Public Suffix List
+// published at https://publicsuffix.org/.
+//
+// usage:
+//
+// go run gen.go -output table.go
+// go run gen.go -file /usr/share/publicsuffix/public_suffix_list.dat -version 2018-06-01
+//
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang_org/x/net/idna"
+)
+
+const pslURL = "https://publicsuffix.org/list/public_suffix_list.dat"
+
+var (
+	filename = flag.String("output", "table.go", "output file name")
+	file     = flag.String("file", "", "read the list from this file instead of fetching "+pslURL)
+	version  = flag.String("version", "", "version of the list recorded in the table; defaults to the list's modification date")
+)
+
+func main() {
+	flag.Parse()
+
+	var (
+		r       io.Reader
+		modTime time.Time
+	)
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, modTime = f, fi.ModTime()
+	} else {
+		res, err := http.Get(pslURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			log.Fatalf("GET %s: %s", pslURL, res.Status)
+		}
+		modTime, err = http.ParseTime(res.Header.Get("Last-Modified"))
+		if err != nil {
+			modTime = time.Now()
+		}
+		r = res.Body
+	}
+	if *version == "" {
+		*version = modTime.UTC().Format("2006-01-02")
+	}
+
+	rules, err := parse(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Copyright 2018 The Go Authors. All rights reserved.\n")
+	fmt.Fprintf(buf, "// Use of this source code is governed by a BSD-style\n")
+	fmt.Fprintf(buf, "// license that can be found in the LICENSE file.\n\n")
+	fmt.Fprintf(buf, "// Code generated by gen.go; DO NOT EDIT.\n")
+	fmt.Fprintf(buf, "// Based on the Public Suffix List from %s\n\n", pslURL)
+	fmt.Fprintf(buf, "package cookiejar\n\n")
+	fmt.Fprintf(buf, "// publicSuffixVersion is the version of the Public Suffix List\n")
+	fmt.Fprintf(buf, "// from which publicSuffixRules was generated.\n")
+	fmt.Fprintf(buf, "const publicSuffixVersion = %q\n\n", *version)
+	fmt.Fprintf(buf, "// publicSuffixRules holds the %d rules of the Public Suffix List,\n", len(rules))
+	fmt.Fprintf(buf, "// in ASCII (Punycode) form, sorted.\n")
+	fmt.Fprintf(buf, "var publicSuffixRules = [...]string{\n")
+	for _, rule := range rules {
+		fmt.Fprintf(buf, "\t%q,\n", rule)
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	data, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(*filename, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parse reads the rules of the list from r, including both the ICANN
+// and the private domains, and returns them sorted and in ASCII form.
+func parse(r io.Reader) ([]string, error) {
+	seen := make(map[string]bool)
+	var rules []string
+	s := bufio.NewScanner(r)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		// Rules end at the first whitespace.
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+		rule, err := toASCII(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %q: %v", lineno, line, err)
+		}
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules found")
+	}
+	sort.Strings(rules)
+	return rules, nil
+}
+
+// toASCII converts a rule to lower case Punycode, preserving
+// its exception ("!") or wildcard ("*.") prefix.
+func toASCII(rule string) (string, error) {
+	prefix := ""
+	switch {
+	case strings.HasPrefix(rule, "!"):
+		prefix, rule = "!", rule[1:]
+	case strings.HasPrefix(rule, "*."):
+		prefix, rule = "*.", rule[2:]
+	}
+	if strings.Contains(rule, "*") {
+		return "", fmt.Errorf("unsupported wildcard")
+	}
+	ascii, err := idna.ToASCII(rule)
+	if err != nil {
+		return "", err
+	}
+	return prefix + strings.ToLower(ascii), nil
+}
@@ -3,6 +3,9 @@
 // license that can be found in the LICENSE file.
 
 // Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar.
+//
+// A Jar's cookies may be saved to and loaded from files, either in JSON
+// or in the Netscape cookie file format.
 package cookiejar
 
 import (
@@ -29,7 +32,9 @@ import (
 // testing but it is not secure: it means that the HTTP server for foo.com can
 // set a cookie for bar.com.
 //
-// A public suffix list implementation is in the package
+// DefaultPublicSuffixList is an implementation generated from the
+// list at https://publicsuffix.org/. Another, which may be updated
+// independently of Go releases, is in the package
 // golang.org/x/net/publicsuffix.
 type PublicSuffixList interface {
 	// PublicSuffix returns the public suffix of domain.
@@ -50,6 +55,8 @@ type Options struct {
 	// PublicSuffixList is the public suffix list that determines whether
 	// an HTTP server can set a cookie for a domain.
 	//
+	// Most users should use DefaultPublicSuffixList.
+	//
 	// A nil value is valid and may be useful for testing but it is not
 	// secure: it means that the HTTP server for foo.co.uk can set a cookie
 	// for bar.co.uk.
@@ -0,0 +1,312 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Save writes the cookies in the jar to w as a JSON array with one
+// object per cookie. The objects' fields are the cookie's attributes
+// as defined by RFC 6265, section 5.3:
+//
+//	Name, Value, Domain, Path, Secure, HttpOnly, Persistent, HostOnly,
+//	Expires, Creation, LastAccess
+//
+// Save preserves everything Load needs to restore the jar's state.
+// Session (non-persistent) cookies are saved too; callers which
+// want them to end with the program should remove them from the
+// output or start with a new Jar.
+func (j *Jar) Save(w io.Writer) error {
+	return j.save(w, time.Now())
+}
+
+// save is like Save but takes the current time as a parameter.
+func (j *Jar) save(w io.Writer, now time.Time) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(j.allEntries(now))
+}
+
+// Load reads cookies in the format written by Save from r and adds
+// them to the jar, replacing any cookies with the same name, domain
+// and path. Cookies which have expired, and domain cookies which the
+// jar's public suffix list would not allow a server to set, are
+// skipped.
+//
+// If r is malformed, Load returns an error and leaves the jar unchanged.
+func (j *Jar) Load(r io.Reader) error {
+	return j.load(r, time.Now())
+}
+
+// load is like Load but takes the current time as a parameter.
+func (j *Jar) load(r io.Reader, now time.Time) error {
+	var entries []entry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("cookiejar: loading cookies: %v", err)
+	}
+	for i := range entries {
+		e := &entries[i]
+		if err := checkLoadedEntry(e); err != nil {
+			return fmt.Errorf("cookiejar: loading cookie %d: %v", i, err)
+		}
+		if !e.Persistent {
+			e.Expires = endOfTime
+		}
+		if e.Creation.IsZero() {
+			e.Creation = now
+		}
+		if e.LastAccess.IsZero() {
+			e.LastAccess = now
+		}
+	}
+	j.addEntries(entries, now)
+	return nil
+}
+
+// SaveNetscape writes the cookies in the jar to w in the Netscape
+// cookie file format used by curl and wget, among others: one
+// cookie per line, with seven tab-separated fields
+//
+//	domain, include subdomains, path, secure, expires, name, value
+//
+// HttpOnly cookies have their domain prefixed with "#HttpOnly_" and
+// session cookies have an expiry time of 0.
+//
+// The format has no room for the creation and last access times of
+// cookies, which are lost. SaveNetscape returns an error if a
+// cookie's name or value contains a tab or newline.
+func (j *Jar) SaveNetscape(w io.Writer) error {
+	return j.saveNetscape(w, time.Now())
+}
+
+// saveNetscape is like SaveNetscape but takes the current time as a parameter.
+func (j *Jar) saveNetscape(w io.Writer, now time.Time) error {
+	entries := j.allEntries(now)
+	for _, e := range entries {
+		if strings.ContainsAny(e.Name, "\t\r\n") || strings.ContainsAny(e.Value, "\t\r\n") {
+			return fmt.Errorf("cookiejar: cookie %q for %s cannot be saved in Netscape format", e.Name, e.Domain)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# Netscape HTTP Cookie File\n")
+	bw.WriteString("# This file was generated by net/http/cookiejar. Edit at your own risk.\n\n")
+	for _, e := range entries {
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+		if e.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if e.Persistent {
+			expires = e.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain, netscapeBool(!e.HostOnly), e.Path, netscapeBool(e.Secure), expires, e.Name, e.Value)
+	}
+	return bw.Flush()
+}
+
+// LoadNetscape reads cookies in the Netscape cookie file format, as
+// written by SaveNetscape, from r and adds them to the jar, replacing
+// any cookies with the same name, domain and path. Lines starting
+// with "#", other than those starting with "#HttpOnly_", are comments.
+// Cookies which have expired, and domain cookies which the jar's
+// public suffix list would not allow a server to set, are skipped.
+//
+// The cookies' creation times are set to the current time, in the
+// order they appear in r.
+//
+// If r is malformed, LoadNetscape returns an error and leaves the
+// jar unchanged.
+func (j *Jar) LoadNetscape(r io.Reader) error {
+	return j.loadNetscape(r, time.Now())
+}
+
+// loadNetscape is like LoadNetscape but takes the current time as a parameter.
+func (j *Jar) loadNetscape(r io.Reader, now time.Time) error {
+	var entries []entry
+	s := bufio.NewScanner(r)
+	for lineno := 1; s.Scan(); lineno++ {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		e, ok, err := parseNetscapeLine(line)
+		if err != nil {
+			return fmt.Errorf("cookiejar: loading cookies: line %d: %v", lineno, err)
+		}
+		if !ok {
+			continue
+		}
+		if err := checkLoadedEntry(&e); err != nil {
+			return fmt.Errorf("cookiejar: loading cookies: line %d: %v", lineno, err)
+		}
+		e.Creation = now
+		e.LastAccess = now
+		entries = append(entries, e)
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("cookiejar: loading cookies: %v", err)
+	}
+	j.addEntries(entries, now)
+	return nil
+}
+
+// httpOnlyPrefix marks HttpOnly cookies in the Netscape format.
+const httpOnlyPrefix = "#HttpOnly_"
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func parseNetscapeBool(s string) (bool, error) {
+	switch {
+	case strings.EqualFold(s, "TRUE"):
+		return true, nil
+	case strings.EqualFold(s, "FALSE"):
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
+
+// parseNetscapeLine parses a line of a Netscape cookie file. ok is
+// false if the line is blank or a comment.
+func parseNetscapeLine(line string) (e entry, ok bool, err error) {
+	if strings.HasPrefix(line, httpOnlyPrefix) {
+		line = line[len(httpOnlyPrefix):]
+		e.HttpOnly = true
+	} else if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+		return e, false, nil
+	}
+
+	f := strings.Split(line, "\t")
+	if len(f) != 7 {
+		return e, false, fmt.Errorf("got %d tab-separated fields, want 7", len(f))
+	}
+	includeSubdomains, err := parseNetscapeBool(f[1])
+	if err != nil {
+		return e, false, err
+	}
+	e.Secure, err = parseNetscapeBool(f[3])
+	if err != nil {
+		return e, false, err
+	}
+	expires, err := strconv.ParseInt(f[4], 10, 64)
+	if err != nil {
+		return e, false, fmt.Errorf("invalid expiry time %q", f[4])
+	}
+
+	e.Domain = strings.TrimPrefix(f[0], ".")
+	e.HostOnly = !includeSubdomains
+	e.Path = f[2]
+	if expires == 0 {
+		e.Expires = endOfTime
+	} else {
+		e.Expires = time.Unix(expires, 0).UTC()
+		e.Persistent = true
+	}
+	e.Name = f[5]
+	e.Value = f[6]
+	return e, true, nil
+}
+
+var (
+	errLoadedDomain = errors.New("missing or malformed domain")
+	errLoadedPath   = errors.New("missing or malformed path")
+)
+
+// checkLoadedEntry checks that the domain and path of an entry read by
+// Load or LoadNetscape are well formed, and canonicalizes its domain.
+func checkLoadedEntry(e *entry) error {
+	if e.Path == "" || e.Path[0] != '/' {
+		return errLoadedPath
+	}
+	d := e.Domain
+	if d == "" || d[0] == '.' || d[len(d)-1] == '.' || strings.Contains(d, ":") && !isIP(d) {
+		return errLoadedDomain
+	}
+	d, err := toASCII(strings.ToLower(d))
+	if err != nil {
+		return errLoadedDomain
+	}
+	e.Domain = d
+	return nil
+}
+
+// allEntries returns the unexpired entries in the jar, ordered by key
+// and then as Cookies orders them.
+func (j *Jar) allEntries(now time.Time) []entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	keys := make([]string, 0, len(j.entries))
+	for key := range j.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := []entry{}
+	for _, key := range keys {
+		var selected []entry
+		for _, e := range j.entries[key] {
+			if e.Persistent && !e.Expires.After(now) {
+				continue
+			}
+			selected = append(selected, e)
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			s := selected
+			if s[i].Domain != s[j].Domain {
+				return s[i].Domain < s[j].Domain
+			}
+			if !s[i].Creation.Equal(s[j].Creation) {
+				return s[i].Creation.Before(s[j].Creation)
+			}
+			return s[i].seqNum < s[j].seqNum
+		})
+		entries = append(entries, selected...)
+	}
+	return entries
+}
+
+// addEntries adds entries to the jar, in order, skipping those which
+// have expired or whose domain is a public suffix.
+func (j *Jar) addEntries(entries []entry, now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, e := range entries {
+		if e.Persistent && !e.Expires.After(now) {
+			continue
+		}
+		if !e.HostOnly && !isIP(e.Domain) && j.psList != nil {
+			// See RFC 6265 section 5.3 #5, as in domainAndType.
+			if ps := j.psList.PublicSuffix(e.Domain); ps != "" && !hasDotSuffix(e.Domain, ps) {
+				continue
+			}
+		}
+		key := jarKey(e.Domain, j.psList)
+		submap := j.entries[key]
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[key] = submap
+		}
+		e.seqNum = j.nextSeqNum
+		j.nextSeqNum++
+		submap[e.id()] = e
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPersistTestJar returns a jar with an assortment of cookies, set at tNow.
+func newPersistTestJar() *Jar {
+	jar := newTestJar()
+	for _, tc := range []struct {
+		url     string
+		cookies []string
+	}{
+		{"http://www.host.test/dir/file", []string{
+			"session=s1",
+			"persistent=p1; " + expiresIn(3600),
+			"domain=d1; domain=host.test; path=/",
+		}},
+		{"https://www.example.co.uk", []string{
+			"secure=s2; secure; httponly; max-age=7200",
+			"public=ps; domain=co.uk",
+		}},
+		{"http://127.0.0.1:8080", []string{
+			"ip=i1",
+		}},
+	} {
+		var cookies []*http.Cookie
+		for _, cs := range tc.cookies {
+			cookies = append(cookies, (&http.Response{Header: http.Header{"Set-Cookie": {cs}}}).Cookies()...)
+		}
+		jar.setCookies(mustParseURL(tc.url), cookies, tNow)
+	}
+	return jar
+}
+
+// persistTestQueries are the URLs queried to compare jars.
+var persistTestQueries = []string{
+	"http://www.host.test/dir/file",
+	"http://host.test/",
+	"http://other.host.test/dir/",
+	"http://www.example.co.uk",
+	"https://www.example.co.uk",
+	"https://example.co.uk",
+	"http://127.0.0.1",
+}
+
+// jarContents describes the cookies sent from jar to each of the
+// persistTestQueries URLs at time now.
+func jarContents(jar *Jar, now time.Time) string {
+	var buf bytes.Buffer
+	for _, u := range persistTestQueries {
+		fmt.Fprintf(&buf, "%s:", u)
+		for _, c := range jar.cookies(mustParseURL(u), now) {
+			fmt.Fprintf(&buf, " %s=%s", c.Name, c.Value)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+func TestSaveLoad(t *testing.T) {
+	jar := newPersistTestJar()
+	var buf bytes.Buffer
+	if err := jar.save(&buf, tNow); err != nil {
+		t.Fatal(err)
+	}
+	saved := buf.String()
+
+	loaded := newTestJar()
+	if err := loaded.load(strings.NewReader(saved), tNow); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := loaded.save(&buf, tNow); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != saved {
+		t.Errorf("saving loaded jar = \n%s\nwant:\n%s", buf.String(), saved)
+	}
+	later := tNow.Add(time.Minute)
+	if got, want := jarContents(loaded, later), jarContents(jar, later); got != want {
+		t.Errorf("loaded jar contents:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Loading after the persistent cookie expired skips it.
+	loaded = newTestJar()
+	if err := loaded.load(strings.NewReader(saved), tNow.Add(90*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	got := jarContents(loaded, tNow.Add(90*time.Minute))
+	want := `http://www.host.test/dir/file: session=s1 domain=d1
+http://host.test/: domain=d1
+http://other.host.test/dir/: domain=d1
+http://www.example.co.uk:
+https://www.example.co.uk: secure=s2
+https://example.co.uk:
+http://127.0.0.1: ip=i1
+`
+	if got != want {
+		t.Errorf("loaded jar contents after expiry:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSaveNetscape(t *testing.T) {
+	jar := newPersistTestJar()
+	var buf bytes.Buffer
+	if err := jar.saveNetscape(&buf, tNow); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`# Netscape HTTP Cookie File
+# This file was generated by net/http/cookiejar. Edit at your own risk.
+
+127.0.0.1	FALSE	/	FALSE	0	ip	i1
+#HttpOnly_www.example.co.uk	FALSE	/	TRUE	%d	secure	s2
+.host.test	TRUE	/	FALSE	0	domain	d1
+www.host.test	FALSE	/dir	FALSE	0	session	s1
+www.host.test	FALSE	/dir	FALSE	%d	persistent	p1
+`, tNow.Add(2*time.Hour).Unix(), tNow.Add(time.Hour).Unix())
+	if got := buf.String(); got != want {
+		t.Errorf("SaveNetscape wrote:\n%s\nwant:\n%s", got, want)
+	}
+
+	loaded := newTestJar()
+	if err := loaded.loadNetscape(&buf, tNow); err != nil {
+		t.Fatal(err)
+	}
+	later := tNow.Add(time.Minute)
+	if got, want := jarContents(loaded, later), jarContents(jar, later); got != want {
+		t.Errorf("loaded jar contents:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSaveNetscapeInvalidValue(t *testing.T) {
+	jar := newTestJar()
+	jar.setCookies(mustParseURL("http://www.host.test"), []*http.Cookie{{Name: "a", Value: "x\ty"}}, tNow)
+	var buf bytes.Buffer
+	if err := jar.saveNetscape(&buf, tNow); err == nil {
+		t.Errorf("SaveNetscape succeeded, wrote:\n%s", buf.String())
+	}
+}
+
+func TestLoadNetscape(t *testing.T) {
+	// A file in the form written by curl.
+	const file = "# Netscape HTTP Cookie File\r\n" +
+		"# https://curl.haxx.se/docs/http-cookies.html\r\n" +
+		"\r\n" +
+		".example.co.uk\tTRUE\t/\tFALSE\t0\tshared\ts\r\n" +
+		"#HttpOnly_www.example.co.uk\tFALSE\t/app\ttrue\t1357045200\tid\t42\r\n" +
+		"www.example.co.uk\tFALSE\t/\tFALSE\t1357038000\texpired\tx\r\n" +
+		".co.uk\tTRUE\t/\tFALSE\t0\tpublic\tx\r\n" +
+		"WWW.Example.CO.UK\tFALSE\t/\tFALSE\t0\tempty\t\r\n"
+	jar := newTestJar()
+	if err := jar.loadNetscape(strings.NewReader(file), tNow); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		url, want string
+	}{
+		{"http://www.example.co.uk/", "shared=s empty="},
+		{"https://www.example.co.uk/app/", "id=42 shared=s empty="},
+		{"http://example.co.uk/", "shared=s"},
+		{"http://other.co.uk/", ""},
+	} {
+		var s []string
+		for _, c := range jar.cookies(mustParseURL(tc.url), tNow) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		if got := strings.Join(s, " "); got != tc.want {
+			t.Errorf("Cookies(%q) = %q, want %q", tc.url, got, tc.want)
+		}
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	for _, file := range []string{
+		"www.host.test\tFALSE\t/\tFALSE\t0\tname\n",
+		"www.host.test\tFALSE\t/\tFALSE\t0\tname\tvalue\textra\n",
+		"www.host.test\tMAYBE\t/\tFALSE\t0\tname\tvalue\n",
+		"www.host.test\tFALSE\t/\tNO\t0\tname\tvalue\n",
+		"www.host.test\tFALSE\t/\tFALSE\tnever\tname\tvalue\n",
+		"www.host.test\tFALSE\tdir\tFALSE\t0\tname\tvalue\n",
+		"\tFALSE\t/\tFALSE\t0\tname\tvalue\n",
+		"..host.test\tTRUE\t/\tFALSE\t0\tname\tvalue\n",
+		"www.host.test:80\tFALSE\t/\tFALSE\t0\tname\tvalue\n",
+	} {
+		jar := newPersistTestJar()
+		before := jarContents(jar, tNow)
+		good := "www.host.test\tFALSE\t/\tFALSE\t0\tgood\tvalue\n"
+		if err := jar.loadNetscape(strings.NewReader(good+file), tNow); err == nil {
+			t.Errorf("LoadNetscape(%q) succeeded", file)
+		} else if !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("LoadNetscape(%q) = %v, want error for line 2", file, err)
+		}
+		if after := jarContents(jar, tNow); after != before {
+			t.Errorf("LoadNetscape(%q) changed the jar to:\n%s", file, after)
+		}
+	}
+
+	for _, file := range []string{
+		``,
+		`{}`,
+		`[{"Name": "a", "Domain": "www.host.test"}]`,
+		`[{"Name": "a", "Domain": ".host.test", "Path": "/"}]`,
+		`[{"Name": "a", "Domain": "www.host.test", "Path": "/"}, "b"]`,
+	} {
+		jar := newPersistTestJar()
+		before := jarContents(jar, tNow)
+		if err := jar.load(strings.NewReader(file), tNow); err == nil {
+			t.Errorf("Load(%q) succeeded", file)
+		}
+		if after := jarContents(jar, tNow); after != before {
+			t.Errorf("Load(%q) changed the jar to:\n%s", file, after)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+//go:generate go run gen.go -output table.go
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultPublicSuffixList is a PublicSuffixList generated from a copy of
+// the Public Suffix List published at https://publicsuffix.org/, which
+// includes both ICANN and privately registered suffixes.
+//
+// The list changes over time, and the copy is only as recent as the
+// Go release it was built with. Programs needing the latest list
+// should use an implementation that fetches it, such as the package
+// golang.org/x/net/publicsuffix.
+var DefaultPublicSuffixList PublicSuffixList = publicSuffixList{}
+
+// publicSuffixList implements PublicSuffixList using publicSuffixRules.
+type publicSuffixList struct{}
+
+// PublicSuffix returns the public suffix of domain, which must be in
+// lower case ASCII (Punycode) form without leading or trailing dots,
+// as the Jar provides it. Domains matching no rule are under the
+// default rule "*", and their public suffix is their last label.
+func (publicSuffixList) PublicSuffix(domain string) string {
+	// Try each suffix of domain, longest first, so that the
+	// first rule to match is the prevailing one.
+	for s := domain; s != ""; {
+		parent := ""
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			parent = s[i+1:]
+		}
+		if hasRule("!" + s) {
+			// An exception rule: its public suffix is the
+			// rule with its leftmost label removed.
+			return parent
+		}
+		if hasRule(s) || parent != "" && hasRule("*."+parent) {
+			return s
+		}
+		s = parent
+	}
+	return domain[strings.LastIndex(domain, ".")+1:]
+}
+
+func (publicSuffixList) String() string {
+	return "publicsuffix.org's public_suffix_list.dat, version " + publicSuffixVersion
+}
+
+// hasRule reports whether rule is in publicSuffixRules.
+func hasRule(rule string) bool {
+	rules := publicSuffixRules[:]
+	i := sort.SearchStrings(rules, rule)
+	return i < len(rules) && rules[i] == rule
+}
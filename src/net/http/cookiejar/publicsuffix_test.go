@@ -0,0 +1,106 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"sort"
+	"testing"
+)
+
+// publicSuffixTests are adapted from the test data published with
+// the Public Suffix List.
+var publicSuffixTests = map[string]string{
+	"":                       "",
+	"com":                    "com",
+	"example.com":            "com",
+	"www.example.com":        "com",
+	"example":                "example",
+	"b.example.example":      "example",
+	"biz":                    "biz",
+	"a.b.example.biz":        "biz",
+	"uk.com":                 "uk.com",
+	"example.uk.com":         "uk.com",
+	"b.example.uk.com":       "uk.com",
+	"ac":                     "ac",
+	"mil.ac":                 "mil.ac",
+	"b.c.mil.ac":             "mil.ac",
+	"co.uk":                  "co.uk",
+	"www.example.co.uk":      "co.uk",
+	"test.ak.us":             "ak.us",
+	"www.test.k12.ak.us":     "k12.ak.us",
+	"c.kobe.jp":              "c.kobe.jp",
+	"b.c.kobe.jp":            "c.kobe.jp",
+	"city.kobe.jp":           "kobe.jp",
+	"www.city.kobe.jp":       "kobe.jp",
+	"test.ck":                "test.ck",
+	"b.test.ck":              "test.ck",
+	"www.ck":                 "ck",
+	"www.www.ck":             "ck",
+	"foo.github.io":          "github.io",
+	"xn--85x722f.com.cn":     "com.cn",
+	"www.xn--55qx5d.cn":      "xn--55qx5d.cn",
+	"shishi.xn--fiqs8s":      "xn--fiqs8s",
+	"www.shishi.xn--fiqs8s":  "xn--fiqs8s",
+	"example.unknowntld":     "unknowntld",
+	"www.example.unknowntld": "unknowntld",
+}
+
+func TestPublicSuffix(t *testing.T) {
+	for domain, want := range publicSuffixTests {
+		if got := DefaultPublicSuffixList.PublicSuffix(domain); got != want {
+			t.Errorf("PublicSuffix(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestPublicSuffixRulesSorted(t *testing.T) {
+	if !sort.StringsAreSorted(publicSuffixRules[:]) {
+		t.Fatal("publicSuffixRules is not sorted")
+	}
+}
+
+// defaultPSLTests are run against a Jar using DefaultPublicSuffixList.
+var defaultPSLTests = [...]jarTest{
+	{
+		"Domain cookie for a registered domain.",
+		"http://www.example.co.uk",
+		[]string{"a=1; domain=example.co.uk"},
+		"a=1",
+		[]query{
+			{"http://example.co.uk", "a=1"},
+			{"http://other.co.uk", ""},
+		},
+	},
+	{
+		"Domain cookie for a public suffix is rejected.",
+		"http://www.example.co.uk",
+		[]string{"a=1; domain=co.uk"},
+		"",
+		[]query{
+			{"http://www.example.co.uk", ""},
+			{"http://other.co.uk", ""},
+		},
+	},
+	{
+		"Domain cookie for a private public suffix is rejected.",
+		"https://foo.github.io",
+		[]string{"a=1; domain=github.io", "b=2; domain=foo.github.io"},
+		"b=2",
+		[]query{
+			{"https://foo.github.io", "b=2"},
+			{"https://bar.github.io", ""},
+		},
+	},
+}
+
+func TestDefaultPublicSuffixListJar(t *testing.T) {
+	for _, test := range defaultPSLTests {
+		jar, err := New(&Options{PublicSuffixList: DefaultPublicSuffixList})
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.run(t, jar)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -357,6 +358,47 @@ func TestFileServerSortsNames(t *testing.T) {
 	}
 }
 
+func TestFileServerFS(t *testing.T) {
+	defer afterTest(t)
+	fsys := fstest.MapFS{
+		"index.txt":     {Data: []byte("root")},
+		"sub/b.txt":     {Data: []byte("b")},
+		"sub/a.txt":     {Data: []byte("a")},
+		"sub/dir/c.txt": {Data: []byte("c")},
+	}
+	ts := httptest.NewServer(FileServer(FS(fsys)))
+	defer ts.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		res, err := Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Get %s: %v", path, err)
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("ReadAll %s: %v", path, err)
+		}
+		return res.StatusCode, string(b)
+	}
+
+	if code, body := get("/index.txt"); code != StatusOK || body != "root" {
+		t.Errorf("GET /index.txt = %d, %q; want 200, %q", code, body, "root")
+	}
+	if code, body := get("/sub/dir/c.txt"); code != StatusOK || body != "c" {
+		t.Errorf("GET /sub/dir/c.txt = %d, %q; want 200, %q", code, body, "c")
+	}
+	if code, body := get("/sub/"); code != StatusOK || !strings.Contains(body, "<a href=\"a.txt\">a.txt</a>\n<a href=\"b.txt\">b.txt</a>\n<a href=\"dir/\">dir/</a>") {
+		t.Errorf("GET /sub/ = %d, %q; want sorted directory listing", code, body)
+	}
+	for _, path := range []string{"/missing.txt", "/index.txt/not-a-file", "/sub/missing/c.txt"} {
+		if code, _ := get(path); code != StatusNotFound {
+			t.Errorf("GET %s = %d; want 404", path, code)
+		}
+	}
+}
+
 func mustRemoveAll(dir string) {
 	err := os.RemoveAll(dir)
 	if err != nil {
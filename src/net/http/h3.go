@@ -0,0 +1,685 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 (RFC 9114) framing and the connection state shared by
+// the client and server. The QUIC transport lives in
+// net/http/internal/quic.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http/internal/quic"
+	"strings"
+	"sync"
+
+	"golang_org/x/net/http/httpguts"
+)
+
+// http3NextProto is the ALPN protocol ID for HTTP/3.
+const http3NextProto = "h3"
+
+// HTTP/3 frame types.
+// https://www.rfc-editor.org/rfc/rfc9114#section-7.2
+const (
+	http3FrameData        = 0x00
+	http3FrameHeaders     = 0x01
+	http3FrameCancelPush  = 0x03
+	http3FrameSettings    = 0x04
+	http3FramePushPromise = 0x05
+	http3FrameGoaway      = 0x07
+	http3FrameMaxPushID   = 0x0d
+)
+
+// HTTP/3 unidirectional stream types.
+// https://www.rfc-editor.org/rfc/rfc9114#section-6.2
+const (
+	http3StreamControl      = 0x00
+	http3StreamPush         = 0x01
+	http3StreamQPACKEncoder = 0x02
+	http3StreamQPACKDecoder = 0x03
+)
+
+// HTTP/3 settings.
+// https://www.rfc-editor.org/rfc/rfc9114#section-7.2.4.1
+const (
+	http3SettingQPACKMaxTableCapacity = 0x01
+	http3SettingMaxFieldSectionSize   = 0x06
+	http3SettingQPACKBlockedStreams   = 0x07
+)
+
+// An http3ErrCode is an HTTP/3 or QPACK error code.
+// https://www.rfc-editor.org/rfc/rfc9114#section-8.1
+type http3ErrCode uint64
+
+const (
+	http3ErrNo                       = http3ErrCode(0x100)
+	http3ErrGeneralProtocol          = http3ErrCode(0x101)
+	http3ErrInternal                 = http3ErrCode(0x102)
+	http3ErrStreamCreation           = http3ErrCode(0x103)
+	http3ErrClosedCriticalStream     = http3ErrCode(0x104)
+	http3ErrFrameUnexpected          = http3ErrCode(0x105)
+	http3ErrFrame                    = http3ErrCode(0x106)
+	http3ErrExcessiveLoad            = http3ErrCode(0x107)
+	http3ErrID                       = http3ErrCode(0x108)
+	http3ErrSettings                 = http3ErrCode(0x109)
+	http3ErrMissingSettings          = http3ErrCode(0x10a)
+	http3ErrRequestRejected          = http3ErrCode(0x10b)
+	http3ErrRequestCancelled         = http3ErrCode(0x10c)
+	http3ErrRequestIncomplete        = http3ErrCode(0x10d)
+	http3ErrMessage                  = http3ErrCode(0x10e)
+	http3ErrConnect                  = http3ErrCode(0x10f)
+	http3ErrVersionFallback          = http3ErrCode(0x110)
+	http3ErrQPACKDecompressionFailed = http3ErrCode(0x200)
+)
+
+var http3ErrCodeName = map[http3ErrCode]string{
+	http3ErrNo:                       "H3_NO_ERROR",
+	http3ErrGeneralProtocol:          "H3_GENERAL_PROTOCOL_ERROR",
+	http3ErrInternal:                 "H3_INTERNAL_ERROR",
+	http3ErrStreamCreation:           "H3_STREAM_CREATION_ERROR",
+	http3ErrClosedCriticalStream:     "H3_CLOSED_CRITICAL_STREAM",
+	http3ErrFrameUnexpected:          "H3_FRAME_UNEXPECTED",
+	http3ErrFrame:                    "H3_FRAME_ERROR",
+	http3ErrExcessiveLoad:            "H3_EXCESSIVE_LOAD",
+	http3ErrID:                       "H3_ID_ERROR",
+	http3ErrSettings:                 "H3_SETTINGS_ERROR",
+	http3ErrMissingSettings:          "H3_MISSING_SETTINGS",
+	http3ErrRequestRejected:          "H3_REQUEST_REJECTED",
+	http3ErrRequestCancelled:         "H3_REQUEST_CANCELLED",
+	http3ErrRequestIncomplete:        "H3_REQUEST_INCOMPLETE",
+	http3ErrMessage:                  "H3_MESSAGE_ERROR",
+	http3ErrConnect:                  "H3_CONNECT_ERROR",
+	http3ErrVersionFallback:          "H3_VERSION_FALLBACK",
+	http3ErrQPACKDecompressionFailed: "QPACK_DECOMPRESSION_FAILED",
+}
+
+func (e http3ErrCode) String() string {
+	if s, ok := http3ErrCodeName[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown error code 0x%x", uint64(e))
+}
+
+func (e http3ErrCode) Error() string {
+	return "http3: " + e.String()
+}
+
+// An http3ResetError is returned when the peer resets a stream
+// or asks us to stop sending on it.
+type http3ResetError struct {
+	code http3ErrCode
+}
+
+func (e http3ResetError) Error() string {
+	return "http3: stream reset by peer: " + e.code.String()
+}
+
+// http3StreamError converts an error from a QUIC stream into an
+// http3ResetError, if the peer reset the stream.
+func http3StreamError(err error) error {
+	var code quic.StreamErrorCode
+	if errors.As(err, &code) {
+		return http3ResetError{http3ErrCode(code)}
+	}
+	return err
+}
+
+var errHTTP3HeaderTooLarge = errors.New("http3: header too large")
+
+func http3AppendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v < 1<<30:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	default:
+		return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+			byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+}
+
+// http3ReadVarint reads a QUIC variable-length integer.
+// It returns io.EOF only if no bytes were read.
+func http3ReadVarint(r io.ByteReader) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	n := 1 << (b >> 6)
+	v := uint64(b & 0x3f)
+	for i := 1; i < n; i++ {
+		b, err = r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func http3AppendFrameHeader(b []byte, ftype uint64, size int) []byte {
+	b = http3AppendVarint(b, ftype)
+	return http3AppendVarint(b, uint64(size))
+}
+
+// http3IsReservedFrame reports whether ftype is an HTTP/2 frame type
+// with no HTTP/3 equivalent. Receiving one is a connection error.
+// https://www.rfc-editor.org/rfc/rfc9114#section-7.2.8
+func http3IsReservedFrame(ftype uint64) bool {
+	switch ftype {
+	case 0x02, 0x06, 0x08, 0x09:
+		return true
+	}
+	return false
+}
+
+// http3FrameReader reads HTTP/3 frames from a stream.
+type http3FrameReader struct {
+	br     *bufio.Reader
+	remain int64 // unread bytes in the current frame's payload
+}
+
+func newHTTP3FrameReader(r io.Reader) *http3FrameReader {
+	return &http3FrameReader{br: bufio.NewReader(r)}
+}
+
+// readFrameHeader discards any unread payload of the current frame
+// and reads the header of the next one, returning its type.
+// It returns io.EOF if the stream ends cleanly between frames.
+func (fr *http3FrameReader) readFrameHeader() (ftype uint64, err error) {
+	if fr.remain > 0 {
+		if _, err := io.CopyN(ioutil.Discard, fr.br, fr.remain); err != nil {
+			return 0, fr.payloadErr(err)
+		}
+		fr.remain = 0
+	}
+	ftype, err = http3ReadVarint(fr.br)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = http3ErrFrame
+		}
+		return 0, http3StreamError(err)
+	}
+	size, err := http3ReadVarint(fr.br)
+	if err != nil {
+		return 0, fr.payloadErr(err)
+	}
+	fr.remain = int64(size)
+	return ftype, nil
+}
+
+// readPayload reads the rest of the current frame's payload.
+// If the payload is longer than max, readPayload returns
+// errHTTP3HeaderTooLarge without reading it.
+func (fr *http3FrameReader) readPayload(max int64) ([]byte, error) {
+	if fr.remain > max {
+		return nil, errHTTP3HeaderTooLarge
+	}
+	b := make([]byte, fr.remain)
+	if _, err := io.ReadFull(fr.br, b); err != nil {
+		return nil, fr.payloadErr(err)
+	}
+	fr.remain = 0
+	return b, nil
+}
+
+// Read reads from the payload of the current frame.
+// It returns io.EOF at the end of the payload.
+func (fr *http3FrameReader) Read(p []byte) (int, error) {
+	if fr.remain == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > fr.remain {
+		p = p[:fr.remain]
+	}
+	n, err := fr.br.Read(p)
+	fr.remain -= int64(n)
+	if err != nil {
+		return n, fr.payloadErr(err)
+	}
+	return n, nil
+}
+
+// payloadErr converts an error reading a frame into an HTTP/3 error.
+// A stream which ends in the middle of a frame is a frame error.
+func (fr *http3FrameReader) payloadErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return http3ErrFrame
+	}
+	return http3StreamError(err)
+}
+
+// http3Conn is the connection state shared by HTTP/3 clients and servers.
+type http3Conn struct {
+	qconn    *quic.Conn
+	isServer bool
+
+	// maxHeaderBytes is the limit on the size of field sections
+	// we are willing to receive, advertised to the peer.
+	maxHeaderBytes int64
+
+	mu                                 sync.Mutex
+	sawControl, sawEncoder, sawDecoder bool
+}
+
+// abort closes the connection with an HTTP/3 error.
+func (c *http3Conn) abort(code http3ErrCode) {
+	c.qconn.Abort(&quic.ApplicationError{Code: uint64(code), Reason: code.String()})
+}
+
+// abortOnError closes the connection if err is an HTTP/3 error code
+// detected locally.
+func (c *http3Conn) abortOnError(err error) {
+	if code, ok := err.(http3ErrCode); ok {
+		c.abort(code)
+	}
+}
+
+// streamError handles a protocol error detected locally on a
+// request stream. Malformed messages are stream errors; all other
+// errors close the connection.
+// https://www.rfc-editor.org/rfc/rfc9114#section-4.1.2
+func (c *http3Conn) streamError(st *quic.Stream, err error) {
+	if err == http3ErrMessage {
+		st.StopSending(uint64(http3ErrMessage))
+		st.Reset(uint64(http3ErrMessage))
+		return
+	}
+	c.abortOnError(err)
+}
+
+// openControlStream opens our control stream and sends our settings.
+func (c *http3Conn) openControlStream(ctx context.Context) (*quic.Stream, error) {
+	st, err := c.qconn.NewSendOnlyStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Our dynamic table capacity and blocked streams limit are
+	// both zero, which is the default.
+	var settings []byte
+	settings = http3AppendVarint(settings, http3SettingMaxFieldSectionSize)
+	settings = http3AppendVarint(settings, uint64(c.maxHeaderBytes))
+	b := http3AppendVarint(nil, http3StreamControl)
+	b = http3AppendFrameHeader(b, http3FrameSettings, len(settings))
+	b = append(b, settings...)
+	if _, err := st.Write(b); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// handleUniStream reads a unidirectional stream opened by the peer.
+// It calls onGoaway with the identifier in each GOAWAY frame received
+// on the peer's control stream.
+func (c *http3Conn) handleUniStream(st *quic.Stream, onGoaway func(id uint64)) {
+	br := bufio.NewReader(st)
+	stype, err := http3ReadVarint(br)
+	if err != nil {
+		st.CloseRead()
+		return
+	}
+	var seen *bool
+	switch stype {
+	case http3StreamControl:
+		seen = &c.sawControl
+	case http3StreamQPACKEncoder:
+		seen = &c.sawEncoder
+	case http3StreamQPACKDecoder:
+		seen = &c.sawDecoder
+	case http3StreamPush:
+		if c.isServer {
+			// Only servers push.
+			c.abort(http3ErrStreamCreation)
+		} else {
+			// We never send MAX_PUSH_ID, so the server may not push.
+			c.abort(http3ErrID)
+		}
+		return
+	default:
+		// Unknown stream types are ignored.
+		// https://www.rfc-editor.org/rfc/rfc9114#section-6.2-7
+		st.StopSending(uint64(http3ErrStreamCreation))
+		return
+	}
+	c.mu.Lock()
+	dup := *seen
+	*seen = true
+	c.mu.Unlock()
+	if dup {
+		c.abort(http3ErrStreamCreation)
+		return
+	}
+	if stype != http3StreamControl {
+		// With a dynamic table capacity of zero, the QPACK streams
+		// carry nothing we need to act on.
+		io.Copy(ioutil.Discard, br)
+		return
+	}
+	err = c.readControlStream(&http3FrameReader{br: br}, onGoaway)
+	if err == io.EOF {
+		err = http3ErrClosedCriticalStream
+	}
+	c.abortOnError(err)
+}
+
+func (c *http3Conn) readControlStream(fr *http3FrameReader, onGoaway func(id uint64)) error {
+	ftype, err := fr.readFrameHeader()
+	if err != nil {
+		return err
+	}
+	if ftype != http3FrameSettings {
+		return http3ErrMissingSettings
+	}
+	settings, err := fr.readPayload(1 << 16)
+	if err != nil {
+		if err == errHTTP3HeaderTooLarge {
+			err = http3ErrExcessiveLoad
+		}
+		return err
+	}
+	if err := http3CheckSettings(settings); err != nil {
+		return err
+	}
+	for {
+		ftype, err := fr.readFrameHeader()
+		if err != nil {
+			return err
+		}
+		switch ftype {
+		case http3FrameGoaway:
+			b, err := fr.readPayload(8)
+			if err != nil {
+				return http3ErrFrame
+			}
+			id, err := http3ReadVarint(strings.NewReader(string(b)))
+			if err != nil {
+				return http3ErrFrame
+			}
+			onGoaway(id)
+		case http3FrameCancelPush, http3FrameMaxPushID:
+			// We neither send nor accept server push.
+		case http3FrameData, http3FrameHeaders, http3FrameSettings, http3FramePushPromise:
+			return http3ErrFrameUnexpected
+		default:
+			if http3IsReservedFrame(ftype) {
+				return http3ErrFrameUnexpected
+			}
+			// Unknown frame types are ignored.
+		}
+	}
+}
+
+// http3CheckSettings validates the payload of a SETTINGS frame.
+// None of the peer's settings change our behavior: we never use the
+// dynamic table, and we don't limit the size of headers we send.
+func http3CheckSettings(b []byte) error {
+	r := strings.NewReader(string(b))
+	seen := make(map[uint64]bool)
+	for r.Len() > 0 {
+		id, err := http3ReadVarint(r)
+		if err != nil {
+			return http3ErrFrame
+		}
+		if _, err := http3ReadVarint(r); err != nil {
+			return http3ErrFrame
+		}
+		switch id {
+		case 0x02, 0x03, 0x04, 0x05:
+			// HTTP/2 settings with no HTTP/3 equivalent.
+			// https://www.rfc-editor.org/rfc/rfc9114#section-7.2.4.1-5
+			return http3ErrSettings
+		}
+		if seen[id] {
+			return http3ErrSettings
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// http3ConnectionHeaders are connection-specific header fields,
+// which are not permitted in HTTP/3 messages.
+// https://www.rfc-editor.org/rfc/rfc9114#section-4.2-4
+var http3ConnectionHeaders = map[string]bool{
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// http3AppendHeader appends the fields of h to an encoded field section.
+// Connection-specific fields and fields with invalid names or values
+// are omitted. If keep is non-nil, only fields for which it returns
+// true are included.
+func http3AppendHeader(b []byte, h Header, keep func(k string) bool) []byte {
+	for k, vv := range h {
+		if http3ConnectionHeaders[CanonicalHeaderKey(k)] || !httpguts.ValidHeaderFieldName(k) {
+			continue
+		}
+		if keep != nil && !keep(k) {
+			continue
+		}
+		name := strings.ToLower(k)
+		for _, v := range vv {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				continue
+			}
+			if name == "te" && v != "trailers" {
+				continue
+			}
+			b = http3AppendField(b, name, v)
+		}
+	}
+	return b
+}
+
+// http3DecodeHeader decodes a field section.
+// Pseudo-header fields are passed to pseudo; a nil pseudo means
+// none are permitted, as in trailers. Malformed fields are an
+// http3ErrMessage error.
+func http3DecodeHeader(b []byte, maxSize int64, pseudo func(name, value string) error) (Header, error) {
+	h := make(Header)
+	size := int64(0)
+	sawRegular := false
+	err := http3DecodeFieldSection(b, func(name, value string) error {
+		// The size of a field is the length of its name and value
+		// plus an overhead of 32 bytes, as in HPACK.
+		// https://www.rfc-editor.org/rfc/rfc9114#section-4.2.2
+		size += int64(len(name)+len(value)) + 32
+		if size > maxSize {
+			return errHTTP3HeaderTooLarge
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return http3ErrMessage
+		}
+		if strings.HasPrefix(name, ":") {
+			if sawRegular || pseudo == nil {
+				return http3ErrMessage
+			}
+			return pseudo(name, value)
+		}
+		sawRegular = true
+		if !httpguts.ValidHeaderFieldName(name) || strings.ToLower(name) != name {
+			return http3ErrMessage
+		}
+		k := CanonicalHeaderKey(name)
+		if http3ConnectionHeaders[k] || (k == "Te" && value != "trailers") {
+			return http3ErrMessage
+		}
+		h[k] = append(h[k], value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// http3Body is the body of a request or response, read from the
+// DATA frames of a request stream.
+type http3Body struct {
+	conn *http3Conn
+	st   *quic.Stream
+	fr   *http3FrameReader
+
+	remain    int64   // bytes remaining according to Content-Length, or -1
+	trailer   *Header // destination of trailing fields, if any
+	closeCode http3ErrCode
+
+	// If ctx is non-nil, reads which fail after it is done
+	// return its error.
+	ctx context.Context
+
+	// onDone, if non-nil, is called once when the body is read to
+	// completion (with io.EOF), a read fails, or the body is closed.
+	onDone func(err error)
+
+	mu         sync.Mutex
+	err        error // sticky read error
+	closed     bool
+	sawTrailer bool
+}
+
+func (b *http3Body) Read(p []byte) (n int, err error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, errReadOnClosedResBody
+	}
+	if b.err != nil {
+		err := b.err
+		b.mu.Unlock()
+		return 0, err
+	}
+	b.mu.Unlock()
+
+	n, err = b.read(p)
+	if err != nil {
+		b.conn.streamError(b.st, err)
+		if b.ctx != nil && err != io.EOF && b.ctx.Err() != nil {
+			err = b.ctx.Err()
+		}
+		b.mu.Lock()
+		b.err = err
+		b.mu.Unlock()
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *http3Body) done(err error) {
+	b.mu.Lock()
+	onDone := b.onDone
+	b.onDone = nil
+	b.mu.Unlock()
+	if onDone != nil {
+		onDone(err)
+	}
+}
+
+func (b *http3Body) read(p []byte) (n int, err error) {
+	for b.fr.remain == 0 {
+		ftype, err := b.fr.readFrameHeader()
+		if err == io.EOF {
+			if b.remain > 0 {
+				return 0, http3ErrMessage
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		switch ftype {
+		case http3FrameData:
+			if b.sawTrailer {
+				return 0, http3ErrFrameUnexpected
+			}
+		case http3FrameHeaders:
+			if b.sawTrailer {
+				return 0, http3ErrFrameUnexpected
+			}
+			b.sawTrailer = true
+			if err := b.readTrailer(); err != nil {
+				return 0, err
+			}
+		case http3FrameCancelPush, http3FrameSettings, http3FrameGoaway, http3FrameMaxPushID:
+			return 0, http3ErrFrameUnexpected
+		case http3FramePushPromise:
+			// We never send MAX_PUSH_ID, so the server may not push.
+			return 0, http3ErrID
+		default:
+			if http3IsReservedFrame(ftype) {
+				return 0, http3ErrFrameUnexpected
+			}
+			// Unknown frame types are ignored.
+			if err := b.skipFrame(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	n, err = b.fr.Read(p)
+	if b.remain >= 0 {
+		b.remain -= int64(n)
+		if b.remain < 0 {
+			return n, http3ErrMessage
+		}
+	}
+	return n, err
+}
+
+func (b *http3Body) skipFrame() error {
+	_, err := io.CopyN(ioutil.Discard, b.fr, b.fr.remain)
+	return err
+}
+
+func (b *http3Body) readTrailer() error {
+	payload, err := b.fr.readPayload(b.conn.maxHeaderBytes)
+	if err != nil {
+		return err
+	}
+	h, err := http3DecodeHeader(payload, b.conn.maxHeaderBytes, nil)
+	if err != nil {
+		return err
+	}
+	if b.trailer == nil {
+		return nil
+	}
+	if *b.trailer == nil {
+		*b.trailer = make(Header)
+	}
+	for k, vv := range h {
+		(*b.trailer)[k] = vv
+	}
+	return nil
+}
+
+// Close closes the body. If the body has not been read to
+// completion, the peer is asked to stop sending it.
+func (b *http3Body) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	eof := b.err == io.EOF
+	b.mu.Unlock()
+	if !eof {
+		b.st.StopSending(uint64(b.closeCode))
+	}
+	b.done(errReadOnClosedResBody)
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTP3Varint(t *testing.T) {
+	for _, v := range []uint64{0, 63, 64, 16383, 16384, 1<<30 - 1, 1 << 30, 1<<62 - 1} {
+		b := http3AppendVarint(nil, v)
+		got, err := http3ReadVarint(bytes.NewReader(b))
+		if err != nil || got != v {
+			t.Errorf("varint %v: got %v, %v", v, got, err)
+		}
+	}
+}
+
+func TestHTTP3QPACKRoundTrip(t *testing.T) {
+	fields := []http3HeaderField{
+		{":method", "GET"},                       // exact static match
+		{":path", "/index.html"},                 // static name reference
+		{"content-type", "text/plain"},           // exact static match
+		{"x-custom", "some value"},               // literal name
+		{"x-empty", ""},                          // literal name, empty value
+		{"user-agent", "Go-http-client/3"},       // static name reference, Huffman
+		{"x-binary", "\x00\x01\xff not huffman"}, // literal name, raw value
+	}
+	b := http3AppendFieldSectionPrefix(nil)
+	for _, f := range fields {
+		b = http3AppendField(b, f.name, f.value)
+	}
+	var got []http3HeaderField
+	err := http3DecodeFieldSection(b, func(name, value string) error {
+		got = append(got, http3HeaderField{name, value})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, fields) {
+		t.Errorf("decoded fields = %q\nwant %q", got, fields)
+	}
+}
+
+func TestHTTP3QPACKRejectsDynamicTable(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x01, 0x00},                  // Required Insert Count != 0
+		{0x00, 0x00, 0x80},            // indexed field line, dynamic table
+		{0x00, 0x00, 0x40},            // name reference, dynamic table
+		{0x00, 0x00, 0x10},            // post-base index
+		{0x00, 0x00, 0xff},            // truncated integer
+		{0x00, 0x00, 0xc0 | 63, 0x7f}, // static index out of range
+	} {
+		err := http3DecodeFieldSection(b, func(name, value string) error { return nil })
+		if err != http3ErrQPACKDecompressionFailed {
+			t.Errorf("decode %x: err = %v, want %v", b, err, http3ErrQPACKDecompressionFailed)
+		}
+	}
+}
+
+func TestHTTP3ParseAltSvc(t *testing.T) {
+	tests := []struct {
+		v      string
+		addr   string
+		maxAge time.Duration
+		clear  bool
+	}{
+		{`h3=":443"`, "example.com:443", 24 * time.Hour, false},
+		{`h3=":8443"; ma=60`, "example.com:8443", time.Minute, false},
+		{`h3="alt.example.com:443"; ma=3600; persist=1`, "alt.example.com:443", time.Hour, false},
+		{`h2=":443", h3=":444"`, "example.com:444", 24 * time.Hour, false},
+		{`h3-29=":443"`, "", 0, false},
+		{`h3=":443`, "", 0, false},
+		{`h3=443`, "", 0, false},
+		{`clear`, "", 0, true},
+		{``, "", 0, false},
+	}
+	for _, tt := range tests {
+		addr, maxAge, clear := http3ParseAltSvc(tt.v, "example.com")
+		if addr != tt.addr || maxAge != tt.maxAge || clear != tt.clear {
+			t.Errorf("http3ParseAltSvc(%q) = %q, %v, %v; want %q, %v, %v", tt.v, addr, maxAge, clear, tt.addr, tt.maxAge, tt.clear)
+		}
+	}
+}
+
+func TestHTTP3DecodeHeader(t *testing.T) {
+	enc := func(fields ...string) []byte {
+		b := http3AppendFieldSectionPrefix(nil)
+		for i := 0; i < len(fields); i += 2 {
+			b = http3AppendField(b, fields[i], fields[i+1])
+		}
+		return b
+	}
+	var status string
+	pseudo := func(name, value string) error {
+		if name != ":status" {
+			return http3ErrMessage
+		}
+		status = value
+		return nil
+	}
+	h, err := http3DecodeHeader(enc(":status", "200", "cookie", "a=1", "x-foo", "bar", "x-foo", "baz"), 1<<20, pseudo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "200" {
+		t.Errorf("status = %q, want 200", status)
+	}
+	want := Header{"Cookie": {"a=1"}, "X-Foo": {"bar", "baz"}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("header = %v, want %v", h, want)
+	}
+
+	for _, b := range [][]byte{
+		enc("x-foo", "bar", ":status", "200"), // pseudo-header after regular field
+		enc("X-Foo", "bar"),                   // uppercase name
+		enc("connection", "close"),            // connection-specific header
+		enc("te", "gzip"),                     // TE other than trailers
+	} {
+		if _, err := http3DecodeHeader(b, 1<<20, pseudo); err == nil {
+			t.Errorf("decode %x: unexpected success", b)
+		}
+	}
+	if _, err := http3DecodeHeader(enc("x-foo", "bar"), 10, pseudo); err != errHTTP3HeaderTooLarge {
+		t.Errorf("decode oversized header: err = %v, want %v", err, errHTTP3HeaderTooLarge)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// QPACK field compression for HTTP/3 (RFC 9204).
+//
+// Both endpoints advertise a dynamic table capacity of zero, so only
+// the static table and literal field lines are ever used, and no
+// encoder or decoder stream instructions are exchanged.
+
+package http
+
+import (
+	"golang_org/x/net/http2/hpack"
+)
+
+type http3HeaderField struct {
+	name, value string
+}
+
+// http3StaticTable is the QPACK static table.
+// https://www.rfc-editor.org/rfc/rfc9204#appendix-A
+var http3StaticTable = [...]http3HeaderField{
+	{":authority", ""},
+	{":path", "/"},
+	{"age", "0"},
+	{"content-disposition", ""},
+	{"content-length", "0"},
+	{"cookie", ""},
+	{"date", ""},
+	{"etag", ""},
+	{"if-modified-since", ""},
+	{"if-none-match", ""},
+	{"last-modified", ""},
+	{"link", ""},
+	{"location", ""},
+	{"referer", ""},
+	{"set-cookie", ""},
+	{":method", "CONNECT"},
+	{":method", "DELETE"},
+	{":method", "GET"},
+	{":method", "HEAD"},
+	{":method", "OPTIONS"},
+	{":method", "POST"},
+	{":method", "PUT"},
+	{":scheme", "http"},
+	{":scheme", "https"},
+	{":status", "103"},
+	{":status", "200"},
+	{":status", "304"},
+	{":status", "404"},
+	{":status", "503"},
+	{"accept", "*/*"},
+	{"accept", "application/dns-message"},
+	{"accept-encoding", "gzip, deflate, br"},
+	{"accept-ranges", "bytes"},
+	{"access-control-allow-headers", "cache-control"},
+	{"access-control-allow-headers", "content-type"},
+	{"access-control-allow-origin", "*"},
+	{"cache-control", "max-age=0"},
+	{"cache-control", "max-age=2592000"},
+	{"cache-control", "max-age=604800"},
+	{"cache-control", "no-cache"},
+	{"cache-control", "no-store"},
+	{"cache-control", "public, max-age=31536000"},
+	{"content-encoding", "br"},
+	{"content-encoding", "gzip"},
+	{"content-type", "application/dns-message"},
+	{"content-type", "application/javascript"},
+	{"content-type", "application/json"},
+	{"content-type", "application/x-www-form-urlencoded"},
+	{"content-type", "image/gif"},
+	{"content-type", "image/jpeg"},
+	{"content-type", "image/png"},
+	{"content-type", "text/css"},
+	{"content-type", "text/html; charset=utf-8"},
+	{"content-type", "text/plain"},
+	{"content-type", "text/plain;charset=utf-8"},
+	{"range", "bytes=0-"},
+	{"strict-transport-security", "max-age=31536000"},
+	{"strict-transport-security", "max-age=31536000; includesubdomains"},
+	{"strict-transport-security", "max-age=31536000; includesubdomains; preload"},
+	{"vary", "accept-encoding"},
+	{"vary", "origin"},
+	{"x-content-type-options", "nosniff"},
+	{"x-xss-protection", "1; mode=block"},
+	{":status", "100"},
+	{":status", "204"},
+	{":status", "206"},
+	{":status", "302"},
+	{":status", "400"},
+	{":status", "403"},
+	{":status", "421"},
+	{":status", "425"},
+	{":status", "500"},
+	{"accept-language", ""},
+	{"access-control-allow-credentials", "FALSE"},
+	{"access-control-allow-credentials", "TRUE"},
+	{"access-control-allow-headers", "*"},
+	{"access-control-allow-methods", "get"},
+	{"access-control-allow-methods", "get, post, options"},
+	{"access-control-allow-methods", "options"},
+	{"access-control-expose-headers", "content-length"},
+	{"access-control-request-headers", "content-type"},
+	{"access-control-request-method", "get"},
+	{"access-control-request-method", "post"},
+	{"alt-svc", "clear"},
+	{"authorization", ""},
+	{"content-security-policy", "script-src 'none'; object-src 'none'; base-uri 'none'"},
+	{"early-data", "1"},
+	{"expect-ct", ""},
+	{"forwarded", ""},
+	{"if-range", ""},
+	{"origin", ""},
+	{"purpose", "prefetch"},
+	{"server", ""},
+	{"timing-allow-origin", "*"},
+	{"upgrade-insecure-requests", "1"},
+	{"user-agent", ""},
+	{"x-forwarded-for", ""},
+	{"x-frame-options", "deny"},
+	{"x-frame-options", "sameorigin"},
+}
+
+var (
+	http3StaticFieldIndex = make(map[http3HeaderField]int) // exact matches
+	http3StaticNameIndex  = make(map[string]int)           // first entry with a name
+)
+
+func init() {
+	for i, f := range http3StaticTable {
+		if _, ok := http3StaticFieldIndex[f]; !ok {
+			http3StaticFieldIndex[f] = i
+		}
+		if _, ok := http3StaticNameIndex[f.name]; !ok {
+			http3StaticNameIndex[f.name] = i
+		}
+	}
+}
+
+// http3AppendFieldSectionPrefix appends the prefix of an encoded
+// field section. With no dynamic table, the Required Insert Count
+// and Delta Base are both zero.
+// https://www.rfc-editor.org/rfc/rfc9204#section-4.5.1
+func http3AppendFieldSectionPrefix(b []byte) []byte {
+	return append(b, 0, 0)
+}
+
+// http3AppendField appends an encoded field line to b.
+// The name must already be lowercase.
+func http3AppendField(b []byte, name, value string) []byte {
+	if i, ok := http3StaticFieldIndex[http3HeaderField{name, value}]; ok {
+		// Indexed Field Line, static table (T=1).
+		// https://www.rfc-editor.org/rfc/rfc9204#section-4.5.2
+		return http3AppendPrefixInt(b, 0xc0, 6, uint64(i))
+	}
+	if i, ok := http3StaticNameIndex[name]; ok {
+		// Literal Field Line with Name Reference, static table (T=1).
+		// https://www.rfc-editor.org/rfc/rfc9204#section-4.5.4
+		b = http3AppendPrefixInt(b, 0x50, 4, uint64(i))
+		return http3AppendString(b, 0, 7, value)
+	}
+	// Literal Field Line with Literal Name.
+	// https://www.rfc-editor.org/rfc/rfc9204#section-4.5.6
+	b = http3AppendString(b, 0x20, 3, name)
+	return http3AppendString(b, 0, 7, value)
+}
+
+// http3AppendPrefixInt appends an integer with an N-bit prefix,
+// as defined by RFC 7541, Section 5.1. The bits of first above the
+// prefix are preserved.
+func http3AppendPrefixInt(b []byte, first byte, n uint8, v uint64) []byte {
+	max := uint64(1)<<n - 1
+	if v < max {
+		return append(b, first|byte(v))
+	}
+	b = append(b, first|byte(max))
+	v -= max
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// http3AppendString appends a string literal with an N-bit length prefix.
+// The bit immediately above the prefix is the Huffman flag.
+// Huffman encoding is used when it is shorter.
+func http3AppendString(b []byte, first byte, n uint8, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		b = http3AppendPrefixInt(b, first|1<<n, n, l)
+		return hpack.AppendHuffmanString(b, s)
+	}
+	b = http3AppendPrefixInt(b, first, n, uint64(len(s)))
+	return append(b, s...)
+}
+
+// http3ReadPrefixInt reads an integer with an N-bit prefix.
+func http3ReadPrefixInt(b []byte, n uint8) (v uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, nil, http3ErrQPACKDecompressionFailed
+	}
+	max := uint64(1)<<n - 1
+	v = uint64(b[0]) & max
+	b = b[1:]
+	if v < max {
+		return v, b, nil
+	}
+	for m := uint(0); m < 63; m += 7 {
+		if len(b) == 0 {
+			break
+		}
+		c := b[0]
+		b = b[1:]
+		v += uint64(c&0x7f) << m
+		if c&0x80 == 0 {
+			return v, b, nil
+		}
+	}
+	return 0, nil, http3ErrQPACKDecompressionFailed
+}
+
+// http3ReadString reads a string literal with an N-bit length prefix.
+func http3ReadString(b []byte, n uint8) (s string, rest []byte, err error) {
+	if len(b) == 0 {
+		return "", nil, http3ErrQPACKDecompressionFailed
+	}
+	huffman := b[0]&(1<<n) != 0
+	l, b, err := http3ReadPrefixInt(b, n)
+	if err != nil {
+		return "", nil, err
+	}
+	if uint64(len(b)) < l {
+		return "", nil, http3ErrQPACKDecompressionFailed
+	}
+	if huffman {
+		s, err = hpack.HuffmanDecodeToString(b[:l])
+		if err != nil {
+			return "", nil, http3ErrQPACKDecompressionFailed
+		}
+	} else {
+		s = string(b[:l])
+	}
+	return s, b[l:], nil
+}
+
+// http3DecodeFieldSection decodes an encoded field section,
+// calling f for each field line in order. Decoding stops at the
+// first error returned by f.
+//
+// References to the dynamic table are an error, since we never
+// permit the peer to use one.
+func http3DecodeFieldSection(b []byte, f func(name, value string) error) error {
+	requiredInsertCount, b, err := http3ReadPrefixInt(b, 8)
+	if err != nil {
+		return err
+	}
+	if requiredInsertCount != 0 {
+		return http3ErrQPACKDecompressionFailed
+	}
+	if _, b, err = http3ReadPrefixInt(b, 7); err != nil { // Delta Base
+		return err
+	}
+	for len(b) > 0 {
+		var name, value string
+		switch {
+		case b[0]&0x80 != 0: // Indexed Field Line
+			if b[0]&0x40 == 0 {
+				return http3ErrQPACKDecompressionFailed
+			}
+			var i uint64
+			if i, b, err = http3ReadPrefixInt(b, 6); err != nil {
+				return err
+			}
+			if i >= uint64(len(http3StaticTable)) {
+				return http3ErrQPACKDecompressionFailed
+			}
+			name, value = http3StaticTable[i].name, http3StaticTable[i].value
+		case b[0]&0x40 != 0: // Literal Field Line with Name Reference
+			if b[0]&0x10 == 0 {
+				return http3ErrQPACKDecompressionFailed
+			}
+			var i uint64
+			if i, b, err = http3ReadPrefixInt(b, 4); err != nil {
+				return err
+			}
+			if i >= uint64(len(http3StaticTable)) {
+				return http3ErrQPACKDecompressionFailed
+			}
+			name = http3StaticTable[i].name
+			if value, b, err = http3ReadString(b, 7); err != nil {
+				return err
+			}
+		case b[0]&0x20 != 0: // Literal Field Line with Literal Name
+			if name, b, err = http3ReadString(b, 3); err != nil {
+				return err
+			}
+			if value, b, err = http3ReadString(b, 7); err != nil {
+				return err
+			}
+		default:
+			// Post-base forms always refer to the dynamic table.
+			return http3ErrQPACKDecompressionFailed
+		}
+		if err := f(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
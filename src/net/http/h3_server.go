@@ -0,0 +1,753 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 server.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/internal/quic"
+	"net/textproto"
+	"net/url"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang_org/x/net/http/httpguts"
+)
+
+// ServeQUIC serves HTTP/3 requests on the UDP packet connection pc,
+// creating a new service goroutine for each request. The service
+// goroutines call srv.Handler to reply to them.
+//
+// srv.TLSConfig must contain a certificate, in Certificates or
+// GetCertificate. ServeQUIC takes ownership of pc and closes it when
+// the server is closed.
+//
+// While ServeQUIC is running, responses sent by srv over HTTPS
+// connections advertise HTTP/3 with an Alt-Svc header naming the port
+// of pc, unless the Handler sets its own Alt-Svc header. ServeQUIC
+// is usually run alongside ServeTLS on the same host and port.
+//
+// ServeQUIC always returns a non-nil error. After Shutdown or Close,
+// the returned error is ErrServerClosed.
+func (srv *Server) ServeQUIC(pc net.PacketConn) error {
+	config := cloneTLSConfig(srv.TLSConfig)
+	if len(config.Certificates) == 0 && config.GetCertificate == nil {
+		pc.Close()
+		return errors.New("http: ServeQUIC requires a certificate in Server.TLSConfig")
+	}
+	return srv.serveQUIC(pc, config)
+}
+
+// ListenAndServeQUIC listens on the UDP network address srv.Addr and
+// then calls ServeQUIC to handle HTTP/3 requests.
+//
+// Filenames containing a certificate and matching private key for the
+// server must be provided if neither the Server's TLSConfig.Certificates
+// nor TLSConfig.GetCertificate are populated.
+//
+// If srv.Addr is blank, ":https" is used.
+//
+// ListenAndServeQUIC always returns a non-nil error.
+func (srv *Server) ListenAndServeQUIC(certFile, keyFile string) error {
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":https"
+	}
+	config := cloneTLSConfig(srv.TLSConfig)
+	configHasCert := len(config.Certificates) > 0 || config.GetCertificate != nil
+	if !configHasCert || certFile != "" || keyFile != "" {
+		var err error
+		config.Certificates = make([]tls.Certificate, 1)
+		config.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+	}
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return srv.serveQUIC(pc, config)
+}
+
+func (srv *Server) serveQUIC(pc net.PacketConn, config *tls.Config) error {
+	config.NextProtos = []string{http3NextProto}
+	qconfig := &quic.Config{TLSConfig: config}
+	if d := srv.idleTimeout(); d > 0 {
+		qconfig.MaxIdleTimeout = d
+	}
+	e := quic.NewEndpoint(pc, qconfig)
+	srv.trackQUICEndpoint(e, true)
+	defer srv.trackQUICEndpoint(e, false)
+
+	// Stop accepting connections when the server is shut down.
+	// The endpoint itself is closed by Close, or by Shutdown once
+	// its connections are done.
+	acceptCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := srv.getDoneChan()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-acceptCtx.Done():
+		}
+	}()
+
+	ctx := context.WithValue(context.Background(), ServerContextKey, srv)
+	for {
+		qconn, err := e.Accept(acceptCtx)
+		if err != nil {
+			select {
+			case <-done:
+				return ErrServerClosed
+			default:
+			}
+			e.Close()
+			return err
+		}
+		sc := &http3ServerConn{
+			http3Conn: http3Conn{
+				qconn:          qconn,
+				isServer:       true,
+				maxHeaderBytes: int64(srv.maxHeaderBytes()),
+			},
+			srv:     srv,
+			baseCtx: context.WithValue(ctx, LocalAddrContextKey, qconn.LocalAddr()),
+		}
+		srv.trackHTTP3Conn(sc, true)
+		go sc.serve()
+	}
+}
+
+// http3AltSvcMaxAge is how long clients may remember our HTTP/3 endpoints.
+const http3AltSvcMaxAge = 24 * time.Hour
+
+// trackQUICEndpoint adds or removes a QUIC endpoint from the set of
+// tracked endpoints.
+func (s *Server) trackQUICEndpoint(e *quic.Endpoint, add bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.quicEndpoints == nil {
+		s.quicEndpoints = make(map[*quic.Endpoint]struct{})
+	}
+	if add {
+		// If the *Server is being reused after a previous
+		// Close or Shutdown, reset its doneChan:
+		if len(s.listeners) == 0 && len(s.activeConn) == 0 && len(s.quicEndpoints) == 0 {
+			s.doneChan = nil
+		}
+		s.quicEndpoints[e] = struct{}{}
+	} else {
+		delete(s.quicEndpoints, e)
+	}
+	s.updateAltSvcLocked()
+}
+
+// updateAltSvcLocked sets the Alt-Svc header value used to advertise
+// HTTP/3 in the server's HTTPS responses.
+func (s *Server) updateAltSvcLocked() {
+	var ports []int
+	for e := range s.quicEndpoints {
+		if addr, ok := e.LocalAddr().(*net.UDPAddr); ok {
+			ports = append(ports, addr.Port)
+		}
+	}
+	sort.Ints(ports)
+	var v []string
+	for _, port := range ports {
+		v = append(v, fmt.Sprintf(`%s=":%d"; ma=%d`, http3NextProto, port, int(http3AltSvcMaxAge/time.Second)))
+	}
+	s.altSvc.Store(strings.Join(v, ", "))
+}
+
+func (s *Server) trackHTTP3Conn(sc *http3ServerConn, add bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.activeHTTP3Conn == nil {
+		s.activeHTTP3Conn = make(map[*http3ServerConn]struct{})
+	}
+	if add {
+		s.activeHTTP3Conn[sc] = struct{}{}
+		if s.shuttingDown() {
+			sc.goAway()
+		}
+	} else {
+		delete(s.activeHTTP3Conn, sc)
+	}
+}
+
+// closeQUICEndpointsLocked closes all QUIC endpoints and their connections.
+func (s *Server) closeQUICEndpointsLocked() error {
+	var err error
+	for e := range s.quicEndpoints {
+		if cerr := e.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(s.quicEndpoints, e)
+	}
+	for sc := range s.activeHTTP3Conn {
+		delete(s.activeHTTP3Conn, sc)
+	}
+	s.updateAltSvcLocked()
+	return err
+}
+
+// goAwayHTTP3Locked asks the clients of all HTTP/3 connections to
+// stop sending new requests.
+func (s *Server) goAwayHTTP3Locked() {
+	for sc := range s.activeHTTP3Conn {
+		sc.goAway()
+	}
+}
+
+// closeIdleHTTP3Conns closes all HTTP/3 connections which have no
+// requests in progress, and reports whether none remain.
+// Once none remain, the QUIC endpoints are closed.
+func (s *Server) closeIdleHTTP3Conns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	quiescent := true
+	for sc := range s.activeHTTP3Conn {
+		if !sc.closeIfIdle() {
+			quiescent = false
+			continue
+		}
+		delete(s.activeHTTP3Conn, sc)
+	}
+	if quiescent {
+		s.closeQUICEndpointsLocked()
+	}
+	return quiescent
+}
+
+// http3ServerConn is a server-side HTTP/3 connection.
+type http3ServerConn struct {
+	http3Conn
+	srv     *Server
+	baseCtx context.Context
+
+	// Guarded by http3Conn.mu.
+	control    *quic.Stream
+	active     int   // requests in progress
+	nextID     int64 // ID of the next request stream to accept
+	sentGoaway bool
+}
+
+func (sc *http3ServerConn) serve() {
+	defer sc.srv.trackHTTP3Conn(sc, false)
+	defer sc.abort(http3ErrNo)
+
+	control, err := sc.openControlStream(sc.baseCtx)
+	if err != nil {
+		return
+	}
+	sc.mu.Lock()
+	sc.control = control
+	if sc.sentGoaway {
+		sc.writeGoawayLocked()
+	}
+	sc.mu.Unlock()
+
+	for {
+		st, err := sc.qconn.AcceptStream(sc.baseCtx)
+		if err != nil {
+			return
+		}
+		if st.IsReadOnly() {
+			go sc.handleUniStream(st, func(uint64) {})
+			continue
+		}
+		sc.mu.Lock()
+		if sc.sentGoaway && st.ID() >= sc.nextID {
+			sc.mu.Unlock()
+			st.StopSending(uint64(http3ErrRequestRejected))
+			st.Reset(uint64(http3ErrRequestRejected))
+			continue
+		}
+		sc.active++
+		sc.nextID = st.ID() + 4
+		sc.mu.Unlock()
+		go sc.serveRequest(st)
+	}
+}
+
+// goAway sends a GOAWAY frame, after which no new requests are accepted.
+// https://www.rfc-editor.org/rfc/rfc9114#section-5.2
+func (sc *http3ServerConn) goAway() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.sentGoaway {
+		return
+	}
+	sc.sentGoaway = true
+	if sc.control != nil {
+		sc.writeGoawayLocked()
+	}
+}
+
+func (sc *http3ServerConn) writeGoawayLocked() {
+	id := http3AppendVarint(nil, uint64(sc.nextID))
+	b := http3AppendFrameHeader(nil, http3FrameGoaway, len(id))
+	sc.control.Write(append(b, id...))
+}
+
+// closeIfIdle closes the connection if no requests are in progress,
+// and reports whether it did so.
+func (sc *http3ServerConn) closeIfIdle() bool {
+	sc.mu.Lock()
+	idle := sc.active == 0
+	sc.mu.Unlock()
+	if idle {
+		sc.abort(http3ErrNo)
+	}
+	return idle
+}
+
+func (sc *http3ServerConn) serveRequest(st *quic.Stream) {
+	defer func() {
+		sc.mu.Lock()
+		sc.active--
+		sc.mu.Unlock()
+	}()
+	fr := newHTTP3FrameReader(st)
+	req, cancel, err := sc.readRequest(st, fr)
+	if err != nil {
+		if err == errHTTP3HeaderTooLarge {
+			w := newHTTP3ResponseWriter(sc, st, &Request{Method: "GET"})
+			w.WriteHeader(StatusRequestHeaderFieldsTooLarge)
+			w.finish()
+			st.StopSending(uint64(http3ErrNo))
+		} else if _, ok := err.(http3ErrCode); ok {
+			sc.streamError(st, err)
+		} else {
+			// The stream ended or was reset before the
+			// request header was complete.
+			st.Reset(uint64(http3ErrRequestIncomplete))
+		}
+		return
+	}
+	defer cancel()
+	w := newHTTP3ResponseWriter(sc, st, req)
+	defer func() {
+		if e := recover(); e != nil {
+			if e != ErrAbortHandler {
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				sc.srv.logf("http3: panic serving %v: %v\n%s", req.RemoteAddr, e, buf)
+			}
+			st.StopSending(uint64(http3ErrInternal))
+			st.Reset(uint64(http3ErrInternal))
+			return
+		}
+		w.finish()
+		req.Body.Close()
+	}()
+	serverHandler{sc.srv}.ServeHTTP(w, req)
+}
+
+// readRequest reads the request header from a request stream.
+func (sc *http3ServerConn) readRequest(st *quic.Stream, fr *http3FrameReader) (*Request, context.CancelFunc, error) {
+	var payload []byte
+	for payload == nil {
+		ftype, err := fr.readFrameHeader()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch ftype {
+		case http3FrameHeaders:
+			payload, err = fr.readPayload(sc.maxHeaderBytes)
+			if err != nil {
+				return nil, nil, err
+			}
+		case http3FrameData, http3FrameCancelPush, http3FrameSettings, http3FramePushPromise, http3FrameGoaway, http3FrameMaxPushID:
+			return nil, nil, http3ErrFrameUnexpected
+		default:
+			if http3IsReservedFrame(ftype) {
+				return nil, nil, http3ErrFrameUnexpected
+			}
+			// Unknown frame types are ignored.
+		}
+	}
+
+	var method, scheme, authority, path string
+	header, err := http3DecodeHeader(payload, sc.maxHeaderBytes, func(name, value string) error {
+		var p *string
+		switch name {
+		case ":method":
+			p = &method
+		case ":scheme":
+			p = &scheme
+		case ":authority":
+			p = &authority
+		case ":path":
+			p = &path
+		default:
+			return http3ErrMessage
+		}
+		if *p != "" {
+			return http3ErrMessage
+		}
+		*p = value
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// https://www.rfc-editor.org/rfc/rfc9114#section-4.3.1
+	if method == "" {
+		return nil, nil, http3ErrMessage
+	}
+	if method == "CONNECT" {
+		if authority == "" || scheme != "" || path != "" {
+			return nil, nil, http3ErrMessage
+		}
+	} else if scheme == "" || path == "" {
+		return nil, nil, http3ErrMessage
+	}
+	if authority == "" {
+		authority = header.Get("Host")
+	}
+	delete(header, "Host")
+	if cookies := header["Cookie"]; len(cookies) > 1 {
+		// The Cookie header may be split into multiple fields.
+		// https://www.rfc-editor.org/rfc/rfc9114#section-4.2.1
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	var u *url.URL
+	requestURI := path
+	if method == "CONNECT" {
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	} else if u, err = url.ParseRequestURI(path); err != nil {
+		return nil, nil, http3ErrMessage
+	}
+
+	contentLength := int64(-1)
+	if vv := header["Content-Length"]; len(vv) > 0 {
+		n, err := strconv.ParseInt(vv[0], 10, 64)
+		if len(vv) > 1 || err != nil || n < 0 {
+			return nil, nil, http3ErrMessage
+		}
+		contentLength = n
+	} else if method == "GET" || method == "HEAD" {
+		// Without a Content-Length, we can't tell whether a body
+		// follows. These methods rarely have one, and a Request
+		// of unknown length would be forwarded with a chunked
+		// body by proxies.
+		contentLength = 0
+	}
+
+	var trailer Header
+	for _, v := range header["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = CanonicalHeaderKey(textproto.TrimString(key))
+			if !httpguts.ValidTrailerHeader(key) {
+				continue
+			}
+			if trailer == nil {
+				trailer = make(Header)
+			}
+			trailer[key] = nil
+		}
+	}
+	delete(header, "Trailer")
+
+	cs := sc.qconn.ConnectionState()
+	ctx, cancel := context.WithCancel(sc.baseCtx)
+	req := &Request{
+		Method:        method,
+		URL:           u,
+		Proto:         "HTTP/3.0",
+		ProtoMajor:    3,
+		ProtoMinor:    0,
+		Header:        header,
+		ContentLength: contentLength,
+		Host:          authority,
+		Trailer:       trailer,
+		RemoteAddr:    sc.qconn.RemoteAddr().String(),
+		RequestURI:    requestURI,
+		TLS:           &cs,
+	}
+	req = req.WithContext(ctx)
+	req.Body = &http3Body{
+		conn:      &sc.http3Conn,
+		st:        st,
+		fr:        fr,
+		remain:    contentLength,
+		trailer:   &req.Trailer,
+		closeCode: http3ErrNo,
+	}
+	return req, cancel, nil
+}
+
+// http3ResponseBufferSize is the size of the buffer used to
+// coalesce small writes to the response body. A response shorter
+// than this which is not flushed is sent with a Content-Length.
+const http3ResponseBufferSize = 4 << 10
+
+// http3ResponseWriter is the ResponseWriter for HTTP/3 requests.
+type http3ResponseWriter struct {
+	sc  *http3ServerConn
+	st  *quic.Stream
+	req *Request
+
+	handlerHeader Header // the Header returned to the Handler
+	snapHeader    Header // handlerHeader at WriteHeader time
+	status        int
+	wroteHeader   bool // WriteHeader called
+	sentHeader    bool // HEADERS frame sent
+	contentLength int64
+	wroteBytes    int64
+	buf           []byte   // unsent body
+	trailers      []string // declared trailer keys
+	err           error    // sticky write error
+}
+
+var (
+	_ ResponseWriter = (*http3ResponseWriter)(nil)
+	_ Flusher        = (*http3ResponseWriter)(nil)
+)
+
+func newHTTP3ResponseWriter(sc *http3ServerConn, st *quic.Stream, req *Request) *http3ResponseWriter {
+	return &http3ResponseWriter{
+		sc:            sc,
+		st:            st,
+		req:           req,
+		handlerHeader: make(Header),
+		contentLength: -1,
+	}
+}
+
+func (w *http3ResponseWriter) Header() Header {
+	return w.handlerHeader
+}
+
+func (w *http3ResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		w.sc.srv.logf("http: multiple response.WriteHeader calls")
+		return
+	}
+	checkWriteHeaderCode(code)
+
+	// Informational headers are sent immediately. Per RFC 8297,
+	// they don't clear the header map.
+	if code >= 100 && code <= 199 {
+		if code == StatusSwitchingProtocols {
+			// https://www.rfc-editor.org/rfc/rfc9114#section-4.5
+			w.sc.srv.logf("http: WriteHeader(101) is not supported by HTTP/3")
+			return
+		}
+		b := http3AppendFieldSectionPrefix(nil)
+		b = http3AppendField(b, ":status", strconv.Itoa(code))
+		b = http3AppendHeader(b, w.handlerHeader, func(k string) bool {
+			return k != "Content-Length" && !strings.HasPrefix(k, TrailerPrefix)
+		})
+		w.writeFrame(http3FrameHeaders, b)
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = code
+	w.snapHeader = w.handlerHeader.clone()
+	if cl := w.snapHeader.get("Content-Length"); cl != "" {
+		v, err := strconv.ParseInt(cl, 10, 64)
+		if err == nil && v >= 0 {
+			w.contentLength = v
+		} else {
+			w.sc.srv.logf("http: invalid Content-Length of %q", cl)
+			w.snapHeader.Del("Content-Length")
+		}
+	}
+}
+
+func (w *http3ResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if !bodyAllowedForStatus(w.status) {
+		return 0, ErrBodyNotAllowed
+	}
+	w.wroteBytes += int64(len(p))
+	if w.contentLength != -1 && w.wroteBytes > w.contentLength {
+		return 0, ErrContentLength
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.req.Method == "HEAD" {
+		// Eat writes.
+		return len(p), nil
+	}
+	if len(w.buf)+len(p) <= http3ResponseBufferSize {
+		w.buf = append(w.buf, p...)
+		return len(p), nil
+	}
+	if !w.sentHeader {
+		sniff := w.buf
+		if len(sniff) < sniffLen {
+			n := sniffLen - len(sniff)
+			if n > len(p) {
+				n = len(p)
+			}
+			sniff = append(sniff[:len(sniff):len(sniff)], p[:n]...)
+		}
+		w.sendHeader(sniff, false)
+	}
+	w.flushBuffer()
+	if len(p) < http3ResponseBufferSize {
+		w.buf = append(w.buf, p...)
+	} else {
+		w.writeFrame(http3FrameData, p)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// flushBuffer sends any buffered body data.
+func (w *http3ResponseWriter) flushBuffer() {
+	if len(w.buf) > 0 {
+		w.writeFrame(http3FrameData, w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+func (w *http3ResponseWriter) Flush() {
+	w.FlushError()
+}
+
+func (w *http3ResponseWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.sentHeader {
+		w.sendHeader(w.buf, false)
+	}
+	w.flushBuffer()
+	return w.err
+}
+
+func (w *http3ResponseWriter) SetReadDeadline(deadline time.Time) error {
+	return w.st.SetReadDeadline(deadline)
+}
+
+func (w *http3ResponseWriter) SetWriteDeadline(deadline time.Time) error {
+	return w.st.SetWriteDeadline(deadline)
+}
+
+// EnableFullDuplex is a no-op: HTTP/3 handlers may always read the
+// request body while writing the response.
+func (w *http3ResponseWriter) EnableFullDuplex() error {
+	return nil
+}
+
+// sendHeader writes the response HEADERS frame. The start of the body,
+// p, is used to sniff the Content-Type if none is set. If final is set,
+// the Handler has returned and p is the complete body.
+func (w *http3ResponseWriter) sendHeader(p []byte, final bool) {
+	w.sentHeader = true
+	h := w.snapHeader
+	if bodyAllowedForStatus(w.status) {
+		if _, ok := h["Content-Type"]; !ok && len(p) > 0 {
+			h.Set("Content-Type", DetectContentType(p))
+		}
+		if final && w.contentLength == -1 && w.req.Method != "HEAD" {
+			h.Set("Content-Length", strconv.Itoa(len(p)))
+		}
+	}
+	if _, ok := h["Date"]; !ok {
+		h.Set("Date", string(appendTime(nil, time.Now())))
+	}
+	for _, v := range h["Trailer"] {
+		foreachHeaderElement(v, w.declareTrailer)
+	}
+	b := http3AppendFieldSectionPrefix(nil)
+	b = http3AppendField(b, ":status", strconv.Itoa(w.status))
+	b = http3AppendHeader(b, h, func(k string) bool {
+		return !strings.HasPrefix(k, TrailerPrefix)
+	})
+	w.writeFrame(http3FrameHeaders, b)
+}
+
+func (w *http3ResponseWriter) declareTrailer(k string) {
+	k = CanonicalHeaderKey(k)
+	if !httpguts.ValidTrailerHeader(k) {
+		// Forbidden by RFC 7230, section 4.1.2.
+		w.sc.srv.logf("http3: ignoring invalid trailer %q", k)
+		return
+	}
+	if !strSliceContains(w.trailers, k) {
+		w.trailers = append(w.trailers, k)
+	}
+}
+
+func (w *http3ResponseWriter) writeFrame(ftype uint64, payload []byte) {
+	if w.err != nil {
+		return
+	}
+	b := http3AppendFrameHeader(make([]byte, 0, 16+len(payload)), ftype, len(payload))
+	b = append(b, payload...)
+	if _, err := w.st.Write(b); err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			// The frame may have been partially written,
+			// so the stream can't be used any further.
+			w.st.Reset(uint64(http3ErrInternal))
+		}
+		w.err = http3StreamError(err)
+	}
+}
+
+// finish completes the response after the Handler returns.
+func (w *http3ResponseWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(StatusOK)
+	}
+	if !w.sentHeader {
+		w.sendHeader(w.buf, true)
+	}
+	w.flushBuffer()
+
+	var trailer Header
+	for _, k := range w.trailers {
+		if vv := w.handlerHeader[k]; len(vv) > 0 {
+			if trailer == nil {
+				trailer = make(Header)
+			}
+			trailer[k] = vv
+		}
+	}
+	for k, vv := range w.handlerHeader {
+		if strings.HasPrefix(k, TrailerPrefix) {
+			if trailer == nil {
+				trailer = make(Header)
+			}
+			trailer[strings.TrimPrefix(k, TrailerPrefix)] = vv
+		}
+	}
+	if trailer != nil {
+		b := http3AppendFieldSectionPrefix(nil)
+		b = http3AppendHeader(b, trailer, nil)
+		w.writeFrame(http3FrameHeaders, b)
+	}
+	if w.err == nil {
+		w.st.Close()
+	}
+}
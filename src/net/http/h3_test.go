@@ -0,0 +1,362 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	. "net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newHTTP3Server starts a TLS test server with HTTP/3 enabled, and
+// returns it along with a client that has discovered its HTTP/3 endpoint.
+// The caller must close the server.
+func newHTTP3Server(t *testing.T, h Handler) (*httptest.Server, *Client) {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(h)
+	ts.EnableHTTP3 = true
+	ts.StartTLS()
+	c := ts.Client()
+
+	// The first request is sent over TCP, and its response
+	// advertises the HTTP/3 endpoint.
+	res, err := c.Get(ts.URL + "/alt-svc")
+	if err != nil {
+		ts.Close()
+		t.Fatal(err)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.ProtoMajor == 3 {
+		ts.Close()
+		t.Fatalf("first request used %v, want TCP", res.Proto)
+	}
+	if v := res.Header.Get("Alt-Svc"); !strings.HasPrefix(v, `h3=":`) {
+		ts.Close()
+		t.Fatalf("Alt-Svc = %q, want an h3 alternative", v)
+	}
+	return ts, c
+}
+
+func TestHTTP3AltSvc(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	defer ts.Close()
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("request %v: %v", i, err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.ProtoMajor != 3 || string(body) != "HTTP/3.0" {
+			t.Errorf("request %v: response proto %v, request proto %q; want HTTP/3.0", i, res.Proto, body)
+		}
+		if v := res.Header.Get("Alt-Svc"); v != "" {
+			t.Errorf("request %v: HTTP/3 response has Alt-Svc %q", i, v)
+		}
+	}
+}
+
+func TestHTTP3RoundTrip(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/alt-svc" {
+			return
+		}
+		if r.ProtoMajor != 3 || r.Proto != "HTTP/3.0" {
+			t.Errorf("request proto = %v, want HTTP/3.0", r.Proto)
+		}
+		if r.TLS == nil || r.TLS.NegotiatedProtocol != "h3" {
+			t.Errorf("request TLS state = %+v, want negotiated h3", r.TLS)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request body: %v", err)
+		}
+		if got := r.Trailer.Get("Req-Trailer"); got != "req-value" {
+			t.Errorf("request trailer = %q, want req-value", got)
+		}
+		w.Header().Set("Trailer", "Res-Trailer")
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Host", r.Host)
+		w.Write(bytes.ToUpper(body))
+		w.Header().Set("Res-Trailer", "res-value")
+	}))
+	defer ts.Close()
+
+	body := strings.Repeat("hello, world. ", 10000)
+	req, _ := NewRequest("POST", ts.URL+"/echo", ioutil.NopCloser(strings.NewReader(body)))
+	req.Trailer = Header{"Req-Trailer": nil}
+	req.Header.Set("Content-Type", "text/plain")
+	// Trailers are set after the body is read.
+	req.Trailer.Set("Req-Trailer", "req-value")
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.ProtoMajor != 3 {
+		t.Fatalf("response proto = %v, want HTTP/3.0", res.Proto)
+	}
+	if res.StatusCode != 200 {
+		t.Errorf("status = %v, want 200", res.StatusCode)
+	}
+	if got, want := res.Header.Get("X-Method"), "POST"; got != want {
+		t.Errorf("X-Method = %q, want %q", got, want)
+	}
+	if got, want := res.Header.Get("X-Host"), strings.TrimPrefix(ts.URL, "https://"); got != want {
+		t.Errorf("X-Host = %q, want %q", got, want)
+	}
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != strings.ToUpper(body) {
+		t.Errorf("response body is %d bytes, want %d bytes", len(got), len(body))
+	}
+	if got := res.Trailer.Get("Res-Trailer"); got != "res-value" {
+		t.Errorf("response trailer = %q, want res-value", got)
+	}
+}
+
+func TestHTTP3HeadAndStatus(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.URL.Path {
+		case "/404":
+			NotFound(w, r)
+		case "/head":
+			w.Header().Set("Content-Length", "1000")
+			w.Write(make([]byte, 1000))
+		}
+	}))
+	defer ts.Close()
+
+	res, err := c.Get(ts.URL + "/404")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.ProtoMajor != 3 || res.StatusCode != 404 || string(body) != "404 page not found\n" {
+		t.Errorf("GET /404 = %v %v %q, want HTTP/3.0 404", res.Proto, res.StatusCode, body)
+	}
+
+	res, err = c.Head(ts.URL + "/head")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.ProtoMajor != 3 || res.ContentLength != 1000 || len(body) != 0 {
+		t.Errorf("HEAD = %v, ContentLength %v, %d body bytes; want HTTP/3.0, 1000, 0", res.Proto, res.ContentLength, len(body))
+	}
+}
+
+func TestHTTP3CancelRequest(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	unblock := make(chan struct{})
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/alt-svc" {
+			return
+		}
+		w.(Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-unblock:
+		}
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequest("GET", ts.URL+"/slow", nil)
+	res, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ProtoMajor != 3 {
+		t.Fatalf("response proto = %v, want HTTP/3.0", res.Proto)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(res.Body); err != context.Canceled {
+		t.Errorf("reading canceled body: err = %v, want %v", err, context.Canceled)
+	}
+	res.Body.Close()
+}
+
+func TestHTTP3FallbackWhenUnreachable(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+
+	// Find a UDP port with nothing listening on it.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := pc.LocalAddr().(*net.UDPAddr)
+	pc.Close()
+
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Alt-Svc", fmt.Sprintf(`h3=":%d"`, deadAddr.Port))
+		io.WriteString(w, r.Proto)
+	}))
+	ts.StartTLS()
+	defer ts.Close()
+	c := ts.Client()
+	tr := c.Transport.(*Transport)
+	tr.EnableHTTP3 = true
+	tr.TLSHandshakeTimeout = 100 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatalf("request %v: %v", i, err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.ProtoMajor == 3 || string(body) == "HTTP/3.0" {
+			t.Fatalf("request %v used %v, want TCP", i, res.Proto)
+		}
+	}
+}
+
+func TestHTTP3Shutdown(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	inHandler := make(chan struct{})
+	unblock := make(chan struct{})
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/alt-svc" {
+			return
+		}
+		close(inHandler)
+		<-unblock
+		io.WriteString(w, "done")
+	}))
+	defer ts.Close()
+
+	type result struct {
+		res *Response
+		err error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		res, err := c.Get(ts.URL + "/slow")
+		resc <- result{res, err}
+	}()
+	<-inHandler
+
+	shutdownc := make(chan error, 1)
+	go func() {
+		shutdownc <- ts.Config.Shutdown(context.Background())
+	}()
+	select {
+	case err := <-shutdownc:
+		t.Fatalf("Shutdown returned %v with a request in progress", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+
+	r := <-resc
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	body, err := ioutil.ReadAll(r.res.Body)
+	r.res.Body.Close()
+	if err != nil || string(body) != "done" || r.res.ProtoMajor != 3 {
+		t.Errorf("response = %v %q, %v; want HTTP/3.0 \"done\"", r.res.Proto, body, err)
+	}
+	if err := <-shutdownc; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+}
+
+func TestHTTP3ResponseControllerReadDeadline(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/alt-svc" {
+			return
+		}
+		ctl := NewResponseController(w)
+		if err := ctl.EnableFullDuplex(); err != nil {
+			t.Errorf("ctl.EnableFullDuplex() = %v, want nil", err)
+		}
+		if err := ctl.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+			t.Errorf("ctl.SetReadDeadline() = %v, want nil", err)
+		}
+		_, err := io.Copy(ioutil.Discard, r.Body)
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Errorf("reading body past deadline: %v, want timeout", err)
+		}
+		io.WriteString(w, "timed out")
+	}))
+	defer ts.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	res, err := c.Post(ts.URL+"/slow", "text/plain", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if res.ProtoMajor != 3 || string(body) != "timed out" || err != nil {
+		t.Errorf("response = %v %q, %v; want HTTP/3.0 \"timed out\"", res.Proto, body, err)
+	}
+}
+
+func TestHTTP3InterimResponses(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	ts, c := newHTTP3Server(t, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(StatusEarlyHints)
+		w.Header().Del("Link")
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	var got []string
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			got = append(got, fmt.Sprintf("%d %s", code, header.Get("Link")))
+			return nil
+		},
+	}
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.ProtoMajor != 3 || res.StatusCode != 200 || string(body) != "hello" {
+		t.Errorf("response = %v %v %q; want HTTP/3.0 200 %q", res.Proto, res.StatusCode, body, "hello")
+	}
+	if want := []string{"103 </style.css>; rel=preload; as=style"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Got1xxResponse calls = %q; want %q", got, want)
+	}
+}
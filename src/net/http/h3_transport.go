@@ -0,0 +1,759 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP/3 client, used by Transport when EnableHTTP3 is set.
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptrace"
+	"net/http/internal/quic"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang_org/x/net/http/httpguts"
+)
+
+const http3DefaultUserAgent = "Go-http-client/3"
+
+// http3BrokenDuration is how long the Transport avoids HTTP/3 for an
+// origin after failing to connect to its advertised alternative service.
+const http3BrokenDuration = 5 * time.Minute
+
+// errHTTP3Unavailable is returned by roundTripHTTP3 when the request
+// should be sent over TCP instead.
+var errHTTP3Unavailable = errors.New("http3: not available")
+
+// http3Transport is the HTTP/3 state of a Transport.
+type http3Transport struct {
+	mu       sync.Mutex
+	endpoint *quic.Endpoint              // created on first use
+	altSvc   map[string]http3AltSvc      // keyed by origin host:port
+	conns    map[string]*http3ClientConn // keyed by origin host:port
+	dialing  map[string]*http3DialCall   // keyed by origin host:port
+}
+
+// http3AltSvc is an HTTP/3 alternative service advertised by an origin.
+type http3AltSvc struct {
+	addr    string    // host:port to connect to
+	expires time.Time // when the advertisement expires
+	broken  time.Time // if in the future, don't try HTTP/3 before then
+}
+
+// http3DialCall is an in-progress HTTP/3 connection attempt.
+type http3DialCall struct {
+	done chan struct{} // closed when the dial completes
+	cc   *http3ClientConn
+	err  error
+}
+
+// roundTripHTTP3 sends req over HTTP/3, if its origin has advertised
+// support for it. It returns errHTTP3Unavailable if the request should
+// be sent over TCP instead.
+func (t *Transport) roundTripHTTP3(req *Request) (*Response, error) {
+	if t.Proxy != nil {
+		if u, err := t.Proxy(req); err != nil || u != nil {
+			return nil, errHTTP3Unavailable
+		}
+	}
+	authority := canonicalAddr(req.URL)
+	cc, err := t.h3.getConn(t, req, authority)
+	if err != nil {
+		return nil, err
+	}
+	if cc == nil {
+		return nil, errHTTP3Unavailable
+	}
+	resp, err := cc.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.h3.recordAltSvc(authority, resp.Header)
+	return resp, nil
+}
+
+// getConn returns a connection to the HTTP/3 alternative service for
+// authority, or nil if there is none.
+func (h *http3Transport) getConn(t *Transport, req *Request, authority string) (*http3ClientConn, error) {
+	h.mu.Lock()
+	if cc := h.conns[authority]; cc != nil && cc.canTakeNewRequest() {
+		h.mu.Unlock()
+		return cc, nil
+	}
+	alt, ok := h.altSvc[authority]
+	now := time.Now()
+	if !ok || now.After(alt.expires) || now.Before(alt.broken) {
+		h.mu.Unlock()
+		return nil, nil
+	}
+	call := h.dialing[authority]
+	if call == nil {
+		call = &http3DialCall{done: make(chan struct{})}
+		if h.dialing == nil {
+			h.dialing = make(map[string]*http3DialCall)
+		}
+		h.dialing[authority] = call
+		go h.dial(t, call, authority, alt.addr)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	if call.err != nil {
+		// Fall back to TCP.
+		return nil, nil
+	}
+	return call.cc, nil
+}
+
+// dial connects to the HTTP/3 alternative service for authority.
+// The dial is not tied to the context of any one request.
+func (h *http3Transport) dial(t *Transport, call *http3DialCall, authority, addr string) {
+	defer close(call.done)
+	call.cc, call.err = h.dialConn(t, authority, addr)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.dialing, authority)
+	if call.err != nil {
+		if alt, ok := h.altSvc[authority]; ok && alt.addr == addr {
+			alt.broken = time.Now().Add(http3BrokenDuration)
+			h.altSvc[authority] = alt
+		}
+		return
+	}
+	if h.conns == nil {
+		h.conns = make(map[string]*http3ClientConn)
+	}
+	h.conns[authority] = call.cc
+}
+
+func (h *http3Transport) dialConn(t *Transport, authority, addr string) (*http3ClientConn, error) {
+	h.mu.Lock()
+	if h.endpoint == nil {
+		e, err := quic.Listen("udp", ":0", nil)
+		if err != nil {
+			h.mu.Unlock()
+			return nil, err
+		}
+		h.endpoint = e
+	}
+	e := h.endpoint
+	h.mu.Unlock()
+
+	host, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		return nil, err
+	}
+	config := cloneTLSConfig(t.TLSClientConfig)
+	config.NextProtos = []string{http3NextProto}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	qconfig := &quic.Config{
+		TLSConfig:        config,
+		HandshakeTimeout: t.TLSHandshakeTimeout,
+	}
+	if t.IdleConnTimeout > 0 {
+		qconfig.MaxIdleTimeout = t.IdleConnTimeout
+	}
+	qconn, err := e.Dial(context.Background(), "udp", addr, qconfig)
+	if err != nil {
+		return nil, err
+	}
+	cc := &http3ClientConn{
+		http3Conn: http3Conn{
+			qconn:          qconn,
+			maxHeaderBytes: t.maxHTTP3HeaderResponseSize(),
+		},
+		t:         t,
+		authority: authority,
+		tlsState:  qconn.ConnectionState(),
+	}
+	if _, err := cc.openControlStream(context.Background()); err != nil {
+		qconn.Abort(nil)
+		return nil, err
+	}
+	go cc.acceptStreams()
+	return cc, nil
+}
+
+func (t *Transport) maxHTTP3HeaderResponseSize() int64 {
+	if v := t.MaxResponseHeaderBytes; v != 0 {
+		return v
+	}
+	return 10 << 20 // conservative default; same as http2
+}
+
+// removeConn forgets cc, after it has closed or received a GOAWAY.
+func (h *http3Transport) removeConn(cc *http3ClientConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conns[cc.authority] == cc {
+		delete(h.conns, cc.authority)
+	}
+}
+
+// closeIdleConnections closes HTTP/3 connections with no requests in
+// progress. Once no connections remain, the QUIC endpoint is closed.
+func (h *http3Transport) closeIdleConnections() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for authority, cc := range h.conns {
+		if cc.closeIfIdle() {
+			delete(h.conns, authority)
+		}
+	}
+	if len(h.conns) == 0 && len(h.dialing) == 0 && h.endpoint != nil {
+		h.endpoint.Close()
+		h.endpoint = nil
+	}
+}
+
+// recordAltSvc updates the alternative services known for authority
+// from the Alt-Svc header of a response.
+func (h *http3Transport) recordAltSvc(authority string, header Header) {
+	vv := header["Alt-Svc"]
+	if len(vv) == 0 {
+		return
+	}
+	host, _, err := net.SplitHostPort(authority)
+	if err != nil {
+		return
+	}
+	addr, maxAge, clear := http3ParseAltSvc(strings.Join(vv, ","), host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if clear {
+		delete(h.altSvc, authority)
+		return
+	}
+	if addr == "" {
+		return
+	}
+	if h.altSvc == nil {
+		h.altSvc = make(map[string]http3AltSvc)
+	}
+	alt := h.altSvc[authority]
+	if alt.addr != addr {
+		alt = http3AltSvc{addr: addr}
+	}
+	alt.expires = time.Now().Add(maxAge)
+	h.altSvc[authority] = alt
+}
+
+// http3ParseAltSvc parses an Alt-Svc header value (RFC 7838, Section 3),
+// returning the address and lifetime of the first HTTP/3 alternative.
+// An empty host in an alternative refers to originHost.
+// It reports clear if the value is "clear".
+func http3ParseAltSvc(v, originHost string) (addr string, maxAge time.Duration, clear bool) {
+	v = textproto.TrimString(v)
+	if v == "clear" {
+		return "", 0, true
+	}
+	for v != "" {
+		// alternative = protocol-id "=" alt-authority *( OWS ";" OWS parameter )
+		var alt string
+		alt, v = http3NextAltSvc(v)
+		i := strings.IndexByte(alt, '=')
+		if i < 0 || textproto.TrimString(alt[:i]) != http3NextProto {
+			continue
+		}
+		alt = textproto.TrimString(alt[i+1:])
+		if len(alt) < 2 || alt[0] != '"' {
+			continue
+		}
+		j := strings.IndexByte(alt[1:], '"')
+		if j < 0 {
+			continue
+		}
+		host, port, err := net.SplitHostPort(alt[1 : j+1])
+		if err != nil {
+			continue
+		}
+		if host == "" {
+			host = originHost
+		}
+		maxAge = 24 * time.Hour
+		for _, param := range strings.Split(alt[j+2:], ";") {
+			param = textproto.TrimString(param)
+			if !strings.HasPrefix(param, "ma=") {
+				continue
+			}
+			if secs, err := strconv.ParseUint(strings.Trim(param[len("ma="):], `"`), 10, 32); err == nil {
+				maxAge = time.Duration(secs) * time.Second
+			}
+		}
+		return net.JoinHostPort(host, port), maxAge, false
+	}
+	return "", 0, false
+}
+
+// http3NextAltSvc splits the first alternative from a list of
+// comma-separated alternatives, ignoring commas in quoted strings.
+func http3NextAltSvc(v string) (alt, rest string) {
+	quoted := false
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			if quoted {
+				i++
+			}
+		case ',':
+			if !quoted {
+				return v[:i], v[i+1:]
+			}
+		}
+	}
+	return v, ""
+}
+
+// http3ClientConn is a client-side HTTP/3 connection.
+type http3ClientConn struct {
+	http3Conn
+	t         *Transport
+	authority string
+	tlsState  tls.ConnectionState
+
+	// Guarded by http3Conn.mu.
+	active int // requests in progress
+	goaway bool
+	closed bool
+}
+
+// acceptStreams handles streams opened by the server, until the
+// connection closes.
+func (cc *http3ClientConn) acceptStreams() {
+	defer func() {
+		cc.mu.Lock()
+		cc.closed = true
+		cc.mu.Unlock()
+		cc.t.h3.removeConn(cc)
+	}()
+	for {
+		st, err := cc.qconn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		if !st.IsReadOnly() {
+			// Servers may not open bidirectional streams.
+			// https://www.rfc-editor.org/rfc/rfc9114#section-6.1-3
+			cc.abort(http3ErrStreamCreation)
+			return
+		}
+		go cc.handleUniStream(st, func(uint64) {
+			cc.mu.Lock()
+			cc.goaway = true
+			cc.mu.Unlock()
+			cc.t.h3.removeConn(cc)
+		})
+	}
+}
+
+func (cc *http3ClientConn) canTakeNewRequest() bool {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return !cc.goaway && !cc.closed
+}
+
+// closeIfIdle closes the connection if no requests are in progress,
+// and reports whether it did so.
+func (cc *http3ClientConn) closeIfIdle() bool {
+	cc.mu.Lock()
+	idle := cc.active == 0
+	if idle {
+		cc.closed = true
+	}
+	cc.mu.Unlock()
+	if idle {
+		cc.abort(http3ErrNo)
+	}
+	return idle
+}
+
+func (cc *http3ClientConn) roundTrip(req *Request) (*Response, error) {
+	ctx := req.Context()
+	header, err := cc.encodeRequestHeader(req)
+	if err != nil {
+		req.closeBody()
+		return nil, err
+	}
+
+	cc.mu.Lock()
+	if cc.goaway || cc.closed {
+		cc.mu.Unlock()
+		return nil, errHTTP3Unavailable
+	}
+	cc.active++
+	cc.mu.Unlock()
+
+	st, err := cc.qconn.NewStream(ctx)
+	if err != nil {
+		cc.requestDone()
+		if ctx.Err() != nil {
+			req.closeBody()
+			return nil, ctx.Err()
+		}
+		// The connection closed. Send the request over TCP.
+		return nil, errHTTP3Unavailable
+	}
+	cs := &http3ClientStream{
+		cc:    cc,
+		st:    st,
+		req:   req,
+		donec: make(chan struct{}),
+	}
+	go cs.watchCancel(ctx)
+
+	cs.writeFrame(http3FrameHeaders, header)
+	if http2actualContentLength(req) != 0 {
+		go cs.writeBody()
+	} else {
+		req.closeBody()
+		st.Close()
+	}
+
+	resp, err := cs.readResponse()
+	if err != nil {
+		cs.abort()
+		if berr := cs.bodyError(); berr != nil {
+			return nil, berr
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if rerr, ok := err.(http3ResetError); ok && rerr.code == http3ErrRequestRejected && req.outgoingLength() == 0 {
+			// The server did not process the request, and
+			// it has no body, so it is safe to send again.
+			// https://www.rfc-editor.org/rfc/rfc9114#section-4.1.1-6
+			return nil, errHTTP3Unavailable
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (cc *http3ClientConn) requestDone() {
+	cc.mu.Lock()
+	cc.active--
+	cc.mu.Unlock()
+}
+
+// encodeRequestHeader returns the payload of the HEADERS frame for req.
+func (cc *http3ClientConn) encodeRequestHeader(req *Request) ([]byte, error) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	host, err := httpguts.PunycodeHostPort(host)
+	if err != nil {
+		return nil, err
+	}
+	if v := req.Header.Get("Upgrade"); v != "" {
+		return nil, fmt.Errorf("http3: invalid Upgrade request header: %q", req.Header["Upgrade"])
+	}
+	if vv := req.Header["Transfer-Encoding"]; len(vv) > 0 && (len(vv) > 1 || vv[0] != "" && vv[0] != "chunked") {
+		return nil, fmt.Errorf("http3: invalid Transfer-Encoding request header: %q", vv)
+	}
+	if vv := req.Header["Connection"]; len(vv) > 0 && (len(vv) > 1 || vv[0] != "" && vv[0] != "close" && vv[0] != "keep-alive") {
+		return nil, fmt.Errorf("http3: invalid Connection request header: %q", vv)
+	}
+	trailers, err := http2commaSeparatedTrailers(req)
+	if err != nil {
+		return nil, err
+	}
+
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	b := http3AppendFieldSectionPrefix(nil)
+	b = http3AppendField(b, ":method", method)
+	b = http3AppendField(b, ":authority", host)
+	if method != "CONNECT" {
+		path := req.URL.RequestURI()
+		if !http2validPseudoPath(path) {
+			return nil, fmt.Errorf("invalid request :path %q", path)
+		}
+		b = http3AppendField(b, ":scheme", req.URL.Scheme)
+		b = http3AppendField(b, ":path", path)
+	}
+	b = http3AppendHeader(b, req.Header, func(k string) bool {
+		switch CanonicalHeaderKey(k) {
+		case "Host", "Content-Length", "Trailer":
+			return false
+		}
+		return true
+	})
+	if trailers != "" {
+		b = http3AppendField(b, "trailer", trailers)
+	}
+	if cl := http2actualContentLength(req); http2shouldSendReqContentLength(method, cl) {
+		b = http3AppendField(b, "content-length", strconv.FormatInt(cl, 10))
+	}
+	if cc.requestedGzip(req) {
+		b = http3AppendField(b, "accept-encoding", "gzip")
+	}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		b = http3AppendField(b, "user-agent", http3DefaultUserAgent)
+	}
+	return b, nil
+}
+
+// requestedGzip reports whether the Transport adds "Accept-Encoding: gzip"
+// to req, and transparently decompresses the response.
+func (cc *http3ClientConn) requestedGzip(req *Request) bool {
+	return !cc.t.DisableCompression &&
+		req.Header.Get("Accept-Encoding") == "" &&
+		req.Header.Get("Range") == "" &&
+		req.Method != "HEAD"
+}
+
+// http3ClientStream is the state of a single request.
+type http3ClientStream struct {
+	cc  *http3ClientConn
+	st  *quic.Stream
+	req *Request
+
+	donec    chan struct{} // closed when the request is complete
+	doneOnce sync.Once
+
+	mu      sync.Mutex
+	bodyErr error // error reading the request body
+}
+
+// watchCancel aborts the request if ctx is done before it completes.
+func (cs *http3ClientStream) watchCancel(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		cs.abort()
+	case <-cs.donec:
+	}
+}
+
+// abort cancels the request, resetting its stream.
+func (cs *http3ClientStream) abort() {
+	cs.st.StopSending(uint64(http3ErrRequestCancelled))
+	cs.st.Reset(uint64(http3ErrRequestCancelled))
+	cs.done()
+}
+
+// done marks the request complete.
+func (cs *http3ClientStream) done() {
+	cs.doneOnce.Do(func() {
+		close(cs.donec)
+		cs.cc.requestDone()
+	})
+}
+
+func (cs *http3ClientStream) bodyError() error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.bodyErr
+}
+
+func (cs *http3ClientStream) writeFrame(ftype uint64, payload []byte) error {
+	b := http3AppendFrameHeader(make([]byte, 0, 16+len(payload)), ftype, len(payload))
+	b = append(b, payload...)
+	_, err := cs.st.Write(b)
+	return err
+}
+
+// writeBody sends the request body and trailers.
+func (cs *http3ClientStream) writeBody() {
+	req := cs.req
+	defer req.closeBody()
+	buf := make([]byte, 16<<10)
+	for {
+		n, err := req.Body.Read(buf)
+		if n > 0 {
+			if werr := cs.writeFrame(http3FrameData, buf[:n]); werr != nil {
+				// The server may stop reading the body
+				// once it has sent its response.
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			cs.mu.Lock()
+			cs.bodyErr = err
+			cs.mu.Unlock()
+			cs.abort()
+			return
+		}
+	}
+	if len(req.Trailer) > 0 {
+		b := http3AppendFieldSectionPrefix(nil)
+		b = http3AppendHeader(b, req.Trailer, nil)
+		if err := cs.writeFrame(http3FrameHeaders, b); err != nil {
+			return
+		}
+	}
+	cs.st.Close()
+}
+
+// readResponse reads the response header.
+func (cs *http3ClientStream) readResponse() (*Response, error) {
+	cc := cs.cc
+	fr := newHTTP3FrameReader(cs.st)
+	var (
+		header Header
+		status int
+		num1xx int // number of informational 1xx headers received
+	)
+	const max1xxResponses = 5 // same as HTTP/1 and HTTP/2
+	trace := httptrace.ContextClientTrace(cs.req.Context())
+	for header == nil {
+		ftype, err := fr.readFrameHeader()
+		if err == io.EOF {
+			err = errors.New("http3: server closed stream without sending a response")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch ftype {
+		case http3FrameHeaders:
+		case http3FramePushPromise:
+			// We never send MAX_PUSH_ID, so the server may not push.
+			cc.abort(http3ErrID)
+			return nil, http3ErrID
+		case http3FrameData, http3FrameCancelPush, http3FrameSettings, http3FrameGoaway, http3FrameMaxPushID:
+			cc.abort(http3ErrFrameUnexpected)
+			return nil, http3ErrFrameUnexpected
+		default:
+			if http3IsReservedFrame(ftype) {
+				cc.abort(http3ErrFrameUnexpected)
+				return nil, http3ErrFrameUnexpected
+			}
+			// Unknown frame types are ignored.
+			continue
+		}
+		payload, err := fr.readPayload(cc.maxHeaderBytes)
+		if err != nil {
+			if err == errHTTP3HeaderTooLarge {
+				err = fmt.Errorf("http3: response header exceeds MaxResponseHeaderBytes (%d)", cc.maxHeaderBytes)
+			}
+			return nil, err
+		}
+		status = 0
+		h, err := http3DecodeHeader(payload, cc.maxHeaderBytes, func(name, value string) error {
+			if name != ":status" || status != 0 {
+				return http3ErrMessage
+			}
+			code, err := strconv.Atoi(value)
+			if err != nil || len(value) != 3 || code < 100 {
+				return http3ErrMessage
+			}
+			status = code
+			return nil
+		})
+		if err == nil && status == 0 {
+			err = http3ErrMessage
+		}
+		if err != nil {
+			cc.streamError(cs.st, err)
+			return nil, err
+		}
+		if status == StatusSwitchingProtocols {
+			// https://www.rfc-editor.org/rfc/rfc9114#section-4.5
+			cc.streamError(cs.st, http3ErrMessage)
+			return nil, http3ErrMessage
+		}
+		if status >= 200 {
+			header = h
+			break
+		}
+		num1xx++
+		if num1xx > max1xxResponses {
+			cc.streamError(cs.st, http3ErrExcessiveLoad)
+			return nil, errors.New("http3: too many 1xx informational responses")
+		}
+		if trace != nil && trace.Got1xxResponse != nil {
+			if err := trace.Got1xxResponse(status, textproto.MIMEHeader(h)); err != nil {
+				return nil, err
+			}
+		}
+		if status == StatusContinue && trace != nil && trace.Got100Continue != nil {
+			trace.Got100Continue()
+		}
+	}
+
+	resp := &Response{
+		Status:     strconv.Itoa(status) + " " + StatusText(status),
+		StatusCode: status,
+		Proto:      "HTTP/3.0",
+		ProtoMajor: 3,
+		ProtoMinor: 0,
+		Header:     header,
+		Request:    cs.req,
+		TLS:        &cc.tlsState,
+	}
+	resp.ContentLength = -1
+	if vv := header["Content-Length"]; len(vv) == 1 {
+		if n, err := strconv.ParseInt(vv[0], 10, 64); err == nil && n >= 0 {
+			resp.ContentLength = n
+		}
+	}
+	for _, v := range header["Trailer"] {
+		foreachHeaderElement(v, func(key string) {
+			if resp.Trailer == nil {
+				resp.Trailer = make(Header)
+			}
+			resp.Trailer[CanonicalHeaderKey(key)] = nil
+		})
+	}
+
+	if cs.req.Method == "HEAD" || status == StatusNoContent || status == StatusNotModified {
+		if cs.req.Method != "HEAD" {
+			resp.ContentLength = 0
+		}
+		resp.Body = NoBody
+		cs.st.StopSending(uint64(http3ErrNo))
+		cs.done()
+		return resp, nil
+	}
+
+	body := &http3Body{
+		conn:      &cc.http3Conn,
+		st:        cs.st,
+		fr:        fr,
+		remain:    resp.ContentLength,
+		trailer:   &resp.Trailer,
+		closeCode: http3ErrRequestCancelled,
+		ctx:       cs.req.Context(),
+		onDone: func(err error) {
+			if err != io.EOF {
+				// The response body was abandoned, so the
+				// request body need not be sent either.
+				cs.st.Reset(uint64(http3ErrRequestCancelled))
+			}
+			cs.done()
+		},
+	}
+	resp.Body = body
+	if cc.requestedGzip(cs.req) && header.get("Content-Encoding") == "gzip" {
+		header.Del("Content-Encoding")
+		header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Body = &http2gzipReader{body: body}
+		resp.Uncompressed = true
+	}
+	return resp, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"net/http/internal"
+	"sync"
+	"time"
+)
+
+// A Clock is a fake clock for a Server whose Listener is a
+// PipeListener. Its time moves only when Advance is called, so tests
+// can trigger server and transport timeouts without waiting for them.
+// See Server.Clock.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer // pending, in no particular order
+}
+
+// NewClock returns a new Clock whose current time is start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d. The timers that expire on the
+// way fire in order of their expiry, with the clock set to the time
+// each one expires; functions scheduled with AfterFunc run before
+// Advance returns.
+func (c *Clock) Advance(d time.Duration) {
+	if d < 0 {
+		panic("httptest: Clock.Advance with negative duration")
+	}
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		t := c.nextLocked(end)
+		if t == nil {
+			break
+		}
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		now := c.now
+		c.mu.Unlock()
+		t.fire(now)
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// nextLocked removes and returns the earliest timer that expires no
+// later than end, or returns nil if there is none.
+func (c *Clock) nextLocked(end time.Time) *clockTimer {
+	var next *clockTimer
+	for _, t := range c.timers {
+		if !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+			next = t
+		}
+	}
+	if next != nil {
+		c.removeLocked(next)
+	}
+	return next
+}
+
+// pending returns the number of timers waiting to fire.
+func (c *Clock) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// afterFunc is like time.AfterFunc. If d is positive, f is called
+// by the Advance that reaches the timer's expiry.
+func (c *Clock) afterFunc(d time.Duration, f func()) *clockTimer {
+	t := &clockTimer{c: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+// newTimer is like time.NewTimer.
+func (c *Clock) newTimer(d time.Duration) *clockTimer {
+	t := &clockTimer{c: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (c *Clock) removeLocked(t *clockTimer) bool {
+	for i, t1 := range c.timers {
+		if t1 == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// A clockTimer is a timer on a Clock. Exactly one of f and ch is set.
+type clockTimer struct {
+	c    *Clock
+	when time.Time
+	f    func()
+	ch   chan time.Time
+}
+
+// Stop prevents the timer from firing. It reports whether the timer
+// was pending.
+func (t *clockTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	return t.c.removeLocked(t)
+}
+
+// Reset changes the timer to expire after d. It reports whether the
+// timer was pending.
+func (t *clockTimer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	active := t.c.removeLocked(t)
+	t.when = t.c.now.Add(d)
+	now := t.c.now
+	if d > 0 {
+		t.c.timers = append(t.c.timers, t)
+	}
+	t.c.mu.Unlock()
+	if d <= 0 {
+		// Expired already. The caller may hold locks that f
+		// needs, so don't run it here.
+		if t.f != nil {
+			go t.f()
+		} else {
+			t.fire(now)
+		}
+	}
+	return active
+}
+
+func (t *clockTimer) fire(now time.Time) {
+	if t.f != nil {
+		t.f()
+		return
+	}
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
+
+// clockHooks adapts a Clock to the clock used by net/http.
+type clockHooks struct {
+	c *Clock
+}
+
+func (h clockHooks) Now() time.Time { return h.c.Now() }
+
+func (h clockHooks) AfterFunc(d time.Duration, f func()) internal.Timer {
+	return h.c.afterFunc(d, f)
+}
+
+func (h clockHooks) NewTimer(d time.Duration) (internal.Timer, <-chan time.Time) {
+	t := h.c.newTimer(d)
+	return t, t.ch
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+
+	var fired []string
+	at := func(name string) func() {
+		return func() {
+			fired = append(fired, name+"@"+c.Now().Sub(start).String())
+		}
+	}
+	c.afterFunc(3*time.Second, at("c"))
+	c.afterFunc(1*time.Second, at("a"))
+	b := c.afterFunc(2*time.Second, at("b"))
+	stopped := c.afterFunc(2*time.Second, at("stopped"))
+	if !stopped.Stop() {
+		t.Errorf("Stop of pending timer = false, want true")
+	}
+	tm := c.newTimer(5 * time.Second)
+
+	c.Advance(2500 * time.Millisecond)
+	if want := []string{"a@1s", "b@2s"}; !reflect.DeepEqual(fired, want) {
+		t.Errorf("after 2.5s, fired %v; want %v", fired, want)
+	}
+	if got, want := c.Now(), start.Add(2500*time.Millisecond); !got.Equal(want) {
+		t.Errorf("Now() = %v, want %v", got, want)
+	}
+	if b.Reset(time.Second) {
+		t.Errorf("Reset of fired timer = true, want false")
+	}
+
+	fired = nil
+	c.Advance(time.Second)
+	if want := []string{"c@3s", "b@3.5s"}; !reflect.DeepEqual(fired, want) {
+		t.Errorf("after 3.5s, fired %v; want %v", fired, want)
+	}
+	select {
+	case <-tm.ch:
+		t.Fatal("timer fired early")
+	default:
+	}
+	c.Advance(2 * time.Second)
+	select {
+	case now := <-tm.ch:
+		if want := start.Add(5 * time.Second); !now.Equal(want) {
+			t.Errorf("timer sent %v, want %v", now, want)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+	if n := c.pending(); n != 0 {
+		t.Errorf("%v timers pending, want 0", n)
+	}
+}
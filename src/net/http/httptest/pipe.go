@@ -0,0 +1,195 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A PipeListener is a net.Listener whose connections are in-memory
+// pipes, created with net.Pipe, rather than network sockets.
+// Connections are made to it with its Dial and DialContext methods.
+//
+// A Server whose Listener is a PipeListener runs without any sockets,
+// and its Client is configured to dial the PipeListener.
+//
+// Pipes support deadlines, so server and transport timeouts work as
+// they do over the network; with a Server's Clock, deadlines are
+// times on the Clock instead of the wall clock. Writes to a pipe block until the peer
+// reads the data; nothing is buffered.
+type PipeListener struct {
+	addr      pipeAddr
+	connc     chan net.Conn
+	closec    chan struct{}
+	closeOnce sync.Once
+
+	// clock, if non-nil, is the clock the deadlines of the
+	// listener's connections are measured by. See Server.Clock.
+	clock *Clock
+}
+
+// NewPipeListener returns a new PipeListener.
+func NewPipeListener() *PipeListener {
+	return &PipeListener{
+		addr:   newPipeAddr(),
+		connc:  make(chan net.Conn),
+		closec: make(chan struct{}),
+	}
+}
+
+var errPipeListenerClosed = errors.New("httptest: use of closed PipeListener")
+
+// Accept waits for and returns the next connection dialed to l.
+func (l *PipeListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.connc:
+		return c, nil
+	case <-l.closec:
+		return nil, &net.OpError{Op: "accept", Net: l.addr.Network(), Addr: l.addr, Err: errPipeListenerClosed}
+	}
+}
+
+// Close closes the listener. Connections which were already
+// accepted are not closed.
+func (l *PipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closec) })
+	return nil
+}
+
+// Addr returns the listener's address. It is a placeholder loopback
+// address with a port number unique within the process, which no
+// network socket is bound to.
+func (l *PipeListener) Addr() net.Addr {
+	return l.addr
+}
+
+// Dial connects to the listener.
+// It is equivalent to DialContext with a background context.
+func (l *PipeListener) Dial(network, address string) (net.Conn, error) {
+	return l.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the listener, waiting until the connection
+// is accepted or ctx is done. The address must be the listener's
+// address, as returned by l.Addr().String(); dialing any other
+// address fails as if the connection were refused.
+func (l *PipeListener) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if address != l.addr.String() {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused: not the PipeListener address " + l.addr.String())}
+	}
+	c1, c2 := net.Pipe()
+	caddr := newPipeAddr()
+	client := &pipeConn{Conn: c1, local: caddr, remote: l.addr, clock: l.clock}
+	server := &pipeConn{Conn: c2, local: l.addr, remote: caddr, clock: l.clock}
+	select {
+	case l.connc <- server:
+		return client, nil
+	case <-l.closec:
+		c1.Close()
+		c2.Close()
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: l.addr, Err: errors.New("connection refused")}
+	case <-ctx.Done():
+		c1.Close()
+		c2.Close()
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: l.addr, Err: ctx.Err()}
+	}
+}
+
+// pipeConn is one end of a pipe, with distinct local and remote
+// addresses so that servers can tell their clients apart.
+type pipeConn struct {
+	net.Conn
+	local, remote pipeAddr
+
+	// clock, if non-nil, is the clock deadlines are times on.
+	// The pipe's own deadlines are then only ever cleared, or set
+	// in the past by readTimer and writeTimer when they fire.
+	clock *Clock
+
+	mu                    sync.Mutex // guards readTimer and writeTimer
+	readTimer, writeTimer *clockTimer
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.local }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.remote }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error {
+	if c.clock == nil {
+		return c.Conn.SetReadDeadline(t)
+	}
+	return c.setDeadline(&c.readTimer, t, c.Conn.SetReadDeadline)
+}
+
+func (c *pipeConn) SetWriteDeadline(t time.Time) error {
+	if c.clock == nil {
+		return c.Conn.SetWriteDeadline(t)
+	}
+	return c.setDeadline(&c.writeTimer, t, c.Conn.SetWriteDeadline)
+}
+
+// expiredDeadline is a deadline that has already passed.
+var expiredDeadline = time.Unix(1, 0)
+
+// setDeadline sets a deadline of t on c.clock, expiring the pipe's
+// deadline with set when c.clock reaches t. *timer is the timer for
+// the previous deadline, if any.
+func (c *pipeConn) setDeadline(timer **clockTimer, t time.Time, set func(time.Time) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *timer != nil {
+		(*timer).Stop()
+		*timer = nil
+	}
+	if t.IsZero() {
+		return set(time.Time{})
+	}
+	d := t.Sub(c.clock.Now())
+	if d <= 0 {
+		return set(expiredDeadline)
+	}
+	if err := set(time.Time{}); err != nil {
+		return err
+	}
+	var tm *clockTimer
+	tm = c.clock.afterFunc(d, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// The timer may have been replaced while it fired.
+		if *timer == tm {
+			*timer = nil
+			set(expiredDeadline)
+		}
+	})
+	*timer = tm
+	return nil
+}
+
+// pipeAddr is the address of one end of a pipe.
+// It looks like a loopback TCP address, so that it may be used
+// as the host in URLs and in TLS server names.
+type pipeAddr int
+
+// lastPipePort is the last port number used by newPipeAddr.
+var lastPipePort int32 = 10000
+
+func newPipeAddr() pipeAddr {
+	return pipeAddr(atomic.AddInt32(&lastPipePort, 1))
+}
+
+func (pipeAddr) Network() string  { return "pipe" }
+func (a pipeAddr) String() string { return "127.0.0.1:" + strconv.Itoa(int(a)) }
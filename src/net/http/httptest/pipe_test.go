@@ -0,0 +1,261 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httptest
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newPipeServer(h http.Handler) *Server {
+	return &Server{
+		Listener: NewPipeListener(),
+		Config:   &http.Server{Handler: h},
+	}
+}
+
+func TestPipeServer(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		tls, h2   bool
+		wantProto string
+	}{
+		{"HTTP", false, false, "HTTP/1.1"},
+		{"HTTPS", true, false, "HTTP/1.1"},
+		{"HTTP2", true, true, "HTTP/2.0"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			remotes := make(chan string, 2)
+			ts := newPipeServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				remotes <- r.RemoteAddr
+				w.Write([]byte(r.Proto))
+			}))
+			ts.EnableHTTP2 = tt.h2
+			if tt.tls {
+				ts.StartTLS()
+			} else {
+				ts.Start()
+			}
+			defer ts.Close()
+
+			for i := 0; i < 2; i++ {
+				res, err := ts.Client().Get(ts.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := ioutil.ReadAll(res.Body)
+				res.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.wantProto || res.Proto != tt.wantProto {
+					t.Errorf("request %v: server saw %q, client saw %q; want %q", i, got, res.Proto, tt.wantProto)
+				}
+			}
+			// Both requests used the same connection.
+			if r1, r2 := <-remotes, <-remotes; r1 != r2 {
+				t.Errorf("requests came from %v and %v, want one connection", r1, r2)
+			}
+		})
+	}
+}
+
+func TestPipeServerIdleTimeout(t *testing.T) {
+	closed := make(chan net.Conn, 1)
+	ts := newPipeServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.IdleTimeout = 10 * time.Millisecond
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- c
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	select {
+	case c := <-closed:
+		if got, want := c.LocalAddr().String(), ts.Listener.Addr().String(); got != want {
+			t.Errorf("closed conn has local address %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
+// waitPending waits until n timers are pending on c.
+func waitPending(t *testing.T, c *Clock, n int) {
+	t.Helper()
+	for i := 0; c.pending() != n; i++ {
+		if i == 500 {
+			t.Fatalf("%v timers pending, want %v", c.pending(), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newClockServer(h http.Handler) (*Server, *Clock, chan net.Conn) {
+	closed := make(chan net.Conn, 1)
+	ts := newPipeServer(h)
+	ts.Clock = NewClock(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- c
+		}
+	}
+	return ts, ts.Clock, closed
+}
+
+func TestPipeServerClockIdleTimeout(t *testing.T) {
+	ts, clock, closed := newClockServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.IdleTimeout = time.Minute
+	ts.Start()
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.Header.Get("Date"), "Mon, 01 Jan 2018 00:00:00 GMT"; got != want {
+		t.Errorf("Date = %q, want %q", got, want)
+	}
+
+	// The idle read deadline is the only timer.
+	waitPending(t, clock, 1)
+	clock.Advance(time.Minute - time.Second)
+	select {
+	case <-closed:
+		t.Fatal("connection closed before IdleTimeout")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
+func TestPipeServerClockReadTimeout(t *testing.T) {
+	ts, clock, closed := newClockServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called for an incomplete request")
+	}))
+	ts.Config.ReadTimeout = 5 * time.Second
+	ts.Start()
+	defer ts.Close()
+
+	c, err := ts.Listener.(*PipeListener).Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := io.WriteString(c, "GET / HTTP/1.1\r\nHost: example.com\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitPending(t, clock, 1)
+	clock.Advance(5 * time.Second)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection was not closed after ReadTimeout")
+	}
+	if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read after ReadTimeout: %v, want EOF", err)
+	}
+}
+
+func TestPipeServerClockTransportIdleTimeout(t *testing.T) {
+	remotes := make(chan string, 2)
+	ts, clock, closed := newClockServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remotes <- r.RemoteAddr
+	}))
+	ts.Start()
+	defer ts.Close()
+	ts.Client().Transport.(*http.Transport).IdleConnTimeout = 30 * time.Second
+
+	get := func() {
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	get()
+	// The transport's idle timer is the only timer.
+	waitPending(t, clock, 1)
+	clock.Advance(30 * time.Second)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection was not closed by the transport")
+	}
+	get()
+	if r1, r2 := <-remotes, <-remotes; r1 == r2 {
+		t.Errorf("both requests came from %v, want a new connection after IdleConnTimeout", r1)
+	}
+}
+
+func TestPipeServerClockRequiresPipe(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Start with a Clock and a network listener did not panic")
+		}
+	}()
+	ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Listener.Close()
+	ts.Clock = NewClock(time.Now())
+	ts.Start()
+}
+
+func TestPipeListenerDial(t *testing.T) {
+	l := NewPipeListener()
+	if _, err := l.Dial("tcp", "127.0.0.1:1"); err == nil {
+		t.Errorf("Dial to another address succeeded")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.DialContext(ctx, "tcp", l.Addr().String()); err == nil {
+		t.Errorf("DialContext with no Accept succeeded")
+	}
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		c.Write([]byte("hello"))
+		c.Close()
+	}()
+	c, err := l.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadAll(c); err != nil || string(got) != "hello" {
+		t.Errorf("read %q, %v; want %q", got, err, "hello")
+	}
+	c.Close()
+
+	l.Close()
+	if _, err := l.Accept(); err == nil {
+		t.Errorf("Accept after Close succeeded")
+	}
+	if _, err := l.Dial("tcp", l.Addr().String()); err == nil {
+		t.Errorf("Dial after Close succeeded")
+	}
+}
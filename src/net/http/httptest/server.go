@@ -24,9 +24,33 @@ import (
 // A Server is an HTTP server listening on a system-chosen port on the
 // local loopback interface, for use in end-to-end HTTP tests.
 type Server struct {
-	URL      string // base URL of form http://ipaddr:port with no trailing slash
+	URL string // base URL of form http://ipaddr:port with no trailing slash
+
+	// Listener is the listener the server accepts connections on.
+	// To run a server without network sockets, create the Server
+	// with a PipeListener instead of calling NewUnstartedServer:
+	//
+	//	ts := &httptest.Server{
+	//		Listener: httptest.NewPipeListener(),
+	//		Config:   &http.Server{Handler: h},
+	//	}
+	//	ts.Start()
 	Listener net.Listener
 
+	// Clock, if non-nil, replaces the wall clock for the timeouts
+	// of Config, of the transport used by Client, and of the
+	// deadlines of the connections between them, so that tests
+	// advance time with Clock.Advance instead of waiting.
+	// It requires a PipeListener, and covers HTTP/1.x only:
+	// HTTP/2 connections keep their own timers on the wall clock.
+	// It must be set before calling Start or StartTLS.
+	Clock *Clock
+
+	// EnableHTTP2 controls whether HTTP/2 is enabled
+	// on the server. It must be set between calling
+	// NewUnstartedServer and calling Server.StartTLS.
+	EnableHTTP2 bool
+
 	// TLS is the optional TLS configuration, populated with a new config
 	// after TLS is started. If set on an unstarted server before StartTLS
 	// is called, existing fields are copied into the new config.
@@ -36,6 +60,12 @@ type Server struct {
 	// before Start or StartTLS.
 	Config *http.Server
 
+	// EnableHTTP3 makes StartTLS also serve HTTP/3 on a UDP port,
+	// advertised to clients with an Alt-Svc header. The client
+	// returned by Client uses HTTP/3 once it has been advertised.
+	// HTTP/3 connections are closed immediately by Close.
+	EnableHTTP3 bool
+
 	// certificate is a parsed version of the TLS config certificate, if present.
 	certificate *x509.Certificate
 
@@ -50,6 +80,9 @@ type Server struct {
 	// client is configured for use with the server.
 	// Its transport is automatically closed when Close is called.
 	client *http.Client
+
+	// quicConn is the UDP connection serving HTTP/3, if EnableHTTP3 is set.
+	quicConn net.PacketConn
 }
 
 func newLocalListener() net.Listener {
@@ -104,6 +137,11 @@ func (s *Server) Start() {
 	if s.client == nil {
 		s.client = &http.Client{Transport: &http.Transport{}}
 	}
+	if pl, ok := s.pipeListener(); ok {
+		tr := &http.Transport{}
+		s.usePipe(pl, tr)
+		s.client.Transport = tr
+	}
 	s.URL = "http://" + s.Listener.Addr().String()
 	s.wrap()
 	s.goServe()
@@ -133,7 +171,11 @@ func (s *Server) StartTLS() {
 		s.TLS = new(tls.Config)
 	}
 	if s.TLS.NextProtos == nil {
-		s.TLS.NextProtos = []string{"http/1.1"}
+		nextProtos := []string{"http/1.1"}
+		if s.EnableHTTP2 {
+			nextProtos = []string{"h2"}
+		}
+		s.TLS.NextProtos = nextProtos
 	}
 	if len(s.TLS.Certificates) == 0 {
 		s.TLS.Certificates = []tls.Certificate{cert}
@@ -144,17 +186,49 @@ func (s *Server) StartTLS() {
 	}
 	certpool := x509.NewCertPool()
 	certpool.AddCert(s.certificate)
-	s.client.Transport = &http.Transport{
+	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{
 			RootCAs: certpool,
 		},
+		ForceAttemptHTTP2: s.EnableHTTP2,
+		EnableHTTP3:       s.EnableHTTP3,
 	}
+	if pl, ok := s.pipeListener(); ok {
+		if s.EnableHTTP3 {
+			panic("httptest: EnableHTTP3 requires a network listener, not a PipeListener")
+		}
+		s.usePipe(pl, tr)
+	}
+	s.client.Transport = tr
 	s.Listener = tls.NewListener(s.Listener, s.TLS)
 	s.URL = "https://" + s.Listener.Addr().String()
 	s.wrap()
+	if s.EnableHTTP3 {
+		s.goServeQUIC()
+	}
 	s.goServe()
 }
 
+// pipeListener returns s.Listener if it is a PipeListener.
+func (s *Server) pipeListener() (*PipeListener, bool) {
+	pl, ok := s.Listener.(*PipeListener)
+	if !ok && s.Clock != nil {
+		panic("httptest: Clock requires a PipeListener")
+	}
+	return pl, ok
+}
+
+// usePipe makes tr dial pl, and installs s.Clock, if set,
+// in pl, tr and s.Config.
+func (s *Server) usePipe(pl *PipeListener, tr *http.Transport) {
+	tr.DialContext = pl.DialContext
+	if s.Clock != nil {
+		pl.clock = s.Clock
+		internal.SetClock(s.Config, clockHooks{s.Clock})
+		internal.SetClock(tr, clockHooks{s.Clock})
+	}
+}
+
 // NewTLSServer starts and returns a new Server using TLS.
 // The caller should call Close when finished, to shut it down.
 func NewTLSServer(handler http.Handler) *Server {
@@ -174,6 +248,9 @@ func (s *Server) Close() {
 	if !s.closed {
 		s.closed = true
 		s.Listener.Close()
+		if s.quicConn != nil {
+			s.quicConn.Close()
+		}
 		s.Config.SetKeepAlivesEnabled(false)
 		for c, st := range s.conns {
 			// Force-close any idle connections (those between
@@ -219,6 +296,11 @@ func (s *Server) Close() {
 	}
 
 	s.wg.Wait()
+
+	if s.Clock != nil {
+		internal.SetClock(s.Config, nil)
+		internal.SetClock(s.client.Transport, nil)
+	}
 }
 
 func (s *Server) logCloseHangDebugInfo() {
@@ -281,6 +363,29 @@ func (s *Server) goServe() {
 	}()
 }
 
+// goServeQUIC serves HTTP/3 on a UDP port, preferably the same port
+// as the TCP listener.
+func (s *Server) goServeQUIC() {
+	pc, err := net.ListenPacket("udp", s.Listener.Addr().String())
+	if err != nil {
+		host, _, _ := net.SplitHostPort(s.Listener.Addr().String())
+		pc, err = net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			panic(fmt.Sprintf("httptest: failed to listen on a UDP port: %v", err))
+		}
+	}
+	s.quicConn = pc
+	if s.Config.TLSConfig == nil {
+		// Serve may modify Config.TLSConfig, so don't share s.TLS.
+		s.Config.TLSConfig = s.TLS.Clone()
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.Config.ServeQUIC(pc)
+	}()
+}
+
 // wrap installs the connection state-tracking hook to know which
 // connections are idle.
 func (s *Server) wrap() {
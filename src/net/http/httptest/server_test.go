@@ -202,3 +202,32 @@ func TestServerZeroValueClose(t *testing.T) {
 
 	ts.Close() // tests that it doesn't panic
 }
+
+func TestTLSServerWithHTTP2(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		h2        bool
+		wantProto string
+	}{
+		{"http1", false, "HTTP/1.1"},
+		{"http2", true, "HTTP/2.0"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Proto", r.Proto)
+			}))
+			ts.EnableHTTP2 = tt.h2
+			ts.StartTLS()
+			defer ts.Close()
+
+			res, err := ts.Client().Get(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if got := res.Header.Get("X-Proto"); got != tt.wantProto {
+				t.Errorf("server saw %q, want %q", got, tt.wantProto)
+			}
+		})
+	}
+}
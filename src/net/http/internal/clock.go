@@ -0,0 +1,69 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package internal
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Clock is the source of the current time and of timers for an
+// http.Server or http.Transport. Both use the wall clock unless a
+// Clock is installed with SetClock; net/http/httptest installs a
+// fake one so tests can trigger timeouts without waiting for them.
+type Clock interface {
+	Now() time.Time
+
+	// AfterFunc arranges for f to be called after d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTimer returns a timer that sends the current time on
+	// the returned channel after d has elapsed.
+	NewTimer(d time.Duration) (Timer, <-chan time.Time)
+}
+
+// A Timer is a timer created by a Clock. Its methods behave like
+// those of time.Timer.
+type Timer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// clocks records the clocks installed by SetClock. A registry
+// rather than a field set by package http keeps binaries that only
+// use the client from linking in the server.
+var clocks struct {
+	n  int32 // number of entries in m, for a lock-free check
+	mu sync.Mutex
+	m  map[interface{}]Clock
+}
+
+// SetClock makes v, an *http.Server or *http.Transport, use c for
+// its timeouts, or the wall clock again if c is nil. It must be
+// called before v is used.
+func SetClock(v interface{}, c Clock) {
+	clocks.mu.Lock()
+	defer clocks.mu.Unlock()
+	if c == nil {
+		delete(clocks.m, v)
+	} else {
+		if clocks.m == nil {
+			clocks.m = make(map[interface{}]Clock)
+		}
+		clocks.m[v] = c
+	}
+	atomic.StoreInt32(&clocks.n, int32(len(clocks.m)))
+}
+
+// ClockOf returns the clock installed for v by SetClock, or nil.
+func ClockOf(v interface{}) Clock {
+	if atomic.LoadInt32(&clocks.n) == 0 {
+		return nil
+	}
+	clocks.mu.Lock()
+	defer clocks.mu.Unlock()
+	return clocks.m[v]
+}
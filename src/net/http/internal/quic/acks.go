@@ -0,0 +1,197 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// A numberSpace is a packet number space: Initial, Handshake, or Application Data.
+// https://www.rfc-editor.org/rfc/rfc9000#section-12.3
+type numberSpace byte
+
+const (
+	initialSpace = numberSpace(iota)
+	handshakeSpace
+	appDataSpace
+	numberSpaceCount
+)
+
+func (n numberSpace) String() string {
+	switch n {
+	case initialSpace:
+		return "Initial"
+	case handshakeSpace:
+		return "Handshake"
+	case appDataSpace:
+		return "AppData"
+	default:
+		return "BUG"
+	}
+}
+
+// maxAckDelay is the max_ack_delay this package advertises, which is
+// the default from RFC 9000.
+const maxAckDelay = defaultParamMaxAckDelayMilliseconds * time.Millisecond
+
+// ackState tracks packets received from a peer within a number space.
+// It handles packet deduplication (don't process the same packet twice) and
+// determines the timing and content of ACK frames.
+type ackState struct {
+	seen rangeset
+
+	// The time at which we must send an ACK frame, even if we have no other data to send.
+	nextAck time.Time
+
+	// The time we received the largest-numbered packet in seen.
+	maxRecvTime time.Time
+
+	// The largest-numbered ack-eliciting packet in seen.
+	maxAckEliciting packetNumber
+
+	// The number of ack-eliciting packets in seen that we have not yet acknowledged.
+	unackedAckEliciting int
+
+	// pending is set when packets have been received since the last ACK frame we sent.
+	pending bool
+}
+
+// shouldProcess reports whether a packet should be handled or discarded.
+func (acks *ackState) shouldProcess(num packetNumber) bool {
+	if packetNumber(acks.seen.min()) > num {
+		// We've discarded the state for this range of packet numbers.
+		// Discard the packet rather than potentially processing a duplicate.
+		// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.2.3-5
+		return false
+	}
+	if acks.seen.contains(int64(num)) {
+		// Discard duplicate packets.
+		return false
+	}
+	return true
+}
+
+// largestSeen reports the largest seen packet.
+func (acks *ackState) largestSeen() packetNumber {
+	return packetNumber(acks.seen.max())
+}
+
+// receive records receipt of a packet.
+func (acks *ackState) receive(now time.Time, space numberSpace, num packetNumber, ackEliciting bool) {
+	if ackEliciting {
+		acks.unackedAckEliciting++
+		if acks.mustAckImmediately(space, num) {
+			acks.nextAck = now
+		} else if acks.nextAck.IsZero() {
+			// This packet does not need to be acknowledged immediately,
+			// but the ack must not be intentionally delayed by more than
+			// the max_ack_delay transport parameter we sent to the peer.
+			//
+			// We always delay acks by the maximum allowed, less the timer
+			// granularity. ("[max_ack_delay] SHOULD include the receiver's
+			// expected delays in alarms firing.")
+			//
+			// https://www.rfc-editor.org/rfc/rfc9000#section-18.2-4.28.1
+			acks.nextAck = now.Add(maxAckDelay - timerGranularity)
+		}
+		if num > acks.maxAckEliciting {
+			acks.maxAckEliciting = num
+		}
+	}
+
+	acks.seen.add(int64(num), int64(num+1))
+	if num == acks.largestSeen() {
+		acks.maxRecvTime = now
+	}
+	acks.pending = true
+
+	// Limit the total number of ACK ranges by dropping older ranges.
+	//
+	// Remembering more ranges results in larger ACK frames.
+	//
+	// Remembering a large number of ranges could result in ACK frames becoming
+	// too large to fit in a packet, in which case we will silently drop older
+	// ranges during packet construction.
+	//
+	// Remembering fewer ranges can result in unnecessary retransmissions,
+	// since we cannot accept packets older than the oldest remembered range.
+	//
+	// https://www.rfc-editor.org/rfc/rfc9000#section-13.2.3
+	if len(acks.seen) > maxAckRanges {
+		acks.seen.removeranges(0, len(acks.seen)-maxAckRanges)
+	}
+}
+
+// mustAckImmediately reports whether an ack-eliciting packet must be acknowledged immediately,
+// or whether the ack may be deferred.
+func (acks *ackState) mustAckImmediately(space numberSpace, num packetNumber) bool {
+	// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.2.1
+	if space != appDataSpace {
+		// "[...] all ack-eliciting Initial and Handshake packets [...]"
+		// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.2.1-2
+		return true
+	}
+	if num < acks.maxAckEliciting {
+		// "[...] when the received packet has a packet number less than another
+		// ack-eliciting packet that has been received [...]"
+		// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.2.1-8.1
+		return true
+	}
+	if acks.seen.numRanges() > 0 && num > acks.largestSeen()+1 {
+		// "[...] when the packet has a packet number larger than the highest-numbered
+		// ack-eliciting packet that has been received and there are missing packets
+		// between that packet and this packet."
+		// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.2.1-8.2
+		return true
+	}
+	// "[...] SHOULD send an ACK frame after receiving at least two ack-eliciting packets."
+	// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.2.2
+	return acks.unackedAckEliciting >= 2
+}
+
+// shouldSendAck reports whether the connection should send an ACK frame at this time,
+// in an ACK-only packet if necessary.
+func (acks *ackState) shouldSendAck(now time.Time) bool {
+	return !acks.nextAck.IsZero() && !acks.nextAck.After(now)
+}
+
+// acksToSend returns the set of packet numbers to ACK at this time, and the current ack delay.
+// It may return acks even if shouldSendAck returns false, when there are unacked
+// packets which may be opportunistically acknowledged.
+func (acks *ackState) acksToSend(now time.Time) (nums rangeset, ackDelay time.Duration) {
+	if !acks.pending {
+		return nil, 0
+	}
+	// "[...] the delays intentionally introduced between the time the packet with the
+	// largest packet number is received and the time an acknowledgement is sent."
+	// https://www.rfc-editor.org/rfc/rfc9000#section-13.2.5-1
+	delay := now.Sub(acks.maxRecvTime)
+	if delay < 0 {
+		delay = 0
+	}
+	return acks.seen, delay
+}
+
+// sentAck records that an ACK frame has been sent.
+func (acks *ackState) sentAck() {
+	acks.nextAck = time.Time{}
+	acks.unackedAckEliciting = 0
+	acks.pending = false
+}
+
+// unscaledAckDelayFromDuration returns the ACK Delay field value for d,
+// scaled by the ack_delay_exponent this package advertises.
+func unscaledAckDelayFromDuration(d time.Duration) uint64 {
+	return uint64(d.Nanoseconds()/int64(time.Microsecond)) >> defaultParamAckDelayExponent
+}
+
+// ackDelayFromUnscaled converts a peer's ACK Delay field to a duration.
+// Values are clamped to the largest permitted max_ack_delay, since
+// the ack delay used in RTT estimation never exceeds it.
+func ackDelayFromUnscaled(v uint64, exp int8) time.Duration {
+	const limit = uint64((1 << 14) * time.Millisecond / time.Microsecond)
+	if v > limit>>uint(exp) {
+		return time.Duration(limit) * time.Microsecond
+	}
+	return time.Duration(v<<uint(exp)) * time.Microsecond
+}
@@ -0,0 +1,483 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// A Conn is a QUIC connection.
+//
+// Multiple goroutines may invoke methods on a Conn simultaneously.
+type Conn struct {
+	side     connSide
+	endpoint *Endpoint
+	config   *Config
+	peerAddr net.Addr
+
+	msgc  chan []byte   // datagrams received from the endpoint
+	wakec chan struct{} // wakes the conn loop
+	donec chan struct{} // closed when the conn loop exits
+
+	// readyc is closed when the handshake completes or the connection fails.
+	readyc chan struct{}
+
+	mu sync.Mutex
+
+	// Everything below is guarded by mu.
+
+	// Connection IDs. This package uses a single connection ID for
+	// each side of a connection.
+	origDstConnID []byte // destination ID from the client's first Initial
+	localConnID   []byte // our source connection ID
+	peerConnID    []byte // destination ID for packets we send
+	gotPeerConnID bool   // client: received the server's chosen ID
+
+	tls  *tls.QUICConn
+	keys [numberSpaceCount]fixedKeyPair
+
+	crypto [numberSpaceCount]cryptoStream
+	acks   [numberSpaceCount]ackState
+	loss   lossState
+	w      packetWriter
+
+	handshakeComplete  bool // TLS handshake is complete
+	handshakeConfirmed bool // https://www.rfc-editor.org/rfc/rfc9001#section-4.1.2
+	sendHandshakeDone  bool // server: HANDSHAKE_DONE needs to be sent
+	peerAddrValidated  bool // server: anti-amplification limit no longer applies
+	bytesRecv          int64
+	bytesSent          int64
+
+	localParams   transportParameters
+	peerParams    transportParameters
+	gotPeerParams bool
+
+	streams streamsState
+
+	// Flow control.
+	inflow  connInflow
+	outflow connOutflow
+
+	pathResponses []uint64 // PATH_RESPONSE frames to send
+
+	idleTimeout       time.Duration
+	idleDeadline      time.Time
+	handshakeDeadline time.Time
+	keepAlive         time.Time
+	sendKeepAlive     bool
+
+	// Connection termination.
+	state     connState
+	closeErr  error     // error sent to the peer in CONNECTION_CLOSE
+	finalErr  error     // error returned to users of the conn
+	sendClose bool      // CONNECTION_CLOSE needs to be sent
+	drainEnd  time.Time // end of the closing or draining period
+	exited    bool      // conn loop should exit
+}
+
+type connState int
+
+const (
+	connStateAlive    = connState(iota)
+	connStateClosing  // we sent CONNECTION_CLOSE
+	connStateDraining // the peer sent CONNECTION_CLOSE
+)
+
+// connInflow tracks connection-level flow control for data received from the peer.
+type connInflow struct {
+	max     int64 // MAX_DATA sent to the peer
+	used    int64 // sum of the largest offsets received on all streams
+	credit  int64 // total bytes consumed by the application
+	sendMax bool  // MAX_DATA needs to be sent
+}
+
+// connOutflow tracks connection-level flow control for data sent to the peer.
+type connOutflow struct {
+	max  int64 // MAX_DATA received from the peer
+	sent int64 // total stream bytes sent, excluding retransmissions
+}
+
+func newConn(now time.Time, side connSide, e *Endpoint, config *Config, peerAddr net.Addr, origDstConnID, peerConnID []byte) (*Conn, error) {
+	c := &Conn{
+		side:        side,
+		endpoint:    e,
+		config:      config,
+		peerAddr:    peerAddr,
+		msgc:        make(chan []byte, 256),
+		wakec:       make(chan struct{}, 1),
+		donec:       make(chan struct{}),
+		readyc:      make(chan struct{}),
+		idleTimeout: config.maxIdleTimeout(),
+	}
+	c.localConnID = newRandomConnID()
+	if side == clientSide {
+		origDstConnID = newRandomConnID()
+		peerConnID = origDstConnID
+		c.peerAddrValidated = true
+	}
+	c.origDstConnID = origDstConnID
+	c.peerConnID = peerConnID
+	c.loss.init(side)
+	c.streams.init(c)
+	c.inflow.max = config.maxConnReadBufferSize()
+	if d := config.handshakeTimeout(); d > 0 {
+		c.handshakeDeadline = now.Add(d)
+	}
+	c.resetIdleTimer(now)
+
+	c.localParams = defaultTransportParameters()
+	c.localParams.initialSrcConnID = c.localConnID
+	if side == serverSide {
+		c.localParams.originalDstConnID = origDstConnID
+	}
+	if c.idleTimeout > 0 {
+		c.localParams.maxIdleTimeout = c.idleTimeout
+	}
+	c.localParams.initialMaxData = c.inflow.max
+	c.localParams.initialMaxStreamDataBidiLocal = config.maxStreamReadBufferSize()
+	c.localParams.initialMaxStreamDataBidiRemote = config.maxStreamReadBufferSize()
+	c.localParams.initialMaxStreamDataUni = config.maxStreamReadBufferSize()
+	c.localParams.initialMaxStreamsBidi = c.streams.remoteLimit[bidiStream]
+	c.localParams.initialMaxStreamsUni = c.streams.remoteLimit[uniStream]
+	c.localParams.disableActiveMigration = true
+
+	if err := c.startTLS(now); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func newRandomConnID() []byte {
+	id := make([]byte, connIDLen)
+	if _, err := rand.Read(id); err != nil {
+		panic("quic: unable to generate connection ID: " + err.Error())
+	}
+	return id
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.endpoint.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.peerAddr
+}
+
+// ConnectionState returns basic TLS details about the connection.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tls.ConnectionState()
+}
+
+// waitReady waits for the handshake to complete.
+func (c *Conn) waitReady(ctx context.Context) error {
+	select {
+	case <-c.readyc:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.handshakeComplete {
+		return c.finalErr
+	}
+	return nil
+}
+
+// Close closes the connection.
+//
+// Close sends a CONNECTION_CLOSE frame to the peer with an application
+// error code of 0, and returns without waiting for the peer to respond.
+// Streams which have not completed are aborted.
+func (c *Conn) Close() error {
+	c.Abort(nil)
+	return nil
+}
+
+// Abort closes the connection with an error.
+//
+// If err is an *ApplicationError, its error code and reason are sent to
+// the peer. Otherwise, the application error code 0 is sent.
+func (c *Conn) Abort(err error) {
+	var aerr *ApplicationError
+	if !errors.As(err, &aerr) {
+		aerr = &ApplicationError{}
+	}
+	c.mu.Lock()
+	c.abort(time.Now(), aerr)
+	c.mu.Unlock()
+	c.wake()
+}
+
+// Wait waits for the connection to terminate,
+// returning the error that caused it to close.
+func (c *Conn) Wait(ctx context.Context) error {
+	select {
+	case <-c.donec:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.finalErr
+}
+
+// wake wakes the conn loop.
+func (c *Conn) wake() {
+	select {
+	case c.wakec <- struct{}{}:
+	default:
+	}
+}
+
+// deliver passes a datagram received by the endpoint to the conn.
+func (c *Conn) deliver(b []byte) {
+	select {
+	case c.msgc <- b:
+	default:
+		// The conn is not keeping up; drop the datagram.
+	}
+}
+
+// loop is the connection's main goroutine.
+// It handles received datagrams, timers, and sending.
+func (c *Conn) loop() {
+	defer c.cleanup()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		now := time.Now()
+		c.afterEvent(now)
+		exited := c.exited
+		next := c.nextTimer()
+		c.mu.Unlock()
+		if exited {
+			return
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if !next.IsZero() {
+			timer.Reset(next.Sub(now))
+		}
+		select {
+		case b := <-c.msgc:
+			c.mu.Lock()
+			c.handleDatagram(time.Now(), b)
+			// Process any other queued datagrams before sending.
+		drain:
+			for i := 0; i < cap(c.msgc); i++ {
+				select {
+				case b := <-c.msgc:
+					c.handleDatagram(time.Now(), b)
+				default:
+					break drain
+				}
+			}
+			c.mu.Unlock()
+		case <-timer.C:
+			c.mu.Lock()
+			c.handleTimer(time.Now())
+			c.mu.Unlock()
+		case <-c.wakec:
+		}
+	}
+}
+
+// afterEvent sends any pending packets and updates timers.
+func (c *Conn) afterEvent(now time.Time) {
+	if c.exited {
+		return
+	}
+	c.maybeSend(now)
+	if c.loss.timerDirty {
+		c.loss.setTimer(now, c.side == clientSide && !c.handshakeConfirmed, c.keys[handshakeSpace].canWrite())
+	}
+}
+
+// nextTimer returns the time of the next timer event.
+func (c *Conn) nextTimer() time.Time {
+	var next time.Time
+	add := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	if c.state != connStateAlive {
+		add(c.drainEnd)
+		return next
+	}
+	add(c.idleDeadline)
+	add(c.keepAlive)
+	add(c.loss.timer)
+	if !c.handshakeComplete {
+		add(c.handshakeDeadline)
+	}
+	for space := range c.acks {
+		if c.keys[space].canWrite() {
+			add(c.acks[space].nextAck)
+		}
+	}
+	return next
+}
+
+// handleTimer handles expiring timers.
+func (c *Conn) handleTimer(now time.Time) {
+	if c.state != connStateAlive {
+		if !now.Before(c.drainEnd) {
+			c.exit()
+		}
+		return
+	}
+	if !c.idleDeadline.IsZero() && !now.Before(c.idleDeadline) {
+		// "[...] the connection is silently closed and its state is discarded [...]"
+		// https://www.rfc-editor.org/rfc/rfc9000.html#section-10.1-1
+		c.setFinalErr(errIdleTimeout)
+		c.exit()
+		return
+	}
+	if !c.handshakeComplete && !c.handshakeDeadline.IsZero() && !now.Before(c.handshakeDeadline) {
+		c.abort(now, localTransportError{code: errConnectionRefused, reason: "handshake timeout"})
+		c.setFinalErr(errHandshakeTimeout)
+		return
+	}
+	if !c.keepAlive.IsZero() && !now.Before(c.keepAlive) {
+		c.sendKeepAlive = true
+		c.keepAlive = time.Time{}
+	}
+	if !c.loss.timer.IsZero() && !now.Before(c.loss.timer) {
+		c.loss.timer = time.Time{}
+		space, probe := c.loss.onTimeout(now, c.handleAckOrLoss)
+		if probe {
+			c.queueProbe(space)
+		}
+	}
+}
+
+// resetIdleTimer restarts the idle and keep-alive timers.
+func (c *Conn) resetIdleTimer(now time.Time) {
+	if c.idleTimeout > 0 {
+		c.idleDeadline = now.Add(c.idleTimeout)
+	}
+	if p := c.config.keepAlivePeriod(); p > 0 {
+		if c.idleTimeout > 0 && p > c.idleTimeout/2 {
+			p = c.idleTimeout / 2
+		}
+		c.keepAlive = now.Add(p)
+	}
+}
+
+// queueProbe prepares to send a probe packet in a space after a PTO.
+// Unacknowledged data is queued for retransmission; if there is none,
+// a PING is sent.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-6.2.4
+func (c *Conn) queueProbe(space numberSpace) {
+	for _, sent := range c.loss.spaces[space].sent {
+		if sent.acked || sent.lost || sent.requeued || !sent.ackEliciting {
+			continue
+		}
+		sent.requeued = true
+		c.requeueFrames(space, sent)
+	}
+}
+
+// abort begins closing the connection with an error.
+func (c *Conn) abort(now time.Time, err error) {
+	if c.state != connStateAlive || c.exited {
+		return
+	}
+	c.closeErr = err
+	if _, ok := err.(*ApplicationError); ok {
+		c.setFinalErr(errConnClosed)
+	} else {
+		c.setFinalErr(err)
+	}
+	c.state = connStateClosing
+	c.sendClose = true
+	// "The closing and draining connection states exist to ensure that
+	// connections close cleanly [...] three times the current PTO interval"
+	// https://www.rfc-editor.org/rfc/rfc9000.html#section-10.2-5
+	c.drainEnd = now.Add(3 * c.loss.ptoDuration())
+	c.notifyAll()
+}
+
+// enterDraining enters the draining state after receiving a CONNECTION_CLOSE.
+func (c *Conn) enterDraining(now time.Time, err error) {
+	if c.state == connStateDraining || c.exited {
+		return
+	}
+	c.setFinalErr(err)
+	c.sendClose = false
+	if c.state == connStateAlive {
+		c.drainEnd = now.Add(3 * c.loss.ptoDuration())
+	}
+	c.state = connStateDraining
+	c.notifyAll()
+}
+
+// exitNow closes the connection immediately,
+// sending a CONNECTION_CLOSE if one has not been sent already.
+func (c *Conn) exitNow(err error) {
+	if c.exited {
+		return
+	}
+	now := time.Now()
+	if c.state == connStateAlive {
+		c.abort(now, err)
+	}
+	if c.sendClose {
+		c.sendConnectionClose(now)
+	}
+	c.exit()
+}
+
+// exit causes the conn loop to exit.
+func (c *Conn) exit() {
+	c.setFinalErr(errConnClosed)
+	c.exited = true
+	c.notifyAll()
+	c.wake()
+}
+
+func (c *Conn) setFinalErr(err error) {
+	if c.finalErr == nil {
+		c.finalErr = err
+	}
+}
+
+// notifyAll wakes all goroutines blocked on the conn,
+// after it has begun to close.
+func (c *Conn) notifyAll() {
+	select {
+	case <-c.readyc:
+	default:
+		close(c.readyc)
+	}
+	c.streams.notifyAll()
+}
+
+// cleanup is called when the conn loop exits.
+func (c *Conn) cleanup() {
+	c.mu.Lock()
+	if c.tls != nil {
+		c.tls.Close()
+	}
+	c.mu.Unlock()
+	c.endpoint.connDone(c)
+	close(c.donec)
+}
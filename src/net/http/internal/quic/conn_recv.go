@@ -0,0 +1,382 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"time"
+)
+
+// handleDatagram processes a datagram received from the peer.
+func (c *Conn) handleDatagram(now time.Time, buf []byte) {
+	if c.exited {
+		return
+	}
+	c.bytesRecv += int64(len(buf))
+	for len(buf) > 0 {
+		var n int
+		ptype := getPacketType(buf)
+		switch ptype {
+		case packetTypeInitial:
+			if c.side == serverSide && len(buf) < minimumDatagram {
+				// "[...] a server MUST discard an Initial packet that is carried
+				// in a UDP datagram with a payload that is smaller than
+				// the smallest allowed maximum datagram size of 1200 bytes."
+				// https://www.rfc-editor.org/rfc/rfc9000#section-14.1-4
+				return
+			}
+			n = c.handleLongHeader(now, ptype, initialSpace, buf)
+		case packetTypeHandshake:
+			n = c.handleLongHeader(now, ptype, handshakeSpace, buf)
+		case packetType1RTT:
+			n = c.handle1RTT(now, buf)
+		case packetTypeVersionNegotiation:
+			// Only one version is supported, so there is nothing to negotiate.
+			// A Version Negotiation packet can only be spurious or an attack,
+			// so it is ignored.
+			return
+		default:
+			// 0-RTT and Retry packets are not supported.
+			if !isLongHeader(buf[0]) {
+				return
+			}
+			n = skipLongHeaderPacket(buf)
+		}
+		if n <= 0 {
+			// Invalid data at the end of a datagram is ignored.
+			return
+		}
+		buf = buf[n:]
+	}
+}
+
+// handleLongHeader handles a single Initial or Handshake packet,
+// returning its length.
+func (c *Conn) handleLongHeader(now time.Time, ptype packetType, space numberSpace, buf []byte) int {
+	n := skipLongHeaderPacket(buf)
+	if n < 0 {
+		return -1
+	}
+	if !c.keys[space].canRead() {
+		// We can't decrypt the packet; skip it.
+		return n
+	}
+	pnumMax := c.acks[space].largestSeen()
+	p, n := parseLongHeaderPacket(buf[:n], c.keys[space].r, pnumMax)
+	if n < 0 {
+		return -1
+	}
+	if p.version != quicVersion1 {
+		return n
+	}
+	if c.state != connStateAlive {
+		c.handleClosingPacket()
+		return n
+	}
+	if !c.acks[space].shouldProcess(p.num) {
+		return n
+	}
+	if ptype == packetTypeInitial && c.side == clientSide && !c.gotPeerConnID {
+		// "[...] the client MUST change the Destination Connection ID
+		// it uses for sending packets to the value of the Source Connection ID
+		// field of the first Initial packet received from the server."
+		// https://www.rfc-editor.org/rfc/rfc9000#section-7.2-6
+		c.peerConnID = append([]byte(nil), p.srcConnID...)
+		c.gotPeerConnID = true
+	} else if !bytes.Equal(p.srcConnID, c.peerConnID) {
+		// Packets with an unexpected Source Connection ID are discarded.
+		// https://www.rfc-editor.org/rfc/rfc9000#section-7.2-7
+		return n
+	}
+	if ptype == packetTypeHandshake && c.side == serverSide {
+		// "[...] a server MUST discard Initial keys when it first
+		// successfully processes a Handshake packet."
+		// https://www.rfc-editor.org/rfc/rfc9001#section-4.9.1-2
+		//
+		// Receiving a Handshake packet also validates the client's address.
+		// https://www.rfc-editor.org/rfc/rfc9000#section-8.1-2
+		c.peerAddrValidated = true
+		c.discardKeys(initialSpace)
+	}
+	ackEliciting, err := c.handleFrames(now, space, p.payload)
+	c.packetReceived(now, space, p.num, ackEliciting, err)
+	return n
+}
+
+// handle1RTT handles a 1-RTT packet, which always occupies
+// the remainder of the datagram.
+func (c *Conn) handle1RTT(now time.Time, buf []byte) int {
+	if !c.keys[appDataSpace].canRead() {
+		return len(buf)
+	}
+	if len(buf) < 1+connIDLen || !bytes.Equal(buf[1:][:connIDLen], c.localConnID) {
+		return len(buf)
+	}
+	pnumMax := c.acks[appDataSpace].largestSeen()
+	p, err := parse1RTTPacket(buf, c.keys[appDataSpace].r, pnumMax)
+	if err != nil {
+		// Packets which fail authentication are discarded.
+		// This includes packets using a key update, which is not supported.
+		return len(buf)
+	}
+	if c.state != connStateAlive {
+		c.handleClosingPacket()
+		return len(buf)
+	}
+	if !c.acks[appDataSpace].shouldProcess(p.num) {
+		return len(buf)
+	}
+	ackEliciting, err := c.handleFrames(now, appDataSpace, p.payload)
+	c.packetReceived(now, appDataSpace, p.num, ackEliciting, err)
+	return len(buf)
+}
+
+// packetReceived finishes processing a packet.
+func (c *Conn) packetReceived(now time.Time, space numberSpace, num packetNumber, ackEliciting bool, err error) {
+	if err != nil {
+		c.abort(now, err)
+		return
+	}
+	if c.keys[space].canRead() {
+		// The keys may have been discarded while processing the packet.
+		c.acks[space].receive(now, space, num, ackEliciting)
+	}
+	c.resetIdleTimer(now)
+}
+
+// handleClosingPacket handles a packet received while the connection is closing.
+func (c *Conn) handleClosingPacket() {
+	if c.state == connStateClosing {
+		// "An endpoint in the closing state sends a packet containing
+		// a CONNECTION_CLOSE frame in response to any incoming packet [...]"
+		// https://www.rfc-editor.org/rfc/rfc9000#section-10.2.1-2
+		c.sendClose = true
+	}
+}
+
+// handleFrames processes the frames in a packet payload.
+// It reports whether the packet was ack-eliciting.
+func (c *Conn) handleFrames(now time.Time, space numberSpace, payload []byte) (ackEliciting bool, err error) {
+	if len(payload) == 0 {
+		// "An endpoint MUST treat receipt of a packet containing no frames
+		// as a connection error of type PROTOCOL_VIOLATION."
+		// https://www.rfc-editor.org/rfc/rfc9000#section-12.4-3
+		return false, localTransportError{code: errProtocolViolation, reason: "packet contains no frames"}
+	}
+	for len(payload) > 0 && c.state == connStateAlive {
+		ftype := payload[0]
+		if ftype >= 0x40 {
+			// All frame types we know are encoded in a single byte.
+			return false, localTransportError{code: errFrameEncoding, reason: "unknown frame type"}
+		}
+		if space != appDataSpace {
+			// Only a few frame types are permitted in Initial and Handshake packets.
+			// https://www.rfc-editor.org/rfc/rfc9000#section-12.4-10
+			switch ftype {
+			case frameTypePadding, frameTypePing, frameTypeAck, frameTypeAckECN,
+				frameTypeCrypto, frameTypeConnectionCloseTransport:
+			default:
+				return false, localTransportError{code: errProtocolViolation, reason: "frame not permitted in packet type"}
+			}
+		}
+		switch ftype {
+		case frameTypePadding, frameTypeAck, frameTypeAckECN,
+			frameTypeConnectionCloseTransport, frameTypeConnectionCloseApplication:
+		default:
+			ackEliciting = true
+		}
+		var n int
+		switch {
+		case ftype == frameTypePadding:
+			n = 1
+		case ftype == frameTypePing:
+			n = 1
+		case ftype == frameTypeAck || ftype == frameTypeAckECN:
+			n, err = c.handleAckFrame(now, space, payload)
+		case ftype == frameTypeResetStream:
+			n, err = c.handleResetStreamFrame(payload)
+		case ftype == frameTypeStopSending:
+			n, err = c.handleStopSendingFrame(payload)
+		case ftype == frameTypeCrypto:
+			n, err = c.handleCryptoFrame(now, space, payload)
+		case ftype == frameTypeNewToken:
+			// Tokens are only useful with Retry or 0-RTT, which are not supported.
+			_, n = consumeNewTokenFrame(payload)
+			if c.side == serverSide {
+				err = localTransportError{code: errProtocolViolation, reason: "NEW_TOKEN from client"}
+			}
+		case ftype >= frameTypeStreamBase && ftype < frameTypeStreamBase+8:
+			n, err = c.handleStreamFrame(payload)
+		case ftype == frameTypeMaxData:
+			var max int64
+			max, n = consumeMaxDataFrame(payload)
+			if n > 0 {
+				c.handleMaxData(max)
+			}
+		case ftype == frameTypeMaxStreamData:
+			n, err = c.handleMaxStreamDataFrame(payload)
+		case ftype == frameTypeMaxStreamsBidi || ftype == frameTypeMaxStreamsUni:
+			var typ streamType
+			var max int64
+			typ, max, n = consumeMaxStreamsFrame(payload)
+			if n > 0 {
+				c.streams.setLocalLimit(typ, max)
+			}
+		case ftype == frameTypeDataBlocked, ftype == frameTypeStreamsBlockedBidi, ftype == frameTypeStreamsBlockedUni:
+			n = consumeVarintFrame(payload, 1)
+		case ftype == frameTypeStreamDataBlocked:
+			n = consumeVarintFrame(payload, 2)
+		case ftype == frameTypeNewConnectionID:
+			// Connection migration is not supported, so alternate
+			// connection IDs are not used.
+			_, _, _, _, n = consumeNewConnectionIDFrame(payload)
+		case ftype == frameTypeRetireConnectionID:
+			// We never issue more than one connection ID,
+			// so there is nothing to retire.
+			n = consumeVarintFrame(payload, 1)
+		case ftype == frameTypePathChallenge:
+			var data uint64
+			data, n = consumePathChallengeFrame(payload)
+			if n > 0 {
+				c.pathResponses = append(c.pathResponses, data)
+			}
+		case ftype == frameTypePathResponse:
+			// We never send PATH_CHALLENGE.
+			_, n = consumePathChallengeFrame(payload)
+		case ftype == frameTypeConnectionCloseTransport:
+			var code transportError
+			var reason string
+			code, _, reason, n = consumeConnectionCloseTransportFrame(payload)
+			if n > 0 {
+				c.enterDraining(now, peerTransportError{code: code, reason: reason})
+			}
+		case ftype == frameTypeConnectionCloseApplication:
+			var code uint64
+			var reason string
+			code, reason, n = consumeConnectionCloseApplicationFrame(payload)
+			if n > 0 {
+				c.enterDraining(now, &ApplicationError{Code: code, Reason: reason})
+			}
+		case ftype == frameTypeHandshakeDone:
+			n = 1
+			err = c.handleHandshakeDoneFrame(now)
+		default:
+			return false, localTransportError{code: errFrameEncoding, reason: "unknown frame type"}
+		}
+		if err != nil {
+			return false, err
+		}
+		if n < 0 {
+			return false, localTransportError{code: errFrameEncoding, reason: "malformed frame"}
+		}
+		payload = payload[n:]
+	}
+	return ackEliciting, nil
+}
+
+func (c *Conn) handleAckFrame(now time.Time, space numberSpace, payload []byte) (int, error) {
+	// Ranges beyond the first few are ignored.
+	// The peer will acknowledge those packets again in later frames.
+	var ranges [maxAckRanges + 1]i64range
+	nranges := 0
+	largest, ackDelay, n := consumeAckFrame(payload, func(start, end packetNumber) {
+		if nranges < len(ranges) {
+			ranges[nranges] = i64range{int64(start), int64(end)}
+			nranges++
+		}
+	})
+	if n < 0 {
+		return -1, nil
+	}
+	if largest >= c.loss.nextNumber(space) {
+		// "An endpoint SHOULD treat receipt of an acknowledgment for a packet
+		// it did not send as a connection error of type PROTOCOL_VIOLATION [...]"
+		// https://www.rfc-editor.org/rfc/rfc9000.html#section-13.1-9
+		return -1, localTransportError{code: errProtocolViolation, reason: "acknowledgement for unsent packet"}
+	}
+	for _, r := range ranges[:nranges] {
+		c.loss.receiveAckRange(now, space, packetNumber(r.start), packetNumber(r.end), c.handleAckOrLoss)
+	}
+	delay := ackDelayFromUnscaled(ackDelay, c.peerParams.ackDelayExponent)
+	c.loss.receiveAckEnd(now, space, largest, delay, c.handleAckOrLoss)
+	return n, nil
+}
+
+func (c *Conn) handleCryptoFrame(now time.Time, space numberSpace, payload []byte) (int, error) {
+	off, data, n := consumeCryptoFrame(payload)
+	if n < 0 {
+		return -1, nil
+	}
+	err := c.crypto[space].handleCrypto(off, data, func(b []byte) error {
+		return c.handleTLSData(now, space, b)
+	})
+	return n, err
+}
+
+func (c *Conn) handleHandshakeDoneFrame(now time.Time) error {
+	if c.side == serverSide {
+		// "A server MUST treat receipt of a HANDSHAKE_DONE frame as a
+		// connection error of type PROTOCOL_VIOLATION."
+		// https://www.rfc-editor.org/rfc/rfc9000#section-19.20-4
+		return localTransportError{code: errProtocolViolation, reason: "HANDSHAKE_DONE from client"}
+	}
+	c.confirmHandshake(now)
+	return nil
+}
+
+// handleAckOrLoss is called when a sent packet is acknowledged or lost.
+func (c *Conn) handleAckOrLoss(space numberSpace, sent *sentPacket, fate packetFate) {
+	if fate == packetLost && sent.requeued {
+		// The frames were already queued for retransmission by a probe.
+		return
+	}
+	for _, f := range sent.frames {
+		switch f.typ {
+		case frameTypeCrypto:
+			c.crypto[space].ackOrLoss(f.start, f.end, fate)
+		case frameTypeStreamBase, frameTypeResetStream, frameTypeStopSending, frameTypeMaxStreamData:
+			if s := c.streams.get(f.id); s != nil {
+				c.streamAckOrLoss(s, f, fate)
+			}
+		case frameTypeMaxData:
+			if fate == packetLost && f.end == c.inflow.max {
+				c.inflow.sendMax = true
+			}
+		case frameTypeMaxStreamsBidi:
+			c.streams.maxStreamsAckOrLoss(bidiStream, f.end, fate)
+		case frameTypeMaxStreamsUni:
+			c.streams.maxStreamsAckOrLoss(uniStream, f.end, fate)
+		case frameTypeHandshakeDone:
+			if fate == packetLost {
+				c.sendHandshakeDone = true
+			}
+		}
+	}
+}
+
+// requeueFrames queues the frames in a sent packet for retransmission,
+// without declaring the packet lost.
+func (c *Conn) requeueFrames(space numberSpace, sent *sentPacket) {
+	for _, f := range sent.frames {
+		switch f.typ {
+		case frameTypeCrypto:
+			c.crypto[space].requeue(f.start, f.end)
+		case frameTypeStreamBase, frameTypeResetStream, frameTypeStopSending, frameTypeMaxStreamData:
+			if s := c.streams.get(f.id); s != nil {
+				c.streamAckOrLoss(s, f, packetLost)
+			}
+		case frameTypeMaxData:
+			if f.end == c.inflow.max {
+				c.inflow.sendMax = true
+			}
+		case frameTypeMaxStreamsBidi:
+			c.streams.maxStreamsAckOrLoss(bidiStream, f.end, packetLost)
+		case frameTypeMaxStreamsUni:
+			c.streams.maxStreamsAckOrLoss(uniStream, f.end, packetLost)
+		case frameTypeHandshakeDone:
+			c.sendHandshakeDone = true
+		}
+	}
+}
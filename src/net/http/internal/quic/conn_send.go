@@ -0,0 +1,253 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"time"
+)
+
+// maybeSend sends datagrams, if possible.
+//
+// If sending is blocked by pacing, congestion control, or
+// anti-amplification limits, maybeSend sends what it can
+// and returns; it is called again after the next event.
+func (c *Conn) maybeSend(now time.Time) {
+	if c.sendClose {
+		c.sendConnectionClose(now)
+		return
+	}
+	if c.state != connStateAlive {
+		return
+	}
+	for {
+		if c.amplificationLimited() {
+			return
+		}
+		c.w.reset(maxDatagramSize)
+
+		// Coalesce packets from each number space with write keys
+		// into a single datagram.
+		// https://www.rfc-editor.org/rfc/rfc9000#section-12.2
+		var sent [numberSpaceCount]*sentPacket
+		for space := initialSpace; space < numberSpaceCount; space++ {
+			if c.keys[space].canWrite() {
+				sent[space] = c.appendPacket(now, space)
+			}
+		}
+		buf := c.w.datagram()
+		if len(buf) == 0 {
+			return
+		}
+		if s := sent[initialSpace]; s != nil && (c.side == clientSide || s.ackEliciting) {
+			// "A client MUST expand the payload of all UDP datagrams carrying
+			// Initial packets to at least the smallest allowed maximum datagram
+			// size of 1200 bytes [...] a server MUST expand the payload of all
+			// UDP datagrams carrying ack-eliciting Initial packets [...]"
+			// https://www.rfc-editor.org/rfc/rfc9000#section-14.1-1
+			//
+			// The padding follows the last packet in the datagram, and
+			// is ignored by the peer. It is accounted to the Initial packet.
+			for len(buf) < minimumDatagram {
+				buf = append(buf, 0)
+				s.size++
+			}
+			s.inFlight = true
+		}
+		c.endpoint.sendDatagram(buf, c.peerAddr)
+		c.bytesSent += int64(len(buf))
+		for space, s := range sent {
+			if s != nil {
+				c.loss.packetSent(now, numberSpace(space), s)
+			}
+		}
+		if c.side == clientSide && sent[handshakeSpace] != nil {
+			// "[...] a client MUST discard Initial keys when it first
+			// sends a Handshake packet [...]"
+			// https://www.rfc-editor.org/rfc/rfc9001#section-4.9.1-2
+			c.discardKeys(initialSpace)
+		}
+	}
+}
+
+// amplificationLimited reports whether the server's anti-amplification
+// limit prevents it from sending a full-sized datagram.
+// https://www.rfc-editor.org/rfc/rfc9000#section-8.1-2
+func (c *Conn) amplificationLimited() bool {
+	if c.peerAddrValidated {
+		return false
+	}
+	return amplificationMult*c.bytesRecv-c.bytesSent < maxDatagramSize
+}
+
+// appendPacket appends a packet in the given number space to the
+// current datagram, returning its sentPacket record.
+// It returns nil if there is nothing to send.
+func (c *Conn) appendPacket(now time.Time, space numberSpace) *sentPacket {
+	pnum := c.loss.nextNumber(space)
+	c.startPacket(space, pnum)
+
+	shouldSendAck := c.acks[space].shouldSendAck(now)
+	ackAdded := false
+	if unacked, delay := c.acks[space].acksToSend(now); unacked != nil {
+		ackAdded = c.w.appendAckFrame(unacked, unscaledAckDelayFromDuration(delay))
+	}
+	if c.loss.canSend() {
+		// When limited by the congestion window, only ACK frames are sent.
+		c.appendFrames(space)
+	}
+	if !c.w.sent.ackEliciting && !shouldSendAck {
+		// Don't send ACK-only packets before the ack is due.
+		c.w.abandonPacket()
+		return nil
+	}
+	sent := c.finishPacket(space, pnum)
+	if sent != nil && ackAdded {
+		c.acks[space].sentAck()
+	}
+	return sent
+}
+
+// appendFrames appends ack-eliciting frames to the current packet.
+func (c *Conn) appendFrames(space numberSpace) {
+	w := &c.w
+	if !c.crypto[space].appendFrames(w) {
+		return
+	}
+	if space == appDataSpace {
+		if c.sendHandshakeDone {
+			if !w.appendHandshakeDoneFrame() {
+				return
+			}
+			c.sendHandshakeDone = false
+		}
+		for len(c.pathResponses) > 0 {
+			if !w.appendPathResponseFrame(c.pathResponses[0]) {
+				return
+			}
+			c.pathResponses = c.pathResponses[1:]
+		}
+		if c.inflow.sendMax {
+			if !w.appendMaxDataFrame(c.inflow.max) {
+				return
+			}
+			c.inflow.sendMax = false
+		}
+		for typ := bidiStream; typ < streamTypeCount; typ++ {
+			if c.streams.sendMaxStreams[typ] {
+				if !w.appendMaxStreamsFrame(typ, c.streams.remoteLimit[typ]) {
+					return
+				}
+				c.streams.sendMaxStreams[typ] = false
+			}
+		}
+		if c.sendKeepAlive {
+			if !w.appendPingFrame() {
+				return
+			}
+			c.sendKeepAlive = false
+		}
+		if c.appendStreamFrames() {
+			return
+		}
+	}
+	if c.loss.probes > 0 && space == c.loss.ptoSpace && !w.sent.ackEliciting {
+		// A probe packet must be ack-eliciting.
+		// https://www.rfc-editor.org/rfc/rfc9002#section-6.2.4
+		w.appendPingFrame()
+	}
+}
+
+func (c *Conn) startPacket(space numberSpace, pnum packetNumber) {
+	if space == appDataSpace {
+		c.w.start1RTTPacket(pnum, c.peerConnID)
+	} else {
+		c.w.startProtectedLongHeaderPacket(c.longPacket(space, pnum))
+	}
+}
+
+func (c *Conn) finishPacket(space numberSpace, pnum packetNumber) *sentPacket {
+	if space == appDataSpace {
+		return c.w.finish1RTTPacket(c.keys[space].w, pnum)
+	}
+	return c.w.finishProtectedLongHeaderPacket(c.keys[space].w, c.longPacket(space, pnum))
+}
+
+func (c *Conn) longPacket(space numberSpace, pnum packetNumber) longPacket {
+	ptype := packetTypeInitial
+	if space == handshakeSpace {
+		ptype = packetTypeHandshake
+	}
+	return longPacket{
+		ptype:     ptype,
+		version:   quicVersion1,
+		num:       pnum,
+		dstConnID: c.peerConnID,
+		srcConnID: c.localConnID,
+	}
+}
+
+// sendConnectionClose sends a datagram containing a CONNECTION_CLOSE frame
+// in each number space with write keys.
+//
+// "[...] an endpoint that is not certain of the peer's state
+// MAY send multiple packets with CONNECTION_CLOSE frames [...]"
+// https://www.rfc-editor.org/rfc/rfc9000#section-10.2.3-3
+func (c *Conn) sendConnectionClose(now time.Time) {
+	c.sendClose = false
+	c.w.reset(maxDatagramSize)
+	var sentInitial bool
+	for space := initialSpace; space < numberSpaceCount; space++ {
+		if !c.keys[space].canWrite() {
+			continue
+		}
+		pnum := c.loss.nextNumber(space)
+		c.startPacket(space, pnum)
+		switch err := c.closeErr.(type) {
+		case *ApplicationError:
+			if space == appDataSpace {
+				c.w.appendConnectionCloseApplicationFrame(err.Code, err.Reason)
+			} else {
+				// Application errors can't be sent in Initial or Handshake
+				// packets, which may be read by an attacker.
+				// https://www.rfc-editor.org/rfc/rfc9000#section-10.2.3-5
+				c.w.appendConnectionCloseTransportFrame(errApplicationError, 0, "")
+			}
+		case localTransportError:
+			c.w.appendConnectionCloseTransportFrame(err.code, 0, err.reason)
+		default:
+			c.w.appendConnectionCloseTransportFrame(errInternal, 0, "")
+		}
+		if c.finishPacket(space, pnum) != nil {
+			c.loss.skipNumber(space)
+			if space == initialSpace {
+				sentInitial = true
+			}
+		}
+	}
+	buf := c.w.datagram()
+	if len(buf) == 0 {
+		return
+	}
+	if sentInitial && c.side == clientSide {
+		for len(buf) < minimumDatagram {
+			buf = append(buf, 0)
+		}
+	}
+	c.endpoint.sendDatagram(buf, c.peerAddr)
+	c.bytesSent += int64(len(buf))
+}
+
+// discardKeys discards the keys for a number space,
+// along with all other state for the space.
+// https://www.rfc-editor.org/rfc/rfc9001#section-4.9
+func (c *Conn) discardKeys(space numberSpace) {
+	if c.loss.spaces[space].discarded {
+		return
+	}
+	c.keys[space].discard()
+	c.crypto[space] = cryptoStream{}
+	c.acks[space] = ackState{}
+	c.loss.discardKeys(space)
+}
@@ -0,0 +1,550 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+)
+
+// streamsState is the per-connection stream state.
+// It is guarded by the conn's mu.
+type streamsState struct {
+	c *Conn
+	m map[streamID]*Stream
+
+	// Streams opened by us.
+	localOpened [streamTypeCount]int64 // number of streams opened
+	localLimit  [streamTypeCount]int64 // MAX_STREAMS received from the peer
+
+	// Streams opened by the peer.
+	remoteOpened   [streamTypeCount]int64 // number of streams opened
+	remoteLimit    [streamTypeCount]int64 // MAX_STREAMS sent to the peer
+	sendMaxStreams [streamTypeCount]bool  // MAX_STREAMS needs to be sent
+
+	// changec is closed and replaced when the stream limits or
+	// accept queue change, or the connection closes.
+	changec chan struct{}
+
+	acceptq []*Stream // streams opened by the peer and not yet accepted
+	sendq   []*Stream // streams with frames to send
+}
+
+func (ss *streamsState) init(c *Conn) {
+	ss.c = c
+	ss.m = make(map[streamID]*Stream)
+	ss.remoteLimit[bidiStream] = c.config.maxBidiRemoteStreams()
+	ss.remoteLimit[uniStream] = c.config.maxUniRemoteStreams()
+	ss.changec = make(chan struct{})
+}
+
+// changed wakes goroutines waiting for the stream state to change.
+func (ss *streamsState) changed() {
+	close(ss.changec)
+	ss.changec = make(chan struct{})
+}
+
+// notifyAll wakes all goroutines blocked on streams,
+// after the connection has begun to close.
+func (ss *streamsState) notifyAll() {
+	ss.changed()
+	for _, s := range ss.m {
+		s.signalReader()
+		s.signalWriter()
+	}
+}
+
+func (ss *streamsState) get(id streamID) *Stream {
+	return ss.m[id]
+}
+
+// setLocalLimit handles a MAX_STREAMS value received from the peer.
+func (ss *streamsState) setLocalLimit(typ streamType, max int64) {
+	if max > ss.localLimit[typ] {
+		ss.localLimit[typ] = max
+		ss.changed()
+	}
+}
+
+// maxStreamsAckOrLoss is called when a MAX_STREAMS frame is acked or lost.
+func (ss *streamsState) maxStreamsAckOrLoss(typ streamType, max int64, fate packetFate) {
+	if fate == packetLost && max == ss.remoteLimit[typ] {
+		ss.sendMaxStreams[typ] = true
+	}
+}
+
+// NewStream creates a stream.
+//
+// If the peer's maximum stream limit for the connection has been reached,
+// NewStream blocks until the limit is increased or the context expires.
+func (c *Conn) NewStream(ctx context.Context) (*Stream, error) {
+	return c.newLocalStream(ctx, bidiStream)
+}
+
+// NewSendOnlyStream creates a unidirectional, send-only stream.
+//
+// If the peer's maximum stream limit for the connection has been reached,
+// NewSendOnlyStream blocks until the limit is increased or the context expires.
+func (c *Conn) NewSendOnlyStream(ctx context.Context) (*Stream, error) {
+	return c.newLocalStream(ctx, uniStream)
+}
+
+func (c *Conn) newLocalStream(ctx context.Context, typ streamType) (*Stream, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return nil, err
+	}
+	for {
+		c.mu.Lock()
+		if c.finalErr != nil {
+			err := c.finalErr
+			c.mu.Unlock()
+			return nil, err
+		}
+		ss := &c.streams
+		if ss.localOpened[typ] < ss.localLimit[typ] {
+			id := newStreamID(c.side, typ, ss.localOpened[typ])
+			ss.localOpened[typ]++
+			s := c.addStream(id)
+			c.mu.Unlock()
+			return s, nil
+		}
+		changec := ss.changec
+		c.mu.Unlock()
+		select {
+		case <-changec:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// AcceptStream waits for and returns the next stream created by the peer.
+func (c *Conn) AcceptStream(ctx context.Context) (*Stream, error) {
+	for {
+		c.mu.Lock()
+		ss := &c.streams
+		if len(ss.acceptq) > 0 {
+			s := ss.acceptq[0]
+			ss.acceptq[0] = nil
+			ss.acceptq = ss.acceptq[1:]
+			c.mu.Unlock()
+			return s, nil
+		}
+		if c.finalErr != nil {
+			err := c.finalErr
+			c.mu.Unlock()
+			return nil, err
+		}
+		changec := ss.changec
+		c.mu.Unlock()
+		select {
+		case <-changec:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// addStream creates a stream and adds it to the stream map.
+func (c *Conn) addStream(id streamID) *Stream {
+	s := newStream(c, id)
+	if !s.IsReadOnly() {
+		p := &c.peerParams
+		switch {
+		case id.streamType() == uniStream:
+			s.outwin = p.initialMaxStreamDataUni
+		case id.initiator() == c.side:
+			s.outwin = p.initialMaxStreamDataBidiRemote
+		default:
+			s.outwin = p.initialMaxStreamDataBidiLocal
+		}
+	}
+	c.streams.m[id] = s
+	return s
+}
+
+// streamForFrame returns the stream a received frame refers to,
+// opening streams created by the peer as required.
+// recv reports whether the frame is for the receiving part of the
+// stream (STREAM, RESET_STREAM) rather than the sending part
+// (MAX_STREAM_DATA, STOP_SENDING).
+//
+// It returns nil if the stream has already been closed and forgotten.
+func (c *Conn) streamForFrame(id streamID, recv bool) (*Stream, error) {
+	typ := id.streamType()
+	local := id.initiator() == c.side
+	if typ == uniStream && local == recv {
+		// Receiving data on a stream we can only send on, or
+		// vice versa.
+		// https://www.rfc-editor.org/rfc/rfc9000#section-19.8-13
+		return nil, localTransportError{code: errStreamState, reason: "invalid frame for unidirectional stream"}
+	}
+	ss := &c.streams
+	num := id.num()
+	if local {
+		if num >= ss.localOpened[typ] {
+			return nil, localTransportError{code: errStreamState, reason: "frame for stream not yet created"}
+		}
+		return ss.m[id], nil
+	}
+	if num >= ss.remoteLimit[typ] {
+		return nil, localTransportError{code: errStreamLimit, reason: "stream limit exceeded"}
+	}
+	if num >= ss.remoteOpened[typ] {
+		// "[...] when a stream is created, all streams of the same type
+		// with lower-numbered stream IDs MUST be created."
+		// https://www.rfc-editor.org/rfc/rfc9000#section-3.2-6
+		for n := ss.remoteOpened[typ]; n <= num; n++ {
+			s := c.addStream(newStreamID(c.side.peer(), typ, n))
+			ss.acceptq = append(ss.acceptq, s)
+		}
+		ss.remoteOpened[typ] = num + 1
+		ss.changed()
+	}
+	return ss.m[id], nil
+}
+
+// queueStream adds s to the queue of streams with frames to send.
+func (c *Conn) queueStream(s *Stream) {
+	if !s.queued && !s.done {
+		s.queued = true
+		c.streams.sendq = append(c.streams.sendq, s)
+	}
+	c.wake()
+}
+
+// streamRecvDone is called when the receive side of a stream is complete.
+func (c *Conn) streamRecvDone(s *Stream) {
+	c.maybeRemoveStream(s)
+}
+
+// maybeRemoveStream removes s from the stream map when
+// both its sending and receiving parts are complete.
+func (c *Conn) maybeRemoveStream(s *Stream) {
+	if s.done || !s.recvDone() || !s.sendDone() {
+		return
+	}
+	s.done = true
+	ss := &c.streams
+	delete(ss.m, s.id)
+	if s.id.initiator() != c.side {
+		// Permit the peer to open another stream.
+		typ := s.id.streamType()
+		ss.remoteLimit[typ]++
+		ss.sendMaxStreams[typ] = true
+		c.wake()
+	}
+}
+
+// connInflowConsumed records that the application has consumed
+// n bytes of stream data, extending the connection's flow control
+// window when half of it has been used.
+func (c *Conn) connInflowConsumed(n int64) {
+	c.inflow.credit += n
+	win := c.config.maxConnReadBufferSize()
+	if c.inflow.max-c.inflow.credit < win/2 {
+		c.inflow.max = c.inflow.credit + win
+		c.inflow.sendMax = true
+		c.wake()
+	}
+}
+
+// handleMaxData handles a MAX_DATA frame.
+func (c *Conn) handleMaxData(max int64) {
+	if max <= c.outflow.max {
+		return
+	}
+	blocked := c.outflow.sent >= c.outflow.max
+	c.outflow.max = max
+	if blocked {
+		// Streams blocked on connection-level flow control
+		// were removed from the send queue.
+		for _, s := range c.streams.m {
+			if s.hasFramesToSend() {
+				c.queueStream(s)
+			}
+		}
+	}
+}
+
+func (c *Conn) handleStreamFrame(payload []byte) (int, error) {
+	id, off, fin, data, n := consumeStreamFrame(payload)
+	if n < 0 {
+		return -1, nil
+	}
+	s, err := c.streamForFrame(id, true)
+	if err != nil || s == nil {
+		return n, err
+	}
+	end := off + int64(len(data))
+	if s.insize >= 0 && (end > s.insize || (fin && end != s.insize)) {
+		return n, localTransportError{code: errFinalSize, reason: "data beyond final size"}
+	}
+	if fin {
+		if end < s.inmaxrecv {
+			return n, localTransportError{code: errFinalSize, reason: "final size below received data"}
+		}
+		s.insize = end
+	}
+	if end > s.inwin {
+		return n, localTransportError{code: errFlowControl, reason: "stream flow control exceeded"}
+	}
+	if end > s.inmaxrecv {
+		c.inflow.used += end - s.inmaxrecv
+		s.inmaxrecv = end
+		if c.inflow.used > c.inflow.max {
+			return n, localTransportError{code: errFlowControl, reason: "connection flow control exceeded"}
+		}
+	}
+	if s.inresetcode >= 0 {
+		return n, nil
+	}
+	if s.inclosed {
+		// Reads have been aborted; discard the data.
+		if s.inmaxrecv > s.in.start {
+			c.connInflowConsumed(s.inmaxrecv - s.in.start)
+			s.in = pipe{start: s.inmaxrecv, end: s.inmaxrecv}
+		}
+		c.maybeRemoveStream(s)
+		return n, nil
+	}
+	if end > s.in.start {
+		if off < s.in.start {
+			data = data[s.in.start-off:]
+			off = s.in.start
+		}
+		s.in.writeAt(data, off)
+		s.inset.add(off, end)
+	}
+	s.signalReader()
+	return n, nil
+}
+
+func (c *Conn) handleResetStreamFrame(payload []byte) (int, error) {
+	id, code, finalSize, n := consumeResetStreamFrame(payload)
+	if n < 0 {
+		return -1, nil
+	}
+	s, err := c.streamForFrame(id, true)
+	if err != nil || s == nil {
+		return n, err
+	}
+	if (s.insize >= 0 && finalSize != s.insize) || finalSize < s.inmaxrecv {
+		return n, localTransportError{code: errFinalSize, reason: "RESET_STREAM final size mismatch"}
+	}
+	if finalSize > s.inwin {
+		return n, localTransportError{code: errFlowControl, reason: "stream flow control exceeded"}
+	}
+	if s.inresetcode >= 0 {
+		return n, nil
+	}
+	if finalSize > s.inmaxrecv {
+		c.inflow.used += finalSize - s.inmaxrecv
+		s.inmaxrecv = finalSize
+		if c.inflow.used > c.inflow.max {
+			return n, localTransportError{code: errFlowControl, reason: "connection flow control exceeded"}
+		}
+	}
+	s.insize = finalSize
+	s.inresetcode = int64(code)
+	s.insendstop = false
+	s.insendmax = false
+	// Unread data will never be read, so release its flow control credit.
+	if finalSize > s.in.start {
+		c.connInflowConsumed(finalSize - s.in.start)
+	}
+	s.in = pipe{start: finalSize, end: finalSize}
+	s.inset = nil
+	s.signalReader()
+	c.maybeRemoveStream(s)
+	return n, nil
+}
+
+func (c *Conn) handleStopSendingFrame(payload []byte) (int, error) {
+	id, code, n := consumeStopSendingFrame(payload)
+	if n < 0 {
+		return -1, nil
+	}
+	s, err := c.streamForFrame(id, false)
+	if err != nil || s == nil {
+		return n, err
+	}
+	if s.outstopcode < 0 {
+		s.outstopcode = int64(code)
+	}
+	// "An endpoint that receives a STOP_SENDING frame MUST send a
+	// RESET_STREAM frame if the stream is in the "Ready" or "Send" state."
+	// https://www.rfc-editor.org/rfc/rfc9000#section-3.5-4
+	s.resetLocked(code)
+	s.signalWriter()
+	return n, nil
+}
+
+func (c *Conn) handleMaxStreamDataFrame(payload []byte) (int, error) {
+	id, max, n := consumeMaxStreamDataFrame(payload)
+	if n < 0 {
+		return -1, nil
+	}
+	s, err := c.streamForFrame(id, false)
+	if err != nil || s == nil {
+		return n, err
+	}
+	if max > s.outwin {
+		s.outwin = max
+		if s.hasFramesToSend() {
+			c.queueStream(s)
+		}
+	}
+	return n, nil
+}
+
+// streamAckOrLoss handles the acknowledgement or loss of a frame
+// for a stream.
+func (c *Conn) streamAckOrLoss(s *Stream, f sentFrame, fate packetFate) {
+	switch f.typ {
+	case frameTypeStreamBase:
+		if s.outresetcode >= 0 {
+			// Data on a reset stream is never retransmitted.
+			break
+		}
+		if fate == packetAcked {
+			// The range may have been queued for retransmission by a probe.
+			s.outunsent.sub(f.start, f.end)
+			if f.end > s.out.start {
+				s.outacked.add(f.start, f.end)
+				if r := s.outacked.rangeContaining(s.out.start); r.end > s.out.start {
+					s.out.discardBefore(r.end)
+					s.outacked.sub(0, r.end)
+					s.signalWriter()
+				}
+			}
+			if f.fin {
+				s.outfinacked = true
+			}
+			break
+		}
+		start := f.start
+		if start < s.out.start {
+			start = s.out.start
+		}
+		if start < f.end {
+			s.outunsent.add(start, f.end)
+			for _, r := range s.outacked {
+				s.outunsent.sub(r.start, r.end)
+			}
+		}
+		if f.fin && !s.outfinacked {
+			s.outfinunsent = true
+		}
+		if s.hasFramesToSend() {
+			c.queueStream(s)
+		}
+	case frameTypeResetStream:
+		if fate == packetAcked {
+			s.outresetacked = true
+		} else {
+			s.outresetunsent = true
+			c.queueStream(s)
+		}
+	case frameTypeStopSending:
+		if fate == packetLost && s.inresetcode < 0 && !(s.insize >= 0 && s.inmaxrecv >= s.insize) {
+			s.insendstop = true
+			c.queueStream(s)
+		}
+	case frameTypeMaxStreamData:
+		if fate == packetLost && f.end == s.inwin && !s.inclosed && s.insize < 0 {
+			s.insendmax = true
+			c.queueStream(s)
+		}
+	}
+	c.maybeRemoveStream(s)
+}
+
+// appendStreamFrames appends frames for streams in the send queue.
+// It reports whether the packet is full.
+func (c *Conn) appendStreamFrames() (full bool) {
+	ss := &c.streams
+	for len(ss.sendq) > 0 {
+		s := ss.sendq[0]
+		if c.appendFramesForStream(s) {
+			// Move the stream to the end of the queue, so streams
+			// share the available bandwidth.
+			copy(ss.sendq, ss.sendq[1:])
+			ss.sendq[len(ss.sendq)-1] = s
+			return true
+		}
+		ss.sendq[0] = nil
+		ss.sendq = ss.sendq[1:]
+		s.queued = false
+	}
+	return false
+}
+
+// appendFramesForStream appends the frames s has to send.
+// It reports whether the packet is full.
+// When it returns false, s has nothing more to send, or is blocked by flow control.
+func (c *Conn) appendFramesForStream(s *Stream) (full bool) {
+	w := &c.w
+	if s.insendstop {
+		if !w.appendStopSendingFrame(s.id, s.instopcode) {
+			return true
+		}
+		s.insendstop = false
+	}
+	if s.insendmax {
+		if !w.appendMaxStreamDataFrame(s.id, s.inwin) {
+			return true
+		}
+		s.insendmax = false
+	}
+	if s.outresetunsent {
+		if !w.appendResetStreamFrame(s.id, uint64(s.outresetcode), s.outmaxsent) {
+			return true
+		}
+		s.outresetunsent = false
+		return false
+	}
+	if s.outresetcode >= 0 {
+		return false
+	}
+	for len(s.outunsent) > 0 || s.outfinunsent {
+		start, end := s.out.end, s.out.end
+		if len(s.outunsent) > 0 {
+			start, end = s.outunsent[0].start, s.outunsent[0].end
+		}
+		if end > s.outwin {
+			end = s.outwin
+		}
+		if end > s.outmaxsent {
+			// Sending new data consumes connection-level flow control.
+			if lim := s.outmaxsent + c.outflow.max - c.outflow.sent; end > lim {
+				end = lim
+			}
+		}
+		fin := s.outfinunsent && end == s.out.end
+		if end < start || (end == start && !fin) {
+			// Blocked by flow control.
+			return false
+		}
+		b, ok := w.appendStreamFrame(s.id, start, int(end-start), fin)
+		if !ok {
+			return true
+		}
+		s.out.copy(start, b)
+		sent := start + int64(len(b))
+		s.outunsent.sub(start, sent)
+		if sent > s.outmaxsent {
+			c.outflow.sent += sent - s.outmaxsent
+			s.outmaxsent = sent
+		}
+		if sent < end {
+			// The packet is full.
+			return true
+		}
+		if fin {
+			s.outfinunsent = false
+		}
+	}
+	return false
+}
@@ -0,0 +1,386 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http/internal"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	cert, err := tls.X509KeyPair(internal.LocalhostCert, internal.LocalhostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      roots,
+		MinVersion:   tls.VersionTLS13,
+		NextProtos:   []string{"test"},
+	}
+}
+
+// lossyPacketConn drops every dropEvery'th datagram it sends.
+type lossyPacketConn struct {
+	net.PacketConn
+	dropEvery int
+
+	mu sync.Mutex
+	n  int
+}
+
+func (pc *lossyPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	pc.mu.Lock()
+	pc.n++
+	drop := pc.dropEvery > 0 && pc.n%pc.dropEvery == 0
+	pc.mu.Unlock()
+	if drop {
+		return len(b), nil
+	}
+	return pc.PacketConn.WriteTo(b, addr)
+}
+
+type testPair struct {
+	cli, srv         *Conn
+	cliEndp, srvEndp *Endpoint
+}
+
+// newTestPair creates a connected client and server over loopback.
+// If dropEvery is non-zero, both sides drop every dropEvery'th datagram.
+func newTestPair(t *testing.T, config *Config, dropEvery int) *testPair {
+	t.Helper()
+	listen := func(config *Config) *Endpoint {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := NewEndpoint(&lossyPacketConn{PacketConn: pc, dropEvery: dropEvery}, config)
+		t.Cleanup(func() { e.Close() })
+		return e
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	config.TLSConfig = testTLSConfig(t)
+	p := &testPair{
+		srvEndp: listen(config),
+		cliEndp: listen(nil),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() {
+		var err error
+		p.srv, err = p.srvEndp.Accept(ctx)
+		errc <- err
+	}()
+	var err error
+	p.cli, err = p.cliEndp.Dial(ctx, "udp", p.srvEndp.LocalAddr().String(), config)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	return p
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestConnHandshake(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	for _, c := range []*Conn{p.cli, p.srv} {
+		state := c.ConnectionState()
+		if state.Version != tls.VersionTLS13 {
+			t.Errorf("TLS version = %x, want TLS 1.3", state.Version)
+		}
+		if state.NegotiatedProtocol != "test" {
+			t.Errorf("NegotiatedProtocol = %q, want %q", state.NegotiatedProtocol, "test")
+		}
+	}
+}
+
+func TestConnFailedHandshake(t *testing.T) {
+	srv, err := Listen("udp", "127.0.0.1:0", &Config{TLSConfig: testTLSConfig(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	cli, err := Listen("udp", "127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	// The client doesn't trust the server's certificate.
+	config := &Config{TLSConfig: testTLSConfig(t)}
+	config.TLSConfig.RootCAs = x509.NewCertPool()
+	if _, err := cli.Dial(testContext(t), "udp", srv.LocalAddr().String(), config); err == nil {
+		t.Fatal("Dial with untrusted certificate succeeded, want error")
+	}
+}
+
+// testStreamEcho sends data on a new stream from the client,
+// which the server echoes back.
+func testStreamEcho(t *testing.T, p *testPair, data []byte) {
+	t.Helper()
+	ctx := testContext(t)
+	srvErr := make(chan error, 1)
+	go func() {
+		s, err := p.srv.AcceptStream(ctx)
+		if err != nil {
+			srvErr <- err
+			return
+		}
+		if _, err := io.Copy(s, s); err != nil {
+			srvErr <- err
+			return
+		}
+		srvErr <- s.Close()
+	}()
+
+	s, err := p.cli.NewStream(ctx)
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	go func() {
+		s.Write(data)
+		s.Close()
+	}()
+	got, err := ioutil.ReadAll(s)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("echoed %v bytes, want %v bytes", len(got), len(data))
+	}
+	if err := <-srvErr; err != nil {
+		t.Errorf("server: %v", err)
+	}
+}
+
+func TestStreamEcho(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	testStreamEcho(t, p, []byte("hello"))
+}
+
+func TestStreamEchoEmpty(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	testStreamEcho(t, p, nil)
+}
+
+func TestStreamEchoLarge(t *testing.T) {
+	// The transfer is larger than the flow control windows.
+	p := newTestPair(t, &Config{
+		MaxStreamReadBufferSize: 64 << 10,
+		MaxConnReadBufferSize:   128 << 10,
+	}, 0)
+	testStreamEcho(t, p, bytes.Repeat([]byte("0123456789abcdef"), 1<<16))
+}
+
+func TestStreamEchoLossy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	p := newTestPair(t, nil, 7)
+	testStreamEcho(t, p, bytes.Repeat([]byte("0123456789abcdef"), 1<<14))
+}
+
+func TestStreamConcurrent(t *testing.T) {
+	p := newTestPair(t, &Config{MaxBidiRemoteStreams: 3}, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			testStreamEcho(t, p, bytes.Repeat([]byte("x"), 10000))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStreamUnidirectional(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	ctx := testContext(t)
+	s, err := p.srv.NewSendOnlyStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsWriteOnly() {
+		t.Errorf("IsWriteOnly = false, want true")
+	}
+	if _, err := s.Read(make([]byte, 1)); err == nil {
+		t.Errorf("Read on write-only stream succeeded")
+	}
+	s.Write([]byte("push"))
+	s.Close()
+
+	rs, err := p.cli.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rs.IsReadOnly() {
+		t.Errorf("IsReadOnly = false, want true")
+	}
+	got, err := ioutil.ReadAll(rs)
+	if err != nil || string(got) != "push" {
+		t.Errorf("ReadAll = %q, %v; want %q, nil", got, err, "push")
+	}
+}
+
+func TestStreamReset(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	ctx := testContext(t)
+	s, err := p.cli.NewStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("data"))
+	s.Reset(42)
+
+	ss, err := p.srv.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ioutil.ReadAll(ss)
+	var code StreamErrorCode
+	if !errors.As(err, &code) || code != 42 {
+		t.Errorf("Read after reset: %v, want StreamErrorCode(42)", err)
+	}
+}
+
+func TestStreamStopSending(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	ctx := testContext(t)
+	s, err := p.cli.NewStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write([]byte("data"))
+
+	ss, err := p.srv.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.StopSending(7)
+
+	var code StreamErrorCode
+	for {
+		if _, err = s.Write([]byte("more")); err != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !errors.As(err, &code) || code != 7 {
+		t.Errorf("Write after STOP_SENDING: %v, want StreamErrorCode(7)", err)
+	}
+}
+
+func TestStreamDeadlines(t *testing.T) {
+	p := newTestPair(t, &Config{MaxStreamWriteBufferSize: 1024}, 0)
+	ctx := testContext(t)
+	s, err := p.cli.NewStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing to read, so Read blocks until the deadline.
+	s.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	var nerr net.Error
+	if _, err := s.Read(make([]byte, 1)); !errors.As(err, &nerr) || !nerr.Timeout() {
+		t.Fatalf("Read past deadline: %v, want timeout", err)
+	}
+	if _, err := s.Read(make([]byte, 1)); err != errTimeout {
+		t.Fatalf("Read after deadline: %v, want %v", err, errTimeout)
+	}
+	s.SetReadDeadline(time.Time{})
+
+	// The peer never reads, so Write blocks once its buffers are full.
+	s.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+	n, err := s.Write(make([]byte, 1<<20))
+	if err != errTimeout {
+		t.Fatalf("Write past deadline: %v, want %v", err, errTimeout)
+	}
+	if n >= 1<<20 {
+		t.Errorf("Write past deadline wrote %v bytes, want fewer", n)
+	}
+
+	// Clearing the deadline lets the stream be used again.
+	s.SetWriteDeadline(time.Time{})
+	ss, err := p.srv.AcceptStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.Write([]byte("x"))
+	ss.Close()
+	if b, err := ioutil.ReadAll(s); err != nil || string(b) != "x" {
+		t.Errorf("ReadAll after clearing deadline = %q, %v; want \"x\"", b, err)
+	}
+}
+
+func TestConnAbort(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	p.srv.Abort(&ApplicationError{Code: 5, Reason: "bye"})
+	err := p.cli.Wait(testContext(t))
+	var aerr *ApplicationError
+	if !errors.As(err, &aerr) || aerr.Code != 5 || aerr.Reason != "bye" {
+		t.Errorf("client Wait = %v, want ApplicationError 5", err)
+	}
+	if _, err := p.cli.NewStream(testContext(t)); err == nil {
+		t.Errorf("NewStream on closed conn succeeded")
+	}
+}
+
+func TestConnIdleTimeout(t *testing.T) {
+	p := newTestPair(t, &Config{MaxIdleTimeout: 100 * time.Millisecond}, 0)
+	if err := p.cli.Wait(testContext(t)); err != errIdleTimeout {
+		t.Errorf("client Wait = %v, want %v", err, errIdleTimeout)
+	}
+}
+
+func TestConnKeepAlive(t *testing.T) {
+	p := newTestPair(t, &Config{
+		MaxIdleTimeout:  200 * time.Millisecond,
+		KeepAlivePeriod: 50 * time.Millisecond,
+	}, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := p.cli.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("client Wait = %v, want connection kept alive", err)
+	}
+}
+
+func TestEndpointClose(t *testing.T) {
+	p := newTestPair(t, nil, 0)
+	p.srvEndp.Close()
+	if _, err := p.srvEndp.Accept(testContext(t)); err != ErrEndpointClosed {
+		t.Errorf("Accept after Close = %v, want ErrEndpointClosed", err)
+	}
+	if err := p.cli.Wait(testContext(t)); err == nil {
+		t.Errorf("client Wait = nil, want error")
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// "Implementations MUST support buffering at least 4096 bytes of data
+// received in out-of-order CRYPTO frames."
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-7.5-2
+//
+// 4096 is too small for real-world cases, however, so we allow more.
+const cryptoBufferSize = 1 << 20
+
+// A cryptoStream is the stream of data passed in CRYPTO frames.
+// There is one cryptoStream per packet number space.
+type cryptoStream struct {
+	// CRYPTO data received from the peer.
+	in    pipe
+	inset rangeset // bytes received
+
+	// CRYPTO data queued for transmission to the peer.
+	out       pipe
+	outunsent rangeset // bytes in need of sending
+	outacked  rangeset // bytes acked by peer
+}
+
+// handleCrypto processes data received in a CRYPTO frame.
+// f is called with each contiguous run of data that becomes available.
+func (s *cryptoStream) handleCrypto(off int64, b []byte, f func([]byte) error) error {
+	end := off + int64(len(b))
+	if end-s.in.start > cryptoBufferSize {
+		return localTransportError{code: errCryptoBufferExceeded}
+	}
+	if end <= s.in.start {
+		// Duplicate data.
+		return nil
+	}
+	s.in.writeAt(b, off)
+	s.inset.add(off, end)
+	r := s.inset.rangeContaining(s.in.start)
+	if r.end <= s.in.start {
+		return nil
+	}
+	start := s.in.start
+	data := s.in.bytes(start)[:r.end-start]
+	err := f(data)
+	s.in.discardBefore(r.end)
+	s.inset.sub(0, r.end)
+	return err
+}
+
+// write queues data for sending to the peer.
+// It does not block or limit the amount of buffered data.
+// QUIC connections don't communicate the amount of CRYPTO data they are willing to buffer,
+// so we send what we have and the peer can close the connection if it is too much.
+func (s *cryptoStream) write(b []byte) {
+	start := s.out.end
+	s.out.writeAt(b, start)
+	s.outunsent.add(start, s.out.end)
+}
+
+// ackOrLoss reports that a CRYPTO frame sent by us has been acknowledged by the peer, or lost.
+func (s *cryptoStream) ackOrLoss(start, end int64, fate packetFate) {
+	switch fate {
+	case packetAcked:
+		// The range may have been queued for retransmission by a probe.
+		s.outunsent.sub(start, end)
+		s.outacked.add(start, end)
+		acked := s.outacked.rangeContaining(s.out.start)
+		if acked.end > s.out.start {
+			s.out.discardBefore(acked.end)
+			s.outacked.sub(0, acked.end)
+		}
+	case packetLost:
+		s.requeue(start, end)
+	}
+}
+
+// requeue marks [start, end) for retransmission,
+// excluding any data the peer has already acknowledged.
+func (s *cryptoStream) requeue(start, end int64) {
+	if start < s.out.start {
+		start = s.out.start
+	}
+	if end <= start {
+		return
+	}
+	s.outunsent.add(start, end)
+	for _, r := range s.outacked {
+		s.outunsent.sub(r.start, r.end)
+	}
+}
+
+// hasData reports whether there is CRYPTO data waiting to be sent.
+func (s *cryptoStream) hasData() bool {
+	return len(s.outunsent) > 0
+}
+
+// appendFrames appends CRYPTO frames for unsent data to w.
+// It reports whether all queued data was written.
+func (s *cryptoStream) appendFrames(w *packetWriter) bool {
+	for len(s.outunsent) > 0 {
+		r := s.outunsent[0]
+		b, ok := w.appendCryptoFrame(r.start, int(r.size()))
+		if !ok {
+			return false
+		}
+		s.out.copy(r.start, b)
+		s.outunsent.sub(r.start, r.start+int64(len(b)))
+	}
+	return true
+}
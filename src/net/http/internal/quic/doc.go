@@ -0,0 +1,15 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quic implements the QUIC transport protocol (RFC 9000),
+// for use by the HTTP/3 support in net/http.
+//
+// The TLS handshake is performed by crypto/tls, using its QUIC API
+// (RFC 9001). Only QUIC version 1 is supported.
+//
+// The implementation is deliberately minimal. It does not support
+// Retry packets, key updates, connection migration, 0-RTT data,
+// path MTU discovery, or stateless resets. Datagrams are limited to
+// 1200 bytes, the smallest size every QUIC path must support.
+package quic
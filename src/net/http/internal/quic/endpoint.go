@@ -0,0 +1,305 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// An Endpoint handles QUIC traffic on a network address.
+// It can accept inbound connections or create outbound ones.
+//
+// Multiple goroutines may invoke methods on an Endpoint simultaneously.
+type Endpoint struct {
+	listenConfig *Config // nil if the endpoint does not accept connections
+	pc           net.PacketConn
+
+	readDone chan struct{} // closed when the read loop exits
+	closec   chan struct{} // closed when Close is called
+
+	mu      sync.Mutex
+	conns   map[string]*Conn // keyed by local connection IDs
+	acceptq []*Conn
+	acceptc chan struct{} // signaled when acceptq becomes non-empty
+	closing bool
+}
+
+// Listen listens on a local network address.
+// The network must be "udp", "udp4", or "udp6".
+//
+// If listenConfig is non-nil, the endpoint accepts inbound connections
+// using that configuration. Otherwise, the endpoint may only be used to
+// create outbound connections with Dial.
+func Listen(network, address string, listenConfig *Config) (*Endpoint, error) {
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	pc, err := net.ListenUDP(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewEndpoint(pc, listenConfig), nil
+}
+
+// NewEndpoint returns an endpoint which sends and receives QUIC
+// datagrams on pc. The endpoint takes ownership of pc, and closes it
+// when the endpoint is closed. If pc is closed or fails, the endpoint
+// is closed as well.
+//
+// If listenConfig is non-nil, the endpoint accepts inbound connections
+// using that configuration.
+func NewEndpoint(pc net.PacketConn, listenConfig *Config) *Endpoint {
+	e := &Endpoint{
+		listenConfig: listenConfig,
+		pc:           pc,
+		readDone:     make(chan struct{}),
+		closec:       make(chan struct{}),
+		conns:        make(map[string]*Conn),
+		acceptc:      make(chan struct{}, 1),
+	}
+	go e.readLoop()
+	return e
+}
+
+// LocalAddr returns the local network address.
+func (e *Endpoint) LocalAddr() net.Addr {
+	return e.pc.LocalAddr()
+}
+
+// Close closes the endpoint.
+//
+// Any active connections are closed with an application error code of 0,
+// and Close waits for them to exit.
+func (e *Endpoint) Close() error {
+	e.mu.Lock()
+	if e.closing {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closing = true
+	close(e.closec)
+	var conns []*Conn
+	seen := make(map[*Conn]bool)
+	for _, c := range e.conns {
+		if !seen[c] {
+			seen[c] = true
+			conns = append(conns, c)
+		}
+	}
+	e.acceptq = nil
+	e.mu.Unlock()
+
+	for _, c := range conns {
+		c.mu.Lock()
+		c.exitNow(&ApplicationError{})
+		c.mu.Unlock()
+	}
+	for _, c := range conns {
+		<-c.donec
+	}
+	err := e.pc.Close()
+	<-e.readDone
+	return err
+}
+
+// Accept waits for and returns the next connection to the endpoint.
+// It returns ErrEndpointClosed after the endpoint is closed.
+func (e *Endpoint) Accept(ctx context.Context) (*Conn, error) {
+	for {
+		e.mu.Lock()
+		if e.closing {
+			e.mu.Unlock()
+			return nil, ErrEndpointClosed
+		}
+		if len(e.acceptq) > 0 {
+			c := e.acceptq[0]
+			e.acceptq[0] = nil
+			e.acceptq = e.acceptq[1:]
+			if len(e.acceptq) > 0 {
+				e.signalAccept()
+			}
+			e.mu.Unlock()
+			return c, nil
+		}
+		e.mu.Unlock()
+		select {
+		case <-e.acceptc:
+		case <-e.closec:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (e *Endpoint) signalAccept() {
+	select {
+	case e.acceptc <- struct{}{}:
+	default:
+	}
+}
+
+// Dial creates and returns a connection to a network address.
+// The network must be "udp", "udp4", or "udp6".
+//
+// If config.TLSConfig does not set ServerName, the host part of the
+// address is used.
+func (e *Endpoint) Dial(ctx context.Context, network, address string, config *Config) (*Conn, error) {
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if config.TLSConfig == nil {
+		return nil, errors.New("quic: Dial requires a TLS configuration")
+	}
+	if config.TLSConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		c := *config
+		c.TLSConfig = config.TLSConfig.Clone()
+		c.TLSConfig.ServerName = host
+		config = &c
+	}
+	c, err := newConn(time.Now(), clientSide, e, config, addr, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	if e.closing {
+		e.mu.Unlock()
+		c.tls.Close()
+		return nil, ErrEndpointClosed
+	}
+	e.conns[string(c.localConnID)] = c
+	e.mu.Unlock()
+	go c.loop()
+	if err := c.waitReady(ctx); err != nil {
+		c.Abort(nil)
+		return nil, err
+	}
+	return c, nil
+}
+
+// readLoop reads datagrams and dispatches them to connections.
+func (e *Endpoint) readLoop() {
+	defer close(e.readDone)
+	buf := make([]byte, 64<<10)
+	for {
+		n, addr, err := e.pc.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			// Close waits for the read loop to exit,
+			// so it must run in its own goroutine.
+			go e.Close()
+			return
+		}
+		b := make([]byte, n)
+		copy(b, buf[:n])
+		e.handleDatagram(b, addr)
+	}
+}
+
+func (e *Endpoint) handleDatagram(b []byte, addr net.Addr) {
+	dstConnID, ok := dstConnIDForDatagram(b)
+	if !ok {
+		return
+	}
+	e.mu.Lock()
+	c := e.conns[string(dstConnID)]
+	e.mu.Unlock()
+	if c != nil {
+		c.deliver(b)
+		return
+	}
+	if e.listenConfig == nil || !isLongHeader(b[0]) {
+		// Stateless resets are not supported, so packets for
+		// unknown connections are dropped.
+		return
+	}
+	if len(b) < minimumDatagram {
+		// Clients pad datagrams containing Initial packets.
+		// https://www.rfc-editor.org/rfc/rfc9000#section-14.1-4
+		return
+	}
+	srcConnID, rest, ok := consumeUint8Bytes(b[6+len(dstConnID):])
+	if !ok || len(rest) == 0 {
+		return
+	}
+	if version := binary.BigEndian.Uint32(b[1:5]); version != quicVersion1 {
+		// https://www.rfc-editor.org/rfc/rfc9000#section-6.1
+		e.sendDatagram(appendVersionNegotiation(nil, srcConnID, dstConnID, quicVersion1), addr)
+		return
+	}
+	if getPacketType(b) != packetTypeInitial || len(dstConnID) < connIDLen {
+		// "When an Initial packet is sent by a client that has not previously
+		// received an Initial or Retry packet from the server, the client
+		// populates the Destination Connection ID field with an unpredictable
+		// value. This Destination Connection ID MUST be at least 8 bytes in length."
+		// https://www.rfc-editor.org/rfc/rfc9000#section-7.2-3
+		return
+	}
+	c, err := newConn(time.Now(), serverSide, e, e.listenConfig, addr,
+		append([]byte(nil), dstConnID...), append([]byte(nil), srcConnID...))
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	if e.closing {
+		e.mu.Unlock()
+		c.tls.Close()
+		return
+	}
+	// Packets from the client use the original destination connection ID
+	// until it receives our first Initial packet.
+	e.conns[string(c.origDstConnID)] = c
+	e.conns[string(c.localConnID)] = c
+	e.mu.Unlock()
+	go c.loop()
+	c.deliver(b)
+}
+
+// sendDatagram sends a datagram to addr.
+// Errors are ignored: QUIC recovers from lost datagrams.
+func (e *Endpoint) sendDatagram(b []byte, addr net.Addr) {
+	e.pc.WriteTo(b, addr)
+}
+
+// serverConnEstablished is called when a server connection
+// completes its handshake, making it available to Accept.
+func (e *Endpoint) serverConnEstablished(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closing {
+		return
+	}
+	e.acceptq = append(e.acceptq, c)
+	e.signalAccept()
+}
+
+// connDone is called when a conn loop exits.
+func (e *Endpoint) connDone(c *Conn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, id := range [][]byte{c.origDstConnID, c.localConnID} {
+		if e.conns[string(id)] == c {
+			delete(e.conns, string(id))
+		}
+	}
+	for i, ac := range e.acceptq {
+		if ac == c {
+			e.acceptq = append(e.acceptq[:i], e.acceptq[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "encoding/binary"
+
+// Frame types.
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-19
+const (
+	frameTypePadding                    = 0x00
+	frameTypePing                       = 0x01
+	frameTypeAck                        = 0x02
+	frameTypeAckECN                     = 0x03
+	frameTypeResetStream                = 0x04
+	frameTypeStopSending                = 0x05
+	frameTypeCrypto                     = 0x06
+	frameTypeNewToken                   = 0x07
+	frameTypeStreamBase                 = 0x08 // low three bits carry stream flags
+	frameTypeMaxData                    = 0x10
+	frameTypeMaxStreamData              = 0x11
+	frameTypeMaxStreamsBidi             = 0x12
+	frameTypeMaxStreamsUni              = 0x13
+	frameTypeDataBlocked                = 0x14
+	frameTypeStreamDataBlocked          = 0x15
+	frameTypeStreamsBlockedBidi         = 0x16
+	frameTypeStreamsBlockedUni          = 0x17
+	frameTypeNewConnectionID            = 0x18
+	frameTypeRetireConnectionID         = 0x19
+	frameTypePathChallenge              = 0x1a
+	frameTypePathResponse               = 0x1b
+	frameTypeConnectionCloseTransport   = 0x1c
+	frameTypeConnectionCloseApplication = 0x1d
+	frameTypeHandshakeDone              = 0x1e
+)
+
+// The low three bits of STREAM frames.
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-19.8
+const (
+	streamOffBit = 0x04
+	streamLenBit = 0x02
+	streamFinBit = 0x01
+)
+
+// maxAckRanges is the largest number of ACK ranges sent in a single ACK frame.
+const maxAckRanges = 32
+
+// The consume*Frame functions below parse a frame (including its type)
+// from the start of b. They return a negative length if the frame
+// could not be parsed.
+
+func consumeAckFrame(frame []byte, f func(start, end packetNumber)) (largest packetNumber, ackDelay uint64, n int) {
+	b := frame[1:] // type
+	largestAck, n := consumeVarint(b)
+	if n < 0 {
+		return 0, 0, -1
+	}
+	b = b[n:]
+	ackDelay, n = consumeVarint(b)
+	if n < 0 {
+		return 0, 0, -1
+	}
+	b = b[n:]
+	ackRangeCount, n := consumeVarint(b)
+	if n < 0 {
+		return 0, 0, -1
+	}
+	b = b[n:]
+	rangeMax := packetNumber(largestAck)
+	for i := uint64(0); ; i++ {
+		rangeLen, n := consumeVarint(b)
+		if n < 0 {
+			return 0, 0, -1
+		}
+		b = b[n:]
+		rangeMin := rangeMax - packetNumber(rangeLen)
+		if rangeMin < 0 || rangeMin > rangeMax {
+			return 0, 0, -1
+		}
+		f(rangeMin, rangeMax+1)
+		if i == ackRangeCount {
+			break
+		}
+		gap, n := consumeVarint(b)
+		if n < 0 {
+			return 0, 0, -1
+		}
+		b = b[n:]
+		rangeMax = rangeMin - packetNumber(gap) - 2
+	}
+	if frame[0] == frameTypeAckECN {
+		// ECT(0), ECT(1), and ECN-CE counts are ignored.
+		for i := 0; i < 3; i++ {
+			_, n := consumeVarint(b)
+			if n < 0 {
+				return 0, 0, -1
+			}
+			b = b[n:]
+		}
+	}
+	return packetNumber(largestAck), ackDelay, len(frame) - len(b)
+}
+
+func consumeResetStreamFrame(b []byte) (id streamID, code uint64, finalSize int64, n int) {
+	n = 1
+	idInt, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, 0, -1
+	}
+	n += nn
+	code, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, 0, -1
+	}
+	n += nn
+	v, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, 0, -1
+	}
+	n += nn
+	return streamID(idInt), code, int64(v), n
+}
+
+func consumeStopSendingFrame(b []byte) (id streamID, code uint64, n int) {
+	n = 1
+	idInt, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, -1
+	}
+	n += nn
+	code, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, -1
+	}
+	n += nn
+	return streamID(idInt), code, n
+}
+
+func consumeCryptoFrame(b []byte) (off int64, data []byte, n int) {
+	n = 1
+	v, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, nil, -1
+	}
+	off = int64(v)
+	n += nn
+	data, nn = consumeVarintBytes(b[n:])
+	if nn < 0 {
+		return 0, nil, -1
+	}
+	n += nn
+	if off+int64(len(data)) > maxVarint {
+		return 0, nil, -1
+	}
+	return off, data, n
+}
+
+func consumeNewTokenFrame(b []byte) (token []byte, n int) {
+	n = 1
+	data, nn := consumeVarintBytes(b[n:])
+	if nn < 0 || len(data) == 0 {
+		return nil, -1
+	}
+	n += nn
+	return data, n
+}
+
+func consumeStreamFrame(b []byte) (id streamID, off int64, fin bool, data []byte, n int) {
+	fin = (b[0] & streamFinBit) != 0
+	n = 1
+	idInt, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, false, nil, -1
+	}
+	n += nn
+	if b[0]&streamOffBit != 0 {
+		v, nn := consumeVarint(b[n:])
+		if nn < 0 {
+			return 0, 0, false, nil, -1
+		}
+		n += nn
+		off = int64(v)
+	}
+	if b[0]&streamLenBit != 0 {
+		data, nn = consumeVarintBytes(b[n:])
+		if nn < 0 {
+			return 0, 0, false, nil, -1
+		}
+		n += nn
+	} else {
+		data = b[n:]
+		n += len(data)
+	}
+	if off+int64(len(data)) > maxVarint {
+		return 0, 0, false, nil, -1
+	}
+	return streamID(idInt), off, fin, data, n
+}
+
+func consumeMaxDataFrame(b []byte) (max int64, n int) {
+	n = 1
+	v, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, -1
+	}
+	n += nn
+	return int64(v), n
+}
+
+func consumeMaxStreamDataFrame(b []byte) (id streamID, max int64, n int) {
+	n = 1
+	v, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, -1
+	}
+	n += nn
+	id = streamID(v)
+	v, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, -1
+	}
+	n += nn
+	max = int64(v)
+	return id, max, n
+}
+
+func consumeMaxStreamsFrame(b []byte) (typ streamType, max int64, n int) {
+	switch b[0] {
+	case frameTypeMaxStreamsBidi:
+		typ = bidiStream
+	case frameTypeMaxStreamsUni:
+		typ = uniStream
+	default:
+		return 0, 0, -1
+	}
+	n = 1
+	v, nn := consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, -1
+	}
+	n += nn
+	if v > maxStreamsLimit {
+		return 0, 0, -1
+	}
+	return typ, int64(v), n
+}
+
+// consumeVarintFrame parses a frame consisting of its type followed by
+// count variable-length integers, all of which are ignored.
+// It is used for DATA_BLOCKED, STREAM_DATA_BLOCKED, STREAMS_BLOCKED,
+// and RETIRE_CONNECTION_ID frames.
+func consumeVarintFrame(b []byte, count int) (n int) {
+	n = 1
+	for i := 0; i < count; i++ {
+		_, nn := consumeVarint(b[n:])
+		if nn < 0 {
+			return -1
+		}
+		n += nn
+	}
+	return n
+}
+
+func consumeNewConnectionIDFrame(b []byte) (seq, retire int64, connID []byte, resetToken []byte, n int) {
+	n = 1
+	var nn int
+	seq, nn = consumeVarintInt64(b[n:])
+	if nn < 0 {
+		return 0, 0, nil, nil, -1
+	}
+	n += nn
+	retire, nn = consumeVarintInt64(b[n:])
+	if nn < 0 {
+		return 0, 0, nil, nil, -1
+	}
+	n += nn
+	if seq < retire {
+		return 0, 0, nil, nil, -1
+	}
+	connID, rest, ok := consumeUint8Bytes(b[n:])
+	if !ok || len(connID) < 1 || len(connID) > maxConnIDLen {
+		return 0, 0, nil, nil, -1
+	}
+	n = len(b) - len(rest)
+	if len(rest) < 16 {
+		return 0, 0, nil, nil, -1
+	}
+	resetToken = rest[:16]
+	n += 16
+	return seq, retire, connID, resetToken, n
+}
+
+func consumePathChallengeFrame(b []byte) (data uint64, n int) {
+	n = 1
+	if len(b) < n+8 {
+		return 0, -1
+	}
+	return binary.BigEndian.Uint64(b[n:]), n + 8
+}
+
+func consumeConnectionCloseTransportFrame(b []byte) (code transportError, frameType uint64, reason string, n int) {
+	n = 1
+	var nn int
+	var codeInt uint64
+	codeInt, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, "", -1
+	}
+	code = transportError(codeInt)
+	n += nn
+	frameType, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, 0, "", -1
+	}
+	n += nn
+	reasonb, nn := consumeVarintBytes(b[n:])
+	if nn < 0 {
+		return 0, 0, "", -1
+	}
+	n += nn
+	reason = string(reasonb)
+	return code, frameType, reason, n
+}
+
+func consumeConnectionCloseApplicationFrame(b []byte) (code uint64, reason string, n int) {
+	n = 1
+	var nn int
+	code, nn = consumeVarint(b[n:])
+	if nn < 0 {
+		return 0, "", -1
+	}
+	n += nn
+	reasonb, nn := consumeVarintBytes(b[n:])
+	if nn < 0 {
+		return 0, "", -1
+	}
+	n += nn
+	reason = string(reasonb)
+	return code, reason, n
+}
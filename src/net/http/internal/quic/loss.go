@@ -0,0 +1,421 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "time"
+
+// Loss detection and congestion control constants.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-a.2
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-b.2
+const (
+	packetThreshold   = 3
+	timerGranularity  = 1 * time.Millisecond
+	initialRTT        = 333 * time.Millisecond
+	maxDatagramSize   = 1200 // path MTU discovery is not supported
+	initialWindow     = 10 * maxDatagramSize
+	minimumWindow     = 2 * maxDatagramSize
+	lossReductionNum  = 1 // kLossReductionFactor, as a fraction
+	lossReductionDen  = 2
+	maxProbePackets   = 2
+	minimumDatagram   = 1200 // minimum size of datagrams carrying Initial packets
+	amplificationMult = 3    // anti-amplification limit factor
+)
+
+// A packetFate is the fate of a sent packet: Either acknowledged by the peer,
+// or declared lost.
+type packetFate byte
+
+const (
+	packetLost = packetFate(iota)
+	packetAcked
+)
+
+// lossState tracks sent packets, RTT estimates, loss detection timers,
+// and the congestion window.
+//
+// https://www.rfc-editor.org/rfc/rfc9002.html
+type lossState struct {
+	side connSide
+
+	spaces [numberSpaceCount]lossSpace
+
+	// RTT estimation.
+	// https://www.rfc-editor.org/rfc/rfc9002.html#section-5
+	rttSampled  bool
+	minRTT      time.Duration
+	smoothedRTT time.Duration
+	rttvar      time.Duration
+	latestRTT   time.Duration
+
+	// peerMaxAckDelay is the peer's max_ack_delay transport parameter.
+	peerMaxAckDelay time.Duration
+
+	// handshakeConfirmed is set when the handshake is confirmed.
+	// https://www.rfc-editor.org/rfc/rfc9001#section-4.1.2
+	handshakeConfirmed bool
+
+	// Loss detection timer.
+	timer      time.Time
+	timerDirty bool // timer must be recomputed
+	ptoCount   int
+	ptoSpace   numberSpace // space whose PTO the timer is for
+	isPTO      bool        // timer is a PTO rather than a loss time
+
+	// probes is the number of probe packets which may be sent in ptoSpace
+	// regardless of the congestion window.
+	probes int
+
+	// Congestion control (NewReno).
+	// https://www.rfc-editor.org/rfc/rfc9002.html#section-7
+	cwnd          int
+	ssthresh      int
+	bytesInFlight int
+	recoveryStart time.Time
+}
+
+// lossSpace is the loss recovery state for a single packet number space.
+type lossSpace struct {
+	nextNum              packetNumber
+	maxAcked             packetNumber // -1 if nothing acked yet
+	sent                 []*sentPacket
+	lossTime             time.Time
+	lastAckElicitingSent time.Time
+	ackElicitingInFlight int
+	discarded            bool
+}
+
+func (l *lossState) init(side connSide) {
+	l.side = side
+	for i := range l.spaces {
+		l.spaces[i].maxAcked = -1
+	}
+	l.smoothedRTT = initialRTT
+	l.rttvar = initialRTT / 2
+	l.peerMaxAckDelay = defaultParamMaxAckDelayMilliseconds * time.Millisecond
+	l.cwnd = initialWindow
+	l.ssthresh = 1<<31 - 1
+}
+
+// nextNumber returns the next packet number to use in a space.
+func (l *lossState) nextNumber(space numberSpace) packetNumber {
+	return l.spaces[space].nextNum
+}
+
+// packetSent records a sent packet.
+func (l *lossState) packetSent(now time.Time, space numberSpace, sent *sentPacket) {
+	s := &l.spaces[space]
+	sent.time = now
+	s.nextNum = sent.num + 1
+	if !sent.inFlight {
+		// Packets that are not in flight (ACK-only packets) need no
+		// further tracking.
+		return
+	}
+	s.sent = append(s.sent, sent)
+	l.bytesInFlight += sent.size
+	if sent.ackEliciting {
+		s.lastAckElicitingSent = now
+		s.ackElicitingInFlight++
+		if l.probes > 0 && space == l.ptoSpace {
+			l.probes--
+		}
+		l.timerDirty = true
+	}
+}
+
+// skipNumber consumes a packet number without tracking a packet,
+// used for packets which are never acknowledged (such as CONNECTION_CLOSE).
+func (l *lossState) skipNumber(space numberSpace) {
+	l.spaces[space].nextNum++
+}
+
+// canSend reports whether the congestion controller permits sending
+// an ack-eliciting packet.
+func (l *lossState) canSend() bool {
+	return l.probes > 0 || l.bytesInFlight < l.cwnd
+}
+
+// receiveAckRange records the packets in [start, end) as acknowledged.
+// The caller must call receiveAckEnd after processing all ranges in an ACK frame.
+func (l *lossState) receiveAckRange(now time.Time, space numberSpace, start, end packetNumber, f func(numberSpace, *sentPacket, packetFate)) {
+	s := &l.spaces[space]
+	for _, sent := range s.sent {
+		if sent.num < start || sent.acked || sent.lost {
+			continue
+		}
+		if sent.num >= end {
+			break
+		}
+		sent.acked = true
+		l.packetDone(space, sent)
+		l.onPacketAcked(sent)
+		f(space, sent, packetAcked)
+	}
+}
+
+// receiveAckEnd finishes processing an ACK frame.
+// largest is the Largest Acknowledged field, and ackDelay the
+// decoded ACK Delay.
+func (l *lossState) receiveAckEnd(now time.Time, space numberSpace, largest packetNumber, ackDelay time.Duration, f func(numberSpace, *sentPacket, packetFate)) {
+	s := &l.spaces[space]
+	if largest > s.maxAcked {
+		s.maxAcked = largest
+		// Take an RTT sample if the largest acknowledged packet is newly
+		// acked and ack-eliciting.
+		// https://www.rfc-editor.org/rfc/rfc9002.html#section-5.1
+		for _, sent := range s.sent {
+			if sent.num == largest && sent.acked && sent.ackEliciting {
+				if space != appDataSpace {
+					ackDelay = 0
+				}
+				l.updateRTT(now.Sub(sent.time), ackDelay)
+				break
+			}
+		}
+		// "[...] the PTO backoff is reset upon receiving an acknowledgement [...]"
+		// except for a client which has not yet validated the server's address.
+		// https://www.rfc-editor.org/rfc/rfc9002.html#section-6.2.1-9
+		l.ptoCount = 0
+	}
+	l.detectLoss(now, space, f)
+	l.compact(space)
+	l.timerDirty = true
+}
+
+func (l *lossState) updateRTT(latest, ackDelay time.Duration) {
+	// https://www.rfc-editor.org/rfc/rfc9002.html#section-5.3
+	l.latestRTT = latest
+	if !l.rttSampled {
+		l.rttSampled = true
+		l.minRTT = latest
+		l.smoothedRTT = latest
+		l.rttvar = latest / 2
+		return
+	}
+	if latest < l.minRTT {
+		l.minRTT = latest
+	}
+	if l.handshakeConfirmed && ackDelay > l.peerMaxAckDelay {
+		ackDelay = l.peerMaxAckDelay
+	}
+	adjusted := latest
+	if adjusted >= l.minRTT+ackDelay {
+		adjusted -= ackDelay
+	}
+	d := l.smoothedRTT - adjusted
+	if d < 0 {
+		d = -d
+	}
+	l.rttvar = (3*l.rttvar + d) / 4
+	l.smoothedRTT = (7*l.smoothedRTT + adjusted) / 8
+}
+
+// detectLoss declares packets lost using the packet and time thresholds.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-6.1
+func (l *lossState) detectLoss(now time.Time, space numberSpace, f func(numberSpace, *sentPacket, packetFate)) {
+	s := &l.spaces[space]
+	s.lossTime = time.Time{}
+	lossDelay := l.latestRTT
+	if l.smoothedRTT > lossDelay {
+		lossDelay = l.smoothedRTT
+	}
+	lossDelay = lossDelay * 9 / 8
+	if lossDelay < timerGranularity {
+		lossDelay = timerGranularity
+	}
+	var congestionSent time.Time
+	for _, sent := range s.sent {
+		if sent.num > s.maxAcked {
+			break
+		}
+		if sent.acked || sent.lost {
+			continue
+		}
+		if sent.num <= s.maxAcked-packetThreshold || !sent.time.After(now.Add(-lossDelay)) {
+			sent.lost = true
+			l.packetDone(space, sent)
+			if sent.time.After(congestionSent) {
+				congestionSent = sent.time
+			}
+			f(space, sent, packetLost)
+			continue
+		}
+		lossTime := sent.time.Add(lossDelay)
+		if s.lossTime.IsZero() || lossTime.Before(s.lossTime) {
+			s.lossTime = lossTime
+		}
+	}
+	if !congestionSent.IsZero() {
+		l.onCongestionEvent(now, congestionSent)
+	}
+}
+
+// packetDone removes a packet from the bytes in flight.
+func (l *lossState) packetDone(space numberSpace, sent *sentPacket) {
+	l.bytesInFlight -= sent.size
+	if sent.ackEliciting {
+		l.spaces[space].ackElicitingInFlight--
+	}
+}
+
+// compact removes acknowledged and lost packets from a space's sent list.
+func (l *lossState) compact(space numberSpace) {
+	s := &l.spaces[space]
+	i := 0
+	for _, sent := range s.sent {
+		if !sent.acked && !sent.lost {
+			s.sent[i] = sent
+			i++
+		}
+	}
+	for j := i; j < len(s.sent); j++ {
+		s.sent[j] = nil
+	}
+	s.sent = s.sent[:i]
+}
+
+// discardKeys is called when a space's keys are discarded.
+// Packets in the space are no longer tracked.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-6.4
+func (l *lossState) discardKeys(space numberSpace) {
+	s := &l.spaces[space]
+	for _, sent := range s.sent {
+		l.packetDone(space, sent)
+	}
+	s.sent = nil
+	s.lossTime = time.Time{}
+	s.discarded = true
+	l.ptoCount = 0
+	if l.ptoSpace == space {
+		l.probes = 0
+	}
+	l.timerDirty = true
+}
+
+// onPacketAcked updates the congestion window for an acknowledged packet.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-b.5
+func (l *lossState) onPacketAcked(sent *sentPacket) {
+	if !sent.inFlight {
+		return
+	}
+	if !sent.time.After(l.recoveryStart) {
+		// Do not increase the congestion window during recovery.
+		return
+	}
+	if l.cwnd < l.ssthresh {
+		// Slow start.
+		l.cwnd += sent.size
+	} else {
+		// Congestion avoidance.
+		l.cwnd += maxDatagramSize * sent.size / l.cwnd
+	}
+}
+
+// onCongestionEvent reduces the congestion window after packet loss.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-b.6
+func (l *lossState) onCongestionEvent(now, sentTime time.Time) {
+	if !sentTime.After(l.recoveryStart) {
+		// Already in recovery for this loss.
+		return
+	}
+	l.recoveryStart = now
+	l.ssthresh = l.cwnd * lossReductionNum / lossReductionDen
+	l.cwnd = l.ssthresh
+	if l.cwnd < minimumWindow {
+		l.cwnd = minimumWindow
+	}
+}
+
+// ptoBasePeriod returns the base PTO duration.
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-6.2.1
+func (l *lossState) ptoBasePeriod() time.Duration {
+	v := 4 * l.rttvar
+	if v < timerGranularity {
+		v = timerGranularity
+	}
+	return l.smoothedRTT + v
+}
+
+// setTimer recomputes the loss detection timer.
+// peerAwaitingAddr reports whether a client has not yet received
+// confirmation that the server has validated its address, in which case
+// the PTO timer must be armed even with nothing in flight.
+//
+// https://www.rfc-editor.org/rfc/rfc9002.html#section-a.8
+func (l *lossState) setTimer(now time.Time, peerAwaitingAddr bool, hasHandshakeKeys bool) {
+	l.timerDirty = false
+	l.isPTO = false
+	l.timer = time.Time{}
+
+	// Time threshold loss detection.
+	for space := range l.spaces {
+		s := &l.spaces[space]
+		if s.lossTime.IsZero() {
+			continue
+		}
+		if l.timer.IsZero() || s.lossTime.Before(l.timer) {
+			l.timer = s.lossTime
+			l.ptoSpace = numberSpace(space)
+		}
+	}
+	if !l.timer.IsZero() {
+		return
+	}
+
+	// Probe timeout.
+	backoff := time.Duration(1) << uint(l.ptoCount)
+	anyInFlight := false
+	for space := initialSpace; space < numberSpaceCount; space++ {
+		s := &l.spaces[space]
+		if s.ackElicitingInFlight == 0 {
+			continue
+		}
+		anyInFlight = true
+		if space == appDataSpace && !l.handshakeConfirmed {
+			// Skip Application Data until the handshake is confirmed.
+			continue
+		}
+		d := l.ptoBasePeriod()
+		if space == appDataSpace {
+			d += l.peerMaxAckDelay
+		}
+		t := s.lastAckElicitingSent.Add(d * backoff)
+		if l.timer.IsZero() || t.Before(l.timer) {
+			l.timer = t
+			l.ptoSpace = space
+		}
+	}
+	if !anyInFlight && peerAwaitingAddr {
+		// Anti-deadlock PTO: arm the timer from now.
+		l.timer = now.Add(l.ptoBasePeriod() * backoff)
+		if hasHandshakeKeys {
+			l.ptoSpace = handshakeSpace
+		} else {
+			l.ptoSpace = initialSpace
+		}
+	}
+	l.isPTO = !l.timer.IsZero()
+}
+
+// onTimeout handles expiration of the loss detection timer.
+// It reports the number space in which a probe should be sent, if any.
+func (l *lossState) onTimeout(now time.Time, f func(numberSpace, *sentPacket, packetFate)) (probeSpace numberSpace, probe bool) {
+	l.timerDirty = true
+	if !l.isPTO {
+		space := l.ptoSpace
+		l.detectLoss(now, space, f)
+		l.compact(space)
+		return 0, false
+	}
+	l.ptoCount++
+	l.probes = maxProbePackets
+	return l.ptoSpace, true
+}
+
+// ptoDuration returns the current PTO duration,
+// used to time closing and draining periods.
+func (l *lossState) ptoDuration() time.Duration {
+	return l.ptoBasePeriod() + l.peerMaxAckDelay
+}
@@ -0,0 +1,330 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import "encoding/binary"
+
+// quicVersion1 is QUIC version 1, defined in RFC 9000.
+// It is the only version this package speaks.
+const quicVersion1 = 1
+
+// connIDLen is the length of the connection IDs chosen by this package.
+// Peers may choose IDs of any length up to maxConnIDLen.
+const connIDLen = 8
+
+// maxConnIDLen is the maximum length of a QUIC version 1 connection ID.
+const maxConnIDLen = 20
+
+// packetType is a QUIC packet type.
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-17
+type packetType byte
+
+const (
+	packetTypeInvalid = packetType(iota)
+	packetTypeInitial
+	packetType0RTT
+	packetTypeHandshake
+	packetTypeRetry
+	packetType1RTT
+	packetTypeVersionNegotiation
+)
+
+func (p packetType) String() string {
+	switch p {
+	case packetTypeInitial:
+		return "Initial"
+	case packetType0RTT:
+		return "0-RTT"
+	case packetTypeHandshake:
+		return "Handshake"
+	case packetTypeRetry:
+		return "Retry"
+	case packetType1RTT:
+		return "1-RTT"
+	case packetTypeVersionNegotiation:
+		return "VersionNegotiation"
+	}
+	return "unknown"
+}
+
+// Bits set in the first byte of a packet.
+const (
+	headerFormLong  = 0x80 // https://www.rfc-editor.org/rfc/rfc9000.html#section-17.2-3.2.1
+	headerFormShort = 0x00 // https://www.rfc-editor.org/rfc/rfc9000.html#section-17.3.1-4.2.1
+	fixedBit        = 0x40 // https://www.rfc-editor.org/rfc/rfc9000.html#section-17.2-3.4.1
+	reservedBits    = 0x0c // https://www.rfc-editor.org/rfc/rfc9000#section-17.2-8.2.1
+	reserved1RTT    = 0x18 // https://www.rfc-editor.org/rfc/rfc9000#section-17.3.1-4.8.1
+)
+
+// Long Packet Type bits.
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-17.2-3.6.1
+const (
+	longPacketTypeInitial   = 0 << 4
+	longPacketType0RTT      = 1 << 4
+	longPacketTypeHandshake = 2 << 4
+	longPacketTypeRetry     = 3 << 4
+)
+
+// isLongHeader returns true if b is the first byte of a long header.
+func isLongHeader(b byte) bool {
+	return b&headerFormLong == headerFormLong
+}
+
+// getPacketType returns the type of a packet.
+func getPacketType(b []byte) packetType {
+	if len(b) == 0 {
+		return packetTypeInvalid
+	}
+	if !isLongHeader(b[0]) {
+		if b[0]&fixedBit != fixedBit {
+			return packetTypeInvalid
+		}
+		return packetType1RTT
+	}
+	if len(b) < 5 {
+		return packetTypeInvalid
+	}
+	if b[1] == 0 && b[2] == 0 && b[3] == 0 && b[4] == 0 {
+		// Version Negotiation packets don't necessarily set the fixed bit.
+		return packetTypeVersionNegotiation
+	}
+	if b[0]&fixedBit != fixedBit {
+		return packetTypeInvalid
+	}
+	switch b[0] & 0x30 {
+	case longPacketTypeInitial:
+		return packetTypeInitial
+	case longPacketType0RTT:
+		return packetType0RTT
+	case longPacketTypeHandshake:
+		return packetTypeHandshake
+	case longPacketTypeRetry:
+		return packetTypeRetry
+	}
+	return packetTypeInvalid
+}
+
+// dstConnIDForDatagram returns the destination connection ID field of the
+// first QUIC packet in a datagram. For short header packets, whose
+// connection ID length is not encoded in the packet, it assumes the ID
+// is one this package chose.
+func dstConnIDForDatagram(pkt []byte) (id []byte, ok bool) {
+	if len(pkt) < 1 {
+		return nil, false
+	}
+	var n int
+	var b []byte
+	if isLongHeader(pkt[0]) {
+		if len(pkt) < 6 {
+			return nil, false
+		}
+		n = int(pkt[5])
+		b = pkt[6:]
+	} else {
+		n = connIDLen
+		b = pkt[1:]
+	}
+	if len(b) < n {
+		return nil, false
+	}
+	return b[:n], true
+}
+
+// A longPacket is a long header packet.
+type longPacket struct {
+	ptype     packetType
+	version   uint32
+	num       packetNumber
+	dstConnID []byte
+	srcConnID []byte
+	payload   []byte
+
+	// The extra data depends on the packet type:
+	//   Initial: Token.
+	//   Retry: Retry token and integrity tag.
+	extra []byte
+}
+
+// A shortPacket is a short header (1-RTT) packet.
+type shortPacket struct {
+	num     packetNumber
+	payload []byte
+}
+
+// parseLongHeaderPacket parses a QUIC long header packet.
+//
+// It does not parse Version Negotiation packets.
+//
+// On input, pkt contains a long header packet (possibly followed by more packets),
+// k the decryption keys for the packet, and pnumMax the largest packet number seen
+// in the number space of this packet.
+//
+// parseLongHeaderPacket returns the parsed packet with protection removed
+// and its length in bytes.
+//
+// It returns an empty packet and -1 if the packet could not be parsed.
+func parseLongHeaderPacket(pkt []byte, k fixedKeys, pnumMax packetNumber) (p longPacket, n int) {
+	if len(pkt) < 5 || !isLongHeader(pkt[0]) {
+		return longPacket{}, -1
+	}
+
+	// Header Form, Fixed Bit, Long Packet Type, Type-Specific Bits.
+	p.ptype = getPacketType(pkt)
+	if p.ptype == packetTypeInvalid {
+		return longPacket{}, -1
+	}
+	b := pkt[1:]
+	// Version.
+	p.version = binary.BigEndian.Uint32(b)
+	b = b[4:]
+	if p.version == 0 {
+		// Version Negotiation packet; not handled here.
+		return longPacket{}, -1
+	}
+	// Destination Connection ID Length, Destination Connection ID.
+	var ok bool
+	p.dstConnID, b, ok = consumeUint8Bytes(b)
+	if !ok || len(p.dstConnID) > maxConnIDLen {
+		return longPacket{}, -1
+	}
+	// Source Connection ID Length, Source Connection ID.
+	p.srcConnID, b, ok = consumeUint8Bytes(b)
+	if !ok || len(p.srcConnID) > maxConnIDLen {
+		return longPacket{}, -1
+	}
+
+	switch p.ptype {
+	case packetTypeInitial:
+		// Token Length, Token.
+		var n int
+		p.extra, n = consumeVarintBytes(b)
+		if n < 0 {
+			return longPacket{}, -1
+		}
+		b = b[n:]
+	case packetTypeRetry:
+		// Retry Token, Retry Integrity Tag.
+		p.extra = b
+		return p, len(pkt)
+	}
+
+	// Length.
+	payLen, n := consumeVarint(b)
+	if n < 0 {
+		return longPacket{}, -1
+	}
+	b = b[n:]
+	if uint64(len(b)) < payLen {
+		return longPacket{}, -1
+	}
+
+	// Packet Number.
+	//
+	// We don't yet know the length of this field,
+	// but we know it is preceded by the Length field
+	// and followed by the payload.
+	pnumOff := len(pkt) - len(b)
+	pkt = pkt[:pnumOff+int(payLen)]
+
+	if k.isSet() {
+		var err error
+		p.payload, p.num, err = k.unprotect(pkt, pnumOff, pnumMax)
+		if err != nil {
+			return longPacket{}, -1
+		}
+		// Reserved bits must be zero once header protection is removed.
+		if pkt[0]&reservedBits != 0 {
+			return longPacket{}, -1
+		}
+	}
+	return p, len(pkt)
+}
+
+// skipLongHeaderPacket returns the length of the long header packet at the start of pkt,
+// or -1 if the buffer does not contain a valid packet.
+func skipLongHeaderPacket(pkt []byte) int {
+	// Header byte, 4 bytes of version.
+	n := 5
+	if len(pkt) <= n {
+		return -1
+	}
+	// Destination connection ID length, destination connection ID.
+	n += 1 + int(pkt[n])
+	if len(pkt) <= n {
+		return -1
+	}
+	// Source connection ID length, source connection ID.
+	n += 1 + int(pkt[n])
+	if len(pkt) <= n {
+		return -1
+	}
+	if getPacketType(pkt) == packetTypeInitial {
+		// Token length, token.
+		_, nn := consumeVarintBytes(pkt[n:])
+		if nn < 0 {
+			return -1
+		}
+		n += nn
+	}
+	// Length, packet number, payload.
+	_, nn := consumeVarintBytes(pkt[n:])
+	if nn < 0 {
+		return -1
+	}
+	n += nn
+	if len(pkt) < n {
+		return -1
+	}
+	return n
+}
+
+// parse1RTTPacket parses a QUIC 1-RTT (short header) packet.
+//
+// On input, pkt contains a short header packet, k the decryption keys for the packet,
+// and pnumMax the largest packet number seen in the number space of this packet.
+func parse1RTTPacket(pkt []byte, k fixedKeys, pnumMax packetNumber) (p shortPacket, err error) {
+	pnumOff := 1 + connIDLen
+	var pay []byte
+	pay, p.num, err = k.unprotect(pkt, pnumOff, pnumMax)
+	if err != nil {
+		return shortPacket{}, err
+	}
+	if pkt[0]&reserved1RTT != 0 {
+		return shortPacket{}, errInvalidPacket
+	}
+	p.payload = pay
+	return p, nil
+}
+
+// consumeUint8Bytes parses a sequence of bytes prefixed with an 8-bit length,
+// returning the bytes and the remainder of the buffer.
+func consumeUint8Bytes(b []byte) (data, rest []byte, ok bool) {
+	if len(b) < 1 {
+		return nil, nil, false
+	}
+	size := int(b[0])
+	b = b[1:]
+	if len(b) < size {
+		return nil, nil, false
+	}
+	return b[:size], b[size:], true
+}
+
+// appendVersionNegotiation appends a Version Negotiation packet to pkt,
+// returning the result.
+//
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-17.2.1
+func appendVersionNegotiation(pkt, dstConnID, srcConnID []byte, versions ...uint32) []byte {
+	pkt = append(pkt, headerFormLong|fixedBit) // header byte
+	pkt = append(pkt, 0, 0, 0, 0)              // Version (0 for Version Negotiation)
+	pkt = append(pkt, byte(len(dstConnID)))
+	pkt = append(pkt, dstConnID...)
+	pkt = append(pkt, byte(len(srcConnID)))
+	pkt = append(pkt, srcConnID...)
+	for _, v := range versions {
+		pkt = append(pkt, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return pkt
+}
@@ -0,0 +1,40 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// A packetNumber is a QUIC packet number.
+// Packet numbers are integers in the range [0, 2^62-1].
+//
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-12.3
+type packetNumber int64
+
+const maxPacketNumber = 1<<62 - 1 // https://www.rfc-editor.org/rfc/rfc9000.html#section-17.1-1
+
+// packetNumberLen is the length of the packet number field in every
+// packet sent by this package. Always using the maximum length means
+// the encoding never depends on what the peer has acknowledged.
+const packetNumberLen = 4
+
+// decodePacketNumber decodes a truncated packet number, given
+// the largest acknowledged packet number in this number space,
+// the truncated number received in a packet, and the size of the
+// number received in bytes.
+//
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-17.1
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-a.3
+func decodePacketNumber(largest, truncated packetNumber, numLenInBytes int) packetNumber {
+	expected := largest + 1
+	win := packetNumber(1) << (uint(numLenInBytes) * 8)
+	hwin := win / 2
+	mask := win - 1
+	candidate := (expected &^ mask) | truncated
+	if candidate <= expected-hwin && candidate < (1<<62)-win {
+		return candidate + win
+	}
+	if candidate > expected+hwin && candidate >= win {
+		return candidate - win
+	}
+	return candidate
+}
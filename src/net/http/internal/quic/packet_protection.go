@@ -0,0 +1,354 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"hash"
+
+	"golang_org/x/crypto/chacha20poly1305"
+	"golang_org/x/crypto/hkdf"
+)
+
+var errInvalidPacket = errors.New("quic: invalid packet")
+
+// headerProtectionSampleSize is the size of the ciphertext sample used for header protection.
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.4.2
+const headerProtectionSampleSize = 16
+
+// aeadOverhead is the difference in size between the AEAD output and input.
+// All cipher suites defined for use with QUIC have 16 bytes of overhead.
+const aeadOverhead = 16
+
+// A headerKey applies or removes header protection.
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.4
+type headerKey struct {
+	hp headerProtection
+}
+
+func (k headerKey) isSet() bool {
+	return k.hp != nil
+}
+
+func (k *headerKey) init(suite uint16, secret []byte) {
+	h, keySize := hashForSuite(suite)
+	hpKey := hkdfExpandLabel(h.New, secret, "quic hp", nil, keySize)
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384:
+		c, err := aes.NewCipher(hpKey)
+		if err != nil {
+			panic(err)
+		}
+		k.hp = &aesHeaderProtection{cipher: c}
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		k.hp = newChaCha20HeaderProtection(hpKey)
+	default:
+		panic("BUG: unknown cipher suite")
+	}
+}
+
+// protect applies header protection.
+// pnumOff is the offset of the packet number in the packet.
+func (k headerKey) protect(hdr []byte, pnumOff int) {
+	// Apply header protection.
+	pnumSize := int(hdr[0]&0x03) + 1
+	sample := hdr[pnumOff+4:][:headerProtectionSampleSize]
+	mask := k.hp.headerProtection(sample)
+	if isLongHeader(hdr[0]) {
+		hdr[0] ^= mask[0] & 0x0f
+	} else {
+		hdr[0] ^= mask[0] & 0x1f
+	}
+	for i := 0; i < pnumSize; i++ {
+		hdr[pnumOff+i] ^= mask[1+i]
+	}
+}
+
+// unprotect removes header protection.
+// pnumOff is the offset of the packet number in the packet.
+// pnumMax is the largest packet number seen in the number space of this packet.
+func (k headerKey) unprotect(pkt []byte, pnumOff int, pnumMax packetNumber) (hdr, pay []byte, pnum packetNumber, _ error) {
+	if len(pkt) < pnumOff+4+headerProtectionSampleSize {
+		return nil, nil, 0, errInvalidPacket
+	}
+	numpay := pkt[pnumOff:]
+	sample := numpay[4:][:headerProtectionSampleSize]
+	mask := k.hp.headerProtection(sample)
+	if isLongHeader(pkt[0]) {
+		pkt[0] ^= mask[0] & 0x0f
+	} else {
+		pkt[0] ^= mask[0] & 0x1f
+	}
+	pnumLen := int((pkt[0] & 0x03) + 1)
+	pnum = packetNumber(0)
+	for i := 0; i < pnumLen; i++ {
+		numpay[i] ^= mask[1+i]
+		pnum = (pnum << 8) | packetNumber(numpay[i])
+	}
+	pnum = decodePacketNumber(pnumMax, pnum, pnumLen)
+	hdr = pkt[:pnumOff+pnumLen]
+	pay = numpay[pnumLen:]
+	return hdr, pay, pnum, nil
+}
+
+// headerProtection is the header_protection function as defined in:
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.4.1
+//
+// This function takes a sample of the packet ciphertext
+// and returns a 5-byte mask which will be applied to the
+// protected portions of the packet header.
+type headerProtection interface {
+	headerProtection(sample []byte) (mask [5]byte)
+}
+
+// AES-based header protection.
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.4.3
+type aesHeaderProtection struct {
+	cipher  cipher.Block
+	scratch [aes.BlockSize]byte
+}
+
+func (hp *aesHeaderProtection) headerProtection(sample []byte) (mask [5]byte) {
+	hp.cipher.Encrypt(hp.scratch[:], sample)
+	copy(mask[:], hp.scratch[:])
+	return mask
+}
+
+// ChaCha20-based header protection.
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.4.4
+type chaCha20HeaderProtection struct {
+	key [8]uint32
+}
+
+func newChaCha20HeaderProtection(key []byte) *chaCha20HeaderProtection {
+	hp := &chaCha20HeaderProtection{}
+	for i := range hp.key {
+		hp.key[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	return hp
+}
+
+func (hp *chaCha20HeaderProtection) headerProtection(sample []byte) (mask [5]byte) {
+	counter := binary.LittleEndian.Uint32(sample)
+	var nonce [3]uint32
+	for i := range nonce {
+		nonce[i] = binary.LittleEndian.Uint32(sample[4+4*i:])
+	}
+	// The mask is the ChaCha20 encryption of five zero bytes,
+	// which is the first five bytes of the keystream.
+	var block [64]byte
+	chaCha20Block(&block, &hp.key, counter, &nonce)
+	copy(mask[:], block[:])
+	return mask
+}
+
+// A packetKey applies or removes packet protection.
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.1
+type packetKey struct {
+	aead cipher.AEAD // AEAD function used for packet protection.
+	iv   []byte      // IV used to construct the AEAD nonce.
+}
+
+func (k *packetKey) init(suite uint16, secret []byte) {
+	// https://www.rfc-editor.org/rfc/rfc9001#section-5.1
+	h, keySize := hashForSuite(suite)
+	key := hkdfExpandLabel(h.New, secret, "quic key", nil, keySize)
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384:
+		k.aead = newAESAEAD(key)
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		k.aead = newChaCha20AEAD(key)
+	default:
+		panic("BUG: unknown cipher suite")
+	}
+	k.iv = hkdfExpandLabel(h.New, secret, "quic iv", nil, k.aead.NonceSize())
+}
+
+func newAESAEAD(key []byte) cipher.AEAD {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+func newChaCha20AEAD(key []byte) cipher.AEAD {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+func (k packetKey) xorIV(pnum packetNumber) {
+	// "The 62 bits of the reconstructed QUIC packet number in network byte order
+	// are left-padded with zeros to the size of the IV. The exclusive OR of the
+	// padded packet number and the IV forms the AEAD nonce."
+	// https://www.rfc-editor.org/rfc/rfc9001#section-5.3
+	for i := 0; i < 8; i++ {
+		k.iv[len(k.iv)-1-i] ^= byte(pnum >> (8 * uint(i)))
+	}
+}
+
+// protect applies packet protection to a packet.
+//
+// On input, hdr contains the packet header, pay the unencrypted payload,
+// and pnum the packet number. On output, the returned slice contains
+// the header and the encrypted payload followed by the AEAD tag.
+// pay is encrypted in place, and must be followed in memory by
+// space for the tag.
+func (k packetKey) protect(hdr, pay []byte, pnum packetNumber) []byte {
+	k.xorIV(pnum)
+	defer k.xorIV(pnum)
+	return k.aead.Seal(hdr, k.iv, pay, hdr)
+}
+
+// unprotect removes packet protection from a packet.
+//
+// On input, hdr contains the packet header, pay the encrypted payload,
+// and pnum the packet number. On output, the payload is decrypted in place.
+func (k packetKey) unprotect(hdr, pay []byte, pnum packetNumber) (dec []byte, err error) {
+	k.xorIV(pnum)
+	defer k.xorIV(pnum)
+	return k.aead.Open(pay[:0], k.iv, pay, hdr)
+}
+
+// fixedKeys is a header protection key and fixed packet protection key.
+// The packet protection key is fixed (it does not update).
+//
+// Fixed keys are used for Initial and Handshake keys, which do not update.
+// This implementation does not support key updates, so 1-RTT keys are
+// also fixed.
+type fixedKeys struct {
+	hdr headerKey
+	pkt packetKey
+}
+
+func (k *fixedKeys) init(suite uint16, secret []byte) {
+	k.hdr.init(suite, secret)
+	k.pkt.init(suite, secret)
+}
+
+func (k fixedKeys) isSet() bool {
+	return k.hdr.hp != nil
+}
+
+// protect applies packet protection to a packet.
+//
+// On input, hdr contains the packet header, pay the unencrypted payload,
+// pnumOff the offset of the packet number in the header, and pnum the
+// packet number. The packet number must be four bytes long.
+//
+// On output, the returned slice contains the protected packet.
+func (k fixedKeys) protect(hdr, pay []byte, pnumOff int, pnum packetNumber) []byte {
+	pkt := k.pkt.protect(hdr, pay, pnum)
+	k.hdr.protect(pkt, pnumOff)
+	return pkt
+}
+
+// unprotect removes packet protection from a packet.
+//
+// On input, pkt contains the full protected packet, pnumOff the offset of
+// the packet number in the header, and pnumMax the largest packet number
+// seen in the number space of this packet.
+//
+// On output, pay contains the unprotected payload and pnum the decoded
+// packet number.
+func (k fixedKeys) unprotect(pkt []byte, pnumOff int, pnumMax packetNumber) (pay []byte, pnum packetNumber, err error) {
+	hdr, pay, pnum, err := k.hdr.unprotect(pkt, pnumOff, pnumMax)
+	if err != nil {
+		return nil, 0, err
+	}
+	pay, err = k.pkt.unprotect(hdr, pay, pnum)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pay, pnum, nil
+}
+
+// fixedKeyPair is a read/write pair of fixed keys.
+type fixedKeyPair struct {
+	r, w fixedKeys
+}
+
+func (k *fixedKeyPair) discard() {
+	*k = fixedKeyPair{}
+}
+
+func (k *fixedKeyPair) canRead() bool {
+	return k.r.isSet()
+}
+
+func (k *fixedKeyPair) canWrite() bool {
+	return k.w.isSet()
+}
+
+// initialSalt is the salt used to derive Initial packet protection keys.
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.2
+var initialSalt = []byte{0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a}
+
+// initialKeys returns the keys used to protect Initial packets.
+//
+// The Initial packet keys are derived from the Destination Connection ID
+// field in the client's first Initial packet.
+//
+// https://www.rfc-editor.org/rfc/rfc9001#section-5.2
+func initialKeys(cid []byte, side connSide) fixedKeyPair {
+	initialSecret := hkdf.Extract(sha256.New, cid, initialSalt)
+	var clientKeys fixedKeys
+	clientSecret := hkdfExpandLabel(sha256.New, initialSecret, "client in", nil, sha256.Size)
+	clientKeys.init(tls.TLS_AES_128_GCM_SHA256, clientSecret)
+	var serverKeys fixedKeys
+	serverSecret := hkdfExpandLabel(sha256.New, initialSecret, "server in", nil, sha256.Size)
+	serverKeys.init(tls.TLS_AES_128_GCM_SHA256, serverSecret)
+	if side == clientSide {
+		return fixedKeyPair{r: serverKeys, w: clientKeys}
+	} else {
+		return fixedKeyPair{w: serverKeys, r: clientKeys}
+	}
+}
+
+// hashForSuite returns the hash function and key size for a TLS 1.3 cipher suite.
+func hashForSuite(suite uint16) (h crypto.Hash, keySize int) {
+	switch suite {
+	case tls.TLS_AES_128_GCM_SHA256:
+		return crypto.SHA256, 128 / 8
+	case tls.TLS_CHACHA20_POLY1305_SHA256:
+		return crypto.SHA256, chacha20poly1305.KeySize
+	case tls.TLS_AES_256_GCM_SHA384:
+		return crypto.SHA384, 256 / 8
+	default:
+		panic("BUG: unknown cipher suite")
+	}
+}
+
+// hkdfExpandLabel implements HKDF-Expand-Label from RFC 8446, Section 7.1.
+//
+// It mirrors cipherSuiteTLS13.expandLabel in crypto/tls.
+func hkdfExpandLabel(hash func() hash.Hash, secret []byte, label string, context []byte, length int) []byte {
+	var hkdfLabel []byte
+	hkdfLabel = append(hkdfLabel, byte(length>>8), byte(length))
+	hkdfLabel = append(hkdfLabel, byte(len("tls13 ")+len(label)))
+	hkdfLabel = append(hkdfLabel, "tls13 "...)
+	hkdfLabel = append(hkdfLabel, label...)
+	hkdfLabel = append(hkdfLabel, byte(len(context)))
+	hkdfLabel = append(hkdfLabel, context...)
+	out := make([]byte, length)
+	n, err := hkdf.Expand(hash, secret, hkdfLabel).Read(out)
+	if err != nil || n != length {
+		panic("quic: HKDF-Expand-Label invocation failed unexpectedly")
+	}
+	return out
+}
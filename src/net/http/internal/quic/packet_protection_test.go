@@ -0,0 +1,139 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang_org/x/crypto/hkdf"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Test vectors from RFC 9001, Appendix A.1.
+func TestInitialSecrets(t *testing.T) {
+	cid := unhex("8394c8f03e515708")
+	initialSecret := hkdf.Extract(sha256.New, cid, initialSalt)
+	for _, test := range []struct {
+		label               string
+		secret, key, iv, hp string
+	}{{
+		label:  "client in",
+		secret: "c00cf151ca5be075ed0ebfb5c80323c4 2d6b7db67881289af4008f1f6c357aea",
+		key:    "1f369613dd76d5467730efcbe3b1a22d",
+		iv:     "fa044b2f42a3fd3b46fb255c",
+		hp:     "9f50449e04a0e810283a1e9933adedd2",
+	}, {
+		label:  "server in",
+		secret: "3c199828fd139efd216c155ad844cc81 fb82fa8d7446fa7d78be803acdda951b",
+		key:    "cf3a5331653c364c88f0f379b6067e37",
+		iv:     "0ac1493ca1905853b0bba03e",
+		hp:     "c206b8d9b9f0f37644430b490eeaa314",
+	}} {
+		secret := hkdfExpandLabel(sha256.New, initialSecret, test.label, nil, sha256.Size)
+		if got, want := secret, unhex(test.secret); !bytes.Equal(got, want) {
+			t.Errorf("%v: secret = %x, want %x", test.label, got, want)
+		}
+		for _, x := range []struct {
+			label string
+			size  int
+			want  string
+		}{
+			{"quic key", 16, test.key},
+			{"quic iv", 12, test.iv},
+			{"quic hp", 16, test.hp},
+		} {
+			got := hkdfExpandLabel(sha256.New, secret, x.label, nil, x.size)
+			if want := unhex(x.want); !bytes.Equal(got, want) {
+				t.Errorf("%v: %q = %x, want %x", test.label, x.label, got, want)
+			}
+		}
+	}
+}
+
+// Test vector from RFC 9001, Appendix A.5.
+func TestChaCha20PacketProtection(t *testing.T) {
+	secret := unhex("9ac312a7f877468ebe69422748ad00a1 5443f18203a07d6060f688f30f21632b")
+	var k fixedKeys
+	k.init(tls.TLS_CHACHA20_POLY1305_SHA256, secret)
+
+	sample := unhex("5e5cd55c41f69080575d7999c25a5bfb")
+	if got, want := k.hdr.hp.headerProtection(sample), unhex("aefefe7d03"); !bytes.Equal(got[:], want) {
+		t.Errorf("header protection mask = %x, want %x", got, want)
+	}
+
+	const pnum = 654360564
+	protected := unhex("4cfe4189655e5cd55c41f69080575d7999c25a5bfb")
+	b := make([]byte, 4, 32)
+	copy(b, unhex("4200bff4"))
+	pay := append(b[4:], 0x01) // PING
+	got := k.protect(b[:4], pay, 1, pnum)
+	if !bytes.Equal(got, protected) {
+		t.Errorf("protect:\ngot  %x\nwant %x", got, protected)
+	}
+
+	pay, num, err := k.unprotect(append([]byte(nil), protected...), 1, pnum-1)
+	if err != nil {
+		t.Fatalf("unprotect: %v", err)
+	}
+	if num != pnum || !bytes.Equal(pay, []byte{0x01}) {
+		t.Errorf("unprotect = %x, %v; want 01, %v", pay, num, pnum)
+	}
+}
+
+func TestLongHeaderPacketRoundTrip(t *testing.T) {
+	cid := unhex("8394c8f03e515708")
+	clientKeys := initialKeys(cid, clientSide)
+	serverKeys := initialKeys(cid, serverSide)
+	p := longPacket{
+		ptype:     packetTypeInitial,
+		version:   quicVersion1,
+		num:       1234,
+		dstConnID: cid,
+		srcConnID: []byte{1, 2, 3, 4},
+	}
+	var w packetWriter
+	w.reset(maxDatagramSize)
+	w.startProtectedLongHeaderPacket(p)
+	w.appendPingFrame()
+	w.appendPaddingTo(minimumDatagram)
+	if sent := w.finishProtectedLongHeaderPacket(clientKeys.w, p); sent == nil {
+		t.Fatal("finishProtectedLongHeaderPacket returned nil")
+	}
+	dgram := w.datagram()
+	if len(dgram) != minimumDatagram {
+		t.Errorf("datagram size = %v, want %v", len(dgram), minimumDatagram)
+	}
+	if got := getPacketType(dgram); got != packetTypeInitial {
+		t.Errorf("getPacketType = %v, want %v", got, packetTypeInitial)
+	}
+	if id, ok := dstConnIDForDatagram(dgram); !ok || !bytes.Equal(id, cid) {
+		t.Errorf("dstConnIDForDatagram = %x, %v; want %x, true", id, ok, cid)
+	}
+	if _, n := parseLongHeaderPacket(append([]byte(nil), dgram...), clientKeys.r, 0); n >= 0 {
+		t.Errorf("packet decrypted with the wrong keys")
+	}
+	got, n := parseLongHeaderPacket(dgram, serverKeys.r, 1233)
+	if n != len(dgram) {
+		t.Fatalf("parseLongHeaderPacket consumed %v bytes, want %v", n, len(dgram))
+	}
+	if got.num != p.num || !bytes.Equal(got.dstConnID, p.dstConnID) || !bytes.Equal(got.srcConnID, p.srcConnID) {
+		t.Errorf("parsed packet = %+v, want %+v", got, p)
+	}
+	if len(got.payload) == 0 || got.payload[0] != frameTypePing {
+		t.Errorf("payload = %x, want PING frame", got.payload)
+	}
+}
@@ -0,0 +1,421 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// A packetWriter constructs QUIC datagrams.
+//
+// A datagram consists of one or more packets.
+// A packet consists of a header followed by one or more frames.
+//
+// Packets are written in three steps: startProtectedLongHeaderPacket or
+// start1RTTPacket prepares the packet, the append*Frame methods append
+// frames to the payload, and finishProtectedLongHeaderPacket or
+// finish1RTTPacket finalizes the packet.
+//
+// The start functions are efficient, so we can start speculatively
+// writing a packet before we know whether we have any frames to
+// put in it. The finish functions will abandon the packet if the
+// payload contains no data.
+type packetWriter struct {
+	dgramLim int // max datagram size
+	pktOff   int // offset of the start of the current packet
+	payOff   int // offset of the payload of the current packet
+	b        []byte
+	sent     *sentPacket
+}
+
+// reset prepares to write a datagram of at most lim bytes.
+func (w *packetWriter) reset(lim int) {
+	if cap(w.b) < lim {
+		w.b = make([]byte, 0, lim)
+	}
+	w.dgramLim = lim
+	w.b = w.b[:0]
+}
+
+// datagram returns the current datagram.
+func (w *packetWriter) datagram() []byte {
+	return w.b
+}
+
+// payload returns the payload of the current packet.
+func (w *packetWriter) payload() []byte {
+	return w.b[w.payOff:]
+}
+
+// avail reports how many more bytes may be written to the current packet.
+func (w *packetWriter) avail() int {
+	return w.dgramLim - aeadOverhead - len(w.b)
+}
+
+// startProtectedLongHeaderPacket starts writing an Initial or Handshake packet.
+func (w *packetWriter) startProtectedLongHeaderPacket(p longPacket) {
+	if w.sent == nil {
+		w.sent = &sentPacket{}
+	}
+	w.pktOff = len(w.b)
+	hdr := byte(headerFormLong | fixedBit | (packetNumberLen - 1))
+	switch p.ptype {
+	case packetTypeInitial:
+		hdr |= longPacketTypeInitial
+	case packetTypeHandshake:
+		hdr |= longPacketTypeHandshake
+	default:
+		panic("BUG: unexpected packet type")
+	}
+	w.b = append(w.b, hdr)
+	w.b = append(w.b, byte(p.version>>24), byte(p.version>>16), byte(p.version>>8), byte(p.version))
+	w.b = append(w.b, byte(len(p.dstConnID)))
+	w.b = append(w.b, p.dstConnID...)
+	w.b = append(w.b, byte(len(p.srcConnID)))
+	w.b = append(w.b, p.srcConnID...)
+	if p.ptype == packetTypeInitial {
+		// Token Length. This package never sends tokens.
+		w.b = appendVarint(w.b, 0)
+	}
+	// Length, filled in by finishProtectedLongHeaderPacket.
+	// Packets never exceed 16383 bytes, so a two-byte varint always suffices.
+	w.b = append(w.b, 0x40, 0)
+	w.b = appendPacketNumber(w.b, p.num)
+	w.payOff = len(w.b)
+}
+
+// finishProtectedLongHeaderPacket finishes writing an Initial or Handshake packet,
+// returning the packet's sentPacket record.
+//
+// If the packet contains no frames, it is abandoned and
+// finishProtectedLongHeaderPacket returns nil.
+func (w *packetWriter) finishProtectedLongHeaderPacket(k fixedKeys, p longPacket) *sentPacket {
+	if len(w.b) == w.payOff || w.avail() < 0 {
+		// The packet contains no frames, or there was no room for the header.
+		w.b = w.b[:w.pktOff]
+		return nil
+	}
+	pnumOff := w.payOff - packetNumberLen
+	length := len(w.b) - pnumOff + aeadOverhead
+	binary.BigEndian.PutUint16(w.b[pnumOff-2:], 0x4000|uint16(length))
+	return w.protect(k, pnumOff, p.num)
+}
+
+// start1RTTPacket starts writing a 1-RTT (short header) packet.
+func (w *packetWriter) start1RTTPacket(pnum packetNumber, dstConnID []byte) {
+	if w.sent == nil {
+		w.sent = &sentPacket{}
+	}
+	w.pktOff = len(w.b)
+	w.b = append(w.b, headerFormShort|fixedBit|(packetNumberLen-1))
+	w.b = append(w.b, dstConnID...)
+	w.b = appendPacketNumber(w.b, pnum)
+	w.payOff = len(w.b)
+}
+
+// finish1RTTPacket finishes writing a 1-RTT packet,
+// returning the packet's sentPacket record.
+//
+// If the packet contains no frames, it is abandoned and
+// finish1RTTPacket returns nil.
+func (w *packetWriter) finish1RTTPacket(k fixedKeys, pnum packetNumber) *sentPacket {
+	if len(w.b) == w.payOff || w.avail() < 0 {
+		w.b = w.b[:w.pktOff]
+		return nil
+	}
+	return w.protect(k, w.payOff-packetNumberLen, pnum)
+}
+
+// abandonPacket discards the current packet.
+func (w *packetWriter) abandonPacket() {
+	w.b = w.b[:w.pktOff]
+	w.sent = nil
+}
+
+func (w *packetWriter) protect(k fixedKeys, pnumOff int, pnum packetNumber) *sentPacket {
+	// Make sure the AEAD tag can be appended in place.
+	w.b = append(w.b, make([]byte, aeadOverhead)...)[:len(w.b)]
+	pkt := k.protect(w.b[w.pktOff:w.payOff], w.b[w.payOff:], pnumOff-w.pktOff, pnum)
+	w.b = w.b[:w.pktOff+len(pkt)]
+	sent := w.sent
+	w.sent = nil
+	sent.num = pnum
+	sent.size = len(pkt)
+	return sent
+}
+
+func appendPacketNumber(b []byte, pnum packetNumber) []byte {
+	return append(b, byte(pnum>>24), byte(pnum>>16), byte(pnum>>8), byte(pnum))
+}
+
+// appendPaddingTo appends PADDING frames until the datagram is at least n bytes long.
+func (w *packetWriter) appendPaddingTo(n int) {
+	n -= aeadOverhead
+	if len(w.b) >= n {
+		return
+	}
+	for len(w.b) < n {
+		w.b = append(w.b, frameTypePadding)
+	}
+	// PADDING frames are not ack-eliciting, but they count towards
+	// bytes in flight.
+	w.sent.inFlight = true
+}
+
+func (w *packetWriter) appendPingFrame() (added bool) {
+	if w.avail() < 1 {
+		return false
+	}
+	w.b = append(w.b, frameTypePing)
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypePing})
+	return true
+}
+
+// appendAckFrame appends an ACK frame acknowledging the packets in seen.
+// delay is the ACK Delay field, already scaled by the ack_delay_exponent.
+func (w *packetWriter) appendAckFrame(seen rangeset, delay uint64) (added bool) {
+	if len(seen) == 0 {
+		return false
+	}
+	var (
+		largest    = uint64(seen.max())
+		firstRange = uint64(seen[len(seen)-1].size() - 1)
+	)
+	if w.avail() < 1+sizeVarint(largest)+sizeVarint(delay)+1+sizeVarint(firstRange) {
+		return false
+	}
+	w.b = append(w.b, frameTypeAck)
+	w.b = appendVarint(w.b, largest)
+	w.b = appendVarint(w.b, delay)
+	// The range count is technically a varint, but we'll reserve a single byte for it
+	// and never add more than 62 ranges (the maximum varint that fits in a byte).
+	rangeCountOff := len(w.b)
+	w.b = append(w.b, 0)
+	w.b = appendVarint(w.b, firstRange)
+	rangeCount := byte(0)
+	for i := len(seen) - 2; i >= 0 && rangeCount < maxAckRanges; i-- {
+		gap := uint64(seen[i+1].start - seen[i].end - 1)
+		size := uint64(seen[i].size() - 1)
+		if w.avail() < sizeVarint(gap)+sizeVarint(size) {
+			break
+		}
+		w.b = appendVarint(w.b, gap)
+		w.b = appendVarint(w.b, size)
+		rangeCount++
+	}
+	w.b[rangeCountOff] = rangeCount
+	w.sent.appendNonAckElicitingFrame(sentFrame{typ: frameTypeAck, end: int64(largest)})
+	return true
+}
+
+func (w *packetWriter) appendResetStreamFrame(id streamID, code uint64, finalSize int64) (added bool) {
+	if w.avail() < 1+sizeVarint(uint64(id))+sizeVarint(code)+sizeVarint(uint64(finalSize)) {
+		return false
+	}
+	w.b = append(w.b, frameTypeResetStream)
+	w.b = appendVarint(w.b, uint64(id))
+	w.b = appendVarint(w.b, code)
+	w.b = appendVarint(w.b, uint64(finalSize))
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypeResetStream, id: id})
+	return true
+}
+
+func (w *packetWriter) appendStopSendingFrame(id streamID, code uint64) (added bool) {
+	if w.avail() < 1+sizeVarint(uint64(id))+sizeVarint(code) {
+		return false
+	}
+	w.b = append(w.b, frameTypeStopSending)
+	w.b = appendVarint(w.b, uint64(id))
+	w.b = appendVarint(w.b, code)
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypeStopSending, id: id})
+	return true
+}
+
+// appendCryptoFrame appends a CRYPTO frame.
+// It returns a []byte into which the data should be written and whether a frame was added.
+// The returned []byte may be smaller than size if the packet cannot hold all the data.
+func (w *packetWriter) appendCryptoFrame(off int64, size int) (_ []byte, added bool) {
+	max := w.avail()
+	max -= 1                        // frame type
+	max -= sizeVarint(uint64(off))  // offset
+	max -= sizeVarint(uint64(size)) // maximum length
+	if max <= 0 {
+		return nil, false
+	}
+	if max < size {
+		size = max
+	}
+	w.b = append(w.b, frameTypeCrypto)
+	w.b = appendVarint(w.b, uint64(off))
+	w.b = appendVarint(w.b, uint64(size))
+	start := len(w.b)
+	w.b = w.b[:start+size]
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypeCrypto, start: off, end: off + int64(size)})
+	return w.b[start:][:size], true
+}
+
+// appendStreamFrame appends a STREAM frame.
+// It returns a []byte into which the data should be written and whether a frame was added.
+// The returned []byte may be smaller than size if the packet cannot hold all the data.
+// If fin is set and the frame holds less than size bytes, the FIN bit is not set.
+func (w *packetWriter) appendStreamFrame(id streamID, off int64, size int, fin bool) (_ []byte, added bool) {
+	typ := byte(frameTypeStreamBase | streamLenBit)
+	max := w.avail()
+	max -= 1 // frame type
+	max -= sizeVarint(uint64(id))
+	if off != 0 {
+		max -= sizeVarint(uint64(off))
+		typ |= streamOffBit
+	}
+	max -= sizeVarint(uint64(size)) // maximum length
+	if max < 0 || (max == 0 && size > 0) {
+		return nil, false
+	}
+	if max < size {
+		size = max
+		fin = false
+	}
+	if fin {
+		typ |= streamFinBit
+	}
+	w.b = append(w.b, typ)
+	w.b = appendVarint(w.b, uint64(id))
+	if off != 0 {
+		w.b = appendVarint(w.b, uint64(off))
+	}
+	w.b = appendVarint(w.b, uint64(size))
+	start := len(w.b)
+	w.b = w.b[:start+size]
+	w.sent.appendAckElicitingFrame(sentFrame{
+		typ:   frameTypeStreamBase,
+		id:    id,
+		start: off,
+		end:   off + int64(size),
+		fin:   fin,
+	})
+	return w.b[start:][:size], true
+}
+
+func (w *packetWriter) appendMaxDataFrame(max int64) (added bool) {
+	if w.avail() < 1+sizeVarint(uint64(max)) {
+		return false
+	}
+	w.b = append(w.b, frameTypeMaxData)
+	w.b = appendVarint(w.b, uint64(max))
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypeMaxData, end: max})
+	return true
+}
+
+func (w *packetWriter) appendMaxStreamDataFrame(id streamID, max int64) (added bool) {
+	if w.avail() < 1+sizeVarint(uint64(id))+sizeVarint(uint64(max)) {
+		return false
+	}
+	w.b = append(w.b, frameTypeMaxStreamData)
+	w.b = appendVarint(w.b, uint64(id))
+	w.b = appendVarint(w.b, uint64(max))
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypeMaxStreamData, id: id, end: max})
+	return true
+}
+
+func (w *packetWriter) appendMaxStreamsFrame(typ streamType, max int64) (added bool) {
+	if w.avail() < 1+sizeVarint(uint64(max)) {
+		return false
+	}
+	var ftype byte
+	if typ == bidiStream {
+		ftype = frameTypeMaxStreamsBidi
+	} else {
+		ftype = frameTypeMaxStreamsUni
+	}
+	w.b = append(w.b, ftype)
+	w.b = appendVarint(w.b, uint64(max))
+	w.sent.appendAckElicitingFrame(sentFrame{typ: ftype, end: max})
+	return true
+}
+
+func (w *packetWriter) appendPathResponseFrame(data uint64) (added bool) {
+	if w.avail() < 1+8 {
+		return false
+	}
+	w.b = append(w.b, frameTypePathResponse)
+	w.b = append(w.b, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(w.b[len(w.b)-8:], data)
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypePathResponse})
+	return true
+}
+
+func (w *packetWriter) appendHandshakeDoneFrame() (added bool) {
+	if w.avail() < 1 {
+		return false
+	}
+	w.b = append(w.b, frameTypeHandshakeDone)
+	w.sent.appendAckElicitingFrame(sentFrame{typ: frameTypeHandshakeDone})
+	return true
+}
+
+func (w *packetWriter) appendConnectionCloseTransportFrame(code transportError, frameType uint64, reason string) (added bool) {
+	if w.avail() < 1+sizeVarint(uint64(code))+sizeVarint(frameType)+sizeVarint(uint64(len(reason)))+len(reason) {
+		return false
+	}
+	w.b = append(w.b, frameTypeConnectionCloseTransport)
+	w.b = appendVarint(w.b, uint64(code))
+	w.b = appendVarint(w.b, frameType)
+	w.b = appendVarintBytes(w.b, []byte(reason))
+	// We don't record CONNECTION_CLOSE frames in w.sent, since they are never acked or
+	// detected as lost.
+	return true
+}
+
+func (w *packetWriter) appendConnectionCloseApplicationFrame(code uint64, reason string) (added bool) {
+	if w.avail() < 1+sizeVarint(code)+sizeVarint(uint64(len(reason)))+len(reason) {
+		return false
+	}
+	w.b = append(w.b, frameTypeConnectionCloseApplication)
+	w.b = appendVarint(w.b, code)
+	w.b = appendVarintBytes(w.b, []byte(reason))
+	return true
+}
+
+// A sentPacket tracks state related to an in-flight packet we sent,
+// to be committed when the peer acks it or resent if the packet is lost.
+type sentPacket struct {
+	num  packetNumber
+	size int       // size in bytes
+	time time.Time // time sent
+
+	ackEliciting bool // https://www.rfc-editor.org/rfc/rfc9002.html#section-2-3.4.1
+	inFlight     bool // https://www.rfc-editor.org/rfc/rfc9002.html#section-2-3.6.1
+	acked        bool // ack has been received
+	lost         bool // packet is presumed lost
+	requeued     bool // frames have been queued for retransmission by a probe
+
+	// frames records the frames in the packet which need handling
+	// when the packet is acknowledged or lost.
+	frames []sentFrame
+}
+
+// A sentFrame records a frame in a sent packet.
+// The meaning of the fields depends on the frame type:
+//
+//	CRYPTO, STREAM: [start, end) is the data range; fin is the FIN bit.
+//	ACK: end is the largest acknowledged packet number.
+//	MAX_DATA, MAX_STREAM_DATA, MAX_STREAMS: end is the maximum value.
+type sentFrame struct {
+	typ        byte
+	id         streamID
+	start, end int64
+	fin        bool
+}
+
+func (sent *sentPacket) appendAckElicitingFrame(f sentFrame) {
+	sent.ackEliciting = true
+	sent.inFlight = true
+	sent.frames = append(sent.frames, f)
+}
+
+func (sent *sentPacket) appendNonAckElicitingFrame(f sentFrame) {
+	sent.frames = append(sent.frames, f)
+}
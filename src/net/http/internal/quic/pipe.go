@@ -0,0 +1,78 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// A pipe is a byte buffer used by streams, addressed by stream offset.
+//
+// A pipe contains a window of stream data.
+// Random access reads and writes are supported within the window.
+// Writing past the end of the window extends it.
+// Data may be discarded from the start of the pipe, advancing the window.
+type pipe struct {
+	start int64  // stream position of first stored byte
+	end   int64  // stream position just past the last stored byte
+	buf   []byte // buf[0] is the byte at stream position start
+}
+
+// writeAt writes len(b) bytes to the pipe at offset off.
+//
+// Writes to offsets before p.start are discarded.
+// Writes to offsets after p.end extend the pipe window.
+func (p *pipe) writeAt(b []byte, off int64) {
+	end := off + int64(len(b))
+	if end > p.end {
+		p.grow(end)
+	}
+	if off < p.start {
+		if end <= p.start {
+			return
+		}
+		b = b[p.start-off:]
+		off = p.start
+	}
+	copy(p.buf[off-p.start:], b)
+}
+
+// grow extends the pipe window to end.
+func (p *pipe) grow(end int64) {
+	n := int(end - p.start)
+	if n <= cap(p.buf) {
+		p.buf = p.buf[:n]
+	} else {
+		buf := make([]byte, n, 2*n)
+		copy(buf, p.buf)
+		p.buf = buf
+	}
+	p.end = end
+}
+
+// copy copies len(b) bytes into b starting from off.
+// The pipe must contain [off, off+len(b)).
+func (p *pipe) copy(off int64, b []byte) {
+	copy(b, p.buf[off-p.start:])
+}
+
+// bytes returns the bytes in the pipe starting at off,
+// which must be within the pipe window.
+func (p *pipe) bytes(off int64) []byte {
+	return p.buf[off-p.start:]
+}
+
+// discardBefore discards all data prior to off.
+func (p *pipe) discardBefore(off int64) {
+	if off <= p.start {
+		return
+	}
+	if off >= p.end {
+		p.start = off
+		p.end = off
+		p.buf = p.buf[:0]
+		return
+	}
+	// Reslicing drops the discarded prefix without copying;
+	// the space is reclaimed when grow next reallocates.
+	p.buf = p.buf[off-p.start:]
+	p.start = off
+}
@@ -0,0 +1,422 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	errStreamClosed    = errors.New("quic: stream closed")
+	errStreamReadOnly  = errors.New("quic: write to read-only stream")
+	errStreamWriteOnly = errors.New("quic: read from write-only stream")
+)
+
+// A Stream is an ordered byte stream.
+//
+// Streams may be bidirectional, read-only, or write-only.
+// Methods inappropriate for a stream's direction
+// (for example, writing to a read-only stream)
+// return errors.
+//
+// Read and Write may be called concurrently with each other and with
+// the other methods, but not with themselves.
+type Stream struct {
+	id   streamID
+	conn *Conn
+
+	// All fields below are guarded by conn.mu.
+
+	// Receive side.
+	in          pipe     // received data; in.start is the read offset
+	inset       rangeset // received ranges, above in.start
+	inwin       int64    // maximum received offset permitted (MAX_STREAM_DATA)
+	inmaxrecv   int64    // largest received offset
+	insize      int64    // final size, or -1 if not yet known
+	inresetcode int64    // RESET_STREAM code received from the peer, or -1
+	inclosed    bool     // StopSending or CloseRead called
+	instopcode  uint64   // STOP_SENDING code
+	insendstop  bool     // STOP_SENDING needs to be sent
+	insendmax   bool     // MAX_STREAM_DATA needs to be sent
+	inready     chan struct{}
+
+	// Send side.
+	out            pipe     // data written but not yet acked; out.start is the acked prefix
+	outunsent      rangeset // ranges needing to be sent
+	outacked       rangeset // ranges acked by the peer, above out.start
+	outwin         int64    // maximum sent offset permitted by the peer (MAX_STREAM_DATA)
+	outmaxsent     int64    // largest offset sent, for connection flow control
+	outmaxbuf      int64    // maximum amount of buffered data
+	outclosed      bool     // Close called
+	outfinunsent   bool     // FIN needs to be sent
+	outfinacked    bool     // FIN has been acked
+	outresetcode   int64    // RESET_STREAM code, or -1
+	outresetunsent bool     // RESET_STREAM needs to be sent
+	outresetacked  bool     // RESET_STREAM has been acked
+	outstopcode    int64    // STOP_SENDING code received from the peer, or -1
+	outready       chan struct{}
+
+	queued bool // in the conn's send queue
+	done   bool // removed from the conn's stream map
+
+	readDeadline  deadline
+	writeDeadline deadline
+}
+
+func newStream(c *Conn, id streamID) *Stream {
+	s := &Stream{
+		id:            id,
+		conn:          c,
+		insize:        -1,
+		inresetcode:   -1,
+		outresetcode:  -1,
+		outstopcode:   -1,
+		inready:       make(chan struct{}, 1),
+		outready:      make(chan struct{}, 1),
+		readDeadline:  makeDeadline(),
+		writeDeadline: makeDeadline(),
+	}
+	if !s.IsWriteOnly() {
+		s.inwin = c.config.maxStreamReadBufferSize()
+	}
+	if !s.IsReadOnly() {
+		s.outmaxbuf = c.config.maxStreamWriteBufferSize()
+	}
+	return s
+}
+
+// ID returns the QUIC stream ID of s.
+//
+// As specified in RFC 9000, the two least significant bits of a stream ID
+// indicate the initiator and directionality of the stream. The upper bits are
+// the stream number.
+func (s *Stream) ID() int64 {
+	return int64(s.id)
+}
+
+// IsReadOnly reports whether the stream is read-only
+// (a unidirectional stream created by the peer).
+func (s *Stream) IsReadOnly() bool {
+	return s.id.streamType() == uniStream && s.id.initiator() != s.conn.side
+}
+
+// IsWriteOnly reports whether the stream is write-only
+// (a unidirectional stream created locally).
+func (s *Stream) IsWriteOnly() bool {
+	return s.id.streamType() == uniStream && s.id.initiator() == s.conn.side
+}
+
+// Read reads data from the stream.
+//
+// Read returns as soon as at least one byte of data is available.
+//
+// If the peer closes the stream cleanly, Read returns io.EOF after
+// returning all data sent by the peer.
+// If the peer resets the stream, Read returns a StreamErrorCode.
+func (s *Stream) Read(b []byte) (n int, err error) {
+	if s.IsWriteOnly() {
+		return 0, errStreamWriteOnly
+	}
+	c := s.conn
+	timeout := s.readDeadline.wait()
+	if isClosedChan(timeout) {
+		return 0, errTimeout
+	}
+	for {
+		c.mu.Lock()
+		n, wait, err := s.readLocked(b)
+		c.mu.Unlock()
+		if !wait {
+			return n, err
+		}
+		select {
+		case <-s.inready:
+		case <-c.donec:
+		case <-timeout:
+			return 0, errTimeout
+		}
+	}
+}
+
+func (s *Stream) readLocked(b []byte) (n int, wait bool, err error) {
+	c := s.conn
+	if s.inresetcode >= 0 {
+		return 0, false, StreamErrorCode(s.inresetcode)
+	}
+	if s.inclosed {
+		return 0, false, errStreamClosed
+	}
+	if r := s.inset.rangeContaining(s.in.start); r.end > s.in.start {
+		avail := r.end - s.in.start
+		if int64(len(b)) > avail {
+			b = b[:avail]
+		}
+		if len(b) == 0 {
+			return 0, false, nil
+		}
+		s.in.copy(s.in.start, b)
+		s.consume(int64(len(b)))
+		return len(b), false, nil
+	}
+	if s.insize >= 0 && s.in.start >= s.insize {
+		c.streamRecvDone(s)
+		return 0, false, io.EOF
+	}
+	if c.finalErr != nil {
+		return 0, false, c.finalErr
+	}
+	return 0, true, nil
+}
+
+// consume advances the read offset by n bytes,
+// releasing flow control credit to the peer.
+func (s *Stream) consume(n int64) {
+	c := s.conn
+	s.in.discardBefore(s.in.start + n)
+	s.inset.sub(0, s.in.start)
+	c.connInflowConsumed(n)
+	if s.insize < 0 {
+		// Extend the window when half of it has been consumed.
+		win := c.config.maxStreamReadBufferSize()
+		if s.inwin-s.in.start < win/2 {
+			s.inwin = s.in.start + win
+			s.insendmax = true
+			c.queueStream(s)
+		}
+	}
+	if s.insize >= 0 && s.in.start >= s.insize {
+		c.streamRecvDone(s)
+	}
+}
+
+// Write writes data to the stream.
+//
+// Write blocks until all of b has been buffered for sending.
+// Buffered data is sent as flow and congestion control permit.
+// If the peer asks us to stop sending, Write returns a StreamErrorCode.
+func (s *Stream) Write(b []byte) (n int, err error) {
+	if s.IsReadOnly() {
+		return 0, errStreamReadOnly
+	}
+	c := s.conn
+	timeout := s.writeDeadline.wait()
+	if isClosedChan(timeout) {
+		return 0, errTimeout
+	}
+	for len(b) > 0 {
+		c.mu.Lock()
+		nn, wait, err := s.writeLocked(b)
+		c.mu.Unlock()
+		if nn > 0 {
+			c.wake()
+		}
+		n += nn
+		b = b[nn:]
+		if err != nil {
+			return n, err
+		}
+		if wait {
+			select {
+			case <-s.outready:
+			case <-c.donec:
+			case <-timeout:
+				return n, errTimeout
+			}
+		}
+	}
+	return n, nil
+}
+
+// SetReadDeadline sets the deadline for future and pending Read calls.
+// A Read which exceeds the deadline returns an error with a Timeout
+// method reporting true. A zero value for t means Read will not time out.
+func (s *Stream) SetReadDeadline(t time.Time) error {
+	s.readDeadline.set(t)
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for future and pending Write calls.
+// A Write which exceeds the deadline returns an error with a Timeout
+// method reporting true. Data buffered before the deadline is still sent.
+// A zero value for t means Write will not time out.
+func (s *Stream) SetWriteDeadline(t time.Time) error {
+	s.writeDeadline.set(t)
+	return nil
+}
+
+func (s *Stream) writeLocked(b []byte) (n int, wait bool, err error) {
+	c := s.conn
+	if s.outstopcode >= 0 {
+		return 0, false, StreamErrorCode(s.outstopcode)
+	}
+	if s.outclosed || s.outresetcode >= 0 {
+		return 0, false, errStreamClosed
+	}
+	if c.finalErr != nil {
+		return 0, false, c.finalErr
+	}
+	space := s.outmaxbuf - (s.out.end - s.out.start)
+	if space <= 0 {
+		return 0, true, nil
+	}
+	if int64(len(b)) > space {
+		b = b[:space]
+	}
+	start := s.out.end
+	s.out.writeAt(b, start)
+	s.outunsent.add(start, s.out.end)
+	c.queueStream(s)
+	return len(b), false, nil
+}
+
+// Close closes the write side of the stream, sending a FIN to the peer.
+// Data written before Close is still delivered.
+//
+// Close does not wait for the peer to acknowledge the data, and
+// does not close the read side of the stream; use CloseRead for that.
+func (s *Stream) Close() error {
+	if s.IsReadOnly() {
+		return s.CloseRead()
+	}
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.outclosed || s.outresetcode >= 0 {
+		return nil
+	}
+	s.outclosed = true
+	s.outfinunsent = true
+	c.queueStream(s)
+	c.wake()
+	return nil
+}
+
+// CloseRead aborts reads on the stream.
+// Any data sent by the peer and not yet read is discarded,
+// and the peer is asked to stop sending with an error code of 0.
+func (s *Stream) CloseRead() error {
+	return s.StopSending(0)
+}
+
+// StopSending aborts reads on the stream, asking the peer to stop
+// sending with the given application error code.
+// It has no effect if the stream has already been read to completion.
+func (s *Stream) StopSending(code uint64) error {
+	if s.IsWriteOnly() {
+		return errStreamWriteOnly
+	}
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.inclosed {
+		return nil
+	}
+	s.inclosed = true
+	s.signalReader()
+	if s.inresetcode >= 0 || (s.insize >= 0 && s.in.start >= s.insize) {
+		// The peer has finished sending.
+		c.streamRecvDone(s)
+		return nil
+	}
+	s.instopcode = code
+	s.insendstop = true
+	s.insendmax = false
+	// Discard buffered data and release its flow control credit.
+	s.discardIn()
+	c.queueStream(s)
+	c.wake()
+	return nil
+}
+
+// discardIn discards all data received on the stream,
+// after reads have been aborted.
+func (s *Stream) discardIn() {
+	if n := s.inmaxrecv - s.in.start; n > 0 {
+		s.consume(n)
+	}
+	s.in = pipe{start: s.in.start, end: s.in.start}
+	s.inset = nil
+}
+
+// Reset aborts writes on the stream and notifies the peer
+// that the stream was terminated abruptly, with the given
+// application error code.
+// Any unsent or unacknowledged data is discarded.
+func (s *Stream) Reset(code uint64) {
+	if s.IsReadOnly() {
+		return
+	}
+	c := s.conn
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.resetLocked(code)
+	c.wake()
+}
+
+func (s *Stream) resetLocked(code uint64) {
+	if s.outresetcode >= 0 || (s.outclosed && s.outfinacked && s.out.start == s.out.end) {
+		// Already reset, or all data has been delivered.
+		return
+	}
+	s.outresetcode = int64(code)
+	s.outresetunsent = true
+	s.outunsent = nil
+	s.outfinunsent = false
+	s.out.discardBefore(s.out.end)
+	s.signalWriter()
+	s.conn.queueStream(s)
+}
+
+func (s *Stream) signalReader() {
+	select {
+	case s.inready <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Stream) signalWriter() {
+	select {
+	case s.outready <- struct{}{}:
+	default:
+	}
+}
+
+// hasFramesToSend reports whether the stream has any frames to send.
+func (s *Stream) hasFramesToSend() bool {
+	if s.insendstop || s.insendmax || s.outresetunsent {
+		return true
+	}
+	if s.outresetcode >= 0 {
+		return false
+	}
+	return len(s.outunsent) > 0 || s.outfinunsent
+}
+
+// recvDone reports whether the receive side of the stream is finished.
+func (s *Stream) recvDone() bool {
+	if s.IsWriteOnly() {
+		return true
+	}
+	if s.insendstop {
+		return false
+	}
+	if s.inresetcode >= 0 {
+		return true
+	}
+	return s.insize >= 0 && s.in.start >= s.insize
+}
+
+// sendDone reports whether the send side of the stream is finished.
+func (s *Stream) sendDone() bool {
+	if s.IsReadOnly() {
+		return true
+	}
+	if s.outresetcode >= 0 {
+		return s.outresetacked
+	}
+	return s.outclosed && s.outfinacked && s.out.start == s.out.end
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+// A connSide distinguishes between the client and server sides of a connection.
+type connSide int8
+
+const (
+	clientSide = connSide(iota)
+	serverSide
+)
+
+func (s connSide) String() string {
+	switch s {
+	case clientSide:
+		return "client"
+	case serverSide:
+		return "server"
+	default:
+		return "BUG"
+	}
+}
+
+func (s connSide) peer() connSide {
+	if s == clientSide {
+		return serverSide
+	}
+	return clientSide
+}
+
+// A streamType is the type of a stream: bidirectional or unidirectional.
+type streamType uint8
+
+const (
+	bidiStream = streamType(iota)
+	uniStream
+	streamTypeCount
+)
+
+func (s streamType) String() string {
+	switch s {
+	case bidiStream:
+		return "bidi"
+	case uniStream:
+		return "uni"
+	default:
+		return "BUG"
+	}
+}
+
+// maxStreamsLimit is the maximum value of the MAX_STREAMS frame
+// and the initial_max_streams transport parameters.
+// https://www.rfc-editor.org/rfc/rfc9000#section-19.11-5
+const maxStreamsLimit = 1 << 60
+
+// A streamID is a QUIC stream ID.
+// https://www.rfc-editor.org/rfc/rfc9000.html#section-2.1
+type streamID uint64
+
+// The two least significant bits of a stream ID indicate the initiator
+// and directionality of the stream. The upper bits are the stream number.
+// Each of the four possible combinations of initiator and direction
+// each has a distinct number space.
+const (
+	clientInitiatedStreamBit = 0x0
+	serverInitiatedStreamBit = 0x1
+	initiatorStreamBitMask   = 0x1
+
+	bidiStreamBit    = 0x0
+	uniStreamBit     = 0x2
+	dirStreamBitMask = 0x2
+)
+
+func newStreamID(initiator connSide, typ streamType, num int64) streamID {
+	id := streamID(num << 2)
+	if typ == uniStream {
+		id |= uniStreamBit
+	}
+	if initiator == serverSide {
+		id |= serverInitiatedStreamBit
+	}
+	return id
+}
+
+func (s streamID) initiator() connSide {
+	if s&initiatorStreamBitMask == serverInitiatedStreamBit {
+		return serverSide
+	}
+	return clientSide
+}
+
+func (s streamID) num() int64 {
+	return int64(s) >> 2
+}
+
+func (s streamID) streamType() streamType {
+	if s&dirStreamBitMask == uniStreamBit {
+		return uniStream
+	}
+	return bidiStream
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"time"
+)
+
+// startTLS starts the TLS handshake.
+func (c *Conn) startTLS(now time.Time) error {
+	c.keys[initialSpace] = initialKeys(c.origDstConnID, c.side)
+
+	qconfig := &tls.QUICConfig{TLSConfig: c.config.tlsConfig()}
+	if c.side == clientSide {
+		c.tls = tls.QUICClient(qconfig)
+	} else {
+		c.tls = tls.QUICServer(qconfig)
+	}
+	c.tls.SetTransportParameters(marshalTransportParameters(c.localParams))
+	// The handshake goroutine is bounded by the handshake timeout and
+	// Conn.Close, so there is no need for a context here.
+	if err := c.tls.Start(context.Background()); err != nil {
+		return err
+	}
+	return c.handleTLSEvents(now)
+}
+
+// handleTLSData passes CRYPTO data received in a number space to TLS.
+func (c *Conn) handleTLSData(now time.Time, space numberSpace, data []byte) error {
+	if err := c.tls.HandleData(tlsLevelForSpace(space), data); err != nil {
+		return tlsTransportError(err)
+	}
+	return c.handleTLSEvents(now)
+}
+
+// handleTLSEvents processes events produced by the TLS handshake.
+func (c *Conn) handleTLSEvents(now time.Time) error {
+	for {
+		e := c.tls.NextEvent()
+		switch e.Kind {
+		case tls.QUICNoEvent:
+			return nil
+		case tls.QUICSetReadSecret:
+			space, err := spaceForTLSLevel(e.Level)
+			if err != nil {
+				return err
+			}
+			c.keys[space].r.init(e.Suite, e.Data)
+		case tls.QUICSetWriteSecret:
+			space, err := spaceForTLSLevel(e.Level)
+			if err != nil {
+				return err
+			}
+			c.keys[space].w.init(e.Suite, e.Data)
+		case tls.QUICWriteData:
+			space, err := spaceForTLSLevel(e.Level)
+			if err != nil {
+				return err
+			}
+			c.crypto[space].write(e.Data)
+		case tls.QUICTransportParameters:
+			if err := c.receiveTransportParameters(e.Data); err != nil {
+				return err
+			}
+		case tls.QUICTransportParametersRequired:
+			// We set our transport parameters before starting the handshake.
+		case tls.QUICHandshakeDone:
+			c.handshakeDone(now)
+		}
+	}
+}
+
+// receiveTransportParameters applies the peer's transport parameters.
+func (c *Conn) receiveTransportParameters(b []byte) error {
+	p, err := unmarshalTransportParams(b)
+	if err != nil {
+		return err
+	}
+	// Authenticate the connection IDs.
+	// https://www.rfc-editor.org/rfc/rfc9000#section-7.3
+	if !bytes.Equal(p.initialSrcConnID, c.peerConnID) {
+		return localTransportError{code: errTransportParameter, reason: "initial_source_connection_id does not match"}
+	}
+	if c.side == clientSide {
+		if !bytes.Equal(p.originalDstConnID, c.origDstConnID) {
+			return localTransportError{code: errTransportParameter, reason: "original_destination_connection_id does not match"}
+		}
+		if p.retrySrcConnID != nil {
+			// We never receive Retry packets, so the server must not send this.
+			return localTransportError{code: errTransportParameter, reason: "unexpected retry_source_connection_id"}
+		}
+	} else if p.originalDstConnID != nil || p.retrySrcConnID != nil || p.statelessResetToken != nil {
+		// "A client MUST NOT include any server-only transport parameter [...]"
+		// https://www.rfc-editor.org/rfc/rfc9000#section-18.2-4
+		return localTransportError{code: errTransportParameter, reason: "client sent server-only transport parameter"}
+	}
+	c.peerParams = p
+	c.gotPeerParams = true
+
+	c.outflow.max = p.initialMaxData
+	c.streams.setLocalLimit(bidiStream, p.initialMaxStreamsBidi)
+	c.streams.setLocalLimit(uniStream, p.initialMaxStreamsUni)
+	c.loss.peerMaxAckDelay = p.maxAckDelay
+	// "Each endpoint advertises a max_idle_timeout, but the effective value
+	// at an endpoint is computed as the minimum of the two advertised values [...]"
+	// https://www.rfc-editor.org/rfc/rfc9000#section-10.1-2
+	if p.maxIdleTimeout > 0 && (c.idleTimeout == 0 || p.maxIdleTimeout < c.idleTimeout) {
+		c.idleTimeout = p.maxIdleTimeout
+	}
+	return nil
+}
+
+// handshakeDone is called when the TLS handshake completes.
+func (c *Conn) handshakeDone(now time.Time) {
+	c.handshakeComplete = true
+	select {
+	case <-c.readyc:
+	default:
+		close(c.readyc)
+	}
+	if c.side == serverSide {
+		// "[...] the TLS handshake is considered confirmed at the server
+		// when the handshake completes."
+		// https://www.rfc-editor.org/rfc/rfc9001#section-4.1.2-1
+		c.sendHandshakeDone = true
+		c.confirmHandshake(now)
+		c.endpoint.serverConnEstablished(c)
+	}
+}
+
+// confirmHandshake is called when the handshake is confirmed.
+// https://www.rfc-editor.org/rfc/rfc9001#section-4.1.2
+func (c *Conn) confirmHandshake(now time.Time) {
+	if c.handshakeConfirmed {
+		return
+	}
+	c.handshakeConfirmed = true
+	c.loss.handshakeConfirmed = true
+	c.loss.timerDirty = true
+	// "An endpoint MUST discard its Handshake keys when the
+	// TLS handshake is confirmed."
+	// https://www.rfc-editor.org/rfc/rfc9001#section-4.9.2-1
+	c.discardKeys(handshakeSpace)
+}
+
+func tlsLevelForSpace(space numberSpace) tls.QUICEncryptionLevel {
+	switch space {
+	case initialSpace:
+		return tls.QUICEncryptionLevelInitial
+	case handshakeSpace:
+		return tls.QUICEncryptionLevelHandshake
+	default:
+		return tls.QUICEncryptionLevelApplication
+	}
+}
+
+func spaceForTLSLevel(level tls.QUICEncryptionLevel) (numberSpace, error) {
+	switch level {
+	case tls.QUICEncryptionLevelInitial:
+		return initialSpace, nil
+	case tls.QUICEncryptionLevelHandshake:
+		return handshakeSpace, nil
+	case tls.QUICEncryptionLevelApplication:
+		return appDataSpace, nil
+	default:
+		// 0-RTT is not supported.
+		return 0, localTransportError{code: errInternal, reason: "unsupported TLS encryption level " + level.String()}
+	}
+}
+
+// tlsHandshakeFailure is the TLS handshake_failure alert.
+const tlsHandshakeFailure = 40
+
+// tlsTransportError converts a TLS handshake error to a transport error.
+//
+// The TLS alert that caused the failure is not available from crypto/tls,
+// so failures are reported with the generic handshake_failure alert.
+// https://www.rfc-editor.org/rfc/rfc9001#section-4.8
+func tlsTransportError(err error) error {
+	var terr localTransportError
+	if errors.As(err, &terr) {
+		return terr
+	}
+	return localTransportError{code: errTLSBase + tlsHandshakeFailure, reason: err.Error()}
+}
@@ -0,0 +1,365 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	. "net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wrapResponseWriter wraps a ResponseWriter, hiding its optional
+// interfaces, and exposes the original with an Unwrap method.
+type wrapResponseWriter struct {
+	ResponseWriter
+}
+
+func (w wrapResponseWriter) Unwrap() ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestResponseControllerFlush_h1(t *testing.T) { testResponseControllerFlush(t, h1Mode) }
+func TestResponseControllerFlush_h2(t *testing.T) { testResponseControllerFlush(t, h2Mode) }
+func testResponseControllerFlush(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	continuec := make(chan struct{})
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(wrapResponseWriter{w})
+		w.Write([]byte("one"))
+		if err := ctl.Flush(); err != nil {
+			t.Errorf("ctl.Flush() = %v, want nil", err)
+			return
+		}
+		<-continuec
+		w.Write([]byte("two"))
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected connection error: %v", err)
+	}
+	defer res.Body.Close()
+
+	buf := make([]byte, 16)
+	n, err := res.Body.Read(buf)
+	close(continuec)
+	if err != nil || string(buf[:n]) != "one" {
+		t.Fatalf("Body.Read = %q, %v, want %q, nil", string(buf[:n]), err, "one")
+	}
+
+	got, err := ioutil.ReadAll(res.Body)
+	if err != nil || string(got) != "two" {
+		t.Fatalf("Body.Read = %q, %v, want %q, nil", string(got), err, "two")
+	}
+}
+
+func TestResponseControllerHijack_h1(t *testing.T) { testResponseControllerHijack(t, h1Mode) }
+func TestResponseControllerHijack_h2(t *testing.T) { testResponseControllerHijack(t, h2Mode) }
+func testResponseControllerHijack(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	const header = "X-Header"
+	const value = "set"
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(wrapResponseWriter{w})
+		if h2 {
+			if _, _, err := ctl.Hijack(); !errors.Is(err, ErrNotSupported) {
+				t.Errorf("ctl.Hijack = %v, want ErrNotSupported", err)
+			}
+			w.Header().Set(header, value)
+			return
+		}
+		c, _, err := ctl.Hijack()
+		if err != nil {
+			t.Errorf("ctl.Hijack = _, _, %v, want _, _, nil", err)
+			return
+		}
+		fmt.Fprintf(c, "HTTP/1.0 200 OK\r\n%v: %v\r\nContent-Length: 0\r\n\r\n", header, value)
+		c.Close()
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got, want := res.Header.Get(header), value; got != want {
+		t.Errorf("response header %q = %q, want %q", header, got, want)
+	}
+}
+
+func TestResponseControllerSetPastWriteDeadline_h1(t *testing.T) {
+	testResponseControllerSetPastWriteDeadline(t, h1Mode)
+}
+func TestResponseControllerSetPastWriteDeadline_h2(t *testing.T) {
+	testResponseControllerSetPastWriteDeadline(t, h2Mode)
+}
+func testResponseControllerSetPastWriteDeadline(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	errc := make(chan error, 1)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(w)
+		w.Write([]byte("one"))
+		if err := ctl.Flush(); err != nil {
+			t.Errorf("before setting deadline: ctl.Flush() = %v, want nil", err)
+		}
+		if err := ctl.SetWriteDeadline(time.Now().Add(-10 * time.Second)); err != nil {
+			t.Errorf("ctl.SetWriteDeadline() = %v, want nil", err)
+		}
+
+		w.Write([]byte("two"))
+		if err := ctl.Flush(); err == nil {
+			// The HTTP/2 deadline resets the stream asynchronously,
+			// so keep writing until it takes effect.
+			for i := 0; i < 1000 && err == nil; i++ {
+				w.Write(make([]byte, 1024))
+				err = ctl.Flush()
+				time.Sleep(time.Millisecond)
+			}
+			if err == nil {
+				errc <- errors.New("writes past the deadline kept succeeding")
+				return
+			}
+		}
+		errc <- nil
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err == nil {
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+	}
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResponseControllerSetFutureWriteDeadline_h1(t *testing.T) {
+	testResponseControllerSetFutureWriteDeadline(t, h1Mode)
+}
+func TestResponseControllerSetFutureWriteDeadline_h2(t *testing.T) {
+	testResponseControllerSetFutureWriteDeadline(t, h2Mode)
+}
+func testResponseControllerSetFutureWriteDeadline(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	errc := make(chan error, 1)
+	startwritec := make(chan struct{})
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctl := NewResponseController(w)
+		w.WriteHeader(200)
+		if err := ctl.Flush(); err != nil {
+			t.Errorf("ctl.Flush() = %v, want nil", err)
+		}
+		<-startwritec // don't set the deadline until the client reads response headers
+		if err := ctl.SetWriteDeadline(time.Now().Add(1 * time.Millisecond)); err != nil {
+			t.Errorf("ctl.SetWriteDeadline() = %v, want nil", err)
+		}
+		_, err := io.Copy(w, neverEnding('a'))
+		errc <- err
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	close(startwritec)
+	if err != nil {
+		t.Fatalf("unexpected connection error: %v", err)
+	}
+	defer res.Body.Close()
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	if err == nil {
+		t.Errorf("client reading from truncated request body: got nil error, want non-nil")
+	}
+	err = <-errc // io.Copy error
+	if err == nil {
+		t.Errorf("server timed out writing request body: got nil error, want non-nil")
+	}
+}
+
+func TestResponseControllerSetPastReadDeadline_h1(t *testing.T) {
+	testResponseControllerSetPastReadDeadline(t, h1Mode)
+}
+func TestResponseControllerSetPastReadDeadline_h2(t *testing.T) {
+	testResponseControllerSetPastReadDeadline(t, h2Mode)
+}
+func testResponseControllerSetPastReadDeadline(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	readc := make(chan struct{})
+	donec := make(chan struct{})
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		defer close(donec)
+		ctl := NewResponseController(w)
+		b := make([]byte, 3)
+		n, err := io.ReadFull(r.Body, b)
+		b = b[:n]
+		if err != nil || string(b) != "one" {
+			t.Errorf("before setting read deadline: Read = %v, %q, want nil, %q", err, string(b), "one")
+			return
+		}
+		if err := ctl.SetReadDeadline(time.Now()); err != nil {
+			t.Errorf("ctl.SetReadDeadline() = %v, want nil", err)
+			return
+		}
+		b, err = ioutil.ReadAll(r.Body)
+		if err == nil || string(b) != "" {
+			t.Errorf("after setting read deadline: Read = %q, nil, want error", string(b))
+		}
+		close(readc)
+	}))
+	defer cst.close()
+
+	pr, pw := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer pw.Close()
+		pw.Write([]byte("one"))
+		select {
+		case <-readc:
+		case <-donec:
+			select {
+			case <-readc:
+			default:
+				t.Errorf("server handler unexpectedly exited without closing readc")
+				return
+			}
+		}
+		pw.Write([]byte("two"))
+	}()
+	defer wg.Wait()
+	res, err := cst.c.Post(cst.ts.URL, "text/foo", pr)
+	if err == nil {
+		defer res.Body.Close()
+	}
+}
+
+func TestResponseControllerSetFutureReadDeadline_h1(t *testing.T) {
+	testResponseControllerSetFutureReadDeadline(t, h1Mode)
+}
+func TestResponseControllerSetFutureReadDeadline_h2(t *testing.T) {
+	testResponseControllerSetFutureReadDeadline(t, h2Mode)
+}
+func testResponseControllerSetFutureReadDeadline(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	respBody := "response body"
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, req *Request) {
+		ctl := NewResponseController(w)
+		if err := ctl.SetReadDeadline(time.Now().Add(1 * time.Millisecond)); err != nil {
+			t.Errorf("ctl.SetReadDeadline() = %v, want nil", err)
+		}
+		_, err := io.Copy(ioutil.Discard, req.Body)
+		if err == nil {
+			t.Errorf("server reading from request body: got nil error, want non-nil")
+		}
+		w.Write([]byte(respBody))
+	}))
+	defer cst.close()
+
+	pr, pw := io.Pipe()
+	res, err := cst.c.Post(cst.ts.URL, "text/apocryphal", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	got, err := ioutil.ReadAll(res.Body)
+	if string(got) != respBody || err != nil {
+		t.Errorf("client read response body: %q, %v; want %q, nil", string(got), err, respBody)
+	}
+	pw.Close()
+}
+
+func TestResponseControllerEnableFullDuplex_h1(t *testing.T) {
+	testResponseControllerEnableFullDuplex(t, h1Mode)
+}
+func TestResponseControllerEnableFullDuplex_h2(t *testing.T) {
+	testResponseControllerEnableFullDuplex(t, h2Mode)
+}
+func testResponseControllerEnableFullDuplex(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, req *Request) {
+		ctl := NewResponseController(wrapResponseWriter{w})
+		if err := ctl.EnableFullDuplex(); err != nil {
+			t.Errorf("ctl.EnableFullDuplex() = %v, want nil", err)
+		}
+		w.WriteHeader(200)
+		ctl.Flush()
+		// Echo each line of the request body as it arrives.
+		br := bufio.NewReader(req.Body)
+		for {
+			line, err := br.ReadString('\n')
+			if line != "" {
+				io.WriteString(w, strings.ToUpper(line))
+				ctl.Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer cst.close()
+
+	pr, pw := io.Pipe()
+	res, err := cst.c.Post(cst.ts.URL, "text/plain", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	br := bufio.NewReader(res.Body)
+	for _, line := range []string{"one\n", "two\n", "three\n"} {
+		if _, err := io.WriteString(pw, line); err != nil {
+			t.Fatal(err)
+		}
+		got, err := br.ReadString('\n')
+		if want := strings.ToUpper(line); got != want || err != nil {
+			t.Fatalf("read echoed line = %q, %v; want %q, nil", got, err, want)
+		}
+	}
+	pw.Close()
+	if rest, err := ioutil.ReadAll(br); len(rest) != 0 || err != nil {
+		t.Errorf("read rest of response = %q, %v; want empty, nil", rest, err)
+	}
+}
+
+// minimalResponseWriter implements only the ResponseWriter interface.
+type minimalResponseWriter struct{}
+
+func (minimalResponseWriter) Header() Header              { return Header{} }
+func (minimalResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (minimalResponseWriter) WriteHeader(int)             {}
+
+func TestResponseControllerNotSupported(t *testing.T) {
+	ctl := NewResponseController(wrapResponseWriter{minimalResponseWriter{}})
+	if err := ctl.Flush(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Flush = %v, want ErrNotSupported", err)
+	}
+	if _, _, err := ctl.Hijack(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Hijack = %v, want ErrNotSupported", err)
+	}
+	if err := ctl.SetReadDeadline(time.Time{}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("SetReadDeadline = %v, want ErrNotSupported", err)
+	}
+	if err := ctl.SetWriteDeadline(time.Time{}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("SetWriteDeadline = %v, want ErrNotSupported", err)
+	}
+	if err := ctl.EnableFullDuplex(); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("EnableFullDuplex = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// White-box tests for routing_tree.go (in package http instead of http_test).
+
+package http
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoutingFirstSegment(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want []string
+	}{
+		{"/a/b/c", []string{"a", "b", "c"}},
+		{"/a/b/", []string{"a", "b", "/"}},
+		{"/", []string{"/"}},
+		{"/a/%62/c", []string{"a", "b", "c"}},
+		{"/a%2Fb%2fc", []string{"a/b/c"}},
+	} {
+		var got []string
+		rest := test.in
+		for len(rest) > 0 {
+			var seg string
+			seg, rest = firstSegment(rest)
+			got = append(got, seg)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func getTestTree() *routingNode {
+	root := &routingNode{}
+	for _, p := range []string{
+		"/a",
+		"/a/b",
+		"/a/{x}",
+		"/g/h/i",
+		"/g/{x}/j",
+		"/a/b/{x...}",
+		"/a/b/{y}",
+		"/a/b/{$}",
+		"/a/{x}/c/{y...}",
+		"GET /foo",
+		"POST /foo",
+		"DELETE /bar/{x}",
+		"example.com/",
+		"example.com/a/{x}",
+		"GET example.com/foo",
+	} {
+		pat, err := parsePattern(p)
+		if err != nil {
+			panic(err)
+		}
+		root.addPattern(pat, nil)
+	}
+	return root
+}
+
+func TestRoutingMatch(t *testing.T) {
+	root := getTestTree()
+	for _, test := range []struct {
+		host, method, path string
+		wantPat            string // "" for nil (no match)
+		wantMatches        []string
+	}{
+		{"", "GET", "/a", "/a", nil},
+		{"", "GET", "/b", "", nil},
+		{"", "GET", "/a/b", "/a/b", nil},
+		{"", "GET", "/a/c", "/a/{x}", []string{"c"}},
+		{"", "GET", "/a/b/", "/a/b/{$}", nil},
+		{"", "GET", "/a/b/c", "/a/b/{y}", []string{"c"}},
+		{"", "GET", "/a/b/c/d", "/a/b/{x...}", []string{"c/d"}},
+		{"", "GET", "/g/h/i", "/g/h/i", nil},
+		{"", "GET", "/g/h/j", "/g/{x}/j", []string{"h"}},
+		{"", "GET", "/a/z/c/d/e", "/a/{x}/c/{y...}", []string{"z", "d/e"}},
+		{"", "GET", "/a/%7a/c/%64", "/a/{x}/c/{y...}", []string{"z", "d"}},
+		{"", "GET", "/foo", "GET /foo", nil},
+		{"", "HEAD", "/foo", "GET /foo", nil},
+		{"", "POST", "/foo", "POST /foo", nil},
+		{"", "PUT", "/foo", "", nil},
+		{"", "DELETE", "/bar/1", "DELETE /bar/{x}", []string{"1"}},
+		{"example.com", "GET", "/", "example.com/", nil},
+		{"example.com", "GET", "/a/b", "example.com/a/{x}", []string{"b"}},
+		{"example.com", "GET", "/foo", "GET example.com/foo", nil},
+		{"example.com", "POST", "/foo", "example.com/", nil},
+		{"example.com", "GET", "/g/h/i", "example.com/", nil},
+		{"other.com", "GET", "/a/b", "/a/b", nil},
+	} {
+		n, matches := root.match(test.host, test.method, test.path)
+		got := ""
+		if n != nil {
+			got = n.pattern.String()
+		}
+		if got != test.wantPat {
+			t.Errorf("%s %s %s: got pattern %q, want %q", test.method, test.host, test.path, got, test.wantPat)
+			continue
+		}
+		if !reflect.DeepEqual(matches, test.wantMatches) {
+			t.Errorf("%s %s %s: got matches %q, want %q", test.method, test.host, test.path, matches, test.wantMatches)
+		}
+	}
+}
+
+func TestRoutingMatchingMethods(t *testing.T) {
+	root := getTestTree()
+	for _, test := range []struct {
+		host, path string
+		want       string
+	}{
+		{"", "/foo", "GET,HEAD,POST"},
+		{"", "/bar/1", "DELETE"},
+		{"", "/bar/1/2", ""},
+		{"", "/a", ""}, // matches any method
+		{"example.com", "/foo", "GET,HEAD,POST"},
+	} {
+		ms := map[string]bool{}
+		root.matchingMethods(test.host, test.path, ms)
+		got := strings.Join(sortedMethods(ms), ",")
+		if got != test.want {
+			t.Errorf("%s %s: got %q, want %q", test.host, test.path, got, test.want)
+		}
+	}
+}
@@ -396,6 +396,171 @@ func TestServeMuxHandleFuncWithNilHandler(t *testing.T) {
 	mux.HandleFunc("/", nil)
 }
 
+func TestServeMuxHandlePatternConflict(t *testing.T) {
+	setParallel(t)
+	for _, test := range []struct {
+		p1, p2 string
+	}{
+		{"/a", "/a"},
+		{"/{x}", "/{y}"},
+		{"/a/{x}", "/{y}/b"},
+		{"GET /", "/foo"},
+		{"example.com/{$}", "example.com/{$}"},
+	} {
+		func() {
+			defer func() {
+				err := recover()
+				if err == nil {
+					t.Errorf("registering %q after %q: got no panic", test.p2, test.p1)
+					return
+				}
+				if !strings.Contains(fmt.Sprint(err), "conflicts with pattern") {
+					t.Errorf("registering %q after %q: got %v, want conflict", test.p2, test.p1, err)
+				}
+			}()
+			mux := NewServeMux()
+			mux.Handle(test.p1, serve(200))
+			mux.Handle(test.p2, serve(200))
+		}()
+	}
+
+	// Patterns that differ in host or where one is more specific
+	// than the other may be registered together.
+	mux := NewServeMux()
+	for _, p := range []string{
+		"/", "/{$}", "/a", "/a/{x}", "/a/{x}/{rest...}", "GET /b", "POST /b",
+		"HEAD /b", "example.com/a", "example.com/{$}",
+	} {
+		mux.Handle(p, serve(200))
+	}
+}
+
+func TestServeMuxHandlePatternErrors(t *testing.T) {
+	setParallel(t)
+	for _, p := range []string{"/{x", "/{x}/{x}", "G(ET /", "/a/{$}/b", "{x}/"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q: expected panic", p)
+				}
+			}()
+			NewServeMux().Handle(p, serve(200))
+		}()
+	}
+}
+
+func TestServeMuxPatterns(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	register := func(pat string) {
+		mux.HandleFunc(pat, func(w ResponseWriter, r *Request) {
+			fmt.Fprintf(w, "%s|%s|%s", pat, r.PathValue("x"), r.PathValue("rest"))
+		})
+	}
+	for _, p := range []string{
+		"/{$}",
+		"/items/{x}",
+		"GET /items/new",
+		"POST /items/{$}",
+		"/files/{rest...}",
+		"DELETE /items/{x}/tags/{rest...}",
+		"example.com/items/{x}",
+	} {
+		register(p)
+	}
+
+	for _, tt := range []struct {
+		method, host, path string
+		code               int
+		body               string
+		allow              string
+	}{
+		{"GET", "", "/", 200, "/{$}||", ""},
+		{"GET", "", "/items/42", 200, "/items/{x}|42|", ""},
+		{"PUT", "", "/items/42", 200, "/items/{x}|42|", ""},
+		{"GET", "", "/items/new", 200, "GET /items/new||", ""},
+		{"HEAD", "", "/items/new", 200, "", ""},
+		{"POST", "", "/items/new", 200, "/items/{x}|new|", ""},
+		{"POST", "", "/items/", 200, "POST /items/{$}||", ""},
+		{"GET", "", "/items/a%2Fb", 200, "/items/{x}|a/b|", ""},
+		{"GET", "", "/files/a/b/c", 200, "/files/{rest...}||a/b/c", ""},
+		{"GET", "", "/files/", 200, "/files/{rest...}||", ""},
+		{"GET", "example.com", "/items/7", 200, "example.com/items/{x}|7|", ""},
+		{"GET", "example.com:8080", "/items/7", 200, "example.com/items/{x}|7|", ""},
+		{"DELETE", "", "/items/3/tags/a/b", 200, "DELETE /items/{x}/tags/{rest...}|3|a/b", ""},
+		{"GET", "", "/items/3/tags/a", 405, "Method Not Allowed\n", "DELETE"},
+		{"GET", "", "/items/", 405, "Method Not Allowed\n", "POST"},
+		{"GET", "", "/nothing", 404, "404 page not found\n", ""},
+	} {
+		r := httptest.NewRequest(tt.method, "http://"+defaultHost(tt.host)+tt.path, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, r)
+		if rr.Code != tt.code {
+			t.Errorf("%s %s%s: code = %d, want %d", tt.method, tt.host, tt.path, rr.Code, tt.code)
+			continue
+		}
+		if tt.method != "HEAD" {
+			if got := rr.Body.String(); got != tt.body {
+				t.Errorf("%s %s%s: body = %q, want %q", tt.method, tt.host, tt.path, got, tt.body)
+			}
+		}
+		if got := rr.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s%s: Allow = %q, want %q", tt.method, tt.host, tt.path, got, tt.allow)
+		}
+	}
+}
+
+func defaultHost(host string) string {
+	if host == "" {
+		return "example.org"
+	}
+	return host
+}
+
+func TestServeMuxMethodNotAllowedAllow(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	mux.Handle("GET /r", serve(200))
+	mux.Handle("POST /r", serve(200))
+	mux.Handle("PUT /r/", serve(200))
+	r := httptest.NewRequest("DELETE", "/r", nil)
+	h, pattern := mux.Handler(r)
+	if pattern != "" {
+		t.Errorf("pattern = %q, want empty", pattern)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, r)
+	if rr.Code != StatusMethodNotAllowed {
+		t.Fatalf("code = %d, want %d", rr.Code, StatusMethodNotAllowed)
+	}
+	if got, want := rr.Header().Get("Allow"), "GET, HEAD, POST, PUT"; got != want {
+		t.Errorf("Allow = %q, want %q", got, want)
+	}
+}
+
+func TestRequestPathValue(t *testing.T) {
+	setParallel(t)
+	mux := NewServeMux()
+	var got []string
+	mux.HandleFunc("/{a}/x/{b}/{rest...}", func(w ResponseWriter, r *Request) {
+		got = append(got, r.PathValue("a"), r.PathValue("b"), r.PathValue("rest"), r.PathValue("c"))
+		r.SetPathValue("a", "A")
+		r.SetPathValue("c", "C")
+		got = append(got, r.PathValue("a"), r.PathValue("c"))
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/p/x/q/r/s%20t", nil))
+	want := []string{"p", "q", "r/s t", "", "A", "C"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Requests not routed through a ServeMux have no path values.
+	r := httptest.NewRequest("GET", "/p/x/q", nil)
+	if v := r.PathValue("a"); v != "" {
+		t.Errorf("unrouted request: PathValue = %q, want empty", v)
+	}
+}
+
 var serveMuxTests2 = []struct {
 	method  string
 	host    string
@@ -3826,7 +3991,7 @@ func testServerReaderFromOrder(t *testing.T, h2 bool) {
 
 // Issue 6157, Issue 6685
 func TestCodesPreventingContentTypeAndBody(t *testing.T) {
-	for _, code := range []int{StatusNotModified, StatusNoContent, StatusContinue} {
+	for _, code := range []int{StatusNotModified, StatusNoContent} {
 		ht := newHandlerTest(HandlerFunc(func(w ResponseWriter, r *Request) {
 			if r.URL.Path == "/header" {
 				w.Header().Set("Content-Length", "123")
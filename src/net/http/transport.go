@@ -21,6 +21,8 @@ import (
 	"log"
 	"net"
 	"net/http/httptrace"
+	"net/http/internal"
+	"net/textproto"
 	"net/url"
 	"os"
 	"strings"
@@ -92,6 +94,11 @@ type Transport struct {
 	reqMu       sync.Mutex
 	reqCanceler map[*Request]func(error)
 
+	connsPerHostMu   sync.Mutex
+	connsPerHost     map[connectMethodKey]int
+	connsPerHostWait map[connectMethodKey][]chan *persistConn // waiting getConn calls, oldest first
+	connsPerHostH2   map[*tls.Conn]connectMethodKey           // conns owned by h2transport
+
 	altMu    sync.Mutex   // guards changing altProto only
 	altProto atomic.Value // of nil or map[string]RoundTripper, key is URI scheme
 
@@ -162,6 +169,19 @@ type Transport struct {
 	// DefaultMaxIdleConnsPerHost is used.
 	MaxIdleConnsPerHost int
 
+	// MaxConnsPerHost optionally limits the total number of
+	// connections per host, including connections in the dialing,
+	// active, and idle states. On limit violation, requests wait,
+	// in order, until a connection to the host becomes idle or is
+	// closed, or until the request is canceled.
+	//
+	// A request that is waiting when a new HTTP/2 connection to the
+	// host is established uses that connection instead. An HTTP/2
+	// connection counts toward the limit until it is closed.
+	//
+	// Zero means no limit.
+	MaxConnsPerHost int
+
 	// IdleConnTimeout is the maximum amount of time an idle
 	// (keep-alive) connection will remain idle before closing
 	// itself.
@@ -206,12 +226,31 @@ type Transport struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
+	// EnableHTTP3 enables HTTP/3 for HTTPS requests.
+	//
+	// When EnableHTTP3 is set, the Transport remembers origins which
+	// advertise HTTP/3 with an "h3" Alt-Svc response header, and
+	// sends later requests to them over QUIC, using TLSClientConfig
+	// for the handshake. If a QUIC connection cannot be established,
+	// requests fall back to HTTP/2 or HTTP/1.1 over TCP, and HTTP/3
+	// is not attempted for that origin again for several minutes.
+	//
+	// HTTP/3 is not used for requests sent through a proxy.
+	EnableHTTP3 bool
+
+	// ForceAttemptHTTP2 controls whether HTTP/2 is enabled when a non-zero
+	// Dial or DialTLS func or TLSClientConfig is provided.
+	// By default, use of any those fields conservatively disables HTTP/2.
+	// To use a custom dialer or TLS config and still attempt HTTP/2
+	// upgrades, set this to true.
+	ForceAttemptHTTP2 bool
+
 	// nextProtoOnce guards initialization of TLSNextProto and
 	// h2transport (via onceSetNextProtoDefaults)
 	nextProtoOnce sync.Once
 	h2transport   *http2Transport // non-nil if http2 wired up
 
-	// TODO: tunable on max per-host TCP dials in flight (Issue 13957)
+	h3 http3Transport // HTTP/3 connections, used if EnableHTTP3 is set
 }
 
 // onceSetNextProtoDefaults initializes TLSNextProto.
@@ -225,12 +264,13 @@ func (t *Transport) onceSetNextProtoDefaults() {
 		// Transport.
 		return
 	}
-	if t.TLSClientConfig != nil || t.Dial != nil || t.DialTLS != nil {
+	if !t.ForceAttemptHTTP2 && (t.TLSClientConfig != nil || t.Dial != nil || t.DialTLS != nil) {
 		// Be conservative and don't automatically enable
 		// http2 if they've specified a custom TLS config or
 		// custom dialers. Let them opt-in themselves via
 		// http2.ConfigureTransport so we don't surprise them
 		// by modifying their tls.Config. Issue 14275.
+		// However, if ForceAttemptHTTP2 is true, it overrides the above checks.
 		return
 	}
 	t2, err := http2configureTransport(t)
@@ -358,6 +398,12 @@ func (t *Transport) roundTrip(req *Request) (*Response, error) {
 		return nil, errors.New("http: no Host in request URL")
 	}
 
+	if t.EnableHTTP3 && scheme == "https" {
+		if resp, err := t.roundTripHTTP3(req); err != errHTTP3Unavailable {
+			return resp, err
+		}
+	}
+
 	for {
 		// treq gets modified by roundTrip, so we need to recreate for each retry.
 		treq := &transportRequest{Request: req, trace: trace}
@@ -387,6 +433,9 @@ func (t *Transport) roundTrip(req *Request) (*Response, error) {
 			resp, err = pconn.roundTrip(treq)
 		}
 		if err == nil {
+			if t.EnableHTTP3 && cm.proxyURL == nil && cm.targetScheme == "https" {
+				t.h3.recordAltSvc(cm.targetAddr, resp.Header)
+			}
 			return resp, nil
 		}
 		if !pconn.shouldRetryRequest(req, err) {
@@ -512,6 +561,7 @@ func (t *Transport) CloseIdleConnections() {
 	if t2 := t.h2transport; t2 != nil {
 		t2.CloseIdleConnections()
 	}
+	t.h3.closeIdleConnections()
 }
 
 // CancelRequest cancels an in-flight request by closing its connection.
@@ -603,6 +653,7 @@ var (
 	errReadLoopExiting    = errors.New("http: persistConn.readLoop exiting")
 	errIdleConnTimeout    = errors.New("http: idle connection timeout")
 	errNotCachingH2Conn   = errors.New("http: not caching alternate protocol's connections")
+	errCallerOwnsConn     = errors.New("read loop ending; caller owns writable underlying conn")
 
 	// errServerClosedIdle is not seen by users for idempotent requests, but may be
 	// seen by a user if the server shuts down an idle connection and sends its FIN
@@ -661,6 +712,15 @@ func (t *Transport) tryPutIdleConn(pconn *persistConn) error {
 	t.idleMu.Lock()
 	defer t.idleMu.Unlock()
 
+	// Requests waiting for a connection slot under MaxConnsPerHost
+	// have no dial in flight, so they get first pick.
+	// This is done while holding idleMu so that a getConn call
+	// queueing at the same time finds pconn in the idle list if it
+	// misses the handoff.
+	if t.handConnPerHost(pconn) {
+		return nil
+	}
+
 	waitingDialer := t.idleConnCh[key]
 	select {
 	case waitingDialer <- pconn:
@@ -703,10 +763,10 @@ func (t *Transport) tryPutIdleConn(pconn *persistConn) error {
 		if pconn.idleTimer != nil {
 			pconn.idleTimer.Reset(t.IdleConnTimeout)
 		} else {
-			pconn.idleTimer = time.AfterFunc(t.IdleConnTimeout, pconn.closeConnIfStillIdle)
+			pconn.idleTimer = t.timeSource().AfterFunc(t.IdleConnTimeout, pconn.closeConnIfStillIdle)
 		}
 	}
-	pconn.idleAt = time.Now()
+	pconn.idleAt = t.timeSource().Now()
 	return nil
 }
 
@@ -861,6 +921,7 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 	req := treq.Request
 	trace := treq.trace
 	ctx := req.Context()
+	cmKey := cm.key()
 	if trace != nil && trace.GetConn != nil {
 		trace.GetConn(cm.addr())
 	}
@@ -891,6 +952,8 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 		go func() {
 			if v := <-dialc; v.err == nil {
 				t.putOrCloseIdleConn(v.pc)
+			} else {
+				t.releaseConnPerHost(cmKey)
 			}
 			testHookPostPendingDial()
 		}()
@@ -899,6 +962,41 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 	cancelc := make(chan error, 1)
 	t.setReqCanceler(req, func(err error) { cancelc <- err })
 
+	if slotc := t.reserveConnPerHost(cmKey); slotc != nil {
+		// The host is at MaxConnsPerHost. Wait for a connection
+		// slot or for a connection to be handed to us, unless an
+		// idle connection showed up since we last looked.
+		if pc, idleSince := t.getIdleConn(cm); pc != nil {
+			t.cancelConnPerHostWait(cmKey, slotc)
+			if trace != nil && trace.GotConn != nil {
+				trace.GotConn(pc.gotIdleConnTrace(idleSince))
+			}
+			return pc, nil
+		}
+		select {
+		case pc := <-slotc:
+			if pc != nil {
+				if trace != nil && trace.GotConn != nil && pc.alt == nil {
+					trace.GotConn(httptrace.GotConnInfo{Conn: pc.conn, Reused: pc.isReused()})
+				}
+				return pc, nil
+			}
+			// We hold a slot now; dial below.
+		case <-req.Cancel:
+			t.cancelConnPerHostWait(cmKey, slotc)
+			return nil, errRequestCanceledConn
+		case <-req.Context().Done():
+			t.cancelConnPerHostWait(cmKey, slotc)
+			return nil, req.Context().Err()
+		case err := <-cancelc:
+			t.cancelConnPerHostWait(cmKey, slotc)
+			if err == errRequestCanceled {
+				err = errRequestCanceledConn
+			}
+			return nil, err
+		}
+	}
+
 	go func() {
 		pc, err := t.dialConn(ctx, cm)
 		dialc <- dialRes{pc, err}
@@ -916,6 +1014,7 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 		}
 		// Our dial failed. See why to return a nicer error
 		// value.
+		t.releaseConnPerHost(cmKey)
 		select {
 		case <-req.Cancel:
 			// It was an error due to cancelation, so prioritize that
@@ -959,6 +1058,152 @@ func (t *Transport) getConn(treq *transportRequest, cm connectMethod) (*persistC
 	}
 }
 
+// reserveConnPerHost reserves one of the MaxConnsPerHost connection
+// slots for key. If a slot is free, reserveConnPerHost takes it and
+// returns nil. Otherwise it queues the caller and returns a channel
+// on which the caller is later sent either nil, meaning a slot has
+// been handed over to it, or a connection to use instead of dialing.
+// A caller that stops waiting must call cancelConnPerHostWait.
+func (t *Transport) reserveConnPerHost(key connectMethodKey) chan *persistConn {
+	if t.MaxConnsPerHost <= 0 {
+		return nil
+	}
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if n := t.connsPerHost[key]; n < t.MaxConnsPerHost {
+		if t.connsPerHost == nil {
+			t.connsPerHost = make(map[connectMethodKey]int)
+		}
+		t.connsPerHost[key] = n + 1
+		return nil
+	}
+	ch := make(chan *persistConn, 1)
+	if t.connsPerHostWait == nil {
+		t.connsPerHostWait = make(map[connectMethodKey][]chan *persistConn)
+	}
+	t.connsPerHostWait[key] = append(t.connsPerHostWait[key], ch)
+	return ch
+}
+
+// cancelConnPerHostWait removes ch, returned by reserveConnPerHost,
+// from key's wait queue. If ch was already sent a slot or an HTTP/1
+// connection, cancelConnPerHostWait gives it back.
+func (t *Transport) cancelConnPerHostWait(key connectMethodKey, ch chan *persistConn) {
+	t.connsPerHostMu.Lock()
+	q := t.connsPerHostWait[key]
+	for i, c := range q {
+		if c == ch {
+			copy(q[i:], q[i+1:])
+			q[len(q)-1] = nil
+			t.setConnsPerHostWaitLocked(key, q[:len(q)-1])
+			t.connsPerHostMu.Unlock()
+			return
+		}
+	}
+	t.connsPerHostMu.Unlock()
+
+	// No longer queued, so a value has already been sent on ch.
+	switch pc := <-ch; {
+	case pc == nil:
+		t.releaseConnPerHost(key)
+	case pc.alt == nil:
+		t.putOrCloseIdleConn(pc)
+	}
+}
+
+// releaseConnPerHost releases a connection slot for key, handing it
+// to the longest-waiting getConn call, if any.
+func (t *Transport) releaseConnPerHost(key connectMethodKey) {
+	if t.MaxConnsPerHost <= 0 {
+		return
+	}
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if q := t.connsPerHostWait[key]; len(q) > 0 {
+		// The slot changes hands; the count stays the same.
+		q[0] <- nil
+		t.setConnsPerHostWaitLocked(key, q[1:])
+		return
+	}
+	if n := t.connsPerHost[key]; n > 1 {
+		t.connsPerHost[key] = n - 1
+	} else {
+		delete(t.connsPerHost, key)
+	}
+}
+
+// handConnPerHost hands the idle HTTP/1 connection pc to the
+// longest-waiting getConn call for pc's host, if any, and reports
+// whether it did so.
+func (t *Transport) handConnPerHost(pc *persistConn) bool {
+	if t.MaxConnsPerHost <= 0 {
+		return false
+	}
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	q := t.connsPerHostWait[pc.cacheKey]
+	if len(q) == 0 {
+		return false
+	}
+	q[0] <- pc
+	t.setConnsPerHostWaitLocked(pc.cacheKey, q[1:])
+	return true
+}
+
+// shareConnPerHost hands the new HTTP/2 (or other alternate protocol)
+// connection pc to every getConn call waiting for a connection slot
+// for pc's host, as those connections can serve many requests at once.
+func (t *Transport) shareConnPerHost(pc *persistConn) {
+	if t.MaxConnsPerHost <= 0 {
+		return
+	}
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	for _, ch := range t.connsPerHostWait[pc.cacheKey] {
+		ch <- pc
+	}
+	delete(t.connsPerHostWait, pc.cacheKey)
+}
+
+// t.connsPerHostMu must be held.
+func (t *Transport) setConnsPerHostWaitLocked(key connectMethodKey, q []chan *persistConn) {
+	if len(q) == 0 {
+		delete(t.connsPerHostWait, key)
+	} else {
+		t.connsPerHostWait[key] = q
+	}
+}
+
+// trackH2Conn records that the slot reserved for key is held by tc
+// once tc is handed to the bundled HTTP/2 transport, until
+// h2ConnClosed is called for tc. It reports whether MaxConnsPerHost
+// is in effect.
+func (t *Transport) trackH2Conn(tc *tls.Conn, key connectMethodKey) bool {
+	if t.MaxConnsPerHost <= 0 {
+		return false
+	}
+	t.connsPerHostMu.Lock()
+	defer t.connsPerHostMu.Unlock()
+	if t.connsPerHostH2 == nil {
+		t.connsPerHostH2 = make(map[*tls.Conn]connectMethodKey)
+	}
+	t.connsPerHostH2[tc] = key
+	return true
+}
+
+// h2ConnClosed is called by the bundled HTTP/2 transport when it is
+// done with tc, either because tc was closed or because it was not
+// needed. It releases the connection slot tc held, if any.
+func (t *Transport) h2ConnClosed(tc *tls.Conn) {
+	t.connsPerHostMu.Lock()
+	key, ok := t.connsPerHostH2[tc]
+	delete(t.connsPerHostH2, tc)
+	t.connsPerHostMu.Unlock()
+	if ok {
+		t.releaseConnPerHost(key)
+	}
+}
+
 // The connect method and the transport can both specify a TLS
 // Host name.  The transport's name takes precedence if present.
 func chooseTLSHost(cm connectMethod, t *Transport) string {
@@ -984,9 +1229,9 @@ func (pconn *persistConn) addTLS(name string, trace *httptrace.ClientTrace) erro
 	plainConn := pconn.conn
 	tlsConn := tls.Client(plainConn, cfg)
 	errc := make(chan error, 2)
-	var timer *time.Timer // for canceling TLS handshake
+	var timer internal.Timer // for canceling TLS handshake
 	if d := pconn.t.TLSHandshakeTimeout; d != 0 {
-		timer = time.AfterFunc(d, func() {
+		timer = pconn.t.timeSource().AfterFunc(d, func() {
 			errc <- tlsHandshakeTimeoutError{}
 		})
 	}
@@ -1152,7 +1397,17 @@ func (t *Transport) dialConn(ctx context.Context, cm connectMethod) (*persistCon
 
 	if s := pconn.tlsState; s != nil && s.NegotiatedProtocolIsMutual && s.NegotiatedProtocol != "" {
 		if next, ok := t.TLSNextProto[s.NegotiatedProtocol]; ok {
-			return &persistConn{alt: next(cm.targetAddr, pconn.conn.(*tls.Conn))}, nil
+			tc := pconn.conn.(*tls.Conn)
+			// The bundled HTTP/2 transport tells us when it is done
+			// with tc (see h2ConnClosed). Connections handed to other
+			// protocols stop counting toward MaxConnsPerHost.
+			tracked := t.h2transport != nil && s.NegotiatedProtocol == "h2" && t.trackH2Conn(tc, pconn.cacheKey)
+			alt := &persistConn{t: t, cacheKey: pconn.cacheKey, alt: next(cm.targetAddr, tc)}
+			if !tracked {
+				t.releaseConnPerHost(pconn.cacheKey)
+			}
+			t.shareConnPerHost(alt)
+			return alt, nil
 		}
 	}
 
@@ -1288,8 +1543,8 @@ type persistConn struct {
 	writeLoopDone chan struct{} // closed when write loop ends
 
 	// Both guarded by Transport.idleMu:
-	idleAt    time.Time   // time it last become idle
-	idleTimer *time.Timer // holding an AfterFunc to close it
+	idleAt    time.Time      // time it last become idle
+	idleTimer internal.Timer // holding an AfterFunc to close it
 
 	mu                   sync.Mutex // guards following fields
 	numExpectedResponses int
@@ -1356,7 +1611,7 @@ func (pc *persistConn) gotIdleConnTrace(idleAt time.Time) (t httptrace.GotConnIn
 	t.Conn = pc.conn
 	t.WasIdle = true
 	if !idleAt.IsZero() {
-		t.IdleTime = time.Since(idleAt)
+		t.IdleTime = pc.t.timeSource().Now().Sub(idleAt)
 	}
 	return
 }
@@ -1504,16 +1759,17 @@ func (pc *persistConn) readLoop() {
 		pc.numExpectedResponses--
 		pc.mu.Unlock()
 
+		bodyWritable := resp.bodyIsWritable()
 		hasBody := rc.req.Method != "HEAD" && resp.ContentLength != 0
 
-		if resp.Close || rc.req.Close || resp.StatusCode <= 199 {
+		if resp.Close || rc.req.Close || resp.StatusCode <= 199 || bodyWritable {
 			// Don't do keep-alive on error if either party requested a close
 			// or we get an unexpected informational (1xx) response.
 			// StatusCode 100 is already handled above.
 			alive = false
 		}
 
-		if !hasBody {
+		if !hasBody || bodyWritable {
 			pc.t.setReqCanceler(rc.req, nil)
 
 			// Put the idle conn back into the pool before we send the response
@@ -1527,6 +1783,10 @@ func (pc *persistConn) readLoop() {
 				pc.wroteRequest() &&
 				tryPutIdleConn(trace)
 
+			if bodyWritable {
+				closeErr = errCallerOwnsConn
+			}
+
 			select {
 			case rc.ch <- responseAndError{res: resp}:
 			case <-rc.callerGone:
@@ -1622,8 +1882,9 @@ func (pc *persistConn) readLoopPeekFailLocked(peekErr error) {
 	}
 }
 
-// readResponse reads an HTTP response (or two, in the case of "Expect:
-// 100-continue") from the server. It returns the final non-100 one.
+// readResponse reads an HTTP response (or two or more, in the case of
+// informational 1xx responses) from the server. It returns the final
+// non-1xx one, or a 101 Switching Protocols response.
 // trace is optional.
 func (pc *persistConn) readResponse(rc requestAndChan, trace *httptrace.ClientTrace) (resp *Response, err error) {
 	if trace != nil && trace.GotFirstResponseByte != nil {
@@ -1631,31 +1892,87 @@ func (pc *persistConn) readResponse(rc requestAndChan, trace *httptrace.ClientTr
 			trace.GotFirstResponseByte()
 		}
 	}
-	resp, err = ReadResponse(pc.br, rc.req)
-	if err != nil {
-		return
-	}
-	if rc.continueCh != nil {
-		if resp.StatusCode == 100 {
-			if trace != nil && trace.Got100Continue != nil {
-				trace.Got100Continue()
-			}
-			rc.continueCh <- struct{}{}
-		} else {
-			close(rc.continueCh)
-		}
-	}
-	if resp.StatusCode == 100 {
-		pc.readLimit = pc.maxHeaderResponseSize() // reset the limit
+	num1xx := 0               // number of informational 1xx headers received
+	const max1xxResponses = 5 // arbitrary bound on number of informational responses
+
+	continueCh := rc.continueCh
+	for {
 		resp, err = ReadResponse(pc.br, rc.req)
 		if err != nil {
 			return
 		}
+		resCode := resp.StatusCode
+		if continueCh != nil {
+			if resCode == 100 {
+				if trace != nil && trace.Got100Continue != nil {
+					trace.Got100Continue()
+				}
+				continueCh <- struct{}{}
+				continueCh = nil
+			} else if resCode >= 200 {
+				close(continueCh)
+				continueCh = nil
+			}
+		}
+		// 101 Switching Protocols is a final response.
+		if 100 <= resCode && resCode <= 199 && resCode != StatusSwitchingProtocols {
+			num1xx++
+			if num1xx > max1xxResponses {
+				return nil, errors.New("net/http: too many 1xx informational responses")
+			}
+			pc.readLimit = pc.maxHeaderResponseSize() // reset the limit
+			if trace != nil && trace.Got1xxResponse != nil {
+				if err := trace.Got1xxResponse(resCode, textproto.MIMEHeader(resp.Header)); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		break
+	}
+	if resp.isProtocolSwitch() {
+		resp.Body = newReadWriteCloserBody(pc.br, pc.conn)
 	}
+
 	resp.TLS = pc.tlsState
 	return
 }
 
+// newReadWriteCloserBody returns a Response.Body for a 101 Switching
+// Protocols response that reads any bytes already buffered in br
+// before reading from, and writing to, rwc directly.
+func newReadWriteCloserBody(br *bufio.Reader, rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	body := &readWriteCloserBody{ReadWriteCloser: rwc}
+	if br.Buffered() != 0 {
+		body.br = br
+	}
+	return body
+}
+
+// readWriteCloserBody is the Response.Body type used when we want to
+// give users write access to the Body through the underlying
+// connection (TCP, unless using custom dialers). This is then
+// the concrete type for a Response.Body on the 101 Switching
+// Protocols response, as used by WebSockets, h2c, etc.
+type readWriteCloserBody struct {
+	br *bufio.Reader // used until empty
+	io.ReadWriteCloser
+}
+
+func (b *readWriteCloserBody) Read(p []byte) (n int, err error) {
+	if b.br != nil {
+		if n := b.br.Buffered(); len(p) > n {
+			p = p[:n]
+		}
+		n, err = b.br.Read(p)
+		if b.br.Buffered() == 0 {
+			b.br = nil
+		}
+		return n, err
+	}
+	return b.ReadWriteCloser.Read(p)
+}
+
 // waitForContinue returns the function to block until
 // any response, timeout or connection close. After any of them,
 // the function returns a bool which indicates if the body should be sent.
@@ -1664,13 +1981,13 @@ func (pc *persistConn) waitForContinue(continueCh <-chan struct{}) func() bool {
 		return nil
 	}
 	return func() bool {
-		timer := time.NewTimer(pc.t.ExpectContinueTimeout)
+		timer, timerC := pc.t.timeSource().NewTimer(pc.t.ExpectContinueTimeout)
 		defer timer.Stop()
 
 		select {
 		case _, ok := <-continueCh:
 			return ok
-		case <-timer.C:
+		case <-timerC:
 			return true
 		case <-pc.closech:
 			return false
@@ -1910,9 +2227,9 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 				if debugRoundTrip {
 					req.logf("starting timer for %v", d)
 				}
-				timer := time.NewTimer(d)
+				timer, timerC := pc.t.timeSource().NewTimer(d)
 				defer timer.Stop() // prevent leaks
-				respHeaderTimer = timer.C
+				respHeaderTimer = timerC
 			}
 		case <-pc.closech:
 			if debugRoundTrip {
@@ -1992,8 +2309,11 @@ func (pc *persistConn) closeLocked(err error) {
 			// freelist for http2. That's done by the
 			// alternate protocol's RoundTripper.
 		} else {
-			pc.conn.Close()
+			if err != errCallerOwnsConn {
+				pc.conn.Close()
+			}
 			close(pc.closech)
+			pc.t.releaseConnPerHost(pc.cacheKey)
 		}
 	}
 	pc.mutateHeaderFunc = nil
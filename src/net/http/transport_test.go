@@ -466,6 +466,152 @@ func TestTransportMaxPerHostIdleConns(t *testing.T) {
 	}
 }
 
+func TestTransportMaxConnsPerHost_h1(t *testing.T) { testTransportMaxConnsPerHost(t, h1Mode) }
+func TestTransportMaxConnsPerHost_h2(t *testing.T) { testTransportMaxConnsPerHost(t, h2Mode) }
+
+func testTransportMaxConnsPerHost(t *testing.T, h2 bool) {
+	setParallel(t)
+	defer afterTest(t)
+	var (
+		mu       sync.Mutex
+		newConns int
+	)
+	release := make(chan struct{})
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		<-release
+		io.WriteString(w, "ok")
+	}), func(ts *httptest.Server) {
+		ts.Config.ConnState = func(c net.Conn, state ConnState) {
+			if state == StateNew {
+				mu.Lock()
+				newConns++
+				mu.Unlock()
+			}
+		}
+	}, func(tr *Transport) {
+		tr.MaxConnsPerHost = 1
+	})
+	defer cst.close()
+
+	const numReqs = 10
+	errc := make(chan error, numReqs)
+	for i := 0; i < numReqs; i++ {
+		go func() {
+			res, err := cst.c.Get(cst.ts.URL)
+			if err == nil {
+				_, err = ioutil.ReadAll(res.Body)
+				res.Body.Close()
+			}
+			errc <- err
+		}()
+	}
+	close(release)
+	for i := 0; i < numReqs; i++ {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if newConns != 1 {
+		t.Errorf("server saw %d connections; want 1", newConns)
+	}
+}
+
+func TestTransportMaxConnsPerHostCancelWaiting(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	gotReq := make(chan bool, 1)
+	release := make(chan struct{})
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		if r.URL.Path == "/block" {
+			gotReq <- true
+			<-release
+		}
+	}))
+	defer ts.Close()
+	c := ts.Client()
+	tr := c.Transport.(*Transport)
+	tr.MaxConnsPerHost = 1
+
+	errc := make(chan error, 1)
+	go func() {
+		res, err := c.Get(ts.URL + "/block")
+		if err == nil {
+			res.Body.Close()
+		}
+		errc <- err
+	}()
+	<-gotReq
+
+	// The only connection is busy, so this request waits for it
+	// until its context expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := NewRequest("GET", ts.URL, nil)
+	if _, err := c.Do(req.WithContext(ctx)); err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Fatalf("waiting request: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	// The canceled request must not have kept a connection slot.
+	res, err := c.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+}
+
+func TestTransportMaxConnsPerHostClosedConnReleasesSlot(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	var (
+		mu       sync.Mutex
+		newConns int
+	)
+	ts := httptest.NewUnstartedServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	ts.Config.ConnState = func(c net.Conn, state ConnState) {
+		if state == StateNew {
+			mu.Lock()
+			newConns++
+			mu.Unlock()
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+	c := ts.Client()
+	tr := c.Transport.(*Transport)
+	tr.MaxConnsPerHost = 1
+	tr.DisableKeepAlives = true
+
+	const numReqs = 3
+	errc := make(chan error, numReqs)
+	for i := 0; i < numReqs; i++ {
+		go func() {
+			res, err := c.Get(ts.URL)
+			if err == nil {
+				res.Body.Close()
+			}
+			errc <- err
+		}()
+	}
+	for i := 0; i < numReqs; i++ {
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if newConns != numReqs {
+		t.Errorf("server saw %d connections; want %d", newConns, numReqs)
+	}
+}
+
 func TestTransportRemovesDeadIdleConnections(t *testing.T) {
 	setParallel(t)
 	defer afterTest(t)
@@ -2311,11 +2457,11 @@ Content-Length: %d
 	}
 
 	// And some other informational 1xx but non-100 responses, to test
-	// we return them but don't re-use the connection.
+	// we skip them and return the final response.
 	for i := 1; i <= numReqs; i++ {
 		req, _ := NewRequest("POST", "http://other.tld/", strings.NewReader(reqBody(i)))
 		req.Header.Set("X-Want-Response-Code", "123 Sesame Street")
-		testResponse(req, fmt.Sprintf("123, %d/%d", i, numReqs), 123)
+		testResponse(req, fmt.Sprintf("123, %d/%d", i, numReqs), 200)
 	}
 }
 
@@ -4488,3 +4634,54 @@ func TestNoBodyOnChunked304Response(t *testing.T) {
 type funcWriter func([]byte) (int, error)
 
 func (f funcWriter) Write(p []byte) (int, error) { return f(p) }
+
+func TestTransportResponseBodyWritableOnProtocolSwitch(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	done := make(chan struct{})
+	defer close(done)
+	cst := newClientServerTest(t, h1Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		conn, _, err := w.(Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols Hi\r\nConnection: upgRADe\r\nUpgrade: foo\r\n\r\nSome buffered data\n")
+		bs := bufio.NewScanner(conn)
+		bs.Scan()
+		fmt.Fprintf(conn, "%s\n", strings.ToUpper(bs.Text()))
+		<-done
+	}))
+	defer cst.close()
+
+	req, _ := NewRequest("GET", cst.ts.URL, nil)
+	req.Header.Set("Upgrade", "foo")
+	req.Header.Set("Connection", "upgrade")
+	res, err := cst.c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 101 {
+		t.Fatalf("expected 101 switching protocols; got %v, %v", res.Status, res.Header)
+	}
+	rwc, ok := res.Body.(io.ReadWriteCloser)
+	if !ok {
+		t.Fatalf("expected a ReadWriteCloser; got a %T", res.Body)
+	}
+	defer rwc.Close()
+	bs := bufio.NewScanner(rwc)
+	if !bs.Scan() {
+		t.Fatalf("expected readable input")
+	}
+	if got, want := bs.Text(), "Some buffered data"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+	io.WriteString(rwc, "echo\n")
+	if !bs.Scan() {
+		t.Fatalf("expected another line")
+	}
+	if got, want := bs.Text(), "ECHO"; got != want {
+		t.Errorf("read %q; want %q", got, want)
+	}
+}
@@ -12,7 +12,10 @@
 
 package net
 
-import _ "unsafe" // for go:linkname
+import (
+	"net/netip"
+	_ "unsafe" // for go:linkname
+)
 
 // IP address lengths (bytes).
 const (
@@ -535,6 +538,42 @@ func (n *IPNet) String() string {
 	return nn.String() + "/" + uitoa(uint(l))
 }
 
+// Prefix returns n as a netip.Prefix. The address is unmasked, like
+// n.IP. IPv4 networks, including those whose IP is in the 16-byte form,
+// are returned as IPv4 prefixes. Prefix reports false if n is nil or
+// malformed, or if its mask is not in the canonical form.
+func (n *IPNet) Prefix() (netip.Prefix, bool) {
+	if n == nil {
+		return netip.Prefix{}, false
+	}
+	ip, m := networkNumberAndMask(n)
+	if ip == nil || m == nil {
+		return netip.Prefix{}, false
+	}
+	ones, bits := m.Size()
+	if bits == 0 {
+		return netip.Prefix{}, false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Prefix{}, false
+	}
+	return netip.PrefixFrom(addr, ones), true
+}
+
+// IPNetFromPrefix returns p as an IPNet. It returns nil if p is not
+// valid.
+func IPNetFromPrefix(p netip.Prefix) *IPNet {
+	if !p.IsValid() {
+		return nil
+	}
+	addr := p.Addr()
+	return &IPNet{
+		IP:   addr.AsSlice(),
+		Mask: CIDRMask(p.Bits(), addr.BitLen()),
+	}
+}
+
 // Parse IPv4 address (d.d.d.d).
 func parseIPv4(s string) IP {
 	var p [IPv4len]byte
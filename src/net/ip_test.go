@@ -7,6 +7,7 @@ package net
 import (
 	"bytes"
 	"math/rand"
+	"net/netip"
 	"reflect"
 	"runtime"
 	"testing"
@@ -411,6 +412,38 @@ func TestIPNetString(t *testing.T) {
 	}
 }
 
+var ipNetPrefixTests = []struct {
+	in  *IPNet
+	out netip.Prefix
+	ok  bool
+}{
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: CIDRMask(26, 32)}, netip.MustParsePrefix("192.168.1.0/26"), true},
+	{&IPNet{IP: IPv4(192, 168, 1, 0).To4(), Mask: CIDRMask(24, 32)}, netip.MustParsePrefix("192.168.1.0/24"), true},
+	{&IPNet{IP: ParseIP("2001:db8::"), Mask: CIDRMask(55, 128)}, netip.MustParsePrefix("2001:db8::/55"), true},
+	{&IPNet{IP: IPv4(192, 168, 1, 0), Mask: IPv4Mask(255, 0, 255, 0)}, netip.Prefix{}, false},
+	{&IPNet{IP: IP{1, 2, 3}, Mask: CIDRMask(24, 32)}, netip.Prefix{}, false},
+	{nil, netip.Prefix{}, false},
+}
+
+func TestIPNetPrefix(t *testing.T) {
+	for _, tt := range ipNetPrefixTests {
+		out, ok := tt.in.Prefix()
+		if out != tt.out || ok != tt.ok {
+			t.Errorf("IPNet.Prefix(%v) = %v, %v, want %v, %v", tt.in, out, ok, tt.out, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if s := IPNetFromPrefix(out).String(); s != tt.in.String() {
+			t.Errorf("IPNetFromPrefix(%v) = %q, want %q", out, s, tt.in.String())
+		}
+	}
+	if n := IPNetFromPrefix(netip.Prefix{}); n != nil {
+		t.Errorf("IPNetFromPrefix(netip.Prefix{}) = %v, want nil", n)
+	}
+}
+
 var cidrMaskTests = []struct {
 	ones int
 	bits int
@@ -9,6 +9,7 @@ package net
 import (
 	"context"
 	"internal/poll"
+	"net/netip"
 	"runtime"
 	"syscall"
 )
@@ -180,3 +181,30 @@ func ipToSockaddr(family int, ip IP, port int, zone string) (syscall.Sockaddr, e
 	}
 	return nil, &AddrError{Err: "invalid address family", Addr: ip.String()}
 }
+
+// addrPortToSockaddrInet4 is like ipToSockaddr for AF_INET, but returns
+// the Sockaddr by value so that it doesn't need to be allocated.
+func addrPortToSockaddrInet4(ap netip.AddrPort) (syscall.SockaddrInet4, error) {
+	// Like ip.To4 in ipToSockaddr, accept IPv4-mapped IPv6 addresses.
+	addr := ap.Addr().Unmap()
+	if !addr.Is4() {
+		return syscall.SockaddrInet4{}, &AddrError{Err: "non-IPv4 address", Addr: addr.String()}
+	}
+	return syscall.SockaddrInet4{Addr: addr.As4(), Port: int(ap.Port())}, nil
+}
+
+// addrPortToSockaddrInet6 is like ipToSockaddr for AF_INET6, but returns
+// the Sockaddr by value so that it doesn't need to be allocated.
+func addrPortToSockaddrInet6(ap netip.AddrPort) (syscall.SockaddrInet6, error) {
+	// We accept any IPv6 address including IPv4-mapped IPv6 address,
+	// which As16 returns for IPv4 addresses.
+	addr := ap.Addr()
+	if !addr.IsValid() {
+		return syscall.SockaddrInet6{}, &AddrError{Err: "non-IPv6 address", Addr: addr.String()}
+	}
+	return syscall.SockaddrInet6{
+		Addr:   addr.As16(),
+		Port:   int(ap.Port()),
+		ZoneId: uint32(zoneCache.index(addr.Zone())),
+	}, nil
+}
@@ -0,0 +1,540 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package netip
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var parseAddrTests = []struct {
+	in  string
+	ip  Addr
+	str string // expected String output; empty means in
+}{
+	{"0.0.0.0", AddrFrom4([4]byte{}), ""},
+	{"192.168.140.255", AddrFrom4([4]byte{192, 168, 140, 255}), ""},
+	{"::", IPv6Unspecified(), ""},
+	{"::1", AddrFrom16([16]byte{15: 1}), ""},
+	{"fd7a:115c:a1e0:ab12:4843:cd96:626b:430b", AddrFrom16([16]byte{0xfd, 0x7a, 0x11, 0x5c, 0xa1, 0xe0, 0xab, 0x12, 0x48, 0x43, 0xcd, 0x96, 0x62, 0x6b, 0x43, 0x0b}), ""},
+	{"FD9E:1A04:F01D::1", AddrFrom16([16]byte{0xfd, 0x9e, 0x1a, 0x04, 0xf0, 0x1d, 15: 1}), "fd9e:1a04:f01d::1"},
+	{"fe80::1cc0:3e8c:119f:c2e1%ens18", AddrFrom16([16]byte{0xfe, 0x80, 8: 0x1c, 0xc0, 0x3e, 0x8c, 0x11, 0x9f, 0xc2, 0xe1}).WithZone("ens18"), ""},
+	{"::ffff:192.168.140.255", AddrFrom16([16]byte{10: 0xff, 0xff, 192, 168, 140, 255}), ""},
+	{"::ffff:c0a8:8cff", AddrFrom16([16]byte{10: 0xff, 0xff, 192, 168, 140, 255}), "::ffff:192.168.140.255"},
+	{"1:0:0:0:0:0:0:2", AddrFrom16([16]byte{1: 1, 15: 2}), "1::2"},
+	{"1:0:0:1:0:0:0:2", AddrFrom16([16]byte{1: 1, 7: 1, 15: 2}), "1:0:0:1::2"},
+	{"2001:db8:0:0:1:0:0:1", AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 9: 1, 15: 1}), "2001:db8::1:0:0:1"},
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tt := range parseAddrTests {
+		ip, err := ParseAddr(tt.in)
+		if err != nil {
+			t.Errorf("ParseAddr(%q): %v", tt.in, err)
+			continue
+		}
+		if ip != tt.ip {
+			t.Errorf("ParseAddr(%q) = %#v; want %#v", tt.in, ip, tt.ip)
+		}
+		want := tt.str
+		if want == "" {
+			want = tt.in
+		}
+		if got := ip.String(); got != want {
+			t.Errorf("ParseAddr(%q).String() = %q; want %q", tt.in, got, want)
+		}
+		ip2, err := ParseAddr(ip.String())
+		if err != nil || ip2 != ip {
+			t.Errorf("ParseAddr(%q) = %v, %v; want %v", ip.String(), ip2, err, ip)
+		}
+		text, _ := ip.MarshalText()
+		var ip3 Addr
+		if err := ip3.UnmarshalText(text); err != nil || ip3 != ip {
+			t.Errorf("UnmarshalText(%q) = %v, %v; want %v", text, ip3, err, ip)
+		}
+		bin, _ := ip.MarshalBinary()
+		var ip4 Addr
+		if err := ip4.UnmarshalBinary(bin); err != nil || ip4 != ip {
+			t.Errorf("UnmarshalBinary(%x) = %v, %v; want %v", bin, ip4, err, ip)
+		}
+	}
+}
+
+var parseAddrErrorTests = []string{
+	"",
+	"bad",
+	"1.2.3",
+	"1.2.3.4.5",
+	"256.0.0.1",
+	"01.2.3.4",
+	"1..2.3",
+	"1.2.3.4%eth0",
+	"-1.2.3.4",
+	"1:2:3:4:5:6:7",
+	"1:2:3:4:5:6:7:8:9",
+	"1::2::3",
+	"fe80::1%",
+	"ffff::12345",
+	"::ffff:1.2.3",
+	"[::1]",
+	":1",
+	"1:",
+}
+
+func TestParseAddrError(t *testing.T) {
+	for _, s := range parseAddrErrorTests {
+		if ip, err := ParseAddr(s); err == nil {
+			t.Errorf("ParseAddr(%q) = %v; want error", s, ip)
+		}
+	}
+}
+
+func TestAddrZeroValue(t *testing.T) {
+	var ip Addr
+	if ip.IsValid() || ip.Is4() || ip.Is6() || ip.BitLen() != 0 {
+		t.Errorf("zero Addr reports valid")
+	}
+	if got, want := ip.String(), "invalid IP"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+	if ip.AsSlice() != nil {
+		t.Errorf("AsSlice() = %v; want nil", ip.AsSlice())
+	}
+	if ip == IPv4Unspecified() || ip == IPv6Unspecified() {
+		t.Errorf("zero Addr equals an unspecified address")
+	}
+	text, _ := ip.MarshalText()
+	if len(text) != 0 {
+		t.Errorf("MarshalText() = %q; want empty", text)
+	}
+}
+
+func TestAddrConversions(t *testing.T) {
+	ip4 := MustParseAddr("1.2.3.4")
+	if got, want := ip4.As4(), [4]byte{1, 2, 3, 4}; got != want {
+		t.Errorf("As4() = %v; want %v", got, want)
+	}
+	if got, want := ip4.As16(), [16]byte{10: 0xff, 0xff, 1, 2, 3, 4}; got != want {
+		t.Errorf("As16() = %v; want %v", got, want)
+	}
+	if got, want := ip4.AsSlice(), []byte{1, 2, 3, 4}; !bytes.Equal(got, want) {
+		t.Errorf("AsSlice() = %v; want %v", got, want)
+	}
+
+	mapped := MustParseAddr("::ffff:1.2.3.4")
+	if !mapped.Is4In6() || mapped.Is4() {
+		t.Errorf("%v: Is4In6 = %v, Is4 = %v", mapped, mapped.Is4In6(), mapped.Is4())
+	}
+	if mapped == ip4 {
+		t.Errorf("%v == %v; want distinct", mapped, ip4)
+	}
+	if got := mapped.Unmap(); got != ip4 {
+		t.Errorf("Unmap() = %v; want %v", got, ip4)
+	}
+	if got := mapped.As4(); got != ip4.As4() {
+		t.Errorf("As4() = %v; want %v", got, ip4.As4())
+	}
+
+	for _, tt := range []struct {
+		in   []byte
+		want Addr
+		ok   bool
+	}{
+		{nil, Addr{}, false},
+		{[]byte{1, 2, 3}, Addr{}, false},
+		{[]byte{1, 2, 3, 4}, ip4, true},
+		{mapped.AsSlice(), mapped, true},
+	} {
+		got, ok := AddrFromSlice(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("AddrFromSlice(%v) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("As4 on IPv6 address did not panic")
+			}
+		}()
+		MustParseAddr("2001:db8::1").As4()
+	}()
+}
+
+func TestStringExpanded(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"1.2.3.4", "1.2.3.4"},
+		{"::", "0000:0000:0000:0000:0000:0000:0000:0000"},
+		{"2001:db8::1", "2001:0db8:0000:0000:0000:0000:0000:0001"},
+		{"fe80::1%eth0", "fe80:0000:0000:0000:0000:0000:0000:0001%eth0"},
+	} {
+		if got := MustParseAddr(tt.in).StringExpanded(); got != tt.want {
+			t.Errorf("StringExpanded(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAddrProperties(t *testing.T) {
+	for _, tt := range []struct {
+		in                                 string
+		global, linkLocal, loopback, multi bool
+		private, unspecified               bool
+	}{
+		{in: "0.0.0.0", unspecified: true},
+		{in: "::", unspecified: true},
+		{in: "127.0.0.1", loopback: true},
+		{in: "::1", loopback: true},
+		{in: "8.8.8.8", global: true},
+		{in: "2001:db8::1", global: true},
+		{in: "10.1.2.3", global: true, private: true},
+		{in: "192.168.0.1", global: true, private: true},
+		{in: "fd00::1", global: true, private: true},
+		{in: "169.254.0.1", linkLocal: true},
+		{in: "fe80::1", linkLocal: true},
+		{in: "fe80::1%eth0", linkLocal: true},
+		{in: "224.0.0.1", multi: true},
+		{in: "ff02::1", multi: true},
+		{in: "255.255.255.255"},
+	} {
+		ip := MustParseAddr(tt.in)
+		if got := ip.IsGlobalUnicast(); got != tt.global {
+			t.Errorf("%s: IsGlobalUnicast = %v; want %v", tt.in, got, tt.global)
+		}
+		if got := ip.IsLinkLocalUnicast(); got != tt.linkLocal {
+			t.Errorf("%s: IsLinkLocalUnicast = %v; want %v", tt.in, got, tt.linkLocal)
+		}
+		if got := ip.IsLoopback(); got != tt.loopback {
+			t.Errorf("%s: IsLoopback = %v; want %v", tt.in, got, tt.loopback)
+		}
+		if got := ip.IsMulticast(); got != tt.multi {
+			t.Errorf("%s: IsMulticast = %v; want %v", tt.in, got, tt.multi)
+		}
+		if got := ip.IsPrivate(); got != tt.private {
+			t.Errorf("%s: IsPrivate = %v; want %v", tt.in, got, tt.private)
+		}
+		if got := ip.IsUnspecified(); got != tt.unspecified {
+			t.Errorf("%s: IsUnspecified = %v; want %v", tt.in, got, tt.unspecified)
+		}
+	}
+	if !IPv6LinkLocalAllNodes().IsLinkLocalMulticast() {
+		t.Errorf("IPv6LinkLocalAllNodes is not link-local multicast")
+	}
+}
+
+func TestAddrCompare(t *testing.T) {
+	// Ordered by Compare: zero Addr, IPv4, then IPv6 with zones after
+	// the unzoned address.
+	ordered := []string{
+		"",
+		"1.2.3.4",
+		"1.2.3.5",
+		"10.0.0.1",
+		"::",
+		"::1",
+		"::ffff:1.2.3.4",
+		"fe80::1",
+		"fe80::1%eth0",
+		"fe80::2",
+	}
+	ips := make([]Addr, len(ordered))
+	for i, s := range ordered {
+		if s != "" {
+			ips[i] = MustParseAddr(s)
+		}
+	}
+	for i := range ips {
+		for j := range ips {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := ips[i].Compare(ips[j]); got != want {
+				t.Errorf("Compare(%v, %v) = %d; want %d", ips[i], ips[j], got, want)
+			}
+			if got := ips[i].Less(ips[j]); got != (want < 0) {
+				t.Errorf("Less(%v, %v) = %v; want %v", ips[i], ips[j], got, want < 0)
+			}
+		}
+	}
+}
+
+func TestAddrNextPrev(t *testing.T) {
+	for _, tt := range []struct {
+		ip, next string
+	}{
+		{"0.0.0.0", "0.0.0.1"},
+		{"1.2.3.255", "1.2.4.0"},
+		{"255.255.255.254", "255.255.255.255"},
+		{"::", "::1"},
+		{"::ffff", "::1:0"},
+		{"::ffff:ffff:ffff:ffff", "0:0:0:1::"},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+	} {
+		ip, next := MustParseAddr(tt.ip), MustParseAddr(tt.next)
+		if got := ip.Next(); got != next {
+			t.Errorf("%v.Next() = %v; want %v", ip, got, next)
+		}
+		if got := next.Prev(); got != ip {
+			t.Errorf("%v.Prev() = %v; want %v", next, got, ip)
+		}
+	}
+	for _, s := range []string{"255.255.255.255", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"} {
+		if got := MustParseAddr(s).Next(); got.IsValid() {
+			t.Errorf("%s.Next() = %v; want zero Addr", s, got)
+		}
+	}
+	for _, s := range []string{"0.0.0.0", "::"} {
+		if got := MustParseAddr(s).Prev(); got.IsValid() {
+			t.Errorf("%s.Prev() = %v; want zero Addr", s, got)
+		}
+	}
+}
+
+func TestAddrAsMapKey(t *testing.T) {
+	m := make(map[Addr]int)
+	m[MustParseAddr("1.2.3.4")] = 1
+	m[MustParseAddr("::ffff:1.2.3.4")] = 2
+	m[MustParseAddr("fe80::1")] = 3
+	m[MustParseAddr("fe80::1%eth0")] = 4
+	m[MustParseAddr("1.2.3.4")]++
+	want := map[Addr]int{
+		MustParseAddr("1.2.3.4"):        2,
+		MustParseAddr("::ffff:1.2.3.4"): 2,
+		MustParseAddr("fe80::1"):        3,
+		MustParseAddr("fe80::1%eth0"):   4,
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v; want %v", m, want)
+	}
+}
+
+var parseAddrPortTests = []struct {
+	in   string
+	want AddrPort
+	ok   bool
+}{
+	{"1.2.3.4:80", AddrPortFrom(AddrFrom4([4]byte{1, 2, 3, 4}), 80), true},
+	{"[::1]:8080", AddrPortFrom(IPv6Unspecified().Next(), 8080), true},
+	{"[fe80::1%eth0]:0", AddrPortFrom(MustParseAddr("fe80::1%eth0"), 0), true},
+	{"1.2.3.4", AddrPort{}, false},
+	{"1.2.3.4:", AddrPort{}, false},
+	{"1.2.3.4:65536", AddrPort{}, false},
+	{"1.2.3.4:-1", AddrPort{}, false},
+	{"::1:80", AddrPort{}, false},
+	{"[1.2.3.4]:80", AddrPort{}, false},
+	{"[::1]80", AddrPort{}, false},
+}
+
+func TestParseAddrPort(t *testing.T) {
+	for _, tt := range parseAddrPortTests {
+		got, err := ParseAddrPort(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseAddrPort(%q) = %v, %v; want ok = %v", tt.in, got, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddrPort(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+		if s := got.String(); s != tt.in {
+			t.Errorf("ParseAddrPort(%q).String() = %q", tt.in, s)
+		}
+		var ap AddrPort
+		text, _ := got.MarshalText()
+		if err := ap.UnmarshalText(text); err != nil || ap != got {
+			t.Errorf("UnmarshalText(%q) = %v, %v; want %v", text, ap, err, got)
+		}
+	}
+	if got, want := (AddrPort{}).String(), "invalid AddrPort"; got != want {
+		t.Errorf("zero AddrPort String() = %q; want %q", got, want)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		ip     Addr
+		bits   int
+		masked string
+	}{
+		{"1.2.3.4/24", MustParseAddr("1.2.3.4"), 24, "1.2.3.0/24"},
+		{"0.0.0.0/0", IPv4Unspecified(), 0, "0.0.0.0/0"},
+		{"1.2.3.4/32", MustParseAddr("1.2.3.4"), 32, "1.2.3.4/32"},
+		{"2001:db8::1/32", MustParseAddr("2001:db8::1"), 32, "2001:db8::/32"},
+		{"::ffff:1.2.3.4/104", MustParseAddr("::ffff:1.2.3.4"), 104, "::ffff:1.0.0.0/104"},
+		{"::/0", IPv6Unspecified(), 0, "::/0"},
+	} {
+		p, err := ParsePrefix(tt.in)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q): %v", tt.in, err)
+			continue
+		}
+		if p.Addr() != tt.ip || p.Bits() != tt.bits {
+			t.Errorf("ParsePrefix(%q) = %v, %d; want %v, %d", tt.in, p.Addr(), p.Bits(), tt.ip, tt.bits)
+		}
+		if s := p.String(); s != tt.in {
+			t.Errorf("ParsePrefix(%q).String() = %q", tt.in, s)
+		}
+		if s := p.Masked().String(); s != tt.masked {
+			t.Errorf("ParsePrefix(%q).Masked() = %q; want %q", tt.in, s, tt.masked)
+		}
+		var p2 Prefix
+		text, _ := p.MarshalText()
+		if err := p2.UnmarshalText(text); err != nil || p2 != p {
+			t.Errorf("UnmarshalText(%q) = %v, %v; want %v", text, p2, err, p)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"1.2.3.4",
+		"1.2.3.4/",
+		"1.2.3.4/33",
+		"1.2.3.4/-1",
+		"1.2.3.4/+8",
+		"1.2.3.4/08",
+		"2001:db8::/129",
+		"fe80::1%eth0/64",
+		"bad/8",
+	} {
+		if p, err := ParsePrefix(s); err == nil {
+			t.Errorf("ParsePrefix(%q) = %v; want error", s, p)
+		}
+	}
+}
+
+func TestPrefixFrom(t *testing.T) {
+	ip := MustParseAddr("fe80::1%eth0")
+	p := PrefixFrom(ip, 64)
+	if !p.IsValid() || p.Addr() != ip.WithZone("") {
+		t.Errorf("PrefixFrom(%v, 64) = %v; want zone dropped", ip, p)
+	}
+	for _, bits := range []int{-1, 129} {
+		if p := PrefixFrom(ip, bits); p.IsValid() || p.Bits() != -1 {
+			t.Errorf("PrefixFrom(%v, %d) = %v; want invalid", ip, bits, p)
+		}
+	}
+	if p := PrefixFrom(Addr{}, 0); p.IsValid() {
+		t.Errorf("PrefixFrom(Addr{}, 0) is valid")
+	}
+	if got, want := (Prefix{}).String(), "invalid Prefix"; got != want {
+		t.Errorf("zero Prefix String() = %q; want %q", got, want)
+	}
+	if !MustParsePrefix("1.2.3.4/32").IsSingleIP() || MustParsePrefix("1.2.3.4/31").IsSingleIP() {
+		t.Errorf("IsSingleIP mismatch")
+	}
+}
+
+func TestPrefixContains(t *testing.T) {
+	for _, tt := range []struct {
+		prefix string
+		ip     string
+		want   bool
+	}{
+		{"192.168.0.0/24", "192.168.0.0", true},
+		{"192.168.0.0/24", "192.168.0.255", true},
+		{"192.168.0.0/24", "192.168.1.0", false},
+		{"192.168.0.0/24", "::ffff:192.168.0.1", false},
+		{"0.0.0.0/0", "255.255.255.255", true},
+		{"1.2.3.4/32", "1.2.3.4", true},
+		{"1.2.3.4/32", "1.2.3.5", false},
+		{"2001:db8::/32", "2001:db8:ffff::1", true},
+		{"2001:db8::/32", "2001:db9::1", false},
+		{"fe80::/64", "fe80::1%eth0", false},
+		{"::/0", "1.2.3.4", false},
+		{"::ffff:0:0/96", "::ffff:1.2.3.4", true},
+	} {
+		p := MustParsePrefix(tt.prefix)
+		if got := p.Contains(MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("%s.Contains(%s) = %v; want %v", tt.prefix, tt.ip, got, tt.want)
+		}
+	}
+	if (Prefix{}).Contains(IPv4Unspecified()) {
+		t.Errorf("zero Prefix contains 0.0.0.0")
+	}
+}
+
+func TestPrefixOverlaps(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want bool
+	}{
+		{"1.2.0.0/16", "1.2.3.0/24", true},
+		{"1.2.3.0/24", "1.2.4.0/24", false},
+		{"0.0.0.0/0", "10.0.0.0/8", true},
+		{"1.2.3.4/32", "1.2.3.4/32", true},
+		{"1.2.3.4/32", "1.2.3.5/32", false},
+		{"2001:db8::/32", "2001:db8:1::/48", true},
+		{"2001:db8::/32", "2001:db9::/32", false},
+		{"::/0", "0.0.0.0/0", false},
+	} {
+		a, b := MustParsePrefix(tt.a), MustParsePrefix(tt.b)
+		if got := a.Overlaps(b); got != tt.want {
+			t.Errorf("%s.Overlaps(%s) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.Overlaps(a); got != tt.want {
+			t.Errorf("%s.Overlaps(%s) = %v; want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestAddrPrefix(t *testing.T) {
+	ip := MustParseAddr("1.2.3.4")
+	p, err := ip.Prefix(16)
+	if err != nil || p != MustParsePrefix("1.2.0.0/16") {
+		t.Errorf("Prefix(16) = %v, %v; want 1.2.0.0/16", p, err)
+	}
+	for _, bits := range []int{-1, 33} {
+		if _, err := ip.Prefix(bits); err == nil {
+			t.Errorf("Prefix(%d) succeeded; want error", bits)
+		}
+	}
+	if p, err := (Addr{}).Prefix(8); err != nil || p.IsValid() {
+		t.Errorf("Addr{}.Prefix(8) = %v, %v; want zero Prefix", p, err)
+	}
+}
+
+func TestParseAddrAllocs(t *testing.T) {
+	for _, s := range []string{"192.168.1.1", "2001:db8::1"} {
+		allocs := testing.AllocsPerRun(1000, func() {
+			if _, err := ParseAddr(s); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs > 0 {
+			t.Errorf("ParseAddr(%q): got %v allocs; want 0", s, allocs)
+		}
+	}
+}
+
+func BenchmarkParseAddr(b *testing.B) {
+	for _, s := range []string{"192.168.1.1", "2001:db8::1", "fe80::1%eth0"} {
+		b.Run(s, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ParseAddr(s)
+			}
+		})
+	}
+}
+
+func BenchmarkAddrString(b *testing.B) {
+	for _, s := range []string{"192.168.1.1", "2001:db8::1"} {
+		ip := MustParseAddr(s)
+		b.Run(s, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = ip.String()
+			}
+		})
+	}
+}
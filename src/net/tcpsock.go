@@ -7,6 +7,7 @@ package net
 import (
 	"context"
 	"io"
+	"net/netip"
 	"os"
 	"syscall"
 	"time"
@@ -50,6 +51,30 @@ func (a *TCPAddr) opAddr() Addr {
 	return a
 }
 
+// AddrPort returns the TCPAddr a as a netip.AddrPort.
+//
+// If a.Port does not fit in a uint16, it's silently truncated.
+// If a is nil or a.IP is not a valid IP address, the returned
+// AddrPort's Addr is the zero netip.Addr.
+func (a *TCPAddr) AddrPort() netip.AddrPort {
+	if a == nil {
+		return netip.AddrPort{}
+	}
+	ip, _ := netip.AddrFromSlice(a.IP)
+	return netip.AddrPortFrom(ip.WithZone(a.Zone), uint16(a.Port))
+}
+
+// TCPAddrFromAddrPort returns addr as a TCPAddr. If addr.IsValid() is
+// false, then the returned TCPAddr will contain a nil IP field,
+// indicating an address family-agnostic unspecified address.
+func TCPAddrFromAddrPort(addr netip.AddrPort) *TCPAddr {
+	return &TCPAddr{
+		IP:   addr.Addr().AsSlice(),
+		Zone: addr.Addr().Zone(),
+		Port: int(addr.Port()),
+	}
+}
+
 // ResolveTCPAddr returns an address of TCP end point.
 //
 // The network must be a TCP network name.
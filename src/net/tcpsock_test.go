@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"internal/testenv"
 	"io"
+	"net/netip"
 	"os"
 	"reflect"
 	"runtime"
@@ -540,6 +541,24 @@ func TestTCPReadWriteAllocs(t *testing.T) {
 	}
 }
 
+func TestTCPAddrAddrPort(t *testing.T) {
+	for _, tt := range []struct {
+		addr *TCPAddr
+		ap   netip.AddrPort
+	}{
+		{&TCPAddr{IP: IPv4(192, 0, 2, 1).To4(), Port: 443}, netip.MustParseAddrPort("192.0.2.1:443")},
+		{&TCPAddr{IP: ParseIP("2001:db8::1"), Port: 443}, netip.MustParseAddrPort("[2001:db8::1]:443")},
+		{&TCPAddr{IP: ParseIP("fe80::1"), Port: 22, Zone: "eth0"}, netip.MustParseAddrPort("[fe80::1%eth0]:22")},
+	} {
+		if ap := tt.addr.AddrPort(); ap != tt.ap {
+			t.Errorf("%v.AddrPort() = %v; want %v", tt.addr, ap, tt.ap)
+		}
+		if addr := TCPAddrFromAddrPort(tt.ap); !reflect.DeepEqual(addr, tt.addr) {
+			t.Errorf("TCPAddrFromAddrPort(%v) = %#v; want %#v", tt.ap, addr, tt.addr)
+		}
+	}
+}
+
 func TestTCPStress(t *testing.T) {
 	const conns = 2
 	const msgLen = 512
@@ -6,6 +6,7 @@ package net
 
 import (
 	"internal/testenv"
+	"net/netip"
 	"reflect"
 	"runtime"
 	"testing"
@@ -148,6 +149,13 @@ func testWriteToConn(t *testing.T, raddr string) {
 	if err != nil && err.(*OpError).Err != ErrWriteToConnected {
 		t.Fatalf("should fail as ErrWriteToConnected: %v", err)
 	}
+	_, err = c.(*UDPConn).WriteToUDPAddrPort(b, ra.AddrPort())
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	if err != nil && err.(*OpError).Err != ErrWriteToConnected {
+		t.Fatalf("should fail as ErrWriteToConnected: %v", err)
+	}
 	_, err = c.Write(b)
 	if err != nil {
 		t.Fatal(err)
@@ -451,3 +459,109 @@ func TestUDPReadSizeError(t *testing.T) {
 		}
 	}
 }
+
+var udpAddrPortTests = []struct {
+	addr *UDPAddr
+	ap   netip.AddrPort
+}{
+	{&UDPAddr{IP: IPv4(127, 0, 0, 1).To4(), Port: 80}, netip.MustParseAddrPort("127.0.0.1:80")},
+	{&UDPAddr{IP: IPv4(127, 0, 0, 1), Port: 80}, netip.MustParseAddrPort("[::ffff:127.0.0.1]:80")},
+	{&UDPAddr{IP: ParseIP("fe80::1"), Port: 53, Zone: "eth0"}, netip.MustParseAddrPort("[fe80::1%eth0]:53")},
+	{&UDPAddr{Port: 53}, netip.AddrPortFrom(netip.Addr{}, 53)},
+}
+
+func TestUDPAddrAddrPort(t *testing.T) {
+	for _, tt := range udpAddrPortTests {
+		if ap := tt.addr.AddrPort(); ap != tt.ap {
+			t.Errorf("%v.AddrPort() = %v; want %v", tt.addr, ap, tt.ap)
+		}
+		if addr := UDPAddrFromAddrPort(tt.ap); !reflect.DeepEqual(addr, tt.addr) {
+			t.Errorf("UDPAddrFromAddrPort(%v) = %#v; want %#v", tt.ap, addr, tt.addr)
+		}
+	}
+	var a *UDPAddr
+	if ap := a.AddrPort(); ap != (netip.AddrPort{}) {
+		t.Errorf("nil UDPAddr AddrPort() = %v; want zero", ap)
+	}
+}
+
+func TestUDPAddrPortReadWrite(t *testing.T) {
+	switch runtime.GOOS {
+	case "nacl", "plan9":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	for _, network := range []string{"udp4", "udp6"} {
+		if !testableNetwork(network) {
+			continue
+		}
+		c1, err := newLocalPacketListener(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c1.Close()
+		c2, err := newLocalPacketListener(network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c2.Close()
+
+		uc1, uc2 := c1.(*UDPConn), c2.(*UDPConn)
+		dst := uc1.LocalAddr().(*UDPAddr).AddrPort()
+		src := uc2.LocalAddr().(*UDPAddr).AddrPort()
+		uc1.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		wb := []byte("UDP ADDRPORT TEST")
+		rb := make([]byte, 128)
+		n, err := uc2.WriteToUDPAddrPort(wb, dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(wb) {
+			t.Fatalf("got %d; want %d", n, len(wb))
+		}
+		n, from, err := uc1.ReadFromUDPAddrPort(rb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rb[:n]) != string(wb) {
+			t.Errorf("got %q; want %q", rb[:n], wb)
+		}
+		if from != src {
+			t.Errorf("got %v; want %v", from, src)
+		}
+
+		if _, err := uc2.WriteToUDPAddrPort(wb, netip.AddrPort{}); err == nil {
+			t.Error("WriteToUDPAddrPort with zero AddrPort should fail")
+		}
+	}
+}
+
+func TestAllocsUDPAddrPort(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux":
+	default:
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	c, err := newLocalPacketListener("udp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	uc := c.(*UDPConn)
+	ap := uc.LocalAddr().(*UDPAddr).AddrPort()
+
+	var buf [128]byte
+	allocs := testing.AllocsPerRun(1000, func() {
+		if _, err := uc.WriteToUDPAddrPort(buf[:], ap); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := uc.ReadFromUDPAddrPort(buf[:]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Fatalf("got %v; want 0", allocs)
+	}
+}
@@ -27,6 +27,8 @@ type Error struct {
 
 func (e *Error) Error() string { return e.Op + " " + e.URL + ": " + e.Err.Error() }
 
+func (e *Error) Unwrap() error { return e.Err }
+
 type timeout interface {
 	Timeout() bool
 }
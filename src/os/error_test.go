@@ -5,6 +5,7 @@
 package os_test
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -155,3 +156,27 @@ func TestErrPathNUL(t *testing.T) {
 		t.Fatal("Open should have failed")
 	}
 }
+
+func TestErrorsIs(t *testing.T) {
+	_, err := os.Open("non-existent")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("errors.Is(%v, os.ErrNotExist) = false, want true", err)
+	}
+	var perr *os.PathError
+	if !errors.As(err, &perr) {
+		t.Fatalf("errors.As(%v, *os.PathError) = false, want true", err)
+	}
+	if errors.Unwrap(err) != perr.Err {
+		t.Errorf("errors.Unwrap(%v) = %v, want %v", err, errors.Unwrap(err), perr.Err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "_Go_ErrorsIs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	err = os.Mkdir(tmpDir, 0777)
+	if !errors.Is(err, os.ErrExist) {
+		t.Errorf("errors.Is(%v, os.ErrExist) = false, want true", err)
+	}
+}
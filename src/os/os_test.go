@@ -23,6 +23,7 @@ import (
 	"sync"
 	"syscall"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -683,6 +684,79 @@ func TestReaddirOfFile(t *testing.T) {
 	}
 }
 
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"c", "a", "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Mkdir(filepath.Join(dir, "d"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	list, err := ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range list {
+		name := d.Name()
+		if d.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	if want := []string{"a", "b", "c", "d/"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ReadDir = %v, want %v", names, want)
+	}
+
+	f, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var n int
+	for {
+		list, err := f.ReadDir(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("ReadDir(1) returned %d entries", len(list))
+		}
+		n++
+	}
+	if n != 4 {
+		t.Errorf("ReadDir(1) loop found %d entries, want 4", n)
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b/c", "b/d/e"} {
+		name = filepath.FromSlash(name)
+		if err := MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fstest.TestFS(DirFS(dir), "a", "b/c", "b/d/e"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid names must be rejected.
+	for _, name := range []string{"/a", "b/../a", "b/", ""} {
+		if _, err := DirFS(dir).Open(name); err == nil {
+			t.Errorf("Open(%q) succeeded, want error", name)
+		}
+	}
+}
+
 func TestHardLink(t *testing.T) {
 	testenv.MustHaveLink(t)
 
@@ -45,7 +45,7 @@ func newFileStatFromGetFileInformationByHandle(path string, h syscall.Handle) (f
 	var d syscall.ByHandleFileInformation
 	err = syscall.GetFileInformationByHandle(h, &d)
 	if err != nil {
-		return nil, &PathError{"GetFileInformationByHandle", path, err}
+		return nil, &PathError{Op: "GetFileInformationByHandle", Path: path, Err: err}
 	}
 	return &fileStat{
 		name:           basename(path),
@@ -105,7 +105,7 @@ func newFileStatFromGetFileAttributesExOrFindFirstFile(path string, pathp *uint1
 	// succeed. So just return ERROR_INVALID_NAME instead.
 	// see https://golang.org/issue/24999 for details.
 	if errno, _ := err.(syscall.Errno); errno == windows.ERROR_INVALID_NAME {
-		return nil, &PathError{"GetFileAttributesEx", path, err}
+		return nil, &PathError{Op: "GetFileAttributesEx", Path: path, Err: err}
 	}
 	// We might have symlink here. But some directories also have
 	// FileAttributes FILE_ATTRIBUTE_REPARSE_POINT bit set.
@@ -119,7 +119,7 @@ func newFileStatFromGetFileAttributesExOrFindFirstFile(path string, pathp *uint1
 	var fd syscall.Win32finddata
 	sh, err := syscall.FindFirstFile(pathp, &fd)
 	if err != nil {
-		return nil, &PathError{"FindFirstFile", path, err}
+		return nil, &PathError{Op: "FindFirstFile", Path: path, Err: err}
 	}
 	syscall.FindClose(sh)
 
@@ -132,7 +132,7 @@ func (fs *fileStat) updatePathAndName(name string) error {
 		var err error
 		fs.path, err = syscall.FullPath(fs.path)
 		if err != nil {
-			return &PathError{"FullPath", name, err}
+			return &PathError{Op: "FullPath", Path: name, Err: err}
 		}
 	}
 	fs.name = basename(name)
@@ -0,0 +1,47 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fstest
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMapFS(t *testing.T) {
+	m := MapFS{
+		"hello":             {Data: []byte("hello, world\n")},
+		"fortune/k/ken.txt": {Data: []byte("If a program is too slow, it must have a loop.\n")},
+	}
+	if err := TestFS(m, "hello", "fortune", "fortune/k", "fortune/k/ken.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMapFSEmptyDir(t *testing.T) {
+	m := MapFS{
+		"empty": {Mode: fs.ModeDir | 0755},
+		"a/b":   {Data: []byte("b")},
+	}
+	if err := TestFS(m, "empty", "a/b"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := fs.ReadDir(m, "empty")
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ReadDir(empty) = %v, %v, want empty list, nil", list, err)
+	}
+	if _, err := m.Open("missing"); !os.IsNotExist(err) {
+		t.Fatalf("Open(missing): err = %v, want not exist", err)
+	}
+}
+
+func TestTestFSReportsErrors(t *testing.T) {
+	m := MapFS{"hello": {Data: []byte("hello")}}
+	err := TestFS(m, "hello", "missing.txt")
+	if err == nil || !strings.Contains(err.Error(), "expected but not found: missing.txt") {
+		t.Fatalf("TestFS with missing file: err = %v, want expected but not found", err)
+	}
+}
@@ -0,0 +1,503 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	matchFuzz    = flag.String("test.fuzz", "", "run the fuzz target matching `regexp`")
+	fuzzDuration = flag.Duration("test.fuzztime", 0, "time to spend fuzzing; default is to run indefinitely")
+	fuzzCacheDir = flag.String("test.fuzzcachedir", "", "directory where interesting fuzzing inputs are stored (for use only by cmd/go)")
+)
+
+// corpusDir is the directory, relative to the package directory, holding
+// the seed corpus of each fuzz target in a subdirectory named after it.
+const corpusDir = "testdata/fuzz"
+
+// InternalFuzzTarget is an internal type but exported because it is
+// cross-package; it is part of the implementation of the "go test" command.
+type InternalFuzzTarget struct {
+	Name string
+	Fn   func(f *F)
+}
+
+// F is a type passed to fuzz targets.
+//
+// A fuzz target may add seed corpus entries using F.Add or by storing files
+// in the testdata/fuzz/<FuzzTargetName> directory. The fuzz target must then
+// call F.Fuzz once to provide a fuzz function. See the testing package
+// documentation for an example, and see the F.Fuzz and F.Add method
+// documentation for details.
+type F struct {
+	common
+	fuzzContext *fuzzContext
+	testContext *testContext
+	corpus      []corpusEntry
+	fuzzCalled  bool
+	inFuzzFn    bool // set while a fuzz function is running
+}
+
+var _ TB = (*F)(nil)
+
+// corpusEntry is an alias to the same type as internal/fuzz.CorpusEntry.
+// We use a type alias because we don't want to export this type, and we can't
+// import internal/fuzz from testing.
+type corpusEntry = struct {
+	Name   string
+	Path   string
+	Values []interface{}
+}
+
+// fuzzMode describes how fuzz targets are run.
+type fuzzMode int
+
+const (
+	seedCorpusOnly  fuzzMode = iota // run each corpus entry once, as a subtest
+	fuzzCoordinator                 // generate and run new inputs
+)
+
+// fuzzContext holds fields common to all fuzz targets.
+type fuzzContext struct {
+	deps testDeps
+	mode fuzzMode
+}
+
+// Add will add the arguments to the seed corpus for the fuzz target. This will
+// be a no-op if called after or within the Fuzz function. The args must match
+// those in the Fuzz function.
+func (f *F) Add(args ...interface{}) {
+	if f.fuzzCalled || f.inFuzzFn {
+		panic("testing: F.Add called after F.Fuzz")
+	}
+	if len(args) == 0 {
+		panic("testing: F.Add must have at least one argument")
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		if t := reflect.TypeOf(arg); !supportedTypes[t] {
+			panic(fmt.Sprintf("testing: unsupported type to Add %v", t))
+		}
+		values[i] = arg
+	}
+	f.corpus = append(f.corpus, corpusEntry{Name: fmt.Sprintf("seed#%d", len(f.corpus)), Values: values})
+}
+
+// supportedTypes represents all of the supported types which can be fuzzed.
+var supportedTypes = map[reflect.Type]bool{
+	reflect.TypeOf(([]byte)("")):  true,
+	reflect.TypeOf((string)("")):  true,
+	reflect.TypeOf((bool)(false)): true,
+	reflect.TypeOf((byte)(0)):     true,
+	reflect.TypeOf((rune)(0)):     true,
+	reflect.TypeOf((float32)(0)):  true,
+	reflect.TypeOf((float64)(0)):  true,
+	reflect.TypeOf((int)(0)):      true,
+	reflect.TypeOf((int8)(0)):     true,
+	reflect.TypeOf((int16)(0)):    true,
+	reflect.TypeOf((int32)(0)):    true,
+	reflect.TypeOf((int64)(0)):    true,
+	reflect.TypeOf((uint)(0)):     true,
+	reflect.TypeOf((uint8)(0)):    true,
+	reflect.TypeOf((uint16)(0)):   true,
+	reflect.TypeOf((uint32)(0)):   true,
+	reflect.TypeOf((uint64)(0)):   true,
+}
+
+// Fuzz runs the fuzz function, ff, for fuzz testing. If ff fails for a set of
+// arguments, those arguments will be added to the seed corpus.
+//
+// ff must be a function with no return value whose first argument is *T and
+// whose remaining arguments are the types to be fuzzed.
+// For example:
+//
+//     f.Fuzz(func(t *testing.T, b []byte, i int) { ... })
+//
+// The following types are allowed: []byte, string, bool, byte, rune, float32,
+// float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64.
+// More types may be supported in the future.
+//
+// ff must not call any *F methods, such as F.Log, F.Error, or F.Skip. Use
+// the corresponding *T method instead. The only *F methods that are allowed
+// in the F.Fuzz function are F.Failed and F.Name.
+//
+// This function should be fast and deterministic, and its behavior should
+// not depend on shared state. No mutable input arguments, or pointers to
+// them, should be retained between executions of the fuzz function, as the
+// memory backing them may be mutated during a subsequent invocation. ff must
+// not modify the underlying data of the arguments provided by the fuzzing
+// engine.
+//
+// When fuzzing, F.Fuzz does not return until a problem is found, time runs
+// out (set with -fuzztime), or the test process is interrupted by a signal.
+// F.Fuzz should be called exactly once, unless F.Skip or F.Fail is called
+// beforehand. Code following the call to F.Fuzz is not run.
+func (f *F) Fuzz(ff interface{}) {
+	if f.fuzzCalled {
+		panic("testing: F.Fuzz called more than once")
+	}
+	f.fuzzCalled = true
+	if f.Failed() {
+		return
+	}
+	f.Helper()
+
+	// Record the stack trace at the point of this call so that if the fuzz
+	// function is marked as a helper, we can continue walking the stack
+	// into the fuzz target.
+	var pc [maxStackLen]uintptr
+	n := runtime.Callers(2, pc[:])
+	creator := pc[:n]
+
+	// ff should be in the form func(*testing.T, ...interface{})
+	fn := reflect.ValueOf(ff)
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func {
+		panic("testing: F.Fuzz must receive a function")
+	}
+	if fnType.NumIn() < 2 || fnType.In(0) != reflect.TypeOf((*T)(nil)) {
+		panic("testing: fuzz target must receive at least two arguments, where the first argument is a *T")
+	}
+	if fnType.NumOut() != 0 {
+		panic("testing: fuzz target must not return a value")
+	}
+
+	// Save the types of the function to compare against the corpus.
+	var types []reflect.Type
+	for i := 1; i < fnType.NumIn(); i++ {
+		t := fnType.In(i)
+		if !supportedTypes[t] {
+			panic(fmt.Sprintf("testing: unsupported type for fuzzing %v", t))
+		}
+		types = append(types, t)
+	}
+
+	// Check the corpus provided by f.Add, then add the seed corpus stored
+	// in testdata.
+	for _, c := range f.corpus {
+		if err := f.fuzzContext.deps.CheckCorpus(c.Values, types); err != nil {
+			f.Fatal(err)
+		}
+	}
+	c, err := f.fuzzContext.deps.ReadCorpus(corpusDir+"/"+f.name, types)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.corpus = append(f.corpus, c...)
+
+	switch f.fuzzContext.mode {
+	case fuzzCoordinator:
+		// Fuzzing is enabled, and this is the test process started by
+		// 'go test'. Generate new inputs and run them, looking for failures.
+		cacheDir := ""
+		if *fuzzCacheDir != "" {
+			cacheDir = fmt.Sprintf("%s%c%s", *fuzzCacheDir, os.PathSeparator, f.name)
+		}
+		err := f.fuzzContext.deps.CoordinateFuzzing(
+			*fuzzDuration,
+			f.corpus,
+			types,
+			corpusDir+"/"+f.name,
+			cacheDir,
+			os.Stdout,
+			func(e corpusEntry) error {
+				return f.runInput(fn, creator, e)
+			})
+		if err != nil {
+			f.Error(err)
+			if crash, ok := err.(interface{ CrashName() string }); ok && crash.CrashName() != "" {
+				f.Logf("To re-run:\ngo test -run=%s/%s", f.name, crash.CrashName())
+			}
+		}
+
+	default:
+		// Fuzzing is not enabled. Run each corpus entry as a subtest.
+		for _, e := range f.corpus {
+			f.runSeed(fn, creator, e)
+		}
+	}
+
+	// Stop the fuzz target; code after the call to Fuzz is not run.
+	f.finished = true
+	runtime.Goexit()
+}
+
+// fuzzArgs returns the arguments with which to call the fuzz function for
+// the input e. []byte values are copied, so that a fuzz function that
+// modifies its arguments does not modify the corpus.
+func fuzzArgs(t *T, e corpusEntry) []reflect.Value {
+	args := make([]reflect.Value, 0, len(e.Values)+1)
+	args = append(args, reflect.ValueOf(t))
+	for _, v := range e.Values {
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		args = append(args, reflect.ValueOf(v))
+	}
+	return args
+}
+
+// newFuzzT returns a T for running the fuzz function on the input named
+// name, as a subtest of f. The creator is the stack trace of the call to
+// F.Fuzz.
+func (f *F) newFuzzT(name string, creator []uintptr) *T {
+	t := &T{
+		common: common{
+			barrier: make(chan bool),
+			signal:  make(chan bool),
+			name:    name,
+			parent:  &f.common,
+			level:   f.level + 1,
+			creator: creator,
+			chatty:  f.chatty,
+		},
+		context:  f.testContext,
+		inFuzzFn: true,
+	}
+	t.w = indenter{&t.common}
+	return t
+}
+
+// runSeed runs the fuzz function on e as a subtest of f, reporting the
+// result like any other subtest.
+func (f *F) runSeed(fn reflect.Value, creator []uintptr, e corpusEntry) {
+	testName, ok, _ := f.testContext.match.fullName(&f.common, e.Name)
+	if !ok || shouldFailFast() {
+		return
+	}
+	atomic.StoreInt32(&f.hasSub, 1)
+	t := f.newFuzzT(testName, creator)
+	if t.chatty {
+		root := t.parent
+		for ; root.parent != nil; root = root.parent {
+		}
+		root.mu.Lock()
+		fmt.Fprintf(root.w, "=== RUN   %s\n", t.name)
+		root.mu.Unlock()
+	}
+	f.inFuzzFn = true
+	go tRunner(t, func(t *T) {
+		fn.Call(fuzzArgs(t, e))
+	})
+	<-t.signal
+	f.inFuzzFn = false
+}
+
+// runInput runs the fuzz function on e while fuzzing. Unlike runSeed, it
+// does not report the result, and it recovers from panics in the fuzz
+// function so that fuzzing can continue with a minimized input.
+// It returns an error containing the output of the fuzz function if the
+// input caused a failure.
+func (f *F) runInput(fn reflect.Value, creator []uintptr, e corpusEntry) error {
+	t := f.newFuzzT(f.name+"/"+e.Name, creator)
+	f.inFuzzFn = true
+	defer func() { f.inFuzzFn = false }()
+
+	var panicked interface{}
+	var stack []byte
+	done := make(chan struct{})
+	go func() {
+		t.runner = callerName(0)
+		defer close(done)
+		defer func() {
+			if err := recover(); err != nil {
+				panicked, stack = err, debug.Stack()
+			} else if !t.finished {
+				panicked = errNilPanicOrGoexit
+			}
+		}()
+		defer t.runCleanup(normalPanic)
+		fn.Call(fuzzArgs(t, e))
+		t.finished = true
+	}()
+	<-done
+	t.done = true
+
+	if panicked == nil && !t.Failed() {
+		return nil
+	}
+	// Report the failure on f and keep the output of t as the error.
+	f.Fail()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	msg := strings.TrimRight(string(t.output), "\n")
+	if panicked != nil {
+		if msg != "" {
+			msg += "\n"
+		}
+		msg += fmt.Sprintf("panic: %v\n%s", panicked, stack)
+	}
+	if msg == "" {
+		msg = "fuzz function failed"
+	}
+	return errors.New(msg)
+}
+
+func (f *F) report() {
+	if f.parent == nil {
+		return
+	}
+	dstr := fmtDuration(f.duration)
+	format := "--- %s: %s (%s)\n"
+	if f.Failed() {
+		f.flushToParent(format, "FAIL", f.name, dstr)
+	} else if f.chatty {
+		if f.Skipped() {
+			f.flushToParent(format, "SKIP", f.name, dstr)
+		} else {
+			f.flushToParent(format, "PASS", f.name, dstr)
+		}
+	}
+}
+
+// fRunner runs a fuzz target, in the way tRunner runs a test.
+func fRunner(f *F, fn func(*F)) {
+	f.runner = callerName(0)
+
+	// When this goroutine is done, either because fn(f) returned normally,
+	// or F.Fuzz, F.FailNow or F.SkipNow called runtime.Goexit, record the
+	// duration and send a signal saying that the fuzz target is done.
+	defer func() {
+		if f.Failed() {
+			atomic.AddUint32(&numFailed, 1)
+		}
+		f.duration += time.Since(f.start)
+		err := recover()
+		if !f.finished && err == nil {
+			err = errNilPanicOrGoexit
+		}
+		if err != nil {
+			f.Fail()
+			f.report()
+			panic(err)
+		}
+		f.report()
+		f.done = true
+		f.setRan()
+		f.signal <- true
+	}()
+	defer func() {
+		f.runCleanup(normalPanic)
+	}()
+
+	f.start = time.Now()
+	fn(f)
+
+	// Code beyond here is only executed if fn did not call F.Fuzz.
+	f.finished = true
+}
+
+// newF returns an F for running the fuzz target named name as a child of
+// root.
+func newF(name string, root *common, fctx *fuzzContext, tctx *testContext) *F {
+	f := &F{
+		common: common{
+			signal:  make(chan bool),
+			barrier: make(chan bool),
+			name:    name,
+			parent:  root,
+			level:   root.level + 1,
+			chatty:  root.chatty,
+		},
+		fuzzContext: fctx,
+		testContext: tctx,
+	}
+	f.w = indenter{&f.common}
+	return f
+}
+
+// runFuzzTests runs the fuzz targets matching the pattern for -run. This will
+// only run the (*F).Fuzz function for each seed corpus entry, without
+// generating new inputs.
+func runFuzzTests(deps testDeps, fuzzTargets []InternalFuzzTarget) (ran, ok bool) {
+	ok = true
+	if len(fuzzTargets) == 0 {
+		return ran, ok
+	}
+	for _, procs := range cpuList {
+		runtime.GOMAXPROCS(procs)
+		for i := uint(0); i < *count; i++ {
+			if shouldFailFast() {
+				break
+			}
+			tctx := newTestContext(*parallel, newMatcher(deps.MatchString, *match, "-test.run"))
+			fctx := &fuzzContext{deps: deps, mode: seedCorpusOnly}
+			root := common{w: os.Stdout, chatty: *chatty}
+			for _, ft := range fuzzTargets {
+				if shouldFailFast() {
+					break
+				}
+				testName, matched, _ := tctx.match.fullName(nil, ft.Name)
+				if !matched {
+					continue
+				}
+				f := newF(testName, &root, fctx, tctx)
+				if f.chatty {
+					root.mu.Lock()
+					fmt.Fprintf(root.w, "=== RUN   %s\n", f.name)
+					root.mu.Unlock()
+				}
+				go fRunner(f, ft.Fn)
+				<-f.signal
+			}
+			ok = ok && !root.Failed()
+			ran = ran || root.ran
+		}
+	}
+	return ran, ok
+}
+
+// runFuzzing runs the fuzz target matching the pattern for -fuzz. Only one
+// fuzz target must match. This will generate new inputs and run them
+// against the fuzz target, and write any inputs that cause a failure to
+// the seed corpus directory, testdata/fuzz/<FuzzTargetName>.
+func runFuzzing(deps testDeps, fuzzTargets []InternalFuzzTarget) (ok bool) {
+	if *matchFuzz == "" {
+		return true
+	}
+	tctx := newTestContext(1, newMatcher(deps.MatchString, *matchFuzz, "-test.fuzz"))
+	fctx := &fuzzContext{deps: deps, mode: fuzzCoordinator}
+	root := common{w: os.Stdout, chatty: *chatty}
+	var target *InternalFuzzTarget
+	var targetName string
+	var matched []string
+	for i := range fuzzTargets {
+		name, ok, _ := tctx.match.fullName(nil, fuzzTargets[i].Name)
+		if !ok {
+			continue
+		}
+		matched = append(matched, name)
+		target = &fuzzTargets[i]
+		targetName = name
+	}
+	if len(matched) == 0 {
+		fmt.Fprintln(os.Stderr, "testing: warning: no fuzz targets to fuzz")
+		return true
+	}
+	if len(matched) > 1 {
+		fmt.Fprintf(os.Stderr, "testing: will not fuzz, -fuzz matches more than one fuzz target: %v\n", matched)
+		return false
+	}
+
+	f := newF(targetName, &root, fctx, tctx)
+	if f.chatty {
+		root.mu.Lock()
+		fmt.Fprintf(root.w, "=== FUZZ  %s\n", f.name)
+		root.mu.Unlock()
+	}
+	go fRunner(f, target.Fn)
+	<-f.signal
+	return !f.Failed()
+}
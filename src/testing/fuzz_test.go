@@ -0,0 +1,62 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testing
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+func FuzzSeedCorpus(f *F) {
+	f.Add([]byte("a"), 1)
+	f.Add([]byte("bb"), 2)
+	f.Fuzz(func(t *T, b []byte, n int) {
+		if len(b) != n {
+			t.Errorf("got input %q, %d; want matching length", b, n)
+		}
+	})
+}
+
+func TestFuzzRunInput(t *T) {
+	root := common{w: &discardWriter{}}
+	tctx := newTestContext(1, newMatcher(regexp.MatchString, "", ""))
+	f := newF("FuzzX", &root, &fuzzContext{mode: fuzzCoordinator}, tctx)
+
+	testCases := []struct {
+		desc   string
+		fn     func(t *T, s string)
+		output string // substring expected in the error; "" for success
+	}{{
+		desc: "pass",
+		fn:   func(t *T, s string) {},
+	}, {
+		desc:   "fatal",
+		fn:     func(t *T, s string) { t.Fatalf("bad input %q", s) },
+		output: `bad input "x"`,
+	}, {
+		desc:   "panic",
+		fn:     func(t *T, s string) { panic("boom " + s) },
+		output: "panic: boom x",
+	}, {
+		desc: "skip",
+		fn:   func(t *T, s string) { t.Skip("skipped") },
+	}}
+	for _, tc := range testCases {
+		err := f.runInput(reflect.ValueOf(tc.fn), nil, corpusEntry{Values: []interface{}{"x"}})
+		switch {
+		case tc.output == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tc.desc, err)
+		case tc.output != "" && err == nil:
+			t.Errorf("%s: got no error, want one containing %q", tc.desc, tc.output)
+		case tc.output != "" && !strings.Contains(err.Error(), tc.output):
+			t.Errorf("%s: got error %q, want one containing %q", tc.desc, err, tc.output)
+		}
+	}
+}
+
+type discardWriter struct{}
+
+func (*discardWriter) Write(b []byte) (int, error) { return len(b), nil }
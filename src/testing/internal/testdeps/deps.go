@@ -12,12 +12,15 @@ package testdeps
 
 import (
 	"bufio"
+	"internal/fuzz"
 	"internal/testlog"
 	"io"
+	"reflect"
 	"regexp"
 	"runtime/pprof"
 	"strings"
 	"sync"
+	"time"
 )
 
 // TestDeps is an implementation of the testing.testDeps interface,
@@ -122,3 +125,22 @@ func (TestDeps) StopTestLog() error {
 	log.w = nil
 	return err
 }
+
+func (TestDeps) CoordinateFuzzing(timeout time.Duration, seed []fuzz.CorpusEntry, types []reflect.Type, corpusDir, cacheDir string, log io.Writer, fn func(fuzz.CorpusEntry) error) error {
+	return fuzz.CoordinateFuzzing(fuzz.CoordinateFuzzingOpts{
+		Log:       log,
+		Timeout:   timeout,
+		Seed:      seed,
+		Types:     types,
+		CorpusDir: corpusDir,
+		CacheDir:  cacheDir,
+	}, fn)
+}
+
+func (TestDeps) ReadCorpus(dir string, types []reflect.Type) ([]fuzz.CorpusEntry, error) {
+	return fuzz.ReadCorpus(dir, types)
+}
+
+func (TestDeps) CheckCorpus(vals []interface{}, types []reflect.Type) error {
+	return fuzz.CheckCorpus(vals, types)
+}
@@ -9,7 +9,9 @@ package template
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"testing"
+	"testing/fstest"
 	"text/template/parse"
 )
 
@@ -153,6 +155,46 @@ func TestParseGlob(t *testing.T) {
 	testExecute(multiExecTests, template, t)
 }
 
+func TestParseFS(t *testing.T) {
+	fs := os.DirFS("testdata")
+
+	{
+		_, err := ParseFS(fs, "DOES NOT EXIST")
+		if err == nil {
+			t.Error("expected error for non-existent file; got none")
+		}
+	}
+
+	{
+		template := New("root")
+		_, err := template.ParseFS(fs, "file1.tmpl", "file2.tmpl")
+		if err != nil {
+			t.Fatalf("error parsing files: %v", err)
+		}
+		testExecute(multiExecTests, template, t)
+	}
+
+	{
+		template := New("root")
+		_, err := template.ParseFS(fs, "file*.tmpl")
+		if err != nil {
+			t.Fatalf("error parsing files: %v", err)
+		}
+		testExecute(multiExecTests, template, t)
+	}
+
+	{
+		// Names are the base names of the files, as with ParseFiles.
+		template, err := ParseFS(fstest.MapFS{"dir/x.tmpl": {Data: []byte("x")}}, "dir/*.tmpl")
+		if err != nil {
+			t.Fatalf("error parsing files: %v", err)
+		}
+		if template.Name() != "x.tmpl" {
+			t.Errorf("template name = %q, want %q", template.Name(), "x.tmpl")
+		}
+	}
+}
+
 // In these tests, actual content (not just template definitions) comes from the parsed files.
 
 var templateFileExecTests = []execTest{
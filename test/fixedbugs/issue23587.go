@@ -7,6 +7,6 @@
 package p
 
 func f(x int) {
-	_ = ~x    // ERROR "invalid character"
-	_ = x ~ x // ERROR "invalid character" "unexpected x at end of statement"
+	_ = ~x    // ERROR "unexpected ~"
+	_ = x ~ x // ERROR "unexpected ~ at end of statement"
 }
@@ -0,0 +1,69 @@
+// errorcheck
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that type arguments not satisfying their constraints
+// are rejected.
+
+package p
+
+type Integer interface {
+	~int | ~int32 | ~int64
+}
+
+type Signed interface {
+	Integer | ~float64
+}
+
+type Exact interface {
+	int | string
+}
+
+type Stringer interface {
+	String() string
+}
+
+type IntStringer interface {
+	~int
+	String() string
+}
+
+func Abs[T Signed](x T) T { return x }
+
+func F[T Exact](x T) T { return x }
+
+func S[T Stringer](x T) string { return x.String() }
+
+func IS[T IntStringer](x T) string { return x.String() }
+
+func Eq[T comparable](x, y T) bool { return x == y }
+
+type myInt int
+
+func (myInt) String() string { return "" }
+
+type ptrStringer int
+
+func (*ptrStringer) String() string { return "" }
+
+type badStringer int
+
+func (badStringer) String() int { return 0 }
+
+var (
+	_ = Abs(myInt(1))
+	_ = Abs(uint(1)) // ERROR "uint does not satisfy Signed"
+	_ = F(1)
+	_ = F(myInt(1)) // ERROR "myInt does not satisfy Exact"
+	_ = S(myInt(1))
+	_ = S(1)              // ERROR "int does not satisfy Stringer \(missing String method\)"
+	_ = S(ptrStringer(1)) // ERROR "ptrStringer does not satisfy Stringer \(String method has pointer receiver\)"
+	_ = S(badStringer(1)) // ERROR "badStringer does not satisfy Stringer \(wrong type for String method\)"
+	_ = IS(myInt(1))
+	_ = IS(ptrStringer(1)) // ERROR "ptrStringer does not satisfy IntStringer"
+	_ = Eq(1, 2)
+	_ = Eq([]int{}, nil)     // ERROR "\[\]int does not satisfy comparable"
+	_ = Eq[func()](nil, nil) // ERROR "func\(\) does not satisfy comparable"
+)
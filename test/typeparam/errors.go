@@ -0,0 +1,36 @@
+// errorcheck
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p
+
+import "fmt"
+
+type Number interface {
+	~int | ~float64
+}
+
+func Sum[T Number](list []T) T {
+	var s T
+	for _, x := range list {
+		s += x
+	}
+	return s
+}
+
+func Id[T any](x T) T { return x }
+
+type List[T any] struct{ val T }
+
+type Str[T fmt.Stringer] struct{ v T }
+
+var _ = Sum([]string{"a"}) // ERROR "string does not satisfy Number"
+var _ = Id                 // ERROR "cannot use generic function Id without instantiation"
+var _ List                 // ERROR "cannot use generic type List without instantiation"
+var _ Number               // ERROR "cannot use Number outside a type constraint"
+var _ = Id[int, int]       // ERROR "got 2 type arguments"
+var _ = Id(nil)            // ERROR "cannot infer T"
+var _ Str[int]             // ERROR "int does not satisfy fmt.Stringer"
+var _ comparable           // ERROR "cannot use comparable outside a type constraint"
@@ -0,0 +1,27 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import "fmt"
+
+type Number interface {
+	~int | ~float64
+}
+
+func Sum[T Number](list ...T) T {
+	var s T
+	for _, x := range list {
+		s += x
+	}
+	return s
+}
+
+func Id[T any](x T) T { return x }
+
+func id[T any](x T) T { return x }
+
+type List[T any] struct{ val T }
+
+type Str[T fmt.Stringer] struct{ v T }
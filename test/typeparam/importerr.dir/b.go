@@ -0,0 +1,20 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+import "./a"
+
+var (
+	_ = a.Sum(1, 2)
+	_ = a.Sum("a")          // ERROR "string does not satisfy Number"
+	_ = a.Id                // ERROR "cannot use generic function a.Id without instantiation"
+	_ a.List                // ERROR "cannot use generic type a.List without instantiation"
+	_ a.Number              // ERROR "cannot use a.Number outside a type constraint"
+	_ = a.Id(nil)           // ERROR "cannot infer T"
+	_ = a.Id[int, int]      // ERROR "got 2 type arguments but a.Id has 1 type parameters"
+	_ a.Str[int]            // ERROR "int does not satisfy fmt.Stringer"
+	_ = a.id[int]           // ERROR "cannot refer to unexported name a.id"
+	_ = a.List[int]{val: 1} // ERROR "unknown field 'val'|unexported field"
+)
@@ -0,0 +1,10 @@
+// errorcheckdir
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test errors in the use of generic declarations of other
+// packages.
+
+package ignored
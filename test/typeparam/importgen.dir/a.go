@@ -0,0 +1,60 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import "strings"
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+var adds int
+
+// Adds returns the number of additions done by Sum.
+func Adds() int { return adds }
+
+func add[T Number](x, y T) T {
+	adds++
+	return x + y
+}
+
+func Sum[T Number](list []T) T {
+	var s T
+	for _, x := range list {
+		s = add(s, x)
+	}
+	return s
+}
+
+const sep = ","
+
+type List[T any] struct {
+	items []T
+}
+
+func (l *List[T]) Push(v T) { l.items = append(l.items, v) }
+
+func (l *List[T]) Len() int { return len(l.items) }
+
+func (l *List[T]) Join(f func(T) string) string {
+	var s []string
+	for _, v := range l.items {
+		s = append(s, f(v))
+	}
+	return strings.Join(s, sep)
+}
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+func Keys[K comparable, V any](ps []Pair[K, V]) []K {
+	var keys []K
+	for _, p := range ps {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
@@ -0,0 +1,28 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+import "./a"
+
+func Max[T a.Number](list ...T) T {
+	m := list[0]
+	for _, x := range list {
+		if x > m {
+			m = x
+		}
+	}
+	return m
+}
+
+// Total is small enough to be inlined into importers.
+func Total(list []int) int { return a.Sum(list) }
+
+func Ints(list ...int) *a.List[int] {
+	l := new(a.List[int])
+	for _, x := range list {
+		l.Push(x)
+	}
+	return l
+}
@@ -0,0 +1,52 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"./a"
+	"./b"
+)
+
+type myFloat float64
+
+func main() {
+	if got, want := a.Sum([]int{1, 2, 3}), 6; got != want {
+		panic(fmt.Sprintf("a.Sum = %d, want %d", got, want))
+	}
+	if got, want := a.Sum([]myFloat{1.5, 2}), myFloat(3.5); got != want {
+		panic(fmt.Sprintf("a.Sum = %v, want %v", got, want))
+	}
+	if got, want := a.Adds(), 5; got != want {
+		panic(fmt.Sprintf("a.Adds = %d, want %d", got, want))
+	}
+
+	if got, want := b.Max(3, 7, 5), 7; got != want {
+		panic(fmt.Sprintf("b.Max = %d, want %d", got, want))
+	}
+	if got, want := b.Total([]int{4, 5}), 9; got != want {
+		panic(fmt.Sprintf("b.Total = %d, want %d", got, want))
+	}
+	if got, want := b.Ints(1, 2).Len(), 2; got != want {
+		panic(fmt.Sprintf("b.Ints.Len = %d, want %d", got, want))
+	}
+
+	var l a.List[float64]
+	l.Push(1.5)
+	l.Push(2)
+	if got, want := l.Join(func(x float64) string { return strconv.FormatFloat(x, 'g', -1, 64) }), "1.5,2"; got != want {
+		panic(fmt.Sprintf("a.List.Join = %q, want %q", got, want))
+	}
+	if got, want := fmt.Sprintf("%T", &l), "*a.List[float64]"; got != want {
+		panic(fmt.Sprintf("%%T = %s, want %s", got, want))
+	}
+
+	ps := []a.Pair[string, int]{{"x", 1}, {"y", 2}}
+	if got, want := fmt.Sprint(a.Keys(ps)), "[x y]"; got != want {
+		panic(fmt.Sprintf("a.Keys = %s, want %s", got, want))
+	}
+}
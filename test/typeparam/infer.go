@@ -0,0 +1,60 @@
+// run
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func Map[T, R any](list []T, f func(T) R) []R {
+	res := make([]R, 0, len(list))
+	for _, x := range list {
+		res = append(res, f(x))
+	}
+	return res
+}
+
+func Keys[K comparable, V any](m map[K]V) []K {
+	var keys []K
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func First[S ~[]E, E any](s S) E { return s[0] }
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+type names []string
+
+func main() {
+	got := Map([]int{1, 2}, func(i int) string { return strings.Repeat("x", i) })
+	if fmt.Sprint(got) != "[x xx]" {
+		panic(fmt.Sprint("Map = ", got))
+	}
+	doubled := Map[int, int]([]int{3}, func(i int) int { return i * 2 })
+	if doubled[0] != 6 {
+		panic(fmt.Sprint("Map[int, int] = ", doubled))
+	}
+	keys := Keys(map[string]int{"a": 1, "b": 2})
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[a b]" {
+		panic(fmt.Sprint("Keys = ", keys))
+	}
+	if s := First(names{"q", "r"}); s != "q" {
+		panic(fmt.Sprint("First = ", s))
+	}
+	if p := (Pair[string, int]{"a", 1}); fmt.Sprint(p) != "{a 1}" {
+		panic(fmt.Sprint("Pair = ", p))
+	}
+}
@@ -0,0 +1,36 @@
+// errorcheck
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that type arguments that cannot be inferred are
+// reported.
+
+package p
+
+func Zero[T any]() T {
+	var x T
+	return x
+}
+
+func Id[T any](x T) T { return x }
+
+func Map[T, R any](list []T, f func(T) R) []R { return nil }
+
+func Conv[To, From any](x From) To { return *new(To) }
+
+func Elem[S ~[]E, E any](s S) E { return s[0] }
+
+var (
+	_ = Zero() // ERROR "cannot infer T"
+	_ = Zero[int]()
+	_ = Id(nil)           // ERROR "cannot infer T"
+	_ = Map([]int{}, nil) // ERROR "cannot infer R"
+	_ = Conv(1)           // ERROR "cannot infer To"
+	_ = Conv[string](1)
+	_ = Elem([]int{1})
+	_ = Elem(nil)           // ERROR "cannot infer S"
+	_ = Id[int, int]        // ERROR "got 2 type arguments but Id has 1 type parameters"
+	_ = Conv[int, int, int] // ERROR "got 3 type arguments but Conv has 2 type parameters"
+)
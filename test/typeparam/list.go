@@ -0,0 +1,70 @@
+// run
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+)
+
+type List[T any] struct {
+	next *List[T]
+	val  T
+}
+
+func (l *List[T]) Push(v T) *List[T] { return &List[T]{l, v} }
+
+func (l *List[_]) Len() int {
+	n := 0
+	for ; l != nil; l = l.next {
+		n++
+	}
+	return n
+}
+
+func (l *List[T]) Slice() []T {
+	var s []T
+	for ; l != nil; l = l.next {
+		s = append(s, l.val)
+	}
+	return s
+}
+
+type Stack[T any] struct {
+	*List[T]
+}
+
+func (s *Stack[T]) Push(v T) { s.List = s.List.Push(v) }
+
+func (s *Stack[T]) Pop() T {
+	v := s.List.val
+	s.List = s.List.next
+	return v
+}
+
+func main() {
+	var l *List[string]
+	l = l.Push("a").Push("b")
+	if got := l.Len(); got != 2 {
+		panic(fmt.Sprintf("Len = %d, want 2", got))
+	}
+	if got := fmt.Sprint(l.Slice()); got != "[b a]" {
+		panic(fmt.Sprintf("Slice = %s, want [b a]", got))
+	}
+	if got := fmt.Sprintf("%T", l); got != "*main.List[string]" {
+		panic(fmt.Sprintf("%%T = %s, want *main.List[string]", got))
+	}
+
+	var s Stack[int]
+	s.Push(1)
+	s.Push(2)
+	if got := s.Pop(); got != 2 {
+		panic(fmt.Sprintf("Pop = %d, want 2", got))
+	}
+	if got := s.Len(); got != 1 {
+		panic(fmt.Sprintf("Len = %d, want 1", got))
+	}
+}
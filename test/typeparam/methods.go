@@ -0,0 +1,89 @@
+// run
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test methods of generic types.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Pair[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// value receiver
+func (p Pair[K, V]) Key() K { return p.key }
+
+// pointer receiver, calling a method with a value receiver
+func (p *Pair[K, V]) Set(v V) V {
+	old := p.val
+	p.val = v
+	if p.Key() == *new(K) {
+		panic("zero key")
+	}
+	return old
+}
+
+// receiver type parameters may be renamed
+func (p Pair[A, B]) String() string { return fmt.Sprintf("%v=%v", p.key, p.val) }
+
+type Set[T comparable] map[T]bool
+
+func (s Set[T]) Add(v T) Set[T] {
+	s[v] = true
+	return s
+}
+
+func (s Set[T]) Has(v T) bool { return s[v] }
+
+type Counter[T any] struct {
+	n int
+}
+
+func (c *Counter[T]) Inc(T) { c.n++ }
+
+func main() {
+	p := Pair[string, int]{"a", 1}
+	if got := p.Set(2); got != 1 {
+		panic(fmt.Sprintf("Set = %d, want 1", got))
+	}
+	if got := p.Key(); got != "a" {
+		panic(fmt.Sprintf("Key = %q, want %q", got, "a"))
+	}
+
+	// method values and expressions
+	key := p.Key
+	set := (*Pair[string, int]).Set
+	set(&p, 3)
+	if got := key(); got != "a" {
+		panic(fmt.Sprintf("method value = %q, want %q", got, "a"))
+	}
+
+	// instances satisfy interfaces
+	var s fmt.Stringer = p
+	if got := s.String(); got != "a=3" {
+		panic(fmt.Sprintf("String = %q, want %q", got, "a=3"))
+	}
+	if got := fmt.Sprint(&p); got != "a=3" {
+		panic(fmt.Sprintf("Sprint = %q, want %q", got, "a=3"))
+	}
+
+	if !make(Set[string]).Add("x").Has("x") {
+		panic("Set.Has = false, want true")
+	}
+
+	var c Counter[[]string]
+	for _, f := range strings.Fields("a b c") {
+		c.Inc([]string{f})
+	}
+	if c.n != 3 {
+		panic(fmt.Sprintf("Counter.n = %d, want 3", c.n))
+	}
+}
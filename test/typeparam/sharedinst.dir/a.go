@@ -0,0 +1,33 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) grow(v T) { s.items = append(s.items, v) }
+
+func (s *Stack[T]) Push(v T) { s.grow(v) }
+
+func (s *Stack[T]) Pop() T {
+	v := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return v
+}
+
+func (s *Stack[T]) Len() int { return len(s.items) }
+
+type Number interface {
+	~int | ~float64
+}
+
+func Sum[T Number](s *Stack[T]) T {
+	var t T
+	for _, v := range s.items {
+		t += v
+	}
+	return t
+}
@@ -0,0 +1,17 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+import "./a"
+
+var S a.Stack[int]
+
+func Push(v int) { S.Push(v) }
+
+func Total(x interface{}) int { return a.Sum(x.(*a.Stack[int])) }
+
+type T struct{ X int }
+
+var P a.Stack[T]
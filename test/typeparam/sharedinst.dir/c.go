@@ -0,0 +1,24 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package c
+
+import (
+	"./a"
+	"./b"
+)
+
+func New(list ...int) interface{} {
+	s := new(a.Stack[int])
+	for _, v := range list {
+		s.Push(v)
+	}
+	return s
+}
+
+func Pop(s *a.Stack[int]) int { return s.Pop() }
+
+func Total(x interface{}) int { return a.Sum(x.(*a.Stack[int])) }
+
+func Pop2(x interface{}) int { return x.(*a.Stack[b.T]).Pop().X }
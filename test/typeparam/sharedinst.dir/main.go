@@ -0,0 +1,74 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"./a"
+	"./b"
+	"./c"
+)
+
+func main() {
+	// b's instance passed to c.
+	b.Push(1)
+	b.Push(2)
+	if got, want := c.Pop(&b.S), 2; got != want {
+		panic(fmt.Sprintf("c.Pop = %d, want %d", got, want))
+	}
+	if got, want := c.Total(&b.S), 1; got != want {
+		panic(fmt.Sprintf("c.Total = %d, want %d", got, want))
+	}
+
+	// c's instance passed to b.
+	x := c.New(3, 4)
+	if got, want := b.Total(x), 7; got != want {
+		panic(fmt.Sprintf("b.Total = %d, want %d", got, want))
+	}
+
+	// And both used here.
+	s, ok := x.(*a.Stack[int])
+	if !ok {
+		panic(fmt.Sprintf("c.New returned %T, not *a.Stack[int]", x))
+	}
+	s.Push(5)
+	if got, want := a.Sum(s), 12; got != want {
+		panic(fmt.Sprintf("a.Sum = %d, want %d", got, want))
+	}
+	var y interface{} = &b.S
+	if _, ok := y.(*a.Stack[int]); !ok {
+		panic(fmt.Sprintf("&b.S is %T, not *a.Stack[int]", y))
+	}
+	if got, want := fmt.Sprintf("%T", x), "*a.Stack[int]"; got != want {
+		panic(fmt.Sprintf("%%T = %s, want %s", got, want))
+	}
+	switch x.(type) {
+	case *a.Stack[float64]:
+		panic("x is a *a.Stack[float64]")
+	case *a.Stack[int]:
+	default:
+		panic(fmt.Sprintf("x is a %T", x))
+	}
+
+	// A type argument declared by one of the importers.
+	b.P.Push(b.T{6})
+	if got, want := c.Pop2(&b.P), 6; got != want {
+		panic(fmt.Sprintf("c.Pop2 = %d, want %d", got, want))
+	}
+	if got, want := fmt.Sprintf("%T", &b.P), "*a.Stack[b.T]"; got != want {
+		panic(fmt.Sprintf("%%T = %s, want %s", got, want))
+	}
+	switch y := interface{}(&b.P).(type) {
+	case *a.Stack[int]:
+		panic("&b.P is a *a.Stack[int]")
+	case *a.Stack[b.T]:
+		if y.Len() != 0 {
+			panic(fmt.Sprintf("b.P.Len = %d, want 0", y.Len()))
+		}
+	default:
+		panic(fmt.Sprintf("&b.P is a %T", y))
+	}
+}
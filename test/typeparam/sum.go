@@ -0,0 +1,50 @@
+// run
+
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+)
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+func Sum[T Number](list []T) T {
+	var s T
+	for _, x := range list {
+		s += x
+	}
+	return s
+}
+
+type myInt int
+
+func Abs[T Number](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func main() {
+	if got, want := Sum([]int{1, 2, 3}), 6; got != want {
+		panic(fmt.Sprintf("Sum(int) = %d, want %d", got, want))
+	}
+	if got, want := Sum([]float64{1.5, 2}), 3.5; got != want {
+		panic(fmt.Sprintf("Sum(float64) = %v, want %v", got, want))
+	}
+	if got, want := Sum([]myInt{4, 5}), myInt(9); got != want {
+		panic(fmt.Sprintf("Sum(myInt) = %d, want %d", got, want))
+	}
+	if got, want := Sum[int64](nil), int64(0); got != want {
+		panic(fmt.Sprintf("Sum(nil) = %d, want %d", got, want))
+	}
+	if got, want := Abs(-2.5), 2.5; got != want {
+		panic(fmt.Sprintf("Abs(-2.5) = %v, want %v", got, want))
+	}
+}